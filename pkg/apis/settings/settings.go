@@ -17,10 +17,15 @@ package settings
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/configmap"
 )
 
@@ -28,18 +33,180 @@ type settingsKeyType struct{}
 
 var ContextKey = settingsKeyType{}
 
+// DeprovisioningMethods are the voluntary deprovisioning methods in their default order
+var DeprovisioningMethods = []string{"repair", "expiration", "drift", "emptiness", "consolidation"}
+
 var defaultSettings = &Settings{
 	BatchMaxDuration:  time.Second * 10,
 	BatchIdleDuration: time.Second * 1,
 	DriftEnabled:      false,
+
+	DriftRateLimitInterval: time.Minute * 10,
+	DeprovisioningOrder:    DeprovisioningMethods,
+
+	SpotToSpotConsolidationMinFlexibility: 15,
+
+	MultiMachineConsolidationMaxMachines: 100,
+	MultiMachineConsolidationTimeout:     time.Minute,
+
+	DeprovisioningMaxParallelActions: 1,
+
+	DisruptionRateLimitInterval: time.Hour,
+
+	DeprovisioningWebhookTimeout: time.Second * 10,
+
+	TerminationHookTimeout:       time.Second * 30,
+	TerminationHookFailurePolicy: TerminationHookFailurePolicyIgnore,
+
+	EvictionBurst:          10,
+	EvictionRetryBaseDelay: time.Millisecond * 100,
+	EvictionRetryMaxDelay:  time.Second * 10,
+
+	LaunchRetryLimit:     5,
+	LaunchRetryBaseDelay: time.Second * 5,
+
+	RegistrationTTL: time.Minute * 15,
+
+	NodeRepairMaxUnhealthyPercentage: 20,
+
+	GarbageCollectionInterval:   time.Minute * 2,
+	GarbageCollectionMinimumAge: time.Second * 10,
 }
 
+const (
+	TerminationHookFailurePolicyFail   = "Fail"
+	TerminationHookFailurePolicyIgnore = "Ignore"
+)
+
 // +k8s:deepcopy-gen=true
 type Settings struct {
 	BatchMaxDuration  time.Duration
 	BatchIdleDuration time.Duration
 	// This feature flag is temporary and will be removed in the near future.
 	DriftEnabled bool
+	// KubeletVersionSkewLimit is the maximum number of minor versions that a node's kubelet can trail the API server
+	// before the node is considered drifted. Kubelet version drift is disabled when this is 0.
+	KubeletVersionSkewLimit int
+	// DriftRateLimitNodes is the maximum number of nodes across the cluster that can be disrupted due to drift within
+	// DriftRateLimitInterval. Drift isn't rate limited globally when this is 0.
+	DriftRateLimitNodes    int
+	DriftRateLimitInterval time.Duration
+	// DeprovisioningOrder is the order in which deprovisioning methods are attempted. Methods that are omitted are
+	// disabled, except for repair which is enabled by NodeRepairUnhealthyDuration and attempted first when omitted.
+	DeprovisioningOrder []string
+	// ConsolidationMinSavingsPercent and ConsolidationMinSavingsPerHour are the minimum savings, relative to the
+	// price of the nodes being consolidated and in absolute price per hour, that consolidation must achieve. The
+	// percentage has the same type and range as the NodePool's consolidationMinSavingsPercent.
+	ConsolidationMinSavingsPercent int
+	ConsolidationMinSavingsPerHour float64
+	// ConsolidationUtilizationThreshold is the CPU and memory utilization percentage, measured as pod requests relative
+	// to the node's allocatable resources, at or above which a node isn't considered for consolidation. Nodes are
+	// considered regardless of their utilization when this is 0.
+	ConsolidationUtilizationThreshold float64
+	// SpotToSpotConsolidation enables consolidation to replace spot nodes with cheaper spot nodes, as long as at least
+	// SpotToSpotConsolidationMinFlexibility cheaper instance types can be launched.
+	SpotToSpotConsolidation               bool
+	SpotToSpotConsolidationMinFlexibility int
+	// MultiMachineConsolidationMaxMachines is the maximum number of machines that multi-machine consolidation attempts
+	// to consolidate in a single action, and MultiMachineConsolidationTimeout bounds the time it spends simulating a pass.
+	MultiMachineConsolidationMaxMachines int
+	MultiMachineConsolidationTimeout     time.Duration
+	// DeprovisioningMaxParallelActions is the maximum number of deprovisioning actions, each on a disjoint set of
+	// candidates, that can execute concurrently. Actions execute one at a time when this is 1.
+	DeprovisioningMaxParallelActions int
+	// DeprovisioningDryRun reports the deprovisioning actions that would be taken without launching or terminating
+	// anything.
+	DeprovisioningDryRun bool
+	// DeprovisioningExcludedNodeSelector is a label selector (e.g. "team=ml,tier!=batch") of nodes that are never
+	// considered for voluntary disruption.
+	DeprovisioningExcludedNodeSelector string
+	// DisruptionRateLimitNodes is the maximum number of nodes that can be voluntarily disrupted within
+	// DisruptionRateLimitInterval across all deprovisioning methods. Disruption isn't rate limited when this is 0.
+	DisruptionRateLimitNodes    int
+	DisruptionRateLimitInterval time.Duration
+	// DeprovisioningWebhookURL is an HTTP endpoint that's asked to approve each deprovisioning command before it's
+	// executed, and DeprovisioningWebhookTimeout bounds how long the call may take. Commands are vetoed if the webhook
+	// rejects them or can't be reached.
+	DeprovisioningWebhookURL     string
+	DeprovisioningWebhookTimeout time.Duration
+	// DrainDaemonSetPods evicts DaemonSet pods, after all other pods, when draining a node so that daemons can shut
+	// down cleanly. DaemonSet pods are left running until the instance is terminated when this is false.
+	DrainDaemonSetPods bool
+	// PreDrainHookURL and PreTerminationHookURL are HTTP endpoints that are called before a node is drained and before
+	// its instance is terminated. TerminationHookTimeout bounds how long each call may take, and
+	// TerminationHookFailurePolicy determines whether a failed hook blocks termination (Fail) or is skipped (Ignore).
+	// Failed hooks never block termination past the drain timeout of the node's NodePool.
+	PreDrainHookURL              string
+	PreTerminationHookURL        string
+	TerminationHookTimeout       time.Duration
+	TerminationHookFailurePolicy string
+	// OutOfServiceTaintEnabled applies the node.kubernetes.io/out-of-service taint to nodes once they're drained so
+	// that volumes that are still attached to them are force detached.
+	OutOfServiceTaintEnabled bool
+	// EvictionQPS and EvictionBurst rate limit the eviction requests that are made across all draining nodes.
+	// Evictions aren't rate limited when EvictionQPS is 0. Failed evictions are retried with an exponential backoff
+	// from EvictionRetryBaseDelay up to EvictionRetryMaxDelay.
+	EvictionQPS            float64
+	EvictionBurst          int
+	EvictionRetryBaseDelay time.Duration
+	EvictionRetryMaxDelay  time.Duration
+	// InterruptionRebalanceReplacementEnabled launches a replacement for a machine that receives a rebalance
+	// recommendation and only drains the machine once its replacement is initialized. Rebalance recommendations are
+	// ignored when this is false.
+	InterruptionRebalanceReplacementEnabled bool
+	// PreemptionAwareProvisioning simulates kube-scheduler preempting lower priority pods on existing nodes before
+	// launching new capacity for a pending pod, so that no capacity is launched for pods that preemption will schedule.
+	PreemptionAwareProvisioning bool
+	// NominationTTL is how long pods stay nominated to the node or machine that a provisioning pass launched or
+	// selected for them. Nominated nodes aren't deprovisioned and pods nominated to a machine that hasn't launched yet
+	// aren't provisioned for again. Defaults to twice BatchMaxDuration, and at least 10s, when this is 0.
+	NominationTTL time.Duration
+	// LaunchRetryLimit is the number of times that launching a machine is retried, with an exponential backoff from
+	// LaunchRetryBaseDelay up to 5m, when the CloudProvider fails to create it. Machines that fail to launch after all
+	// retries are marked as failed and garbage collected.
+	LaunchRetryLimit     int
+	LaunchRetryBaseDelay time.Duration
+	// RegistrationTTL is how long a launched machine may take to register as a node before it's deleted and replaced.
+	// Provisioners can override it with registrationTTLSeconds.
+	RegistrationTTL time.Duration
+	// InitializationRequiredResources are extended resources (e.g. vendor.com/fpga) that must be registered on a node,
+	// even if they weren't requested, before its machine is initialized. They're only waited on for nodes whose
+	// instance type advertises them in its capacity. InitializationIgnoredResources are requested
+	// resources that initialization doesn't wait on, e.g. because they're registered long after the node is usable.
+	InitializationRequiredResources []string
+	InitializationIgnoredResources  []string
+	// MachineTerminationTimeout is how long a machine may fail to terminate its instance before its termination
+	// finalizer is removed, as long as the CloudProvider confirms that the instance no longer exists. Machines wait for
+	// their instance to terminate indefinitely when this is 0.
+	MachineTerminationTimeout time.Duration
+	// NodeRepairUnhealthyDuration is how long a node may be unhealthy before its machine is replaced. A node is
+	// unhealthy while it isn't Ready, while its network is unavailable or while one of NodeRepairConditions is True
+	// (e.g. KernelDeadlock reported by node-problem-detector). Node repair is disabled when this is 0.
+	NodeRepairUnhealthyDuration time.Duration
+	NodeRepairConditions        []string
+	// NodeRepairMaxUnhealthyPercentage stops node repair for a NodePool while more than this percentage of its nodes,
+	// rounded up, are unhealthy. So many nodes failing at once usually points to a problem that replacing them won't
+	// fix, like a network outage, and replacing them all would only add to the disruption.
+	NodeRepairMaxUnhealthyPercentage int
+	// GarbageCollectionInterval is how often machines whose instance no longer exists or that failed to launch are
+	// garbage collected. GarbageCollectionBatchSize limits how many machines are deleted in each pass and isn't limited
+	// when it's 0. GarbageCollectionMinimumAge is how long a machine must have been launched before it's considered,
+	// which guards against an eventually consistent CloudProvider not yet listing its instance. Machines are garbage
+	// collected ten times less often for CloudProviders that push machine events.
+	GarbageCollectionInterval   time.Duration
+	GarbageCollectionBatchSize  int
+	GarbageCollectionMinimumAge time.Duration
+	// LeakedInstanceGracePeriod is how long a CloudProvider instance may exist without a machine before it's
+	// terminated as leaked. Leaked instances aren't terminated when this is 0.
+	LeakedInstanceGracePeriod time.Duration
+	// MetricsDroppedLabels and MetricsHashedLabels are labels of the pod, node and provisioner metrics (e.g. name, node
+	// or node_name) whose values are dropped or replaced with a short hash to bound the cardinality and size of their
+	// series on large clusters. Series that only differ by a dropped label are summed, so labels aren't dropped from
+	// percentage metrics. MetricsDisabled are the metric families (e.g. karpenter_pods_state) that these controllers
+	// don't emit at all.
+	MetricsDroppedLabels []string
+	MetricsHashedLabels  []string
+	MetricsDisabled      []string
 }
 
 func (*Settings) ConfigMap() string {
@@ -54,6 +221,53 @@ func (*Settings) Inject(ctx context.Context, cm *v1.ConfigMap) (context.Context,
 		configmap.AsDuration("batchMaxDuration", &s.BatchMaxDuration),
 		configmap.AsDuration("batchIdleDuration", &s.BatchIdleDuration),
 		configmap.AsBool("featureGates.driftEnabled", &s.DriftEnabled),
+		configmap.AsInt("kubeletVersionSkewLimit", &s.KubeletVersionSkewLimit),
+		configmap.AsInt("driftRateLimitNodes", &s.DriftRateLimitNodes),
+		configmap.AsDuration("driftRateLimitInterval", &s.DriftRateLimitInterval),
+		asStringSlice("deprovisioningOrder", &s.DeprovisioningOrder),
+		configmap.AsInt("consolidationMinSavingsPercent", &s.ConsolidationMinSavingsPercent),
+		configmap.AsFloat64("consolidationMinSavingsPerHour", &s.ConsolidationMinSavingsPerHour),
+		configmap.AsFloat64("consolidationUtilizationThreshold", &s.ConsolidationUtilizationThreshold),
+		configmap.AsBool("featureGates.spotToSpotConsolidation", &s.SpotToSpotConsolidation),
+		configmap.AsInt("spotToSpotConsolidationMinFlexibility", &s.SpotToSpotConsolidationMinFlexibility),
+		configmap.AsInt("multiMachineConsolidationMaxMachines", &s.MultiMachineConsolidationMaxMachines),
+		configmap.AsDuration("multiMachineConsolidationTimeout", &s.MultiMachineConsolidationTimeout),
+		configmap.AsInt("deprovisioningMaxParallelActions", &s.DeprovisioningMaxParallelActions),
+		configmap.AsBool("deprovisioningDryRun", &s.DeprovisioningDryRun),
+		configmap.AsString("deprovisioningExcludedNodeSelector", &s.DeprovisioningExcludedNodeSelector),
+		configmap.AsInt("disruptionRateLimitNodes", &s.DisruptionRateLimitNodes),
+		configmap.AsDuration("disruptionRateLimitInterval", &s.DisruptionRateLimitInterval),
+		configmap.AsString("deprovisioningWebhookURL", &s.DeprovisioningWebhookURL),
+		configmap.AsDuration("deprovisioningWebhookTimeout", &s.DeprovisioningWebhookTimeout),
+		configmap.AsBool("drainDaemonSetPods", &s.DrainDaemonSetPods),
+		configmap.AsString("preDrainHookURL", &s.PreDrainHookURL),
+		configmap.AsString("preTerminationHookURL", &s.PreTerminationHookURL),
+		configmap.AsDuration("terminationHookTimeout", &s.TerminationHookTimeout),
+		configmap.AsString("terminationHookFailurePolicy", &s.TerminationHookFailurePolicy),
+		configmap.AsBool("outOfServiceTaintEnabled", &s.OutOfServiceTaintEnabled),
+		configmap.AsFloat64("evictionQPS", &s.EvictionQPS),
+		configmap.AsInt("evictionBurst", &s.EvictionBurst),
+		configmap.AsDuration("evictionRetryBaseDelay", &s.EvictionRetryBaseDelay),
+		configmap.AsDuration("evictionRetryMaxDelay", &s.EvictionRetryMaxDelay),
+		configmap.AsBool("interruptionRebalanceReplacementEnabled", &s.InterruptionRebalanceReplacementEnabled),
+		configmap.AsBool("preemptionAwareProvisioning", &s.PreemptionAwareProvisioning),
+		configmap.AsDuration("nominationTTL", &s.NominationTTL),
+		configmap.AsInt("launchRetryLimit", &s.LaunchRetryLimit),
+		configmap.AsDuration("launchRetryBaseDelay", &s.LaunchRetryBaseDelay),
+		configmap.AsDuration("registrationTTL", &s.RegistrationTTL),
+		asStringSlice("initializationRequiredResources", &s.InitializationRequiredResources),
+		asStringSlice("initializationIgnoredResources", &s.InitializationIgnoredResources),
+		configmap.AsDuration("machineTerminationTimeout", &s.MachineTerminationTimeout),
+		configmap.AsDuration("nodeRepairUnhealthyDuration", &s.NodeRepairUnhealthyDuration),
+		asStringSlice("nodeRepairConditions", &s.NodeRepairConditions),
+		configmap.AsInt("nodeRepairMaxUnhealthyPercentage", &s.NodeRepairMaxUnhealthyPercentage),
+		configmap.AsDuration("garbageCollectionInterval", &s.GarbageCollectionInterval),
+		configmap.AsInt("garbageCollectionBatchSize", &s.GarbageCollectionBatchSize),
+		configmap.AsDuration("garbageCollectionMinimumAge", &s.GarbageCollectionMinimumAge),
+		configmap.AsDuration("leakedInstanceGracePeriod", &s.LeakedInstanceGracePeriod),
+		asStringSlice("metricsDroppedLabels", &s.MetricsDroppedLabels),
+		asStringSlice("metricsHashedLabels", &s.MetricsHashedLabels),
+		asStringSlice("metricsDisabled", &s.MetricsDisabled),
 	); err != nil {
 		return ctx, fmt.Errorf("parsing settings, %w", err)
 	}
@@ -70,9 +284,144 @@ func (in *Settings) Validate() (err error) {
 	if in.BatchIdleDuration < time.Second {
 		err = multierr.Append(err, fmt.Errorf("batchIdleDuration cannot be less then 1s"))
 	}
+	if in.KubeletVersionSkewLimit < 0 {
+		err = multierr.Append(err, fmt.Errorf("kubeletVersionSkewLimit cannot be negative"))
+	}
+	if in.DriftRateLimitNodes < 0 {
+		err = multierr.Append(err, fmt.Errorf("driftRateLimitNodes cannot be negative"))
+	}
+	if in.DriftRateLimitNodes > 0 && in.DriftRateLimitInterval <= 0 {
+		err = multierr.Append(err, fmt.Errorf("driftRateLimitInterval must be positive when driftRateLimitNodes is set"))
+	}
+	if in.DisruptionRateLimitNodes < 0 {
+		err = multierr.Append(err, fmt.Errorf("disruptionRateLimitNodes cannot be negative"))
+	}
+	if in.DisruptionRateLimitNodes > 0 && in.DisruptionRateLimitInterval <= 0 {
+		err = multierr.Append(err, fmt.Errorf("disruptionRateLimitInterval must be positive when disruptionRateLimitNodes is set"))
+	}
+	if in.ConsolidationMinSavingsPercent < 0 || in.ConsolidationMinSavingsPercent > 99 {
+		err = multierr.Append(err, fmt.Errorf("consolidationMinSavingsPercent must be between 0 and 99"))
+	}
+	if in.ConsolidationMinSavingsPerHour < 0 {
+		err = multierr.Append(err, fmt.Errorf("consolidationMinSavingsPerHour cannot be negative"))
+	}
+	if in.ConsolidationUtilizationThreshold < 0 || in.ConsolidationUtilizationThreshold > 100 {
+		err = multierr.Append(err, fmt.Errorf("consolidationUtilizationThreshold must be between 0 and 100"))
+	}
+	if in.SpotToSpotConsolidationMinFlexibility < 1 {
+		err = multierr.Append(err, fmt.Errorf("spotToSpotConsolidationMinFlexibility must be at least 1"))
+	}
+	if in.MultiMachineConsolidationMaxMachines < 2 {
+		err = multierr.Append(err, fmt.Errorf("multiMachineConsolidationMaxMachines must be at least 2"))
+	}
+	if in.MultiMachineConsolidationTimeout <= 0 {
+		err = multierr.Append(err, fmt.Errorf("multiMachineConsolidationTimeout must be positive"))
+	}
+	if in.DeprovisioningMaxParallelActions < 1 {
+		err = multierr.Append(err, fmt.Errorf("deprovisioningMaxParallelActions must be at least 1"))
+	}
+	if _, e := labels.Parse(in.DeprovisioningExcludedNodeSelector); e != nil {
+		err = multierr.Append(err, fmt.Errorf("deprovisioningExcludedNodeSelector is not a valid label selector, %w", e))
+	}
+	if in.DeprovisioningWebhookURL != "" {
+		if u, e := url.Parse(in.DeprovisioningWebhookURL); e != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = multierr.Append(err, fmt.Errorf("deprovisioningWebhookURL must be an absolute http or https URL"))
+		}
+		if in.DeprovisioningWebhookTimeout <= 0 {
+			err = multierr.Append(err, fmt.Errorf("deprovisioningWebhookTimeout must be positive when deprovisioningWebhookURL is set"))
+		}
+	}
+	for key, hookURL := range map[string]string{"preDrainHookURL": in.PreDrainHookURL, "preTerminationHookURL": in.PreTerminationHookURL} {
+		if hookURL == "" {
+			continue
+		}
+		if u, e := url.Parse(hookURL); e != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = multierr.Append(err, fmt.Errorf("%s must be an absolute http or https URL", key))
+		}
+	}
+	if in.TerminationHookTimeout <= 0 {
+		err = multierr.Append(err, fmt.Errorf("terminationHookTimeout must be positive"))
+	}
+	if in.TerminationHookFailurePolicy != TerminationHookFailurePolicyFail && in.TerminationHookFailurePolicy != TerminationHookFailurePolicyIgnore {
+		err = multierr.Append(err, fmt.Errorf("terminationHookFailurePolicy must be one of %s or %s", TerminationHookFailurePolicyFail, TerminationHookFailurePolicyIgnore))
+	}
+	if in.EvictionQPS < 0 {
+		err = multierr.Append(err, fmt.Errorf("evictionQPS cannot be negative"))
+	}
+	if in.EvictionQPS > 0 && in.EvictionBurst < 1 {
+		err = multierr.Append(err, fmt.Errorf("evictionBurst must be at least 1 when evictionQPS is set"))
+	}
+	if in.EvictionRetryBaseDelay <= 0 {
+		err = multierr.Append(err, fmt.Errorf("evictionRetryBaseDelay must be positive"))
+	}
+	if in.EvictionRetryMaxDelay < in.EvictionRetryBaseDelay {
+		err = multierr.Append(err, fmt.Errorf("evictionRetryMaxDelay cannot be less than evictionRetryBaseDelay"))
+	}
+	if in.NominationTTL < 0 {
+		err = multierr.Append(err, fmt.Errorf("nominationTTL cannot be negative"))
+	}
+	if in.LaunchRetryLimit < 0 {
+		err = multierr.Append(err, fmt.Errorf("launchRetryLimit cannot be negative"))
+	}
+	if in.LaunchRetryBaseDelay <= 0 {
+		err = multierr.Append(err, fmt.Errorf("launchRetryBaseDelay must be positive"))
+	}
+	if in.RegistrationTTL <= 0 {
+		err = multierr.Append(err, fmt.Errorf("registrationTTL must be positive"))
+	}
+	if in.MachineTerminationTimeout < 0 {
+		err = multierr.Append(err, fmt.Errorf("machineTerminationTimeout cannot be negative"))
+	}
+	if in.NodeRepairUnhealthyDuration < 0 {
+		err = multierr.Append(err, fmt.Errorf("nodeRepairUnhealthyDuration cannot be negative"))
+	}
+	if in.NodeRepairMaxUnhealthyPercentage < 1 || in.NodeRepairMaxUnhealthyPercentage > 100 {
+		err = multierr.Append(err, fmt.Errorf("nodeRepairMaxUnhealthyPercentage must be between 1 and 100"))
+	}
+	if in.GarbageCollectionInterval <= 0 {
+		err = multierr.Append(err, fmt.Errorf("garbageCollectionInterval must be positive"))
+	}
+	if in.GarbageCollectionBatchSize < 0 {
+		err = multierr.Append(err, fmt.Errorf("garbageCollectionBatchSize cannot be negative"))
+	}
+	if in.GarbageCollectionMinimumAge < 0 {
+		err = multierr.Append(err, fmt.Errorf("garbageCollectionMinimumAge cannot be negative"))
+	}
+	if in.LeakedInstanceGracePeriod < 0 {
+		err = multierr.Append(err, fmt.Errorf("leakedInstanceGracePeriod cannot be negative"))
+	}
+	for _, name := range lo.Flatten([][]string{in.InitializationRequiredResources, in.InitializationIgnoredResources}) {
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+			err = multierr.Append(err, fmt.Errorf("initialization resource %q is not a valid resource name, %s", name, strings.Join(errs, ", ")))
+		}
+	}
+	for _, name := range lo.Intersect(in.InitializationRequiredResources, in.InitializationIgnoredResources) {
+		err = multierr.Append(err, fmt.Errorf("initialization resource %q cannot be both required and ignored", name))
+	}
+	for _, name := range lo.Intersect(in.MetricsDroppedLabels, in.MetricsHashedLabels) {
+		err = multierr.Append(err, fmt.Errorf("metric label %q cannot be both dropped and hashed", name))
+	}
+	for i, method := range in.DeprovisioningOrder {
+		if !lo.Contains(DeprovisioningMethods, method) {
+			err = multierr.Append(err, fmt.Errorf("deprovisioningOrder contains unknown method %q, expected one of %v", method, DeprovisioningMethods))
+		}
+		if lo.Contains(in.DeprovisioningOrder[:i], method) {
+			err = multierr.Append(err, fmt.Errorf("deprovisioningOrder contains duplicate method %q", method))
+		}
+	}
 	return err
 }
 
+// asStringSlice parses a comma separated list, preserving the order of its elements
+func asStringSlice(key string, target *[]string) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			*target = lo.Compact(lo.Map(strings.Split(raw, ","), func(s string, _ int) string { return strings.TrimSpace(s) }))
+		}
+		return nil
+	}
+}
+
 func ToContext(ctx context.Context, s *Settings) context.Context {
 	return context.WithValue(ctx, ContextKey, s)
 }
@@ -46,6 +46,14 @@ var _ = Describe("Validation", func() {
 		Expect(s.BatchMaxDuration).To(Equal(time.Second * 10))
 		Expect(s.BatchIdleDuration).To(Equal(time.Second))
 		Expect(s.DriftEnabled).To(BeFalse())
+		Expect(s.MultiMachineConsolidationMaxMachines).To(Equal(100))
+		Expect(s.MultiMachineConsolidationTimeout).To(Equal(time.Minute))
+		Expect(s.DisruptionRateLimitNodes).To(Equal(0))
+		Expect(s.DisruptionRateLimitInterval).To(Equal(time.Hour))
+		Expect(s.GarbageCollectionInterval).To(Equal(time.Minute * 2))
+		Expect(s.GarbageCollectionBatchSize).To(Equal(0))
+		Expect(s.GarbageCollectionMinimumAge).To(Equal(time.Second * 10))
+		Expect(s.NodeRepairMaxUnhealthyPercentage).To(Equal(20))
 	})
 	It("should succeed to set custom values", func() {
 		cm := &v1.ConfigMap{
@@ -53,6 +61,9 @@ var _ = Describe("Validation", func() {
 				"batchMaxDuration":          "30s",
 				"batchIdleDuration":         "5s",
 				"featureGates.driftEnabled": "true",
+				"kubeletVersionSkewLimit":   "2",
+				"driftRateLimitNodes":       "5",
+				"driftRateLimitInterval":    "30m",
 			},
 		}
 		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
@@ -61,6 +72,9 @@ var _ = Describe("Validation", func() {
 		Expect(s.BatchMaxDuration).To(Equal(time.Second * 30))
 		Expect(s.BatchIdleDuration).To(Equal(time.Second * 5))
 		Expect(s.DriftEnabled).To(BeTrue())
+		Expect(s.KubeletVersionSkewLimit).To(Equal(2))
+		Expect(s.DriftRateLimitNodes).To(Equal(5))
+		Expect(s.DriftRateLimitInterval).To(Equal(30 * time.Minute))
 	})
 	It("should fail validation when batchMaxDuration is negative", func() {
 		cm := &v1.ConfigMap{
@@ -116,6 +130,498 @@ var _ = Describe("Validation", func() {
 		_, err := (&settings.Settings{}).Inject(ctx, cm)
 		Expect(err).To(HaveOccurred())
 	})
+	It("should fail validation when kubeletVersionSkewLimit is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"kubeletVersionSkewLimit": "-1",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when driftRateLimitNodes is set without a positive interval", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"driftRateLimitNodes":    "5",
+				"driftRateLimitInterval": "0s",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when disruptionRateLimitNodes is set without a positive interval", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"disruptionRateLimitNodes":    "5",
+				"disruptionRateLimitInterval": "0s",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse the deprovisioning webhook", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningWebhookURL":     "https://change-management.example.com/approve",
+				"deprovisioningWebhookTimeout": "5s",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).DeprovisioningWebhookURL).To(Equal("https://change-management.example.com/approve"))
+		Expect(settings.FromContext(ctx).DeprovisioningWebhookTimeout).To(Equal(5 * time.Second))
+	})
+	It("should fail validation when deprovisioningWebhookURL is not an absolute http URL", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningWebhookURL": "change-management/approve",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when drainDaemonSetPods is not a valid boolean value", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"drainDaemonSetPods": "foobar",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse the termination hook settings", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"preDrainHookURL":              "https://hooks.example.com/drain",
+				"preTerminationHookURL":        "https://hooks.example.com/terminate",
+				"terminationHookTimeout":       "1m",
+				"terminationHookFailurePolicy": "Fail",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).PreDrainHookURL).To(Equal("https://hooks.example.com/drain"))
+		Expect(settings.FromContext(ctx).PreTerminationHookURL).To(Equal("https://hooks.example.com/terminate"))
+		Expect(settings.FromContext(ctx).TerminationHookTimeout).To(Equal(time.Minute))
+		Expect(settings.FromContext(ctx).TerminationHookFailurePolicy).To(Equal(settings.TerminationHookFailurePolicyFail))
+	})
+	It("should fail validation when a termination hook URL isn't absolute", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"preDrainHookURL": "/drain",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when terminationHookFailurePolicy is unknown", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"terminationHookFailurePolicy": "Retry",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse the eviction rate limits", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"evictionQPS":            "20",
+				"evictionBurst":          "40",
+				"evictionRetryBaseDelay": "1s",
+				"evictionRetryMaxDelay":  "1m",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		s := settings.FromContext(ctx)
+		Expect(s.EvictionQPS).To(Equal(20.0))
+		Expect(s.EvictionBurst).To(Equal(40))
+		Expect(s.EvictionRetryBaseDelay).To(Equal(time.Second))
+		Expect(s.EvictionRetryMaxDelay).To(Equal(time.Minute))
+	})
+	It("should fail validation when evictionRetryMaxDelay is less than evictionRetryBaseDelay", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"evictionRetryBaseDelay": "1m",
+				"evictionRetryMaxDelay":  "1s",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse interruptionRebalanceReplacementEnabled", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"interruptionRebalanceReplacementEnabled": "true",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).InterruptionRebalanceReplacementEnabled).To(BeTrue())
+	})
+	It("should parse preemptionAwareProvisioning", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"preemptionAwareProvisioning": "true",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).PreemptionAwareProvisioning).To(BeTrue())
+	})
+	It("should parse nominationTTL", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"nominationTTL": "1m",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).NominationTTL).To(Equal(time.Minute))
+	})
+	It("should fail validation when nominationTTL is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"nominationTTL": "-1s",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse launch retry settings", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"launchRetryLimit":     "3",
+				"launchRetryBaseDelay": "10s",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).LaunchRetryLimit).To(Equal(3))
+		Expect(settings.FromContext(ctx).LaunchRetryBaseDelay).To(Equal(10 * time.Second))
+	})
+	It("should fail validation when launchRetryLimit is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"launchRetryLimit": "-1",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse registrationTTL", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"registrationTTL": "30m",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).RegistrationTTL).To(Equal(30 * time.Minute))
+	})
+	It("should parse initialization resources", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"initializationRequiredResources": "vendor.com/fpga",
+				"initializationIgnoredResources":  "vendor.com/gpu, vendor.com/nic",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).InitializationRequiredResources).To(Equal([]string{"vendor.com/fpga"}))
+		Expect(settings.FromContext(ctx).InitializationIgnoredResources).To(Equal([]string{"vendor.com/gpu", "vendor.com/nic"}))
+	})
+	It("should fail validation when an initialization resource is both required and ignored", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"initializationRequiredResources": "vendor.com/fpga",
+				"initializationIgnoredResources":  "vendor.com/fpga",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when an initialization resource is invalid", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"initializationRequiredResources": "vendor.com/not a resource",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse machineTerminationTimeout", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"machineTerminationTimeout": "1h",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).MachineTerminationTimeout).To(Equal(time.Hour))
+	})
+	It("should fail validation when machineTerminationTimeout is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"machineTerminationTimeout": "-1h",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse node repair settings", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"nodeRepairUnhealthyDuration":      "15m",
+				"nodeRepairConditions":             "KernelDeadlock, ReadonlyFilesystem",
+				"nodeRepairMaxUnhealthyPercentage": "50",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).NodeRepairUnhealthyDuration).To(Equal(15 * time.Minute))
+		Expect(settings.FromContext(ctx).NodeRepairConditions).To(Equal([]string{"KernelDeadlock", "ReadonlyFilesystem"}))
+		Expect(settings.FromContext(ctx).NodeRepairMaxUnhealthyPercentage).To(Equal(50))
+	})
+	It("should parse garbage collection settings", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"garbageCollectionInterval":   "5m",
+				"garbageCollectionBatchSize":  "50",
+				"garbageCollectionMinimumAge": "1m",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).GarbageCollectionInterval).To(Equal(5 * time.Minute))
+		Expect(settings.FromContext(ctx).GarbageCollectionBatchSize).To(Equal(50))
+		Expect(settings.FromContext(ctx).GarbageCollectionMinimumAge).To(Equal(time.Minute))
+	})
+	It("should parse metric cardinality settings", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"metricsDroppedLabels": "name, node",
+				"metricsHashedLabels":  "node_name",
+				"metricsDisabled":      "karpenter_pods_state",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).MetricsDroppedLabels).To(Equal([]string{"name", "node"}))
+		Expect(settings.FromContext(ctx).MetricsHashedLabels).To(Equal([]string{"node_name"}))
+		Expect(settings.FromContext(ctx).MetricsDisabled).To(Equal([]string{"karpenter_pods_state"}))
+	})
+	It("should fail validation when a metric label is both dropped and hashed", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"metricsDroppedLabels": "name",
+				"metricsHashedLabels":  "name",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when garbageCollectionInterval isn't positive", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"garbageCollectionInterval": "0s",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse leakedInstanceGracePeriod", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"leakedInstanceGracePeriod": "1h",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).LeakedInstanceGracePeriod).To(Equal(time.Hour))
+	})
+	It("should fail validation when leakedInstanceGracePeriod is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"leakedInstanceGracePeriod": "-1h",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when garbageCollectionBatchSize is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"garbageCollectionBatchSize": "-1",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when nodeRepairUnhealthyDuration is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"nodeRepairUnhealthyDuration": "-15m",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when nodeRepairMaxUnhealthyPercentage is out of range", func() {
+		for _, percentage := range []string{"0", "101"} {
+			cm := &v1.ConfigMap{
+				Data: map[string]string{
+					"nodeRepairMaxUnhealthyPercentage": percentage,
+				},
+			}
+			_, err := (&settings.Settings{}).Inject(ctx, cm)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+	It("should parse a custom deprovisioningOrder", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningOrder": "drift, expiration,consolidation",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).DeprovisioningOrder).To(Equal([]string{"drift", "expiration", "consolidation"}))
+	})
+	It("should fail validation when deprovisioningOrder contains an unknown method", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningOrder": "drift,replacement",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when deprovisioningOrder contains a duplicate method", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningOrder": "drift,expiration,drift",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse consolidation savings thresholds", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationMinSavingsPercent": "12",
+				"consolidationMinSavingsPerHour": "0.05",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).ConsolidationMinSavingsPercent).To(Equal(12))
+		Expect(settings.FromContext(ctx).ConsolidationMinSavingsPerHour).To(Equal(0.05))
+	})
+	It("should fail validation when consolidationMinSavingsPercent is out of range", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationMinSavingsPercent": "100",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail to parse a fractional consolidationMinSavingsPercent", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationMinSavingsPercent": "12.5",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when consolidationMinSavingsPerHour is negative", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationMinSavingsPerHour": "-0.01",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse the consolidation utilization threshold", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationUtilizationThreshold": "50",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).ConsolidationUtilizationThreshold).To(Equal(50.0))
+	})
+	It("should fail validation when consolidationUtilizationThreshold is out of range", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"consolidationUtilizationThreshold": "101",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should parse multi-machine consolidation limits", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"multiMachineConsolidationMaxMachines": "20",
+				"multiMachineConsolidationTimeout":     "30s",
+			},
+		}
+		ctx, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.FromContext(ctx).MultiMachineConsolidationMaxMachines).To(Equal(20))
+		Expect(settings.FromContext(ctx).MultiMachineConsolidationTimeout).To(Equal(30 * time.Second))
+	})
+	It("should fail validation when multiMachineConsolidationMaxMachines is less than 2", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"multiMachineConsolidationMaxMachines": "1",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when multiMachineConsolidationTimeout is not positive", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"multiMachineConsolidationTimeout": "0s",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when deprovisioningMaxParallelActions is less than 1", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningMaxParallelActions": "0",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when deprovisioningDryRun is not a valid boolean value", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningDryRun": "foobar",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail validation when deprovisioningExcludedNodeSelector is not a valid label selector", func() {
+		cm := &v1.ConfigMap{
+			Data: map[string]string{
+				"deprovisioningExcludedNodeSelector": "team in (ml",
+			},
+		}
+		_, err := (&settings.Settings{}).Inject(ctx, cm)
+		Expect(err).To(HaveOccurred())
+	})
 	It("should fail validation when driftEnabled is not a valid boolean value", func() {
 		cm := &v1.ConfigMap{
 			Data: map[string]string{
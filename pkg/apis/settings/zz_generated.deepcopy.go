@@ -23,6 +23,41 @@ import ()
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Settings) DeepCopyInto(out *Settings) {
 	*out = *in
+	if in.DeprovisioningOrder != nil {
+		in, out := &in.DeprovisioningOrder, &out.DeprovisioningOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitializationRequiredResources != nil {
+		in, out := &in.InitializationRequiredResources, &out.InitializationRequiredResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitializationIgnoredResources != nil {
+		in, out := &in.InitializationIgnoredResources, &out.InitializationIgnoredResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeRepairConditions != nil {
+		in, out := &in.NodeRepairConditions, &out.NodeRepairConditions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MetricsDroppedLabels != nil {
+		in, out := &in.MetricsDroppedLabels, &out.MetricsDroppedLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MetricsHashedLabels != nil {
+		in, out := &in.MetricsHashedLabels, &out.MetricsHashedLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MetricsDisabled != nil {
+		in, out := &in.MetricsDisabled, &out.MetricsDisabled
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Settings.
@@ -28,6 +28,7 @@ const (
 	ArchitectureArm64    = "arm64"
 	CapacityTypeSpot     = "spot"
 	CapacityTypeOnDemand = "on-demand"
+	CapacityTypeReserved = "reserved"
 )
 
 // Karpenter specific domains and labels
@@ -36,20 +37,49 @@ const (
 	LabelNodeInitialized    = Group + "/initialized"
 	LabelNodeRegistered     = Group + "/registered"
 	LabelCapacityType       = Group + "/capacity-type"
+	// LabelCapacitySpread is populated by the scheduler in a round-robin fashion from the values allowed by the
+	// provisioner so that pods can spread across arbitrary buckets of capacity with topologySpreadConstraints
+	LabelCapacitySpread = Group + "/capacity-spread"
 )
 
 // Karpenter specific annotations
 const (
 	DoNotEvictPodAnnotationKey        = Group + "/do-not-evict"
 	DoNotConsolidateNodeAnnotationKey = Group + "/do-not-consolidate"
+	DisruptionPausedAnnotationKey     = Group + "/disruption-paused"
 	EmptinessTimestampAnnotationKey   = Group + "/emptiness-timestamp"
 	MachineLinkedAnnotationKey        = Group + "/linked"
 	MachineManagedByAnnotationKey     = Group + "/managed-by"
 	ProvisionerHashAnnotationKey      = Group + "/provisioner-hash"
+	TTLUntilExpiredAnnotationKey      = Group + "/ttl-until-expired"
+	TTLAfterEmptyAnnotationKey        = Group + "/ttl-after-empty"
+	// PreDrainHookCompletedAnnotationKey records on a node that its pre-drain hooks have been invoked
+	PreDrainHookCompletedAnnotationKey = Group + "/pre-drain-hook-completed"
+	// InterruptionReplacementAnnotationKey is the name of the replacement that was launched for a machine that
+	// received a rebalance recommendation, the machine is drained once its replacement is initialized
+	InterruptionReplacementAnnotationKey = Group + "/interruption-replacement"
+	// EvictionBlockedSinceAnnotationKey checkpoints when the eviction of a pod was first blocked so that the time isn't
+	// reset when Karpenter restarts mid-drain
+	EvictionBlockedSinceAnnotationKey = Group + "/eviction-blocked-since"
+	// LaunchPriceAnnotationKey is the hourly price of the offering that a machine was launched with
+	LaunchPriceAnnotationKey = Group + "/launch-price"
+	// MachinePausedAnnotationKey stops the lifecycle, disruption and garbage collection controllers from reconciling a
+	// machine so that its node can be investigated without Karpenter mutating or deleting it
+	MachinePausedAnnotationKey = Group + "/machine-paused"
+	// InitializationTimedOutAnnotationKey is set on machines whose nodes registered but didn't initialize within the
+	// registration TTL, so that they're only counted as failed once
+	InitializationTimedOutAnnotationKey = Group + "/initialization-timed-out"
 
 	ProviderCompatabilityAnnotationKey = CompatabilityGroup + "/provider"
 )
 
+// Karpenter specific taints
+const (
+	// TerminatingNoExecuteTaintKey is applied to a node that exceeds its drain timeout when its Provisioner opts into
+	// the NoExecuteFallback, falling back to the taint manager to remove its pods
+	TerminatingNoExecuteTaintKey = Group + "/terminating"
+)
+
 // Karpenter specific finalizers
 const (
 	TerminationFinalizer = Group + "/termination"
@@ -83,6 +113,7 @@ var (
 		v1.LabelArchStable,
 		v1.LabelOSStable,
 		LabelCapacityType,
+		LabelCapacitySpread,
 	)
 
 	// RestrictedLabels are labels that should not be used
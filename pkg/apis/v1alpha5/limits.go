@@ -20,16 +20,49 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
+// ResourceNodes is the resource name used to count the nodes launched by a provisioner in its status resources
+const ResourceNodes v1.ResourceName = "nodes"
+
 // Limits define bounds on the resources being provisioned by Karpenter
 type Limits struct {
 	// Resources contains all the allocatable resources that Karpenter supports for limiting.
 	Resources v1.ResourceList `json:"resources,omitempty"`
+	// MaxNodes is the maximum number of nodes that can be launched by the provisioner.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
+	// Scoped contains limits that only apply to the nodes launched into a zone and/or capacity type.
+	// +optional
+	Scoped []ScopedLimit `json:"scoped,omitempty"`
+}
+
+// ScopedLimit bounds the resources provisioned into a zone and/or capacity type
+type ScopedLimit struct {
+	// Zone restricts the limit to nodes launched into the topology zone. If unset, nodes in every zone are counted.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// CapacityType restricts the limit to nodes launched with the capacity type. If unset, nodes of every
+	// capacity type are counted.
+	// +optional
+	CapacityType string `json:"capacityType,omitempty"`
+	// Resources contains all the allocatable resources that Karpenter supports for limiting.
+	// +optional
+	Resources v1.ResourceList `json:"resources,omitempty"`
+	// MaxNodes is the maximum number of nodes that can be launched into the scope.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
 }
 
 func (l *Limits) ExceededBy(resources v1.ResourceList) error {
-	if l == nil || l.Resources == nil {
+	if l == nil {
 		return nil
 	}
+	if l.MaxNodes != nil {
+		if usage, ok := resources[ResourceNodes]; ok && usage.Value() > int64(*l.MaxNodes) {
+			return fmt.Errorf("node count of %d exceeds limit of %d", usage.Value(), *l.MaxNodes)
+		}
+	}
 	for resourceName, usage := range resources {
 		if limit, ok := l.Resources[resourceName]; ok {
 			if usage.Cmp(limit) > 0 {
@@ -32,6 +32,9 @@ type MachineSpec struct {
 	StartupTaints []v1.Taint `json:"startupTaints,omitempty"`
 	// Requirements are layered with Labels and applied to every node.
 	Requirements []v1.NodeSelectorRequirement `json:"requirements,omitempty"`
+	// Preferences are weighted terms used to order the instance types that satisfy the Machine's requirements
+	// +optional
+	Preferences []v1.PreferredSchedulingTerm `json:"preferences,omitempty"`
 	// Resources models the resource requirements for the Machine to launch
 	Resources ResourceRequirements `json:"resources,omitempty"`
 	// Kubelet are options passed to the kubelet when provisioning nodes
@@ -39,6 +42,10 @@ type MachineSpec struct {
 	Kubelet *KubeletConfiguration `json:"kubelet,omitempty"`
 	// MachineTemplateRef is a reference to an object that defines provider specific configuration
 	MachineTemplateRef *MachineTemplateRef `json:"machineTemplateRef,omitempty"`
+	// CloudProvider is the name of the registered cloudprovider that launches the machine. If unset, the machine
+	// is launched by the default cloudprovider.
+	// +optional
+	CloudProvider string `json:"cloudProvider,omitempty"`
 }
 
 // KubeletConfiguration defines args to be used when configuring kubelet on provisioned nodes.
@@ -16,6 +16,7 @@ package v1alpha5
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
 )
 
@@ -36,6 +37,22 @@ type MachineStatus struct {
 	// Conditions contains signals for health and readiness
 	// +optional
 	Conditions apis.Conditions `json:"conditions,omitempty"`
+	// ExpirationTime is the time at which the machine will be considered expired, including any
+	// expiration jitter configured on the owning provisioner
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// InstanceType is the instance type of the offering that the machine was launched with
+	// +optional
+	InstanceType string `json:"instanceType,omitempty"`
+	// Zone is the zone of the offering that the machine was launched with
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// CapacityType is the capacity type (e.g. on-demand or spot) of the offering that the machine was launched with
+	// +optional
+	CapacityType string `json:"capacityType,omitempty"`
+	// Price is the hourly price of the offering that the machine was launched with, at the time that it was launched
+	// +optional
+	Price string `json:"price,omitempty"`
 }
 
 func (in *Machine) StatusConditions() apis.ConditionManager {
@@ -49,12 +66,14 @@ var LivingConditions = []apis.ConditionType{
 }
 
 var (
-	MachineLaunched    apis.ConditionType = "MachineLaunched"
-	MachineRegistered  apis.ConditionType = "MachineRegistered"
-	MachineInitialized apis.ConditionType = "MachineInitialized"
-	MachineDrifted     apis.ConditionType = "MachineDrifted"
-	MachineEmpty       apis.ConditionType = "MachineEmpty"
-	MachineExpired     apis.ConditionType = "MachineExpired"
+	MachineLaunched     apis.ConditionType = "MachineLaunched"
+	MachineRegistered   apis.ConditionType = "MachineRegistered"
+	MachineInitialized  apis.ConditionType = "MachineInitialized"
+	MachineDrifted      apis.ConditionType = "MachineDrifted"
+	MachineEmpty        apis.ConditionType = "MachineEmpty"
+	MachineExpired      apis.ConditionType = "MachineExpired"
+	MachineLaunchFailed apis.ConditionType = "MachineLaunchFailed"
+	MachineUnhealthy    apis.ConditionType = "MachineUnhealthy"
 )
 
 func (in *Machine) GetConditions() apis.Conditions {
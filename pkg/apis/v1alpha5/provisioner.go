@@ -22,8 +22,10 @@ import (
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/ptr"
 )
 
@@ -52,6 +54,10 @@ type ProvisionerSpec struct {
 	StartupTaints []v1.Taint `json:"startupTaints,omitempty"`
 	// Requirements are layered with Labels and applied to every node.
 	Requirements []v1.NodeSelectorRequirement `json:"requirements,omitempty" hash:"ignore"`
+	// Preferences are weighted terms used to order the instance types that satisfy a machine's requirements.
+	// Instance types that match a higher summed weight of terms are preferred before falling back to price.
+	// +optional
+	Preferences []v1.PreferredSchedulingTerm `json:"preferences,omitempty" hash:"ignore"`
 	// KubeletConfiguration are options passed to the kubelet when provisioning nodes
 	//+optional
 	KubeletConfiguration *KubeletConfiguration `json:"kubeletConfiguration,omitempty"`
@@ -62,6 +68,10 @@ type ProvisionerSpec struct {
 	// additional configuration options
 	// +optional
 	ProviderRef *MachineTemplateRef `json:"providerRef,omitempty" hash:"ignore"`
+	// CloudProvider is the name of the registered cloudprovider that launches nodes for this provisioner. If unset,
+	// nodes are launched by the default cloudprovider.
+	// +optional
+	CloudProvider string `json:"cloudProvider,omitempty"`
 	// TTLSecondsAfterEmpty is the number of seconds the controller will wait
 	// before attempting to delete a node, measured from when the node is
 	// detected to be empty. A Node is considered to be empty when it does not
@@ -78,6 +88,51 @@ type ProvisionerSpec struct {
 	// Termination due to expiration is disabled if this field is not set.
 	// +optional
 	TTLSecondsUntilExpired *int64 `json:"ttlSecondsUntilExpired,omitempty" hash:"ignore"`
+	// ExpirationJitter is the maximum amount of time added to TTLSecondsUntilExpired for each node so that
+	// nodes launched at the same time don't expire together. It may be an absolute number of seconds (e.g. 3600)
+	// or a percentage of TTLSecondsUntilExpired (e.g. "10%").
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	ExpirationJitter *intstr.IntOrString `json:"expirationJitter,omitempty" hash:"ignore"`
+	// MaxNodeLifetimeSeconds is the number of seconds after which a node is forcibly expired, measured from when
+	// the node is created. Once exceeded, warning events are emitted and, after ForceExpirationGracePeriodSeconds,
+	// the node is drained and deleted even if PodDisruptionBudgets or do-not-evict pods would block it.
+	//
+	// Forced expiration is disabled if this field is not set.
+	// +optional
+	MaxNodeLifetimeSeconds *int64 `json:"maxNodeLifetimeSeconds,omitempty" hash:"ignore"`
+	// ForceExpirationGracePeriodSeconds is the number of seconds to wait after a node exceeds MaxNodeLifetimeSeconds
+	// before ignoring PodDisruptionBudgets and do-not-evict pods that block its disruption.
+	// +optional
+	ForceExpirationGracePeriodSeconds *int64 `json:"forceExpirationGracePeriodSeconds,omitempty" hash:"ignore"`
+	// DrainTimeoutSeconds is the number of seconds that a deleted node may spend draining before its remaining pods
+	// are force deleted, without waiting for their termination grace period, and its instance is terminated.
+	//
+	// Draining waits indefinitely if this field is not set.
+	// +optional
+	DrainTimeoutSeconds *int64 `json:"drainTimeoutSeconds,omitempty" hash:"ignore"`
+	// NoExecuteFallback taints a node that exceeds DrainTimeoutSeconds with a NoExecute taint, leaving the taint
+	// manager to remove its remaining pods with their termination grace period, rather than force deleting them.
+	// Requires drainTimeoutSeconds to be set.
+	// +optional
+	NoExecuteFallback *bool `json:"noExecuteFallback,omitempty" hash:"ignore"`
+	// ForceEvictAfterSeconds is the number of seconds that the eviction of a pod from a draining node may be blocked
+	// by a PodDisruptionBudget before the pod is deleted directly, bypassing the PodDisruptionBudget.
+	//
+	// PodDisruptionBudgets are always respected if this field is not set.
+	// +optional
+	ForceEvictAfterSeconds *int64 `json:"forceEvictAfterSeconds,omitempty" hash:"ignore"`
+	// RegistrationTTLSeconds is the number of seconds that a launched machine may take to register as a node before
+	// it's deleted and replaced. This is useful for slow booting instance types (e.g. GPU or Windows instances).
+	//
+	// The global registrationTTL setting is used if this field is not set.
+	// +optional
+	RegistrationTTLSeconds *int64 `json:"registrationTTLSeconds,omitempty" hash:"ignore"`
+	// ReadinessGates are node conditions or taints that must be satisfied before a machine is considered initialized,
+	// in addition to the node being Ready and its StartupTaints being removed. These are commonly used to wait for
+	// CNI, GPU or other daemon bootstrap (e.g. the cilium.io/agent-not-ready taint) to complete.
+	// +optional
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty" hash:"ignore"`
 	// Limits define a set of bounds for provisioning capacity.
 	Limits *Limits `json:"limits,omitempty" hash:"ignore"`
 	// Weight is the priority given to the provisioner during scheduling. A higher
@@ -88,9 +143,27 @@ type ProvisionerSpec struct {
 	// +kubebuilder:validation:Maximum:=100
 	// +optional
 	Weight *int32 `json:"weight,omitempty" hash:"ignore"`
+	// Replicas is the minimum number of nodes that the provisioner maintains, even when there are no pending pods.
+	// Empty nodes are launched to fill the floor and aren't removed by emptiness or consolidation while the number of
+	// nodes doesn't exceed it.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty" hash:"ignore"`
 	// Consolidation are the consolidation parameters
 	// +optional
 	Consolidation *Consolidation `json:"consolidation,omitempty" hash:"ignore"`
+	// ConsolidationPolicy describes which nodes consolidation can deprovision when it is enabled. WhenEmpty only
+	// removes nodes without workload pods, while WhenUnderutilized (the default) also bin-packs pods onto fewer or
+	// cheaper nodes.
+	// +kubebuilder:validation:Enum:={WhenEmpty,WhenUnderutilized}
+	// +optional
+	ConsolidationPolicy ConsolidationPolicy `json:"consolidationPolicy,omitempty" hash:"ignore"`
+	// Drift are the drift parameters
+	// +optional
+	Drift *Drift `json:"drift,omitempty" hash:"ignore"`
+	// Disruption are the voluntary disruption parameters
+	// +optional
+	Disruption *Disruption `json:"disruption,omitempty" hash:"ignore"`
 }
 
 func (p *Provisioner) Hash() string {
@@ -102,9 +175,108 @@ func (p *Provisioner) Hash() string {
 	return fmt.Sprint(hash)
 }
 
+// ConsolidationPolicy describes which nodes consolidation can deprovision
+type ConsolidationPolicy string
+
+const (
+	ConsolidationPolicyWhenEmpty         ConsolidationPolicy = "WhenEmpty"
+	ConsolidationPolicyWhenUnderutilized ConsolidationPolicy = "WhenUnderutilized"
+)
+
 type Consolidation struct {
 	// Enabled enables consolidation if it has been set
 	Enabled *bool `json:"enabled,omitempty"`
+	// ConsolidateAfterSeconds is the number of seconds a node must go without pods being bound to or removed from it
+	// before it's considered for consolidation. This reduces churn for bursty workloads. It can't be set when the
+	// ConsolidationPolicy is WhenEmpty.
+	// +optional
+	ConsolidateAfterSeconds *int64 `json:"consolidateAfterSeconds,omitempty"`
+	// MinSavingsPercent is the minimum percentage by which consolidation must reduce the price of the machines it
+	// disrupts. If unset, the global consolidationMinSavingsPercent setting is used.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=99
+	// +optional
+	MinSavingsPercent *int32 `json:"minSavingsPercent,omitempty"`
+	// MinSavingsPerHour is the minimum reduction in price per hour that consolidation must achieve. If unset, the
+	// global consolidationMinSavingsPerHour setting is used.
+	// +optional
+	MinSavingsPerHour *resource.Quantity `json:"minSavingsPerHour,omitempty"`
+}
+
+// ReadinessGate is a node condition or taint that must be satisfied before a node is considered initialized
+type ReadinessGate struct {
+	// ConditionType is the type of a node condition that must have the expected Status
+	// +optional
+	ConditionType v1.NodeConditionType `json:"conditionType,omitempty"`
+	// Status is the status that the node condition must have. Defaults to True.
+	// +kubebuilder:validation:Enum:={True,False,Unknown}
+	// +optional
+	Status v1.ConditionStatus `json:"status,omitempty"`
+	// TaintKey is the key of a taint that must be removed from the node, regardless of its value or effect
+	// +optional
+	TaintKey string `json:"taintKey,omitempty"`
+}
+
+type Disruption struct {
+	// Budgets limit the number of machines launched by this provisioner that can be voluntarily disrupted at once.
+	// When multiple budgets are specified, the most restrictive one is used.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	Budgets []Budget `json:"budgets,omitempty"`
+	// DryRun reports the deprovisioning actions that would be taken for machines launched by this provisioner
+	// without launching or terminating anything.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// ExcludedNodeSelector selects nodes launched by this provisioner that are never considered for voluntary
+	// disruption. It's evaluated in addition to the global deprovisioningExcludedNodeSelector setting.
+	// +optional
+	ExcludedNodeSelector *metav1.LabelSelector `json:"excludedNodeSelector,omitempty"`
+	// LaunchBeforeCordon launches and initializes the replacements for machines that are drifted or expired before
+	// the machines are cordoned and drained, so that workloads with a single replica don't incur avoidable downtime.
+	// +optional
+	LaunchBeforeCordon bool `json:"launchBeforeCordon,omitempty"`
+	// Order overrides the priority of the deprovisioning methods (repair, expiration, drift, emptiness and consolidation)
+	// for machines launched by this provisioner. A method takes precedence over the methods that follow it, and
+	// methods that are omitted are disabled for this provisioner, except for repair which takes precedence over all other
+	// methods when omitted. If unset, the global deprovisioningOrder is used.
+	// +kubebuilder:validation:MaxItems=5
+	// +optional
+	Order []string `json:"order,omitempty"`
+}
+
+type Budget struct {
+	// Nodes is the maximum number of nodes that can be voluntarily disrupted at once. It may be an absolute
+	// number (e.g. "5") or a percentage of the nodes owned by the provisioner (e.g. "10%").
+	// +kubebuilder:validation:Pattern:="^((100|[0-9]{1,2})%|[0-9]+)$"
+	Nodes string `json:"nodes"`
+	// Schedule specifies when the budget becomes active using cron syntax (e.g. "0 9 * * 1-5") or a macro
+	// (e.g. "@daily"), evaluated in UTC. Budgets without a schedule are always active.
+	// +optional
+	Schedule *string `json:"schedule,omitempty"`
+	// Duration determines how long the budget stays active after each activation of its Schedule.
+	// Duration must be set if and only if Schedule is set.
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+type Drift struct {
+	// Enabled enables or disables drift detection for machines launched by this provisioner.
+	// If unset, the global featureGates.driftEnabled setting is used.
+	Enabled *bool `json:"enabled,omitempty"`
+	// RateLimit limits how many machines launched by this provisioner can be replaced due to drift within an interval.
+	// If unset, only the global driftRateLimitNodes setting applies.
+	// +optional
+	RateLimit *DriftRateLimit `json:"rateLimit,omitempty"`
+}
+
+type DriftRateLimit struct {
+	// Nodes is the maximum number of nodes that can be disrupted due to drift within the interval.
+	// +kubebuilder:validation:Minimum:=1
+	Nodes int32 `json:"nodes"`
+	// IntervalSeconds is the length of the interval in seconds.
+	// +kubebuilder:validation:Minimum:=1
+	IntervalSeconds int64 `json:"intervalSeconds"`
 }
 
 // +kubebuilder:object:generate=false
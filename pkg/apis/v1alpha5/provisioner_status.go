@@ -34,6 +34,20 @@ type ProvisionerStatus struct {
 
 	// Resources is the list of resources that have been provisioned.
 	Resources v1.ResourceList `json:"resources,omitempty"`
+
+	// ScopedResources is the list of resources that have been provisioned into each scope of the scoped limits.
+	// +optional
+	ScopedResources []ScopedResources `json:"scopedResources,omitempty"`
+}
+
+// ScopedResources is the list of resources that have been provisioned into a zone and/or capacity type
+type ScopedResources struct {
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// +optional
+	CapacityType string `json:"capacityType,omitempty"`
+	// Resources is the list of resources that have been provisioned into the scope.
+	Resources v1.ResourceList `json:"resources,omitempty"`
 }
 
 func (p *Provisioner) StatusConditions() apis.ConditionManager {
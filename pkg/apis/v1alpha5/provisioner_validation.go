@@ -17,6 +17,7 @@ package v1alpha5
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -25,13 +26,19 @@ import (
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
+
+	"github.com/aws/karpenter-core/pkg/utils/cron"
 )
 
 var (
+	deprovisioningMethods = sets.NewString("repair", "expiration", "drift", "emptiness", "consolidation")
+
 	SupportedNodeSelectorOps = sets.NewString(
 		string(v1.NodeSelectorOpIn),
 		string(v1.NodeSelectorOpNotIn),
@@ -56,6 +63,8 @@ var (
 		"imagefs.inodesFree",
 		"pid.available",
 	)
+
+	budgetNodesRegex = regexp.MustCompile(`^((100|[0-9]{1,2})%|[0-9]+)$`)
 )
 
 const (
@@ -80,7 +89,16 @@ func (p *Provisioner) Validate(ctx context.Context) (errs *apis.FieldError) {
 func (s *ProvisionerSpec) validate(ctx context.Context) (errs *apis.FieldError) {
 	return errs.Also(
 		s.validateTTLSecondsUntilExpired(),
+		s.validateExpirationJitter(),
+		s.validateMaxNodeLifetimeSeconds(),
+		s.validateDrainTimeoutSeconds(),
+		s.validateForceEvictAfterSeconds(),
+		s.validateRegistrationTTLSeconds(),
+		s.validateReadinessGates(),
+		s.validateDisruption(),
+		s.validateDrift(),
 		s.validateTTLSecondsAfterEmpty(),
+		s.validateConsolidationPolicy(),
 		s.Validate(ctx),
 	)
 }
@@ -92,6 +110,138 @@ func (s *ProvisionerSpec) validateTTLSecondsUntilExpired() (errs *apis.FieldErro
 	return errs
 }
 
+func (s *ProvisionerSpec) validateExpirationJitter() (errs *apis.FieldError) {
+	if s.ExpirationJitter == nil {
+		return errs
+	}
+	if s.TTLSecondsUntilExpired == nil {
+		return errs.Also(apis.ErrGeneric("expected ttlSecondsUntilExpired to be set", "expirationJitter"))
+	}
+	jitter, err := intstr.GetScaledValueFromIntOrPercent(s.ExpirationJitter, int(ptr.Int64Value(s.TTLSecondsUntilExpired)), true)
+	if err != nil {
+		return errs.Also(apis.ErrInvalidValue(s.ExpirationJitter.String(), "expirationJitter", err.Error()))
+	}
+	if jitter < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "expirationJitter"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateMaxNodeLifetimeSeconds() (errs *apis.FieldError) {
+	if ptr.Int64Value(s.MaxNodeLifetimeSeconds) < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "maxNodeLifetimeSeconds"))
+	}
+	if ptr.Int64Value(s.ForceExpirationGracePeriodSeconds) < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "forceExpirationGracePeriodSeconds"))
+	}
+	if s.ForceExpirationGracePeriodSeconds != nil && s.MaxNodeLifetimeSeconds == nil {
+		errs = errs.Also(apis.ErrGeneric("expected maxNodeLifetimeSeconds to be set", "forceExpirationGracePeriodSeconds"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateDrainTimeoutSeconds() (errs *apis.FieldError) {
+	if ptr.Int64Value(s.DrainTimeoutSeconds) < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "drainTimeoutSeconds"))
+	}
+	if s.NoExecuteFallback != nil && s.DrainTimeoutSeconds == nil {
+		errs = errs.Also(apis.ErrGeneric("expected drainTimeoutSeconds to be set", "noExecuteFallback"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateForceEvictAfterSeconds() (errs *apis.FieldError) {
+	if ptr.Int64Value(s.ForceEvictAfterSeconds) < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "forceEvictAfterSeconds"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateRegistrationTTLSeconds() (errs *apis.FieldError) {
+	if s.RegistrationTTLSeconds != nil && *s.RegistrationTTLSeconds <= 0 {
+		return errs.Also(apis.ErrInvalidValue("must be positive", "registrationTTLSeconds"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateReadinessGates() (errs *apis.FieldError) {
+	for i := range s.ReadinessGates {
+		errs = errs.Also(s.ReadinessGates[i].validate().ViaFieldIndex("readinessGates", i))
+	}
+	return errs
+}
+func (in *ReadinessGate) validate() (errs *apis.FieldError) {
+	switch {
+	case in.ConditionType == "" && in.TaintKey == "":
+		return errs.Also(apis.ErrMissingOneOf("conditionType", "taintKey"))
+	case in.ConditionType != "" && in.TaintKey != "":
+		return errs.Also(apis.ErrMultipleOneOf("conditionType", "taintKey"))
+	}
+	if in.TaintKey != "" {
+		if in.Status != "" {
+			errs = errs.Also(apis.ErrDisallowedFields("status"))
+		}
+		for _, err := range validation.IsQualifiedName(in.TaintKey) {
+			errs = errs.Also(apis.ErrInvalidValue(err, "taintKey"))
+		}
+	}
+	switch in.Status {
+	case v1.ConditionTrue, v1.ConditionFalse, v1.ConditionUnknown, "":
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(in.Status, "status"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateDisruption() (errs *apis.FieldError) {
+	if s.Disruption == nil {
+		return errs
+	}
+	for i, budget := range s.Disruption.Budgets {
+		if !budgetNodesRegex.MatchString(budget.Nodes) {
+			errs = errs.Also(apis.ErrInvalidValue(budget.Nodes, "nodes", "expected an integer or a percentage").ViaFieldIndex("budgets", i))
+		}
+		if (budget.Schedule == nil) != (budget.Duration == nil) {
+			errs = errs.Also(apis.ErrGeneric("expected schedule and duration to both be set or both be unset", "schedule", "duration").ViaFieldIndex("budgets", i))
+		}
+		if budget.Schedule != nil {
+			if _, err := cron.Parse(*budget.Schedule); err != nil {
+				errs = errs.Also(apis.ErrInvalidValue(*budget.Schedule, "schedule", err.Error()).ViaFieldIndex("budgets", i))
+			}
+		}
+		if budget.Duration != nil && budget.Duration.Duration <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue("must be positive", "duration").ViaFieldIndex("budgets", i))
+		}
+	}
+	for i, method := range s.Disruption.Order {
+		if !deprovisioningMethods.Has(method) {
+			errs = errs.Also(apis.ErrInvalidArrayValue(method, "order", i))
+		}
+		if lo.Contains(s.Disruption.Order[:i], method) {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("duplicate method %q", method), "order"))
+		}
+	}
+	if s.Disruption.ExcludedNodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(s.Disruption.ExcludedNodeSelector); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(err.Error(), "excludedNodeSelector"))
+		}
+	}
+	return errs.ViaField("disruption")
+}
+
+func (s *ProvisionerSpec) validateDrift() (errs *apis.FieldError) {
+	if s.Drift == nil || s.Drift.RateLimit == nil {
+		return errs
+	}
+	if s.Drift.RateLimit.Nodes < 1 {
+		errs = errs.Also(apis.ErrInvalidValue("must be positive", "nodes"))
+	}
+	if s.Drift.RateLimit.IntervalSeconds < 1 {
+		errs = errs.Also(apis.ErrInvalidValue("must be positive", "intervalSeconds"))
+	}
+	return errs.ViaField("drift", "rateLimit")
+}
+
 func (s *ProvisionerSpec) validateTTLSecondsAfterEmpty() (errs *apis.FieldError) {
 	if ptr.Int64Value(s.TTLSecondsAfterEmpty) < 0 {
 		return errs.Also(apis.ErrInvalidValue("cannot be negative", "ttlSecondsAfterEmpty"))
@@ -100,6 +250,32 @@ func (s *ProvisionerSpec) validateTTLSecondsAfterEmpty() (errs *apis.FieldError)
 	if s.Consolidation != nil && ptr.BoolValue(s.Consolidation.Enabled) && s.TTLSecondsAfterEmpty != nil {
 		return errs.Also(apis.ErrMultipleOneOf("ttlSecondsAfterEmpty", "consolidation.enabled"))
 	}
+	if s.Consolidation != nil && ptr.Int64Value(s.Consolidation.ConsolidateAfterSeconds) < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidation.consolidateAfterSeconds"))
+	}
+	if s.Consolidation != nil && s.Consolidation.MinSavingsPercent != nil && (*s.Consolidation.MinSavingsPercent < 0 || *s.Consolidation.MinSavingsPercent > 99) {
+		return errs.Also(apis.ErrOutOfBoundsValue(*s.Consolidation.MinSavingsPercent, 0, 99, "consolidation.minSavingsPercent"))
+	}
+	if s.Consolidation != nil && s.Consolidation.MinSavingsPerHour != nil && s.Consolidation.MinSavingsPerHour.Sign() < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidation.minSavingsPerHour"))
+	}
+	return errs
+}
+
+func (s *ProvisionerSpec) validateConsolidationPolicy() (errs *apis.FieldError) {
+	if s.ConsolidationPolicy == "" {
+		return errs
+	}
+	if s.ConsolidationPolicy != ConsolidationPolicyWhenEmpty && s.ConsolidationPolicy != ConsolidationPolicyWhenUnderutilized {
+		return errs.Also(apis.ErrInvalidValue(s.ConsolidationPolicy, "consolidationPolicy"))
+	}
+	if s.Consolidation == nil || !ptr.BoolValue(s.Consolidation.Enabled) {
+		return errs.Also(apis.ErrGeneric("consolidationPolicy requires consolidation to be enabled", "consolidationPolicy"))
+	}
+	// Empty nodes are deprovisioned as soon as they're empty, so consolidateAfterSeconds would be silently ignored
+	if s.ConsolidationPolicy == ConsolidationPolicyWhenEmpty && s.Consolidation.ConsolidateAfterSeconds != nil {
+		return errs.Also(apis.ErrGeneric("consolidation.consolidateAfterSeconds can't be set when consolidationPolicy is WhenEmpty", "consolidation.consolidateAfterSeconds"))
+	}
 	return errs
 }
 
@@ -110,6 +286,7 @@ func (s *ProvisionerSpec) Validate(_ context.Context) (errs *apis.FieldError) {
 		s.validateLabels(),
 		s.validateTaints(),
 		s.validateRequirements(),
+		s.validatePreferences(),
 		s.validateKubeletConfiguration().ViaField("kubeletConfiguration"),
 	)
 }
@@ -193,6 +370,20 @@ func (s *ProvisionerSpec) validateRequirements() (errs *apis.FieldError) {
 	return errs
 }
 
+func (s *ProvisionerSpec) validatePreferences() (errs *apis.FieldError) {
+	for i, preference := range s.Preferences {
+		if preference.Weight < 1 || preference.Weight > 100 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(preference.Weight, 1, 100, "weight").ViaFieldIndex("preferences", i))
+		}
+		for j, requirement := range preference.Preference.MatchExpressions {
+			if err := ValidateRequirement(requirement); err != nil {
+				errs = errs.Also(apis.ErrInvalidArrayValue(err, "matchExpressions", j).ViaField("preference").ViaFieldIndex("preferences", i))
+			}
+		}
+	}
+	return errs
+}
+
 // validateProvider checks if exactly one of provider and providerRef are set
 func (s *ProvisionerSpec) validateProvider() *apis.FieldError {
 	if s.Provider != nil && s.ProviderRef != nil {
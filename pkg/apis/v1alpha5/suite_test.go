@@ -36,6 +36,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -71,6 +72,166 @@ var _ = Describe("Validation", func() {
 		provisioner.Spec.TTLSecondsUntilExpired = nil
 		Expect(provisioner.Validate(ctx)).To(Succeed())
 	})
+	It("should succeed on a valid expiration jitter", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(3600)
+		provisioner.Spec.ExpirationJitter = lo.ToPtr(intstr.FromString("10%"))
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+		provisioner.Spec.ExpirationJitter = lo.ToPtr(intstr.FromInt(300))
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on expiration jitter without an expiry ttl", func() {
+		provisioner.Spec.ExpirationJitter = lo.ToPtr(intstr.FromInt(300))
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on negative expiration jitter", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(3600)
+		provisioner.Spec.ExpirationJitter = lo.ToPtr(intstr.FromInt(-1))
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on invalid expiration jitter", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(3600)
+		provisioner.Spec.ExpirationJitter = lo.ToPtr(intstr.FromString("ten"))
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on negative max node lifetime", func() {
+		provisioner.Spec.MaxNodeLifetimeSeconds = ptr.Int64(-1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on negative force expiration grace period", func() {
+		provisioner.Spec.MaxNodeLifetimeSeconds = ptr.Int64(3600)
+		provisioner.Spec.ForceExpirationGracePeriodSeconds = ptr.Int64(-1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on force expiration grace period without a max node lifetime", func() {
+		provisioner.Spec.ForceExpirationGracePeriodSeconds = ptr.Int64(300)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on negative drain timeout", func() {
+		provisioner.Spec.DrainTimeoutSeconds = ptr.Int64(-1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on the NoExecute fallback without a drain timeout", func() {
+		provisioner.Spec.NoExecuteFallback = ptr.Bool(true)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on the NoExecute fallback with a drain timeout", func() {
+		provisioner.Spec.DrainTimeoutSeconds = ptr.Int64(600)
+		provisioner.Spec.NoExecuteFallback = ptr.Bool(true)
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on negative force evict after", func() {
+		provisioner.Spec.ForceEvictAfterSeconds = ptr.Int64(-1)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on valid disruption budgets", func() {
+		provisioner.Spec.Disruption = &Disruption{Budgets: []Budget{{Nodes: "10%"}, {Nodes: "5"}}}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on invalid disruption budgets", func() {
+		for _, nodes := range []string{"-1", "101%", "ten", "10.5%", ""} {
+			provisioner.Spec.Disruption = &Disruption{Budgets: []Budget{{Nodes: nodes}}}
+			Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		}
+	})
+	It("should succeed on a disruption budget with a schedule and duration", func() {
+		provisioner.Spec.Disruption = &Disruption{Budgets: []Budget{{Nodes: "0", Schedule: lo.ToPtr("@daily"), Duration: &metav1.Duration{Duration: time.Hour}}}}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a disruption budget with a schedule but no duration", func() {
+		provisioner.Spec.Disruption = &Disruption{Budgets: []Budget{{Nodes: "0", Schedule: lo.ToPtr("@daily")}}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on a disruption budget with an invalid schedule", func() {
+		provisioner.Spec.Disruption = &Disruption{Budgets: []Budget{{Nodes: "0", Schedule: lo.ToPtr("every day"), Duration: &metav1.Duration{Duration: time.Hour}}}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a valid deprovisioning order", func() {
+		provisioner.Spec.Disruption = &Disruption{Order: []string{"drift", "expiration"}}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a deprovisioning order with unknown or duplicate methods", func() {
+		provisioner.Spec.Disruption = &Disruption{Order: []string{"drift", "replacement"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Disruption = &Disruption{Order: []string{"drift", "drift"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on an invalid excluded node selector", func() {
+		provisioner.Spec.Disruption = &Disruption{ExcludedNodeSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Matches"}},
+		}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a consolidation policy with consolidation enabled", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true)}
+		provisioner.Spec.ConsolidationPolicy = ConsolidationPolicyWhenEmpty
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a consolidation policy without consolidation enabled", func() {
+		provisioner.Spec.ConsolidationPolicy = ConsolidationPolicyWhenEmpty
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on a negative consolidateAfterSeconds", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), ConsolidateAfterSeconds: ptr.Int64(-1)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on consolidateAfterSeconds with the WhenEmpty consolidation policy", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), ConsolidateAfterSeconds: ptr.Int64(30)}
+		provisioner.Spec.ConsolidationPolicy = ConsolidationPolicyWhenEmpty
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.ConsolidationPolicy = ConsolidationPolicyWhenUnderutilized
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on consolidation minimum savings that are out of range", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinSavingsPercent: ptr.Int32(100)}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true), MinSavingsPerHour: lo.ToPtr(resource.MustParse("-0.01"))}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on an unknown consolidation policy", func() {
+		provisioner.Spec.Consolidation = &Consolidation{Enabled: ptr.Bool(true)}
+		provisioner.Spec.ConsolidationPolicy = "Never"
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a valid drift rate limit", func() {
+		provisioner.Spec.Drift = &Drift{RateLimit: &DriftRateLimit{Nodes: 5, IntervalSeconds: 600}}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a drift rate limit without nodes or an interval", func() {
+		provisioner.Spec.Drift = &Drift{RateLimit: &DriftRateLimit{Nodes: 0, IntervalSeconds: 600}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.Drift = &Drift{RateLimit: &DriftRateLimit{Nodes: 5, IntervalSeconds: 0}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on a non-positive registration ttl", func() {
+		provisioner.Spec.RegistrationTTLSeconds = ptr.Int64(0)
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a valid registration ttl", func() {
+		provisioner.Spec.RegistrationTTLSeconds = ptr.Int64(1800)
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should succeed on valid readiness gates", func() {
+		provisioner.Spec.ReadinessGates = []ReadinessGate{
+			{ConditionType: "NetworkAvailable"},
+			{ConditionType: "NetworkUnavailable", Status: v1.ConditionFalse},
+			{TaintKey: "cilium.io/agent-not-ready"},
+		}
+		Expect(provisioner.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on a readiness gate without a condition type or taint key", func() {
+		provisioner.Spec.ReadinessGates = []ReadinessGate{{}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on a readiness gate with both a condition type and taint key", func() {
+		provisioner.Spec.ReadinessGates = []ReadinessGate{{ConditionType: "NetworkAvailable", TaintKey: "cilium.io/agent-not-ready"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should fail on a readiness gate with an invalid status or taint key", func() {
+		provisioner.Spec.ReadinessGates = []ReadinessGate{{ConditionType: "NetworkAvailable", Status: "Maybe"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		provisioner.Spec.ReadinessGates = []ReadinessGate{{TaintKey: "not a valid key"}}
+		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+	})
 	It("should fail on negative empty ttl", func() {
 		provisioner.Spec.TTLSecondsAfterEmpty = ptr.Int64(-1)
 		Expect(provisioner.Validate(ctx)).ToNot(Succeed())
@@ -215,6 +376,34 @@ var _ = Describe("Validation", func() {
 			Expect(provisioner.Validate(ctx)).ToNot(Succeed())
 		})
 	})
+	Context("Preferences", func() {
+		It("should succeed for valid preferences", func() {
+			provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+				{Weight: 100, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{ArchitectureArm64}},
+				}}},
+			}
+			Expect(provisioner.Validate(ctx)).To(Succeed())
+		})
+		It("should fail for weights outside of 1-100", func() {
+			for _, weight := range []int32{0, 101} {
+				provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+					{Weight: weight, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{ArchitectureArm64}},
+					}}},
+				}
+				Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+			}
+		})
+		It("should fail for invalid match expressions", func() {
+			provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+				{Weight: 10, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelArchStable, Operator: "unknown", Values: []string{ArchitectureArm64}},
+				}}},
+			}
+			Expect(provisioner.Validate(ctx)).ToNot(Succeed())
+		})
+	})
 	Context("Requirements", func() {
 		It("should fail for the provisioner name label", func() {
 			provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
@@ -534,6 +723,16 @@ var _ = Describe("Limits", func() {
 		provisioner.Status.Resources = v1.ResourceList{"cpu": resource.MustParse("17")}
 		Expect(provisioner.Spec.Limits.ExceededBy(provisioner.Status.Resources)).To(MatchError("cpu resource usage of 17 exceeds limit of 16"))
 	})
+	It("should work when the node count is equal to the maxNodes limit", func() {
+		provisioner.Spec.Limits.MaxNodes = ptr.Int32(2)
+		provisioner.Status.Resources = v1.ResourceList{ResourceNodes: resource.MustParse("2")}
+		Expect(provisioner.Spec.Limits.ExceededBy(provisioner.Status.Resources)).To(Succeed())
+	})
+	It("should fail when the node count is higher than the maxNodes limit", func() {
+		provisioner.Spec.Limits.MaxNodes = ptr.Int32(2)
+		provisioner.Status.Resources = v1.ResourceList{ResourceNodes: resource.MustParse("3")}
+		Expect(provisioner.Spec.Limits.ExceededBy(provisioner.Status.Resources)).To(MatchError("node count of 3 exceeds limit of 2"))
+	})
 })
 
 var _ = Describe("Provisioner Annotation", func() {
@@ -645,7 +844,7 @@ var _ = Describe("Provisioner Annotation", func() {
 		Entry(
 			"should match with modified consolidation flag",
 			baseProvisionerExpectedHash,
-			test.ProvisionerOptions{Consolidation: &Consolidation{lo.ToPtr(true)}},
+			test.ProvisionerOptions{Consolidation: &Consolidation{Enabled: lo.ToPtr(true)}},
 		),
 	)
 	It("should change hash when static fields are updated", func() {
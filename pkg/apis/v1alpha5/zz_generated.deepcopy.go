@@ -22,9 +22,35 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/apis"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Budget) DeepCopyInto(out *Budget) {
+	*out = *in
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(string)
+		**out = **in
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Budget.
+func (in *Budget) DeepCopy() *Budget {
+	if in == nil {
+		return nil
+	}
+	out := new(Budget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Consolidation) DeepCopyInto(out *Consolidation) {
 	*out = *in
@@ -33,6 +59,21 @@ func (in *Consolidation) DeepCopyInto(out *Consolidation) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ConsolidateAfterSeconds != nil {
+		in, out := &in.ConsolidateAfterSeconds, &out.ConsolidateAfterSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MinSavingsPercent != nil {
+		in, out := &in.MinSavingsPercent, &out.MinSavingsPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinSavingsPerHour != nil {
+		in, out := &in.MinSavingsPerHour, &out.MinSavingsPerHour
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Consolidation.
@@ -45,6 +86,78 @@ func (in *Consolidation) DeepCopy() *Consolidation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Disruption) DeepCopyInto(out *Disruption) {
+	*out = *in
+	if in.Budgets != nil {
+		in, out := &in.Budgets, &out.Budgets
+		*out = make([]Budget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludedNodeSelector != nil {
+		in, out := &in.ExcludedNodeSelector, &out.ExcludedNodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Disruption.
+func (in *Disruption) DeepCopy() *Disruption {
+	if in == nil {
+		return nil
+	}
+	out := new(Disruption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Drift) DeepCopyInto(out *Drift) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(DriftRateLimit)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Drift.
+func (in *Drift) DeepCopy() *Drift {
+	if in == nil {
+		return nil
+	}
+	out := new(Drift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftRateLimit) DeepCopyInto(out *DriftRateLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftRateLimit.
+func (in *DriftRateLimit) DeepCopy() *DriftRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeletConfiguration) DeepCopyInto(out *KubeletConfiguration) {
 	*out = *in
@@ -145,6 +258,18 @@ func (in *Limits) DeepCopyInto(out *Limits) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Scoped != nil {
+		in, out := &in.Scoped, &out.Scoped
+		*out = make([]ScopedLimit, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Limits.
@@ -240,6 +365,13 @@ func (in *MachineSpec) DeepCopyInto(out *MachineSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Preferences != nil {
+		in, out := &in.Preferences, &out.Preferences
+		*out = make([]v1.PreferredSchedulingTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.Kubelet != nil {
 		in, out := &in.Kubelet, &out.Kubelet
@@ -287,6 +419,10 @@ func (in *MachineStatus) DeepCopyInto(out *MachineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineStatus.
@@ -411,6 +547,13 @@ func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Preferences != nil {
+		in, out := &in.Preferences, &out.Preferences
+		*out = make([]v1.PreferredSchedulingTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.KubeletConfiguration != nil {
 		in, out := &in.KubeletConfiguration, &out.KubeletConfiguration
 		*out = new(KubeletConfiguration)
@@ -436,6 +579,46 @@ func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.ExpirationJitter != nil {
+		in, out := &in.ExpirationJitter, &out.ExpirationJitter
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxNodeLifetimeSeconds != nil {
+		in, out := &in.MaxNodeLifetimeSeconds, &out.MaxNodeLifetimeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ForceExpirationGracePeriodSeconds != nil {
+		in, out := &in.ForceExpirationGracePeriodSeconds, &out.ForceExpirationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DrainTimeoutSeconds != nil {
+		in, out := &in.DrainTimeoutSeconds, &out.DrainTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.NoExecuteFallback != nil {
+		in, out := &in.NoExecuteFallback, &out.NoExecuteFallback
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ForceEvictAfterSeconds != nil {
+		in, out := &in.ForceEvictAfterSeconds, &out.ForceEvictAfterSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RegistrationTTLSeconds != nil {
+		in, out := &in.RegistrationTTLSeconds, &out.RegistrationTTLSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGate, len(*in))
+		copy(*out, *in)
+	}
 	if in.Limits != nil {
 		in, out := &in.Limits, &out.Limits
 		*out = new(Limits)
@@ -446,11 +629,26 @@ func (in *ProvisionerSpec) DeepCopyInto(out *ProvisionerSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	if in.Consolidation != nil {
 		in, out := &in.Consolidation, &out.Consolidation
 		*out = new(Consolidation)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(Drift)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Disruption != nil {
+		in, out := &in.Disruption, &out.Disruption
+		*out = new(Disruption)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerSpec.
@@ -485,6 +683,13 @@ func (in *ProvisionerStatus) DeepCopyInto(out *ProvisionerStatus) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.ScopedResources != nil {
+		in, out := &in.ScopedResources, &out.ScopedResources
+		*out = make([]ScopedResources, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerStatus.
@@ -497,6 +702,21 @@ func (in *ProvisionerStatus) DeepCopy() *ProvisionerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceRequirements) DeepCopyInto(out *ResourceRequirements) {
 	*out = *in
@@ -518,3 +738,52 @@ func (in *ResourceRequirements) DeepCopy() *ResourceRequirements {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScopedLimit) DeepCopyInto(out *ScopedLimit) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScopedLimit.
+func (in *ScopedLimit) DeepCopy() *ScopedLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ScopedLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScopedResources) DeepCopyInto(out *ScopedResources) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScopedResources.
+func (in *ScopedResources) DeepCopy() *ScopedResources {
+	if in == nil {
+		return nil
+	}
+	out := new(ScopedResources)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -28,6 +28,7 @@ const (
 	ArchitectureArm64    = "arm64"
 	CapacityTypeSpot     = "spot"
 	CapacityTypeOnDemand = "on-demand"
+	CapacityTypeReserved = "reserved"
 )
 
 // Karpenter specific domains and labels
@@ -36,14 +37,31 @@ const (
 	NodeInitializedLabelKey = Group + "/initialized"
 	NodeRegisteredLabelKey  = Group + "/registered"
 	CapacityTypeLabelKey    = Group + "/capacity-type"
+	// CapacitySpreadLabelKey is populated by the scheduler in a round-robin fashion from the values allowed by the
+	// nodepool so that pods can spread across arbitrary buckets of capacity with topologySpreadConstraints
+	CapacitySpreadLabelKey = Group + "/capacity-spread"
 )
 
 // Karpenter specific annotations
 const (
 	DoNotDisruptAnnotationKey          = Group + "/do-not-disrupt"
+	DisruptionPausedAnnotationKey      = Group + "/disruption-paused"
 	ProviderCompatabilityAnnotationKey = CompatabilityGroup + "/provider"
 	ManagedByAnnotationKey             = Group + "/managed-by"
 	NodePoolHashAnnotationKey          = Group + "/nodepool-hash"
+	TTLUntilExpiredAnnotationKey       = Group + "/ttl-until-expired"
+	TTLAfterEmptyAnnotationKey         = Group + "/ttl-after-empty"
+	// PreferencePolicyAnnotationKey controls whether the scheduler may relax a pod's preferences when they can't be
+	// satisfied. Valid values are Relax (default), RelaxAndLog and Strict.
+	PreferencePolicyAnnotationKey = Group + "/preference-policy"
+	// LaunchPriceAnnotationKey is the hourly price of the offering that a NodeClaim was launched with
+	LaunchPriceAnnotationKey = Group + "/launch-price"
+	// NodeClaimPausedAnnotationKey stops the lifecycle, disruption and garbage collection controllers from reconciling
+	// a NodeClaim so that its node can be investigated without Karpenter mutating or deleting it
+	NodeClaimPausedAnnotationKey = Group + "/nodeclaim-paused"
+	// InitializationTimedOutAnnotationKey is set on NodeClaims whose nodes registered but didn't initialize within the
+	// registration TTL, so that they're only counted as failed once
+	InitializationTimedOutAnnotationKey = Group + "/initialization-timed-out"
 )
 
 // Karpenter specific finalizers
@@ -78,6 +96,7 @@ var (
 		v1.LabelArchStable,
 		v1.LabelOSStable,
 		CapacityTypeLabelKey,
+		CapacitySpreadLabelKey,
 	)
 
 	// RestrictedLabels are labels that should not be used
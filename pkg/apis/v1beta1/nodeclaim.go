@@ -34,6 +34,10 @@ type NodeClaimSpec struct {
 	// Requirements are layered with GetLabels and applied to every node.
 	// +optional
 	Requirements []v1.NodeSelectorRequirement `json:"requirements,omitempty" hash:"ignore"`
+	// Preferences are weighted terms used to order the instance types that satisfy the NodeClaim's requirements.
+	// Instance types that match a higher summed weight of terms are preferred before falling back to price.
+	// +optional
+	Preferences []v1.PreferredSchedulingTerm `json:"preferences,omitempty" hash:"ignore"`
 	// Resources models the resource requirements for the NodeClaim to launch
 	// +optional
 	Resources ResourceRequirements `json:"resources,omitempty" hash:"ignore"`
@@ -43,6 +47,10 @@ type NodeClaimSpec struct {
 	// NodeClass is a reference to an object that defines provider specific configuration
 	// +required
 	NodeClass *NodeClassReference `json:"nodeClass"`
+	// CloudProvider is the name of the registered cloudprovider that launches the NodeClaim. If unset, the NodeClaim
+	// is launched by the default cloudprovider.
+	// +optional
+	CloudProvider string `json:"cloudProvider,omitempty"`
 	// Provider stores CloudProvider-specific details from a conversion from a v1alpha5.Provisioner
 	// TODO @joinnis: Remove this field when v1alpha5 is unsupported in a future version of Karpenter
 	Provider *Provider `json:"-"`
@@ -16,6 +16,7 @@ package v1beta1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
 )
 
@@ -36,6 +37,22 @@ type NodeClaimStatus struct {
 	// Conditions contains signals for health and readiness
 	// +optional
 	Conditions apis.Conditions `json:"conditions,omitempty"`
+	// ExpirationTime is the time at which the node will be considered expired, including any
+	// expiration jitter configured on the owning NodePool
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// InstanceType is the instance type of the offering that the node was launched with
+	// +optional
+	InstanceType string `json:"instanceType,omitempty"`
+	// Zone is the zone of the offering that the node was launched with
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// CapacityType is the capacity type (e.g. on-demand or spot) of the offering that the node was launched with
+	// +optional
+	CapacityType string `json:"capacityType,omitempty"`
+	// Price is the hourly price of the offering that the node was launched with, at the time that it was launched
+	// +optional
+	Price string `json:"price,omitempty"`
 }
 
 func (in *NodeClaim) StatusConditions() apis.ConditionManager {
@@ -60,6 +77,8 @@ var (
 	NodeDrifted       apis.ConditionType = "NodeDrifted"
 	NodeExpired       apis.ConditionType = "NodeExpired"
 	NodeUnderutilized apis.ConditionType = "NodeUnderutilized"
+	NodeLaunchFailed  apis.ConditionType = "NodeLaunchFailed"
+	NodeUnhealthy     apis.ConditionType = "NodeUnhealthy"
 )
 
 func (in *NodeClaim) GetConditions() apis.Conditions {
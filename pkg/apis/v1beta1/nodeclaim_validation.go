@@ -69,6 +69,7 @@ func (in *NodeClaimSpec) validate() (errs *apis.FieldError) {
 	return errs.Also(
 		in.validateTaints(),
 		in.validateRequirements(),
+		in.validatePreferences(),
 		in.KubeletConfiguration.validate().ViaField("kubeletConfiguration"),
 	)
 }
@@ -134,6 +135,20 @@ func (in *NodeClaimSpec) validateRequirements() (errs *apis.FieldError) {
 	return errs
 }
 
+func (in *NodeClaimSpec) validatePreferences() (errs *apis.FieldError) {
+	for i, preference := range in.Preferences {
+		if preference.Weight < 1 || preference.Weight > 100 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(preference.Weight, 1, 100, "weight").ViaFieldIndex("preferences", i))
+		}
+		for j, requirement := range preference.Preference.MatchExpressions {
+			if err := in.validateRequirement(requirement); err != nil {
+				errs = errs.Also(apis.ErrInvalidArrayValue(err, "matchExpressions", j).ViaField("preference").ViaFieldIndex("preferences", i))
+			}
+		}
+	}
+	return errs
+}
+
 func (in *NodeClaimSpec) validateRequirement(requirement v1.NodeSelectorRequirement) error { //nolint:gocyclo
 	var errs error
 	if normalized, ok := NormalizedLabels[requirement.Key]; ok {
@@ -16,13 +16,19 @@ package v1beta1
 
 import (
 	"fmt"
+	"math"
 	"sort"
 
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/ptr"
+
+	"github.com/aws/karpenter-core/pkg/utils/cron"
 )
 
 // NodePoolSpec is the top level provisioner specification. Provisioners
@@ -41,6 +47,9 @@ type NodePoolSpec struct {
 	// Limits define a set of bounds for provisioning capacity.
 	// +optional
 	Limits Limits `json:"limits,omitempty"`
+	// ScopedLimits define bounds for provisioning capacity into a zone and/or capacity type.
+	// +optional
+	ScopedLimits []ScopedLimit `json:"scopedLimits,omitempty"`
 	// Weight is the priority given to the provisioner during scheduling. A higher
 	// numerical weight indicates that this provisioner will be ordered
 	// ahead of other provisioners with lower weights. A provisioner with no weight
@@ -49,6 +58,21 @@ type NodePoolSpec struct {
 	// +kubebuilder:validation:Maximum:=100
 	// +optional
 	Weight *int32 `json:"weight,omitempty"`
+	// Replicas is the minimum number of nodes that the NodePool maintains, even when there are no pending pods.
+	// Empty nodes are launched to fill the floor and aren't removed by emptiness or consolidation while the number of
+	// nodes doesn't exceed it.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// RegistrationTTL is the duration that a launched NodeClaim may take to register as a node before it's deleted
+	// and replaced. This is useful for slow booting instance types (e.g. GPU or Windows instances).
+	// If unset, the global registrationTTL setting is used.
+	// +optional
+	RegistrationTTL *metav1.Duration `json:"registrationTTL,omitempty"`
+	// ReadinessGates are node conditions or taints that must be satisfied before a NodeClaim is considered
+	// initialized, in addition to the node being Ready and its StartupTaints being removed.
+	// +optional
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty"`
 }
 
 type Deprovisioning struct {
@@ -64,6 +88,21 @@ type Deprovisioning struct {
 	// +kubebuilder:validation:Enum:={Never,WhenEmpty,WhenUnderutilized}
 	// +optional
 	ConsolidationPolicy ConsolidationPolicy `json:"consolidationPolicy,omitempty"`
+	// ConsolidateAfter is the duration a node must go without pods being bound to or removed from it
+	// before it's considered for consolidation. This reduces churn for bursty workloads. It can't be set when the
+	// ConsolidationPolicy is WhenEmpty.
+	// +optional
+	ConsolidateAfter *metav1.Duration `json:"consolidateAfter,omitempty"`
+	// ConsolidationMinSavingsPercent is the minimum percentage by which consolidation must reduce the price of the
+	// nodes it disrupts. If unset, the global consolidationMinSavingsPercent setting is used.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=99
+	// +optional
+	ConsolidationMinSavingsPercent *int32 `json:"consolidationMinSavingsPercent,omitempty"`
+	// ConsolidationMinSavingsPerHour is the minimum reduction in price per hour that consolidation must achieve.
+	// If unset, the global consolidationMinSavingsPerHour setting is used.
+	// +optional
+	ConsolidationMinSavingsPerHour *resource.Quantity `json:"consolidationMinSavingsPerHour,omitempty"`
 	// ExpirationTTL is the duration the controller will wait
 	// before terminating a node, measured from when the node is created. This
 	// is useful to implement features like eventually consistent node upgrade,
@@ -71,6 +110,106 @@ type Deprovisioning struct {
 	// +kubebuilder:default:="90d"
 	// +optional
 	ExpirationTTL metav1.Duration `json:"expirationTTL,omitempty"`
+	// ExpirationJitter is the maximum amount of time added to ExpirationTTL for each node so that
+	// nodes launched at the same time don't expire together. It may be an absolute number of seconds (e.g. 3600)
+	// or a percentage of ExpirationTTL (e.g. "10%").
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	ExpirationJitter *intstr.IntOrString `json:"expirationJitter,omitempty"`
+	// MaxNodeLifetime is the duration after which a node is forcibly expired, measured from when the node is
+	// created. Once exceeded, warning events are emitted and, after ForceExpirationGracePeriod, the node is
+	// drained and deleted even if PodDisruptionBudgets or do-not-evict pods would block it.
+	// +optional
+	MaxNodeLifetime *metav1.Duration `json:"maxNodeLifetime,omitempty"`
+	// ForceExpirationGracePeriod is the duration to wait after a node exceeds MaxNodeLifetime before ignoring
+	// PodDisruptionBudgets and do-not-evict pods that block its disruption.
+	// +optional
+	ForceExpirationGracePeriod *metav1.Duration `json:"forceExpirationGracePeriod,omitempty"`
+	// DrainTimeout is the duration that a deleted node may spend draining before its remaining pods are force
+	// deleted, without waiting for their termination grace period, and its instance is terminated.
+	// If unset, draining waits indefinitely.
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+	// NoExecuteFallback taints a node that exceeds its DrainTimeout with a NoExecute taint, leaving the taint manager
+	// to remove its remaining pods with their termination grace period, rather than force deleting them. Requires
+	// DrainTimeout to be set.
+	// +optional
+	NoExecuteFallback *bool `json:"noExecuteFallback,omitempty"`
+	// ForceEvictAfter is the duration that the eviction of a pod from a draining node may be blocked by a
+	// PodDisruptionBudget before the pod is deleted directly, bypassing the PodDisruptionBudget.
+	// If unset, PodDisruptionBudgets are always respected.
+	// +optional
+	ForceEvictAfter *metav1.Duration `json:"forceEvictAfter,omitempty"`
+	// DriftEnabled enables or disables drift detection for NodeClaims launched by this NodePool.
+	// If unset, the global featureGates.driftEnabled setting is used.
+	// +optional
+	DriftEnabled *bool `json:"driftEnabled,omitempty"`
+	// DriftRateLimit limits how many NodeClaims launched by this NodePool can be replaced due to drift within an
+	// interval. If unset, only the global driftRateLimitNodes setting applies.
+	// +optional
+	DriftRateLimit *DriftRateLimit `json:"driftRateLimit,omitempty"`
+	// Budgets limit the number of NodeClaims launched by this NodePool that can be voluntarily disrupted at once.
+	// When multiple budgets are specified, the most restrictive one is used.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	Budgets []Budget `json:"budgets,omitempty"`
+	// DryRun reports the deprovisioning actions that would be taken for NodeClaims launched by this NodePool
+	// without launching or terminating anything.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// ExcludedNodeSelector selects nodes launched by this NodePool that are never considered for voluntary
+	// disruption. It's evaluated in addition to the global deprovisioningExcludedNodeSelector setting.
+	// +optional
+	ExcludedNodeSelector *metav1.LabelSelector `json:"excludedNodeSelector,omitempty"`
+	// LaunchBeforeCordon launches and initializes the replacements for NodeClaims that are drifted or expired
+	// before the NodeClaims are cordoned and drained, so that workloads with a single replica don't incur
+	// avoidable downtime.
+	// +optional
+	LaunchBeforeCordon bool `json:"launchBeforeCordon,omitempty"`
+	// Order overrides the priority of the deprovisioning methods (repair, expiration, drift, emptiness and consolidation)
+	// for NodeClaims launched by this NodePool. A method takes precedence over the methods that follow it, and
+	// methods that are omitted are disabled for this NodePool, except for repair which takes precedence over all other
+	// methods when omitted. If unset, the global deprovisioningOrder is used.
+	// +kubebuilder:validation:MaxItems=5
+	// +optional
+	Order []string `json:"order,omitempty"`
+}
+
+// Budget limits the number of nodes that can be voluntarily disrupted at once
+type Budget struct {
+	// Nodes is the maximum number of nodes that can be voluntarily disrupted at once. It may be an absolute
+	// number (e.g. "5") or a percentage of the nodes owned by the NodePool (e.g. "10%").
+	// +kubebuilder:validation:Pattern:="^((100|[0-9]{1,2})%|[0-9]+)$"
+	Nodes string `json:"nodes"`
+	// Schedule specifies when the budget becomes active using cron syntax (e.g. "0 9 * * 1-5") or a macro
+	// (e.g. "@daily"), evaluated in UTC. Budgets without a schedule are always active.
+	// +optional
+	Schedule *string `json:"schedule,omitempty"`
+	// Duration determines how long the budget stays active after each activation of its Schedule.
+	// Duration must be set if and only if Schedule is set.
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+type DriftRateLimit struct {
+	// Nodes is the maximum number of nodes that can be disrupted due to drift within the interval.
+	// +kubebuilder:validation:Minimum:=1
+	Nodes int32 `json:"nodes"`
+	// Interval is the length of the interval.
+	Interval metav1.Duration `json:"interval"`
+}
+
+// IsActive returns whether the budget currently applies. Budgets without a schedule are always active.
+func (in *Budget) IsActive(clk clock.Clock) (bool, error) {
+	if in.Schedule == nil {
+		return true, nil
+	}
+	schedule, err := cron.Parse(*in.Schedule)
+	if err != nil {
+		return false, fmt.Errorf("parsing budget schedule %q, %w", *in.Schedule, err)
+	}
+	return schedule.Active(clk.Now(), lo.FromPtr(in.Duration).Duration), nil
 }
 
 type ConsolidationPolicy string
@@ -81,6 +220,9 @@ const (
 	ConsolidationPolicyWhenUnderutilized ConsolidationPolicy = "WhenUnderutilized"
 )
 
+// ResourceNodes is the resource name used to limit and count the number of nodes launched by a NodePool
+const ResourceNodes v1.ResourceName = "nodes"
+
 type Limits v1.ResourceList
 
 func (l Limits) ExceededBy(resources v1.ResourceList) error {
@@ -97,6 +239,57 @@ func (l Limits) ExceededBy(resources v1.ResourceList) error {
 	return nil
 }
 
+// ScopedLimit bounds the resources provisioned into a zone and/or capacity type
+type ScopedLimit struct {
+	// Zone restricts the limit to nodes launched into the topology zone. If unset, nodes in every zone are counted.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// CapacityType restricts the limit to nodes launched with the capacity type. If unset, nodes of every
+	// capacity type are counted.
+	// +optional
+	CapacityType string `json:"capacityType,omitempty"`
+	// Limits define a set of bounds for provisioning capacity into the scope.
+	// +optional
+	Limits Limits `json:"limits,omitempty"`
+}
+
+// Matches returns true if a node with the given zone and capacity type is counted against the limit
+func (l ScopedLimit) Matches(zone, capacityType string) bool {
+	return (l.Zone == "" || l.Zone == zone) && (l.CapacityType == "" || l.CapacityType == capacityType)
+}
+
+// ReadinessGate is a node condition or taint that must be satisfied before a node is considered initialized
+type ReadinessGate struct {
+	// ConditionType is the type of a node condition that must have the expected Status
+	// +optional
+	ConditionType v1.NodeConditionType `json:"conditionType,omitempty"`
+	// Status is the status that the node condition must have. Defaults to True.
+	// +kubebuilder:validation:Enum:={True,False,Unknown}
+	// +optional
+	Status v1.ConditionStatus `json:"status,omitempty"`
+	// TaintKey is the key of a taint that must be removed from the node, regardless of its value or effect
+	// +optional
+	TaintKey string `json:"taintKey,omitempty"`
+}
+
+// IsSatisfied returns true if the node has the condition with the expected status and doesn't have the taint
+func (in ReadinessGate) IsSatisfied(node *v1.Node) bool {
+	if in.ConditionType != "" {
+		status := lo.Ternary(in.Status == "", v1.ConditionTrue, in.Status)
+		// a condition that hasn't been reported yet is treated as Unknown
+		condition, _ := lo.Find(node.Status.Conditions, func(c v1.NodeCondition) bool { return c.Type == in.ConditionType })
+		if lo.Ternary(condition.Status == "", v1.ConditionUnknown, condition.Status) != status {
+			return false
+		}
+	}
+	if in.TaintKey != "" {
+		if lo.ContainsBy(node.Spec.Taints, func(t v1.Taint) bool { return t.Key == in.TaintKey }) {
+			return false
+		}
+	}
+	return true
+}
+
 type NodeClaimTemplate struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec              NodeClaimSpec `json:"spec,omitempty"`
@@ -129,6 +322,29 @@ func (in *NodePool) Hash() string {
 	})))
 }
 
+// AllowedDisruptions returns the number of nodes that can be voluntarily disrupted at once given the number of nodes
+// owned by the NodePool. When multiple budgets are active, the most restrictive one is used. Disruptions aren't
+// limited if no budgets are active.
+func (in *NodePool) AllowedDisruptions(clk clock.Clock, numNodes int) (int, error) {
+	allowed := math.MaxInt32
+	for _, budget := range in.Spec.Deprovisioning.Budgets {
+		active, err := budget.IsActive(clk)
+		if err != nil {
+			return 0, err
+		}
+		if !active {
+			continue
+		}
+		nodes := intstr.Parse(budget.Nodes)
+		n, err := intstr.GetScaledValueFromIntOrPercent(&nodes, numNodes, true)
+		if err != nil {
+			return 0, fmt.Errorf("parsing budget nodes %q, %w", budget.Nodes, err)
+		}
+		allowed = lo.Min([]int{allowed, n})
+	}
+	return allowed, nil
+}
+
 // NodePoolList contains a list of NodePool
 // +kubebuilder:object:root=true
 type NodePoolList struct {
@@ -23,4 +23,17 @@ type NodePoolStatus struct {
 	// Resources is the list of resources that have been provisioned.
 	// +optional
 	Resources v1.ResourceList `json:"resources,omitempty"`
+	// ScopedResources is the list of resources that have been provisioned into each scope of the scoped limits.
+	// +optional
+	ScopedResources []ScopedResources `json:"scopedResources,omitempty"`
+}
+
+// ScopedResources is the list of resources that have been provisioned into a zone and/or capacity type
+type ScopedResources struct {
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// +optional
+	CapacityType string `json:"capacityType,omitempty"`
+	// Resources is the list of resources that have been provisioned into the scope.
+	Resources v1.ResourceList `json:"resources,omitempty"`
 }
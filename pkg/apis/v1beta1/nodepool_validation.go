@@ -18,11 +18,19 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/samber/lo"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
+
+	"github.com/aws/karpenter-core/pkg/utils/cron"
 )
 
+var deprovisioningMethods = sets.New("repair", "expiration", "drift", "emptiness", "consolidation")
+
 func (in *NodePool) SupportedVerbs() []admissionregistrationv1.OperationType {
 	return []admissionregistrationv1.OperationType{
 		admissionregistrationv1.Create,
@@ -38,12 +46,41 @@ func (in *NodePool) Validate(_ context.Context) (errs *apis.FieldError) {
 }
 
 func (in *NodePoolSpec) validate() (errs *apis.FieldError) {
+	if in.RegistrationTTL != nil && in.RegistrationTTL.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue("must be positive", "registrationTTL"))
+	}
+	for i := range in.ReadinessGates {
+		errs = errs.Also(in.ReadinessGates[i].validate().ViaFieldIndex("readinessGates", i))
+	}
 	return errs.Also(
 		in.Template.validate().ViaField("template"),
 		in.Deprovisioning.validate().ViaField("deprovisioning"),
 	)
 }
 
+func (in *ReadinessGate) validate() (errs *apis.FieldError) {
+	switch {
+	case in.ConditionType == "" && in.TaintKey == "":
+		return errs.Also(apis.ErrMissingOneOf("conditionType", "taintKey"))
+	case in.ConditionType != "" && in.TaintKey != "":
+		return errs.Also(apis.ErrMultipleOneOf("conditionType", "taintKey"))
+	}
+	if in.TaintKey != "" {
+		if in.Status != "" {
+			errs = errs.Also(apis.ErrDisallowedFields("status"))
+		}
+		for _, err := range validation.IsQualifiedName(in.TaintKey) {
+			errs = errs.Also(apis.ErrInvalidValue(err, "taintKey"))
+		}
+	}
+	switch in.Status {
+	case v1.ConditionTrue, v1.ConditionFalse, v1.ConditionUnknown, "":
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(in.Status, "status"))
+	}
+	return errs
+}
+
 func (in *NodeClaimTemplate) validate() (errs *apis.FieldError) {
 	if len(in.Spec.Resources.Requests) > 0 {
 		errs = errs.Also(apis.ErrDisallowedFields("resources.requests"))
@@ -79,5 +116,85 @@ func (in *Deprovisioning) validate() (errs *apis.FieldError) {
 	if in.ConsolidationTTL.Duration < 0 {
 		return errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidationTTL"))
 	}
+	if in.ConsolidateAfter != nil && in.ConsolidateAfter.Duration < 0 {
+		return errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidateAfter"))
+	}
+	// Empty nodes are deprovisioned after the consolidationTTL, so consolidateAfter would be silently ignored
+	if in.ConsolidateAfter != nil && in.ConsolidationPolicy == ConsolidationPolicyWhenEmpty {
+		return errs.Also(apis.ErrGeneric("consolidateAfter can't be set when consolidationPolicy is WhenEmpty", "consolidateAfter"))
+	}
+	if in.ConsolidationMinSavingsPercent != nil && (*in.ConsolidationMinSavingsPercent < 0 || *in.ConsolidationMinSavingsPercent > 99) {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(*in.ConsolidationMinSavingsPercent, 0, 99, "consolidationMinSavingsPercent"))
+	}
+	errs = errs.Also(in.validateMaxNodeLifetime())
+	if in.DrainTimeout != nil && in.DrainTimeout.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "drainTimeout"))
+	}
+	if in.NoExecuteFallback != nil && in.DrainTimeout == nil {
+		errs = errs.Also(apis.ErrGeneric("expected drainTimeout to be set", "noExecuteFallback"))
+	}
+	if in.ForceEvictAfter != nil && in.ForceEvictAfter.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "forceEvictAfter"))
+	}
+	if in.ConsolidationMinSavingsPerHour != nil && in.ConsolidationMinSavingsPerHour.Sign() < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "consolidationMinSavingsPerHour"))
+	}
+	for i := range in.Budgets {
+		errs = errs.Also(in.Budgets[i].validate().ViaFieldIndex("budgets", i))
+	}
+	if in.DriftRateLimit != nil {
+		errs = errs.Also(in.DriftRateLimit.validate().ViaField("driftRateLimit"))
+	}
+	for i, method := range in.Order {
+		if !deprovisioningMethods.Has(method) {
+			errs = errs.Also(apis.ErrInvalidArrayValue(method, "order", i))
+		}
+		if lo.Contains(in.Order[:i], method) {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("duplicate method %q", method), "order"))
+		}
+	}
+	if in.ExcludedNodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(in.ExcludedNodeSelector); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(err.Error(), "excludedNodeSelector"))
+		}
+	}
+	return errs
+}
+
+func (in *Deprovisioning) validateMaxNodeLifetime() (errs *apis.FieldError) {
+	if in.MaxNodeLifetime != nil && in.MaxNodeLifetime.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "maxNodeLifetime"))
+	}
+	if in.ForceExpirationGracePeriod != nil && in.ForceExpirationGracePeriod.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue("cannot be negative", "forceExpirationGracePeriod"))
+	}
+	if in.ForceExpirationGracePeriod != nil && in.MaxNodeLifetime == nil {
+		errs = errs.Also(apis.ErrGeneric("expected maxNodeLifetime to be set", "forceExpirationGracePeriod"))
+	}
+	return errs
+}
+
+func (in *DriftRateLimit) validate() (errs *apis.FieldError) {
+	if in.Nodes < 1 {
+		errs = errs.Also(apis.ErrInvalidValue("must be positive", "nodes"))
+	}
+	if in.Interval.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue("must be positive", "interval"))
+	}
+	return errs
+}
+
+func (in *Budget) validate() (errs *apis.FieldError) {
+	if (in.Schedule == nil) != (in.Duration == nil) {
+		return errs.Also(apis.ErrGeneric("expected schedule and duration to both be set or both be unset", "schedule", "duration"))
+	}
+	if in.Schedule != nil {
+		if _, err := cron.Parse(*in.Schedule); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(*in.Schedule, "schedule", err.Error()))
+		}
+	}
+	if in.Duration != nil && in.Duration.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue("must be positive", "duration"))
+	}
 	return errs
 }
@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	clock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/ptr"
 )
 
@@ -49,6 +50,29 @@ var _ = Describe("Validation", func() {
 		}
 	})
 
+	It("should fail on a non-positive registration ttl", func() {
+		nodePool.Spec.RegistrationTTL = &metav1.Duration{Duration: 0}
+		Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+	})
+	It("should succeed on a valid registration ttl", func() {
+		nodePool.Spec.RegistrationTTL = &metav1.Duration{Duration: 30 * time.Minute}
+		Expect(nodePool.Validate(ctx)).To(Succeed())
+	})
+	It("should succeed on valid readiness gates", func() {
+		nodePool.Spec.ReadinessGates = []ReadinessGate{
+			{ConditionType: "NetworkAvailable"},
+			{TaintKey: "cilium.io/agent-not-ready"},
+		}
+		Expect(nodePool.Validate(ctx)).To(Succeed())
+	})
+	It("should fail on invalid readiness gates", func() {
+		nodePool.Spec.ReadinessGates = []ReadinessGate{{}}
+		Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		nodePool.Spec.ReadinessGates = []ReadinessGate{{ConditionType: "NetworkAvailable", TaintKey: "cilium.io/agent-not-ready"}}
+		Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		nodePool.Spec.ReadinessGates = []ReadinessGate{{TaintKey: "cilium.io/agent-not-ready", Status: v1.ConditionTrue}}
+		Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+	})
 	Context("Deprovisioning", func() {
 		It("should fail on negative expiry ttl", func() {
 			nodePool.Spec.Deprovisioning.ExpirationTTL.Duration = lo.Must(time.ParseDuration("-1s"))
@@ -76,6 +100,125 @@ var _ = Describe("Validation", func() {
 			nodePool.Spec.Deprovisioning.ConsolidationTTL.Duration = lo.Must(time.ParseDuration("30s"))
 			Expect(nodePool.Validate(ctx)).To(Succeed())
 		})
+		It("should fail on negative max node lifetime", func() {
+			nodePool.Spec.Deprovisioning.MaxNodeLifetime = &metav1.Duration{Duration: -time.Second}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on negative force expiration grace period", func() {
+			nodePool.Spec.Deprovisioning.MaxNodeLifetime = &metav1.Duration{Duration: time.Hour}
+			nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod = &metav1.Duration{Duration: -time.Second}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on a force expiration grace period without a max node lifetime", func() {
+			nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod = &metav1.Duration{Duration: time.Hour}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should succeed on a max node lifetime with a force expiration grace period", func() {
+			nodePool.Spec.Deprovisioning.MaxNodeLifetime = &metav1.Duration{Duration: time.Hour}
+			nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod = &metav1.Duration{Duration: 10 * time.Minute}
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on negative drain timeout", func() {
+			nodePool.Spec.Deprovisioning.DrainTimeout = &metav1.Duration{Duration: -time.Second}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should succeed on a valid drain timeout", func() {
+			nodePool.Spec.Deprovisioning.DrainTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on the NoExecute fallback without a drain timeout", func() {
+			nodePool.Spec.Deprovisioning.NoExecuteFallback = lo.ToPtr(true)
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should succeed on the NoExecute fallback with a drain timeout", func() {
+			nodePool.Spec.Deprovisioning.DrainTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+			nodePool.Spec.Deprovisioning.NoExecuteFallback = lo.ToPtr(true)
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on negative force evict after", func() {
+			nodePool.Spec.Deprovisioning.ForceEvictAfter = &metav1.Duration{Duration: -time.Second}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should succeed on a budget with a schedule and duration", func() {
+			nodePool.Spec.Deprovisioning.Budgets = []Budget{{Nodes: "0", Schedule: lo.ToPtr("0 9 * * 1-5"), Duration: &metav1.Duration{Duration: 8 * time.Hour}}}
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on a budget with a schedule but no duration", func() {
+			nodePool.Spec.Deprovisioning.Budgets = []Budget{{Nodes: "0", Schedule: lo.ToPtr("@daily")}}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on a budget with a duration but no schedule", func() {
+			nodePool.Spec.Deprovisioning.Budgets = []Budget{{Nodes: "0", Duration: &metav1.Duration{Duration: time.Hour}}}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on a budget with an invalid schedule", func() {
+			nodePool.Spec.Deprovisioning.Budgets = []Budget{{Nodes: "0", Schedule: lo.ToPtr("0 25 * * *"), Duration: &metav1.Duration{Duration: time.Hour}}}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on a negative consolidateAfter", func() {
+			nodePool.Spec.Deprovisioning.ConsolidateAfter = &metav1.Duration{Duration: -time.Second}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on consolidateAfter with the WhenEmpty consolidation policy", func() {
+			nodePool.Spec.Deprovisioning.ConsolidateAfter = &metav1.Duration{Duration: time.Minute}
+			nodePool.Spec.Deprovisioning.ConsolidationPolicy = ConsolidationPolicyWhenEmpty
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+			nodePool.Spec.Deprovisioning.ConsolidationPolicy = ConsolidationPolicyWhenUnderutilized
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on consolidation minimum savings that are out of range", func() {
+			nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent = lo.ToPtr[int32](100)
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+			nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent = nil
+			nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPerHour = lo.ToPtr(resource.MustParse("-0.01"))
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should succeed on a valid deprovisioning order", func() {
+			nodePool.Spec.Deprovisioning.Order = []string{"drift", "expiration"}
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on a deprovisioning order with unknown or duplicate methods", func() {
+			nodePool.Spec.Deprovisioning.Order = []string{"drift", "replacement"}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+			nodePool.Spec.Deprovisioning.Order = []string{"drift", "drift"}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail on an invalid excluded node selector", func() {
+			nodePool.Spec.Deprovisioning.ExcludedNodeSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Matches"}},
+			}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should succeed on a valid drift rate limit", func() {
+			nodePool.Spec.Deprovisioning.DriftRateLimit = &DriftRateLimit{Nodes: 5, Interval: metav1.Duration{Duration: 10 * time.Minute}}
+			Expect(nodePool.Validate(ctx)).To(Succeed())
+		})
+		It("should fail on a drift rate limit without a positive interval", func() {
+			nodePool.Spec.Deprovisioning.DriftRateLimit = &DriftRateLimit{Nodes: 5}
+			Expect(nodePool.Validate(ctx)).ToNot(Succeed())
+		})
+	})
+	Context("AllowedDisruptions", func() {
+		var fakeClock *clock.FakeClock
+		BeforeEach(func() {
+			fakeClock = clock.NewFakeClock(time.Date(2023, time.August, 16, 10, 0, 0, 0, time.UTC))
+		})
+		It("should not limit disruptions without budgets", func() {
+			Expect(nodePool.AllowedDisruptions(fakeClock, 10)).To(BeNumerically(">", 10))
+		})
+		It("should use the most restrictive budget", func() {
+			nodePool.Spec.Deprovisioning.Budgets = []Budget{{Nodes: "50%"}, {Nodes: "3"}, {Nodes: "20%"}}
+			Expect(nodePool.AllowedDisruptions(fakeClock, 10)).To(Equal(2))
+		})
+		It("should only use budgets whose schedule is active", func() {
+			nodePool.Spec.Deprovisioning.Budgets = []Budget{
+				{Nodes: "5"},
+				{Nodes: "0", Schedule: lo.ToPtr("0 9 * * *"), Duration: &metav1.Duration{Duration: 2 * time.Hour}},
+			}
+			Expect(nodePool.AllowedDisruptions(fakeClock, 10)).To(Equal(0))
+			fakeClock.Step(2 * time.Hour)
+			Expect(nodePool.AllowedDisruptions(fakeClock, 10)).To(Equal(5))
+		})
 	})
 	Context("Limits", func() {
 		It("should allow undefined limits", func() {
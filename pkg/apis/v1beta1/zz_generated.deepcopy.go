@@ -22,14 +22,112 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/apis"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Budget) DeepCopyInto(out *Budget) {
+	*out = *in
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(string)
+		**out = **in
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Budget.
+func (in *Budget) DeepCopy() *Budget {
+	if in == nil {
+		return nil
+	}
+	out := new(Budget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Deprovisioning) DeepCopyInto(out *Deprovisioning) {
 	*out = *in
 	out.ConsolidationTTL = in.ConsolidationTTL
+	if in.ConsolidateAfter != nil {
+		in, out := &in.ConsolidateAfter, &out.ConsolidateAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ConsolidationMinSavingsPercent != nil {
+		in, out := &in.ConsolidationMinSavingsPercent, &out.ConsolidationMinSavingsPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConsolidationMinSavingsPerHour != nil {
+		in, out := &in.ConsolidationMinSavingsPerHour, &out.ConsolidationMinSavingsPerHour
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	out.ExpirationTTL = in.ExpirationTTL
+	if in.ExpirationJitter != nil {
+		in, out := &in.ExpirationJitter, &out.ExpirationJitter
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxNodeLifetime != nil {
+		in, out := &in.MaxNodeLifetime, &out.MaxNodeLifetime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ForceExpirationGracePeriod != nil {
+		in, out := &in.ForceExpirationGracePeriod, &out.ForceExpirationGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DrainTimeout != nil {
+		in, out := &in.DrainTimeout, &out.DrainTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.NoExecuteFallback != nil {
+		in, out := &in.NoExecuteFallback, &out.NoExecuteFallback
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ForceEvictAfter != nil {
+		in, out := &in.ForceEvictAfter, &out.ForceEvictAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DriftEnabled != nil {
+		in, out := &in.DriftEnabled, &out.DriftEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DriftRateLimit != nil {
+		in, out := &in.DriftRateLimit, &out.DriftRateLimit
+		*out = new(DriftRateLimit)
+		**out = **in
+	}
+	if in.Budgets != nil {
+		in, out := &in.Budgets, &out.Budgets
+		*out = make([]Budget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludedNodeSelector != nil {
+		in, out := &in.ExcludedNodeSelector, &out.ExcludedNodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Deprovisioning.
@@ -42,6 +140,22 @@ func (in *Deprovisioning) DeepCopy() *Deprovisioning {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftRateLimit) DeepCopyInto(out *DriftRateLimit) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftRateLimit.
+func (in *DriftRateLimit) DeepCopy() *DriftRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeletConfiguration) DeepCopyInto(out *KubeletConfiguration) {
 	*out = *in
@@ -236,6 +350,13 @@ func (in *NodeClaimSpec) DeepCopyInto(out *NodeClaimSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Preferences != nil {
+		in, out := &in.Preferences, &out.Preferences
+		*out = make([]v1.PreferredSchedulingTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.KubeletConfiguration != nil {
 		in, out := &in.KubeletConfiguration, &out.KubeletConfiguration
@@ -288,6 +409,10 @@ func (in *NodeClaimStatus) DeepCopyInto(out *NodeClaimStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeClaimStatus.
@@ -395,7 +520,7 @@ func (in *NodePoolList) DeepCopyObject() runtime.Object {
 func (in *NodePoolSpec) DeepCopyInto(out *NodePoolSpec) {
 	*out = *in
 	in.Template.DeepCopyInto(&out.Template)
-	out.Deprovisioning = in.Deprovisioning
+	in.Deprovisioning.DeepCopyInto(&out.Deprovisioning)
 	if in.Limits != nil {
 		in, out := &in.Limits, &out.Limits
 		*out = make(Limits, len(*in))
@@ -403,11 +528,33 @@ func (in *NodePoolSpec) DeepCopyInto(out *NodePoolSpec) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.ScopedLimits != nil {
+		in, out := &in.ScopedLimits, &out.ScopedLimits
+		*out = make([]ScopedLimit, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Weight != nil {
 		in, out := &in.Weight, &out.Weight
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RegistrationTTL != nil {
+		in, out := &in.RegistrationTTL, &out.RegistrationTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGate, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolSpec.
@@ -430,6 +577,13 @@ func (in *NodePoolStatus) DeepCopyInto(out *NodePoolStatus) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.ScopedResources != nil {
+		in, out := &in.ScopedResources, &out.ScopedResources
+		*out = make([]ScopedResources, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolStatus.
@@ -442,6 +596,21 @@ func (in *NodePoolStatus) DeepCopy() *NodePoolStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceRequirements) DeepCopyInto(out *ResourceRequirements) {
 	*out = *in
@@ -463,3 +632,47 @@ func (in *ResourceRequirements) DeepCopy() *ResourceRequirements {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScopedLimit) DeepCopyInto(out *ScopedLimit) {
+	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make(Limits, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScopedLimit.
+func (in *ScopedLimit) DeepCopy() *ScopedLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ScopedLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScopedResources) DeepCopyInto(out *ScopedResources) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScopedResources.
+func (in *ScopedResources) DeepCopy() *ScopedResources {
+	if in == nil {
+		return nil
+	}
+	out := new(ScopedResources)
+	in.DeepCopyInto(out)
+	return out
+}
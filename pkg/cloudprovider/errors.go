@@ -0,0 +1,241 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error classes describe the well-known errors returned by CloudProviders. They are surfaced as the reason of
+// NodeClaim conditions and as the error label of metrics.
+const (
+	MachineNotFoundErrorClass      = "MachineNotFoundError"
+	InsufficientCapacityErrorClass = "InsufficientCapacityError"
+	QuotaExceededErrorClass        = "QuotaExceededError"
+	RateLimitedErrorClass          = "RateLimitedError"
+	InvalidTemplateErrorClass      = "InvalidTemplateError"
+	UnauthorizedErrorClass         = "UnauthorizedError"
+)
+
+// ErrorClass returns the class of a well-known CloudProvider error or an empty string if the error isn't well-known
+func ErrorClass(err error) string {
+	switch {
+	case IsMachineNotFoundError(err):
+		return MachineNotFoundErrorClass
+	case IsInsufficientCapacityError(err):
+		return InsufficientCapacityErrorClass
+	case IsQuotaExceededError(err):
+		return QuotaExceededErrorClass
+	case IsRateLimitedError(err):
+		return RateLimitedErrorClass
+	case IsInvalidTemplateError(err):
+		return InvalidTemplateErrorClass
+	case IsUnauthorizedError(err):
+		return UnauthorizedErrorClass
+	default:
+		return ""
+	}
+}
+
+// IsTerminalError returns true if retrying the call that returned the error can't succeed until the configuration
+// of the cluster or the cloud account changes
+func IsTerminalError(err error) bool {
+	return IsInvalidTemplateError(err) || IsUnauthorizedError(err)
+}
+
+// MachineNotFoundError is an error type returned by CloudProviders when the reason for failure is NotFound
+type MachineNotFoundError struct {
+	error
+}
+
+func NewMachineNotFoundError(err error) *MachineNotFoundError {
+	return &MachineNotFoundError{
+		error: err,
+	}
+}
+
+func (e *MachineNotFoundError) Error() string {
+	return fmt.Sprintf("machine not found, %s", e.error)
+}
+
+func IsMachineNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var mnfErr *MachineNotFoundError
+	return errors.As(err, &mnfErr)
+}
+
+func IgnoreMachineNotFoundError(err error) error {
+	if IsMachineNotFoundError(err) {
+		return nil
+	}
+	return err
+}
+
+// UnavailableOffering identifies an offering of an instance type that ran out of capacity
+type UnavailableOffering struct {
+	InstanceType string
+	Zone         string
+	CapacityType string
+}
+
+// InsufficientCapacityError is an error type returned by CloudProviders when a launch fails due to a lack of capacity from machine requirements
+type InsufficientCapacityError struct {
+	error
+	// Offerings are the offerings that ran out of capacity, if the CloudProvider knows them
+	Offerings []UnavailableOffering
+}
+
+func NewInsufficientCapacityError(err error, offerings ...UnavailableOffering) *InsufficientCapacityError {
+	return &InsufficientCapacityError{
+		error:     err,
+		Offerings: offerings,
+	}
+}
+
+func (e *InsufficientCapacityError) Error() string {
+	return fmt.Sprintf("insufficient capacity, %s", e.error)
+}
+
+func IsInsufficientCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var icErr *InsufficientCapacityError
+	return errors.As(err, &icErr)
+}
+
+func IgnoreInsufficientCapacityError(err error) error {
+	if IsInsufficientCapacityError(err) {
+		return nil
+	}
+	return err
+}
+
+// QuotaExceededError is an error type returned by CloudProviders when a launch fails because it would exceed a quota
+// of the cloud account
+type QuotaExceededError struct {
+	error
+}
+
+func NewQuotaExceededError(err error) *QuotaExceededError {
+	return &QuotaExceededError{
+		error: err,
+	}
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded, %s", e.error)
+}
+
+func IsQuotaExceededError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var qeErr *QuotaExceededError
+	return errors.As(err, &qeErr)
+}
+
+// RateLimitedError is an error type returned by CloudProviders when a call is throttled by the cloud provider API
+type RateLimitedError struct {
+	error
+	// RetryAfter is how long the cloud provider API asked callers to wait before retrying, if it's known
+	RetryAfter time.Duration
+}
+
+func NewRateLimitedError(err error) *RateLimitedError {
+	return &RateLimitedError{
+		error: err,
+	}
+}
+
+func NewRateLimitedErrorWithRetryAfter(err error, retryAfter time.Duration) *RateLimitedError {
+	return &RateLimitedError{
+		error:      err,
+		RetryAfter: retryAfter,
+	}
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, %s", e.error)
+}
+
+func IsRateLimitedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rlErr *RateLimitedError
+	return errors.As(err, &rlErr)
+}
+
+// RetryAfter returns how long to wait before retrying a call that was rate limited, or zero if it isn't known
+func RetryAfter(err error) time.Duration {
+	var rlErr *RateLimitedError
+	if !errors.As(err, &rlErr) {
+		return 0
+	}
+	return rlErr.RetryAfter
+}
+
+// InvalidTemplateError is an error type returned by CloudProviders when the configuration that a machine is launched
+// from (e.g. the node template) is invalid
+type InvalidTemplateError struct {
+	error
+}
+
+func NewInvalidTemplateError(err error) *InvalidTemplateError {
+	return &InvalidTemplateError{
+		error: err,
+	}
+}
+
+func (e *InvalidTemplateError) Error() string {
+	return fmt.Sprintf("invalid template, %s", e.error)
+}
+
+func IsInvalidTemplateError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var itErr *InvalidTemplateError
+	return errors.As(err, &itErr)
+}
+
+// UnauthorizedError is an error type returned by CloudProviders when the credentials used to call the cloud provider
+// API aren't allowed to perform the call
+type UnauthorizedError struct {
+	error
+}
+
+func NewUnauthorizedError(err error) *UnauthorizedError {
+	return &UnauthorizedError{
+		error: err,
+	}
+}
+
+func (e *UnauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized, %s", e.error)
+}
+
+func IsUnauthorizedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var uaErr *UnauthorizedError
+	return errors.As(err, &uaErr)
+}
@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+var _ cloudprovider.BatchCreator = (*BatchCloudProvider)(nil)
+
+// BatchCloudProvider is a fake CloudProvider that launches machines with BatchCreate, it's kept separate from the
+// CloudProvider so that only the tests that use it batch their launches
+type BatchCloudProvider struct {
+	*CloudProvider
+
+	batchMu sync.Mutex
+	// BatchCreateCalls contains the machines of every BatchCreate call that was made since it was cleared
+	BatchCreateCalls [][]*v1alpha5.Machine
+	// NextBatchCreateErrs are returned for the machines at the same index of the next BatchCreate call instead of
+	// launching them, so that a batch partially fails
+	NextBatchCreateErrs []error
+}
+
+func NewBatchCloudProvider() *BatchCloudProvider {
+	return &BatchCloudProvider{CloudProvider: NewCloudProvider()}
+}
+
+// Reset is for BeforeEach calls in testing to reset the tracking of BatchCreateCalls
+func (c *BatchCloudProvider) Reset() {
+	c.CloudProvider.Reset()
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	c.BatchCreateCalls = nil
+	c.NextBatchCreateErrs = nil
+}
+
+func (c *BatchCloudProvider) BatchCreate(ctx context.Context, machines []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	c.batchMu.Lock()
+	c.BatchCreateCalls = append(c.BatchCreateCalls, machines)
+	injected := c.NextBatchCreateErrs
+	c.NextBatchCreateErrs = nil
+	c.batchMu.Unlock()
+
+	created := make([]*v1alpha5.Machine, len(machines))
+	errs := make([]error, len(machines))
+	for i := range machines {
+		if i < len(injected) && injected[i] != nil {
+			errs[i] = injected[i]
+			continue
+		}
+		created[i], errs[i] = c.CloudProvider.Create(ctx, machines[i])
+	}
+	return created, errs
+}
@@ -18,27 +18,35 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"sort"
 	"sync"
+	"time"
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/test"
 	"github.com/aws/karpenter-core/pkg/utils/functional"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 	"github.com/aws/karpenter-core/pkg/utils/resources"
 )
 
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
+var _ cloudprovider.InterruptionSource = (*CloudProvider)(nil)
+var _ cloudprovider.Pricing = (*CloudProvider)(nil)
+var _ cloudprovider.HealthChecker = (*CloudProvider)(nil)
+var _ cloudprovider.MachineWatcher = (*CloudProvider)(nil)
 
 type CloudProvider struct {
 	InstanceTypes []*cloudprovider.InstanceType
+	// ErrorsForProvisioner contains the errors returned by GetInstanceTypes for the provisioners with these names
+	ErrorsForProvisioner map[string]error
 
 	mu sync.RWMutex
 	// CreateCalls contains the arguments for every create call that was made since it was cleared
@@ -46,15 +54,51 @@ type CloudProvider struct {
 	AllowedCreateCalls int
 	NextCreateErr      error
 	DeleteCalls        []*v1alpha5.Machine
+	// DeleteErr is returned by every Delete call while it's set
+	DeleteErr error
+	// ReadyErr is returned by Ready while it's set
+	ReadyErr error
+	// InsufficientCapacityOfferings are the offerings that have run out of capacity. Create doesn't launch into them,
+	// and returns an InsufficientCapacityError that names them if no other offering satisfies the machine.
+	InsufficientCapacityOfferings []cloudprovider.UnavailableOffering
+	// ThrottledCalls is the number of upcoming Create and Delete calls that are rate limited with ThrottleRetryAfter
+	ThrottledCalls     int
+	ThrottleRetryAfter time.Duration
+	// CreateLatency is how long every Create call takes before the machine is launched
+	CreateLatency time.Duration
+	// TerminationDelay is how long deleted machines keep existing before they're terminated. Until then they're
+	// returned by Get and List with a deletion timestamp.
+	TerminationDelay time.Duration
+	// Clock measures the CreateLatency and TerminationDelay, the real clock is used if it isn't set
+	Clock clock.Clock
+	// terminating maps the provider ids of deleted machines to when they're terminated
+	terminating map[string]time.Time
 
 	CreatedMachines map[string]*v1alpha5.Machine
-	Drifted         cloudprovider.DriftReason
+	// ProviderIDFormat converts provider IDs to the format that CreatedMachines is keyed by, it's used to simulate a
+	// CloudProvider with more than one provider ID format
+	ProviderIDFormat func(string) string
+	// OnDemandPrices and SpotPrices are the prices returned by Pricing keyed by instance type name, instance types
+	// without a price fall back to the prices of their offerings
+	OnDemandPrices map[string]float64
+	SpotPrices     map[string]float64
+	Drifted        cloudprovider.DriftReason
+	// Interruptions is the channel returned to consumers of InterruptionMessages, tests send messages to it
+	Interruptions chan cloudprovider.InterruptionMessage
+	// MachineEvents is the channel returned to consumers of WatchMachines, tests send events to it
+	MachineEvents chan cloudprovider.MachineEvent
 }
 
 func NewCloudProvider() *CloudProvider {
 	return &CloudProvider{
-		AllowedCreateCalls: math.MaxInt,
-		CreatedMachines:    map[string]*v1alpha5.Machine{},
+		AllowedCreateCalls:   math.MaxInt,
+		CreatedMachines:      map[string]*v1alpha5.Machine{},
+		ErrorsForProvisioner: map[string]error{},
+		OnDemandPrices:       map[string]float64{},
+		SpotPrices:           map[string]float64{},
+		Interruptions:        make(chan cloudprovider.InterruptionMessage, 100),
+		MachineEvents:        make(chan cloudprovider.MachineEvent, 100),
+		terminating:          map[string]time.Time{},
 	}
 }
 
@@ -66,11 +110,41 @@ func (c *CloudProvider) Reset() {
 	c.CreatedMachines = map[string]*v1alpha5.Machine{}
 	c.AllowedCreateCalls = math.MaxInt
 	c.NextCreateErr = nil
+	c.ErrorsForProvisioner = map[string]error{}
 	c.DeleteCalls = []*v1alpha5.Machine{}
+	c.DeleteErr = nil
+	c.ReadyErr = nil
+	c.InsufficientCapacityOfferings = nil
+	c.ThrottledCalls = 0
+	c.ThrottleRetryAfter = 0
+	c.CreateLatency = 0
+	c.TerminationDelay = 0
+	c.terminating = map[string]time.Time{}
+	c.ProviderIDFormat = nil
+	c.OnDemandPrices = map[string]float64{}
+	c.SpotPrices = map[string]float64{}
 	c.Drifted = "drifted"
+	// drain any interruption messages that weren't consumed, the channel is kept since consumers hold onto it
+	for len(c.Interruptions) > 0 {
+		<-c.Interruptions
+	}
+	for len(c.MachineEvents) > 0 {
+		<-c.MachineEvents
+	}
 }
 
 func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (*v1alpha5.Machine, error) {
+	c.mu.RLock()
+	latency, clk := c.CreateLatency, c.clock()
+	c.mu.RUnlock()
+	// the lock isn't held while waiting so that slow launches don't block other calls
+	if latency > 0 {
+		select {
+		case <-clk.After(latency):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -79,24 +153,37 @@ func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (
 		c.NextCreateErr = nil
 		return nil, temp
 	}
+	if err := c.throttle(); err != nil {
+		return nil, err
+	}
 
 	c.CreateCalls = append(c.CreateCalls, machine)
 	if len(c.CreateCalls) > c.AllowedCreateCalls {
 		return &v1alpha5.Machine{}, fmt.Errorf("erroring as number of AllowedCreateCalls has been exceeded")
 	}
 	reqs := scheduling.NewNodeSelectorRequirements(machine.Spec.Requirements...)
-	instanceTypes := lo.Filter(lo.Must(c.GetInstanceTypes(ctx, nil)), func(i *cloudprovider.InstanceType, _ int) bool {
+	kubelet := nodepoolutil.NewKubeletConfiguration(machine.Spec.Kubelet)
+	compatible := lo.Filter(lo.Must(c.GetInstanceTypes(ctx, nil)), func(i *cloudprovider.InstanceType, _ int) bool {
 		return reqs.Compatible(i.Requirements) == nil &&
 			len(i.Offerings.Requirements(reqs).Available()) > 0 &&
-			resources.Fits(machine.Spec.Resources.Requests, i.Allocatable())
+			resources.Fits(machine.Spec.Resources.Requests, i.AllocatableWithKubelet(kubelet))
 	})
-	// Order instance types so that we get the cheapest instance types of the available offerings
-	sort.Slice(instanceTypes, func(i, j int) bool {
-		iOfferings := instanceTypes[i].Offerings.Available().Requirements(reqs)
-		jOfferings := instanceTypes[j].Offerings.Available().Requirements(reqs)
-		return iOfferings.Cheapest().Price < jOfferings.Cheapest().Price
+	instanceTypes := lo.Filter(compatible, func(i *cloudprovider.InstanceType, _ int) bool {
+		return lo.ContainsBy(i.Offerings.Requirements(reqs).Available(), func(o cloudprovider.Offering) bool {
+			return !c.insufficientCapacity(i.Name, o)
+		})
 	})
-	instanceType := instanceTypes[0]
+	if len(compatible) > 0 && len(instanceTypes) == 0 {
+		var unavailable []cloudprovider.UnavailableOffering
+		for _, it := range compatible {
+			for _, o := range it.Offerings.Requirements(reqs).Available() {
+				unavailable = append(unavailable, cloudprovider.UnavailableOffering{InstanceType: it.Name, Zone: o.Zone, CapacityType: o.CapacityType})
+			}
+		}
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all requested offerings are out of capacity"), unavailable...)
+	}
+	// Order instance types so that we get the most preferred and then cheapest instance types of the available offerings
+	instanceType := cloudprovider.InstanceTypes(instanceTypes).OrderByPreference(machine.Spec.Preferences, reqs)[0]
 	// Labels
 	labels := map[string]string{}
 	for key, requirement := range instanceType.Requirements {
@@ -104,8 +191,12 @@ func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (
 			labels[key] = requirement.Values()[0]
 		}
 	}
-	// Find Offering
-	for _, o := range instanceType.Offerings.Available() {
+	// Find Offering, preferring reserved capacity
+	offerings := instanceType.Offerings.Available()
+	for _, o := range append(offerings.Reserved(), offerings...) {
+		if c.insufficientCapacity(instanceType.Name, o) {
+			continue
+		}
 		if reqs.Compatible(scheduling.NewRequirements(
 			scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, o.Zone),
 			scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, o.CapacityType),
@@ -125,7 +216,7 @@ func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (
 		Status: v1alpha5.MachineStatus{
 			ProviderID:  test.RandomProviderID(),
 			Capacity:    functional.FilterMap(instanceType.Capacity, func(_ v1.ResourceName, v resource.Quantity) bool { return !resources.IsZero(v) }),
-			Allocatable: functional.FilterMap(instanceType.Allocatable(), func(_ v1.ResourceName, v resource.Quantity) bool { return !resources.IsZero(v) }),
+			Allocatable: functional.FilterMap(instanceType.AllocatableWithKubelet(kubelet), func(_ v1.ResourceName, v resource.Quantity) bool { return !resources.IsZero(v) }),
 		},
 	}
 	c.CreatedMachines[created.Status.ProviderID] = created
@@ -133,25 +224,32 @@ func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (
 }
 
 func (c *CloudProvider) Get(_ context.Context, id string) (*v1alpha5.Machine, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if machine, ok := c.CreatedMachines[id]; ok {
+	c.terminate()
+	if machine, ok := c.CreatedMachines[c.normalizeProviderID(id)]; ok {
 		return machine.DeepCopy(), nil
 	}
 	return nil, cloudprovider.NewMachineNotFoundError(fmt.Errorf("no machine exists with id '%s'", id))
 }
 
 func (c *CloudProvider) List(_ context.Context) ([]*v1alpha5.Machine, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
+	c.terminate()
 	return lo.Map(lo.Values(c.CreatedMachines), func(m *v1alpha5.Machine, _ int) *v1alpha5.Machine {
 		return m.DeepCopy()
 	}), nil
 }
 
-func (c *CloudProvider) GetInstanceTypes(_ context.Context, _ *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+func (c *CloudProvider) GetInstanceTypes(_ context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+	if provisioner != nil {
+		if err, ok := c.ErrorsForProvisioner[provisioner.Name]; ok {
+			return nil, err
+		}
+	}
 	if c.InstanceTypes != nil {
 		return c.InstanceTypes, nil
 	}
@@ -200,8 +298,23 @@ func (c *CloudProvider) Delete(_ context.Context, m *v1alpha5.Machine) error {
 	defer c.mu.Unlock()
 
 	c.DeleteCalls = append(c.DeleteCalls, m)
-	if _, ok := c.CreatedMachines[m.Status.ProviderID]; ok {
-		delete(c.CreatedMachines, m.Status.ProviderID)
+	if c.DeleteErr != nil {
+		return c.DeleteErr
+	}
+	if err := c.throttle(); err != nil {
+		return err
+	}
+	c.terminate()
+	id := c.normalizeProviderID(m.Status.ProviderID)
+	if created, ok := c.CreatedMachines[id]; ok {
+		if c.TerminationDelay == 0 {
+			delete(c.CreatedMachines, id)
+			return nil
+		}
+		if _, ok := c.terminating[id]; !ok {
+			c.terminating[id] = c.clock().Now().Add(c.TerminationDelay)
+			created.DeletionTimestamp = lo.ToPtr(metav1.NewTime(c.clock().Now()))
+		}
 		return nil
 	}
 	return cloudprovider.NewMachineNotFoundError(fmt.Errorf("no machine exists with provider id '%s'", m.Status.ProviderID))
@@ -214,7 +327,83 @@ func (c *CloudProvider) IsMachineDrifted(context.Context, *v1alpha5.Machine) (cl
 	return c.Drifted, nil
 }
 
+func (c *CloudProvider) Ready(_ context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.ReadyErr
+}
+
+func (c *CloudProvider) OnDemandPrice(instanceType string, _ string) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	price, ok := c.OnDemandPrices[instanceType]
+	return price, ok
+}
+
+func (c *CloudProvider) SpotPrice(instanceType string, _ string) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	price, ok := c.SpotPrices[instanceType]
+	return price, ok
+}
+
+func (c *CloudProvider) NormalizeProviderID(id string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.normalizeProviderID(id)
+}
+
+func (c *CloudProvider) normalizeProviderID(id string) string {
+	if c.ProviderIDFormat == nil {
+		return id
+	}
+	return c.ProviderIDFormat(id)
+}
+
+func (c *CloudProvider) InterruptionMessages(context.Context) <-chan cloudprovider.InterruptionMessage {
+	return c.Interruptions
+}
+
+func (c *CloudProvider) WatchMachines(context.Context) <-chan cloudprovider.MachineEvent {
+	return c.MachineEvents
+}
+
 // Name returns the CloudProvider implementation name.
 func (c *CloudProvider) Name() string {
 	return "fake"
 }
+
+// throttle returns a RateLimitedError while there are ThrottledCalls left, it must be called with the lock held
+func (c *CloudProvider) throttle() error {
+	if c.ThrottledCalls <= 0 {
+		return nil
+	}
+	c.ThrottledCalls--
+	return cloudprovider.NewRateLimitedErrorWithRetryAfter(fmt.Errorf("request was throttled"), c.ThrottleRetryAfter)
+}
+
+// insufficientCapacity returns true if the offering of the instance type is out of capacity
+func (c *CloudProvider) insufficientCapacity(instanceType string, o cloudprovider.Offering) bool {
+	return lo.Contains(c.InsufficientCapacityOfferings, cloudprovider.UnavailableOffering{InstanceType: instanceType, Zone: o.Zone, CapacityType: o.CapacityType})
+}
+
+// terminate removes the deleted machines whose TerminationDelay has passed, it must be called with the lock held
+func (c *CloudProvider) terminate() {
+	for id, terminateAt := range c.terminating {
+		if !c.clock().Now().Before(terminateAt) {
+			delete(c.CreatedMachines, id)
+			delete(c.terminating, id)
+		}
+	}
+}
+
+func (c *CloudProvider) clock() clock.Clock {
+	if c.Clock == nil {
+		return clock.RealClock{}
+	}
+	return c.Clock
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clock "k8s.io/utils/clock/testing"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var ctx context.Context
+var fakeClock *clock.FakeClock
+var cloudProvider *fake.CloudProvider
+
+func TestFake(t *testing.T) {
+	ctx = context.Background()
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fake")
+}
+
+var _ = BeforeEach(func() {
+	fakeClock = clock.NewFakeClock(time.Now())
+	cloudProvider = fake.NewCloudProvider()
+	cloudProvider.Clock = fakeClock
+})
+
+var _ = Describe("CreateLatency", func() {
+	It("should launch the machine once the latency has passed", func() {
+		cloudProvider.CreateLatency = time.Minute
+		created := make(chan *v1alpha5.Machine)
+		go func() {
+			defer GinkgoRecover()
+			machine, err := cloudProvider.Create(ctx, test.Machine())
+			Expect(err).ToNot(HaveOccurred())
+			created <- machine
+		}()
+		Eventually(fakeClock.HasWaiters).Should(BeTrue())
+		fakeClock.Step(time.Second * 30)
+		Consistently(created, time.Millisecond*100).ShouldNot(Receive())
+
+		fakeClock.Step(time.Second * 30)
+		var machine *v1alpha5.Machine
+		Eventually(created).Should(Receive(&machine))
+		Expect(machine.Status.ProviderID).ToNot(BeEmpty())
+		Expect(cloudProvider.CreatedMachines).To(HaveLen(1))
+	})
+	It("should stop waiting when the context is cancelled", func() {
+		cloudProvider.CreateLatency = time.Minute
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := cloudProvider.Create(cancelCtx, test.Machine())
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(cloudProvider.CreateCalls).To(BeEmpty())
+	})
+})
+
+var _ = Describe("TerminationDelay", func() {
+	It("should terminate deleted machines right away without a termination delay", func() {
+		machine, err := cloudProvider.Create(ctx, test.Machine())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+
+		_, err = cloudProvider.Get(ctx, machine.Status.ProviderID)
+		Expect(cloudprovider.IsMachineNotFoundError(err)).To(BeTrue())
+	})
+	It("should keep returning deleted machines until the termination delay has passed", func() {
+		cloudProvider.TerminationDelay = time.Minute
+		machine, err := cloudProvider.Create(ctx, test.Machine())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+
+		terminating, err := cloudProvider.Get(ctx, machine.Status.ProviderID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(terminating.DeletionTimestamp).ToNot(BeNil())
+		machines, err := cloudProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machines).To(HaveLen(1))
+
+		// deleting the machine again doesn't extend the delay
+		fakeClock.Step(time.Second * 30)
+		Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+		fakeClock.Step(time.Second * 30)
+
+		_, err = cloudProvider.Get(ctx, machine.Status.ProviderID)
+		Expect(cloudprovider.IsMachineNotFoundError(err)).To(BeTrue())
+		machines, err = cloudProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machines).To(BeEmpty())
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetypecache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+// InstanceTypesTTL is how long the instance types of a provisioner are cached for
+const InstanceTypesTTL = time.Minute
+
+// decorator implements CloudProvider
+var _ cloudprovider.CloudProvider = (*decorator)(nil)
+
+type decorator struct {
+	cloudprovider.CloudProvider
+	cache *cache.Cache
+	group singleflight.Group
+	// generation is incremented on every invalidation so that results that were fetched before an invalidation
+	// aren't cached
+	generation atomic.Int64
+}
+
+// Decorate returns a new `CloudProvider` instance that will delegate all method calls to the argument,
+// `cloudProvider`, and cache the instance types returned by GetInstanceTypes for each provisioner and the
+// requirements passed through cloudprovider.WithRequirements. Concurrent calls for the same provisioner are deduplicated, and the cache is flushed whenever a CloudProvider that implements
+// InstanceTypesInvalidator reports that its instance types changed. Callers must not modify the returned instance
+// types since they're shared.
+func Decorate(cloudProvider cloudprovider.CloudProvider) cloudprovider.CloudProvider {
+	d := &decorator{
+		CloudProvider: cloudProvider,
+		cache:         cache.New(InstanceTypesTTL, time.Minute),
+	}
+	if invalidator, ok := cloudProvider.(cloudprovider.InstanceTypesInvalidator); ok {
+		invalidator.OnInstanceTypesChanged(d.Invalidate)
+	}
+	// BatchCreate is only exposed if the CloudProvider implements it so that consumers can tell whether it's supported
+	if _, ok := cloudProvider.(cloudprovider.BatchCreator); ok {
+		return &batchDecorator{d}
+	}
+	return d
+}
+
+// batchDecorator is a decorator for CloudProviders that implement BatchCreate
+type batchDecorator struct {
+	*decorator
+}
+
+func (d *batchDecorator) BatchCreate(ctx context.Context, machines []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	return cloudprovider.BatchCreate(ctx, d.CloudProvider, machines)
+}
+
+func (d *decorator) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+	if provisioner == nil {
+		return d.CloudProvider.GetInstanceTypes(ctx, provisioner)
+	}
+	// the instance types depend on the provisioner spec, so changes to it are picked up right away
+	key := fmt.Sprintf("%s/%s", provisioner.Name, provisioner.Hash())
+	// the CloudProvider may pre-filter the instance types with the requirements, so they're cached separately
+	if requirements, ok := cloudprovider.RequirementsFromContext(ctx); ok {
+		key = fmt.Sprintf("%s/%s", key, requirements)
+	}
+	if instanceTypes, ok := d.cache.Get(key); ok {
+		return instanceTypes.([]*cloudprovider.InstanceType), nil
+	}
+	instanceTypes, err, _ := d.group.Do(key, func() (interface{}, error) {
+		generation := d.generation.Load()
+		instanceTypes, err := d.CloudProvider.GetInstanceTypes(ctx, provisioner)
+		if err != nil {
+			return nil, err
+		}
+		if generation == d.generation.Load() {
+			d.cache.SetDefault(key, instanceTypes)
+		}
+		return instanceTypes, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceTypes.([]*cloudprovider.InstanceType), nil
+}
+
+// Invalidate removes the cached instance types of every provisioner
+func (d *decorator) Invalidate() {
+	d.generation.Add(1)
+	d.cache.Flush()
+}
+
+func (d *decorator) NormalizeProviderID(id string) string {
+	return cloudprovider.NormalizeProviderID(d.CloudProvider, id)
+}
+
+func (d *decorator) OnDemandPrice(instanceType string, zone string) (float64, bool) {
+	return cloudprovider.Price(d.CloudProvider, instanceType, v1alpha5.CapacityTypeOnDemand, zone)
+}
+
+func (d *decorator) SpotPrice(instanceType string, zone string) (float64, bool) {
+	return cloudprovider.Price(d.CloudProvider, instanceType, v1alpha5.CapacityTypeSpot, zone)
+}
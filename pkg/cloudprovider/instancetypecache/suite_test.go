@@ -0,0 +1,149 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetypecache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/instancetypecache"
+	"github.com/aws/karpenter-core/pkg/scheduling"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var ctx context.Context
+var fakeCloudProvider *countingCloudProvider
+var cloudProvider cloudprovider.CloudProvider
+
+func TestInstanceTypeCache(t *testing.T) {
+	ctx = context.Background()
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "InstanceTypeCache")
+}
+
+var _ = BeforeEach(func() {
+	fakeCloudProvider = &countingCloudProvider{CloudProvider: fake.NewCloudProvider()}
+	cloudProvider = instancetypecache.Decorate(fakeCloudProvider)
+})
+
+var _ = Describe("InstanceTypeCache", func() {
+	var provisioner *v1alpha5.Provisioner
+	BeforeEach(func() {
+		provisioner = test.Provisioner()
+	})
+	It("should cache the instance types of a provisioner", func() {
+		first, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		second, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(second).To(Equal(first))
+		Expect(fakeCloudProvider.calls.Load()).To(BeNumerically("==", 1))
+	})
+	It("should get the instance types again when the provisioner changes", func() {
+		_, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		provisioner.Spec.Labels = map[string]string{"test": "changed"}
+		_, err = cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cloudProvider.GetInstanceTypes(ctx, test.Provisioner())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeCloudProvider.calls.Load()).To(BeNumerically("==", 3))
+	})
+	It("should cache the instance types separately for different requirements", func() {
+		zoneRequirements := scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, "test-zone-1"))
+		archRequirements := scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, v1alpha5.ArchitectureArm64))
+		for i := 0; i < 2; i++ {
+			_, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cloudProvider.GetInstanceTypes(cloudprovider.WithRequirements(ctx, zoneRequirements), provisioner)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cloudProvider.GetInstanceTypes(cloudprovider.WithRequirements(ctx, archRequirements), provisioner)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(fakeCloudProvider.calls.Load()).To(BeNumerically("==", 3))
+	})
+	It("should get the instance types again when the cloudprovider invalidates them", func() {
+		_, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		fakeCloudProvider.invalidate()
+		_, err = cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeCloudProvider.calls.Load()).To(BeNumerically("==", 2))
+	})
+	It("should not cache errors", func() {
+		fakeCloudProvider.ErrorsForProvisioner[provisioner.Name] = errors.New("failed")
+		_, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).To(HaveOccurred())
+		delete(fakeCloudProvider.ErrorsForProvisioner, provisioner.Name)
+		_, err = cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeCloudProvider.calls.Load()).To(BeNumerically("==", 2))
+	})
+	It("should deduplicate concurrent calls for the same provisioner", func() {
+		fakeCloudProvider.release = make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+		}
+		Eventually(func() int32 { return fakeCloudProvider.calls.Load() }).Should(BeNumerically("==", 1))
+		close(fakeCloudProvider.release)
+		wg.Wait()
+
+		Expect(fakeCloudProvider.calls.Load()).To(BeNumerically("==", 1))
+	})
+})
+
+// countingCloudProvider counts the calls to GetInstanceTypes, optionally blocking them until release is closed, and
+// reports instance type changes through invalidate
+type countingCloudProvider struct {
+	*fake.CloudProvider
+	calls      atomic.Int32
+	release    chan struct{}
+	invalidate func()
+}
+
+func (c *countingCloudProvider) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+	c.calls.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	return c.CloudProvider.GetInstanceTypes(ctx, provisioner)
+}
+
+func (c *countingCloudProvider) OnInstanceTypesChanged(invalidate func()) {
+	c.invalidate = invalidate
+}
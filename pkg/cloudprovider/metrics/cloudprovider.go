@@ -28,15 +28,21 @@ import (
 )
 
 const (
-	metricLabelController = "controller"
-	metricLabelMethod     = "method"
-	metricLabelProvider   = "provider"
-	metricLabelError      = "error"
+	metricLabelController   = "controller"
+	metricLabelMethod       = "method"
+	metricLabelProvider     = "provider"
+	metricLabelError        = "error"
+	metricLabelCapacityType = "capacity_type"
+	metricLabelZone         = "zone"
 	// MetricLabelErrorDefaultVal is the default string value that represents "error type unknown"
 	MetricLabelErrorDefaultVal = ""
 	// Well-known metricLabelError values
-	MachineNotFoundError      = "MachineNotFoundError"
-	InsufficientCapacityError = "InsufficientCapacityError"
+	MachineNotFoundError      = cloudprovider.MachineNotFoundErrorClass
+	InsufficientCapacityError = cloudprovider.InsufficientCapacityErrorClass
+	QuotaExceededError        = cloudprovider.QuotaExceededErrorClass
+	RateLimitedError          = cloudprovider.RateLimitedErrorClass
+	InvalidTemplateError      = cloudprovider.InvalidTemplateErrorClass
+	UnauthorizedError         = cloudprovider.UnauthorizedErrorClass
 )
 
 // decorator implements CloudProvider
@@ -73,8 +79,22 @@ var (
 	)
 )
 
+var instanceTypePriceEstimate = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "cloudprovider",
+		Name:      "instance_type_price_estimate",
+		Help:      "Estimated hourly price of the offerings of instance types. Labeled by the instance type, capacity type and zone.",
+	},
+	[]string{
+		metrics.InstanceTypeLabel,
+		metricLabelCapacityType,
+		metricLabelZone,
+	},
+)
+
 func init() {
-	crmetrics.Registry.MustRegister(methodDurationHistogramVec, errorsTotalCounter)
+	crmetrics.Registry.MustRegister(methodDurationHistogramVec, errorsTotalCounter, instanceTypePriceEstimate)
 }
 
 type decorator struct {
@@ -89,9 +109,30 @@ type decorator struct {
 // Do not decorate a `CloudProvider` multiple times or published metrics will contain
 // duplicated method call counts and latencies.
 func Decorate(cloudProvider cloudprovider.CloudProvider) cloudprovider.CloudProvider {
+	// BatchCreate is only exposed if the CloudProvider implements it so that consumers can tell whether it's supported
+	if _, ok := cloudProvider.(cloudprovider.BatchCreator); ok {
+		return &batchDecorator{&decorator{cloudProvider}}
+	}
 	return &decorator{cloudProvider}
 }
 
+// batchDecorator is a decorator for CloudProviders that implement BatchCreate
+type batchDecorator struct {
+	*decorator
+}
+
+func (d *batchDecorator) BatchCreate(ctx context.Context, machines []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	method := "BatchCreate"
+	defer metrics.Measure(methodDurationHistogramVec.With(getLabelsMapForDuration(ctx, d.decorator, method)))()
+	created, errs := cloudprovider.BatchCreate(ctx, d.CloudProvider, machines)
+	for _, err := range errs {
+		if err != nil {
+			errorsTotalCounter.With(getLabelsMapForError(ctx, d.decorator, method, err)).Inc()
+		}
+	}
+	return created, errs
+}
+
 func (d *decorator) Create(ctx context.Context, machine *v1alpha5.Machine) (*v1alpha5.Machine, error) {
 	method := "Create"
 	defer metrics.Measure(methodDurationHistogramVec.With(getLabelsMapForDuration(ctx, d, method)))()
@@ -139,6 +180,15 @@ func (d *decorator) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.
 	if err != nil {
 		errorsTotalCounter.With(getLabelsMapForError(ctx, d, method, err)).Inc()
 	}
+	for _, it := range cloudprovider.WithPrices(d.CloudProvider, instanceType) {
+		for _, offering := range it.Offerings {
+			instanceTypePriceEstimate.With(prometheus.Labels{
+				metrics.InstanceTypeLabel: it.Name,
+				metricLabelCapacityType:   offering.CapacityType,
+				metricLabelZone:           offering.Zone,
+			}).Set(offering.Price)
+		}
+	}
 	return instanceType, err
 }
 
@@ -152,6 +202,43 @@ func (d *decorator) IsMachineDrifted(ctx context.Context, machine *v1alpha5.Mach
 	return isDrifted, err
 }
 
+// OnDemandPrice isn't measured since prices are expected to be served from memory
+func (d *decorator) OnDemandPrice(instanceType string, zone string) (float64, bool) {
+	return cloudprovider.Price(d.CloudProvider, instanceType, v1alpha5.CapacityTypeOnDemand, zone)
+}
+
+// SpotPrice isn't measured since prices are expected to be served from memory
+func (d *decorator) SpotPrice(instanceType string, zone string) (float64, bool) {
+	return cloudprovider.Price(d.CloudProvider, instanceType, v1alpha5.CapacityTypeSpot, zone)
+}
+
+// Ready reports the CloudProvider as ready if it isn't able to check its health
+func (d *decorator) Ready(ctx context.Context) error {
+	checker, ok := d.CloudProvider.(cloudprovider.HealthChecker)
+	if !ok {
+		return nil
+	}
+	method := "Ready"
+	defer metrics.Measure(methodDurationHistogramVec.With(getLabelsMapForDuration(ctx, d, method)))()
+	err := checker.Ready(ctx)
+	if err != nil {
+		errorsTotalCounter.With(getLabelsMapForError(ctx, d, method, err)).Inc()
+	}
+	return err
+}
+
+// OnInstanceTypesChanged forwards the registration to the CloudProvider if it's able to report instance type changes
+func (d *decorator) OnInstanceTypesChanged(invalidate func()) {
+	if invalidator, ok := d.CloudProvider.(cloudprovider.InstanceTypesInvalidator); ok {
+		invalidator.OnInstanceTypesChanged(invalidate)
+	}
+}
+
+// NormalizeProviderID isn't measured since it doesn't call the CloudProvider's APIs
+func (d *decorator) NormalizeProviderID(id string) string {
+	return cloudprovider.NormalizeProviderID(d.CloudProvider, id)
+}
+
 // getLabelsMapForDuration is a convenience func that constructs a map[string]string
 // for a prometheus Label map used to compose a duration metric spec
 func getLabelsMapForDuration(ctx context.Context, d *decorator, method string) map[string]string {
@@ -176,10 +263,8 @@ func getLabelsMapForError(ctx context.Context, d *decorator, method string, err
 // GetErrorTypeLabelValue is a convenience func that returns
 // a string representation of well-known CloudProvider error types
 func GetErrorTypeLabelValue(err error) string {
-	if cloudprovider.IsInsufficientCapacityError(err) {
-		return InsufficientCapacityError
-	} else if cloudprovider.IsMachineNotFoundError(err) {
-		return MachineNotFoundError
+	if class := cloudprovider.ErrorClass(err); class != "" {
+		return class
 	}
 	return MetricLabelErrorDefaultVal
 }
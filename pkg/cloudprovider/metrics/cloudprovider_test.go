@@ -16,6 +16,7 @@ package metrics_test
 
 import (
 	"errors"
+	"fmt"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -27,6 +28,10 @@ import (
 var _ = Describe("Cloudprovider", func() {
 	var machineNotFoundErr = cloudprovider.NewMachineNotFoundError(errors.New("not found"))
 	var insufficientCapacityErr = cloudprovider.NewInsufficientCapacityError(errors.New("not enough capacity"))
+	var quotaExceededErr = cloudprovider.NewQuotaExceededError(errors.New("quota exceeded"))
+	var rateLimitedErr = cloudprovider.NewRateLimitedError(errors.New("request limit exceeded"))
+	var invalidTemplateErr = cloudprovider.NewInvalidTemplateError(errors.New("image not found"))
+	var unauthorizedErr = cloudprovider.NewUnauthorizedError(errors.New("access denied"))
 	var unknownErr = errors.New("this is an error we don't know about")
 
 	Describe("CloudProvider machine errors via GetErrorTypeLabelValue()", func() {
@@ -37,6 +42,21 @@ var _ = Describe("Cloudprovider", func() {
 			It("insufficient capacity should be recognized", func() {
 				Expect(metrics.GetErrorTypeLabelValue(insufficientCapacityErr)).To(Equal(metrics.InsufficientCapacityError))
 			})
+			It("quota exceeded should be recognized", func() {
+				Expect(metrics.GetErrorTypeLabelValue(quotaExceededErr)).To(Equal(metrics.QuotaExceededError))
+			})
+			It("rate limited should be recognized", func() {
+				Expect(metrics.GetErrorTypeLabelValue(rateLimitedErr)).To(Equal(metrics.RateLimitedError))
+			})
+			It("invalid template should be recognized", func() {
+				Expect(metrics.GetErrorTypeLabelValue(invalidTemplateErr)).To(Equal(metrics.InvalidTemplateError))
+			})
+			It("unauthorized should be recognized", func() {
+				Expect(metrics.GetErrorTypeLabelValue(unauthorizedErr)).To(Equal(metrics.UnauthorizedError))
+			})
+			It("wrapped errors should be recognized", func() {
+				Expect(metrics.GetErrorTypeLabelValue(fmt.Errorf("creating machine, %w", rateLimitedErr))).To(Equal(metrics.RateLimitedError))
+			})
 		})
 		Context("when the error is unknown", func() {
 			It("should always return empty string", func() {
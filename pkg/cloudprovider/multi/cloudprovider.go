@@ -0,0 +1,312 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
+var _ cloudprovider.InterruptionSource = (*CloudProvider)(nil)
+var _ cloudprovider.HealthChecker = (*CloudProvider)(nil)
+var _ cloudprovider.InstanceTypesInvalidator = (*CloudProvider)(nil)
+var _ cloudprovider.MachineWatcher = (*CloudProvider)(nil)
+var _ cloudprovider.BatchCreator = (*CloudProvider)(nil)
+var _ cloudprovider.Pricing = (*CloudProvider)(nil)
+
+// CloudProvider routes calls to one of several registered CloudProviders so that a single operator can manage nodes
+// from different sources (e.g. a cloud and bare metal). Provisioners, Machines and NodeClaims select their
+// CloudProvider by name through their spec, and are handled by the default CloudProvider if they don't set one.
+type CloudProvider struct {
+	defaultCloudProvider cloudprovider.CloudProvider
+	cloudProviders       map[string]cloudprovider.CloudProvider
+	// names are the names of the CloudProviders in the order that they were registered
+	names []string
+}
+
+// New returns a CloudProvider that routes calls to the CloudProvider that's registered with the name that a
+// Provisioner or Machine references, falling back to the default CloudProvider. CloudProviders are registered by
+// the names that they return from Name(), which must be unique.
+func New(defaultCloudProvider cloudprovider.CloudProvider, cloudProviders ...cloudprovider.CloudProvider) *CloudProvider {
+	c := &CloudProvider{
+		defaultCloudProvider: defaultCloudProvider,
+		cloudProviders:       map[string]cloudprovider.CloudProvider{defaultCloudProvider.Name(): defaultCloudProvider},
+		names:                []string{defaultCloudProvider.Name()},
+	}
+	for _, cp := range cloudProviders {
+		if _, ok := c.cloudProviders[cp.Name()]; ok {
+			panic(fmt.Sprintf("cloudprovider %q is registered more than once", cp.Name()))
+		}
+		c.cloudProviders[cp.Name()] = cp
+		c.names = append(c.names, cp.Name())
+	}
+	return c
+}
+
+// cloudProvider returns the CloudProvider that's registered with the name, or the default CloudProvider if the name
+// is empty
+func (c *CloudProvider) cloudProvider(name string) (cloudprovider.CloudProvider, error) {
+	if name == "" {
+		return c.defaultCloudProvider, nil
+	}
+	cp, ok := c.cloudProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("cloudprovider %q isn't registered", name)
+	}
+	return cp, nil
+}
+
+func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (*v1alpha5.Machine, error) {
+	cp, err := c.cloudProvider(machine.Spec.CloudProvider)
+	if err != nil {
+		return nil, err
+	}
+	return cp.Create(ctx, machine)
+}
+
+// BatchCreate creates the machines of each CloudProvider together, using BatchCreate for the CloudProviders that
+// implement it. The results are returned in the order of the machines.
+func (c *CloudProvider) BatchCreate(ctx context.Context, machines []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	created := make([]*v1alpha5.Machine, len(machines))
+	errs := make([]error, len(machines))
+	indexes := map[string][]int{}
+	for i, machine := range machines {
+		indexes[machine.Spec.CloudProvider] = append(indexes[machine.Spec.CloudProvider], i)
+	}
+	for name, is := range indexes {
+		cp, err := c.cloudProvider(name)
+		if err != nil {
+			for _, i := range is {
+				errs[i] = err
+			}
+			continue
+		}
+		cpCreated, cpErrs := cloudprovider.BatchCreate(ctx, cp, lo.Map(is, func(i int, _ int) *v1alpha5.Machine { return machines[i] }))
+		for j, i := range is {
+			if j >= len(cpErrs) || j >= len(cpCreated) {
+				errs[i] = fmt.Errorf("cloudprovider %q didn't return a result for the machine", lo.Ternary(name == "", c.Name(), name))
+				continue
+			}
+			created[i], errs[i] = cpCreated[j], cpErrs[j]
+		}
+	}
+	return created, errs
+}
+
+func (c *CloudProvider) Delete(ctx context.Context, machine *v1alpha5.Machine) error {
+	cp, err := c.cloudProvider(machine.Spec.CloudProvider)
+	if err != nil {
+		return err
+	}
+	return cp.Delete(ctx, machine)
+}
+
+// Get returns the machine from the first CloudProvider that knows of the provider id, since provider ids don't
+// identify the CloudProvider that they belong to. A CloudProvider may fail to get a provider id that isn't its own
+// (e.g. because it can't parse it), so the other CloudProviders are still asked, but the machine is only reported as
+// not found if every CloudProvider reported it as not found. Otherwise, a CloudProvider that's failing could cause a
+// machine that it launched to be garbage collected.
+func (c *CloudProvider) Get(ctx context.Context, id string) (*v1alpha5.Machine, error) {
+	var errs error
+	for _, name := range c.names {
+		cp := c.cloudProviders[name]
+		machine, err := cp.Get(ctx, id)
+		if cloudprovider.IsMachineNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("getting machine from cloudprovider %q, %w", name, err))
+			continue
+		}
+		machine.Spec.CloudProvider = c.nameOf(name)
+		return machine, nil
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return nil, cloudprovider.NewMachineNotFoundError(fmt.Errorf("no cloudprovider has a machine with id '%s'", id))
+}
+
+// List returns the machines of every CloudProvider, referencing the CloudProvider that they belong to so that they
+// can be deleted through it
+func (c *CloudProvider) List(ctx context.Context) ([]*v1alpha5.Machine, error) {
+	var machines []*v1alpha5.Machine
+	for _, name := range c.names {
+		cp := c.cloudProviders[name]
+		cpMachines, err := cp.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing machines from cloudprovider %q, %w", name, err)
+		}
+		for _, machine := range cpMachines {
+			machine.Spec.CloudProvider = c.nameOf(name)
+		}
+		machines = append(machines, cpMachines...)
+	}
+	return machines, nil
+}
+
+func (c *CloudProvider) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+	if provisioner == nil {
+		return c.defaultCloudProvider.GetInstanceTypes(ctx, provisioner)
+	}
+	cp, err := c.cloudProvider(provisioner.Spec.CloudProvider)
+	if err != nil {
+		return nil, err
+	}
+	return cp.GetInstanceTypes(ctx, provisioner)
+}
+
+func (c *CloudProvider) IsMachineDrifted(ctx context.Context, machine *v1alpha5.Machine) (cloudprovider.DriftReason, error) {
+	cp, err := c.cloudProvider(machine.Spec.CloudProvider)
+	if err != nil {
+		return "", err
+	}
+	return cp.IsMachineDrifted(ctx, machine)
+}
+
+// Name returns the name of the default CloudProvider
+func (c *CloudProvider) Name() string {
+	return c.defaultCloudProvider.Name()
+}
+
+// InterruptionMessages merges the interruption messages of every CloudProvider that implements InterruptionSource
+func (c *CloudProvider) InterruptionMessages(ctx context.Context) <-chan cloudprovider.InterruptionMessage {
+	var sources []<-chan cloudprovider.InterruptionMessage
+	for _, name := range c.names {
+		if source, ok := c.cloudProviders[name].(cloudprovider.InterruptionSource); ok {
+			sources = append(sources, source.InterruptionMessages(ctx))
+		}
+	}
+	return merge(ctx, sources)
+}
+
+// WatchMachines merges the machine events of every CloudProvider that implements MachineWatcher
+func (c *CloudProvider) WatchMachines(ctx context.Context) <-chan cloudprovider.MachineEvent {
+	var sources []<-chan cloudprovider.MachineEvent
+	for _, name := range c.names {
+		if watcher, ok := c.cloudProviders[name].(cloudprovider.MachineWatcher); ok {
+			sources = append(sources, watcher.WatchMachines(ctx))
+		}
+	}
+	return merge(ctx, sources)
+}
+
+// Ready returns the errors of every CloudProvider that implements HealthChecker and isn't ready
+func (c *CloudProvider) Ready(ctx context.Context) error {
+	var errs error
+	for _, name := range c.names {
+		cp := c.cloudProviders[name]
+		checker, ok := cp.(cloudprovider.HealthChecker)
+		if !ok {
+			continue
+		}
+		if err := checker.Ready(ctx); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("cloudprovider %q, %w", name, err))
+		}
+	}
+	return errs
+}
+
+// OnInstanceTypesChanged registers the func with every CloudProvider that implements InstanceTypesInvalidator
+func (c *CloudProvider) OnInstanceTypesChanged(f func()) {
+	for _, name := range c.names {
+		cp := c.cloudProviders[name]
+		if invalidator, ok := cp.(cloudprovider.InstanceTypesInvalidator); ok {
+			invalidator.OnInstanceTypesChanged(f)
+		}
+	}
+}
+
+// NormalizeProviderID normalizes the provider id with every CloudProvider that implements ProviderIDNormalizer
+func (c *CloudProvider) NormalizeProviderID(id string) string {
+	return lo.Reduce(c.names, func(id string, name string, _ int) string {
+		return cloudprovider.NormalizeProviderID(c.cloudProviders[name], id)
+	}, id)
+}
+
+// OnDemandPrice returns the on-demand price from the first CloudProvider that implements Pricing and knows the price
+// of the instance type
+func (c *CloudProvider) OnDemandPrice(instanceType string, zone string) (float64, bool) {
+	return c.price(func(pricing cloudprovider.Pricing) (float64, bool) { return pricing.OnDemandPrice(instanceType, zone) })
+}
+
+// SpotPrice returns the spot price from the first CloudProvider that implements Pricing and knows the price of the
+// instance type
+func (c *CloudProvider) SpotPrice(instanceType string, zone string) (float64, bool) {
+	return c.price(func(pricing cloudprovider.Pricing) (float64, bool) { return pricing.SpotPrice(instanceType, zone) })
+}
+
+func (c *CloudProvider) price(f func(cloudprovider.Pricing) (float64, bool)) (float64, bool) {
+	for _, name := range c.names {
+		pricing, ok := c.cloudProviders[name].(cloudprovider.Pricing)
+		if !ok {
+			continue
+		}
+		if price, ok := f(pricing); ok {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+// nameOf returns the name that Machines use to reference the CloudProvider, which is empty for the default
+// CloudProvider
+func (c *CloudProvider) nameOf(name string) string {
+	return lo.Ternary(name == c.defaultCloudProvider.Name(), "", name)
+}
+
+// merge forwards the values of every source to the returned channel until the context is cancelled. The channel is
+// closed once every source is closed, and is left open if there are no sources.
+func merge[T any](ctx context.Context, sources []<-chan T) <-chan T {
+	merged := make(chan T)
+	if len(sources) == 0 {
+		return merged
+	}
+	wg := &sync.WaitGroup{}
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source <-chan T) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case value, ok := <-source:
+					if !ok {
+						return
+					}
+					select {
+					case merged <- value:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(source)
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged
+}
@@ -0,0 +1,187 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multi_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/multi"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var ctx context.Context
+var defaultCloudProvider *fake.CloudProvider
+var metalCloudProvider *namedCloudProvider
+var cloudProvider *multi.CloudProvider
+
+func TestMulti(t *testing.T) {
+	ctx = context.Background()
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Multi")
+}
+
+var _ = BeforeEach(func() {
+	defaultCloudProvider = fake.NewCloudProvider()
+	metalCloudProvider = &namedCloudProvider{CloudProvider: fake.NewCloudProvider(), name: "metal"}
+	metalCloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+		fake.NewInstanceType(fake.InstanceTypeOptions{Name: "metal-instance-type"}),
+	}
+	cloudProvider = multi.New(defaultCloudProvider, metalCloudProvider)
+})
+
+var _ = Describe("Multi", func() {
+	It("should panic when two cloudproviders are registered with the same name", func() {
+		Expect(func() { multi.New(defaultCloudProvider, fake.NewCloudProvider()) }).To(Panic())
+	})
+	It("should get instance types from the cloudprovider that the provisioner references", func() {
+		provisioner := test.Provisioner()
+		provisioner.Spec.CloudProvider = "metal"
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).To(HaveLen(1))
+		Expect(instanceTypes[0].Name).To(Equal("metal-instance-type"))
+	})
+	It("should get instance types from the default cloudprovider when the provisioner doesn't reference one", func() {
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, test.Provisioner())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).ToNot(ContainElement(HaveField("Name", "metal-instance-type")))
+	})
+	It("should error when the provisioner references a cloudprovider that isn't registered", func() {
+		provisioner := test.Provisioner()
+		provisioner.Spec.CloudProvider = "unknown"
+		_, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).To(MatchError(ContainSubstring(`cloudprovider "unknown" isn't registered`)))
+	})
+	It("should create and delete machines with the cloudprovider that they reference", func() {
+		machine := test.Machine(v1alpha5.Machine{Spec: v1alpha5.MachineSpec{CloudProvider: "metal"}})
+		created, err := cloudProvider.Create(ctx, machine)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "metal-instance-type"))
+		Expect(metalCloudProvider.CreateCalls).To(HaveLen(1))
+		Expect(defaultCloudProvider.CreateCalls).To(HaveLen(0))
+
+		machine.Status.ProviderID = created.Status.ProviderID
+		Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+		Expect(metalCloudProvider.DeleteCalls).To(HaveLen(1))
+		Expect(defaultCloudProvider.DeleteCalls).To(HaveLen(0))
+	})
+	It("should get machines from the cloudprovider that launched them", func() {
+		created, err := cloudProvider.Create(ctx, test.Machine(v1alpha5.Machine{Spec: v1alpha5.MachineSpec{CloudProvider: "metal"}}))
+		Expect(err).ToNot(HaveOccurred())
+
+		machine, err := cloudProvider.Get(ctx, created.Status.ProviderID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Spec.CloudProvider).To(Equal("metal"))
+
+		_, err = cloudProvider.Get(ctx, test.RandomProviderID())
+		Expect(cloudprovider.IsMachineNotFoundError(err)).To(BeTrue())
+	})
+	It("should get machines from another cloudprovider when a cloudprovider fails to get them", func() {
+		metalCloudProvider.getErr = errors.New("invalid provider id")
+		created, err := cloudProvider.Create(ctx, test.Machine())
+		Expect(err).ToNot(HaveOccurred())
+
+		machine, err := cloudProvider.Get(ctx, created.Status.ProviderID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Spec.CloudProvider).To(BeEmpty())
+	})
+	It("should not report machines as not found when a cloudprovider fails to get them", func() {
+		metalCloudProvider.getErr = errors.New("bmc unreachable")
+
+		_, err := cloudProvider.Get(ctx, test.RandomProviderID())
+		Expect(err).To(MatchError(ContainSubstring("bmc unreachable")))
+		Expect(cloudprovider.IsMachineNotFoundError(err)).To(BeFalse())
+	})
+	It("should batch create machines with the cloudproviders that they reference", func() {
+		machines := []*v1alpha5.Machine{
+			test.Machine(v1alpha5.Machine{Spec: v1alpha5.MachineSpec{CloudProvider: "metal"}}),
+			test.Machine(),
+			test.Machine(v1alpha5.Machine{Spec: v1alpha5.MachineSpec{CloudProvider: "unknown"}}),
+			test.Machine(v1alpha5.Machine{Spec: v1alpha5.MachineSpec{CloudProvider: "metal"}}),
+		}
+		created, errs := cloudProvider.BatchCreate(ctx, machines)
+		Expect(errs).To(HaveLen(4))
+		Expect(errs[0]).ToNot(HaveOccurred())
+		Expect(errs[1]).ToNot(HaveOccurred())
+		Expect(errs[2]).To(MatchError(ContainSubstring(`cloudprovider "unknown" isn't registered`)))
+		Expect(errs[3]).ToNot(HaveOccurred())
+		Expect(created[0].Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "metal-instance-type"))
+		Expect(created[1].Labels).ToNot(HaveKeyWithValue(v1.LabelInstanceTypeStable, "metal-instance-type"))
+		Expect(created[3].Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "metal-instance-type"))
+		Expect(metalCloudProvider.CreateCalls).To(HaveLen(2))
+		Expect(defaultCloudProvider.CreateCalls).To(HaveLen(1))
+	})
+	It("should forward the prices of the cloudproviders", func() {
+		metalCloudProvider.OnDemandPrices["metal-instance-type"] = 0.5
+		defaultCloudProvider.SpotPrices["default-instance-type"] = 0.25
+
+		price, ok := cloudprovider.Price(cloudProvider, "metal-instance-type", v1alpha5.CapacityTypeOnDemand, "test-zone-1")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.5))
+		price, ok = cloudprovider.Price(cloudProvider, "default-instance-type", v1alpha5.CapacityTypeSpot, "test-zone-1")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.25))
+		_, ok = cloudprovider.Price(cloudProvider, "default-instance-type", v1alpha5.CapacityTypeOnDemand, "test-zone-1")
+		Expect(ok).To(BeFalse())
+	})
+	It("should list the machines of every cloudprovider", func() {
+		_, err := cloudProvider.Create(ctx, test.Machine())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cloudProvider.Create(ctx, test.Machine(v1alpha5.Machine{Spec: v1alpha5.MachineSpec{CloudProvider: "metal"}}))
+		Expect(err).ToNot(HaveOccurred())
+
+		machines, err := cloudProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machines).To(HaveLen(2))
+		Expect(machines).To(ContainElements(HaveField("Spec.CloudProvider", ""), HaveField("Spec.CloudProvider", "metal")))
+	})
+	It("should report the cloudproviders that aren't ready", func() {
+		Expect(cloudProvider.Ready(ctx)).To(Succeed())
+
+		metalCloudProvider.ReadyErr = errors.New("bmc unreachable")
+		err := cloudProvider.Ready(ctx)
+		Expect(err).To(MatchError(ContainSubstring(`cloudprovider "metal"`)))
+		Expect(err).To(MatchError(ContainSubstring("bmc unreachable")))
+	})
+})
+
+// namedCloudProvider is a fake CloudProvider that's registered under a different name
+type namedCloudProvider struct {
+	*fake.CloudProvider
+	name string
+	// getErr is returned by Get instead of getting the machine
+	getErr error
+}
+
+func (c *namedCloudProvider) Name() string {
+	return c.name
+}
+
+func (c *namedCloudProvider) Get(ctx context.Context, id string) (*v1alpha5.Machine, error) {
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+	return c.CloudProvider.Get(ctx, id)
+}
@@ -16,22 +16,37 @@ package cloudprovider
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"encoding/json"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/util/workqueue"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/utils/resources"
 )
 
+// DriftReason is a typed reason returned by the CloudProvider to describe why a machine has drifted
 type DriftReason string
 
+const (
+	// NodeTemplateDrifted signals that the machine no longer matches the node template (e.g. the AWSNodeTemplate)
+	// that it was launched from
+	NodeTemplateDrifted DriftReason = "NodeTemplateDrifted"
+	// ProviderDrifted signals that the machine has drifted from cloudprovider-specific configuration that isn't
+	// captured by the node template (e.g. a resolved image has changed)
+	ProviderDrifted DriftReason = "ProviderDrifted"
+)
+
 // CloudProvider interface is implemented by cloud providers to support provisioning.
 type CloudProvider interface {
 	// Create launches a machine with the given resource requests and requirements and returns a hydrated
@@ -55,11 +70,203 @@ type CloudProvider interface {
 	Name() string
 }
 
+// InterruptionKind is a typed reason returned by an InterruptionSource to describe why a machine is being interrupted
+type InterruptionKind string
+
+const (
+	// SpotInterruption signals that the cloudprovider is reclaiming spot capacity that the machine is running on
+	SpotInterruption InterruptionKind = "SpotInterruption"
+	// ScheduledMaintenance signals that the cloudprovider has scheduled maintenance that will impact the machine
+	ScheduledMaintenance InterruptionKind = "ScheduledMaintenance"
+	// InstanceStopping signals that the instance backing the machine is stopping
+	InstanceStopping InterruptionKind = "InstanceStopping"
+	// InstanceTerminating signals that the instance backing the machine is terminating
+	InstanceTerminating InterruptionKind = "InstanceTerminating"
+	// RebalanceRecommendation signals that the machine is at an elevated risk of interruption but hasn't been
+	// interrupted yet, so there's time to launch a replacement before draining it
+	RebalanceRecommendation InterruptionKind = "RebalanceRecommendation"
+)
+
+// InterruptionMessage is a notice from the cloudprovider that the machine with the given provider id will be
+// involuntarily disrupted
+type InterruptionMessage struct {
+	// ID uniquely identifies the message at the source
+	ID string
+	// ProviderID is the provider id of the machine that is being interrupted
+	ProviderID string
+	// Kind is the reason that the machine is being interrupted
+	Kind InterruptionKind
+	// StartTime is when the interruption was first observed by the cloudprovider
+	StartTime time.Time
+}
+
+// InterruptionSource is optionally implemented by cloud providers that can notify Karpenter of upcoming
+// involuntary disruptions to their machines so that they can be proactively drained and replaced.
+type InterruptionSource interface {
+	// InterruptionMessages returns a channel that receives interruption messages until the context is cancelled
+	InterruptionMessages(context.Context) <-chan InterruptionMessage
+}
+
+// MachineEventKind is a typed state that a MachineWatcher reports a machine has transitioned to
+type MachineEventKind string
+
+const (
+	// MachineLaunched signals that the instance backing the machine was launched
+	MachineLaunched MachineEventKind = "Launched"
+	// MachineRunning signals that the instance backing the machine is running
+	MachineRunning MachineEventKind = "Running"
+	// MachineTerminated signals that the instance backing the machine no longer exists
+	MachineTerminated MachineEventKind = "Terminated"
+	// MachineInterrupted signals that the instance backing the machine is being involuntarily disrupted
+	MachineInterrupted MachineEventKind = "Interrupted"
+)
+
+// MachineEvent is a notice from the cloudprovider that the machine with the given provider id changed state
+type MachineEvent struct {
+	// ProviderID is the provider id of the machine that changed state
+	ProviderID string
+	// Kind is the state that the machine transitioned to
+	Kind MachineEventKind
+	// Time is when the cloudprovider observed the transition
+	Time time.Time
+}
+
+// MachineWatcher is optionally implemented by cloud providers that can push the state changes of their machines so
+// that Karpenter reacts to terminated and interrupted machines as soon as they happen, rather than when it next
+// polls the cloudprovider.
+type MachineWatcher interface {
+	// WatchMachines returns a channel that receives machine events until the context is cancelled
+	WatchMachines(context.Context) <-chan MachineEvent
+}
+
+// HealthChecker is optionally implemented by cloud providers that can report whether they're able to serve requests
+// (e.g. that their credentials haven't expired and their APIs are reachable) so that the operator reports itself as
+// degraded rather than silently failing to launch machines.
+type HealthChecker interface {
+	// Ready returns an error describing why the cloud provider can't serve requests, or nil if it's healthy
+	Ready(context.Context) error
+}
+
+type requirementsKeyType struct{}
+
+// WithRequirements returns a context that carries the resolved requirements of the provisioner that GetInstanceTypes
+// is called for. CloudProviders may use them to filter their instance types before returning them (e.g. server-side),
+// but aren't required to since core also filters the instance types that it receives.
+func WithRequirements(ctx context.Context, requirements scheduling.Requirements) context.Context {
+	return context.WithValue(ctx, requirementsKeyType{}, requirements)
+}
+
+// RequirementsFromContext returns the requirements that were passed to GetInstanceTypes through WithRequirements
+func RequirementsFromContext(ctx context.Context) (scheduling.Requirements, bool) {
+	requirements, ok := ctx.Value(requirementsKeyType{}).(scheduling.Requirements)
+	return requirements, ok
+}
+
+// BatchCreator is optionally implemented by cloud providers that can launch several machines with a single call
+// (e.g. as a fleet), which reduces the number of API calls made when many similar machines are launched at once.
+type BatchCreator interface {
+	// BatchCreate launches the machines and returns the hydrated machines and the errors that launching each of them
+	// returned, in the same order as the machines that were passed in
+	BatchCreate(context.Context, []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error)
+}
+
+// BatchCreate launches the machines with a single BatchCreate call if the CloudProvider supports it and falls back to
+// calling Create for each of the machines otherwise
+func BatchCreate(ctx context.Context, cloudProvider CloudProvider, machines []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	if batchCreator, ok := cloudProvider.(BatchCreator); ok {
+		return batchCreator.BatchCreate(ctx, machines)
+	}
+	created := make([]*v1alpha5.Machine, len(machines))
+	errs := make([]error, len(machines))
+	workqueue.ParallelizeUntil(ctx, len(machines), len(machines), func(i int) {
+		created[i], errs[i] = cloudProvider.Create(ctx, machines[i])
+	})
+	return created, errs
+}
+
+// Pricing is optionally implemented by cloud providers that can price their instance types independently of the
+// offerings returned by GetInstanceTypes (e.g. from a pricing API). Prices are per hour and a price that isn't known
+// is reported as not ok, in which case the price of the offering is used.
+type Pricing interface {
+	// OnDemandPrice returns the on-demand price of the instance type in the zone
+	OnDemandPrice(instanceType string, zone string) (float64, bool)
+	// SpotPrice returns the spot price of the instance type in the zone
+	SpotPrice(instanceType string, zone string) (float64, bool)
+}
+
+// Price returns the price of the instance type in the zone for the capacity type if the CloudProvider implements
+// Pricing and knows the price
+func Price(cloudProvider CloudProvider, instanceType, capacityType, zone string) (float64, bool) {
+	pricing, ok := cloudProvider.(Pricing)
+	if !ok {
+		return 0, false
+	}
+	switch capacityType {
+	case v1alpha5.CapacityTypeSpot:
+		return pricing.SpotPrice(instanceType, zone)
+	case v1alpha5.CapacityTypeOnDemand:
+		return pricing.OnDemandPrice(instanceType, zone)
+	}
+	return 0, false
+}
+
+// WithPrices returns the instance types with the prices of their offerings replaced by the prices that the
+// CloudProvider's Pricing reports. Instance types whose prices change are copied rather than modified.
+func WithPrices(cloudProvider CloudProvider, instanceTypes []*InstanceType) []*InstanceType {
+	if _, ok := cloudProvider.(Pricing); !ok {
+		return instanceTypes
+	}
+	return lo.Map(instanceTypes, func(it *InstanceType, _ int) *InstanceType {
+		repriced := false
+		offerings := lo.Map(it.Offerings, func(o Offering, _ int) Offering {
+			if price, ok := Price(cloudProvider, it.Name, o.CapacityType, o.Zone); ok && price != o.Price {
+				o.Price = price
+				repriced = true
+			}
+			return o
+		})
+		if !repriced {
+			return it
+		}
+		return it.WithOfferings(offerings)
+	})
+}
+
+// InstanceTypesInvalidator is optionally implemented by cloud providers whose instance types can change between calls to
+// GetInstanceTypes (e.g. when the node templates they're built from are updated) so that the instance types that are
+// cached by core are refreshed right away rather than when they expire.
+type InstanceTypesInvalidator interface {
+	// OnInstanceTypesChanged registers a func that the cloud provider calls whenever its instance types change
+	OnInstanceTypesChanged(func())
+}
+
+// ProviderIDNormalizer is optionally implemented by cloud providers whose provider IDs have more than one format
+// (e.g. a legacy and a current format) so that nodes that were launched by other autoscalers can be matched to the
+// machines that they're linked to.
+type ProviderIDNormalizer interface {
+	// NormalizeProviderID returns the canonical format of the provider ID
+	NormalizeProviderID(string) string
+}
+
+// NormalizeProviderID returns the canonical format of the provider ID if the CloudProvider has more than one format
+func NormalizeProviderID(cloudProvider CloudProvider, id string) string {
+	if normalizer, ok := cloudProvider.(ProviderIDNormalizer); ok && id != "" {
+		return normalizer.NormalizeProviderID(id)
+	}
+	return id
+}
+
 type InstanceTypes []*InstanceType
 
+// OrderByPrice orders instance types so that instance types with available reserved offerings come first, since that
+// capacity is paid for whether or not it's used, followed by the cheapest instance types of the available offerings
 func (its InstanceTypes) OrderByPrice(reqs scheduling.Requirements) InstanceTypes {
-	// Order instance types so that we get the cheapest instance types of the available offerings
 	sort.Slice(its, func(i, j int) bool {
+		iReserved := len(its[i].Offerings.Available().Requirements(reqs).Reserved()) > 0
+		jReserved := len(its[j].Offerings.Available().Requirements(reqs).Reserved()) > 0
+		if iReserved != jReserved {
+			return iReserved
+		}
 		iPrice := math.MaxFloat64
 		jPrice := math.MaxFloat64
 		if len(its[i].Offerings.Available().Requirements(reqs)) > 0 {
@@ -76,6 +283,23 @@ func (its InstanceTypes) OrderByPrice(reqs scheduling.Requirements) InstanceType
 	return its
 }
 
+// OrderByPreference orders instance types by the summed weight of the preferred scheduling terms that they match,
+// falling back to price ordering for instance types with equal preference. An instance type matches a term if its
+// requirements are compatible with the term and it has an available offering that satisfies both reqs and the term.
+func (its InstanceTypes) OrderByPreference(preferences []v1.PreferredSchedulingTerm, reqs scheduling.Requirements) InstanceTypes {
+	its = its.OrderByPrice(reqs)
+	if len(preferences) == 0 {
+		return its
+	}
+	weights := lo.SliceToMap(its, func(it *InstanceType) (string, int32) {
+		return it.Name, it.PreferenceWeight(preferences, reqs)
+	})
+	sort.SliceStable(its, func(i, j int) bool {
+		return weights[its[i].Name] > weights[its[j].Name]
+	})
+	return its
+}
+
 // InstanceType describes the properties of a potential node (either concrete attributes of an instance of this type
 // or supported options in the case of arrays)
 type InstanceType struct {
@@ -94,6 +318,8 @@ type InstanceType struct {
 
 	once        sync.Once
 	allocatable v1.ResourceList
+	// kubeletAllocatable caches the allocatable resources for each kubelet configuration, keyed by its serialized form
+	kubeletAllocatable sync.Map
 }
 
 // precompute is used to ensure we only compute the allocatable resources onces as its called many times
@@ -107,6 +333,74 @@ func (i *InstanceType) Allocatable() v1.ResourceList {
 	return i.allocatable.DeepCopy()
 }
 
+// WithOfferings returns a copy of the instance type with its offerings replaced. The instance type itself isn't
+// modified since CloudProviders may share instance types between calls.
+func (i *InstanceType) WithOfferings(offerings Offerings) *InstanceType {
+	return &InstanceType{
+		Name:         i.Name,
+		Requirements: i.Requirements,
+		Offerings:    offerings,
+		Capacity:     i.Capacity,
+		Overhead:     i.Overhead,
+	}
+}
+
+// AllocatableWithKubelet returns the allocatable resources of the instance type once the kubelet configuration of the
+// owning Provisioner is applied. Reserved resources and hard eviction thresholds set in the kubelet configuration
+// replace the instance type's defaults, and maxPods/podsPerCore cap the allocatable pods.
+func (i *InstanceType) AllocatableWithKubelet(kubelet *v1beta1.KubeletConfiguration) v1.ResourceList {
+	if kubelet == nil {
+		return i.Allocatable()
+	}
+	// this is called for every instance type that a pod is checked against during scheduling, so it's cached for each
+	// kubelet configuration like Allocatable. The serialized form is used as the key since, unlike a structural hash,
+	// it captures the values of the resource quantities.
+	key := string(lo.Must(json.Marshal(kubelet)))
+	if allocatable, ok := i.kubeletAllocatable.Load(key); ok {
+		return allocatable.(v1.ResourceList).DeepCopy()
+	}
+	allocatable := resources.Subtract(i.Capacity, i.Overhead.WithKubelet(i.Capacity, kubelet).Total())
+	if maxPods, ok := i.MaxPods(kubelet); ok {
+		if pods, exists := allocatable[v1.ResourcePods]; !exists || pods.Value() > maxPods {
+			allocatable[v1.ResourcePods] = *resource.NewQuantity(maxPods, resource.DecimalSI)
+		}
+	}
+	i.kubeletAllocatable.Store(key, allocatable)
+	return allocatable.DeepCopy()
+}
+
+// MaxPods returns the number of pods that kubelet will admit on the instance type when the kubelet configuration
+// sets maxPods or podsPerCore. If both are set, the lower of the two applies.
+func (i *InstanceType) MaxPods(kubelet *v1beta1.KubeletConfiguration) (int64, bool) {
+	if kubelet == nil || (kubelet.MaxPods == nil && lo.FromPtr(kubelet.PodsPerCore) <= 0) {
+		return 0, false
+	}
+	maxPods := int64(math.MaxInt64)
+	if kubelet.MaxPods != nil {
+		maxPods = int64(*kubelet.MaxPods)
+	}
+	if podsPerCore := lo.FromPtr(kubelet.PodsPerCore); podsPerCore > 0 {
+		maxPods = lo.Min([]int64{maxPods, int64(podsPerCore) * i.Capacity.Cpu().Value()})
+	}
+	return maxPods, true
+}
+
+// PreferenceWeight returns the summed weight of the preferred scheduling terms that the instance type matches
+func (i *InstanceType) PreferenceWeight(preferences []v1.PreferredSchedulingTerm, reqs scheduling.Requirements) int32 {
+	var weight int32
+	for _, preference := range preferences {
+		termReqs := scheduling.NewNodeSelectorRequirements(preference.Preference.MatchExpressions...)
+		if i.Requirements.Compatible(termReqs) != nil {
+			continue
+		}
+		if len(i.Offerings.Available().Requirements(reqs).Requirements(termReqs)) == 0 {
+			continue
+		}
+		weight += preference.Weight
+	}
+	return weight
+}
+
 type InstanceTypeOverhead struct {
 	// KubeReserved returns the default resources allocated to kubernetes system daemons by default
 	KubeReserved v1.ResourceList
@@ -116,10 +410,70 @@ type InstanceTypeOverhead struct {
 	EvictionThreshold v1.ResourceList
 }
 
-func (i InstanceTypeOverhead) Total() v1.ResourceList {
+func (i *InstanceTypeOverhead) Total() v1.ResourceList {
+	if i == nil {
+		return v1.ResourceList{}
+	}
 	return resources.Merge(i.KubeReserved, i.SystemReserved, i.EvictionThreshold)
 }
 
+// evictionSignals maps the kubelet eviction signals to the resource whose capacity they reserve
+var evictionSignals = map[string]v1.ResourceName{
+	"memory.available": v1.ResourceMemory,
+	"nodefs.available": v1.ResourceEphemeralStorage,
+}
+
+// WithKubelet returns the overhead with the reserved resources and eviction thresholds from the kubelet configuration
+// layered on top. Each resource set in the kubelet configuration replaces the default for that resource only; the
+// remaining defaults are kept. Kubelet starts evicting pods at the higher of the hard and soft thresholds of a
+// signal, so that's the threshold that's reserved.
+func (i *InstanceTypeOverhead) WithKubelet(capacity v1.ResourceList, kubelet *v1beta1.KubeletConfiguration) *InstanceTypeOverhead {
+	overhead := &InstanceTypeOverhead{}
+	if i != nil {
+		overhead.KubeReserved = i.KubeReserved.DeepCopy()
+		overhead.SystemReserved = i.SystemReserved.DeepCopy()
+		overhead.EvictionThreshold = i.EvictionThreshold.DeepCopy()
+	}
+	if kubelet == nil {
+		return overhead
+	}
+	overhead.KubeReserved = lo.Assign(overhead.KubeReserved, kubelet.KubeReserved)
+	overhead.SystemReserved = lo.Assign(overhead.SystemReserved, kubelet.SystemReserved)
+	for signal, resourceName := range evictionSignals {
+		var thresholds []resource.Quantity
+		for _, signals := range []map[string]string{kubelet.EvictionHard, kubelet.EvictionSoft} {
+			if value, ok := signals[signal]; ok {
+				if threshold, ok := evictionThreshold(capacity[resourceName], value); ok {
+					thresholds = append(thresholds, threshold)
+				}
+			}
+		}
+		if len(thresholds) == 0 {
+			continue
+		}
+		threshold := lo.MaxBy(thresholds, func(a, b resource.Quantity) bool { return a.Cmp(b) > 0 })
+		overhead.EvictionThreshold = lo.Assign(overhead.EvictionThreshold, v1.ResourceList{resourceName: threshold})
+	}
+	return overhead
+}
+
+// evictionThreshold resolves a kubelet eviction threshold, which is either an absolute quantity or a percentage of
+// the resource's capacity
+func evictionThreshold(capacity resource.Quantity, value string) (resource.Quantity, bool) {
+	if strings.HasSuffix(value, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return resource.Quantity{}, false
+		}
+		return *resource.NewQuantity(int64(math.Ceil(float64(capacity.Value())*percentage/100)), resource.BinarySI), true
+	}
+	threshold, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+	return threshold, true
+}
+
 // An Offering describes where an InstanceType is available to be used, with the expectation that its properties
 // may be tightly coupled (e.g. the availability of an instance type in some zone is scoped to a capacity type)
 type Offering struct {
@@ -156,69 +510,16 @@ func (ofs Offerings) Requirements(reqs scheduling.Requirements) Offerings {
 	})
 }
 
+// Reserved filters the offerings to those on reserved capacity
+func (ofs Offerings) Reserved() Offerings {
+	return lo.Filter(ofs, func(o Offering, _ int) bool {
+		return o.CapacityType == v1alpha5.CapacityTypeReserved
+	})
+}
+
 // Cheapest returns the cheapest offering from the returned offerings
 func (ofs Offerings) Cheapest() Offering {
 	return lo.MinBy(ofs, func(a, b Offering) bool {
 		return a.Price < b.Price
 	})
 }
-
-// MachineNotFoundError is an error type returned by CloudProviders when the reason for failure is NotFound
-type MachineNotFoundError struct {
-	error
-}
-
-func NewMachineNotFoundError(err error) *MachineNotFoundError {
-	return &MachineNotFoundError{
-		error: err,
-	}
-}
-
-func (e *MachineNotFoundError) Error() string {
-	return fmt.Sprintf("machine not found, %s", e.error)
-}
-
-func IsMachineNotFoundError(err error) bool {
-	if err == nil {
-		return false
-	}
-	var mnfErr *MachineNotFoundError
-	return errors.As(err, &mnfErr)
-}
-
-func IgnoreMachineNotFoundError(err error) error {
-	if IsMachineNotFoundError(err) {
-		return nil
-	}
-	return err
-}
-
-// InsufficientCapacityError is an error type returned by CloudProviders when a launch fails due to a lack of capacity from machine requirements
-type InsufficientCapacityError struct {
-	error
-}
-
-func NewInsufficientCapacityError(err error) *InsufficientCapacityError {
-	return &InsufficientCapacityError{
-		error: err,
-	}
-}
-
-func (e *InsufficientCapacityError) Error() string {
-	return fmt.Sprintf("insufficient capacity, %s", e.error)
-}
-
-func IsInsufficientCapacityError(err error) bool {
-	if err == nil {
-		return false
-	}
-	var icErr *InsufficientCapacityError
-	return errors.As(err, &icErr)
-}
-
-func IgnoreInsufficientCapacityError(err error) error {
-	if IsInsufficientCapacityError(err) {
-		return nil
-	}
-	return err
-}
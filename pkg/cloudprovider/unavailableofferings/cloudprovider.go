@@ -0,0 +1,157 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unavailableofferings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/logging"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/scheduling"
+)
+
+// UnavailableOfferingsTTL is how long an offering is excluded from scheduling after it ran out of capacity
+const UnavailableOfferingsTTL = 3 * time.Minute
+
+// decorator implements CloudProvider
+var _ cloudprovider.CloudProvider = (*decorator)(nil)
+
+type decorator struct {
+	cloudprovider.CloudProvider
+	cache *cache.Cache
+}
+
+// Decorate returns a new `CloudProvider` instance that will delegate all method calls to the argument,
+// `cloudProvider`, and remember the offerings that an insufficient capacity error was returned for when
+// creating a machine. These offerings are reported as unavailable by GetInstanceTypes until they expire, so
+// that subsequent scheduling rounds pick other offerings.
+func Decorate(cloudProvider cloudprovider.CloudProvider) cloudprovider.CloudProvider {
+	d := &decorator{
+		CloudProvider: cloudProvider,
+		cache:         cache.New(UnavailableOfferingsTTL, time.Minute),
+	}
+	// BatchCreate is only exposed if the CloudProvider implements it so that consumers can tell whether it's supported
+	if _, ok := cloudProvider.(cloudprovider.BatchCreator); ok {
+		return &batchDecorator{d}
+	}
+	return d
+}
+
+// batchDecorator is a decorator for CloudProviders that implement BatchCreate
+type batchDecorator struct {
+	*decorator
+}
+
+func (d *decorator) Create(ctx context.Context, machine *v1alpha5.Machine) (*v1alpha5.Machine, error) {
+	created, err := d.CloudProvider.Create(ctx, machine)
+	d.markUnavailable(ctx, machine, err)
+	return created, err
+}
+
+func (d *batchDecorator) BatchCreate(ctx context.Context, machines []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	created, errs := cloudprovider.BatchCreate(ctx, d.CloudProvider, machines)
+	// errors can't be matched to the machines that they were returned for if the CloudProvider didn't return one for
+	// each machine, which the caller reports
+	if len(errs) != len(machines) {
+		return created, errs
+	}
+	for i, err := range errs {
+		d.markUnavailable(ctx, machines[i], err)
+	}
+	return created, errs
+}
+
+// markUnavailable caches the offerings that ran out of capacity when launching the machine
+func (d *decorator) markUnavailable(ctx context.Context, machine *v1alpha5.Machine, err error) {
+	if !cloudprovider.IsInsufficientCapacityError(err) {
+		return
+	}
+	for _, offering := range Offerings(machine, err) {
+		logging.FromContext(ctx).With(
+			"instance-type", offering.InstanceType,
+			"zone", offering.Zone,
+			"capacity-type", offering.CapacityType,
+			"ttl", UnavailableOfferingsTTL).Debugf("removing offering from offerings")
+		d.cache.SetDefault(key(offering.InstanceType, offering.Zone, offering.CapacityType), struct{}{})
+	}
+}
+
+func (d *decorator) NormalizeProviderID(id string) string {
+	return cloudprovider.NormalizeProviderID(d.CloudProvider, id)
+}
+
+func (d *decorator) OnDemandPrice(instanceType string, zone string) (float64, bool) {
+	return cloudprovider.Price(d.CloudProvider, instanceType, v1alpha5.CapacityTypeOnDemand, zone)
+}
+
+func (d *decorator) SpotPrice(instanceType string, zone string) (float64, bool) {
+	return cloudprovider.Price(d.CloudProvider, instanceType, v1alpha5.CapacityTypeSpot, zone)
+}
+
+func (d *decorator) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+	instanceTypes, err := d.CloudProvider.GetInstanceTypes(ctx, provisioner)
+	if err != nil || d.cache.ItemCount() == 0 {
+		return instanceTypes, err
+	}
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		return d.withUnavailableOfferings(it)
+	}), nil
+}
+
+// withUnavailableOfferings returns a copy of the instance type with its cached offerings marked as unavailable
+func (d *decorator) withUnavailableOfferings(it *cloudprovider.InstanceType) *cloudprovider.InstanceType {
+	unavailable := func(o cloudprovider.Offering) bool {
+		_, ok := d.cache.Get(key(it.Name, o.Zone, o.CapacityType))
+		return o.Available && ok
+	}
+	if !lo.SomeBy(it.Offerings, unavailable) {
+		return it
+	}
+	return it.WithOfferings(lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+		if unavailable(o) {
+			o.Available = false
+		}
+		return o
+	}))
+}
+
+// Offerings returns the offerings that ran out of capacity. If the CloudProvider doesn't report them, the
+// offering is only known when the machine requirements select a single instance type, zone and capacity type.
+func Offerings(machine *v1alpha5.Machine, err error) []cloudprovider.UnavailableOffering {
+	var icErr *cloudprovider.InsufficientCapacityError
+	if errors.As(err, &icErr) && len(icErr.Offerings) > 0 {
+		return icErr.Offerings
+	}
+	requirements := scheduling.NewNodeSelectorRequirements(machine.Spec.Requirements...)
+	instanceType := requirements.Get(v1.LabelInstanceTypeStable)
+	zone := requirements.Get(v1.LabelTopologyZone)
+	capacityType := requirements.Get(v1alpha5.LabelCapacityType)
+	if instanceType.Len() != 1 || zone.Len() != 1 || capacityType.Len() != 1 {
+		return nil
+	}
+	return []cloudprovider.UnavailableOffering{{InstanceType: instanceType.Any(), Zone: zone.Any(), CapacityType: capacityType.Any()}}
+}
+
+func key(instanceType, zone, capacityType string) string {
+	return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
+}
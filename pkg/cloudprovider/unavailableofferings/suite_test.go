@@ -0,0 +1,193 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unavailableofferings_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/unavailableofferings"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var ctx context.Context
+var fakeCloudProvider *fake.CloudProvider
+var cloudProvider cloudprovider.CloudProvider
+
+func TestUnavailableOfferings(t *testing.T) {
+	ctx = context.Background()
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "UnavailableOfferings")
+}
+
+var _ = BeforeEach(func() {
+	fakeCloudProvider = fake.NewCloudProvider()
+	cloudProvider = unavailableofferings.Decorate(fakeCloudProvider)
+})
+
+var _ = Describe("UnavailableOfferings", func() {
+	It("should mark the offerings returned with an insufficient capacity error as unavailable", func() {
+		fakeCloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(errors.New("no capacity"), cloudprovider.UnavailableOffering{
+			InstanceType: "default-instance-type",
+			Zone:         "test-zone-1",
+			CapacityType: v1alpha5.CapacityTypeSpot,
+		})
+		_, err := cloudProvider.Create(ctx, test.Machine())
+		Expect(cloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+
+		ExpectUnavailable("default-instance-type", "test-zone-1", v1alpha5.CapacityTypeSpot)
+		ExpectAvailable("default-instance-type", "test-zone-2", v1alpha5.CapacityTypeSpot)
+		ExpectAvailable("default-instance-type", "test-zone-1", v1alpha5.CapacityTypeOnDemand)
+		ExpectAvailable("small-instance-type", "test-zone-1", v1alpha5.CapacityTypeSpot)
+	})
+	It("should mark the offering selected by the machine requirements as unavailable", func() {
+		fakeCloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(errors.New("no capacity"))
+		_, err := cloudProvider.Create(ctx, test.Machine(v1alpha5.Machine{
+			Spec: v1alpha5.MachineSpec{
+				Requirements: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"small-instance-type"}},
+					{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-2"}},
+					{Key: v1alpha5.LabelCapacityType, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.CapacityTypeOnDemand}},
+				},
+			},
+		}))
+		Expect(cloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+
+		ExpectUnavailable("small-instance-type", "test-zone-2", v1alpha5.CapacityTypeOnDemand)
+		ExpectAvailable("small-instance-type", "test-zone-1", v1alpha5.CapacityTypeOnDemand)
+	})
+	It("should not mark offerings as unavailable when the machine requirements select multiple offerings", func() {
+		fakeCloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(errors.New("no capacity"))
+		_, err := cloudProvider.Create(ctx, test.Machine(v1alpha5.Machine{
+			Spec: v1alpha5.MachineSpec{
+				Requirements: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"small-instance-type"}},
+					{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1", "test-zone-2"}},
+					{Key: v1alpha5.LabelCapacityType, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.CapacityTypeOnDemand}},
+				},
+			},
+		}))
+		Expect(cloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+
+		ExpectAvailable("small-instance-type", "test-zone-1", v1alpha5.CapacityTypeOnDemand)
+		ExpectAvailable("small-instance-type", "test-zone-2", v1alpha5.CapacityTypeOnDemand)
+	})
+	It("should not mark offerings as unavailable for other errors", func() {
+		fakeCloudProvider.NextCreateErr = errors.New("launch failed")
+		_, err := cloudProvider.Create(ctx, test.Machine(v1alpha5.Machine{
+			Spec: v1alpha5.MachineSpec{
+				Requirements: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"small-instance-type"}},
+					{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-2"}},
+					{Key: v1alpha5.LabelCapacityType, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.CapacityTypeOnDemand}},
+				},
+			},
+		}))
+		Expect(err).To(HaveOccurred())
+
+		ExpectAvailable("small-instance-type", "test-zone-2", v1alpha5.CapacityTypeOnDemand)
+	})
+	It("should not mark offerings as unavailable when a batch doesn't return an error for each machine", func() {
+		batchCloudProvider := &shortBatchCloudProvider{CloudProvider: fakeCloudProvider, errs: []error{
+			cloudprovider.NewInsufficientCapacityError(errors.New("no capacity"), cloudprovider.UnavailableOffering{
+				InstanceType: "default-instance-type",
+				Zone:         "test-zone-1",
+				CapacityType: v1alpha5.CapacityTypeSpot,
+			}),
+		}}
+		cloudProvider = unavailableofferings.Decorate(batchCloudProvider)
+		_, errs := cloudprovider.BatchCreate(ctx, cloudProvider, []*v1alpha5.Machine{test.Machine(), test.Machine()})
+		Expect(errs).To(HaveLen(1))
+
+		ExpectAvailable("default-instance-type", "test-zone-1", v1alpha5.CapacityTypeSpot)
+	})
+	It("should not modify the instance types of the decorated cloudprovider", func() {
+		fakeCloudProvider.InstanceTypes = []*cloudprovider.InstanceType{fake.NewInstanceType(fake.InstanceTypeOptions{Name: "default-instance-type"})}
+		fakeCloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(errors.New("no capacity"), cloudprovider.UnavailableOffering{
+			InstanceType: "default-instance-type",
+			Zone:         "test-zone-1",
+			CapacityType: v1alpha5.CapacityTypeSpot,
+		})
+		_, err := cloudProvider.Create(ctx, test.Machine())
+		Expect(cloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+
+		ExpectUnavailable("default-instance-type", "test-zone-1", v1alpha5.CapacityTypeSpot)
+		Expect(fakeCloudProvider.InstanceTypes[0].Offerings.Available()).To(HaveLen(len(fakeCloudProvider.InstanceTypes[0].Offerings)))
+	})
+	It("should forward the prices of the decorated cloudprovider", func() {
+		fakeCloudProvider.SpotPrices["default-instance-type"] = 0.5
+
+		price, ok := cloudprovider.Price(cloudProvider, "default-instance-type", v1alpha5.CapacityTypeSpot, "test-zone-1")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.5))
+		_, ok = cloudprovider.Price(cloudProvider, "default-instance-type", v1alpha5.CapacityTypeOnDemand, "test-zone-1")
+		Expect(ok).To(BeFalse())
+	})
+	It("should price the offerings of instance types with the cloudprovider's pricing", func() {
+		fakeCloudProvider.InstanceTypes = []*cloudprovider.InstanceType{fake.NewInstanceType(fake.InstanceTypeOptions{Name: "default-instance-type"})}
+		fakeCloudProvider.SpotPrices["default-instance-type"] = 0.5
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, test.Provisioner())
+		Expect(err).ToNot(HaveOccurred())
+
+		priced := cloudprovider.WithPrices(cloudProvider, instanceTypes)
+		for _, o := range priced[0].Offerings {
+			if o.CapacityType == v1alpha5.CapacityTypeSpot {
+				Expect(o.Price).To(BeNumerically("==", 0.5))
+			} else {
+				Expect(o.Price).ToNot(BeNumerically("==", 0.5))
+			}
+		}
+		// the offerings of the decorated cloudprovider aren't modified
+		Expect(lo.EveryBy(fakeCloudProvider.InstanceTypes[0].Offerings, func(o cloudprovider.Offering) bool { return o.Price != 0.5 })).To(BeTrue())
+	})
+})
+
+// shortBatchCloudProvider returns fewer errors from BatchCreate than the machines it was called with
+type shortBatchCloudProvider struct {
+	*fake.CloudProvider
+	errs []error
+}
+
+func (c *shortBatchCloudProvider) BatchCreate(_ context.Context, _ []*v1alpha5.Machine) ([]*v1alpha5.Machine, []error) {
+	return nil, c.errs
+}
+
+func ExpectAvailable(instanceType, zone, capacityType string) {
+	ExpectWithOffset(1, offering(instanceType, zone, capacityType).Available).To(BeTrue())
+}
+
+func ExpectUnavailable(instanceType, zone, capacityType string) {
+	ExpectWithOffset(1, offering(instanceType, zone, capacityType).Available).To(BeFalse())
+}
+
+func offering(instanceType, zone, capacityType string) cloudprovider.Offering {
+	instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, test.Provisioner())
+	ExpectWithOffset(2, err).ToNot(HaveOccurred())
+	it, ok := lo.Find(instanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == instanceType })
+	ExpectWithOffset(2, ok).To(BeTrue())
+	o, ok := lo.Find(it.Offerings, func(o cloudprovider.Offering) bool { return o.Zone == zone && o.CapacityType == capacityType })
+	ExpectWithOffset(2, ok).To(BeTrue())
+	return o
+}
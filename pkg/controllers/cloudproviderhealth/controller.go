@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudproviderhealth
+
+import (
+	"context"
+	"time"
+
+	"github.com/samber/lo"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
+)
+
+// checkInterval is how often the health of the CloudProvider is checked
+const checkInterval = 30 * time.Second
+
+// Controller periodically checks the health of a CloudProvider that implements HealthChecker and reports it through
+// the cloudprovider_ready metric. It deliberately doesn't fail the operator's readiness probe since that would take the
+// webhooks that are served by the same pod out of their Service while the CloudProvider is degraded.
+type Controller struct {
+	checker cloudprovider.HealthChecker
+}
+
+// NewController is a constructor
+func NewController(checker cloudprovider.HealthChecker) *Controller {
+	return &Controller{checker: checker}
+}
+
+func (c *Controller) Name() string {
+	return "cloudprovider.health"
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	err := c.checker.Ready(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("cloudprovider isn't ready, %s", err)
+	}
+	ReadyGauge.Set(lo.Ternary(err == nil, 1.0, 0.0))
+	return reconcile.Result{RequeueAfter: checkInterval}, nil
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.NewSingletonManagedBy(m)
+}
@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudproviderhealth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	. "knative.dev/pkg/logging/testing"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/cloudproviderhealth"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var ctx context.Context
+var cloudProvider *fake.CloudProvider
+var healthController *cloudproviderhealth.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CloudProviderHealth")
+}
+
+var _ = BeforeEach(func() {
+	cloudProvider = fake.NewCloudProvider()
+	healthController = cloudproviderhealth.NewController(cloudProvider)
+})
+
+var _ = Describe("CloudProviderHealth", func() {
+	It("should report the cloudprovider as ready when its health check succeeds", func() {
+		result := ExpectReconcileSucceeded(ctx, healthController, types.NamespacedName{})
+		Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+
+		ExpectReady(1)
+	})
+	It("should report the cloudprovider as not ready when its health check fails", func() {
+		cloudProvider.ReadyErr = errors.New("credentials expired")
+		ExpectReconcileSucceeded(ctx, healthController, types.NamespacedName{})
+		ExpectReady(0)
+	})
+	It("should report the cloudprovider as ready again once its health check recovers", func() {
+		cloudProvider.ReadyErr = errors.New("credentials expired")
+		ExpectReconcileSucceeded(ctx, healthController, types.NamespacedName{})
+		ExpectReady(0)
+
+		cloudProvider.ReadyErr = nil
+		ExpectReconcileSucceeded(ctx, healthController, types.NamespacedName{})
+		ExpectReady(1)
+	})
+})
+
+func ExpectReady(value float64) {
+	m, ok := FindMetricWithLabelValues("karpenter_cloudprovider_ready", map[string]string{})
+	ExpectWithOffset(1, ok).To(BeTrue())
+	ExpectWithOffset(1, m.GetGauge().GetValue()).To(BeNumerically("==", value))
+}
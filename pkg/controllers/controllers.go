@@ -22,18 +22,24 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/instancetypecache"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/unavailableofferings"
+	"github.com/aws/karpenter-core/pkg/controllers/cloudproviderhealth"
 	"github.com/aws/karpenter-core/pkg/controllers/deprovisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/interruption"
 	"github.com/aws/karpenter-core/pkg/controllers/leasegarbagecollection"
 	"github.com/aws/karpenter-core/pkg/controllers/machine/consistency"
 	nodeclaimdisruption "github.com/aws/karpenter-core/pkg/controllers/machine/disruption"
 	nodeclaimgarbagecollection "github.com/aws/karpenter-core/pkg/controllers/machine/garbagecollection"
 	nodeclaimlifecycle "github.com/aws/karpenter-core/pkg/controllers/machine/lifecycle"
 	nodeclaimtermination "github.com/aws/karpenter-core/pkg/controllers/machine/termination"
+	nodeclaimwatch "github.com/aws/karpenter-core/pkg/controllers/machine/watch"
 	metricsnode "github.com/aws/karpenter-core/pkg/controllers/metrics/node"
 	metricspod "github.com/aws/karpenter-core/pkg/controllers/metrics/pod"
 	metricsprovisioner "github.com/aws/karpenter-core/pkg/controllers/metrics/provisioner"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioner/counter"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioner/hash"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioner/replicas"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/controllers/state/informer"
@@ -52,11 +58,19 @@ func NewControllers(
 	recorder events.Recorder,
 	cloudProvider cloudprovider.CloudProvider,
 ) []controller.Controller {
-
+	// Interruption handling is only enabled for cloudproviders that are able to notify us of interruptions
+	source, isInterruptionSource := cloudProvider.(cloudprovider.InterruptionSource)
+	// Machine events are only consumed from cloudproviders that are able to push them
+	watcher, isMachineWatcher := cloudProvider.(cloudprovider.MachineWatcher)
+	// Health checks are only enabled for cloudproviders that are able to report their health
+	checker, isHealthChecker := cloudProvider.(cloudprovider.HealthChecker)
+	// Instance types are cached so that scheduling and deprovisioning loops don't call the CloudProvider every time.
+	// Offerings that run out of capacity are excluded from scheduling for every CloudProvider.
+	cloudProvider = unavailableofferings.Decorate(instancetypecache.Decorate(cloudProvider))
 	p := provisioning.NewProvisioner(kubeClient, kubernetesInterface.CoreV1(), recorder, cloudProvider, cluster)
-	terminator := terminator.NewTerminator(clock, kubeClient, terminator.NewEvictionQueue(ctx, kubernetesInterface.CoreV1(), recorder))
+	terminator := terminator.NewTerminator(clock, kubeClient, terminator.NewEvictionQueue(ctx, clock, kubernetesInterface.CoreV1(), recorder), recorder)
 
-	return []controller.Controller{
+	controllers := []controller.Controller{
 		p,
 		deprovisioning.NewController(clock, kubeClient, p, cloudProvider, recorder, cluster),
 		provisioning.NewController(kubeClient, p, recorder),
@@ -67,15 +81,26 @@ func NewControllers(
 		informer.NewProvisionerController(kubeClient, cluster),
 		informer.NewMachineController(kubeClient, cluster),
 		termination.NewController(kubeClient, cloudProvider, terminator, recorder),
-		metricspod.NewController(kubeClient),
-		metricsprovisioner.NewController(kubeClient),
+		metricspod.NewController(clock, kubeClient),
+		metricsprovisioner.NewController(clock, kubeClient),
 		metricsnode.NewController(cluster),
 		counter.NewProvisionerController(kubeClient, cluster),
+		replicas.NewProvisionerController(kubeClient, cluster, cloudProvider, p),
 		consistency.NewMachineController(clock, kubeClient, recorder, cloudProvider),
-		nodeclaimlifecycle.NewMachineController(clock, kubeClient, cloudProvider, recorder),
-		nodeclaimgarbagecollection.NewController(clock, kubeClient, cloudProvider),
-		nodeclaimtermination.NewMachineController(kubeClient, cloudProvider),
-		nodeclaimdisruption.NewMachineController(clock, kubeClient, cluster, cloudProvider),
+		nodeclaimlifecycle.NewMachineController(ctx, clock, kubeClient, cloudProvider, cluster, recorder),
+		nodeclaimgarbagecollection.NewController(clock, kubeClient, cloudProvider, recorder, isMachineWatcher),
+		nodeclaimtermination.NewMachineController(clock, kubeClient, cloudProvider, recorder),
+		nodeclaimdisruption.NewMachineController(clock, kubeClient, cluster, cloudProvider, kubernetesInterface.Discovery()),
 		leasegarbagecollection.NewController(kubeClient),
 	}
+	if isInterruptionSource {
+		controllers = append(controllers, interruption.NewController(clock, kubeClient, source, terminator, recorder))
+	}
+	if isMachineWatcher {
+		controllers = append(controllers, nodeclaimwatch.NewController(clock, kubeClient, cloudProvider, watcher, terminator))
+	}
+	if isHealthChecker {
+		controllers = append(controllers, cloudproviderhealth.NewController(checker))
+	}
+	return controllers
 }
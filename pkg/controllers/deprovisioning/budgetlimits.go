@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+)
+
+// BudgetLimits is used to evaluate how many more nodes each NodePool can have voluntarily disrupted based on its
+// disruption budgets and the disruptions that are already in-flight in cluster state.
+type BudgetLimits struct {
+	allowed map[nodepoolutil.Key]int
+}
+
+func NewBudgetLimits(ctx context.Context, clk clock.Clock, cluster *state.Cluster, candidates []*Candidate) *BudgetLimits {
+	nodes, disrupting := cluster.DisruptionCounts()
+	b := &BudgetLimits{allowed: map[nodepoolutil.Key]int{}}
+	for _, c := range candidates {
+		key := c.OwnerKey()
+		if _, ok := b.allowed[key]; ok {
+			continue
+		}
+		allowed, err := c.nodePool.AllowedDisruptions(clk, nodes[key])
+		if err != nil {
+			// Budgets are validated at admission, so we should never get here. If we do, block disruption rather
+			// than disrupting more nodes than the user intended.
+			logging.FromContext(ctx).With("nodepool", key.Name).Errorf("computing allowed disruptions, %s", err)
+			allowed = 0
+		}
+		b.allowed[key] = allowed - disrupting[key]
+	}
+	return b
+}
+
+// Allowed returns the number of nodes owned by the NodePool that can still be disrupted
+func (b *BudgetLimits) Allowed(key nodepoolutil.Key) int {
+	return b.allowed[key]
+}
+
+// Reason returns a description of why a candidate is being deferred by its NodePool's disruption budget
+func (b *BudgetLimits) Reason(key nodepoolutil.Key) string {
+	return fmt.Sprintf("Disruption budget for %q is exhausted", key.Name)
+}
+
+// ApplyToCommand restricts the command to the remaining disruption budgets. Delete commands are trimmed to fit the
+// budgets, while replace commands that exceed them are deferred entirely since their replacements were computed for
+// the full set of candidates. It returns the candidates that were deferred.
+func (b *BudgetLimits) ApplyToCommand(cmd Command) (Command, []*Candidate) {
+	remaining := map[nodepoolutil.Key]int{}
+	for k, v := range b.allowed {
+		remaining[k] = v
+	}
+	var allowed, deferred []*Candidate
+	for _, c := range cmd.candidates {
+		if remaining[c.OwnerKey()] <= 0 {
+			deferred = append(deferred, c)
+			continue
+		}
+		remaining[c.OwnerKey()]--
+		allowed = append(allowed, c)
+	}
+	if len(deferred) == 0 {
+		return cmd, nil
+	}
+	if cmd.Action() == ReplaceAction {
+		return Command{}, cmd.candidates
+	}
+	return Command{candidates: allowed}, deferred
+}
@@ -26,11 +26,13 @@ import (
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
@@ -72,7 +74,7 @@ func (c *consolidation) String() string {
 // sortAndFilterCandidates orders deprovisionable nodes by the disruptionCost, removing any that we already know won't
 // be viable consolidation options.
 func (c *consolidation) sortAndFilterCandidates(ctx context.Context, nodes []*Candidate) ([]*Candidate, error) {
-	candidates, err := filterCandidates(ctx, c.kubeClient, c.recorder, nodes)
+	candidates, err := filterCandidates(ctx, c.kubeClient, c.recorder, nodes, false)
 	if err != nil {
 		return nil, fmt.Errorf("filtering candidates, %w", err)
 	}
@@ -94,7 +96,7 @@ func (c *consolidation) markConsolidated() {
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable nodes
-func (c *consolidation) ShouldDeprovision(_ context.Context, cn *Candidate) bool {
+func (c *consolidation) ShouldDeprovision(ctx context.Context, cn *Candidate) bool {
 	if cn.Annotations()[v1alpha5.DoNotConsolidateNodeAnnotationKey] == "true" {
 		c.recorder.Publish(deprovisioningevents.Unconsolidatable(cn.Node, cn.NodeClaim, fmt.Sprintf("%s annotation exists", v1alpha5.DoNotConsolidateNodeAnnotationKey))...)
 		return false
@@ -103,9 +105,46 @@ func (c *consolidation) ShouldDeprovision(_ context.Context, cn *Candidate) bool
 		c.recorder.Publish(deprovisioningevents.Unconsolidatable(cn.Node, cn.NodeClaim, fmt.Sprintf("%s %q has empty consolidation disabled by consolidation policy", lo.Ternary(cn.nodePool.IsProvisioner, "Provisioner", "NodePool"), cn.nodePool.Name))...)
 		return false
 	}
+	if atReplicaFloor(cn) {
+		c.recorder.Publish(deprovisioningevents.Unconsolidatable(cn.Node, cn.NodeClaim, fmt.Sprintf("%s %q is at its minimum of %d replicas", lo.Ternary(cn.nodePool.IsProvisioner, "Provisioner", "NodePool"), cn.nodePool.Name, *cn.nodePool.Spec.Replicas))...)
+		return false
+	}
+	// Wait for the node's pods to settle before considering it, measuring from node creation if no pod has been
+	// bound to or removed from the node yet
+	if consolidateAfter := cn.nodePool.Spec.Deprovisioning.ConsolidateAfter; consolidateAfter != nil {
+		stableSince := cn.LastPodEventTime()
+		if stableSince.Before(cn.Node.CreationTimestamp.Time) {
+			stableSince = cn.Node.CreationTimestamp.Time
+		}
+		if c.clock.Since(stableSince) < consolidateAfter.Duration {
+			c.recorder.Publish(deprovisioningevents.Unconsolidatable(cn.Node, cn.NodeClaim, fmt.Sprintf("node has had pod changes within consolidateAfter (%s)", consolidateAfter.Duration))...)
+			return false
+		}
+	}
+	// Skip well utilized nodes before running any scheduling simulations for them
+	if threshold := settings.FromContext(ctx).ConsolidationUtilizationThreshold; threshold > 0 {
+		if u := utilization(cn); u >= threshold {
+			c.recorder.Publish(deprovisioningevents.Unconsolidatable(cn.Node, cn.NodeClaim, fmt.Sprintf("node utilization (%.0f%%) is above the consolidation utilization threshold (%.0f%%)", u, threshold))...)
+			return false
+		}
+	}
 	return true
 }
 
+// utilization returns the higher of the candidate's CPU and memory utilization, as the percentage of its allocatable
+// resources that are requested by pods
+func utilization(cn *Candidate) float64 {
+	requests, allocatable := cn.PodRequests(), cn.Allocatable()
+	return lo.Max(lo.Map([]v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}, func(name v1.ResourceName, _ int) float64 {
+		total := allocatable[name]
+		if total.IsZero() {
+			return 0
+		}
+		used := requests[name]
+		return 100 * used.AsApproximateFloat64() / total.AsApproximateFloat64()
+	}))
+}
+
 // computeConsolidation computes a consolidation action to take
 //
 // nolint:gocyclo
@@ -131,6 +170,14 @@ func (c *consolidation) computeConsolidation(ctx context.Context, candidates ...
 
 	// were we able to schedule all the pods on the inflight candidates?
 	if len(results.NewNodeClaims) == 0 {
+		// removing the candidates saves their entire price, which can still fall short of an absolute threshold. If
+		// the price is unknown, we don't block the deletion.
+		if nodesPrice, err := getCandidatePrices(candidates); err == nil && nodesPrice < minSavings(ctx, candidates, nodesPrice) {
+			if len(candidates) == 1 {
+				c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim, "Savings from removing the node are below the minimum savings threshold")...)
+			}
+			return Command{}, nil
+		}
 		return Command{
 			candidates: candidates,
 		}, nil
@@ -144,24 +191,39 @@ func (c *consolidation) computeConsolidation(ctx context.Context, candidates ...
 		return Command{}, nil
 	}
 
+	// Capacity reservations are paid for whether or not they're used, so replacing a node on reserved capacity with a
+	// cheaper node would only add cost. Removing the node is still allowed since its reservation can be reused.
+	if unreserved := lo.Reject(candidates, func(cn *Candidate, _ int) bool { return cn.capacityType == v1alpha5.CapacityTypeReserved }); len(unreserved) != len(candidates) {
+		if len(candidates) == 1 {
+			c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim, "Can't replace a node on reserved capacity")...)
+		}
+		if len(unreserved) == 0 {
+			return Command{}, nil
+		}
+		// multi-node consolidation leaves the nodes on reserved capacity in place and consolidates the rest
+		return c.computeConsolidation(ctx, unreserved...)
+	}
+
 	// get the current node price based on the offering
 	// fallback if we can't find the specific zonal pricing data
 	nodesPrice, err := getCandidatePrices(candidates)
 	if err != nil {
 		return Command{}, fmt.Errorf("getting offering price from candidate node, %w", err)
 	}
-	results.NewNodeClaims[0].InstanceTypeOptions = filterByPrice(results.NewNodeClaims[0].InstanceTypeOptions, results.NewNodeClaims[0].Requirements, nodesPrice)
+	// only consider replacements that are cheaper by at least the minimum savings
+	savings := minSavings(ctx, candidates, nodesPrice)
+	results.NewNodeClaims[0].InstanceTypeOptions = filterByPrice(results.NewNodeClaims[0].InstanceTypeOptions, results.NewNodeClaims[0].Requirements, nodesPrice-savings)
 	if len(results.NewNodeClaims[0].InstanceTypeOptions) == 0 {
 		if len(candidates) == 1 {
-			c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim, "Can't replace with a cheaper node")...)
+			c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim,
+				lo.Ternary(savings > 0, "Can't replace with a node that is cheaper by the minimum savings threshold", "Can't replace with a cheaper node"))...)
 		}
 		// no instance types remain after filtering by price
 		return Command{}, nil
 	}
 
-	// If the existing candidates are all spot and the replacement is spot, we don't consolidate.  We don't have a reliable
-	// mechanism to determine if this replacement makes sense given instance type availability (e.g. we may replace
-	// a spot node with one that is less available and more likely to be reclaimed).
+	// If the existing candidates are all spot and the replacement is spot, we only consolidate if spot-to-spot
+	// consolidation is enabled and leaves the replacement enough flexibility.
 	allExistingAreSpot := true
 	for _, cn := range candidates {
 		if cn.capacityType != v1alpha5.CapacityTypeSpot {
@@ -171,19 +233,16 @@ func (c *consolidation) computeConsolidation(ctx context.Context, candidates ...
 
 	if allExistingAreSpot &&
 		results.NewNodeClaims[0].Requirements.Get(v1alpha5.LabelCapacityType).Has(v1alpha5.CapacityTypeSpot) {
-		if len(candidates) == 1 {
-			c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim, "Can't replace a spot node with a spot node")...)
-		}
-		return Command{}, nil
+		return c.computeSpotToSpotConsolidation(ctx, candidates, results)
 	}
 
 	// We are consolidating a node from OD -> [OD,Spot] but have filtered the instance types by cost based on the
 	// assumption, that the spot variant will launch. We also need to add a requirement to the node to ensure that if
 	// spot capacity is insufficient we don't replace the node with a more expensive on-demand node.  Instead the launch
-	// should fail and we'll just leave the node alone.
+	// should fail and we'll just leave the node alone. Reserved capacity is still allowed since it's launched first.
 	ctReq := results.NewNodeClaims[0].Requirements.Get(v1alpha5.LabelCapacityType)
 	if ctReq.Has(v1alpha5.CapacityTypeSpot) && ctReq.Has(v1alpha5.CapacityTypeOnDemand) {
-		results.NewNodeClaims[0].Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, v1alpha5.CapacityTypeSpot))
+		results.NewNodeClaims[0].Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, v1alpha5.CapacityTypeReserved, v1alpha5.CapacityTypeSpot))
 	}
 
 	return Command{
@@ -192,6 +251,74 @@ func (c *consolidation) computeConsolidation(ctx context.Context, candidates ...
 	}, nil
 }
 
+// computeSpotToSpotConsolidation computes a command that replaces a single spot node with a cheaper spot node. Always
+// launching the cheapest spot offering would chase the pools that are most likely to be reclaimed, so we only
+// consolidate when enough cheaper instance types remain and launch the replacement with that many options.
+func (c *consolidation) computeSpotToSpotConsolidation(ctx context.Context, candidates []*Candidate, results *pscheduling.Results) (Command, error) {
+	// Spot-to-spot consolidation is only performed by single machine consolidation
+	if len(candidates) != 1 {
+		return Command{}, nil
+	}
+	if !settings.FromContext(ctx).SpotToSpotConsolidation {
+		c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim, "Can't replace a spot node with a spot node")...)
+		return Command{}, nil
+	}
+	// The replacement must launch as spot, we've already filtered the instance types by the price of their spot offerings
+	results.NewNodeClaims[0].Requirements.Add(scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, v1alpha5.CapacityTypeSpot))
+	minFlexibility := settings.FromContext(ctx).SpotToSpotConsolidationMinFlexibility
+	if len(results.NewNodeClaims[0].InstanceTypeOptions) < minFlexibility {
+		c.recorder.Publish(deprovisioningevents.Unconsolidatable(candidates[0].Node, candidates[0].NodeClaim,
+			fmt.Sprintf("Spot-to-spot consolidation requires %d cheaper instance type options, found %d", minFlexibility, len(results.NewNodeClaims[0].InstanceTypeOptions)))...)
+		return Command{}, nil
+	}
+	// Only keep the cheapest options so that the replacement isn't immediately consolidated again
+	results.NewNodeClaims[0].InstanceTypeOptions = results.NewNodeClaims[0].InstanceTypeOptions.OrderByPrice(results.NewNodeClaims[0].Requirements)[:minFlexibility]
+	return Command{
+		candidates:   candidates,
+		replacements: results.NewNodeClaims,
+	}, nil
+}
+
+// minSavings returns the minimum reduction in price per hour that consolidating the candidates must achieve. Each
+// NodePool's thresholds take precedence over the global settings, and the most restrictive threshold across the
+// candidates' NodePools is used.
+func minSavings(ctx context.Context, candidates []*Candidate, price float64) float64 {
+	var savings float64
+	for _, cn := range candidates {
+		percent := float64(settings.FromContext(ctx).ConsolidationMinSavingsPercent)
+		if cn.nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent != nil {
+			percent = float64(*cn.nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent)
+		}
+		perHour := settings.FromContext(ctx).ConsolidationMinSavingsPerHour
+		if cn.nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPerHour != nil {
+			perHour = cn.nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPerHour.AsApproximateFloat64()
+		}
+		savings = lo.Max([]float64{savings, price * percent / 100, perHour})
+	}
+	return savings
+}
+
+// estimatedSavings returns the hourly price of the candidates minus the price of launching the cheapest instance type
+// for each of the replacements. The second return value is false if any of the prices can't be determined.
+func estimatedSavings(cmd Command) (float64, bool) {
+	savings, err := getCandidatePrices(cmd.candidates)
+	if err != nil {
+		return 0, false
+	}
+	for _, replacement := range cmd.replacements {
+		instanceTypes := replacement.InstanceTypeOptions.OrderByPrice(replacement.Requirements)
+		if len(instanceTypes) == 0 {
+			return 0, false
+		}
+		offerings := instanceTypes[0].Offerings.Available().Requirements(replacement.Requirements)
+		if len(offerings) == 0 {
+			return 0, false
+		}
+		savings -= offerings.Cheapest().Price
+	}
+	return savings, true
+}
+
 // getCandidatePrices returns the sum of the prices of the given candidate nodes
 func getCandidatePrices(candidates []*Candidate) (float64, error) {
 	var price float64
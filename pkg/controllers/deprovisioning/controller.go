@@ -21,10 +21,13 @@ import (
 	"time"
 
 	"github.com/avast/retry-go"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
@@ -32,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
@@ -52,6 +56,8 @@ type Controller struct {
 	clock          clock.Clock
 	cloudProvider  cloudprovider.CloudProvider
 	deprovisioners []Deprovisioner
+	rateLimiter    *DisruptionRateLimiter
+	vetoer         Vetoer
 	mu             sync.Mutex
 	lastRun        map[string]time.Time
 }
@@ -86,11 +92,15 @@ func NewController(clk clock.Clock, kubeClient client.Client, provisioner *provi
 		recorder:      recorder,
 		cloudProvider: cp,
 		lastRun:       map[string]time.Time{},
+		rateLimiter:   NewDisruptionRateLimiter(clk),
+		vetoer:        NewWebhookVetoer(),
 		deprovisioners: []Deprovisioner{
+			// Replace any machines whose nodes have stayed unhealthy, as their pods are unlikely to be running.
+			NewRepair(kubeClient, cluster, provisioner, recorder),
 			// Expire any machines that must be deleted, allowing their pods to potentially land on currently
 			NewExpiration(clk, kubeClient, cluster, provisioner, recorder),
 			// Terminate any machines that have drifted from provisioning specifications, allowing the pods to reschedule.
-			NewDrift(kubeClient, cluster, provisioner, recorder),
+			NewDrift(clk, kubeClient, cluster, provisioner, recorder),
 			// Delete any remaining empty machines as there is zero cost in terms of disruption.  Emptiness and
 			// emptyNodeConsolidation are mutually exclusive, only one of these will operate
 			NewEmptiness(clk),
@@ -124,8 +134,12 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 		logging.FromContext(ctx).Debugf("waiting on cluster sync")
 		return reconcile.Result{RequeueAfter: time.Second}, nil
 	}
+	// Don't compute more commands than we can execute
+	if c.cluster.DeprovisioningCommands() >= settings.FromContext(ctx).DeprovisioningMaxParallelActions {
+		return reconcile.Result{RequeueAfter: time.Second}, nil
+	}
 	// Attempt different deprovisioning methods. We'll only let one method perform an action
-	for _, d := range c.deprovisioners {
+	for _, d := range c.orderedDeprovisioners(ctx) {
 		c.recordRun(fmt.Sprintf("%T", d))
 		success, err := c.deprovision(ctx, d)
 		if err != nil {
@@ -140,52 +154,234 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 	return reconcile.Result{RequeueAfter: pollingPeriod}, nil
 }
 
+// orderedDeprovisioners returns the deprovisioners in the globally configured deprovisioning order, dropping any
+// methods that aren't listed. Deprovisioners that share a method (e.g. consolidation) keep their relative order.
+func (c *Controller) orderedDeprovisioners(ctx context.Context) []Deprovisioner {
+	return lo.FlatMap(withRepair(settings.FromContext(ctx).DeprovisioningOrder), func(method string, _ int) []Deprovisioner {
+		return lo.Filter(c.deprovisioners, func(d Deprovisioner, _ int) bool { return d.String() == method })
+	})
+}
+
+// withRepair puts repair first in a deprovisioning order that omits it. Repair is enabled by
+// nodeRepairUnhealthyDuration rather than by the order, so that orders written before repair existed don't disable it.
+func withRepair(order []string) []string {
+	if lo.Contains(order, metrics.RepairReason) {
+		return order
+	}
+	return append([]string{metrics.RepairReason}, order...)
+}
+
+// shouldDeprovision extends the deprovisioner's predicate with the deprovisioning order of the candidate's NodePool.
+// A candidate is skipped if its NodePool omits the method, or if a method that the NodePool prioritizes above it
+// would also deprovision the candidate, in which case we leave the candidate for that method.
+func (c *Controller) shouldDeprovision(deprovisioner Deprovisioner) CandidateFilter {
+	return func(ctx context.Context, cn *Candidate) bool {
+		if !deprovisioner.ShouldDeprovision(ctx, cn) {
+			return false
+		}
+		order := cn.nodePool.Spec.Deprovisioning.Order
+		if len(order) == 0 {
+			return true
+		}
+		order = withRepair(order)
+		i := lo.IndexOf(order, deprovisioner.String())
+		if i < 0 {
+			return false
+		}
+		// Consolidation only determines eligibility here, the decision itself is made when computing the command,
+		// so it can't preempt the methods ordered after it
+		return !lo.ContainsBy(c.orderedDeprovisioners(ctx), func(d Deprovisioner) bool {
+			return d.String() != metrics.ConsolidationReason && lo.Contains(order[:i], d.String()) && d.ShouldDeprovision(ctx, cn)
+		})
+	}
+}
+
 func (c *Controller) deprovision(ctx context.Context, deprovisioner Deprovisioner) (bool, error) {
 	defer metrics.Measure(deprovisioningDurationHistogram.WithLabelValues(deprovisioner.String()))()
-	candidates, err := GetCandidates(ctx, c.cluster, c.kubeClient, c.recorder, c.clock, c.cloudProvider, deprovisioner.ShouldDeprovision)
+	ctx = withMethodLabels(ctx, deprovisioner)
+	candidates, err := GetCandidates(ctx, c.cluster, c.kubeClient, c.recorder, c.clock, c.cloudProvider, c.shouldDeprovision(deprovisioner))
 	if err != nil {
 		return false, fmt.Errorf("determining candidates, %w", err)
 	}
+	// Skip candidates whose NodePool has no disruption budget remaining
+	budgets := NewBudgetLimits(ctx, c.clock, c.cluster, candidates)
+	candidates = lo.Filter(candidates, func(cn *Candidate, _ int) bool {
+		if budgets.Allowed(cn.OwnerKey()) <= 0 {
+			c.recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, budgets.Reason(cn.OwnerKey()))...)
+			return false
+		}
+		return true
+	})
 	// If there are no candidate nodes, move to the next deprovisioner
 	if len(candidates) == 0 {
 		return false, nil
 	}
+	deprovisioningCandidatesEvaluatedCounter.With(methodLabels(deprovisioner)).Add(float64(len(candidates)))
 
 	// Determine the deprovisioning action
 	cmd, err := deprovisioner.ComputeCommand(ctx, candidates...)
 	if err != nil {
 		return false, fmt.Errorf("computing deprovisioning decision, %w", err)
 	}
+	// A single command may disrupt more nodes than the budgets allow, so restrict it to what remains
+	cmd, deferred := budgets.ApplyToCommand(cmd)
+	for _, cn := range deferred {
+		c.recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, budgets.Reason(cn.OwnerKey()))...)
+	}
+	// The cluster-wide rate limit applies across all deprovisioners
+	cmd, deferred = c.rateLimiter.ApplyToCommand(ctx, cmd)
+	if len(deferred) > 0 {
+		deprovisioningRateLimitedActionsCounter.WithLabelValues(deprovisioner.String()).Inc()
+		for _, cn := range deferred {
+			c.recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, "Disruption rate limit has been reached")...)
+		}
+	}
 	if cmd.Action() == NoOpAction {
 		return false, nil
 	}
+	// In dry-run mode, report the command and move on to the next deprovisioner without disrupting anything
+	if dryRun(ctx, cmd) {
+		c.reportDryRun(ctx, deprovisioner, cmd)
+		return false, nil
+	}
+	// Give any external systems a chance to veto the command, its candidates are reconsidered on a later pass
+	if vetoed, reason, err := c.vetoer.Veto(ctx, deprovisioner, cmd); vetoed {
+		if err != nil {
+			logging.FromContext(ctx).Errorf("checking whether deprovisioning is vetoed, %s", err)
+			reason = "deprovisioning webhook could not be reached"
+		}
+		deprovisioningVetoedActionsCounter.WithLabelValues(deprovisioner.String()).Inc()
+		for _, cn := range cmd.candidates {
+			c.recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, fmt.Sprintf("Disruption was vetoed by the deprovisioning webhook: %s", reason))...)
+		}
+		return false, nil
+	}
+	c.rateLimiter.Take(ctx, len(cmd.candidates))
+	// Count the command against the deprovisioner's own limits as it's dispatched, so that commands computed while it's
+	// in flight can't overshoot them
+	if r, ok := deprovisioner.(CommandRecorder); ok {
+		r.RecordCommand(cmd)
+	}
+	// Track the command in cluster state while it executes so that other commands don't select its candidates and
+	// scheduling simulations for other commands don't move pods onto them
+	id := uuid.NewUUID()
+	c.cluster.StartDeprovisioningCommand(id, lo.Map(cmd.candidates, func(cn *Candidate, _ int) string { return cn.ProviderID() })...)
 
 	// Attempt to deprovision
-	if err := c.executeCommand(ctx, deprovisioner, cmd); err != nil {
+	if settings.FromContext(ctx).DeprovisioningMaxParallelActions > 1 {
+		c.executeCommandAsync(ctx, deprovisioner, id, cmd)
+		return true, nil
+	}
+	if err := c.executeCommand(ctx, deprovisioner, id, cmd); err != nil {
 		return false, fmt.Errorf("deprovisioning candidates, %w", err)
 	}
 
 	return true, nil
 }
 
-func (c *Controller) executeCommand(ctx context.Context, d Deprovisioner, command Command) error {
+// executeCommandAsync executes the command in the background so that commands for other candidates can be computed
+// and executed concurrently. The command is already tracked in cluster state, which excludes its candidates from
+// subsequent commands and counts them against the disruption budgets while the command is in flight.
+func (c *Controller) executeCommandAsync(ctx context.Context, d Deprovisioner, id types.UID, cmd Command) {
+	go func() {
+		if err := c.executeCommand(ctx, d, id, cmd); err != nil {
+			logging.FromContext(ctx).Errorf("deprovisioning candidates, %s", err)
+		}
+	}()
+}
+
+// dryRun returns true if deprovisioning is in dry-run mode globally or for the NodePool of any of the candidates
+func dryRun(ctx context.Context, cmd Command) bool {
+	return settings.FromContext(ctx).DeprovisioningDryRun || lo.ContainsBy(cmd.candidates, func(cn *Candidate) bool {
+		return cn.nodePool.Spec.Deprovisioning.DryRun
+	})
+}
+
+// reportDryRun surfaces the command that would have been executed through logs, events and metrics
+func (c *Controller) reportDryRun(ctx context.Context, d Deprovisioner, cmd Command) {
+	deprovisioningDryRunActionsCounter.With(map[string]string{
+		actionLabel:        fmt.Sprintf("%s/%s", d, cmd.Action()),
+		deprovisionerLabel: d.String(),
+	}).Inc()
+	logger := logging.FromContext(ctx)
+	if savings, ok := estimatedSavings(cmd); ok {
+		logger = logger.With("estimated-savings-per-hour", savings)
+	}
+	logger.Infof("dry run, would deprovision via %s %s", d, cmd)
+
+	reason := fmt.Sprintf("%s/%s", d, cmd.Action())
+	for _, candidate := range cmd.candidates {
+		c.recorder.Publish(deprovisioningevents.DryRun(candidate.Node, candidate.NodeClaim, reason)...)
+	}
+}
+
+// recordDecision leaves an audit trail of an executed command through a structured log line and an event on each
+// candidate so that users can reconstruct why a node was disrupted after the fact
+func (c *Controller) recordDecision(ctx context.Context, d Deprovisioner, cmd Command, replacements []nodeclaimutil.Key,
+	duration time.Duration, err error) {
+	decision := deprovisioningevents.Decision{
+		Method:          d.String(),
+		Action:          string(cmd.Action()),
+		Candidates:      lo.Map(cmd.candidates, func(cn *Candidate, _ int) string { return cn.Name() }),
+		Replacements:    lo.Map(replacements, func(k nodeclaimutil.Key, _ int) string { return k.Name }),
+		Outcome:         deprovisioningevents.DecisionSucceeded,
+		DurationSeconds: duration.Seconds(),
+	}
+	if savings, ok := estimatedSavings(cmd); ok {
+		decision.EstimatedSavings = lo.ToPtr(savings)
+	}
+	if err != nil {
+		decision.Outcome = deprovisioningevents.DecisionFailed
+		decision.Error = err.Error()
+	}
+	deprovisioningActionOutcomesCounter.With(lo.Assign(methodLabels(d), prometheus.Labels{outcomeLabel: decision.Outcome})).Inc()
+	if decision.Outcome == deprovisioningevents.DecisionSucceeded && decision.EstimatedSavings != nil {
+		deprovisioningEstimatedSavingsHistogram.With(methodLabels(d)).Observe(*decision.EstimatedSavings)
+	}
+	logger := logging.FromContext(ctx).With("method", decision.Method, "action", decision.Action, "candidates", decision.Candidates,
+		"replacements", decision.Replacements, "outcome", decision.Outcome, "duration", duration)
+	if decision.EstimatedSavings != nil {
+		logger = logger.With("estimated-savings-per-hour", *decision.EstimatedSavings)
+	}
+	logger.Infof("deprovisioning decision")
+
+	for _, candidate := range cmd.candidates {
+		c.recorder.Publish(deprovisioningevents.DecisionRecorded(candidate.Node, candidate.NodeClaim, decision)...)
+	}
+}
+
+// executeCommand executes the command that was started in cluster state under the id, finishing it once the command
+// completes
+func (c *Controller) executeCommand(ctx context.Context, d Deprovisioner, id types.UID, command Command) (err error) {
+	start := c.clock.Now()
+	var replacements []nodeclaimutil.Key
+	defer func() { c.recordDecision(ctx, d, command, replacements, c.clock.Since(start), err) }()
+	defer c.cluster.FinishDeprovisioningCommand(id)
+
 	deprovisioningActionsPerformedCounter.With(map[string]string{
 		// TODO: make this just command.Action() since we've added the deprovisioner as its own label.
 		actionLabel:        fmt.Sprintf("%s/%s", d, command.Action()),
 		deprovisionerLabel: d.String(),
 	}).Inc()
+	deprovisioningActionsAttemptedCounter.With(methodLabels(d)).Inc()
 	logging.FromContext(ctx).Infof("deprovisioning via %s %s", d, command)
 
 	reason := fmt.Sprintf("%s/%s", d, command.Action())
 	if command.Action() == ReplaceAction {
-		if err := c.launchReplacementMachines(ctx, command, reason); err != nil {
+		if replacements, err = c.launchReplacementMachines(ctx, command, reason, launchBeforeCordon(d, command)); err != nil {
 			// If we failed to launch the replacement, don't deprovision.  If this is some permanent failure,
 			// we don't want to disrupt workloads with no way to provision new nodes for them.
+			// The candidates weren't disrupted, so they shouldn't count against the rate limits
+			c.rateLimiter.Refund(ctx, len(command.candidates))
+			if r, ok := d.(CommandRecorder); ok {
+				r.ReleaseCommand(command)
+			}
 			return fmt.Errorf("launching replacement machine, %w", err)
 		}
 	}
 
 	for _, candidate := range command.candidates {
+		c.recorder.Publish(maxNodeLifetimeEvents(candidate)...)
 		c.recorder.Publish(deprovisioningevents.Terminating(candidate.Node, candidate.NodeClaim, reason)...)
 
 		if err := nodeclaimutil.Delete(ctx, c.kubeClient, candidate.NodeClaim); err != nil {
@@ -205,25 +401,41 @@ func (c *Controller) executeCommand(ctx context.Context, d Deprovisioner, comman
 	return nil
 }
 
-// launchReplacementMachines launches replacement machines and blocks until it is ready
+// launchBeforeCordon returns whether the replacements for the command should be launched and initialized before the
+// candidates are cordoned. This only applies to drift and expiration, and only if every candidate's NodePool opts in.
+func launchBeforeCordon(d Deprovisioner, cmd Command) bool {
+	if d.String() != metrics.DriftReason && d.String() != metrics.ExpirationReason {
+		return false
+	}
+	return lo.EveryBy(cmd.candidates, func(cn *Candidate) bool {
+		return cn.nodePool.Spec.Deprovisioning.LaunchBeforeCordon
+	})
+}
+
+// launchReplacementMachines launches replacement machines and blocks until it is ready, returning the keys of any
+// machines that were created. If cordonLast is set, the old nodes are only cordoned once the replacements are initialized.
 // nolint:gocyclo
-func (c *Controller) launchReplacementMachines(ctx context.Context, action Command, reason string) error {
+func (c *Controller) launchReplacementMachines(ctx context.Context, action Command, reason string, cordonLast bool) ([]nodeclaimutil.Key, error) {
 	defer metrics.Measure(deprovisioningReplacementNodeInitializedHistogram)()
 
 	// cordon the old nodes before we launch the replacements to prevent new pods from scheduling to the old nodes
-	if err := c.setNodesUnschedulable(ctx, true, action.candidates...); err != nil {
-		return fmt.Errorf("cordoning nodes, %w", err)
+	if !cordonLast {
+		if err := c.setNodesUnschedulable(ctx, true, action.candidates...); err != nil {
+			return nil, fmt.Errorf("cordoning nodes, %w", err)
+		}
 	}
 
 	nodeClaimKeys, err := c.provisioner.CreateNodeClaims(ctx, action.replacements, provisioning.WithReason(reason))
 	if err != nil {
 		// uncordon the nodes as the launch may fail (e.g. ICE)
-		err = multierr.Append(err, c.setNodesUnschedulable(ctx, false, action.candidates...))
-		return err
+		if !cordonLast {
+			err = multierr.Append(err, c.setNodesUnschedulable(ctx, false, action.candidates...))
+		}
+		return nodeClaimKeys, err
 	}
 	if len(nodeClaimKeys) != len(action.replacements) {
 		// shouldn't ever occur since a partially failed CreateNodeClaims should return an error
-		return fmt.Errorf("expected %d nodes, got %d", len(action.replacements), len(nodeClaimKeys))
+		return nodeClaimKeys, fmt.Errorf("expected %d nodes, got %d", len(action.replacements), len(nodeClaimKeys))
 	}
 
 	candidateProviderIDs := lo.Map(action.candidates, func(c *Candidate, _ int) string { return c.ProviderID() })
@@ -242,10 +454,20 @@ func (c *Controller) launchReplacementMachines(ctx context.Context, action Comma
 	})
 	if err = multierr.Combine(errs...); err != nil {
 		c.cluster.UnmarkForDeletion(candidateProviderIDs...)
-		return multierr.Combine(c.setNodesUnschedulable(ctx, false, action.candidates...),
+		if cordonLast {
+			return nodeClaimKeys, fmt.Errorf("timed out checking machine readiness, %w", err)
+		}
+		return nodeClaimKeys, multierr.Combine(c.setNodesUnschedulable(ctx, false, action.candidates...),
 			fmt.Errorf("timed out checking machine readiness, %w", err))
 	}
-	return nil
+	// the replacements are initialized, so the old nodes can now be cordoned ahead of their deletion
+	if cordonLast {
+		if err = c.setNodesUnschedulable(ctx, true, action.candidates...); err != nil {
+			c.cluster.UnmarkForDeletion(candidateProviderIDs...)
+			return nodeClaimKeys, fmt.Errorf("cordoning nodes, %w", err)
+		}
+	}
+	return nodeClaimKeys, nil
 }
 
 // TODO @njtran: Allow to bypass this check for certain deprovisioners
@@ -0,0 +1,66 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning_test
+
+import (
+	"encoding/json"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/ptr"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/test"
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var _ = Describe("Deprovisioning Decision", func() {
+	var prov *v1alpha5.Provisioner
+	var machine *v1alpha5.Machine
+	var node *v1.Node
+
+	BeforeEach(func() {
+		prov = test.Provisioner(test.ProvisionerOptions{TTLSecondsUntilExpired: ptr.Int64(30)})
+		machine, node = expiredMachineAndNode(prov)
+	})
+	It("should record the deprovisioning decision for expired nodes", func() {
+		ExpectApplied(ctx, env.Client, machine, node, prov)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+
+		// Expect a decision event on both the node and the machine
+		Expect(recorder.Calls("DeprovisioningDecision")).To(Equal(2))
+		evt, ok := lo.Find(recorder.Events(), func(e events.Event) bool { return e.Reason == "DeprovisioningDecision" })
+		Expect(ok).To(BeTrue())
+		decision := deprovisioningevents.Decision{}
+		Expect(json.Unmarshal([]byte(evt.Message), &decision)).To(Succeed())
+		Expect(decision.Method).To(Equal("expiration"))
+		Expect(decision.Action).To(Equal("delete"))
+		Expect(decision.Candidates).To(ConsistOf(node.Name))
+		Expect(decision.Outcome).To(Equal(deprovisioningevents.DecisionSucceeded))
+	})
+})
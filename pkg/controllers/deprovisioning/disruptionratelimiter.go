@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+)
+
+// DisruptionRateLimiter bounds the rate at which nodes are voluntarily disrupted across all deprovisioners. It's a
+// token bucket that holds up to disruptionRateLimitNodes tokens and refills at that many tokens per
+// disruptionRateLimitInterval, with each disrupted node consuming a token.
+type DisruptionRateLimiter struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	tokens float64
+	// lastRefill is the last time that tokens were added to the bucket, the bucket starts full when this is zero
+	lastRefill time.Time
+}
+
+func NewDisruptionRateLimiter(clk clock.Clock) *DisruptionRateLimiter {
+	return &DisruptionRateLimiter{clock: clk}
+}
+
+// ApplyToCommand restricts the command to the number of nodes that can be disrupted without exceeding the rate
+// limit. Like disruption budgets, a replacement can't be partially executed so it's deferred entirely if any of its
+// candidates are rate limited. The candidates that were deferred are returned.
+func (r *DisruptionRateLimiter) ApplyToCommand(ctx context.Context, cmd Command) (Command, []*Candidate) {
+	allowed := r.allowed(ctx)
+	if len(cmd.candidates) <= allowed {
+		return cmd, nil
+	}
+	if cmd.Action() == ReplaceAction || allowed <= 0 {
+		return Command{}, cmd.candidates
+	}
+	return Command{candidates: cmd.candidates[:allowed]}, cmd.candidates[allowed:]
+}
+
+// Take consumes a token for each node that's being disrupted
+func (r *DisruptionRateLimiter) Take(ctx context.Context, nodes int) {
+	s := settings.FromContext(ctx)
+	if s.DisruptionRateLimitNodes <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refill(s)
+	r.tokens = math.Max(0, r.tokens-float64(nodes))
+}
+
+// Refund returns the tokens taken for nodes whose disruption failed
+func (r *DisruptionRateLimiter) Refund(ctx context.Context, nodes int) {
+	s := settings.FromContext(ctx)
+	if s.DisruptionRateLimitNodes <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refill(s)
+	r.tokens = math.Min(r.tokens+float64(nodes), float64(s.DisruptionRateLimitNodes))
+}
+
+// allowed returns the number of nodes that can currently be disrupted
+func (r *DisruptionRateLimiter) allowed(ctx context.Context) int {
+	s := settings.FromContext(ctx)
+	if s.DisruptionRateLimitNodes <= 0 {
+		return math.MaxInt32
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refill(s)
+	return int(r.tokens)
+}
+
+func (r *DisruptionRateLimiter) refill(s *settings.Settings) {
+	now := r.clock.Now()
+	capacity := float64(s.DisruptionRateLimitNodes)
+	if r.lastRefill.IsZero() {
+		r.tokens = capacity
+	} else {
+		r.tokens += capacity * now.Sub(r.lastRefill).Seconds() / s.DisruptionRateLimitInterval.Seconds()
+	}
+	// the capacity may have been lowered since the last refill
+	r.tokens = math.Min(r.tokens, capacity)
+	r.lastRefill = now
+}
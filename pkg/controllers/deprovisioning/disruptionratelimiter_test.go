@@ -0,0 +1,117 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning_test
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/test"
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var _ = Describe("Disruption Rate Limiter", func() {
+	var prov *v1alpha5.Provisioner
+
+	BeforeEach(func() {
+		prov = test.Provisioner(test.ProvisionerOptions{TTLSecondsUntilExpired: ptr.Int64(30)})
+	})
+	It("should only deprovision as many empty expired nodes as the disruption rate limit allows", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, DisruptionRateLimitNodes: 3, DisruptionRateLimitInterval: time.Hour}))
+		machines, nodes := test.MachinesAndNodes(10, v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				},
+			},
+		})
+		for _, m := range machines {
+			m.StatusConditions().MarkTrue(v1alpha5.MachineExpired)
+			ExpectApplied(ctx, env.Client, m)
+		}
+		for _, n := range nodes {
+			ExpectApplied(ctx, env.Client, n)
+		}
+		ExpectApplied(ctx, env.Client, prov)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, nodes, machines)
+
+		// Refill the rate limit after any disruptions by earlier tests
+		fakeClock.Step(2 * time.Hour)
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machines...)
+
+		// Expect that only 3 of the expired machines are gone
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(7))
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(7))
+	})
+	It("should refund the disruption rate limit when the replacement fails", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, DisruptionRateLimitNodes: 1, DisruptionRateLimitInterval: time.Hour}))
+		cloudProvider.AllowedCreateCalls = 0 // fail the replacement
+		machine, node := expiredMachineAndNode(prov)
+		pods := ExpectReplicaSetPods(ctx, env.Client, 1)
+		ExpectApplied(ctx, env.Client, pods[0], machine, node, prov)
+		ExpectManualBinding(ctx, env.Client, pods[0], node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		// Refill the rate limit after any disruptions by earlier tests
+		fakeClock.Step(2 * time.Hour)
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectNewMachinesDeleted(ctx, env.Client, &wg, 1)
+		_, err := deprovisioningController.Reconcile(ctx, reconcile.Request{})
+		Expect(err).To(HaveOccurred())
+		wg.Wait()
+		ExpectExists(ctx, env.Client, machine)
+
+		// The token taken for the failed command was refunded, so the node is replaced without waiting for a refill
+		cloudProvider.AllowedCreateCalls = math.MaxInt
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine, node)
+	})
+})
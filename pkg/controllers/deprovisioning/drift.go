@@ -20,18 +20,19 @@ import (
 	"fmt"
 	"sort"
 
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/samber/lo"
 
-	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 )
 
 // Drift is a subreconciler that deletes drifted machines.
@@ -40,26 +41,28 @@ type Drift struct {
 	cluster     *state.Cluster
 	provisioner *provisioning.Provisioner
 	recorder    events.Recorder
+	rateLimiter *DriftRateLimiter
 }
 
-func NewDrift(kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner, recorder events.Recorder) *Drift {
+func NewDrift(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner, recorder events.Recorder) *Drift {
 	return &Drift{
 		kubeClient:  kubeClient,
 		cluster:     cluster,
 		provisioner: provisioner,
 		recorder:    recorder,
+		rateLimiter: NewDriftRateLimiter(clk),
 	}
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable machines
 func (d *Drift) ShouldDeprovision(ctx context.Context, c *Candidate) bool {
-	return settings.FromContext(ctx).DriftEnabled &&
+	return nodepoolutil.DriftEnabled(ctx, c.nodePool) &&
 		c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeDrifted).IsTrue()
 }
 
 // SortCandidates orders drifted nodes by when they've drifted
 func (d *Drift) filterAndSortCandidates(ctx context.Context, nodes []*Candidate) ([]*Candidate, error) {
-	candidates, err := filterCandidates(ctx, d.kubeClient, d.recorder, nodes)
+	candidates, err := filterCandidates(ctx, d.kubeClient, d.recorder, nodes, false)
 	if err != nil {
 		return nil, fmt.Errorf("filtering candidates, %w", err)
 	}
@@ -78,6 +81,39 @@ func (d *Drift) ComputeCommand(ctx context.Context, nodes ...*Candidate) (Comman
 	}
 	deprovisioningEligibleMachinesGauge.WithLabelValues(d.String()).Set(float64(len(candidates)))
 
+	return d.computeCommand(ctx, d.rateLimit(ctx, candidates))
+}
+
+// RecordCommand counts the candidates of a command that is being executed against the drift rate limits
+func (d *Drift) RecordCommand(cmd Command) {
+	d.rateLimiter.Record(cmd.candidates...)
+}
+
+// ReleaseCommand stops counting the candidates of a command that failed against the drift rate limits
+func (d *Drift) ReleaseCommand(cmd Command) {
+	d.rateLimiter.Release(cmd.candidates...)
+}
+
+// rateLimit restricts the candidates to the number of nodes that can still be disrupted due to drift across the
+// cluster and for each NodePool, keeping the candidates that drifted first
+func (d *Drift) rateLimit(ctx context.Context, candidates []*Candidate) []*Candidate {
+	allowed := d.rateLimiter.Allowed(ctx)
+	remaining := map[nodepoolutil.Key]int{}
+	return lo.Filter(candidates, func(c *Candidate, _ int) bool {
+		if _, ok := remaining[c.OwnerKey()]; !ok {
+			remaining[c.OwnerKey()] = d.rateLimiter.AllowedForNodePool(c.OwnerKey(), c.nodePool)
+		}
+		if allowed <= 0 || remaining[c.OwnerKey()] <= 0 {
+			d.recorder.Publish(deprovisioningevents.Blocked(c.Node, c.NodeClaim, "Drift rate limit has been reached")...)
+			return false
+		}
+		allowed--
+		remaining[c.OwnerKey()]--
+		return true
+	})
+}
+
+func (d *Drift) computeCommand(ctx context.Context, candidates []*Candidate) (Command, error) {
 	// Deprovision all empty drifted nodes, as they require no scheduling simulations.
 	if empty := lo.Filter(candidates, func(c *Candidate, _ int) bool {
 		return len(c.pods) == 0
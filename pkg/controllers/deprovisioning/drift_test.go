@@ -15,6 +15,7 @@ limitations under the License.
 package deprovisioning_test
 
 import (
+	"math"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
@@ -232,6 +234,184 @@ var _ = Describe("Drift", func() {
 		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(0))
 		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(0))
 	})
+	It("should only deprovision as many drifted nodes as the global drift rate limit allows", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, DriftRateLimitNodes: 2, DriftRateLimitInterval: time.Minute}))
+		machines, nodes := test.MachinesAndNodes(5, v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				},
+			},
+		})
+		for _, m := range machines {
+			m.StatusConditions().MarkTrue(v1alpha5.MachineDrifted)
+			ExpectApplied(ctx, env.Client, m)
+		}
+		for _, n := range nodes {
+			ExpectApplied(ctx, env.Client, n)
+		}
+		ExpectApplied(ctx, env.Client, prov)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, nodes, machines)
+
+		// Move past any drift disruptions recorded by earlier tests
+		fakeClock.Step(2 * time.Minute)
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machines...)
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(3))
+	})
+	It("should only deprovision as many drifted nodes as the provisioner drift rate limit allows", func() {
+		prov.Spec.Drift = &v1alpha5.Drift{RateLimit: &v1alpha5.DriftRateLimit{Nodes: 3, IntervalSeconds: 600}}
+		machines, nodes := test.MachinesAndNodes(10, v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				},
+			},
+		})
+		for _, m := range machines {
+			m.StatusConditions().MarkTrue(v1alpha5.MachineDrifted)
+			ExpectApplied(ctx, env.Client, m)
+		}
+		for _, n := range nodes {
+			ExpectApplied(ctx, env.Client, n)
+		}
+		ExpectApplied(ctx, env.Client, prov)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, nodes, machines)
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machines...)
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(7))
+
+		// The rate limit has been reached for the interval
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(7))
+
+		// Once the interval has passed, more drifted nodes can be deprovisioned
+		fakeClock.Step(10 * time.Minute)
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machines...)
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(4))
+	})
+	It("should not count drifted nodes against the provisioner drift rate limit when their replacement fails", func() {
+		prov.Spec.Drift = &v1alpha5.Drift{RateLimit: &v1alpha5.DriftRateLimit{Nodes: 1, IntervalSeconds: 600}}
+		cloudProvider.AllowedCreateCalls = 0 // fail the replacement
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		ExpectApplied(ctx, env.Client, rs, pod, machine, node, prov)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectNewMachinesDeleted(ctx, env.Client, &wg, 1)
+		_, err := deprovisioningController.Reconcile(ctx, reconcile.Request{})
+		Expect(err).To(HaveOccurred())
+		wg.Wait()
+		ExpectExists(ctx, env.Client, machine)
+
+		// The failed replacement didn't use up the rate limit, so the drifted node is replaced once launches succeed
+		cloudProvider.AllowedCreateCalls = math.MaxInt
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine, node)
+	})
+	It("should count drifted nodes against the provisioner drift rate limit while their commands are in flight", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, DeprovisioningMaxParallelActions: 2}))
+		prov.Spec.Drift = &v1alpha5.Drift{RateLimit: &v1alpha5.DriftRateLimit{Nodes: 1, IntervalSeconds: 600}}
+		pods := ExpectReplicaSetPods(ctx, env.Client, 2)
+		// Make each pod request only fit on a single node
+		for _, p := range pods {
+			p.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("30")}
+		}
+		machine2, node2 := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID: test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU:  resource.MustParse("32"),
+					v1.ResourcePods: resource.MustParse("100"),
+				},
+			},
+		})
+		machine2.StatusConditions().MarkTrue(v1alpha5.MachineDrifted)
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], machine, node, machine2, node2, prov)
+		ExpectManualBinding(ctx, env.Client, pods[0], node)
+		ExpectManualBinding(ctx, env.Client, pods[1], node2)
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node, node2}, []*v1alpha5.Machine{machine, machine2})
+
+		// The first command is still waiting on its replacement when the second reconcile computes the next command, so
+		// the rate limit is already used up
+		var wg sync.WaitGroup
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+
+		Eventually(func(g Gomega) {
+			g.Expect(lo.Map(ExpectMachines(ctx, env.Client), func(m *v1alpha5.Machine, _ int) string { return m.Name })).To(Or(
+				Not(ContainElement(machine.Name)), Not(ContainElement(machine2.Name))))
+		}).Should(Succeed())
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine, machine2)
+
+		// Only one of the drifted nodes was replaced
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(2))
+	})
 	It("can replace drifted nodes", func() {
 		labels := map[string]string{
 			"app": "test",
@@ -284,6 +464,58 @@ var _ = Describe("Drift", func() {
 		Expect(machines[0].Name).ToNot(Equal(machine.Name))
 		Expect(nodes[0].Name).ToNot(Equal(node.Name))
 	})
+	It("should launch and initialize the replacement before cordoning the drifted node if the provisioner enables it", func() {
+		prov.Spec.Disruption = &v1alpha5.Disruption{LaunchBeforeCordon: true}
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		ExpectApplied(ctx, env.Client, rs, pod, machine, node, prov)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			var replacement *v1alpha5.Machine
+			Eventually(func(g Gomega) {
+				machineList := &v1alpha5.MachineList{}
+				g.Expect(env.Client.List(ctx, machineList)).To(Succeed())
+				m, ok := lo.Find(machineList.Items, func(m v1alpha5.Machine) bool { return m.Name != machine.Name })
+				g.Expect(ok).To(BeTrue())
+				replacement = &m
+			}).Should(Succeed())
+			// the drifted node should keep accepting pods while its replacement is launching
+			Expect(ExpectExists(ctx, env.Client, node).Spec.Unschedulable).To(BeFalse())
+			m, n := ExpectMachineDeployed(ctx, env.Client, cluster, cloudProvider, replacement)
+			ExpectMakeMachinesInitialized(ctx, env.Client, m)
+			ExpectMakeNodesInitialized(ctx, env.Client, n)
+		}()
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine, node)
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+	})
 	It("can replace drifted nodes with multiple nodes", func() {
 		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
 			Name: "current-on-demand",
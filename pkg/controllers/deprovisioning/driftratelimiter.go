@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+)
+
+// DriftRateLimiter paces drift-driven disruption so that a change affecting many nodes doesn't replace large parts of
+// the cluster at once. It tracks the nodes disrupted due to drift over a sliding window, both across the cluster
+// and per NodePool, independently of consolidation.
+type DriftRateLimiter struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	disrupted []driftDisruption
+	// maxInterval is the longest interval that has been evaluated, disruptions older than this can be forgotten
+	maxInterval time.Duration
+}
+
+type driftDisruption struct {
+	key        nodepoolutil.Key
+	providerID string
+	time       time.Time
+}
+
+func NewDriftRateLimiter(clk clock.Clock) *DriftRateLimiter {
+	return &DriftRateLimiter{clock: clk}
+}
+
+// Allowed returns the number of nodes across the cluster that can still be disrupted due to drift
+func (r *DriftRateLimiter) Allowed(ctx context.Context) int {
+	s := settings.FromContext(ctx)
+	if s.DriftRateLimitNodes <= 0 {
+		return math.MaxInt32
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return s.DriftRateLimitNodes - r.count(s.DriftRateLimitInterval, func(driftDisruption) bool { return true })
+}
+
+// AllowedForNodePool returns the number of nodes owned by the NodePool that can still be disrupted due to drift
+func (r *DriftRateLimiter) AllowedForNodePool(key nodepoolutil.Key, nodePool *v1beta1.NodePool) int {
+	limit := nodePool.Spec.Deprovisioning.DriftRateLimit
+	if limit == nil {
+		return math.MaxInt32
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int(limit.Nodes) - r.count(limit.Interval.Duration, func(d driftDisruption) bool { return d.key == key })
+}
+
+// Record tracks the candidates as disrupted due to drift at the current time
+func (r *DriftRateLimiter) Record(candidates ...*Candidate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	for _, c := range candidates {
+		r.disrupted = append(r.disrupted, driftDisruption{key: c.OwnerKey(), providerID: c.ProviderID(), time: now})
+	}
+	r.disrupted = lo.Filter(r.disrupted, func(d driftDisruption, _ int) bool {
+		return now.Sub(d.time) < r.maxInterval
+	})
+}
+
+// Release stops counting the candidates as disrupted due to drift, e.g. because the command disrupting them failed
+func (r *DriftRateLimiter) Release(candidates ...*Candidate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	providerIDs := sets.NewString(lo.Map(candidates, func(c *Candidate, _ int) string { return c.ProviderID() })...)
+	r.disrupted = lo.Reject(r.disrupted, func(d driftDisruption, _ int) bool { return providerIDs.Has(d.providerID) })
+}
+
+func (r *DriftRateLimiter) count(interval time.Duration, predicate func(driftDisruption) bool) int {
+	r.maxInterval = lo.Max([]time.Duration{r.maxInterval, interval})
+	since := r.clock.Now().Add(-interval)
+	return lo.CountBy(r.disrupted, func(d driftDisruption) bool {
+		return d.time.After(since) && predicate(d)
+	})
+}
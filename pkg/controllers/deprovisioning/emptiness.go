@@ -22,10 +22,11 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/metrics"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
 )
 
 // Emptiness is a subreconciler that deletes empty machines.
-// Emptiness will respect TTLSecondsAfterEmpty
+// Emptiness will respect TTLSecondsAfterEmpty and the ttl-after-empty annotation
 type Emptiness struct {
 	clock clock.Clock
 }
@@ -37,17 +38,24 @@ func NewEmptiness(clk clock.Clock) *Emptiness {
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable machines
-func (e *Emptiness) ShouldDeprovision(_ context.Context, c *Candidate) bool {
-	return c.nodePool.Spec.Deprovisioning.ConsolidationPolicy == v1beta1.ConsolidationPolicyWhenEmpty &&
+func (e *Emptiness) ShouldDeprovision(ctx context.Context, c *Candidate) bool {
+	ttl, overridden := nodeclaimutil.EmptinessTTLOverride(ctx, c.Node, c.NodeClaim)
+	if !overridden {
+		if c.nodePool.Spec.Deprovisioning.ConsolidationPolicy != v1beta1.ConsolidationPolicyWhenEmpty {
+			return false
+		}
+		ttl = c.nodePool.Spec.Deprovisioning.ConsolidationTTL.Duration
+	}
+	return ttl >= 0 && !atReplicaFloor(c) &&
 		c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeEmpty).IsTrue() &&
-		!e.clock.Now().Before(c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeEmpty).LastTransitionTime.Inner.Add(c.nodePool.Spec.Deprovisioning.ConsolidationTTL.Duration))
+		!e.clock.Now().Before(c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeEmpty).LastTransitionTime.Inner.Add(ttl))
 }
 
 // ComputeCommand generates a deprovisioning command given deprovisionable machines
 func (e *Emptiness) ComputeCommand(_ context.Context, candidates ...*Candidate) (Command, error) {
-	emptyCandidates := lo.Filter(candidates, func(cn *Candidate, _ int) bool {
+	emptyCandidates := limitToReplicaFloor(lo.Filter(candidates, func(cn *Candidate, _ int) bool {
 		return cn.NodeClaim.DeletionTimestamp.IsZero() && len(cn.pods) == 0
-	})
+	}))
 	deprovisioningEligibleMachinesGauge.WithLabelValues(e.String()).Set(float64(len(candidates)))
 
 	return Command{
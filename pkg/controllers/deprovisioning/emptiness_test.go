@@ -83,6 +83,80 @@ var _ = Describe("Empty Nodes (TTLSecondsAfterEmpty)", func() {
 		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(0))
 		ExpectNotFound(ctx, env.Client, machine, node)
 	})
+	It("should record the outcome of deleting empty nodes", func() {
+		ExpectApplied(ctx, env.Client, prov, machine, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+		wg := sync.WaitGroup{}
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+
+		_, found := FindMetricWithLabelValues("karpenter_deprovisioning_actions_attempted", map[string]string{
+			"deprovisioner": "emptiness",
+		})
+		Expect(found).To(BeTrue())
+		_, found = FindMetricWithLabelValues("karpenter_deprovisioning_candidates_evaluated", map[string]string{
+			"deprovisioner": "emptiness",
+		})
+		Expect(found).To(BeTrue())
+		_, found = FindMetricWithLabelValues("karpenter_deprovisioning_action_outcomes", map[string]string{
+			"deprovisioner": "emptiness",
+			"outcome":       "succeeded",
+		})
+		Expect(found).To(BeTrue())
+	})
+	It("should not delete empty nodes that the provisioner keeps as replicas", func() {
+		prov.Spec.Replicas = ptr.Int32(1)
+		prov.Status.Resources = v1.ResourceList{v1alpha5.ResourceNodes: resource.MustParse("1")}
+		ExpectApplied(ctx, env.Client, prov, machine, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Expect to not create or delete more machines
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("should wait for the ttl-after-empty annotation on the node instead of TTLSecondsAfterEmpty", func() {
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1alpha5.TTLAfterEmptyAnnotationKey: "1h"})
+		ExpectApplied(ctx, env.Client, prov, machine, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Expect to not create or delete more machines
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("should not delete empty nodes when the ttl-after-empty annotation disables emptiness", func() {
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.TTLAfterEmptyAnnotationKey: "Never"})
+		ExpectApplied(ctx, env.Client, prov, machine, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Expect to not create or delete more machines
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
 	It("should ignore TTLSecondsAfterEmpty nodes without the empty status condition", func() {
 		_ = machine.StatusConditions().ClearCondition(v1alpha5.MachineEmpty)
 		ExpectApplied(ctx, env.Client, machine, node, prov)
@@ -199,6 +273,26 @@ var _ = Describe("Empty Nodes (Consolidation)", func() {
 		ExpectNotFound(ctx, env.Client, machine1)
 		ExpectNotFound(ctx, env.Client, machine2)
 	})
+	It("should only delete the empty nodes above the replicas of the provisioner", func() {
+		prov.Spec.Replicas = ptr.Int32(1)
+		prov.Status.Resources = v1.ResourceList{v1alpha5.ResourceNodes: resource.MustParse("2")}
+		ExpectApplied(ctx, env.Client, machine1, node1, machine2, node2, prov)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node1, node2}, []*v1alpha5.Machine{machine1, machine2})
+
+		fakeClock.Step(10 * time.Minute)
+		wg := sync.WaitGroup{}
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine1, machine2)
+
+		// we should keep one of the empty nodes as a replica
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+	})
 	It("considers pending pods when consolidating", func() {
 		largeTypes := lo.Filter(cloudProvider.InstanceTypes, func(item *cloudprovider.InstanceType, index int) bool {
 			return item.Capacity.Cpu().Cmp(resource.MustParse("64")) >= 0
@@ -52,7 +52,7 @@ func (c *EmptyMachineConsolidation) ComputeCommand(ctx context.Context, candidat
 	deprovisioningEligibleMachinesGauge.WithLabelValues(c.String()).Set(float64(len(candidates)))
 
 	// select the entirely empty nodes
-	emptyCandidates := lo.Filter(candidates, func(n *Candidate, _ int) bool { return len(n.pods) == 0 })
+	emptyCandidates := limitToReplicaFloor(lo.Filter(candidates, func(n *Candidate, _ int) bool { return len(n.pods) == 0 }))
 	if len(emptyCandidates) == 0 {
 		// none empty, so do nothing
 		c.markConsolidated()
@@ -15,6 +15,7 @@ limitations under the License.
 package events
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -154,6 +155,39 @@ func Unconsolidatable(node *v1.Node, nodeClaim *v1beta1.NodeClaim, reason string
 	return evts
 }
 
+// DryRun is an event that informs the user that a Machine/Node combination would have been deprovisioned if
+// deprovisioning wasn't in dry-run mode
+func DryRun(node *v1.Node, nodeClaim *v1beta1.NodeClaim, reason string) []events.Event {
+	evts := []events.Event{
+		{
+			InvolvedObject: node,
+			Type:           v1.EventTypeNormal,
+			Reason:         "DeprovisioningDryRun",
+			Message:        fmt.Sprintf("Would deprovision Node: %s", reason),
+			DedupeValues:   []string{string(node.UID), reason},
+		},
+	}
+	if nodeClaim.IsMachine {
+		machine := machineutil.NewFromNodeClaim(nodeClaim)
+		evts = append(evts, events.Event{
+			InvolvedObject: machine,
+			Type:           v1.EventTypeNormal,
+			Reason:         "DeprovisioningDryRun",
+			Message:        fmt.Sprintf("Would deprovision Machine: %s", reason),
+			DedupeValues:   []string{string(machine.UID), reason},
+		})
+	} else {
+		evts = append(evts, events.Event{
+			InvolvedObject: nodeClaim,
+			Type:           v1.EventTypeNormal,
+			Reason:         "DeprovisioningDryRun",
+			Message:        fmt.Sprintf("Would deprovision NodeClaim: %s", reason),
+			DedupeValues:   []string{string(nodeClaim.UID), reason},
+		})
+	}
+	return evts
+}
+
 // Blocked is an event that informs the user that a Machine/Node combination is blocked on deprovisioning
 // due to the state of the Machine/Node or due to some state of the pods that are scheduled to the Machine/Node
 func Blocked(node *v1.Node, nodeClaim *v1beta1.NodeClaim, reason string) []events.Event {
@@ -186,3 +220,134 @@ func Blocked(node *v1.Node, nodeClaim *v1beta1.NodeClaim, reason string) []event
 	}
 	return evts
 }
+
+// MaxNodeLifetimeExceeded is an event that warns the user that a Machine/Node combination has exceeded its MaxNodeLifetime
+// and that PodDisruptionBudgets and do-not-evict pods will stop blocking its deprovisioning after forceAt
+func MaxNodeLifetimeExceeded(node *v1.Node, nodeClaim *v1beta1.NodeClaim, forceAt time.Time) []events.Event {
+	message := fmt.Sprintf("Exceeded max node lifetime, deprovisioning will be forced after %s", forceAt.Format(time.RFC3339))
+	evts := []events.Event{
+		{
+			InvolvedObject: node,
+			Type:           v1.EventTypeWarning,
+			Reason:         "MaxNodeLifetimeExceeded",
+			Message:        message,
+			DedupeValues:   []string{string(node.UID)},
+		},
+	}
+	if nodeClaim.IsMachine {
+		machine := machineutil.NewFromNodeClaim(nodeClaim)
+		evts = append(evts, events.Event{
+			InvolvedObject: machine,
+			Type:           v1.EventTypeWarning,
+			Reason:         "MaxNodeLifetimeExceeded",
+			Message:        message,
+			DedupeValues:   []string{string(machine.UID)},
+		})
+	} else {
+		evts = append(evts, events.Event{
+			InvolvedObject: nodeClaim,
+			Type:           v1.EventTypeWarning,
+			Reason:         "MaxNodeLifetimeExceeded",
+			Message:        message,
+			DedupeValues:   []string{string(nodeClaim.UID)},
+		})
+	}
+	return evts
+}
+
+// ForcedDeprovisioning is an event that warns the user that a Machine/Node combination is being deprovisioned
+// despite a blocker since it has exceeded its MaxNodeLifetime and ForceExpirationGracePeriod
+func ForcedDeprovisioning(node *v1.Node, nodeClaim *v1beta1.NodeClaim, reason string) []events.Event {
+	evts := []events.Event{
+		{
+			InvolvedObject: node,
+			Type:           v1.EventTypeWarning,
+			Reason:         "DeprovisioningForced",
+			Message:        fmt.Sprintf("Forcing deprovisioning of Node past its max node lifetime: %s", reason),
+			DedupeValues:   []string{string(node.UID)},
+		},
+	}
+	if nodeClaim.IsMachine {
+		machine := machineutil.NewFromNodeClaim(nodeClaim)
+		evts = append(evts, events.Event{
+			InvolvedObject: machine,
+			Type:           v1.EventTypeWarning,
+			Reason:         "DeprovisioningForced",
+			Message:        fmt.Sprintf("Forcing deprovisioning of Machine past its max node lifetime: %s", reason),
+			DedupeValues:   []string{string(machine.UID)},
+		})
+	} else {
+		evts = append(evts, events.Event{
+			InvolvedObject: nodeClaim,
+			Type:           v1.EventTypeWarning,
+			Reason:         "DeprovisioningForced",
+			Message:        fmt.Sprintf("Forcing deprovisioning of NodeClaim past its max node lifetime: %s", reason),
+			DedupeValues:   []string{string(nodeClaim.UID)},
+		})
+	}
+	return evts
+}
+
+const (
+	DecisionSucceeded = "succeeded"
+	DecisionFailed    = "failed"
+)
+
+// Decision is the audit record of an executed deprovisioning command. It's serialized as the message of the
+// DeprovisioningDecision event, so its fields make up a stable schema and should only be added to.
+type Decision struct {
+	// Method is the deprovisioner that produced the command (e.g. expiration, drift, consolidation)
+	Method string `json:"method"`
+	// Action is the action that the command took (e.g. delete, replace)
+	Action string `json:"action"`
+	// Candidates are the names of the nodes that were deprovisioned
+	Candidates []string `json:"candidates"`
+	// Replacements are the names of the machines that were launched to replace the candidates
+	Replacements []string `json:"replacements,omitempty"`
+	// EstimatedSavings is the estimated hourly savings of the command when it can be computed
+	EstimatedSavings *float64 `json:"estimatedSavings,omitempty"`
+	// Outcome is either succeeded or failed
+	Outcome string `json:"outcome"`
+	// DurationSeconds is how long the command took to execute
+	DurationSeconds float64 `json:"durationSeconds"`
+	// Error is the reason that the command failed
+	Error string `json:"error,omitempty"`
+}
+
+// DecisionRecorded is an event that records the outcome of a deprovisioning command on each of its candidates
+func DecisionRecorded(node *v1.Node, nodeClaim *v1beta1.NodeClaim, decision Decision) []events.Event {
+	// marshaling can't fail since the decision is made up of only strings and numbers
+	raw, _ := json.Marshal(decision)
+	eventType := v1.EventTypeNormal
+	if decision.Outcome == DecisionFailed {
+		eventType = v1.EventTypeWarning
+	}
+	evts := []events.Event{
+		{
+			InvolvedObject: node,
+			Type:           eventType,
+			Reason:         "DeprovisioningDecision",
+			Message:        string(raw),
+			DedupeValues:   []string{string(node.UID), decision.Outcome},
+		},
+	}
+	if nodeClaim.IsMachine {
+		machine := machineutil.NewFromNodeClaim(nodeClaim)
+		evts = append(evts, events.Event{
+			InvolvedObject: machine,
+			Type:           eventType,
+			Reason:         "DeprovisioningDecision",
+			Message:        string(raw),
+			DedupeValues:   []string{string(machine.UID), decision.Outcome},
+		})
+	} else {
+		evts = append(evts, events.Event{
+			InvolvedObject: nodeClaim,
+			Type:           eventType,
+			Reason:         "DeprovisioningDecision",
+			Message:        string(raw),
+			DedupeValues:   []string{string(nodeClaim.UID), decision.Outcome},
+		})
+	}
+	return evts
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"k8s.io/utils/clock"
 
@@ -33,6 +34,8 @@ import (
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/metrics"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 )
 
 // Expiration is a subreconciler that deletes empty nodes.
@@ -56,24 +59,36 @@ func NewExpiration(clk clock.Clock, kubeClient client.Client, cluster *state.Clu
 }
 
 // ShouldDeprovision is a predicate used to filter deprovisionable nodes
-func (e *Expiration) ShouldDeprovision(_ context.Context, c *Candidate) bool {
-	return c.nodePool.Spec.Deprovisioning.ExpirationTTL.Duration >= 0 &&
-		c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeExpired).IsTrue()
+func (e *Expiration) ShouldDeprovision(ctx context.Context, c *Candidate) bool {
+	ttl, overridden := nodeclaimutil.ExpirationTTLOverride(ctx, c.Node, c.NodeClaim)
+	if !overridden {
+		ttl = c.nodePool.Spec.Deprovisioning.ExpirationTTL.Duration
+	}
+	return c.maxNodeLifetimeExceeded || (ttl >= 0 && c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeExpired).IsTrue())
 }
 
 // SortCandidates orders expired nodes by when they've expired
 func (e *Expiration) filterAndSortCandidates(ctx context.Context, nodes []*Candidate) ([]*Candidate, error) {
-	candidates, err := filterCandidates(ctx, e.kubeClient, e.recorder, nodes)
+	candidates, err := filterCandidates(ctx, e.kubeClient, e.recorder, nodes, true)
 	if err != nil {
 		return nil, fmt.Errorf("filtering candidates, %w", err)
 	}
 	sort.Slice(candidates, func(i int, j int) bool {
-		return candidates[i].NodeClaim.StatusConditions().GetCondition(v1beta1.NodeExpired).LastTransitionTime.Inner.Time.Before(
-			candidates[j].NodeClaim.StatusConditions().GetCondition(v1beta1.NodeExpired).LastTransitionTime.Inner.Time)
+		return expiredAt(candidates[i]).Before(expiredAt(candidates[j]))
 	})
 	return candidates, nil
 }
 
+// expiredAt returns when the candidate expired, either through the expired status condition or by exceeding
+// the MaxNodeLifetime of its NodePool
+func expiredAt(c *Candidate) time.Time {
+	if cond := c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeExpired); cond.IsTrue() {
+		return cond.LastTransitionTime.Inner.Time
+	}
+	expireAt, _, _ := nodepoolutil.MaxNodeLifetimeDeadlines(c.nodePool, c.Node)
+	return expireAt
+}
+
 // ComputeCommand generates a deprovisioning command given deprovisionable nodes
 func (e *Expiration) ComputeCommand(ctx context.Context, nodes ...*Candidate) (Command, error) {
 	candidates, err := e.filterAndSortCandidates(ctx, nodes)
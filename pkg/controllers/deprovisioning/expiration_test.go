@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
@@ -288,6 +289,113 @@ var _ = Describe("Expiration", func() {
 		ExpectExists(ctx, env.Client, machine)
 		ExpectExists(ctx, env.Client, node)
 	})
+	It("should replace nodes past their max node lifetime even if a pod has the do-not-evict annotation", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				},
+			},
+		})
+		_ = machine.StatusConditions().ClearCondition(v1alpha5.MachineExpired)
+		prov.Spec.TTLSecondsUntilExpired = nil
+		prov.Spec.MaxNodeLifetimeSeconds = ptr.Int64(60)
+		ExpectApplied(ctx, env.Client, rs, pod, machine, node, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		// step past the max node lifetime
+		fakeClock.Step(10 * time.Minute)
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		wg.Wait()
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+
+		ExpectNotFound(ctx, env.Client, machine, node)
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		// Expect a single forced deprovisioning event on both the node and the machine
+		Expect(recorder.Calls("DeprovisioningForced")).To(Equal(2))
+	})
+	It("should not ignore do-not-evict pods within the force expiration grace period", func() {
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+			},
+		})
+		_ = machine.StatusConditions().ClearCondition(v1alpha5.MachineExpired)
+		prov.Spec.TTLSecondsUntilExpired = nil
+		prov.Spec.MaxNodeLifetimeSeconds = ptr.Int64(60)
+		prov.Spec.ForceExpirationGracePeriodSeconds = ptr.Int64(3600)
+		ExpectApplied(ctx, env.Client, pod, machine, node, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		// step past the max node lifetime, but not past the grace period
+		fakeClock.Step(10 * time.Minute)
+
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Expect to not create or delete more machines
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+		// No command was executed, so no warning is emitted for the exceeded lifetime
+		Expect(recorder.Calls("MaxNodeLifetimeExceeded")).To(Equal(0))
+	})
+	It("should not ignore do-not-evict pods past the force expiration grace period for other deprovisioners", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, DeprovisioningOrder: []string{"drift"}}))
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+			},
+		})
+		machine.StatusConditions().MarkTrue(v1alpha5.MachineDrifted)
+		prov.Spec.MaxNodeLifetimeSeconds = ptr.Int64(60)
+		ExpectApplied(ctx, env.Client, pod, machine, node, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		// step past the max node lifetime and grace period, only expiration may force the node's deprovisioning
+		fakeClock.Step(10 * time.Minute)
+
+		ExpectReconcileSucceeded(ctx, deprovisioningController, types.NamespacedName{})
+
+		// Expect to not create or delete more machines
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+		Expect(recorder.Calls("DeprovisioningForced")).To(Equal(0))
+	})
 	It("can replace node for expiration", func() {
 		labels := map[string]string{
 			"app": "test",
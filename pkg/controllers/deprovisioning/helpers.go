@@ -20,8 +20,10 @@ import (
 	"math"
 	"strconv"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
@@ -29,21 +31,28 @@ import (
 	pscheduling "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/metrics"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	nodeutils "github.com/aws/karpenter-core/pkg/utils/node"
 	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+	"github.com/aws/karpenter-core/pkg/utils/pdb"
 	"github.com/aws/karpenter-core/pkg/utils/pod"
 	provisionerutil "github.com/aws/karpenter-core/pkg/utils/provisioner"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func filterCandidates(ctx context.Context, kubeClient client.Client, recorder events.Recorder, nodes []*Candidate) ([]*Candidate, error) {
-	pdbs, err := NewPDBLimits(ctx, kubeClient)
+// filterCandidates filters out the candidates that can't be deprovisioned right now. If force is set, candidates past
+// their MaxNodeLifetime and ForceExpirationGracePeriod aren't blocked by PDBs or do-not-evict and do-not-disrupt pods,
+// the blockers that were ignored are recorded on the candidate instead.
+func filterCandidates(ctx context.Context, kubeClient client.Client, recorder events.Recorder, nodes []*Candidate, force bool) ([]*Candidate, error) {
+	pdbs, err := pdb.NewLimits(ctx, kubeClient)
 	if err != nil {
 		return nil, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
 	}
@@ -54,27 +63,59 @@ func filterCandidates(ctx context.Context, kubeClient client.Client, recorder ev
 			recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, "Node in the process of deletion")...)
 			return false
 		}
-		if pdb, ok := pdbs.CanEvictPods(cn.pods); !ok {
-			recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, fmt.Sprintf("PDB %q prevents pod evictions", pdb))...)
-			return false
+		forced := force && cn.forceDeprovisioning
+		cn.forcedBlockers = nil
+		if pdbKey, ok := pdbs.CanEvictPods(cn.pods); !ok {
+			if !forced {
+				recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, fmt.Sprintf("PDB %q prevents pod evictions", pdbKey))...)
+				return false
+			}
+			cn.forcedBlockers = append(cn.forcedBlockers, fmt.Sprintf("ignoring PDB %q", pdbKey))
 		}
 		if p, ok := hasDoNotEvictPod(cn); ok {
-			recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, fmt.Sprintf("Pod %q has do not evict annotation", client.ObjectKeyFromObject(p)))...)
-			return false
+			if !forced {
+				recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, fmt.Sprintf("Pod %q has do not evict annotation", client.ObjectKeyFromObject(p)))...)
+				return false
+			}
+			cn.forcedBlockers = append(cn.forcedBlockers, fmt.Sprintf("ignoring do not evict annotation on pod %q", client.ObjectKeyFromObject(p)))
+		}
+		if p, ok := hasDoNotDisruptPod(cn); ok {
+			if !forced {
+				recorder.Publish(deprovisioningevents.Blocked(cn.Node, cn.NodeClaim, fmt.Sprintf("Pod %q has do not disrupt annotation", client.ObjectKeyFromObject(p)))...)
+				return false
+			}
+			cn.forcedBlockers = append(cn.forcedBlockers, fmt.Sprintf("ignoring do not disrupt annotation on pod %q", client.ObjectKeyFromObject(p)))
 		}
 		return true
 	})
 	return nodes, nil
 }
 
+// maxNodeLifetimeEvents returns the events that warn about a candidate past its MaxNodeLifetime, which are published
+// once a command deprovisioning the candidate is executed
+func maxNodeLifetimeEvents(cn *Candidate) []events.Event {
+	if !cn.maxNodeLifetimeExceeded {
+		return nil
+	}
+	if !cn.forceDeprovisioning {
+		return deprovisioningevents.MaxNodeLifetimeExceeded(cn.Node, cn.NodeClaim, cn.forceDeprovisioningAt)
+	}
+	return lo.Flatten(lo.Map(cn.forcedBlockers, func(reason string, _ int) []events.Event {
+		return deprovisioningevents.ForcedDeprovisioning(cn.Node, cn.NodeClaim, reason)
+	}))
+}
+
 //nolint:gocyclo
 func simulateScheduling(ctx context.Context, kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner,
 	candidates ...*Candidate) (*pscheduling.Results, error) {
+	defer metrics.Measure(deprovisioningSimulationDurationHistogram.With(methodLabelsFromContext(ctx)))()
 	candidateNames := sets.NewString(lo.Map(candidates, func(t *Candidate, i int) string { return t.Name() })...)
 	nodes := cluster.Nodes()
 	deletingNodes := nodes.Deleting()
+	// Nodes that are targets of another deprovisioning command can't receive the candidates' pods, otherwise those
+	// pods would be evicted twice
 	stateNodes := lo.Filter(nodes.Active(), func(n *state.StateNode, _ int) bool {
-		return !candidateNames.Has(n.Name())
+		return !candidateNames.Has(n.Name()) && !n.DisruptionTarget()
 	})
 
 	// We do one final check to ensure that the node that we are attempting to consolidate isn't
@@ -97,8 +138,20 @@ func simulateScheduling(ctx context.Context, kubeClient client.Client, cluster *
 		return nil, fmt.Errorf("determining pending pods, %w", err)
 	}
 
+	// Pods that mount zonal persistent volumes have to stay in the candidate's zone, otherwise they could be
+	// rescheduled to a replacement in a zone where their volumes can't be attached. Candidates without a known zone
+	// leave their pods unconstrained.
+	volumeTopology := pscheduling.NewVolumeTopology(kubeClient)
 	for _, n := range candidates {
-		pods = append(pods, n.pods...)
+		for _, p := range n.pods {
+			if n.zone != "" && lo.ContainsBy(p.Spec.Volumes, func(v v1.Volume) bool { return v.PersistentVolumeClaim != nil || v.Ephemeral != nil }) {
+				p = p.DeepCopy()
+				if err := volumeTopology.InjectZone(ctx, p, n.zone); err != nil {
+					logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(p)).Errorf("getting volume zone requirements, %s", err)
+				}
+			}
+			pods = append(pods, p)
+		}
 	}
 	pods = append(pods, deletingNodePods...)
 	scheduler, err := provisioner.NewScheduler(ctx, pods, stateNodes, pscheduling.SchedulerOptions{
@@ -184,6 +237,7 @@ func GetCandidates(ctx context.Context, cluster *state.Cluster, kubeClient clien
 	if err != nil {
 		return nil, err
 	}
+	recordDisruptionPaused(nodePoolMap)
 	candidates := lo.FilterMap(cluster.Nodes(), func(n *state.StateNode, _ int) (*Candidate, bool) {
 		cn, e := NewCandidate(ctx, kubeClient, recorder, clk, n, nodePoolMap, nodePoolToInstanceTypesMap)
 		return cn, e == nil
@@ -192,6 +246,34 @@ func GetCandidates(ctx context.Context, cluster *state.Cluster, kubeClient clien
 	return lo.Filter(candidates, func(c *Candidate, _ int) bool { return shouldDeprovision(ctx, c) }), nil
 }
 
+// recordDisruptionPaused reports which NodePools have voluntary disruption paused through the disruption-paused annotation
+func recordDisruptionPaused(nodePoolMap map[nodepoolutil.Key]*v1beta1.NodePool) {
+	deprovisioningPausedGauge.Reset()
+	for key, np := range nodePoolMap {
+		deprovisioningPausedGauge.With(prometheus.Labels{
+			metrics.NodePoolLabel:    lo.Ternary(key.IsProvisioner, "", key.Name),
+			metrics.ProvisionerLabel: lo.Ternary(key.IsProvisioner, key.Name, ""),
+		}).Set(lo.Ternary(nodepoolutil.DisruptionPaused(np), 1.0, 0.0))
+	}
+}
+
+// excludedByNodeSelector returns true if the node labels match either the global deprovisioningExcludedNodeSelector
+// setting or the excluded node selector of the node's NodePool
+func excludedByNodeSelector(ctx context.Context, nodePool *v1beta1.NodePool, nodeLabels map[string]string) bool {
+	// selectors are validated by the settings parser and the NodePool webhook, so parsing errors are ignored here
+	if raw := settings.FromContext(ctx).DeprovisioningExcludedNodeSelector; raw != "" {
+		if selector, err := labels.Parse(raw); err == nil && selector.Matches(labels.Set(nodeLabels)) {
+			return true
+		}
+	}
+	if nodePool.Spec.Deprovisioning.ExcludedNodeSelector != nil {
+		if selector, err := metav1.LabelSelectorAsSelector(nodePool.Spec.Deprovisioning.ExcludedNodeSelector); err == nil && selector.Matches(labels.Set(nodeLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
 // buildNodePoolMap builds a provName -> nodePool map and a provName -> instanceName -> instance type map
 func buildNodePoolMap(ctx context.Context, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) (map[nodepoolutil.Key]*v1beta1.NodePool, map[nodepoolutil.Key]map[string]*cloudprovider.InstanceType, error) {
 	nodePoolMap := map[nodepoolutil.Key]*v1beta1.NodePool{}
@@ -213,7 +295,7 @@ func buildNodePoolMap(ctx context.Context, kubeClient client.Client, cloudProvid
 			continue
 		}
 		nodePoolToInstanceTypesMap[key] = map[string]*cloudprovider.InstanceType{}
-		for _, it := range nodePoolInstanceTypes {
+		for _, it := range cloudprovider.WithPrices(cloudProvider, nodePoolInstanceTypes) {
 			nodePoolToInstanceTypesMap[key][it.Name] = it
 		}
 	}
@@ -229,9 +311,19 @@ func mapCandidates(proposed, current []*Candidate) []*Candidate {
 }
 
 // worstLaunchPrice gets the worst-case launch price from the offerings that are offered
-// on an instance type. If the instance type has a spot offering available, then it uses the spot offering
-// to get the launch price; else, it uses the on-demand launch price
+// on an instance type. Reserved offerings are launched first, then spot offerings, and finally on-demand offerings,
+// so the launch price comes from the first of those capacity types that's allowed and available
 func worstLaunchPrice(ofs []cloudprovider.Offering, reqs scheduling.Requirements) float64 {
+	if reqs.Get(v1beta1.CapacityTypeLabelKey).Has(v1beta1.CapacityTypeReserved) {
+		reservedOfferings := lo.Filter(ofs, func(of cloudprovider.Offering, _ int) bool {
+			return of.CapacityType == v1beta1.CapacityTypeReserved && reqs.Get(v1.LabelTopologyZone).Has(of.Zone)
+		})
+		if len(reservedOfferings) > 0 {
+			return lo.MaxBy(reservedOfferings, func(of1, of2 cloudprovider.Offering) bool {
+				return of1.Price > of2.Price
+			}).Price
+		}
+	}
 	// We prefer to launch spot offerings, so we will get the worst price based on the node requirements
 	if reqs.Get(v1beta1.CapacityTypeLabelKey).Has(v1beta1.CapacityTypeSpot) {
 		spotOfferings := lo.Filter(ofs, func(of cloudprovider.Offering, _ int) bool {
@@ -274,3 +366,42 @@ func hasDoNotEvictPod(c *Candidate) (*v1.Pod, bool) {
 		return pod.HasDoNotEvict(p)
 	})
 }
+
+func hasDoNotDisruptPod(c *Candidate) (*v1.Pod, bool) {
+	return lo.Find(c.pods, func(p *v1.Pod) bool {
+		if pod.IsTerminating(p) || pod.IsTerminal(p) || pod.IsOwnedByNode(p) {
+			return false
+		}
+		return pod.HasDoNotDisrupt(p)
+	})
+}
+
+// atReplicaFloor returns true if the NodePool of the candidate owns no more nodes than its replicas, meaning that
+// removing the candidate would take the NodePool below its minimum capacity
+func atReplicaFloor(c *Candidate) bool {
+	if c.nodePool.Spec.Replicas == nil {
+		return false
+	}
+	nodes := c.nodePool.Status.Resources[v1beta1.ResourceNodes]
+	return nodes.Value() <= int64(*c.nodePool.Spec.Replicas)
+}
+
+// limitToReplicaFloor drops the candidates that would take their NodePool below its replicas if all the candidates
+// were removed together
+func limitToReplicaFloor(candidates []*Candidate) []*Candidate {
+	surplus := map[*v1beta1.NodePool]int64{}
+	return lo.Filter(candidates, func(c *Candidate, _ int) bool {
+		if c.nodePool.Spec.Replicas == nil {
+			return true
+		}
+		if _, ok := surplus[c.nodePool]; !ok {
+			nodes := c.nodePool.Status.Resources[v1beta1.ResourceNodes]
+			surplus[c.nodePool] = nodes.Value() - int64(*c.nodePool.Spec.Replicas)
+		}
+		if surplus[c.nodePool] <= 0 {
+			return false
+		}
+		surplus[c.nodePool]--
+		return true
+	})
+}
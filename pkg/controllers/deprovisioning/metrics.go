@@ -15,6 +15,8 @@ limitations under the License.
 package deprovisioning
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
@@ -23,7 +25,10 @@ import (
 
 func init() {
 	crmetrics.Registry.MustRegister(deprovisioningDurationHistogram, deprovisioningReplacementNodeInitializedHistogram, deprovisioningActionsPerformedCounter,
-		deprovisioningEligibleMachinesGauge, deprovisioningReplacementNodeLaunchFailedCounter, deprovisioningConsolidationTimeoutsCounter)
+		deprovisioningEligibleMachinesGauge, deprovisioningReplacementNodeLaunchFailedCounter, deprovisioningConsolidationTimeoutsCounter,
+		deprovisioningPausedGauge, deprovisioningDryRunActionsCounter, deprovisioningRateLimitedActionsCounter,
+		deprovisioningVetoedActionsCounter, deprovisioningActionsAttemptedCounter, deprovisioningActionOutcomesCounter,
+		deprovisioningCandidatesEvaluatedCounter, deprovisioningSimulationDurationHistogram, deprovisioningEstimatedSavingsHistogram)
 }
 
 const (
@@ -31,7 +36,9 @@ const (
 	deprovisionerLabel      = "deprovisioner"
 	actionLabel             = "action"
 	consolidationType       = "consolidation_type"
+	outcomeLabel            = "outcome"
 
+	emptyMachineConsolidationLabelValue  = "empty-machine"
 	multiMachineConsolidationLabelValue  = "multi-machine"
 	singleMachineConsolidationLabelValue = "single-machine"
 )
@@ -63,6 +70,33 @@ var (
 		},
 		[]string{actionLabel, deprovisionerLabel},
 	)
+	deprovisioningDryRunActionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "dry_run_actions",
+			Help:      "Number of deprovisioning actions that would have been performed in dry-run mode. Labeled by action and deprovisioner.",
+		},
+		[]string{actionLabel, deprovisionerLabel},
+	)
+	deprovisioningRateLimitedActionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "rate_limited_actions",
+			Help:      "Number of deprovisioning actions that were deferred by the disruption rate limit. Labeled by deprovisioner.",
+		},
+		[]string{deprovisionerLabel},
+	)
+	deprovisioningVetoedActionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "vetoed_actions",
+			Help:      "Number of deprovisioning actions that were vetoed by the deprovisioning webhook. Labeled by deprovisioner.",
+		},
+		[]string{deprovisionerLabel},
+	)
 	deprovisioningEligibleMachinesGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: metrics.Namespace,
@@ -72,6 +106,15 @@ var (
 		},
 		[]string{deprovisionerLabel},
 	)
+	deprovisioningPausedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "paused",
+			Help:      "Whether voluntary disruption is paused through the disruption-paused annotation. Labeled by provisioner and nodepool.",
+		},
+		[]string{metrics.ProvisionerLabel, metrics.NodePoolLabel},
+	)
 	deprovisioningConsolidationTimeoutsCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: metrics.Namespace,
@@ -90,4 +133,83 @@ var (
 		},
 		[]string{deprovisionerLabel},
 	)
+	deprovisioningActionsAttemptedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "actions_attempted",
+			Help:      "Number of deprovisioning actions that were attempted. Labeled by deprovisioner and consolidation type.",
+		},
+		[]string{deprovisionerLabel, consolidationType},
+	)
+	deprovisioningActionOutcomesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "action_outcomes",
+			Help:      "Number of attempted deprovisioning actions that succeeded or failed. Labeled by deprovisioner, consolidation type and outcome.",
+		},
+		[]string{deprovisionerLabel, consolidationType, outcomeLabel},
+	)
+	deprovisioningCandidatesEvaluatedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "candidates_evaluated",
+			Help:      "Number of candidates evaluated for deprovisioning after disruption budgets are applied. Labeled by deprovisioner and consolidation type.",
+		},
+		[]string{deprovisionerLabel, consolidationType},
+	)
+	deprovisioningSimulationDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "simulation_duration_seconds",
+			Help:      "Duration of the scheduling simulations used to determine whether candidates can be deprovisioned in seconds. Labeled by deprovisioner and consolidation type.",
+			Buckets:   metrics.DurationBuckets(),
+		},
+		[]string{deprovisionerLabel, consolidationType},
+	)
+	deprovisioningEstimatedSavingsHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: deprovisioningSubsystem,
+			Name:      "estimated_savings_per_hour",
+			Help:      "Estimated hourly savings of the deprovisioning actions that succeeded. Labeled by deprovisioner and consolidation type.",
+			Buckets:   []float64{0, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50},
+		},
+		[]string{deprovisionerLabel, consolidationType},
+	)
 )
+
+// methodLabels returns the labels that identify the deprovisioning method. The consolidation deprovisioners share a
+// name, so they are told apart by their consolidation type.
+func methodLabels(d Deprovisioner) prometheus.Labels {
+	labels := prometheus.Labels{deprovisionerLabel: d.String(), consolidationType: ""}
+	switch d.(type) {
+	case *EmptyMachineConsolidation:
+		labels[consolidationType] = emptyMachineConsolidationLabelValue
+	case *SingleMachineConsolidation:
+		labels[consolidationType] = singleMachineConsolidationLabelValue
+	case *MultiMachineConsolidation:
+		labels[consolidationType] = multiMachineConsolidationLabelValue
+	}
+	return labels
+}
+
+type methodLabelsKeyType struct{}
+
+var methodLabelsKey = methodLabelsKeyType{}
+
+// withMethodLabels stores the labels of the deprovisioning method in the context so that the scheduling simulations
+// performed on its behalf are attributed to it
+func withMethodLabels(ctx context.Context, d Deprovisioner) context.Context {
+	return context.WithValue(ctx, methodLabelsKey, methodLabels(d))
+}
+
+func methodLabelsFromContext(ctx context.Context) prometheus.Labels {
+	if labels, ok := ctx.Value(methodLabelsKey).(prometheus.Labels); ok {
+		return labels
+	}
+	return prometheus.Labels{deprovisionerLabel: "", consolidationType: ""}
+}
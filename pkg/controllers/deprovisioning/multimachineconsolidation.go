@@ -18,7 +18,6 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"time"
 
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -26,6 +25,7 @@ import (
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
@@ -33,8 +33,6 @@ import (
 	"github.com/aws/karpenter-core/pkg/events"
 )
 
-const MultiMachineConsolidationTimeoutDuration = 1 * time.Minute
-
 type MultiMachineConsolidation struct {
 	consolidation
 }
@@ -54,9 +52,8 @@ func (m *MultiMachineConsolidation) ComputeCommand(ctx context.Context, candidat
 	}
 	deprovisioningEligibleMachinesGauge.WithLabelValues(m.String()).Set(float64(len(candidates)))
 
-	// Only consider a maximum batch of 100 machines to save on computation.
-	// This could be further configurable in the future.
-	maxParallel := lo.Clamp(len(candidates), 0, 100)
+	// Only consider a maximum batch of machines to save on computation
+	maxParallel := lo.Clamp(len(candidates), 0, settings.FromContext(ctx).MultiMachineConsolidationMaxMachines)
 
 	cmd, err := m.firstNMachineConsolidationOption(ctx, candidates, maxParallel)
 	if err != nil {
@@ -96,7 +93,7 @@ func (m *MultiMachineConsolidation) firstNMachineConsolidationOption(ctx context
 
 	lastSavedCommand := Command{}
 	// Set a timeout
-	timeout := m.clock.Now().Add(MultiMachineConsolidationTimeoutDuration)
+	timeout := m.clock.Now().Add(settings.FromContext(ctx).MultiMachineConsolidationTimeout)
 	// binary search to find the maximum number of machines we can terminate
 	for min <= max {
 		if m.clock.Now().After(timeout) {
@@ -120,7 +117,7 @@ func (m *MultiMachineConsolidation) firstNMachineConsolidationOption(ctx context
 		// required
 		replacementHasValidInstanceTypes := false
 		if cmd.Action() == ReplaceAction {
-			cmd.replacements[0].InstanceTypeOptions = filterOutSameType(cmd.replacements[0], candidatesToConsolidate)
+			cmd.replacements[0].InstanceTypeOptions = filterOutSameType(cmd.replacements[0], cmd.candidates)
 			replacementHasValidInstanceTypes = len(cmd.replacements[0].InstanceTypeOptions) > 0
 		}
 
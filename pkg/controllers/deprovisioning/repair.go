@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	deprovisioningevents "github.com/aws/karpenter-core/pkg/controllers/deprovisioning/events"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/metrics"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+)
+
+// Repair is a subreconciler that replaces machines whose nodes have stayed unhealthy for longer than
+// nodeRepairUnhealthyDuration.
+type Repair struct {
+	kubeClient  client.Client
+	cluster     *state.Cluster
+	provisioner *provisioning.Provisioner
+	recorder    events.Recorder
+}
+
+func NewRepair(kubeClient client.Client, cluster *state.Cluster, provisioner *provisioning.Provisioner, recorder events.Recorder) *Repair {
+	return &Repair{
+		kubeClient:  kubeClient,
+		cluster:     cluster,
+		provisioner: provisioner,
+		recorder:    recorder,
+	}
+}
+
+// ShouldDeprovision is a predicate used to filter deprovisionable machines
+func (r *Repair) ShouldDeprovision(ctx context.Context, c *Candidate) bool {
+	return settings.FromContext(ctx).NodeRepairUnhealthyDuration > 0 &&
+		c.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeUnhealthy).IsTrue()
+}
+
+// filterAndSortCandidates orders unhealthy nodes by when they were marked unhealthy
+func (r *Repair) filterAndSortCandidates(ctx context.Context, nodes []*Candidate) ([]*Candidate, error) {
+	candidates, err := filterCandidates(ctx, r.kubeClient, r.recorder, nodes, false)
+	if err != nil {
+		return nil, fmt.Errorf("filtering candidates, %w", err)
+	}
+	sort.Slice(candidates, func(i int, j int) bool {
+		return candidates[i].NodeClaim.StatusConditions().GetCondition(v1beta1.NodeUnhealthy).LastTransitionTime.Inner.Time.Before(
+			candidates[j].NodeClaim.StatusConditions().GetCondition(v1beta1.NodeUnhealthy).LastTransitionTime.Inner.Time)
+	})
+	return candidates, nil
+}
+
+// ComputeCommand generates a deprovisioning command given deprovisionable machines
+func (r *Repair) ComputeCommand(ctx context.Context, nodes ...*Candidate) (Command, error) {
+	candidates, err := r.filterAndSortCandidates(ctx, nodes)
+	if err != nil {
+		return Command{}, err
+	}
+	deprovisioningEligibleMachinesGauge.WithLabelValues(r.String()).Set(float64(len(candidates)))
+	candidates = r.filterByMaxUnhealthy(ctx, candidates)
+
+	// Deprovision all empty unhealthy nodes, as they require no scheduling simulations.
+	if empty := lo.Filter(candidates, func(c *Candidate, _ int) bool {
+		return len(c.pods) == 0
+	}); len(empty) > 0 {
+		return Command{
+			candidates: empty,
+		}, nil
+	}
+
+	for _, candidate := range candidates {
+		// Check if we need to create any machines.
+		results, err := simulateScheduling(ctx, r.kubeClient, r.cluster, r.provisioner, candidate)
+		if err != nil {
+			// if a candidate machine is now deleting, just retry
+			if errors.Is(err, errCandidateDeleting) {
+				continue
+			}
+			return Command{}, err
+		}
+		// Log when all pods can't schedule, as the command will get executed immediately.
+		if !results.AllNonPendingPodsScheduled() {
+			logging.FromContext(ctx).With("machine", candidate.NodeClaim.Name, "node", candidate.Node.Name).Debugf("cannot terminate unhealthy machine since scheduling simulation failed to schedule all pods, %s", results.PodSchedulingErrors())
+			r.recorder.Publish(deprovisioningevents.Blocked(candidate.Node, candidate.NodeClaim, "Scheduling simulation failed to schedule all pods")...)
+			continue
+		}
+		logging.FromContext(ctx).With("machine", candidate.NodeClaim.Name, "node", candidate.Node.Name).Infof("triggering termination for unhealthy node, %s",
+			candidate.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeUnhealthy).Message)
+		return Command{
+			candidates:   []*Candidate{candidate},
+			replacements: results.NewNodeClaims,
+		}, nil
+	}
+	return Command{}, nil
+}
+
+// filterByMaxUnhealthy drops the candidates of NodePools where more than nodeRepairMaxUnhealthyPercentage of the
+// nodes are unhealthy, since replacing them is unlikely to help and would only add to the disruption
+func (r *Repair) filterByMaxUnhealthy(ctx context.Context, candidates []*Candidate) []*Candidate {
+	nodes := map[nodepoolutil.Key]int{}
+	unhealthy := map[nodepoolutil.Key]int{}
+	r.cluster.ForEachNode(func(n *state.StateNode) bool {
+		if !n.Managed() {
+			return true
+		}
+		nodes[n.OwnerKey()]++
+		if n.NodeClaim != nil && n.NodeClaim.StatusConditions().GetCondition(v1beta1.NodeUnhealthy).IsTrue() {
+			unhealthy[n.OwnerKey()]++
+		}
+		return true
+	})
+	maxUnhealthyPercentage := settings.FromContext(ctx).NodeRepairMaxUnhealthyPercentage
+	return lo.Filter(candidates, func(c *Candidate, _ int) bool {
+		key := c.OwnerKey()
+		maxUnhealthy := int(math.Ceil(float64(nodes[key]*maxUnhealthyPercentage) / 100))
+		if unhealthy[key] <= maxUnhealthy {
+			return true
+		}
+		logging.FromContext(ctx).With("nodepool", key.Name, "unhealthy", unhealthy[key], "nodes", nodes[key]).Debugf("not repairing unhealthy nodes, more than %d%% of the nodes are unhealthy", maxUnhealthyPercentage)
+		r.recorder.Publish(deprovisioningevents.Blocked(c.Node, c.NodeClaim, fmt.Sprintf("More than %d%% of the nodes of %q are unhealthy", maxUnhealthyPercentage, key.Name))...)
+		return false
+	})
+}
+
+// String is the string representation of the deprovisioner
+func (r *Repair) String() string {
+	return metrics.RepairReason
+}
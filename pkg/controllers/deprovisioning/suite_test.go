@@ -37,6 +37,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	clock "k8s.io/utils/clock/testing"
 	. "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/ptr"
@@ -268,6 +269,321 @@ var _ = Describe("Replace Nodes", func() {
 		// and delete the old one
 		ExpectNotFound(ctx, env.Client, machine, node)
 	})
+	It("should keep the replacement in the zone of its pods' persistent volumes", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// the volume could be attached in any zone, but it's only known to be attached in the node's zone
+		pv := test.PersistentVolume(test.PersistentVolumeOptions{Zones: []string{"test-zone-1", "test-zone-2", "test-zone-3"}})
+		pvc := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{VolumeName: pv.Name})
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			PersistentVolumeClaims: []string{pvc.Name},
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		ExpectApplied(ctx, env.Client, rs, pv, pvc, pod, node, machine, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+
+		// consolidation won't delete the old machine until the new machine is ready
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		wg.Wait()
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+
+		// the replacement is constrained to the zone of the node that it replaced
+		machines := ExpectMachines(ctx, env.Client)
+		Expect(machines).To(HaveLen(1))
+		Expect(machines[0].Name).ToNot(Equal(machine.Name))
+		Expect(scheduling.NewNodeSelectorRequirements(machines[0].Spec.Requirements...).Get(v1.LabelTopologyZone).Values()).To(ConsistOf(mostExpensiveOffering.Zone))
+		ExpectNotFound(ctx, env.Client, machine, node)
+	})
+	It("should keep the replacement in the zone of its pods' ephemeral volumes", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		// the volume could be attached in any zone, but it's only known to be attached in the node's zone
+		pv := test.PersistentVolume(test.PersistentVolumeOptions{Zones: []string{"test-zone-1", "test-zone-2", "test-zone-3"}})
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}},
+			EphemeralVolumeTemplates: []test.EphemeralVolumeTemplateOptions{{}},
+		})
+		// the claim of an ephemeral volume is named after the pod and its volume
+		pvc := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s", pod.Name, pod.Spec.Volumes[0].Name)},
+			VolumeName: pv.Name,
+		})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		ExpectApplied(ctx, env.Client, rs, pv, pvc, pod, node, machine, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+
+		// consolidation won't delete the old machine until the new machine is ready
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		wg.Wait()
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+
+		// the replacement is constrained to the zone of the node that it replaced
+		machines := ExpectMachines(ctx, env.Client)
+		Expect(machines).To(HaveLen(1))
+		Expect(machines[0].Name).ToNot(Equal(machine.Name))
+		Expect(scheduling.NewNodeSelectorRequirements(machines[0].Spec.Requirements...).Get(v1.LabelTopologyZone).Values()).To(ConsistOf(mostExpensiveOffering.Zone))
+		ExpectNotFound(ctx, env.Client, machine, node)
+	})
+	It("should not replace a node when the savings are below the provisioner's minimum savings percent", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true), MinSavingsPercent: ptr.Int32(99)},
+		})
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		ExpectApplied(ctx, env.Client, rs, pod, node, machine, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		// no replacement is 99% cheaper than the most expensive instance type
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("should not replace a node when the savings are below the global minimum savings per hour", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, ConsolidationMinSavingsPerHour: mostExpensiveOffering.Price}))
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		ExpectApplied(ctx, env.Client, rs, pod, node, machine, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pod, node)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		// no replacement saves the entire price of the existing node
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("should compare prices with the cloudprovider's pricing when replacing nodes", func() {
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+
+		prov := test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+					v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+					v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		// the pricing reports every other instance type as more expensive than the offerings suggest
+		for _, it := range cloudProvider.InstanceTypes {
+			if it.Name != mostExpensiveInstance.Name {
+				cloudProvider.OnDemandPrices[it.Name] = 1000
+				cloudProvider.SpotPrices[it.Name] = 1000
+			}
+		}
+		ExpectApplied(ctx, env.Client, rs, pod, node, machine, prov)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+
+		fakeClock.Step(10 * time.Minute)
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		wg.Wait()
+
+		// no replacement is cheaper, so the machine is kept
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+	})
 	It("can replace nodes if another provisioner has no node template", func() {
 		labels := map[string]string{
 			"app": "test",
@@ -1043,7 +1359,98 @@ var _ = Describe("Delete Node", func() {
 			},
 		})
 	})
-	It("can delete nodes", func() {
+	It("can delete nodes", func() {
+		labels := map[string]string{
+			"app": "test",
+		}
+		// create our RS so we can link a pod to it
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		pods := test.Pods(3, test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], machine1, node1, machine2, node2, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node1, node2}, []*v1alpha5.Machine{machine1, machine2})
+
+		fakeClock.Step(10 * time.Minute)
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		wg.Wait()
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine2)
+
+		// we don't need a new node, but we should evict everything off one of node2 which only has a single pod
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		// and delete the old one
+		ExpectNotFound(ctx, env.Client, machine2, node2)
+	})
+	It("should not delete nodes whose pods would only fit on the target of another deprovisioning command", func() {
+		pods := ExpectReplicaSetPods(ctx, env.Client, 3)
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], machine1, node1, machine2, node2, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node1, node2}, []*v1alpha5.Machine{machine1, machine2})
+
+		// node1 is being disrupted by another command, so node2's pod can't be moved onto it
+		cluster.StartDeprovisioningCommand(uuid.NewUUID(), node1.Spec.ProviderID)
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		// Expect to not create or delete more machines
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(2))
+		ExpectExists(ctx, env.Client, machine1)
+		ExpectExists(ctx, env.Client, machine2)
+	})
+	It("should not delete nodes that have had pod changes within consolidateAfter", func() {
+		prov.Spec.Consolidation.ConsolidateAfterSeconds = ptr.Int64(1800)
+		pods := ExpectReplicaSetPods(ctx, env.Client, 3)
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], machine1, node1, machine2, node2, prov)
+
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node1, node2}, []*v1alpha5.Machine{machine1, machine2})
+
+		// pods were bound less than consolidateAfter ago, so neither node is a consolidation candidate
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(2))
+		ExpectExists(ctx, env.Client, machine1)
+		ExpectExists(ctx, env.Client, machine2)
+	})
+	It("should not delete nodes utilized above the consolidation utilization threshold", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, ConsolidationUtilizationThreshold: 25}))
 		labels := map[string]string{
 			"app": "test",
 		}
@@ -1061,7 +1468,11 @@ var _ = Describe("Delete Node", func() {
 						Controller:         ptr.Bool(true),
 						BlockOwnerDeletion: ptr.Bool(true),
 					},
-				}}})
+				}},
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")},
+			},
+		})
 		ExpectApplied(ctx, env.Client, rs, pods[0], pods[1], pods[2], machine1, node1, machine2, node2, prov)
 
 		// bind pods to node
@@ -1072,21 +1483,36 @@ var _ = Describe("Delete Node", func() {
 		// inform cluster state about nodes and machines
 		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node1, node2}, []*v1alpha5.Machine{machine1, machine2})
 
+		// node2 would otherwise be deleted, but its pod requests ~31% of its CPU
 		fakeClock.Step(10 * time.Minute)
-
-		var wg sync.WaitGroup
-		ExpectTriggerVerifyAction(&wg)
 		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
-		wg.Wait()
 
-		// Cascade any deletion of the machine to the node
-		ExpectMachinesCascadeDeletion(ctx, env.Client, machine2)
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(2))
+		ExpectExists(ctx, env.Client, machine1)
+		ExpectExists(ctx, env.Client, machine2)
+	})
+	It("should not delete underutilized nodes when the consolidation policy is WhenEmpty", func() {
+		prov.Spec.ConsolidationPolicy = v1alpha5.ConsolidationPolicyWhenEmpty
+		pods := ExpectReplicaSetPods(ctx, env.Client, 3)
+		ExpectApplied(ctx, env.Client, pods[0], pods[1], pods[2], machine1, node1, machine2, node2, prov)
 
-		// we don't need a new node, but we should evict everything off one of node2 which only has a single pod
-		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
-		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
-		// and delete the old one
-		ExpectNotFound(ctx, env.Client, machine2, node2)
+		// bind pods to node
+		ExpectManualBinding(ctx, env.Client, pods[0], node1)
+		ExpectManualBinding(ctx, env.Client, pods[1], node1)
+		ExpectManualBinding(ctx, env.Client, pods[2], node2)
+
+		// inform cluster state about nodes and machines
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node1, node2}, []*v1alpha5.Machine{machine1, machine2})
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		// Neither node is empty, so both should remain
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(2))
+		ExpectExists(ctx, env.Client, machine1)
+		ExpectExists(ctx, env.Client, machine2)
 	})
 	It("can delete nodes if another provisioner has no node template", func() {
 		labels := map[string]string{
@@ -2331,7 +2757,7 @@ var _ = Describe("Consolidation Timeout", func() {
 		}()
 
 		// advance the clock so that the timeout expires
-		fakeClock.Step(deprovisioning.MultiMachineConsolidationTimeoutDuration)
+		fakeClock.Step(settings.FromContext(ctx).MultiMachineConsolidationTimeout)
 
 		// wait for the controller to block on the validation timeout
 		Eventually(fakeClock.HasWaiters, time.Second*10).Should(BeTrue())
@@ -2423,7 +2849,7 @@ var _ = Describe("Consolidation Timeout", func() {
 		}()
 
 		// advance the clock so that the timeout expires for multi-machine
-		fakeClock.Step(deprovisioning.MultiMachineConsolidationTimeoutDuration)
+		fakeClock.Step(settings.FromContext(ctx).MultiMachineConsolidationTimeout)
 		// advance the clock so that the timeout expires for single-machine
 		fakeClock.Step(deprovisioning.SingleMachineConsolidationTimeoutDuration)
 
@@ -3007,6 +3433,262 @@ var _ = Describe("Multi-Node Consolidation", func() {
 	})
 })
 
+var _ = Describe("Spot To Spot Consolidation", func() {
+	var prov *v1alpha5.Provisioner
+	var machine *v1alpha5.Machine
+	var node *v1.Node
+	var pod *v1.Pod
+
+	BeforeEach(func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-spot",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeSpot,
+					Zone:         "test-zone-1a",
+					Price:        1.0,
+					Available:    false,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance}
+		// three spot instance types that are cheaper than the current one
+		for i := 0; i < 3; i++ {
+			cloudProvider.InstanceTypes = append(cloudProvider.InstanceTypes, fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: fmt.Sprintf("spot-replacement-%d", i),
+				Offerings: []cloudprovider.Offering{
+					{
+						CapacityType: v1alpha5.CapacityTypeSpot,
+						Zone:         "test-zone-1a",
+						Price:        0.1 * float64(i+1),
+						Available:    true,
+					},
+				},
+			}))
+		}
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		pod = test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}})
+		prov = test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+			Requirements: []v1.NodeSelectorRequirement{
+				{
+					Key:      v1alpha5.LabelCapacityType,
+					Operator: v1.NodeSelectorOpIn,
+					Values:   []string{v1alpha5.CapacityTypeSpot},
+				},
+			},
+		})
+		machine, node = test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		ExpectApplied(ctx, env.Client, rs, pod, machine, node, prov)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+		fakeClock.Step(10 * time.Minute)
+	})
+	It("won't replace a spot node with a spot node when spot-to-spot consolidation is disabled", func() {
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("won't replace a spot node when fewer cheaper instance types than the minimum flexibility remain", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, SpotToSpotConsolidation: true, SpotToSpotConsolidationMinFlexibility: 4}))
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("can replace a spot node with the cheapest spot instance types when enough remain", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, SpotToSpotConsolidation: true, SpotToSpotConsolidationMinFlexibility: 2}))
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		wg.Wait()
+
+		// Cascade any deletion of the machine to the node
+		ExpectMachinesCascadeDeletion(ctx, env.Client, machine)
+
+		machines := ExpectMachines(ctx, env.Client)
+		Expect(machines).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		requirements := scheduling.NewNodeSelectorRequirements(machines[0].Spec.Requirements...)
+		Expect(requirements.Get(v1.LabelInstanceTypeStable).Values()).To(ConsistOf("spot-replacement-0", "spot-replacement-1"))
+		Expect(requirements.Get(v1alpha5.LabelCapacityType).Values()).To(ConsistOf(v1alpha5.CapacityTypeSpot))
+		ExpectNotFound(ctx, env.Client, machine, node)
+	})
+})
+
+var _ = Describe("Reserved Capacity Consolidation", func() {
+	var machine *v1alpha5.Machine
+	var node *v1.Node
+	var prov *v1alpha5.Provisioner
+	var podOptions test.PodOptions
+
+	BeforeEach(func() {
+		currentInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-reserved",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeReserved,
+					Zone:         "test-zone-1a",
+					Price:        1.0,
+					Available:    true,
+				},
+			},
+		})
+		replacementInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "cheaper-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        0.1,
+					Available:    true,
+				},
+			},
+		})
+		onDemandInstance := fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "current-on-demand",
+			Offerings: []cloudprovider.Offering{
+				{
+					CapacityType: v1alpha5.CapacityTypeOnDemand,
+					Zone:         "test-zone-1a",
+					Price:        1.0,
+					Available:    true,
+				},
+			},
+		})
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{currentInstance, replacementInstance, onDemandInstance}
+
+		rs := test.ReplicaSet()
+		ExpectApplied(ctx, env.Client, rs)
+		Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(rs), rs)).To(Succeed())
+		podOptions = test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "apps/v1",
+						Kind:               "ReplicaSet",
+						Name:               rs.Name,
+						UID:                rs.UID,
+						Controller:         ptr.Bool(true),
+						BlockOwnerDeletion: ptr.Bool(true),
+					},
+				}}}
+		pod := test.Pod(podOptions)
+		prov = test.Provisioner(test.ProvisionerOptions{
+			Consolidation: &v1alpha5.Consolidation{Enabled: ptr.Bool(true)},
+		})
+		machine, node = test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: prov.Name,
+					v1.LabelInstanceTypeStable:       currentInstance.Name,
+					v1alpha5.LabelCapacityType:       currentInstance.Offerings[0].CapacityType,
+					v1.LabelTopologyZone:             currentInstance.Offerings[0].Zone,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID:  test.RandomProviderID(),
+				Allocatable: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("32")},
+			},
+		})
+		ExpectApplied(ctx, env.Client, rs, pod, machine, node, prov)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, []*v1.Node{node}, []*v1alpha5.Machine{machine})
+		fakeClock.Step(10 * time.Minute)
+	})
+	It("won't replace a node on reserved capacity with a cheaper node", func() {
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		Expect(ExpectNodes(ctx, env.Client)).To(HaveLen(1))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		ExpectExists(ctx, env.Client, machine)
+		ExpectExists(ctx, env.Client, node)
+	})
+	It("should consolidate the other nodes of a multi-node command and leave the node on reserved capacity", func() {
+		var onDemandMachines []*v1alpha5.Machine
+		var onDemandNodes []*v1.Node
+		for i := 0; i < 2; i++ {
+			m, n := test.MachineAndNode(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: prov.Name,
+						v1.LabelInstanceTypeStable:       "current-on-demand",
+						v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeOnDemand,
+						v1.LabelTopologyZone:             "test-zone-1a",
+					},
+				},
+				Status: v1alpha5.MachineStatus{
+					ProviderID: test.RandomProviderID(),
+					Allocatable: map[v1.ResourceName]resource.Quantity{
+						v1.ResourceCPU:  resource.MustParse("1"),
+						v1.ResourcePods: resource.MustParse("1"),
+					},
+				},
+			})
+			opts := podOptions
+			opts.ResourceRequirements = v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}
+			pod := test.Pod(opts)
+			ExpectApplied(ctx, env.Client, pod, m, n)
+			ExpectManualBinding(ctx, env.Client, pod, n)
+			onDemandMachines = append(onDemandMachines, m)
+			onDemandNodes = append(onDemandNodes, n)
+		}
+		ExpectMakeInitializedAndStateUpdated(ctx, env.Client, nodeStateController, machineStateController, append(onDemandNodes, node), append(onDemandMachines, machine))
+		fakeClock.Step(10 * time.Minute)
+
+		var wg sync.WaitGroup
+		ExpectTriggerVerifyAction(&wg)
+		ExpectMakeNewMachinesReady(ctx, env.Client, &wg, cluster, cloudProvider, 1)
+		ExpectReconcileSucceeded(ctx, deprovisioningController, client.ObjectKey{})
+		wg.Wait()
+
+		// Cascade any deletion of the machines to their nodes
+		ExpectMachinesCascadeDeletion(ctx, env.Client, onDemandMachines...)
+
+		// both on-demand nodes are replaced by a single cheaper node while the reserved node is left alone
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+		ExpectExists(ctx, env.Client, machine)
+		ExpectExists(ctx, env.Client, node)
+		for _, m := range onDemandMachines {
+			ExpectNotFound(ctx, env.Client, m)
+		}
+	})
+})
+
 func leastExpensiveInstanceWithZone(zone string) *cloudprovider.InstanceType {
 	for _, elem := range onDemandInstances {
 		if hasZone(elem.Offerings, zone) {
@@ -3155,6 +3837,61 @@ func ExpectMakeInitializedAndStateUpdated(ctx context.Context, c client.Client,
 	}
 }
 
+// ExpectReplicaSetPods applies a ReplicaSet and returns count pods that it owns, so that the pods can be rescheduled
+// during deprovisioning
+func ExpectReplicaSetPods(ctx context.Context, c client.Client, count int) []*v1.Pod {
+	rs := test.ReplicaSet()
+	ExpectApplied(ctx, c, rs)
+	return test.Pods(count, test.PodOptions{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"app": "test"},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         "apps/v1",
+					Kind:               "ReplicaSet",
+					Name:               rs.Name,
+					UID:                rs.UID,
+					Controller:         ptr.Bool(true),
+					BlockOwnerDeletion: ptr.Bool(true),
+				},
+			},
+		},
+	})
+}
+
+// expiredMachineAndNode returns a machine and node of the provisioner on the most expensive offering, the machine
+// holding the expired status condition
+func expiredMachineAndNode(prov *v1alpha5.Provisioner) (*v1alpha5.Machine, *v1.Node) {
+	machine, node := test.MachineAndNode(v1alpha5.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				v1alpha5.ProvisionerNameLabelKey: prov.Name,
+				v1.LabelInstanceTypeStable:       mostExpensiveInstance.Name,
+				v1alpha5.LabelCapacityType:       mostExpensiveOffering.CapacityType,
+				v1.LabelTopologyZone:             mostExpensiveOffering.Zone,
+			},
+		},
+		Status: v1alpha5.MachineStatus{
+			ProviderID: test.RandomProviderID(),
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:  resource.MustParse("32"),
+				v1.ResourcePods: resource.MustParse("100"),
+			},
+		},
+	})
+	machine.StatusConditions().MarkTrue(v1alpha5.MachineExpired)
+	return machine, node
+}
+
+// ExpectDryRunActions returns the number of actions that the deprovisioner would have taken in dry-run mode
+func ExpectDryRunActions(deprovisioner string) float64 {
+	m, ok := FindMetricWithLabelValues("karpenter_deprovisioning_dry_run_actions", map[string]string{"deprovisioner": deprovisioner})
+	if !ok {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
 // cheapestOffering grabs the cheapest offering from the passed offerings
 func cheapestOffering(ofs []cloudprovider.Offering) cloudprovider.Offering {
 	offering := cloudprovider.Offering{Price: math.MaxFloat64}
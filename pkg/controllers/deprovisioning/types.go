@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
@@ -42,6 +43,15 @@ type Deprovisioner interface {
 	String() string
 }
 
+// CommandRecorder is implemented by deprovisioners that need to know which of their commands are executed, e.g. to
+// count the disrupted nodes against a rate limit while the command is in flight
+type CommandRecorder interface {
+	// RecordCommand is called when the command is dispatched for execution
+	RecordCommand(Command)
+	// ReleaseCommand is called when the command failed, undoing RecordCommand
+	ReleaseCommand(Command)
+}
+
 type CandidateFilter func(context.Context, *Candidate) bool
 
 // Candidate is a state.StateNode that we are considering for deprovisioning along with extra information to be used in
@@ -54,6 +64,14 @@ type Candidate struct {
 	capacityType   string
 	disruptionCost float64
 	pods           []*v1.Pod
+	// maxNodeLifetimeExceeded is set when the node is older than the MaxNodeLifetime of its NodePool
+	maxNodeLifetimeExceeded bool
+	// forceDeprovisioning is set once the ForceExpirationGracePeriod has also elapsed, after which
+	// PodDisruptionBudgets and do-not-evict pods no longer block expiration of the node
+	forceDeprovisioning   bool
+	forceDeprovisioningAt time.Time
+	// forcedBlockers describes the PDBs and pods that would have blocked the forced deprovisioning of the node
+	forcedBlockers []string
 }
 
 //nolint:gocyclo
@@ -67,10 +85,18 @@ func NewCandidate(ctx context.Context, kubeClient client.Client, recorder events
 	if node.MarkedForDeletion() {
 		return nil, fmt.Errorf("state node is marked for deletion")
 	}
+	// skip nodes that are already being disrupted by another command
+	if node.DisruptionTarget() {
+		return nil, fmt.Errorf("state node is the target of a deprovisioning command")
+	}
 	// skip nodes that aren't initialized
 	if !node.Initialized() {
 		return nil, fmt.Errorf("state node isn't initialized")
 	}
+	if nodeclaimutil.Paused(node.NodeClaim) {
+		recorder.Publish(deprovisioningevents.Blocked(node.Node, node.NodeClaim, fmt.Sprintf("%s is paused", lo.Ternary(node.NodeClaim.IsMachine, "Machine", "NodeClaim")))...)
+		return nil, fmt.Errorf("%s is paused", lo.Ternary(node.NodeClaim.IsMachine, "machine", "nodeclaim"))
+	}
 	if _, ok := node.Annotations()[v1beta1.DoNotDisruptAnnotationKey]; ok {
 		recorder.Publish(deprovisioningevents.Blocked(node.Node, node.NodeClaim, fmt.Sprintf("Disruption is blocked with the %q annotation", v1beta1.DoNotDisruptAnnotationKey))...)
 		return nil, fmt.Errorf("disruption is blocked through the %q annotation", v1beta1.DoNotDisruptAnnotationKey)
@@ -96,6 +122,14 @@ func NewCandidate(ctx context.Context, kubeClient client.Client, recorder events
 		recorder.Publish(deprovisioningevents.Blocked(node.Node, node.NodeClaim, fmt.Sprintf("Owning %s %q not found", lo.Ternary(ownerKey.IsProvisioner, "provisioner", "nodepool"), ownerKey.Name))...)
 		return nil, fmt.Errorf("%s %q can't be resolved for state node", lo.Ternary(ownerKey.IsProvisioner, "provisioner", "nodepool"), ownerKey.Name)
 	}
+	if nodepoolutil.DisruptionPaused(nodePool) {
+		recorder.Publish(deprovisioningevents.Blocked(node.Node, node.NodeClaim, fmt.Sprintf("Disruption is paused on the owning %s with the %q annotation", lo.Ternary(ownerKey.IsProvisioner, "provisioner", "nodepool"), v1beta1.DisruptionPausedAnnotationKey))...)
+		return nil, fmt.Errorf("disruption is paused on the owning %s", lo.Ternary(ownerKey.IsProvisioner, "provisioner", "nodepool"))
+	}
+	if excludedByNodeSelector(ctx, nodePool, node.Labels()) {
+		recorder.Publish(deprovisioningevents.Blocked(node.Node, node.NodeClaim, "Disruption is blocked by an excluded node selector")...)
+		return nil, fmt.Errorf("state node matches an excluded node selector")
+	}
 	instanceType := instanceTypeMap[node.Labels()[v1.LabelInstanceTypeStable]]
 	// skip any nodes that we can't determine the instance of
 	if instanceType == nil {
@@ -121,6 +155,11 @@ func NewCandidate(ctx context.Context, kubeClient client.Client, recorder events
 		pods:         pods,
 	}
 	cn.disruptionCost = disruptionCost(ctx, pods) * cn.lifetimeRemaining(clk)
+	if expireAt, forceAt, ok := nodepoolutil.MaxNodeLifetimeDeadlines(nodePool, node.Node); ok && !clk.Now().Before(expireAt) {
+		cn.maxNodeLifetimeExceeded = true
+		cn.forceDeprovisioning = !clk.Now().Before(forceAt)
+		cn.forceDeprovisioningAt = forceAt
+	}
 	return cn, nil
 }
 
@@ -82,7 +82,7 @@ func (v *Validation) IsValid(ctx context.Context, cmd Command) (bool, error) {
 			return false, fmt.Errorf("constructing validation candidates, %w", err)
 		}
 	}
-	nodes, err := filterCandidates(ctx, v.kubeClient, v.recorder, cmd.candidates)
+	nodes, err := filterCandidates(ctx, v.kubeClient, v.recorder, cmd.candidates, false)
 	if err != nil {
 		return false, fmt.Errorf("filtering candidates, %w", err)
 	}
@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
+)
+
+// Vetoer is consulted before a deprovisioning command is executed, allowing an external system to veto it. A vetoed
+// command is skipped and its candidates are reconsidered on a later pass.
+type Vetoer interface {
+	// Veto returns true and a reason if the command shouldn't be executed
+	Veto(ctx context.Context, d Deprovisioner, cmd Command) (bool, string, error)
+}
+
+// VetoRequest is the body that's POSTed to the deprovisioning webhook
+type VetoRequest struct {
+	Method       string            `json:"method"`
+	Action       string            `json:"action"`
+	Candidates   []VetoCandidate   `json:"candidates"`
+	Replacements []VetoReplacement `json:"replacements,omitempty"`
+}
+
+type VetoCandidate struct {
+	Name         string `json:"name"`
+	NodePool     string `json:"nodePool"`
+	InstanceType string `json:"instanceType"`
+	CapacityType string `json:"capacityType"`
+}
+
+type VetoReplacement struct {
+	// InstanceTypes are the instance types that the replacement may be launched as
+	InstanceTypes []string `json:"instanceTypes"`
+}
+
+// VetoResponse is the body that the deprovisioning webhook is expected to respond with
+type VetoResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// WebhookVetoer asks the webhook configured by deprovisioningWebhookURL whether a command may be executed. Commands are
+// always allowed when no webhook is configured, and vetoed if the webhook can't be reached so that disruption isn't
+// silently performed without the webhook's approval.
+type WebhookVetoer struct {
+	client *http.Client
+}
+
+func NewWebhookVetoer() *WebhookVetoer {
+	return &WebhookVetoer{client: &http.Client{}}
+}
+
+func (w *WebhookVetoer) Veto(ctx context.Context, d Deprovisioner, cmd Command) (bool, string, error) {
+	s := settings.FromContext(ctx)
+	if s.DeprovisioningWebhookURL == "" {
+		return false, "", nil
+	}
+	body, err := json.Marshal(NewVetoRequest(d, cmd))
+	if err != nil {
+		return true, "", fmt.Errorf("marshaling veto request, %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.DeprovisioningWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.DeprovisioningWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return true, "", fmt.Errorf("creating veto request, %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, "", fmt.Errorf("calling deprovisioning webhook, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return true, "", fmt.Errorf("calling deprovisioning webhook, unexpected status code %d", resp.StatusCode)
+	}
+	veto := VetoResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&veto); err != nil {
+		return true, "", fmt.Errorf("decoding veto response, %w", err)
+	}
+	return !veto.Allowed, veto.Reason, nil
+}
+
+func NewVetoRequest(d Deprovisioner, cmd Command) VetoRequest {
+	return VetoRequest{
+		Method: d.String(),
+		Action: string(cmd.Action()),
+		Candidates: lo.Map(cmd.candidates, func(cn *Candidate, _ int) VetoCandidate {
+			return VetoCandidate{
+				Name:         cn.Name(),
+				NodePool:     cn.nodePool.Name,
+				InstanceType: cn.instanceType.Name,
+				CapacityType: cn.capacityType,
+			}
+		}),
+		Replacements: lo.Map(cmd.replacements, func(r *scheduling.NodeClaim, _ int) VetoReplacement {
+			return VetoReplacement{
+				InstanceTypes: lo.Map(r.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }),
+			}
+		}),
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/controllers/termination/terminator"
+	"github.com/aws/karpenter-core/pkg/events"
+	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
+	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+)
+
+const (
+	// maxBatchSize is the maximum number of messages that are handled in parallel in a single reconcile
+	maxBatchSize = 100
+	// replacementPollPeriod is how often replacements for machines with rebalance recommendations are checked for
+	// initialization
+	replacementPollPeriod = time.Second * 10
+)
+
+// Controller consumes interruption messages from the cloudprovider and proactively cordons and deletes the
+// interrupted machines. Termination drains the node and provisioning launches replacement capacity for its pods
+// before the cloudprovider reclaims the instance.
+type Controller struct {
+	clock      clock.Clock
+	kubeClient client.Client
+	source     cloudprovider.InterruptionSource
+	terminator *terminator.Terminator
+	recorder   events.Recorder
+
+	once     sync.Once
+	messages <-chan cloudprovider.InterruptionMessage
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, source cloudprovider.InterruptionSource,
+	terminator *terminator.Terminator, recorder events.Recorder) corecontroller.Controller {
+	return &Controller{
+		clock:      clk,
+		kubeClient: kubeClient,
+		source:     source,
+		terminator: terminator,
+		recorder:   recorder,
+	}
+}
+
+func (c *Controller) Name() string {
+	return "interruption"
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	c.once.Do(func() { c.messages = c.source.InterruptionMessages(ctx) })
+
+	pending, err := c.reconcileReplacements(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	// We only block on messages when there are no replacements that we need to poll for initialization
+	msgs, err := c.receive(ctx, pending == 0)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(msgs) == 0 {
+		return reconcile.Result{RequeueAfter: replacementPollPeriod}, nil
+	}
+
+	nodeClaimList, err := nodeclaimutil.List(ctx, c.kubeClient)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing machines, %w", err)
+	}
+	nodeClaims := lo.SliceToMap(lo.Filter(lo.ToSlicePtr(nodeClaimList.Items), func(n *v1beta1.NodeClaim, _ int) bool {
+		return n.Status.ProviderID != ""
+	}), func(n *v1beta1.NodeClaim) (string, *v1beta1.NodeClaim) {
+		return n.Status.ProviderID, n
+	})
+	errs := make([]error, len(msgs))
+	workqueue.ParallelizeUntil(ctx, 10, len(msgs), func(i int) {
+		errs[i] = c.handleMessage(ctx, msgs[i], nodeClaims[msgs[i].ProviderID])
+	})
+	return reconcile.Result{}, multierr.Combine(errs...)
+}
+
+// receive returns the messages that are waiting, up to maxBatchSize. When block is true, it waits until at least one
+// message is available.
+func (c *Controller) receive(ctx context.Context, block bool) ([]cloudprovider.InterruptionMessage, error) {
+	var msgs []cloudprovider.InterruptionMessage
+	if block {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				return nil, fmt.Errorf("interruption message source was closed")
+			}
+			msgs = append(msgs, msg)
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+	for done := false; !done && len(msgs) < maxBatchSize; {
+		select {
+		case msg, ok := <-c.messages:
+			if ok {
+				msgs = append(msgs, msg)
+			}
+			done = !ok
+		default:
+			done = true
+		}
+	}
+	return msgs, nil
+}
+
+func (c *Controller) handleMessage(ctx context.Context, msg cloudprovider.InterruptionMessage, nodeClaim *v1beta1.NodeClaim) error {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("message-id", msg.ID, "message-kind", msg.Kind, "provider-id", msg.ProviderID))
+	ReceivedMessagesCounter.WithLabelValues(string(msg.Kind)).Inc()
+	if !msg.StartTime.IsZero() {
+		MessageLatency.Observe(c.clock.Since(msg.StartTime).Seconds())
+	}
+	// Karpenter doesn't manage the interrupted instance, so there's nothing for us to do
+	if nodeClaim == nil {
+		ActionsPerformedCounter.WithLabelValues(NoAction).Inc()
+		logging.FromContext(ctx).Debugf("ignoring interruption message for unmanaged instance")
+		return nil
+	}
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), nodeClaim.Name))
+	node, err := nodeclaimutil.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if nodeclaimutil.IgnoreNodeNotFoundError(err) != nil {
+		return fmt.Errorf("getting node, %w", err)
+	}
+	c.recorder.Publish(InterruptionReceived(node, nodeClaim, msg)...)
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		ActionsPerformedCounter.WithLabelValues(NoAction).Inc()
+		return nil
+	}
+	if msg.Kind == cloudprovider.RebalanceRecommendation {
+		return c.replace(ctx, nodeClaim)
+	}
+	return c.drain(ctx, nodeClaim, node)
+}
+
+// replace launches a replacement for a machine that received a rebalance recommendation. The machine keeps running
+// until its replacement is initialized, trading the cost of the extra machine for the availability of its pods.
+func (c *Controller) replace(ctx context.Context, nodeClaim *v1beta1.NodeClaim) error {
+	// Rebalance recommendations are only acted on when enabled, and only once per machine
+	if !settings.FromContext(ctx).InterruptionRebalanceReplacementEnabled || nodeClaim.Annotations[v1alpha5.InterruptionReplacementAnnotationKey] != "" {
+		ActionsPerformedCounter.WithLabelValues(NoAction).Inc()
+		return nil
+	}
+	replacement, err := c.launchReplacement(ctx, nodeClaim)
+	if err != nil {
+		return fmt.Errorf("launching replacement, %w", err)
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1alpha5.InterruptionReplacementAnnotationKey: replacement.Name})
+	if err = nodeclaimutil.Patch(ctx, c.kubeClient, stored, nodeClaim); err != nil {
+		return fmt.Errorf("patching %s, %w", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), err)
+	}
+	logging.FromContext(ctx).With("replacement", replacement.Name).Infof("launched replacement for %s with a rebalance recommendation", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"))
+	ActionsPerformedCounter.WithLabelValues(ReplaceAction).Inc()
+	return nil
+}
+
+// launchReplacement creates a machine with the same owner and spec as the machine that's being replaced
+func (c *Controller) launchReplacement(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (*v1beta1.NodeClaim, error) {
+	owner, err := nodeclaimutil.Owner(ctx, c.kubeClient, nodeClaim)
+	if err != nil {
+		return nil, fmt.Errorf("getting owner, %w", err)
+	}
+	if err = owner.Spec.Limits.ExceededBy(owner.Status.Resources); err != nil {
+		return nil, err
+	}
+	replacement := &v1beta1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", owner.Name),
+			// Labels that were resolved when the machine was launched are left off so that the replacement is free to
+			// launch into different capacity
+			Labels: lo.Assign(owner.Spec.Template.Labels, lo.PickByKeys(nodeClaim.Labels, []string{v1alpha5.ProvisionerNameLabelKey, v1beta1.NodePoolLabelKey})),
+			Annotations: lo.PickByKeys(nodeClaim.Annotations, []string{
+				v1alpha5.ProvisionerHashAnnotationKey,
+				v1beta1.NodePoolHashAnnotationKey,
+				v1alpha5.ProviderCompatabilityAnnotationKey,
+			}),
+			OwnerReferences: nodeClaim.OwnerReferences,
+		},
+		Spec:      *nodeClaim.Spec.DeepCopy(),
+		IsMachine: nodeClaim.IsMachine,
+	}
+	var obj client.Object = replacement
+	if replacement.IsMachine {
+		obj = machineutil.NewFromNodeClaim(replacement)
+	}
+	if err = c.kubeClient.Create(ctx, obj); err != nil {
+		return nil, err
+	}
+	replacement.Name = obj.GetName()
+	nodeclaimutil.CreatedCounter(replacement, "interruption").Inc()
+	return replacement, nil
+}
+
+// reconcileReplacements drains the machines that received a rebalance recommendation once their replacements are
+// initialized, returning the number of replacements that are still initializing
+func (c *Controller) reconcileReplacements(ctx context.Context) (int, error) {
+	nodeClaimList, err := nodeclaimutil.List(ctx, c.kubeClient)
+	if err != nil {
+		return 0, fmt.Errorf("listing machines, %w", err)
+	}
+	nodeClaims := lo.Filter(lo.ToSlicePtr(nodeClaimList.Items), func(n *v1beta1.NodeClaim, _ int) bool {
+		return n.Annotations[v1alpha5.InterruptionReplacementAnnotationKey] != "" && n.DeletionTimestamp.IsZero()
+	})
+	pending := make([]bool, len(nodeClaims))
+	errs := make([]error, len(nodeClaims))
+	workqueue.ParallelizeUntil(ctx, 10, len(nodeClaims), func(i int) {
+		pending[i], errs[i] = c.reconcileReplacement(ctx, nodeClaims[i])
+	})
+	return lo.Count(pending, true), multierr.Combine(errs...)
+}
+
+func (c *Controller) reconcileReplacement(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (bool, error) {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), nodeClaim.Name))
+	replacement, err := nodeclaimutil.Get(ctx, c.kubeClient, nodeclaimutil.Key{Name: nodeClaim.Annotations[v1alpha5.InterruptionReplacementAnnotationKey], IsMachine: nodeClaim.IsMachine})
+	// If the replacement failed to launch and was removed, we drain the machine anyways rather than leave it at risk
+	// of being interrupted. Its pods will be rescheduled by provisioning.
+	if client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("getting replacement, %w", err)
+	}
+	if err == nil && !replacement.StatusConditions().GetCondition(v1beta1.NodeInitialized).IsTrue() {
+		return true, nil
+	}
+	node, err := nodeclaimutil.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if nodeclaimutil.IgnoreNodeNotFoundError(err) != nil {
+		return false, fmt.Errorf("getting node, %w", err)
+	}
+	return false, c.drain(ctx, nodeClaim, node)
+}
+
+// drain cordons the node and deletes the machine so that termination drains it and provisioning replaces its pods
+func (c *Controller) drain(ctx context.Context, nodeClaim *v1beta1.NodeClaim, node *v1.Node) error {
+	if node != nil {
+		if err := c.cordon(ctx, node); err != nil {
+			return fmt.Errorf("cordoning node, %w", err)
+		}
+	}
+	if err := nodeclaimutil.Delete(ctx, c.kubeClient, nodeClaim); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("deleting %s, %w", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), err)
+	}
+	logging.FromContext(ctx).Infof("initiating delete for interrupted %s", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"))
+	ActionsPerformedCounter.WithLabelValues(CordonAndDrainAction).Inc()
+	nodeclaimutil.TerminatedCounter(nodeClaim, "interrupted").Inc()
+	return nil
+}
+
+// cordon marks the node as unschedulable as soon as the interruption is received so that no new pods land on it
+// while the machine is waiting on termination to drain it
+func (c *Controller) cordon(ctx context.Context, node *v1.Node) error {
+	if !node.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	return client.IgnoreNotFound(c.terminator.Cordon(ctx, node))
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.NewSingletonManagedBy(m)
+}
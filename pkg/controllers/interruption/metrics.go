@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruption
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+const (
+	interruptionSubsystem = "interruption"
+	messageTypeLabel      = "message_type"
+	actionTypeLabel       = "action_type"
+
+	// CordonAndDrainAction is the action taken when the interrupted machine is cordoned and deleted so that it's
+	// drained by termination and its pods are rescheduled onto replacement capacity
+	CordonAndDrainAction = "CordonAndDrain"
+	// ReplaceAction is the action taken when a machine receives a rebalance recommendation, a replacement is launched
+	// and the machine is drained once the replacement is initialized
+	ReplaceAction = "Replace"
+	// NoAction is the action taken when the interrupted machine isn't managed by Karpenter or is already deleting
+	NoAction = "NoAction"
+)
+
+var (
+	ReceivedMessagesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "received_messages",
+			Help:      "Count of interruption messages received from the cloudprovider. Labeled by message type.",
+		},
+		[]string{messageTypeLabel},
+	)
+	ActionsPerformedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "actions_performed",
+			Help:      "Count of actions performed in response to interruption messages. Labeled by action type.",
+		},
+		[]string{actionTypeLabel},
+	)
+	MessageLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "message_latency_time_seconds",
+			Help:      "Length of time between the cloudprovider observing an interruption and Karpenter handling it.",
+			Buckets:   metrics.DurationBuckets(),
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(ReceivedMessagesCounter, ActionsPerformedCounter, MessageLatency)
+}
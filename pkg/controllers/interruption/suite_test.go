@@ -0,0 +1,259 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruption_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/interruption"
+	"github.com/aws/karpenter-core/pkg/controllers/termination/terminator"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/operator/controller"
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var ctx context.Context
+var interruptionController controller.Controller
+var env *test.Environment
+var fakeClock *clock.FakeClock
+var cloudProvider *fake.CloudProvider
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Interruption")
+}
+
+var _ = BeforeSuite(func() {
+	ctx = settings.ToContext(ctx, test.Settings())
+	fakeClock = clock.NewFakeClock(time.Now())
+	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...), test.WithFieldIndexers(func(c cache.Cache) error {
+		return c.IndexField(ctx, &v1.Node{}, "spec.providerID", func(obj client.Object) []string {
+			return []string{obj.(*v1.Node).Spec.ProviderID}
+		})
+	}))
+
+	cloudProvider = fake.NewCloudProvider()
+	recorder := events.NewRecorder(&record.FakeRecorder{})
+	evictionQueue := terminator.NewEvictionQueue(ctx, fakeClock, env.KubernetesInterface.CoreV1(), recorder)
+	interruptionController = interruption.NewController(fakeClock, env.Client, cloudProvider, terminator.NewTerminator(fakeClock, env.Client, evictionQueue, recorder), recorder)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	fakeClock.SetTime(time.Now())
+	ExpectCleanedUp(ctx, env.Client)
+	cloudProvider.Reset()
+
+	interruption.ReceivedMessagesCounter.Reset()
+	interruption.ActionsPerformedCounter.Reset()
+})
+
+var _ = Describe("Interruption", func() {
+	var provisioner *v1alpha5.Provisioner
+	var machine *v1alpha5.Machine
+	var node *v1.Node
+
+	BeforeEach(func() {
+		provisioner = test.Provisioner()
+		machine, node = test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+				Finalizers: []string{v1alpha5.TerminationFinalizer},
+			},
+		})
+	})
+	It("should cordon the node and delete the machine when an interruption message is received", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.Interruptions <- cloudprovider.InterruptionMessage{
+			ID:         "test-message",
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.SpotInterruption,
+			StartTime:  fakeClock.Now(),
+		}
+		ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeTrue())
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+
+		m, ok := FindMetricWithLabelValues("karpenter_interruption_received_messages", map[string]string{"message_type": string(cloudprovider.SpotInterruption)})
+		Expect(ok).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+		m, ok = FindMetricWithLabelValues("karpenter_interruption_actions_performed", map[string]string{"action_type": interruption.CordonAndDrainAction})
+		Expect(ok).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+	})
+	It("should delete the machine when its node hasn't registered", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		cloudProvider.Interruptions <- cloudprovider.InterruptionMessage{
+			ID:         "test-message",
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.ScheduledMaintenance,
+		}
+		ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+	})
+	It("should ignore interruption messages for instances that aren't managed by Karpenter", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.Interruptions <- cloudprovider.InterruptionMessage{
+			ID:         "test-message",
+			ProviderID: test.RandomProviderID(),
+			Kind:       cloudprovider.SpotInterruption,
+		}
+		ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeTrue())
+
+		m, ok := FindMetricWithLabelValues("karpenter_interruption_actions_performed", map[string]string{"action_type": interruption.NoAction})
+		Expect(ok).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+	})
+	Context("Rebalance Recommendations", func() {
+		var msg cloudprovider.InterruptionMessage
+
+		BeforeEach(func() {
+			msg = cloudprovider.InterruptionMessage{
+				ID:         "test-message",
+				ProviderID: machine.Status.ProviderID,
+				Kind:       cloudprovider.RebalanceRecommendation,
+			}
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{InterruptionRebalanceReplacementEnabled: true}))
+			DeferCleanup(func() {
+				ctx = settings.ToContext(ctx, test.Settings())
+			})
+		})
+		It("should ignore rebalance recommendations when replacement is disabled", func() {
+			ctx = settings.ToContext(ctx, test.Settings())
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			cloudProvider.Interruptions <- msg
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.DeletionTimestamp.IsZero()).To(BeTrue())
+			Expect(machine.Annotations).ToNot(HaveKey(v1alpha5.InterruptionReplacementAnnotationKey))
+			Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+		})
+		It("should launch a replacement and only drain the machine once the replacement is initialized", func() {
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			cloudProvider.Interruptions <- msg
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+			machines := ExpectMachines(ctx, env.Client)
+			Expect(machines).To(HaveLen(2))
+			replacement, ok := lo.Find(machines, func(m *v1alpha5.Machine) bool { return m.Name != machine.Name })
+			Expect(ok).To(BeTrue())
+			Expect(replacement.Labels).To(HaveKeyWithValue(v1alpha5.ProvisionerNameLabelKey, provisioner.Name))
+			Expect(replacement.Spec.Requirements).To(Equal(machine.Spec.Requirements))
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.Annotations).To(HaveKeyWithValue(v1alpha5.InterruptionReplacementAnnotationKey, replacement.Name))
+
+			// The machine is left running while its replacement initializes
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.DeletionTimestamp.IsZero()).To(BeTrue())
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Unschedulable).To(BeFalse())
+
+			ExpectMakeMachinesInitialized(ctx, env.Client, replacement)
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Unschedulable).To(BeTrue())
+		})
+		It("should only launch a single replacement for repeated rebalance recommendations", func() {
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			cloudProvider.Interruptions <- msg
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+			cloudProvider.Interruptions <- msg
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+			Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(2))
+		})
+		It("should drain the machine if its replacement is removed before initializing", func() {
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			cloudProvider.Interruptions <- msg
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+			replacement, ok := lo.Find(ExpectMachines(ctx, env.Client), func(m *v1alpha5.Machine) bool { return m.Name != machine.Name })
+			Expect(ok).To(BeTrue())
+			ExpectDeleted(ctx, env.Client, replacement)
+
+			ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+		})
+	})
+	It("should handle a batch of interruption messages", func() {
+		var machines []*v1alpha5.Machine
+		for i := 0; i < 10; i++ {
+			m, n := test.MachineAndNode(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+					},
+					Finalizers: []string{v1alpha5.TerminationFinalizer},
+				},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, m, n)
+			machines = append(machines, m)
+			cloudProvider.Interruptions <- cloudprovider.InterruptionMessage{
+				ID:         fmt.Sprintf("test-message-%d", i),
+				ProviderID: m.Status.ProviderID,
+				Kind:       cloudprovider.InstanceTerminating,
+			}
+		}
+		ExpectReconcileSucceeded(ctx, interruptionController, client.ObjectKey{})
+
+		for _, m := range machines {
+			m = ExpectExists(ctx, env.Client, m)
+			Expect(m.DeletionTimestamp.IsZero()).To(BeFalse())
+		}
+		Expect(lo.Filter(ExpectNodes(ctx, env.Client), func(n *v1.Node, _ int) bool { return n.Spec.Unschedulable })).To(HaveLen(10))
+	})
+})
@@ -22,8 +22,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
-	"github.com/aws/karpenter-core/pkg/controllers/deprovisioning"
 	nodeutils "github.com/aws/karpenter-core/pkg/utils/node"
+	"github.com/aws/karpenter-core/pkg/utils/pdb"
 )
 
 // Termination detects nodes that are stuck terminating and reports why.
@@ -42,7 +42,7 @@ func (t *Termination) Check(ctx context.Context, node *v1.Node, nodeClaim *v1bet
 	if nodeClaim.DeletionTimestamp.IsZero() {
 		return nil, nil
 	}
-	pdbs, err := deprovisioning.NewPDBLimits(ctx, t.kubeClient)
+	pdbs, err := pdb.NewLimits(ctx, t.kubeClient)
 	if err != nil {
 		return nil, err
 	}
@@ -51,8 +51,8 @@ func (t *Termination) Check(ctx context.Context, node *v1.Node, nodeClaim *v1bet
 		return nil, err
 	}
 	var issues []Issue
-	if pdb, ok := pdbs.CanEvictPods(pods); !ok {
-		issues = append(issues, Issue(fmt.Sprintf("can't drain node, PDB %s is blocking evictions", pdb)))
+	if pdbKey, ok := pdbs.CanEvictPods(pods); !ok {
+		issues = append(issues, Issue(fmt.Sprintf("can't drain node, PDB %s is blocking evictions", pdbKey)))
 	}
 	return issues, nil
 }
@@ -16,11 +16,16 @@ package disruption
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
 	"k8s.io/utils/clock"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -38,7 +43,10 @@ import (
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
 	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
+	nodeutil "github.com/aws/karpenter-core/pkg/utils/node"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
 	"github.com/aws/karpenter-core/pkg/utils/result"
 )
 
@@ -50,19 +58,29 @@ type nodeClaimReconciler interface {
 // e.g. When the NodeClaim has surpassed its owning provisioner's expirationTTL, then it is marked as "Expired" in the StatusConditions
 type Controller struct {
 	kubeClient client.Client
+	cluster    *state.Cluster
 
 	drift      *Drift
 	expiration *Expiration
 	emptiness  *Emptiness
+	health     *Health
 }
 
 // NewController constructs a machine disruption controller
-func NewController(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider) *Controller {
+func NewController(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider,
+	serverVersion discovery.ServerVersionInterface) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
-		drift:      &Drift{cloudProvider: cloudProvider},
+		cluster:    cluster,
+		drift: &Drift{
+			kubeClient:    kubeClient,
+			cloudProvider: cloudProvider,
+			serverVersion: serverVersion,
+			cache:         cache.New(serverVersionTTL, time.Minute),
+		},
 		expiration: &Expiration{kubeClient: kubeClient, clock: clk},
 		emptiness:  &Emptiness{kubeClient: kubeClient, cluster: cluster, clock: clk},
+		health:     &Health{kubeClient: kubeClient, clock: clk},
 	}
 }
 
@@ -71,19 +89,33 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim
 	if !nodeClaim.DeletionTimestamp.IsZero() {
 		return reconcile.Result{}, nil
 	}
+	// Paused NodeClaims keep their current disruption conditions until they're unpaused
+	if nodeclaimutil.Paused(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
 
 	stored := nodeClaim.DeepCopy()
 	nodePool, err := nodeclaimutil.Owner(ctx, c.kubeClient, nodeClaim)
 	if err != nil {
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
+	// Voluntary disruption is suspended for NodeClaims owned by a paused NodePool
+	if nodepoolutil.DisruptionPaused(nodePool) {
+		return reconcile.Result{}, nil
+	}
+	// Voluntary disruption is also blocked while the Node is running a pod with the do-not-disrupt annotation, but
+	// unhealthy Nodes are still detected so that they can be repaired
+	blocked, err := c.hasDoNotDisruptPod(ctx, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
 	var results []reconcile.Result
 	var errs error
-	reconcilers := []nodeClaimReconciler{
-		c.expiration,
-		c.drift,
-		c.emptiness,
+	var reconcilers []nodeClaimReconciler
+	if !blocked {
+		reconcilers = append(reconcilers, c.expiration, c.drift, c.emptiness)
 	}
+	reconcilers = append(reconcilers, c.health)
 	for _, reconciler := range reconcilers {
 		res, err := reconciler.Reconcile(ctx, nodePool, nodeClaim)
 		errs = multierr.Append(errs, err)
@@ -100,15 +132,33 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim
 	return result.Min(results...), errs
 }
 
+func (c *Controller) hasDoNotDisruptPod(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (bool, error) {
+	n, err := nodeclaimutil.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if err != nil {
+		return false, nodeclaimutil.IgnoreNodeNotFoundError(nodeclaimutil.IgnoreDuplicateNodeError(err))
+	}
+	pods, err := nodeutil.GetNodePods(ctx, c.kubeClient, n)
+	if err != nil {
+		return false, fmt.Errorf("retrieving node pods, %w", err)
+	}
+	return lo.ContainsBy(pods, func(p *v1.Pod) bool {
+		if podutil.IsTerminating(p) || podutil.IsTerminal(p) || podutil.IsOwnedByNode(p) {
+			return false
+		}
+		return podutil.HasDoNotDisrupt(p)
+	}), nil
+}
+
 var _ corecontroller.TypedController[*v1beta1.NodeClaim] = (*NodeClaimController)(nil)
 
 type NodeClaimController struct {
 	*Controller
 }
 
-func NewNodeClaimController(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider) corecontroller.Controller {
+func NewNodeClaimController(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider,
+	serverVersion discovery.ServerVersionInterface) corecontroller.Controller {
 	return corecontroller.Typed[*v1beta1.NodeClaim](kubeClient, &NodeClaimController{
-		Controller: NewController(clk, kubeClient, cluster, cloudProvider),
+		Controller: NewController(clk, kubeClient, cluster, cloudProvider, serverVersion),
 	})
 }
 
@@ -158,6 +208,10 @@ func (c *NodeClaimController) Builder(ctx context.Context, m manager.Manager) co
 		Watches(
 			&source.Kind{Type: &v1.Pod{}},
 			nodeclaimutil.PodEventHandler(ctx, c.kubeClient),
+		).
+		Watches(
+			&source.Channel{Source: c.cluster.WatchEmptyNodes()},
+			nodeclaimutil.NodeEventHandler(ctx, c.kubeClient),
 		),
 	)
 }
@@ -168,9 +222,10 @@ type MachineController struct {
 	*Controller
 }
 
-func NewMachineController(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider) corecontroller.Controller {
+func NewMachineController(clk clock.Clock, kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider,
+	serverVersion discovery.ServerVersionInterface) corecontroller.Controller {
 	return corecontroller.Typed[*v1alpha5.Machine](kubeClient, &MachineController{
-		Controller: NewController(clk, kubeClient, cluster, cloudProvider),
+		Controller: NewController(clk, kubeClient, cluster, cloudProvider, serverVersion),
 	})
 }
 
@@ -220,6 +275,10 @@ func (c *Controller) Builder(ctx context.Context, m manager.Manager) corecontrol
 		Watches(
 			&source.Kind{Type: &v1.Pod{}},
 			machineutil.PodEventHandler(ctx, c.kubeClient),
+		).
+		Watches(
+			&source.Channel{Source: c.cluster.WatchEmptyNodes()},
+			machineutil.NodeEventHandler(ctx, c.kubeClient),
 		),
 	)
 }
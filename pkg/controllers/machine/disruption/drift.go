@@ -20,10 +20,14 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 
 	"github.com/aws/karpenter-core/pkg/apis/settings"
@@ -34,16 +38,24 @@ import (
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 )
 
 const (
-	ProvisionerDrifted  cloudprovider.DriftReason = "ProvisionerDrifted"
-	RequirementsDrifted cloudprovider.DriftReason = "RequirementsDrifted"
+	ProvisionerDrifted    cloudprovider.DriftReason = "ProvisionerDrifted"
+	RequirementsDrifted   cloudprovider.DriftReason = "RequirementsDrifted"
+	KubeletVersionDrifted cloudprovider.DriftReason = "KubeletVersionDrifted"
 )
 
+// serverVersionTTL is how long the API server version is cached before it's discovered again
+const serverVersionTTL = 5 * time.Minute
+
 // Drift is a machine sub-controller that adds or removes status conditions on drifted machines
 type Drift struct {
+	kubeClient    client.Client
 	cloudProvider cloudprovider.CloudProvider
+	serverVersion discovery.ServerVersionInterface
+	cache         *cache.Cache
 }
 
 func (d *Drift) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) (reconcile.Result, error) {
@@ -51,7 +63,7 @@ func (d *Drift) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, nodeC
 
 	// From here there are three scenarios to handle:
 	// 1. If drift is not enabled but the NodeClaim is drifted, remove the status condition
-	if !settings.FromContext(ctx).DriftEnabled {
+	if !nodepoolutil.DriftEnabled(ctx, nodePool) {
 		_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeDrifted)
 		if hasDriftedCondition {
 			logging.FromContext(ctx).Debugf("removing drift status condition, drift has been disabled")
@@ -84,9 +96,10 @@ func (d *Drift) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, nodeC
 		Status:   v1.ConditionTrue,
 		Severity: apis.ConditionSeverityWarning,
 		Reason:   string(driftedReason),
+		Message:  driftMessage(driftedReason),
 	})
 	if !hasDriftedCondition {
-		logging.FromContext(ctx).Debugf("marking drifted")
+		logging.FromContext(ctx).With("reason", driftedReason).Debugf("marking drifted")
 		nodeclaimutil.DisruptedCounter(nodeClaim, metrics.DriftReason).Inc()
 		nodeclaimutil.DriftedCounter(nodeClaim, string(driftedReason)).Inc()
 	}
@@ -94,6 +107,24 @@ func (d *Drift) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, nodeC
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
 
+// driftMessage returns a human-readable explanation for the drift reason that is surfaced on the status condition
+func driftMessage(reason cloudprovider.DriftReason) string {
+	switch reason {
+	case ProvisionerDrifted:
+		return "Static fields on the owning provisioner have changed since launch"
+	case RequirementsDrifted:
+		return "Node labels no longer satisfy the owning provisioner's requirements"
+	case KubeletVersionDrifted:
+		return "Kubelet version trails the API server by more than the allowed number of minor versions"
+	case cloudprovider.NodeTemplateDrifted:
+		return "Node template referenced by the owning provisioner has changed since launch"
+	case cloudprovider.ProviderDrifted:
+		return "Cloudprovider configuration has changed since launch"
+	default:
+		return fmt.Sprintf("Cloudprovider reported drift, %s", reason)
+	}
+}
+
 // isDrifted will check if a NodeClaim is drifted from the fields in the NodePool Spec and the CloudProvider
 func (d *Drift) isDrifted(ctx context.Context, nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) (cloudprovider.DriftReason, error) {
 	// First check for static drift or node requirements have drifted to save on API calls.
@@ -102,6 +133,9 @@ func (d *Drift) isDrifted(ctx context.Context, nodePool *v1beta1.NodePool, nodeC
 	}); reason != "" {
 		return reason, nil
 	}
+	if reason, err := d.isKubeletVersionDrifted(ctx, nodeClaim); err != nil || reason != "" {
+		return reason, err
+	}
 	driftedReason, err := d.cloudProvider.IsMachineDrifted(ctx, machineutil.NewFromNodeClaim(nodeClaim))
 	if err != nil {
 		return "", err
@@ -111,6 +145,8 @@ func (d *Drift) isDrifted(ctx context.Context, nodePool *v1beta1.NodePool, nodeC
 
 // Eligible fields for static drift are described in the docs
 // https://karpenter.sh/docs/concepts/deprovisioning/#drift
+// The NodePool hash is computed from the in-memory object rather than read from its annotation so that static drift
+// doesn't depend on the hash controller having reconciled the latest NodePool spec.
 func areStaticFieldsDrifted(nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) cloudprovider.DriftReason {
 	var ownerHashKey string
 	if nodeClaim.IsMachine {
@@ -118,17 +154,58 @@ func areStaticFieldsDrifted(nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeC
 	} else {
 		ownerHashKey = v1beta1.NodePoolHashAnnotationKey
 	}
-	nodePoolHash, foundHashNodePool := nodePool.Annotations[ownerHashKey]
 	nodeClaimHash, foundHashNodeClaim := nodeClaim.Annotations[ownerHashKey]
-	if !foundHashNodePool || !foundHashNodeClaim {
+	if !foundHashNodeClaim {
 		return ""
 	}
-	if nodePoolHash != nodeClaimHash {
+	if nodepoolutil.HashAnnotation(nodePool)[ownerHashKey] != nodeClaimHash {
 		return ProvisionerDrifted
 	}
 	return ""
 }
 
+// isKubeletVersionDrifted checks whether the kubelet on the NodeClaim's node trails the API server by more minor
+// versions than the configured skew limit
+func (d *Drift) isKubeletVersionDrifted(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (cloudprovider.DriftReason, error) {
+	limit := settings.FromContext(ctx).KubeletVersionSkewLimit
+	if limit <= 0 {
+		return "", nil
+	}
+	node, err := nodeclaimutil.NodeForNodeClaim(ctx, d.kubeClient, nodeClaim)
+	if err != nil {
+		return "", nodeclaimutil.IgnoreNodeNotFoundError(nodeclaimutil.IgnoreDuplicateNodeError(err))
+	}
+	kubeletVersion, err := version.ParseGeneric(node.Status.NodeInfo.KubeletVersion)
+	if err != nil {
+		// The kubelet hasn't reported its version yet
+		return "", nil
+	}
+	serverVersion, err := d.getServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("getting server version, %w", err)
+	}
+	if serverVersion.Major() != kubeletVersion.Major() || int(serverVersion.Minor())-int(kubeletVersion.Minor()) > limit {
+		return KubeletVersionDrifted, nil
+	}
+	return "", nil
+}
+
+func (d *Drift) getServerVersion() (*version.Version, error) {
+	if v, ok := d.cache.Get("server-version"); ok {
+		return v.(*version.Version), nil
+	}
+	info, err := d.serverVersion.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return nil, fmt.Errorf("parsing server version, %w", err)
+	}
+	d.cache.SetDefault("server-version", v)
+	return v, nil
+}
+
 func areRequirementsDrifted(nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) cloudprovider.DriftReason {
 	provisionerReq := scheduling.NewNodeSelectorRequirements(nodePool.Spec.Template.Spec.Requirements...)
 	nodeClaimReq := scheduling.NewLabelRequirements(nodeClaim.Labels)
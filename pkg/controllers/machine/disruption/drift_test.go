@@ -29,6 +29,7 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/machine/disruption"
 	"github.com/aws/karpenter-core/pkg/test"
 
@@ -63,10 +64,21 @@ var _ = Describe("Drift", func() {
 
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Message).To(Equal("Cloudprovider reported drift, drifted"))
+	})
+	It("should propagate the cloud provider drift reason and message to the status condition", func() {
+		cp.Drifted = cloudprovider.NodeTemplateDrifted
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Reason).To(Equal(string(cloudprovider.NodeTemplateDrifted)))
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Message).To(Equal("Node template referenced by the owning provisioner has changed since launch"))
 	})
 	It("should detect static drift before cloud provider drift", func() {
 		cp.Drifted = "drifted"
-		provisioner.Annotations = lo.Assign(provisioner.Annotations, map[string]string{
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{
 			v1alpha5.ProvisionerHashAnnotationKey: "123456789",
 		})
 		ExpectApplied(ctx, env.Client, provisioner, machine)
@@ -75,6 +87,7 @@ var _ = Describe("Drift", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Reason).To(Equal(string(disruption.ProvisionerDrifted)))
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Message).To(Equal("Static fields on the owning provisioner have changed since launch"))
 	})
 	It("should detect node requirement drift before cloud provider drift", func() {
 		cp.Drifted = "drifted"
@@ -90,6 +103,7 @@ var _ = Describe("Drift", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Reason).To(Equal(string(disruption.RequirementsDrifted)))
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Message).To(Equal("Node labels no longer satisfy the owning provisioner's requirements"))
 	})
 	It("should not detect drift if the feature flag is disabled", func() {
 		cp.Drifted = "drifted"
@@ -100,6 +114,25 @@ var _ = Describe("Drift", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
 	})
+	It("should detect drift if the feature flag is disabled but the provisioner enables drift", func() {
+		cp.Drifted = "drifted"
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: false}))
+		provisioner.Spec.Drift = &v1alpha5.Drift{Enabled: lo.ToPtr(true)}
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
+	})
+	It("should not detect drift if the feature flag is enabled but the provisioner disables drift", func() {
+		cp.Drifted = "drifted"
+		provisioner.Spec.Drift = &v1alpha5.Drift{Enabled: lo.ToPtr(false)}
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
+	})
 	It("should remove the status condition from the machine if the feature flag is disabled", func() {
 		cp.Drifted = "drifted"
 		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: false}))
@@ -155,6 +188,45 @@ var _ = Describe("Drift", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
 	})
+	Context("Kubelet Version Drift", func() {
+		BeforeEach(func() {
+			cp.Drifted = ""
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true, KubeletVersionSkewLimit: 2}))
+		})
+		It("should detect drift when the kubelet version skew exceeds the limit", func() {
+			node.Status.NodeInfo.KubeletVersion = "v1.24.10"
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Reason).To(Equal(string(disruption.KubeletVersionDrifted)))
+		})
+		It("should not detect drift when the kubelet version skew is within the limit", func() {
+			node.Status.NodeInfo.KubeletVersion = "v1.25.12-eks-2d98532"
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
+		})
+		It("should not detect drift when kubelet version drift is disabled", func() {
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DriftEnabled: true}))
+			node.Status.NodeInfo.KubeletVersion = "v1.20.0"
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
+		})
+		It("should not detect drift when the kubelet version hasn't been reported", func() {
+			ExpectApplied(ctx, env.Client, provisioner, machine, node)
+			ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
+		})
+	})
 	Context("NodeRequirement Drift", func() {
 		DescribeTable("",
 			func(oldProvisionerReq []v1.NodeSelectorRequirement, newProvisionerReq []v1.NodeSelectorRequirement, machineLabels map[string]string, drifted bool) {
@@ -426,6 +498,18 @@ var _ = Describe("Drift", func() {
 			machine = ExpectExists(ctx, env.Client, machine)
 			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted)).To(BeNil())
 		})
+		It("should detect static drift before the provisioner-hash annotation is updated on the provisioner", func() {
+			ExpectApplied(ctx, env.Client, provisioner, machine)
+			ExpectReconcileSucceeded(ctx, provisionerController, client.ObjectKeyFromObject(provisioner))
+
+			// Change a static field without reconciling the hash controller
+			updatedProvisioner := test.Provisioner(testProvisionerOptions, test.ProvisionerOptions{ObjectMeta: provisioner.ObjectMeta, Labels: map[string]string{"keyLabelTest": "valueLabelTest"}})
+			ExpectApplied(ctx, env.Client, updatedProvisioner)
+			ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+			machine = ExpectExists(ctx, env.Client, machine)
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).IsTrue()).To(BeTrue())
+			Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineDrifted).Reason).To(Equal(string(disruption.ProvisionerDrifted)))
+		})
 		It("should not return drifted if karpenter.sh/provisioner-hash annotation is not present on the machine", func() {
 			machine.ObjectMeta.Annotations = map[string]string{}
 			ExpectApplied(ctx, env.Client, provisioner, machine)
@@ -34,6 +34,7 @@ import (
 )
 
 // Emptiness is a machine sub-controller that adds or removes status conditions on empty machines based on TTLSecondsAfterEmpty
+// and the ttl-after-empty annotation
 type Emptiness struct {
 	kubeClient client.Client
 	cluster    *state.Cluster
@@ -45,8 +46,8 @@ func (e *Emptiness) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, n
 	hasEmptyCondition := nodeClaim.StatusConditions().GetCondition(v1beta1.NodeEmpty) != nil
 
 	// From here there are a few scenarios to handle:
-	// 1. If ConsolidationPolicyWhenEmpty is not configured, remove the emptiness status condition
-	if nodePool.Spec.Deprovisioning.ConsolidationPolicy != v1beta1.ConsolidationPolicyWhenEmpty {
+	// 1. If ConsolidationPolicyWhenEmpty is not configured and not overridden, remove the emptiness status condition
+	if !e.enabled(ctx, nodePool, nodeClaim) {
 		if hasEmptyCondition {
 			_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeEmpty)
 			logging.FromContext(ctx).Debugf("removing emptiness status condition, emptiness is disabled")
@@ -109,3 +110,14 @@ func (e *Emptiness) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, n
 	}
 	return reconcile.Result{}, nil
 }
+
+// enabled returns whether emptiness applies to the NodeClaim. A ttl-after-empty annotation on the NodeClaim or its
+// Node takes precedence over the NodePool's ConsolidationPolicy.
+func (e *Emptiness) enabled(ctx context.Context, nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) bool {
+	// If the Node mapping doesn't resolve, only the NodeClaim can override the TTL
+	n, _ := nodeclaimutil.NodeForNodeClaim(ctx, e.kubeClient, nodeClaim)
+	if ttl, ok := nodeclaimutil.EmptinessTTLOverride(ctx, n, nodeClaim); ok {
+		return ttl >= 0
+	}
+	return nodePool.Spec.Deprovisioning.ConsolidationPolicy == v1beta1.ConsolidationPolicyWhenEmpty
+}
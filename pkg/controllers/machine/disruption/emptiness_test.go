@@ -60,6 +60,56 @@ var _ = Describe("Emptiness", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineEmpty).IsTrue()).To(BeTrue())
 	})
+	It("should mark machines as empty when the only pods on the node are terminal", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		ExpectApplied(ctx, env.Client,
+			test.Pod(test.PodOptions{NodeName: node.Name, Phase: v1.PodSucceeded}),
+			test.Pod(test.PodOptions{NodeName: node.Name, Phase: v1.PodFailed}),
+		)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineEmpty).IsTrue()).To(BeTrue())
+	})
+	It("should mark machines as empty when the only pods on the node have completed containers", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		pod := test.Pod(test.PodOptions{NodeName: node.Name, Phase: v1.PodRunning})
+		pod.Spec.RestartPolicy = v1.RestartPolicyNever
+		pod.Status.ContainerStatuses = lo.Map(pod.Spec.Containers, func(c v1.Container, _ int) v1.ContainerStatus {
+			return v1.ContainerStatus{Name: c.Name, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}}
+		})
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineEmpty).IsTrue()).To(BeTrue())
+	})
+	It("should mark machines as empty using the ttl-after-empty annotation when emptiness is disabled on the provisioner", func() {
+		provisioner.Spec.TTLSecondsAfterEmpty = nil
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.TTLAfterEmptyAnnotationKey: "5m"})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineEmpty).IsTrue()).To(BeTrue())
+	})
+	It("should remove the status condition when the ttl-after-empty annotation on the node disables emptiness", func() {
+		machine.StatusConditions().MarkTrue(v1alpha5.MachineEmpty)
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1alpha5.TTLAfterEmptyAnnotationKey: "Never"})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineEmpty)).To(BeNil())
+	})
 	It("should remove the status condition from the machine when emptiness is disabled", func() {
 		provisioner.Spec.TTLSecondsAfterEmpty = nil
 		machine.StatusConditions().MarkTrue(v1alpha5.MachineEmpty)
@@ -16,9 +16,12 @@ package disruption
 
 import (
 	"context"
+	"hash/fnv"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
@@ -30,7 +33,8 @@ import (
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
 )
 
-// Expiration is a machine sub-controller that adds or removes status conditions on expired machines based on TTLSecondsUntilExpired
+// Expiration is a machine sub-controller that adds or removes status conditions on expired machines based on TTLSecondsUntilExpired,
+// ExpirationJitter and the ttl-until-expired annotation
 type Expiration struct {
 	kubeClient client.Client
 	clock      clock.Clock
@@ -40,29 +44,41 @@ type Expiration struct {
 func (e *Expiration) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) (reconcile.Result, error) {
 	hasExpiredCondition := nodeClaim.StatusConditions().GetCondition(v1beta1.NodeExpired) != nil
 
+	node, err := nodeclaimutil.NodeForNodeClaim(ctx, e.kubeClient, nodeClaim)
+	if nodeclaimutil.IgnoreNodeNotFoundError(nodeclaimutil.IgnoreDuplicateNodeError(err)) != nil {
+		return reconcile.Result{}, err
+	}
+	// A ttl-until-expired annotation on the NodeClaim or the Node overrides the NodePool ExpirationTTL
+	ttl, overridden := nodeclaimutil.ExpirationTTLOverride(ctx, node, nodeClaim)
+	if !overridden {
+		ttl = nodePool.Spec.Deprovisioning.ExpirationTTL.Duration
+	}
+
 	// From here there are three scenarios to handle:
 	// 1. If ExpirationTTL is not configured, remove the expired status condition
-	if nodePool.Spec.Deprovisioning.ExpirationTTL.Duration < 0 {
+	if ttl < 0 {
 		_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeExpired)
+		nodeClaim.Status.ExpirationTime = nil
 		if hasExpiredCondition {
 			logging.FromContext(ctx).Debugf("removing expiration status condition, expiration has been disabled")
 		}
 		return reconcile.Result{}, nil
 	}
-	node, err := nodeclaimutil.NodeForNodeClaim(ctx, e.kubeClient, nodeClaim)
-	if nodeclaimutil.IgnoreNodeNotFoundError(nodeclaimutil.IgnoreDuplicateNodeError(err)) != nil {
-		return reconcile.Result{}, err
-	}
 	// We do the expiration check in this way since there is still a migration path for creating Machines from Nodes
 	// In this case, we need to make sure that we take the older of the two for expiration
 	// TODO @joinnis: This check that takes the minimum between the Node and Machine CreationTimestamps can be removed
 	// once machine migration is ripped out, which should happen when apis and Karpenter are promoted to v1
 	var expirationTime time.Time
 	if node == nil || nodeClaim.CreationTimestamp.Before(&node.CreationTimestamp) {
-		expirationTime = nodeClaim.CreationTimestamp.Add(nodePool.Spec.Deprovisioning.ExpirationTTL.Duration)
+		expirationTime = nodeClaim.CreationTimestamp.Add(ttl)
 	} else {
-		expirationTime = node.CreationTimestamp.Add(nodePool.Spec.Deprovisioning.ExpirationTTL.Duration)
+		expirationTime = node.CreationTimestamp.Add(ttl)
 	}
+	// Jitter only applies to the NodePool ExpirationTTL since an override explicitly pins the expiration
+	if !overridden {
+		expirationTime = expirationTime.Add(expirationJitter(nodePool, nodeClaim))
+	}
+	nodeClaim.Status.ExpirationTime = &metav1.Time{Time: expirationTime}
 	// 2. If the NodeClaim isn't expired, remove the status condition.
 	if e.clock.Now().Before(expirationTime) {
 		_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeExpired)
@@ -85,3 +101,19 @@ func (e *Expiration) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool,
 	}
 	return reconcile.Result{}, nil
 }
+
+// expirationJitter returns a jitter in the range [0, expirationJitter) that is added to the NodeClaim's expiration time
+// so that NodeClaims launched at the same time don't expire together. The jitter is derived from the NodeClaim UID
+// so that it stays stable across reconciles.
+func expirationJitter(nodePool *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) time.Duration {
+	if nodePool.Spec.Deprovisioning.ExpirationJitter == nil {
+		return 0
+	}
+	maxJitter, err := intstr.GetScaledValueFromIntOrPercent(nodePool.Spec.Deprovisioning.ExpirationJitter, int(nodePool.Spec.Deprovisioning.ExpirationTTL.Seconds()), true)
+	if err != nil || maxJitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(nodeClaim.UID))
+	return time.Duration(h.Sum64()%uint64(maxJitter)) * time.Second
+}
@@ -17,12 +17,15 @@ package disruption_test
 import (
 	"time"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -64,6 +67,137 @@ var _ = Describe("Expiration", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired).IsTrue()).To(BeTrue())
 	})
+	It("should not mark paused machines as expired", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(30)
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.MachinePausedAnnotationKey: "true"})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired)).To(BeNil())
+	})
+	It("should not mark machines as expired when disruption is paused on the provisioner", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(30)
+		provisioner.Annotations = lo.Assign(provisioner.Annotations, map[string]string{v1alpha5.DisruptionPausedAnnotationKey: "true"})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired)).To(BeNil())
+	})
+	It("should not mark machines as expired when the node is running a do-not-disrupt pod", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(30)
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1.DoNotDisruptAnnotationKey: "true"}},
+			NodeName:   node.Name,
+			Phase:      v1.PodRunning,
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node, pod)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired)).To(BeNil())
+
+		// Once the pod is gone, the machine is marked as expired
+		ExpectDeleted(ctx, env.Client, pod)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired).IsTrue()).To(BeTrue())
+	})
+	It("should mark machines as expired when the do-not-disrupt pod on the node has completed", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(30)
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1.DoNotDisruptAnnotationKey: "true"}},
+			NodeName:   node.Name,
+			Phase:      v1.PodSucceeded,
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node, pod)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired).IsTrue()).To(BeTrue())
+	})
+	It("should surface the expiration time on the machine status", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(30)
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.ExpirationTime).ToNot(BeNil())
+		Expect(machine.Status.ExpirationTime.Time).To(BeTemporally("==", machine.CreationTimestamp.Add(30*time.Second)))
+	})
+	It("should add jitter to the expiration time within the configured bounds", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(100)
+		provisioner.Spec.ExpirationJitter = lo.ToPtr(intstr.FromString("50%"))
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.ExpirationTime).ToNot(BeNil())
+		Expect(machine.Status.ExpirationTime.Time).To(BeTemporally(">=", machine.CreationTimestamp.Add(100*time.Second)))
+		Expect(machine.Status.ExpirationTime.Time).To(BeTemporally("<", machine.CreationTimestamp.Add(150*time.Second)))
+
+		// The jitter should be stable across reconciles
+		expirationTime := machine.Status.ExpirationTime.DeepCopy()
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.ExpirationTime.Time).To(BeTemporally("==", expirationTime.Time))
+	})
+	It("should expire machines using the ttl-until-expired annotation when expiration is disabled on the provisioner", func() {
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.TTLUntilExpiredAnnotationKey: "30s"})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired).IsTrue()).To(BeTrue())
+	})
+	It("should prefer the ttl-until-expired annotation on the machine over the provisioner ttl", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(30)
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.TTLUntilExpiredAnnotationKey: "24h"})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired)).To(BeNil())
+		Expect(machine.Status.ExpirationTime.Time).To(BeTemporally("==", machine.CreationTimestamp.Add(24*time.Hour)))
+	})
+	It("should use the ttl-until-expired annotation on the node", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(3600)
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1alpha5.TTLUntilExpiredAnnotationKey: "30s"})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired).IsTrue()).To(BeTrue())
+	})
+	It("should ignore an invalid ttl-until-expired annotation", func() {
+		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(3600)
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.TTLUntilExpiredAnnotationKey: "soon"})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		fakeClock.Step(60 * time.Second)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineExpired)).To(BeNil())
+	})
 	It("should remove the status condition from non-expired machines", func() {
 		provisioner.Spec.TTLSecondsUntilExpired = ptr.Int64(200)
 		machine.StatusConditions().MarkTrue(v1alpha5.MachineExpired)
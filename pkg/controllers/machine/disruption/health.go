@@ -0,0 +1,110 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruption
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/metrics"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+)
+
+// Health is a machine sub-controller that adds or removes the unhealthy status condition on machines whose nodes
+// have been NotReady, NetworkUnavailable or reporting one of the configured node repair conditions for longer than
+// nodeRepairUnhealthyDuration
+type Health struct {
+	kubeClient client.Client
+	clock      clock.Clock
+}
+
+func (h *Health) Reconcile(ctx context.Context, _ *v1beta1.NodePool, nodeClaim *v1beta1.NodeClaim) (reconcile.Result, error) {
+	hasUnhealthyCondition := nodeClaim.StatusConditions().GetCondition(v1beta1.NodeUnhealthy) != nil
+	unhealthyDuration := settings.FromContext(ctx).NodeRepairUnhealthyDuration
+
+	// 1. If node repair is disabled or the NodeClaim hasn't initialized, remove the status condition.
+	// NodeClaims whose nodes never become ready are handled by the liveness controller instead.
+	if unhealthyDuration == 0 || !nodeClaim.StatusConditions().GetCondition(v1beta1.NodeInitialized).IsTrue() {
+		_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeUnhealthy)
+		if hasUnhealthyCondition {
+			logging.FromContext(ctx).Debugf("removing unhealthy status condition, node repair doesn't apply")
+		}
+		return reconcile.Result{}, nil
+	}
+	node, err := nodeclaimutil.NodeForNodeClaim(ctx, h.kubeClient, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, nodeclaimutil.IgnoreNodeNotFoundError(nodeclaimutil.IgnoreDuplicateNodeError(err))
+	}
+	// 2. If the Node is healthy, remove the status condition.
+	condition, ok := unhealthyCondition(ctx, node)
+	if !ok {
+		_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeUnhealthy)
+		if hasUnhealthyCondition {
+			logging.FromContext(ctx).Debugf("removing unhealthy status condition, node is healthy")
+		}
+		return reconcile.Result{}, nil
+	}
+	// 3. If the Node hasn't been unhealthy for long enough, remove the status condition and requeue once it has.
+	// Use t.Sub(clock.Now()) instead of time.Until() to ensure we're using the injected clock.
+	unhealthyTime := condition.LastTransitionTime.Add(unhealthyDuration)
+	if h.clock.Now().Before(unhealthyTime) {
+		_ = nodeClaim.StatusConditions().ClearCondition(v1beta1.NodeUnhealthy)
+		return reconcile.Result{RequeueAfter: unhealthyTime.Sub(h.clock.Now())}, nil
+	}
+	// 4. Otherwise, the Node has been unhealthy for longer than the unhealthy duration, so add the status condition.
+	nodeClaim.StatusConditions().SetCondition(apis.Condition{
+		Type:     v1beta1.NodeUnhealthy,
+		Status:   v1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   string(condition.Type),
+		Message:  fmt.Sprintf("Node condition %s has been %s since %s", condition.Type, condition.Status, condition.LastTransitionTime.Format(time.RFC3339)),
+	})
+	if !hasUnhealthyCondition {
+		logging.FromContext(ctx).With("condition", condition.Type).Debugf("marking unhealthy")
+		nodeclaimutil.DisruptedCounter(nodeClaim, metrics.RepairReason).Inc()
+	}
+	return reconcile.Result{}, nil
+}
+
+// unhealthyCondition returns the node condition that has been unhealthy the longest, if any
+func unhealthyCondition(ctx context.Context, node *v1.Node) (v1.NodeCondition, bool) {
+	unhealthy := lo.Filter(node.Status.Conditions, func(c v1.NodeCondition, _ int) bool {
+		switch {
+		case c.Type == v1.NodeReady:
+			return c.Status != v1.ConditionTrue
+		case c.Type == v1.NodeNetworkUnavailable:
+			return c.Status == v1.ConditionTrue
+		default:
+			return c.Status == v1.ConditionTrue && lo.Contains(settings.FromContext(ctx).NodeRepairConditions, string(c.Type))
+		}
+	})
+	if len(unhealthy) == 0 {
+		return v1.NodeCondition{}, false
+	}
+	return lo.MinBy(unhealthy, func(a, b v1.NodeCondition) bool {
+		return a.LastTransitionTime.Before(&b.LastTransitionTime)
+	}), true
+}
@@ -0,0 +1,145 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruption_test
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var _ = Describe("Health", func() {
+	var provisioner *v1alpha5.Provisioner
+	var machine *v1alpha5.Machine
+	var node *v1.Node
+	BeforeEach(func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{NodeRepairUnhealthyDuration: 10 * time.Minute, NodeRepairConditions: []string{"KernelDeadlock"}}))
+		provisioner = test.Provisioner()
+		machine, node = test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+			},
+		})
+	})
+
+	It("should mark machines as unhealthy when the node has been NotReady longer than the unhealthy duration", func() {
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionUnknown, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}}}
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		fakeClock.Step(11 * time.Minute)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy).IsTrue()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy).Reason).To(Equal(string(v1.NodeReady)))
+	})
+	It("should mark machines as unhealthy when the node is running a do-not-disrupt pod", func() {
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionUnknown, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}}}
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1.DoNotDisruptAnnotationKey: "true"}},
+			NodeName:   node.Name,
+			Phase:      v1.PodRunning,
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node, pod)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		fakeClock.Step(11 * time.Minute)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy).IsTrue()).To(BeTrue())
+	})
+	It("should mark machines as unhealthy when a configured node condition has been true longer than the unhealthy duration", func() {
+		node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: "KernelDeadlock", Status: v1.ConditionTrue, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		fakeClock.Step(11 * time.Minute)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy).IsTrue()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy).Reason).To(Equal("KernelDeadlock"))
+	})
+	It("should not mark machines as unhealthy before the unhealthy duration has elapsed", func() {
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}}}
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		fakeClock.Step(8 * time.Minute)
+		result := ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+		Expect(result.RequeueAfter).To(BeNumerically("~", 2*time.Minute, time.Second))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy)).To(BeNil())
+	})
+	It("should not mark machines as unhealthy when node conditions that aren't configured are true", func() {
+		node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: "FrequentKubeletRestart", Status: v1.ConditionTrue, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		fakeClock.Step(11 * time.Minute)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy)).To(BeNil())
+	})
+	It("should remove the status condition from machines when the node becomes healthy", func() {
+		machine.StatusConditions().MarkTrue(v1alpha5.MachineUnhealthy)
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy)).To(BeNil())
+	})
+	It("should remove the status condition from machines when node repair is disabled", func() {
+		ctx = settings.ToContext(ctx, test.Settings())
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}}}
+		machine.StatusConditions().MarkTrue(v1alpha5.MachineUnhealthy)
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+
+		fakeClock.Step(11 * time.Minute)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy)).To(BeNil())
+	})
+	It("should not mark machines as unhealthy before they're initialized", func() {
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.Time{Time: fakeClock.Now()}}}
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+
+		fakeClock.Step(11 * time.Minute)
+		ExpectReconcileSucceeded(ctx, disruptionController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineUnhealthy)).To(BeNil())
+	})
+})
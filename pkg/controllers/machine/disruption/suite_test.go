@@ -24,6 +24,7 @@ import (
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
 	clock "k8s.io/utils/clock/testing"
 	. "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/ptr"
@@ -66,9 +67,18 @@ var _ = BeforeSuite(func() {
 	ctx = settings.ToContext(ctx, test.Settings())
 	cp = fake.NewCloudProvider()
 	cluster = state.NewCluster(fakeClock, env.Client, cp)
-	disruptionController = nodeclaimdisruption.NewMachineController(fakeClock, env.Client, cluster, cp)
+	disruptionController = nodeclaimdisruption.NewMachineController(fakeClock, env.Client, cluster, cp, &fakeServerVersion{gitVersion: "v1.27.3"})
 })
 
+// fakeServerVersion reports a fixed API server version for kubelet version drift
+type fakeServerVersion struct {
+	gitVersion string
+}
+
+func (f *fakeServerVersion) ServerVersion() (*version.Info, error) {
+	return &version.Info{GitVersion: f.gitVersion}, nil
+}
+
 var _ = AfterSuite(func() {
 	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
 })
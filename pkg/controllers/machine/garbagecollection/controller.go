@@ -16,10 +16,14 @@ package garbagecollection
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
@@ -27,25 +31,37 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/metrics"
 	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
 	"github.com/aws/karpenter-core/pkg/utils/sets"
 )
 
+// watchedIntervalMultiplier lengthens the garbage collection interval for CloudProviders that push machine events.
+// Terminated instances are already removed as soon as they're reported, so garbage collection only needs to catch the
+// events that were missed.
+const watchedIntervalMultiplier = 10
+
 type Controller struct {
 	clock         clock.Clock
 	kubeClient    client.Client
 	cloudProvider cloudprovider.CloudProvider
+	recorder      events.Recorder
+	watched       bool // whether the CloudProvider pushes machine events
 }
 
-func NewController(c clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) corecontroller.Controller {
+func NewController(c clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, recorder events.Recorder, watched bool) corecontroller.Controller {
 	return &Controller{
 		clock:         c,
 		kubeClient:    kubeClient,
 		cloudProvider: cloudProvider,
+		recorder:      recorder,
+		watched:       watched,
 	}
 }
 
@@ -66,16 +82,26 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 		return m.DeletionTimestamp.IsZero()
 	})
 	cloudProviderProviderIDs := sets.New[string](lo.Map(cloudProviderMachines, func(m *v1alpha5.Machine, _ int) string {
-		return m.Status.ProviderID
+		return cloudprovider.NormalizeProviderID(c.cloudProvider, m.Status.ProviderID)
 	})...)
 	nodeClaims := lo.Filter(lo.ToSlicePtr(nodeClaimList.Items), func(n *v1beta1.NodeClaim, _ int) bool {
 		return n.StatusConditions().GetCondition(v1beta1.NodeLaunched).IsTrue() &&
 			n.DeletionTimestamp.IsZero() &&
-			c.clock.Since(n.StatusConditions().GetCondition(v1beta1.NodeLaunched).LastTransitionTime.Inner.Time) > time.Second*10 &&
-			!cloudProviderProviderIDs.Has(n.Status.ProviderID)
+			!nodeclaimutil.Paused(n) &&
+			c.clock.Since(n.StatusConditions().GetCondition(v1beta1.NodeLaunched).LastTransitionTime.Inner.Time) > settings.FromContext(ctx).GarbageCollectionMinimumAge &&
+			!cloudProviderProviderIDs.Has(cloudprovider.NormalizeProviderID(c.cloudProvider, n.Status.ProviderID))
+	})
+	// NodeClaims that exhausted their launch retries are replaced by provisioning once they're removed
+	failed := lo.Filter(lo.ToSlicePtr(nodeClaimList.Items), func(n *v1beta1.NodeClaim, _ int) bool {
+		return n.StatusConditions().GetCondition(v1beta1.NodeLaunchFailed).IsTrue() && n.DeletionTimestamp.IsZero() && !nodeclaimutil.Paused(n)
 	})
 
-	errs := make([]error, len(nodeClaims))
+	// Pace deletions so that large clusters don't delete many NodeClaims at once, the rest are deleted in later passes
+	if batchSize := settings.FromContext(ctx).GarbageCollectionBatchSize; batchSize > 0 {
+		nodeClaims = lo.Slice(nodeClaims, 0, batchSize)
+		failed = lo.Slice(failed, 0, batchSize-len(nodeClaims))
+	}
+	errs := make([]error, len(nodeClaims)+len(failed))
 	workqueue.ParallelizeUntil(ctx, 20, len(nodeClaims), func(i int) {
 		if err := nodeclaimutil.Delete(ctx, c.kubeClient, nodeClaims[i]); err != nil {
 			errs[i] = client.IgnoreNotFound(err)
@@ -89,8 +115,99 @@ func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconc
 			).
 			Debugf("garbage collecting %s with no cloudprovider representation", lo.Ternary(nodeClaims[i].IsMachine, "machine", "nodeclaim"))
 		nodeclaimutil.TerminatedCounter(nodeClaims[i], "garbage_collected").Inc()
+		nodeclaimutil.FailedCounter(nodeClaims[i], metrics.TerminatedExternallyReason).Inc()
+	})
+	workqueue.ParallelizeUntil(ctx, 20, len(failed), func(i int) {
+		if err := nodeclaimutil.Delete(ctx, c.kubeClient, failed[i]); err != nil {
+			errs[len(nodeClaims)+i] = client.IgnoreNotFound(err)
+			return
+		}
+		logging.FromContext(ctx).
+			With(
+				lo.Ternary(failed[i].IsMachine, "machine", "nodeclaim"), failed[i].Name,
+				lo.Ternary(failed[i].IsMachine, "provisioner", "nodepool"), nodeclaimutil.OwnerKey(failed[i]).Name,
+			).
+			Debugf("garbage collecting %s that failed to launch", lo.Ternary(failed[i].IsMachine, "machine", "nodeclaim"))
+		nodeclaimutil.TerminatedCounter(failed[i], "launch_failed").Inc()
 	})
-	return reconcile.Result{RequeueAfter: time.Minute * 2}, multierr.Combine(errs...)
+	if err = c.terminateLeakedInstances(ctx, nodeClaimList.Items, cloudProviderMachines); err != nil {
+		errs = append(errs, fmt.Errorf("terminating leaked instances, %w", err))
+	}
+	return reconcile.Result{RequeueAfter: c.interval(ctx)}, multierr.Combine(errs...)
+}
+
+func (c *Controller) interval(ctx context.Context) time.Duration {
+	if c.watched {
+		return settings.FromContext(ctx).GarbageCollectionInterval * watchedIntervalMultiplier
+	}
+	return settings.FromContext(ctx).GarbageCollectionInterval
+}
+
+// terminateLeakedInstances deletes the CloudProvider instances that no NodeClaim or Node refers to once they're older
+// than the leakedInstanceGracePeriod. Instances that don't report when they were created are never considered leaked.
+func (c *Controller) terminateLeakedInstances(ctx context.Context, nodeClaims []v1beta1.NodeClaim, instances []*v1alpha5.Machine) error {
+	gracePeriod := settings.FromContext(ctx).LeakedInstanceGracePeriod
+	if gracePeriod == 0 {
+		return nil
+	}
+	nodeList := &v1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodeList); err != nil {
+		return fmt.Errorf("listing nodes, %w", err)
+	}
+	// Nodes are included so that instances that were launched before their Machine existed aren't deleted
+	knownProviderIDs := sets.New[string](lo.Map(nodeList.Items, func(n v1.Node, _ int) string {
+		return cloudprovider.NormalizeProviderID(c.cloudProvider, n.Spec.ProviderID)
+	})...)
+	for _, n := range nodeClaims {
+		knownProviderIDs.Insert(cloudprovider.NormalizeProviderID(c.cloudProvider, n.Status.ProviderID),
+			cloudprovider.NormalizeProviderID(c.cloudProvider, n.Annotations[v1alpha5.MachineLinkedAnnotationKey]))
+	}
+	leaked := lo.Filter(instances, func(m *v1alpha5.Machine, _ int) bool {
+		return m.Status.ProviderID != "" &&
+			!knownProviderIDs.Has(cloudprovider.NormalizeProviderID(c.cloudProvider, m.Status.ProviderID)) &&
+			!m.CreationTimestamp.IsZero() &&
+			c.clock.Since(m.CreationTimestamp.Time) > gracePeriod
+	})
+	errs := make([]error, len(leaked))
+	workqueue.ParallelizeUntil(ctx, 20, len(leaked), func(i int) {
+		if err := c.cloudProvider.Delete(ctx, leaked[i]); cloudprovider.IgnoreMachineNotFoundError(err) != nil {
+			errs[i] = err
+			return
+		}
+		age := c.clock.Since(leaked[i].CreationTimestamp.Time).Truncate(time.Second)
+		if owner := c.owner(ctx, leaked[i]); owner != nil {
+			c.recorder.Publish(LeakedInstanceTerminatedEvent(owner, leaked[i].Status.ProviderID, age))
+		}
+		logging.FromContext(ctx).
+			With(
+				"provider-id", leaked[i].Status.ProviderID,
+				"age", age,
+				"provisioner", leaked[i].Labels[v1alpha5.ProvisionerNameLabelKey],
+				"nodepool", leaked[i].Labels[v1beta1.NodePoolLabelKey],
+			).
+			Infof("terminated leaked instance with no machine")
+		LeakedInstancesTerminatedCounter.With(prometheus.Labels{
+			metrics.ProvisionerLabel: leaked[i].Labels[v1alpha5.ProvisionerNameLabelKey],
+			metrics.NodePoolLabel:    leaked[i].Labels[v1beta1.NodePoolLabelKey],
+		}).Inc()
+	})
+	return multierr.Combine(errs...)
+}
+
+// owner returns the provisioner or nodepool that the instance was launched for, or nil if it no longer exists
+func (c *Controller) owner(ctx context.Context, instance *v1alpha5.Machine) client.Object {
+	var owner client.Object
+	if name, ok := instance.Labels[v1alpha5.ProvisionerNameLabelKey]; ok {
+		owner = &v1alpha5.Provisioner{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	} else if name, ok := instance.Labels[v1beta1.NodePoolLabelKey]; ok {
+		owner = &v1beta1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	} else {
+		return nil
+	}
+	if err := c.kubeClient.Get(ctx, client.ObjectKeyFromObject(owner), owner); err != nil {
+		return nil
+	}
+	return owner
 }
 
 func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
@@ -0,0 +1,37 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/events"
+)
+
+// LeakedInstanceTerminatedEvent records the termination of a CloudProvider instance that no machine referred to
+// against the provisioner or nodepool that it was launched for, since the instance itself has no object in the cluster
+func LeakedInstanceTerminatedEvent(owner client.Object, providerID string, age time.Duration) events.Event {
+	return events.Event{
+		InvolvedObject: owner,
+		Type:           v1.EventTypeWarning,
+		Reason:         "LeakedInstanceTerminated",
+		Message:        fmt.Sprintf("Terminated leaked instance %s after %s with no machine", providerID, age),
+		DedupeValues:   []string{providerID},
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+const garbageCollectionSubsystem = "garbage_collection"
+
+var (
+	LeakedInstancesTerminatedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: garbageCollectionSubsystem,
+			Name:      "leaked_instances_terminated",
+			Help:      "Number of cloudprovider instances with no machine that were terminated after the leaked instance grace period. Labeled by the provisioner or nodepool the instance was launched for.",
+		},
+		[]string{
+			metrics.ProvisionerLabel,
+			metrics.NodePoolLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(LeakedInstancesTerminatedCounter)
+}
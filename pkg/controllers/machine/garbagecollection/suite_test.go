@@ -16,6 +16,7 @@ package garbagecollection_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	nodeclaimgarbagecollection "github.com/aws/karpenter-core/pkg/controllers/machine/garbagecollection"
 	nodeclaimlifcycle "github.com/aws/karpenter-core/pkg/controllers/machine/lifecycle"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/scheme"
@@ -51,6 +53,8 @@ var garbageCollectionController controller.Controller
 var env *test.Environment
 var fakeClock *clock.FakeClock
 var cloudProvider *fake.CloudProvider
+var cluster *state.Cluster
+var recorder *test.EventRecorder
 
 func TestAPIs(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -68,8 +72,10 @@ var _ = BeforeSuite(func() {
 	ctx = settings.ToContext(ctx, test.Settings())
 
 	cloudProvider = fake.NewCloudProvider()
-	garbageCollectionController = nodeclaimgarbagecollection.NewController(fakeClock, env.Client, cloudProvider)
-	machineController = nodeclaimlifcycle.NewMachineController(fakeClock, env.Client, cloudProvider, events.NewRecorder(&record.FakeRecorder{}))
+	recorder = test.NewEventRecorder()
+	garbageCollectionController = nodeclaimgarbagecollection.NewController(fakeClock, env.Client, cloudProvider, recorder, false)
+	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
+	machineController = nodeclaimlifcycle.NewMachineController(ctx, fakeClock, env.Client, cloudProvider, cluster, events.NewRecorder(&record.FakeRecorder{}))
 })
 
 var _ = AfterSuite(func() {
@@ -80,6 +86,8 @@ var _ = AfterEach(func() {
 	fakeClock.SetTime(time.Now())
 	ExpectCleanedUp(ctx, env.Client)
 	cloudProvider.Reset()
+	cluster.Reset()
+	recorder.Reset()
 })
 
 var _ = Describe("GarbageCollection", func() {
@@ -110,6 +118,9 @@ var _ = Describe("GarbageCollection", func() {
 		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
 		ExpectFinalizersRemoved(ctx, env.Client, machine)
 		ExpectNotFound(ctx, env.Client, machine)
+		m, found := FindMetricWithLabelValues("karpenter_machines_failed", map[string]string{"reason": "terminated_externally", "provisioner": provisioner.Name})
+		Expect(found).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
 	})
 	It("should delete many Machines when the Node never appears and the instance is gone", func() {
 		var machines []*v1alpha5.Machine
@@ -143,6 +154,90 @@ var _ = Describe("GarbageCollection", func() {
 		}
 		ExpectNotFound(ctx, env.Client, lo.Map(machines, func(m *v1alpha5.Machine, _ int) client.Object { return m })...)
 	})
+	It("should only delete as many Machines as the garbage collection batch size allows", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{GarbageCollectionBatchSize: 2}))
+		var machines []*v1alpha5.Machine
+		for i := 0; i < 5; i++ {
+			machine := test.Machine(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+					},
+				},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, machine)
+			ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+			machine = ExpectExists(ctx, env.Client, machine)
+			machines = append(machines, machine)
+		}
+
+		// Step forward to move past the cache eventual consistency timeout
+		fakeClock.SetTime(time.Now().Add(time.Second * 20))
+
+		for _, machine := range machines {
+			// Delete the machine from the cloudprovider
+			Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+		}
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		for _, machine := range machines {
+			ExpectFinalizersRemoved(ctx, env.Client, machine)
+		}
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(3))
+	})
+	It("shouldn't delete the Machine before it reaches the garbage collection minimum age", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{GarbageCollectionMinimumAge: time.Minute}))
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		fakeClock.SetTime(time.Now().Add(time.Second * 20))
+		Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectExists(ctx, env.Client, machine)
+
+		// Step forward past the minimum age
+		fakeClock.SetTime(time.Now().Add(time.Minute * 2))
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectFinalizersRemoved(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine)
+	})
+	It("should requeue after the garbage collection interval", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{GarbageCollectionInterval: time.Minute * 5}))
+		result := ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		Expect(result.RequeueAfter).To(Equal(time.Minute * 5))
+	})
+	It("should requeue after a longer interval when the cloudprovider pushes machine events", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{GarbageCollectionInterval: time.Minute * 5}))
+		controller := nodeclaimgarbagecollection.NewController(fakeClock, env.Client, cloudProvider, recorder, true)
+		result := ExpectReconcileSucceeded(ctx, controller, client.ObjectKey{})
+		Expect(result.RequeueAfter).To(Equal(time.Minute * 50))
+	})
+	It("should delete the Machine when it failed to launch", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+				Finalizers: []string{v1alpha5.TerminationFinalizer},
+			},
+		})
+		machine.StatusConditions().MarkFalse(v1alpha5.MachineLaunched, "LaunchFailed", "launch failed")
+		machine.StatusConditions().MarkTrueWithReason(v1alpha5.MachineLaunchFailed, "RetriesExhausted", "launch failed")
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectFinalizersRemoved(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine)
+	})
 	It("shouldn't delete the Machine when the Node isn't there but the instance is there", func() {
 		machine := test.Machine(v1alpha5.Machine{
 			ObjectMeta: metav1.ObjectMeta{
@@ -163,4 +258,138 @@ var _ = Describe("GarbageCollection", func() {
 		ExpectFinalizersRemoved(ctx, env.Client, machine)
 		ExpectExists(ctx, env.Client, machine)
 	})
+	It("shouldn't delete the Machine when it's paused and the instance is gone", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		machine.Annotations = lo.Assign(machine.Annotations, map[string]string{v1alpha5.MachinePausedAnnotationKey: "true"})
+		ExpectApplied(ctx, env.Client, machine)
+
+		// Step forward to move past the cache eventual consistency timeout
+		fakeClock.SetTime(time.Now().Add(time.Second * 20))
+
+		// Delete the machine from the cloudprovider
+		Expect(cloudProvider.Delete(ctx, machine)).To(Succeed())
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("shouldn't delete the Machine when it's paused and failed to launch", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+				Annotations: map[string]string{
+					v1alpha5.MachinePausedAnnotationKey: "true",
+				},
+			},
+		})
+		machine.StatusConditions().MarkFalse(v1alpha5.MachineLaunched, "LaunchFailed", "launch failed")
+		machine.StatusConditions().MarkTrueWithReason(v1alpha5.MachineLaunchFailed, "RetriesExhausted", "launch failed")
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+
+		ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+		ExpectExists(ctx, env.Client, machine)
+	})
+	Context("Leaked Instances", func() {
+		var instance *v1alpha5.Machine
+		BeforeEach(func() {
+			instance = &v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              test.RandomName(),
+					Labels:            map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+					CreationTimestamp: metav1.Time{Time: fakeClock.Now().Add(-time.Hour)},
+				},
+				Status: v1alpha5.MachineStatus{ProviderID: test.RandomProviderID()},
+			}
+			cloudProvider.CreatedMachines[instance.Status.ProviderID] = instance
+		})
+		It("should terminate instances without a Machine after the grace period", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LeakedInstanceGracePeriod: time.Minute * 30}))
+			ExpectApplied(ctx, env.Client, provisioner)
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(1))
+			Expect(cloudProvider.CreatedMachines).ToNot(HaveKey(instance.Status.ProviderID))
+			Expect(recorder.Calls("LeakedInstanceTerminated")).To(Equal(1))
+			m, found := FindMetricWithLabelValues("karpenter_garbage_collection_leaked_instances_terminated", map[string]string{"provisioner": provisioner.Name})
+			Expect(found).To(BeTrue())
+			Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+		})
+		It("should terminate instances without a Machine when their provisioner no longer exists", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LeakedInstanceGracePeriod: time.Minute * 30}))
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(1))
+			Expect(cloudProvider.CreatedMachines).ToNot(HaveKey(instance.Status.ProviderID))
+			// There's no object to record the event against, so the termination is only logged and counted
+			Expect(recorder.Calls("LeakedInstanceTerminated")).To(Equal(0))
+			m, found := FindMetricWithLabelValues("karpenter_garbage_collection_leaked_instances_terminated", map[string]string{"provisioner": provisioner.Name})
+			Expect(found).To(BeTrue())
+			Expect(m.GetCounter().GetValue()).To(BeNumerically(">=", 1))
+		})
+		It("shouldn't terminate instances without a Machine before the grace period", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LeakedInstanceGracePeriod: time.Hour * 2}))
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(0))
+			Expect(cloudProvider.CreatedMachines).To(HaveKey(instance.Status.ProviderID))
+		})
+		It("shouldn't terminate instances without a Machine when the grace period isn't set", func() {
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(0))
+			Expect(cloudProvider.CreatedMachines).To(HaveKey(instance.Status.ProviderID))
+		})
+		It("shouldn't terminate instances with a Machine", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LeakedInstanceGracePeriod: time.Minute * 30}))
+			machine := test.Machine(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+				},
+				Status: v1alpha5.MachineStatus{ProviderID: instance.Status.ProviderID},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, machine)
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(0))
+			Expect(cloudProvider.CreatedMachines).To(HaveKey(instance.Status.ProviderID))
+		})
+		It("shouldn't terminate instances with a Machine that uses a different provider ID format", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LeakedInstanceGracePeriod: time.Minute * 30}))
+			cloudProvider.ProviderIDFormat = func(id string) string { return strings.Replace(id, "legacy:///", "fake:///", 1) }
+			machine := test.Machine(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+				},
+				Status: v1alpha5.MachineStatus{ProviderID: strings.Replace(instance.Status.ProviderID, "fake:///", "legacy:///", 1)},
+			})
+			machine.StatusConditions().MarkTrue(v1alpha5.MachineLaunched)
+			ExpectApplied(ctx, env.Client, provisioner, machine)
+			fakeClock.SetTime(time.Now().Add(time.Minute))
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			// Neither the instance nor the Machine are garbage collected
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(0))
+			Expect(cloudProvider.CreatedMachines).To(HaveKey(instance.Status.ProviderID))
+			ExpectExists(ctx, env.Client, machine)
+		})
+		It("shouldn't terminate instances with a Node but no Machine", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LeakedInstanceGracePeriod: time.Minute * 30}))
+			node := test.Node(test.NodeOptions{ProviderID: instance.Status.ProviderID})
+			ExpectApplied(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, garbageCollectionController, client.ObjectKey{})
+
+			Expect(cloudProvider.DeleteCalls).To(HaveLen(0))
+			Expect(cloudProvider.CreatedMachines).To(HaveKey(instance.Status.ProviderID))
+		})
+	})
 })
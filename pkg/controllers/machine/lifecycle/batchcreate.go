@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+)
+
+// createBatchWindow is how long launches are collected for before they're created together through BatchCreate
+const createBatchWindow = 100 * time.Millisecond
+
+type createResult struct {
+	machine *v1alpha5.Machine
+	err     error
+}
+
+type createRequest struct {
+	machine *v1alpha5.Machine
+	result  chan createResult
+}
+
+// createBatcher collects the machines that are launched for the same owner within the createBatchWindow and launches
+// them with a single BatchCreate call, so that CloudProviders can launch the similar machines of a provisioning round
+// together. Batches are launched with the operator's context rather than the context of the reconcile that opened
+// them, since the batch outlives it and launches the machines of other reconciles.
+type createBatcher struct {
+	ctx           context.Context
+	cloudProvider cloudprovider.BatchCreator
+
+	mu      sync.Mutex
+	batches map[nodepoolutil.Key][]*createRequest
+}
+
+// newCreateBatcher returns a createBatcher if the CloudProvider implements BatchCreate and nil otherwise
+func newCreateBatcher(ctx context.Context, cloudProvider cloudprovider.CloudProvider) *createBatcher {
+	batchCreator, ok := cloudProvider.(cloudprovider.BatchCreator)
+	if !ok {
+		return nil
+	}
+	return &createBatcher{ctx: ctx, cloudProvider: batchCreator, batches: map[nodepoolutil.Key][]*createRequest{}}
+}
+
+// Create adds the machine to the current batch of its owner and blocks until the batch has been launched
+func (b *createBatcher) Create(owner nodepoolutil.Key, machine *v1alpha5.Machine) (*v1alpha5.Machine, error) {
+	request := &createRequest{machine: machine, result: make(chan createResult, 1)}
+	b.mu.Lock()
+	// the first machine for the owner opens the batching window
+	if _, ok := b.batches[owner]; !ok {
+		time.AfterFunc(createBatchWindow, func() { b.flush(owner) })
+	}
+	b.batches[owner] = append(b.batches[owner], request)
+	b.mu.Unlock()
+
+	result := <-request.result
+	return result.machine, result.err
+}
+
+func (b *createBatcher) flush(owner nodepoolutil.Key) {
+	b.mu.Lock()
+	requests := b.batches[owner]
+	delete(b.batches, owner)
+	b.mu.Unlock()
+
+	created, errs := b.cloudProvider.BatchCreate(b.ctx, lo.Map(requests, func(r *createRequest, _ int) *v1alpha5.Machine { return r.machine }))
+	for i, request := range requests {
+		if i >= len(created) || i >= len(errs) {
+			request.result <- createResult{err: fmt.Errorf("batch create returned %d machines for %d requests", len(created), len(requests))}
+			continue
+		}
+		request.result <- createResult{machine: created[i], err: errs[i]}
+	}
+}
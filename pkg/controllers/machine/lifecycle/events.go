@@ -20,17 +20,20 @@ import (
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/events"
 	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
 )
 
-func InsufficientCapacityErrorEvent(nodeClaim *v1beta1.NodeClaim, err error) events.Event {
+// LaunchFailedEvent is published when launching fails with a well-known CloudProvider error, using the class of the
+// error as the reason
+func LaunchFailedEvent(nodeClaim *v1beta1.NodeClaim, err error) events.Event {
 	if nodeClaim.IsMachine {
 		machine := machineutil.NewFromNodeClaim(nodeClaim)
 		return events.Event{
 			InvolvedObject: machine,
 			Type:           v1.EventTypeWarning,
-			Reason:         "InsufficientCapacityError",
+			Reason:         cloudprovider.ErrorClass(err),
 			Message:        fmt.Sprintf("Machine %s event: %s", machine.Name, truncateMessage(err.Error())),
 			DedupeValues:   []string{string(machine.UID)},
 		}
@@ -38,8 +41,29 @@ func InsufficientCapacityErrorEvent(nodeClaim *v1beta1.NodeClaim, err error) eve
 	return events.Event{
 		InvolvedObject: nodeClaim,
 		Type:           v1.EventTypeWarning,
-		Reason:         "InsufficientCapacityError",
+		Reason:         cloudprovider.ErrorClass(err),
 		Message:        fmt.Sprintf("NodeClaim %s event: %s", nodeClaim.Name, truncateMessage(err.Error())),
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+// LaunchRetriesExhaustedEvent is published when launching still fails after all retries
+func LaunchRetriesExhaustedEvent(nodeClaim *v1beta1.NodeClaim, attempts int, err error) events.Event {
+	if nodeClaim.IsMachine {
+		machine := machineutil.NewFromNodeClaim(nodeClaim)
+		return events.Event{
+			InvolvedObject: machine,
+			Type:           v1.EventTypeWarning,
+			Reason:         "LaunchRetriesExhausted",
+			Message:        fmt.Sprintf("Machine %s failed to launch after %d attempts: %s", machine.Name, attempts, truncateMessage(err.Error())),
+			DedupeValues:   []string{string(machine.UID)},
+		}
+	}
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "LaunchRetriesExhausted",
+		Message:        fmt.Sprintf("NodeClaim %s failed to launch after %d attempts: %s", nodeClaim.Name, attempts, truncateMessage(err.Error())),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	nodeutil "github.com/aws/karpenter-core/pkg/utils/node"
@@ -40,6 +41,7 @@ type Initialization struct {
 // a) its current status is set to Ready
 // b) all the startup taints have been removed from the node
 // c) all extended resources have been registered
+// d) all the readiness gates of its owner have been satisfied
 // This method handles both nil provisioners and nodes without extended resources gracefully.
 func (i *Initialization) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (reconcile.Result, error) {
 	if nodeClaim.StatusConditions().GetCondition(v1beta1.NodeInitialized).IsTrue() {
@@ -68,10 +70,18 @@ func (i *Initialization) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeC
 		nodeClaim.StatusConditions().MarkFalse(v1beta1.NodeInitialized, "KnownEphemeralTaintsExist", "KnownEphemeralTaint %q still exists", formatTaint(taint))
 		return reconcile.Result{}, nil
 	}
-	if name, ok := RequestedResourcesRegistered(node, nodeClaim); !ok {
+	if name, ok := RequestedResourcesRegistered(ctx, node, nodeClaim); !ok {
 		nodeClaim.StatusConditions().MarkFalse(v1beta1.NodeInitialized, "ResourceNotRegistered", "Resource %q was requested but not registered", name)
 		return reconcile.Result{}, nil
 	}
+	nodePool, err := nodeclaimutil.Owner(ctx, i.kubeClient, nodeClaim)
+	if client.IgnoreNotFound(err) != nil {
+		return reconcile.Result{}, fmt.Errorf("getting owner, %w", err)
+	}
+	if gate, ok := ReadinessGatesSatisfied(node, nodePool); !ok {
+		nodeClaim.StatusConditions().MarkFalse(v1beta1.NodeInitialized, "ReadinessGateNotSatisfied", "ReadinessGate %s is not satisfied", formatReadinessGate(gate))
+		return reconcile.Result{}, nil
+	}
 	stored := node.DeepCopy()
 	node.Labels = lo.Assign(node.Labels, map[string]string{v1beta1.NodeInitializedLabelKey: "true"})
 	if !equality.Semantic.DeepEqual(stored, node) {
@@ -82,6 +92,7 @@ func (i *Initialization) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeC
 	logging.FromContext(ctx).Debugf("initialized %s", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"))
 	nodeClaim.StatusConditions().MarkTrue(v1beta1.NodeInitialized)
 	nodeclaimutil.InitializedCounter(nodeClaim).Inc()
+	nodeclaimutil.PhaseDurationHistogram(nodeClaim, "initialized").Observe(phaseDuration(nodeClaim, v1beta1.NodeRegistered, v1beta1.NodeInitialized).Seconds())
 	return reconcile.Result{}, nil
 }
 
@@ -114,11 +125,35 @@ func StartupTaintsRemoved(node *v1.Node, nodeClaim *v1beta1.NodeClaim) (*v1.Tain
 	return nil, true
 }
 
+// ReadinessGatesSatisfied returns true if there are no readiness gates configured for the nodePool, or if all the
+// readiness gates are satisfied by the node
+func ReadinessGatesSatisfied(node *v1.Node, nodePool *v1beta1.NodePool) (*v1beta1.ReadinessGate, bool) {
+	if nodePool != nil {
+		for i := range nodePool.Spec.ReadinessGates {
+			if !nodePool.Spec.ReadinessGates[i].IsSatisfied(node) {
+				return &nodePool.Spec.ReadinessGates[i], false
+			}
+		}
+	}
+	return nil, true
+}
+
 // RequestedResourcesRegistered returns true if there are no extended resources on the node, or they have all been
-// registered by device plugins
-func RequestedResourcesRegistered(node *v1.Node, nodeClaim *v1beta1.NodeClaim) (v1.ResourceName, bool) {
+// registered by device plugins. Resources that are required by the initializationRequiredResources setting are waited
+// on even if they weren't requested, as long as the launched instance type advertises them in its capacity, and
+// resources ignored by the initializationIgnoredResources setting never are.
+func RequestedResourcesRegistered(ctx context.Context, node *v1.Node, nodeClaim *v1beta1.NodeClaim) (v1.ResourceName, bool) {
+	for _, name := range settings.FromContext(ctx).InitializationRequiredResources {
+		if resources.IsZero(nodeClaim.Status.Capacity[v1.ResourceName(name)]) {
+			continue
+		}
+		if resources.IsZero(node.Status.Allocatable[v1.ResourceName(name)]) {
+			return v1.ResourceName(name), false
+		}
+	}
+	ignored := settings.FromContext(ctx).InitializationIgnoredResources
 	for resourceName, quantity := range nodeClaim.Spec.Resources.Requests {
-		if quantity.IsZero() {
+		if quantity.IsZero() || lo.Contains(ignored, string(resourceName)) {
 			continue
 		}
 		// kubelet will zero out both the capacity and allocatable for an extended resource on startup, so if our
@@ -141,3 +176,13 @@ func formatTaint(taint *v1.Taint) string {
 	}
 	return fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect)
 }
+
+func formatReadinessGate(gate *v1beta1.ReadinessGate) string {
+	if gate == nil {
+		return "<nil>"
+	}
+	if gate.TaintKey != "" {
+		return fmt.Sprintf("taint %q", gate.TaintKey)
+	}
+	return fmt.Sprintf("condition %q=%s", gate.ConditionType, lo.Ternary(gate.Status == "", v1.ConditionTrue, gate.Status))
+}
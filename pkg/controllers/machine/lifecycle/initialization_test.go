@@ -15,13 +15,16 @@ limitations under the License.
 package lifecycle_test
 
 import (
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	cloudproviderapi "k8s.io/cloud-provider/api"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	"github.com/aws/karpenter-core/pkg/test"
 
@@ -87,6 +90,12 @@ var _ = Describe("Initialization", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineRegistered).Status).To(Equal(v1.ConditionTrue))
 		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
+
+		for _, phase := range []string{"registered", "initialized"} {
+			m, found := FindMetricWithLabelValues("karpenter_machines_phase_duration_seconds", map[string]string{"phase": phase, "provisioner": provisioner.Name})
+			Expect(found).To(BeTrue())
+			Expect(m.GetHistogram().GetSampleCount()).To(BeNumerically("==", 1))
+		}
 	})
 	It("should add the initialization label to the node when the Machine is initialized", func() {
 		machine := test.Machine(v1alpha5.Machine{
@@ -568,4 +577,191 @@ var _ = Describe("Initialization", func() {
 		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineRegistered).Status).To(Equal(v1.ConditionTrue))
 		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
 	})
+	It("should consider the Node to be initialized once the readiness gate conditions are satisfied", func() {
+		provisioner.Spec.ReadinessGates = []v1alpha5.ReadinessGate{{ConditionType: "NetworkAvailable"}}
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		node := test.Node(test.NodeOptions{ProviderID: machine.Status.ProviderID})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectMakeNodesReady(ctx, env.Client, node) // Remove the not-ready taint
+
+		// Shouldn't consider the node initialized since the condition hasn't been reported
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionFalse))
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Reason).To(Equal("ReadinessGateNotSatisfied"))
+
+		node = ExpectExists(ctx, env.Client, node)
+		node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: "NetworkAvailable", Status: v1.ConditionFalse})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionFalse))
+
+		node = ExpectExists(ctx, env.Client, node)
+		for i := range node.Status.Conditions {
+			if node.Status.Conditions[i].Type == "NetworkAvailable" {
+				node.Status.Conditions[i].Status = v1.ConditionTrue
+			}
+		}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
+	})
+	It("should consider the Node to be initialized once the readiness gate taints are removed", func() {
+		provisioner.Spec.ReadinessGates = []v1alpha5.ReadinessGate{{TaintKey: "cilium.io/agent-not-ready"}}
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		node := test.Node(test.NodeOptions{
+			ProviderID: machine.Status.ProviderID,
+			Taints: []v1.Taint{
+				{
+					Key:    "cilium.io/agent-not-ready",
+					Effect: v1.TaintEffectNoExecute,
+					Value:  "true",
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectMakeNodesReady(ctx, env.Client, node) // Remove the not-ready taint
+
+		// Shouldn't consider the node initialized since the readiness gate taint still exists
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionFalse))
+
+		node = ExpectExists(ctx, env.Client, node)
+		node.Spec.Taints = lo.Reject(node.Spec.Taints, func(t v1.Taint, _ int) bool { return t.Key == "cilium.io/agent-not-ready" })
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
+	})
+	It("should consider the Node to be initialized when an ignored resource isn't registered", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{InitializationIgnoredResources: []string{string(fake.ResourceGPUVendorA)}}))
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+			Spec: v1alpha5.MachineSpec{
+				Resources: v1alpha5.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:          resource.MustParse("2"),
+						fake.ResourceGPUVendorA: resource.MustParse("1"),
+					},
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		// Extended resource hasn't registered yet by the daemonset
+		node := test.Node(test.NodeOptions{
+			ProviderID: machine.Status.ProviderID,
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("8"),
+			},
+		})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectMakeNodesReady(ctx, env.Client, node) // Remove the not-ready taint
+
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
+	})
+	It("should not consider the Node to be initialized until a required resource is registered", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{InitializationRequiredResources: []string{"vendor.com/fpga"}}))
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "fpga-instance-type",
+				Resources: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("8"),
+					v1.ResourcePods:   resource.MustParse("110"),
+					"vendor.com/fpga": resource.MustParse("1"),
+				},
+			}),
+		}
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		node := test.Node(test.NodeOptions{
+			ProviderID: machine.Status.ProviderID,
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("8"),
+			},
+		})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectMakeNodesReady(ctx, env.Client, node) // Remove the not-ready taint
+
+		// The required resource wasn't requested, but hasn't been registered yet
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionFalse))
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Reason).To(Equal("ResourceNotRegistered"))
+
+		node = ExpectExists(ctx, env.Client, node)
+		node.Status.Capacity["vendor.com/fpga"] = resource.MustParse("1")
+		node.Status.Allocatable["vendor.com/fpga"] = resource.MustParse("1")
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
+	})
+	It("should not wait on a required resource that the instance type doesn't advertise", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{InitializationRequiredResources: []string{"vendor.com/fpga"}}))
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.Capacity).ToNot(HaveKey(v1.ResourceName("vendor.com/fpga")))
+
+		node := test.Node(test.NodeOptions{
+			ProviderID: machine.Status.ProviderID,
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("8"),
+			},
+		})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectMakeNodesReady(ctx, env.Client, node) // Remove the not-ready taint
+
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineInitialized).Status).To(Equal(v1.ConditionTrue))
+	})
 })
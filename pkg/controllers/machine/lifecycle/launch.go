@@ -16,7 +16,10 @@ package lifecycle
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
@@ -25,26 +28,56 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/unavailableofferings"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/metrics"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	provisionerutil "github.com/aws/karpenter-core/pkg/utils/provisioner"
 )
 
+// rateLimitedRequeueDelay is how long we wait before retrying a launch that was rate limited by the CloudProvider
+const rateLimitedRequeueDelay = time.Second * 30
+
+// maxLaunchRetryDelay caps the exponential backoff between launch retries
+const maxLaunchRetryDelay = time.Minute * 5
+
 type Launch struct {
 	kubeClient    client.Client
 	cloudProvider cloudprovider.CloudProvider
+	cluster       *state.Cluster
 	cache         *cache.Cache // exists due to eventual consistency on the cache
+	attempts      *cache.Cache // number of failed launch attempts of each NodeClaim
 	recorder      events.Recorder
+	batcher       *createBatcher // nil if the CloudProvider doesn't implement BatchCreate
+}
+
+// launchRetryError is returned when a failed launch should be retried after a delay
+type launchRetryError struct {
+	error
+	delay time.Duration
 }
 
 func (l *Launch) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (reconcile.Result, error) {
 	if nodeClaim.StatusConditions().GetCondition(v1beta1.NodeLaunched).IsTrue() {
 		return reconcile.Result{}, nil
 	}
+	// Launches that failed with a terminal error aren't retried, the NodeClaim is removed by liveness once it fails
+	// to register within the registration TTL so that capacity is provisioned again after a backoff
+	if launched := nodeClaim.StatusConditions().GetCondition(v1beta1.NodeLaunched); launched.IsFalse() &&
+		lo.Contains([]string{cloudprovider.InvalidTemplateErrorClass, cloudprovider.UnauthorizedErrorClass}, launched.Reason) {
+		return reconcile.Result{}, nil
+	}
+	// Launches that exhausted their retries are garbage collected
+	if nodeClaim.StatusConditions().GetCondition(v1beta1.NodeLaunchFailed).IsTrue() {
+		return reconcile.Result{}, nil
+	}
 
 	var err error
 	var created *v1beta1.NodeClaim
@@ -65,12 +98,28 @@ func (l *Launch) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (r
 	}
 	// Either the Node launch failed or the Node was deleted due to InsufficientCapacity/NotFound
 	if err != nil || created == nil {
+		// Calls that were rate limited are retried after the delay that the CloudProvider asked for, or a fixed delay,
+		// rather than with the exponential backoff
+		if cloudprovider.IsRateLimitedError(err) {
+			return reconcile.Result{RequeueAfter: rateLimitedDelay(err)}, nil
+		}
+		if retryErr := (&launchRetryError{}); errors.As(err, &retryErr) {
+			logging.FromContext(ctx).With("delay", retryErr.delay).Errorf("retrying launch, %s", retryErr)
+			return reconcile.Result{RequeueAfter: retryErr.delay}, nil
+		}
 		return reconcile.Result{}, err
 	}
+	l.attempts.Delete(string(nodeClaim.UID))
 	l.cache.SetDefault(string(nodeClaim.UID), created)
 	nodeClaim = PopulateNodeClaimDetails(nodeClaim, created)
+	if err = l.populatePrice(ctx, nodeClaim); err != nil {
+		// The price is informational, so we don't block the launch on failing to resolve it
+		logging.FromContext(ctx).Errorf("resolving launch price, %s", err)
+	}
 	nodeClaim.StatusConditions().MarkTrue(v1beta1.NodeLaunched)
 	nodeclaimutil.LaunchedCounter(nodeClaim).Inc()
+	nodeclaimutil.PhaseDurationHistogram(nodeClaim, "launched").Observe(
+		nodeClaim.StatusConditions().GetCondition(v1beta1.NodeLaunched).LastTransitionTime.Inner.Sub(nodeClaim.CreationTimestamp.Time).Seconds())
 
 	return reconcile.Result{}, nil
 }
@@ -88,6 +137,7 @@ func (l *Launch) linkNodeClaim(ctx context.Context, nodeClaim *v1beta1.NodeClaim
 		}
 		logging.FromContext(ctx).Debugf("garbage collected with no cloudprovider representation")
 		nodeclaimutil.TerminatedCounter(nodeClaim, "garbage_collected").Inc()
+		nodeclaimutil.FailedCounter(nodeClaim, metrics.TerminatedExternallyReason).Inc()
 		return nil, nil
 	}
 	logging.FromContext(ctx).With(
@@ -96,24 +146,46 @@ func (l *Launch) linkNodeClaim(ctx context.Context, nodeClaim *v1beta1.NodeClaim
 		"zone", created.Labels[v1.LabelTopologyZone],
 		"capacity-type", created.Labels[v1alpha5.LabelCapacityType],
 		"allocatable", created.Status.Allocatable).Infof("linked %s", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"))
+	// The node may have registered with a different format of the provider ID than the CloudProvider returns, so we keep
+	// the node's format to ensure that the node is still matched to the NodeClaim by its provider ID
+	if linkedID := nodeClaim.Annotations[v1alpha5.MachineLinkedAnnotationKey]; created.Status.ProviderID != linkedID &&
+		cloudprovider.NormalizeProviderID(l.cloudProvider, created.Status.ProviderID) == cloudprovider.NormalizeProviderID(l.cloudProvider, linkedID) {
+		created.Status.ProviderID = linkedID
+	}
 	return nodeclaimutil.New(created), nil
 }
 
 func (l *Launch) launchNodeClaim(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (*v1beta1.NodeClaim, error) {
-	created, err := l.cloudProvider.Create(ctx, machineutil.NewFromNodeClaim(nodeClaim))
+	created, err := l.create(ctx, nodeClaim)
 	if err != nil {
 		switch {
-		case cloudprovider.IsInsufficientCapacityError(err):
-			l.recorder.Publish(InsufficientCapacityErrorEvent(nodeClaim, err))
+		case cloudprovider.IsInsufficientCapacityError(err), cloudprovider.IsQuotaExceededError(err):
+			l.recorder.Publish(LaunchFailedEvent(nodeClaim, err))
+			reason := lo.Ternary(cloudprovider.IsQuotaExceededError(err), "quota_exceeded", "insufficient_capacity")
+			// Offerings that ran out of capacity are excluded from scheduling, so the owner is only demoted in favor of lower
+			// weight owners when the failure can't be attributed to specific offerings
+			demote := cloudprovider.IsQuotaExceededError(err) || len(unavailableofferings.Offerings(machineutil.NewFromNodeClaim(nodeClaim), err)) == 0
+			l.cluster.MarkLaunchFailed(nodeclaimutil.OwnerKey(nodeClaim), err, demote)
 			logging.FromContext(ctx).Error(err)
 			if err = nodeclaimutil.Delete(ctx, l.kubeClient, nodeClaim); err != nil {
 				return nil, client.IgnoreNotFound(err)
 			}
-			nodeclaimutil.TerminatedCounter(nodeClaim, "insufficient_capacity").Inc()
+			nodeclaimutil.TerminatedCounter(nodeClaim, reason).Inc()
+			nodeclaimutil.LaunchFailedCounter(nodeClaim).Inc()
+			return nil, nil
+		case cloudprovider.IsRateLimitedError(err):
+			nodeClaim.StatusConditions().MarkFalse(v1beta1.NodeLaunched, cloudprovider.RateLimitedErrorClass, truncateMessage(err.Error()))
+			logging.FromContext(ctx).With("delay", rateLimitedDelay(err)).Debugf("retrying launch, %s", err)
+			return nil, err
+		case cloudprovider.IsTerminalError(err):
+			nodeClaim.StatusConditions().MarkFalse(v1beta1.NodeLaunched, cloudprovider.ErrorClass(err), truncateMessage(err.Error()))
+			l.recorder.Publish(LaunchFailedEvent(nodeClaim, err))
+			logging.FromContext(ctx).Error(err)
+			nodeclaimutil.LaunchFailedCounter(nodeClaim).Inc()
 			return nil, nil
 		default:
 			nodeClaim.StatusConditions().MarkFalse(v1beta1.NodeLaunched, "LaunchFailed", truncateMessage(err.Error()))
-			return nil, fmt.Errorf("creating %s, %w", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), err)
+			return nil, l.retry(ctx, nodeClaim, fmt.Errorf("creating %s, %w", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), err))
 		}
 	}
 	logging.FromContext(ctx).With(
@@ -125,6 +197,45 @@ func (l *Launch) launchNodeClaim(ctx context.Context, nodeClaim *v1beta1.NodeCla
 	return nodeclaimutil.New(created), nil
 }
 
+// create launches the NodeClaim, together with the other NodeClaims of its owner that are launched at the same time if
+// the CloudProvider implements BatchCreate
+func (l *Launch) create(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (*v1alpha5.Machine, error) {
+	if l.batcher == nil {
+		return l.cloudProvider.Create(ctx, machineutil.NewFromNodeClaim(nodeClaim))
+	}
+	return l.batcher.Create(nodeclaimutil.OwnerKey(nodeClaim), machineutil.NewFromNodeClaim(nodeClaim))
+}
+
+// retry returns a launchRetryError with an exponential backoff for the failed launch. Once the launch has been retried
+// LaunchRetryLimit times, the NodeClaim is marked as failed to launch so that it's garbage collected and no error is
+// returned.
+func (l *Launch) retry(ctx context.Context, nodeClaim *v1beta1.NodeClaim, err error) error {
+	attempts := 1
+	if ret, ok := l.attempts.Get(string(nodeClaim.UID)); ok {
+		attempts = ret.(int) + 1
+	}
+	if attempts > settings.FromContext(ctx).LaunchRetryLimit {
+		l.attempts.Delete(string(nodeClaim.UID))
+		nodeClaim.StatusConditions().MarkTrueWithReason(v1beta1.NodeLaunchFailed, "RetriesExhausted", truncateMessage(err.Error()))
+		l.recorder.Publish(LaunchRetriesExhaustedEvent(nodeClaim, attempts, err))
+		logging.FromContext(ctx).With("attempts", attempts).Errorf("launch failed, %s", err)
+		nodeclaimutil.LaunchFailedCounter(nodeClaim).Inc()
+		return nil
+	}
+	l.attempts.SetDefault(string(nodeClaim.UID), attempts)
+	nodeclaimutil.LaunchRetriedCounter(nodeClaim).Inc()
+	return &launchRetryError{error: err, delay: retryDelay(settings.FromContext(ctx).LaunchRetryBaseDelay, attempts)}
+}
+
+// retryDelay doubles the base delay for each attempt after the first, up to maxLaunchRetryDelay
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxLaunchRetryDelay; i++ {
+		delay *= 2
+	}
+	return lo.Min([]time.Duration{delay, maxLaunchRetryDelay})
+}
+
 func PopulateNodeClaimDetails(nodeClaim, retrieved *v1beta1.NodeClaim) *v1beta1.NodeClaim {
 	// These are ordered in priority order so that user-defined nodeClaim labels and requirements trump retrieved labels
 	// or the static nodeClaim labels
@@ -137,12 +248,61 @@ func PopulateNodeClaimDetails(nodeClaim, retrieved *v1beta1.NodeClaim) *v1beta1.
 	nodeClaim.Status.ProviderID = retrieved.Status.ProviderID
 	nodeClaim.Status.Allocatable = retrieved.Status.Allocatable
 	nodeClaim.Status.Capacity = retrieved.Status.Capacity
+	nodeClaim.Status.InstanceType = nodeClaim.Labels[v1.LabelInstanceTypeStable]
+	nodeClaim.Status.Zone = nodeClaim.Labels[v1.LabelTopologyZone]
+	nodeClaim.Status.CapacityType = nodeClaim.Labels[v1beta1.CapacityTypeLabelKey]
 	return nodeClaim
 }
 
+// populatePrice resolves the price of the offering that the NodeClaim was launched with, from the CloudProvider's Pricing
+// or else from the instance types of its owner, and records it on the NodeClaim's status and annotations
+func (l *Launch) populatePrice(ctx context.Context, nodeClaim *v1beta1.NodeClaim) error {
+	if nodeClaim.Status.Price != "" || nodeClaim.Status.InstanceType == "" {
+		return nil
+	}
+	if price, ok := cloudprovider.Price(l.cloudProvider, nodeClaim.Status.InstanceType, nodeClaim.Status.CapacityType, nodeClaim.Status.Zone); ok {
+		setPrice(nodeClaim, price)
+		return nil
+	}
+	nodePool, err := nodeclaimutil.Owner(ctx, l.kubeClient, nodeClaim)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	instanceTypes, err := l.cloudProvider.GetInstanceTypes(ctx, provisionerutil.New(nodePool))
+	if err != nil {
+		return fmt.Errorf("getting instance types, %w", err)
+	}
+	instanceType, ok := lo.Find(instanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == nodeClaim.Status.InstanceType })
+	if !ok {
+		return fmt.Errorf("instance type %q not found", nodeClaim.Status.InstanceType)
+	}
+	offering, ok := instanceType.Offerings.Get(nodeClaim.Status.CapacityType, nodeClaim.Status.Zone)
+	if !ok {
+		return fmt.Errorf("unable to determine offering for %s/%s/%s", nodeClaim.Status.InstanceType, nodeClaim.Status.CapacityType, nodeClaim.Status.Zone)
+	}
+	setPrice(nodeClaim, offering.Price)
+	return nil
+}
+
+func setPrice(nodeClaim *v1beta1.NodeClaim, price float64) {
+	nodeClaim.Status.Price = strconv.FormatFloat(price, 'f', -1, 64)
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1beta1.LaunchPriceAnnotationKey: nodeClaim.Status.Price,
+	})
+}
+
 func truncateMessage(msg string) string {
 	if len(msg) < 300 {
 		return msg
 	}
 	return msg[:300] + "..."
 }
+
+// rateLimitedDelay returns how long to wait before retrying a launch that was rate limited, preferring the delay that
+// the CloudProvider asked for
+func rateLimitedDelay(err error) time.Duration {
+	if retryAfter := cloudprovider.RetryAfter(err); retryAfter > 0 {
+		return retryAfter
+	}
+	return rateLimitedRequeueDelay
+}
@@ -16,15 +16,26 @@ package lifecycle_test
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	nodeclaimlifecycle "github.com/aws/karpenter-core/pkg/controllers/machine/lifecycle"
+	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/test"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -55,6 +66,44 @@ var _ = Describe("Launch", func() {
 		_, err := cloudProvider.Get(ctx, machine.Status.ProviderID)
 		Expect(err).ToNot(HaveOccurred())
 	})
+	It("should not launch an instance when the Machine is paused", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+				Annotations: map[string]string{
+					v1alpha5.MachinePausedAnnotationKey: "true",
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched)).To(BeNil())
+	})
+	It("should record how long the Machine took to launch", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		m, found := FindMetricWithLabelValues("karpenter_machines_phase_duration_seconds", map[string]string{
+			"phase":         "launched",
+			"provisioner":   provisioner.Name,
+			"instance_type": machine.Labels[v1.LabelInstanceTypeStable],
+		})
+		Expect(found).To(BeTrue())
+		Expect(m.GetHistogram().GetSampleCount()).To(BeNumerically("==", 1))
+	})
 	It("should add the MachineLaunched status condition after creating the Machine", func() {
 		machine := test.Machine(v1alpha5.Machine{
 			ObjectMeta: metav1.ObjectMeta{
@@ -69,6 +118,53 @@ var _ = Describe("Launch", func() {
 		machine = ExpectExists(ctx, env.Client, machine)
 		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineLaunched).Status).To(Equal(v1.ConditionTrue))
 	})
+	It("should populate the offering details of the launched Machine", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.InstanceType).ToNot(BeEmpty())
+		Expect(machine.Status.InstanceType).To(Equal(machine.Labels[v1.LabelInstanceTypeStable]))
+		Expect(machine.Status.Zone).To(Equal(machine.Labels[v1.LabelTopologyZone]))
+		Expect(machine.Status.CapacityType).To(Equal(machine.Labels[v1alpha5.LabelCapacityType]))
+
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		instanceType, ok := lo.Find(instanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == machine.Status.InstanceType })
+		Expect(ok).To(BeTrue())
+		offering, ok := instanceType.Offerings.Get(machine.Status.CapacityType, machine.Status.Zone)
+		Expect(ok).To(BeTrue())
+		Expect(machine.Status.Price).To(Equal(strconv.FormatFloat(offering.Price, 'f', -1, 64)))
+		Expect(machine.Annotations).To(HaveKeyWithValue(v1alpha5.LaunchPriceAnnotationKey, machine.Status.Price))
+	})
+	It("should populate the price of the launched Machine from the CloudProvider's pricing", func() {
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, provisioner)
+		Expect(err).ToNot(HaveOccurred())
+		for _, it := range instanceTypes {
+			cloudProvider.OnDemandPrices[it.Name] = 0.123
+			cloudProvider.SpotPrices[it.Name] = 0.123
+		}
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.Price).To(Equal("0.123"))
+		Expect(machine.Annotations).To(HaveKeyWithValue(v1alpha5.LaunchPriceAnnotationKey, "0.123"))
+	})
 	It("should link an instance with the karpenter.sh/linked annotation", func() {
 		cloudProviderMachine := &v1alpha5.Machine{
 			ObjectMeta: metav1.ObjectMeta{
@@ -118,6 +214,41 @@ var _ = Describe("Launch", func() {
 		Expect(machine.Labels).To(HaveKeyWithValue(v1.LabelTopologyRegion, "test-zone"))
 		Expect(machine.Labels).To(HaveKeyWithValue(v1alpha5.LabelCapacityType, v1alpha5.CapacityTypeSpot))
 	})
+	It("should keep the provider ID format of the linked node when the cloudprovider uses a different format", func() {
+		// The cloudprovider reports provider IDs in the "fake" format while the node registered with a legacy format
+		cloudProvider.ProviderIDFormat = func(id string) string { return strings.Replace(id, "legacy:///", "fake:///", 1) }
+		cloudProviderMachine := &v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1.LabelInstanceTypeStable: "small-instance-type",
+					v1.LabelTopologyZone:       "test-zone-1a",
+					v1alpha5.LabelCapacityType: v1alpha5.CapacityTypeSpot,
+				},
+			},
+			Status: v1alpha5.MachineStatus{
+				ProviderID: test.RandomProviderID(),
+			},
+		}
+		cloudProvider.CreatedMachines[cloudProviderMachine.Status.ProviderID] = cloudProviderMachine
+		legacyProviderID := strings.Replace(cloudProviderMachine.Status.ProviderID, "fake:///", "legacy:///", 1)
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1alpha5.MachineLinkedAnnotationKey: legacyProviderID,
+				},
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		node := test.Node(test.NodeOptions{ProviderID: legacyProviderID})
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Status.ProviderID).To(Equal(legacyProviderID))
+		Expect(ExpectStatusConditionExists(machine, v1alpha5.MachineRegistered).Status).To(Equal(v1.ConditionTrue))
+	})
 	It("should delete the machine if InsufficientCapacity is returned from the cloudprovider", func() {
 		cloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all instance types were unavailable"))
 		machine := test.Machine()
@@ -126,4 +257,244 @@ var _ = Describe("Launch", func() {
 		ExpectFinalizersRemoved(ctx, env.Client, machine)
 		ExpectNotFound(ctx, env.Client, machine)
 	})
+	It("should demote the provisioner if InsufficientCapacity is returned from the cloudprovider without the unavailable offerings", func() {
+		cloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all instance types were unavailable"))
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		failure := cluster.LaunchFailure(nodepoolutil.Key{Name: provisioner.Name, IsProvisioner: true})
+		Expect(failure).ToNot(BeNil())
+		Expect(failure.Demoted).To(BeTrue())
+		Expect(failure.Err).To(MatchError(ContainSubstring("all instance types were unavailable")))
+	})
+	It("should not demote the provisioner if InsufficientCapacity is returned from the cloudprovider with the unavailable offerings", func() {
+		cloudProvider.NextCreateErr = cloudprovider.NewInsufficientCapacityError(fmt.Errorf("spot capacity was unavailable"), cloudprovider.UnavailableOffering{
+			InstanceType: "default-instance-type",
+			Zone:         "test-zone-1",
+			CapacityType: v1alpha5.CapacityTypeSpot,
+		})
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		failure := cluster.LaunchFailure(nodepoolutil.Key{Name: provisioner.Name, IsProvisioner: true})
+		Expect(failure).ToNot(BeNil())
+		Expect(failure.Demoted).To(BeFalse())
+	})
+	It("should delete the machine if QuotaExceeded is returned from the cloudprovider", func() {
+		cloudProvider.NextCreateErr = cloudprovider.NewQuotaExceededError(fmt.Errorf("vcpu limit exceeded"))
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectFinalizersRemoved(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine)
+	})
+	It("should retry the launch after a delay if RateLimited is returned from the cloudprovider", func() {
+		cloudProvider.NextCreateErr = cloudprovider.NewRateLimitedError(fmt.Errorf("request limit exceeded"))
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		result := ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsFalse()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).Reason).To(Equal(cloudprovider.RateLimitedErrorClass))
+
+		// The next launch attempt succeeds
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsTrue()).To(BeTrue())
+	})
+	It("should retry the launch after the delay that the cloudprovider asked for when it's throttled", func() {
+		cloudProvider.ThrottledCalls = 1
+		cloudProvider.ThrottleRetryAfter = 5 * time.Second
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		result := ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsTrue()).To(BeTrue())
+	})
+	It("should delete the machine if every offering that it can launch into is out of capacity", func() {
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name: "out-of-capacity",
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1.0, Available: true},
+				},
+			}),
+		}
+		cloudProvider.InsufficientCapacityOfferings = []cloudprovider.UnavailableOffering{
+			{InstanceType: "out-of-capacity", Zone: "test-zone-1", CapacityType: v1alpha5.CapacityTypeOnDemand},
+		}
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectFinalizersRemoved(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine)
+	})
+	It("should not retry the launch if InvalidTemplate is returned from the cloudprovider", func() {
+		cloudProvider.NextCreateErr = cloudprovider.NewInvalidTemplateError(fmt.Errorf("image not found"))
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsFalse()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).Reason).To(Equal(cloudprovider.InvalidTemplateErrorClass))
+
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsFalse()).To(BeTrue())
+	})
+	It("should not retry the launch if Unauthorized is returned from the cloudprovider", func() {
+		cloudProvider.NextCreateErr = cloudprovider.NewUnauthorizedError(fmt.Errorf("access denied"))
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).Reason).To(Equal(cloudprovider.UnauthorizedErrorClass))
+
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+	})
+	It("should retry a failed launch with an exponential backoff", func() {
+		cloudProvider.AllowedCreateCalls = 0
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		result := ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsFalse()).To(BeTrue())
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunched).Reason).To(Equal("LaunchFailed"))
+
+		result = ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(2))
+	})
+	It("should cap the backoff between launch retries", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LaunchRetryLimit: 100, LaunchRetryBaseDelay: time.Minute}))
+		cloudProvider.AllowedCreateCalls = 0
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		for _, delay := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+			result := ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+			Expect(result.RequeueAfter).To(Equal(delay))
+		}
+	})
+	It("should mark the machine as failed to launch once its retries are exhausted", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{LaunchRetryLimit: 1}))
+		cloudProvider.AllowedCreateCalls = 0
+		machine := test.Machine()
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		result := ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(result.RequeueAfter).To(BeZero())
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.StatusConditions().GetCondition(v1alpha5.MachineLaunchFailed).IsTrue()).To(BeTrue())
+
+		// The launch isn't retried once it has failed
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(cloudProvider.CreateCalls).To(HaveLen(2))
+	})
+	Context("BatchCreate", func() {
+		It("should launch the machines of a provisioner that are created together with a single BatchCreate call", func() {
+			batchCloudProvider := &fake.BatchCloudProvider{CloudProvider: cloudProvider}
+			controller := nodeclaimlifecycle.NewMachineController(ctx, fakeClock, env.Client, batchCloudProvider, cluster, events.NewRecorder(&record.FakeRecorder{}))
+			machines := lo.Times(3, func(_ int) *v1alpha5.Machine {
+				return test.Machine(v1alpha5.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+						},
+					},
+				})
+			})
+			ExpectApplied(ctx, env.Client, provisioner)
+			for _, m := range machines {
+				ExpectApplied(ctx, env.Client, m)
+			}
+			var wg sync.WaitGroup
+			for _, m := range machines {
+				wg.Add(1)
+				go func(m *v1alpha5.Machine) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(m))
+				}(m)
+			}
+			wg.Wait()
+
+			Expect(batchCloudProvider.BatchCreateCalls).To(HaveLen(1))
+			Expect(batchCloudProvider.BatchCreateCalls[0]).To(HaveLen(3))
+			for _, m := range machines {
+				m = ExpectExists(ctx, env.Client, m)
+				Expect(m.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsTrue()).To(BeTrue())
+				Expect(m.Status.ProviderID).ToNot(BeEmpty())
+			}
+		})
+		It("should only fail the machines of a batch that the cloudprovider couldn't launch", func() {
+			batchCloudProvider := &fake.BatchCloudProvider{CloudProvider: cloudProvider}
+			batchCloudProvider.NextBatchCreateErrs = []error{nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all instance types were unavailable"))}
+			controller := nodeclaimlifecycle.NewMachineController(ctx, fakeClock, env.Client, batchCloudProvider, cluster, events.NewRecorder(&record.FakeRecorder{}))
+			machines := lo.Times(3, func(_ int) *v1alpha5.Machine {
+				return test.Machine(v1alpha5.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+						},
+					},
+				})
+			})
+			ExpectApplied(ctx, env.Client, provisioner)
+			for _, m := range machines {
+				ExpectApplied(ctx, env.Client, m)
+			}
+			var wg sync.WaitGroup
+			for _, m := range machines {
+				wg.Add(1)
+				go func(m *v1alpha5.Machine) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(m))
+				}(m)
+			}
+			wg.Wait()
+
+			Expect(batchCloudProvider.BatchCreateCalls).To(HaveLen(1))
+			launched := lo.CountBy(machines, func(m *v1alpha5.Machine) bool {
+				stored := &v1alpha5.Machine{}
+				if err := env.Client.Get(ctx, client.ObjectKeyFromObject(m), stored); err != nil {
+					return false
+				}
+				return stored.DeletionTimestamp.IsZero() && stored.StatusConditions().GetCondition(v1alpha5.MachineLaunched).IsTrue()
+			})
+			Expect(launched).To(Equal(2))
+			Expect(cloudProvider.CreateCalls).To(HaveLen(2))
+		})
+		It("should launch with Create when the CloudProvider doesn't implement BatchCreate", func() {
+			machines := []*v1alpha5.Machine{test.Machine(), test.Machine()}
+			created, errs := cloudprovider.BatchCreate(ctx, cloudProvider, machines)
+			Expect(errs).To(HaveEach(BeNil()))
+			Expect(created).To(HaveLen(2))
+			Expect(cloudProvider.CreateCalls).To(HaveLen(2))
+		})
+	})
 })
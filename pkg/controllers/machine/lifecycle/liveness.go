@@ -16,14 +16,18 @@ package lifecycle
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/samber/lo"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/metrics"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
 )
 
@@ -32,18 +36,18 @@ type Liveness struct {
 	kubeClient client.Client
 }
 
-// registrationTTL is a heuristic time that we expect the node to register within
-// If we don't see the node within this time, then we should delete the NodeClaim and try again
-const registrationTTL = time.Minute * 15
-
 func (l *Liveness) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (reconcile.Result, error) {
 	registered := nodeClaim.StatusConditions().GetCondition(v1beta1.NodeRegistered)
 	if registered.IsTrue() {
-		return reconcile.Result{}, nil
+		return l.initializationTimeout(ctx, nodeClaim, registered.LastTransitionTime.Inner.Time)
 	}
 	if registered == nil {
 		return reconcile.Result{Requeue: true}, nil
 	}
+	registrationTTL, err := l.registrationTTL(ctx, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
 	// If the NodeRegistered statusCondition hasn't gone True during the TTL since we first updated it, we should terminate the NodeClaim
 	if l.clock.Since(registered.LastTransitionTime.Inner.Time) < registrationTTL {
 		return reconcile.Result{RequeueAfter: registrationTTL - l.clock.Since(registered.LastTransitionTime.Inner.Time)}, nil
@@ -54,6 +58,44 @@ func (l *Liveness) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeClaim)
 	}
 	logging.FromContext(ctx).With("ttl", registrationTTL).Debugf("terminating due to registration ttl")
 	nodeclaimutil.TerminatedCounter(nodeClaim, "liveness").Inc()
+	nodeclaimutil.FailedCounter(nodeClaim, metrics.RegistrationTimeoutReason).Inc()
+
+	return reconcile.Result{}, nil
+}
 
+// initializationTimeout counts NodeClaims whose nodes registered but haven't initialized within the registration TTL
+// since registering. These NodeClaims aren't removed since the node may still become initialized, so they're annotated
+// to only be counted once.
+func (l *Liveness) initializationTimeout(ctx context.Context, nodeClaim *v1beta1.NodeClaim, registeredTime time.Time) (reconcile.Result, error) {
+	if nodeClaim.StatusConditions().GetCondition(v1beta1.NodeInitialized).IsTrue() {
+		return reconcile.Result{}, nil
+	}
+	if nodeClaim.Annotations[v1beta1.InitializationTimedOutAnnotationKey] == "true" {
+		return reconcile.Result{}, nil
+	}
+	registrationTTL, err := l.registrationTTL(ctx, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if l.clock.Since(registeredTime) < registrationTTL {
+		return reconcile.Result{RequeueAfter: registrationTTL - l.clock.Since(registeredTime)}, nil
+	}
+	logging.FromContext(ctx).With("ttl", registrationTTL).Debugf("node hasn't initialized within the registration ttl")
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.InitializationTimedOutAnnotationKey: "true"})
+	nodeclaimutil.FailedCounter(nodeClaim, metrics.InitializationTimeoutReason).Inc()
 	return reconcile.Result{}, nil
 }
+
+// registrationTTL is the time that we expect the node to register within. If we don't see the node within this time,
+// then we should delete the NodeClaim and try again. The TTL of the owning NodePool takes precedence over the global
+// setting.
+func (l *Liveness) registrationTTL(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (time.Duration, error) {
+	nodePool, err := nodeclaimutil.Owner(ctx, l.kubeClient, nodeClaim)
+	if client.IgnoreNotFound(err) != nil {
+		return 0, fmt.Errorf("getting owner, %w", err)
+	}
+	if nodePool != nil && nodePool.Spec.RegistrationTTL != nil {
+		return nodePool.Spec.RegistrationTTL.Duration, nil
+	}
+	return settings.FromContext(ctx).RegistrationTTL, nil
+}
@@ -20,13 +20,16 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	"github.com/aws/karpenter-core/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 
 	. "github.com/aws/karpenter-core/pkg/test/expectations"
 )
@@ -67,6 +70,32 @@ var _ = Describe("Liveness", func() {
 		ExpectExists(ctx, env.Client, machine)
 		ExpectExists(ctx, env.Client, node)
 	})
+	It("should count the Machine as failed once when the Node hasn't initialized past the registration ttl", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		node := test.MachineLinkedNode(machine)
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+
+		// The Machine isn't deleted since its node may still initialize, but it's annotated so that it's only counted once
+		fakeClock.Step(time.Minute * 20)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.Annotations).To(HaveKeyWithValue(v1alpha5.InitializationTimedOutAnnotationKey, "true"))
+		m, found := FindMetricWithLabelValues("karpenter_machines_failed", map[string]string{"reason": "initialization_timeout", "provisioner": provisioner.Name})
+		Expect(found).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+	})
 	It("should delete the Machine when the Node hasn't registered past the registration ttl", func() {
 		machine := test.Machine(v1alpha5.Machine{
 			ObjectMeta: metav1.ObjectMeta{
@@ -94,6 +123,9 @@ var _ = Describe("Liveness", func() {
 		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
 		ExpectFinalizersRemoved(ctx, env.Client, machine)
 		ExpectNotFound(ctx, env.Client, machine)
+		m, found := FindMetricWithLabelValues("karpenter_machines_failed", map[string]string{"reason": "registration_timeout", "provisioner": provisioner.Name})
+		Expect(found).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
 	})
 	It("should delete the Machine when the Machine hasn't launched past the registration ttl", func() {
 		machine := test.Machine(v1alpha5.Machine{
@@ -115,12 +147,63 @@ var _ = Describe("Liveness", func() {
 		})
 		cloudProvider.AllowedCreateCalls = 0 // Don't allow Create() calls to succeed
 		ExpectApplied(ctx, env.Client, provisioner, machine)
-		ExpectReconcileFailed(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
 		machine = ExpectExists(ctx, env.Client, machine)
 
 		// If the node hasn't registered in the registration timeframe, then we deprovision the Machine
 		fakeClock.Step(time.Minute * 20)
-		ExpectReconcileFailed(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectFinalizersRemoved(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine)
+	})
+	It("shouldn't delete the Machine before the registration ttl configured on the Provisioner", func() {
+		provisioner.Spec.RegistrationTTLSeconds = ptr.Int64(1800)
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+			Spec: v1alpha5.MachineSpec{
+				Resources: v1alpha5.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:          resource.MustParse("2"),
+						v1.ResourceMemory:       resource.MustParse("50Mi"),
+						v1.ResourcePods:         resource.MustParse("5"),
+						fake.ResourceGPUVendorA: resource.MustParse("1"),
+					},
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		// The Provisioner's registration ttl takes precedence over the global default
+		fakeClock.Step(time.Minute * 20)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectExists(ctx, env.Client, machine)
+
+		fakeClock.Step(time.Minute * 15)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectFinalizersRemoved(ctx, env.Client, machine)
+		ExpectNotFound(ctx, env.Client, machine)
+	})
+	It("should delete the Machine when the Node hasn't registered past the registration ttl configured in settings", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{RegistrationTTL: time.Minute * 5}))
+		machine := test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		fakeClock.Step(time.Minute * 10)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
 		ExpectFinalizersRemoved(ctx, env.Client, machine)
 		ExpectNotFound(ctx, env.Client, machine)
 	})
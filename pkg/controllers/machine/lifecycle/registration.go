@@ -71,6 +71,7 @@ func (r *Registration) Reconcile(ctx context.Context, nodeClaim *v1beta1.NodeCla
 	nodeClaim.Status.NodeName = node.Name
 
 	nodeclaimutil.RegisteredCounter(nodeClaim).Inc()
+	nodeclaimutil.PhaseDurationHistogram(nodeClaim, "registered").Observe(phaseDuration(nodeClaim, v1beta1.NodeLaunched, v1beta1.NodeRegistered).Seconds())
 	// If the NodeClaim is linked, then the node already existed, so we don't mark it as created
 	if _, ok := nodeClaim.Annotations[v1alpha5.MachineLinkedAnnotationKey]; !ok {
 		metrics.NodesCreatedCounter.With(prometheus.Labels{
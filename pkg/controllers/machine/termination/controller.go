@@ -24,6 +24,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -36,9 +37,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/events"
 	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
 	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
@@ -47,15 +50,19 @@ import (
 // Controller is a NodeClaim Termination controller that triggers deletion of the Node and the
 // CloudProvider NodeClaim through its graceful termination mechanism
 type Controller struct {
+	clock         clock.Clock
 	kubeClient    client.Client
 	cloudProvider cloudprovider.CloudProvider
+	recorder      events.Recorder
 }
 
 // NewController is a constructor for the NodeClaim Controller
-func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, recorder events.Recorder) *Controller {
 	return &Controller{
+		clock:         clk,
 		kubeClient:    kubeClient,
 		cloudProvider: cloudProvider,
+		recorder:      recorder,
 	}
 }
 
@@ -89,7 +96,13 @@ func (c *Controller) Finalize(ctx context.Context, nodeClaim *v1beta1.NodeClaim)
 	}
 	if nodeClaim.Status.ProviderID != "" || nodeClaim.Annotations[v1alpha5.MachineLinkedAnnotationKey] != "" {
 		if err = c.cloudProvider.Delete(ctx, machineutil.NewFromNodeClaim(nodeClaim)); cloudprovider.IgnoreMachineNotFoundError(err) != nil {
-			return reconcile.Result{}, fmt.Errorf("terminating cloudprovider instance, %w", err)
+			forced, forceErr := c.forceDelete(ctx, nodeClaim, err)
+			if forceErr != nil {
+				return reconcile.Result{}, forceErr
+			}
+			if !forced {
+				return reconcile.Result{}, fmt.Errorf("terminating cloudprovider instance, %w", err)
+			}
 		}
 	}
 	controllerutil.RemoveFinalizer(nodeClaim, v1beta1.TerminationFinalizer)
@@ -98,19 +111,45 @@ func (c *Controller) Finalize(ctx context.Context, nodeClaim *v1beta1.NodeClaim)
 			return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("removing termination finalizer, %w", err))
 		}
 		logging.FromContext(ctx).Infof("deleted %s", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"))
+		if !nodeClaim.DeletionTimestamp.IsZero() {
+			nodeclaimutil.PhaseDurationHistogram(nodeClaim, "terminated").Observe(c.clock.Since(nodeClaim.DeletionTimestamp.Time).Seconds())
+		}
 	}
 	return reconcile.Result{}, nil
 }
 
+// forceDelete returns true if the NodeClaim has been terminating for longer than the machineTerminationTimeout and the
+// CloudProvider confirms that its instance no longer exists, in which case its finalizer can be removed even though
+// deleting the instance failed
+func (c *Controller) forceDelete(ctx context.Context, nodeClaim *v1beta1.NodeClaim, deleteErr error) (bool, error) {
+	timeout := settings.FromContext(ctx).MachineTerminationTimeout
+	if timeout == 0 || nodeClaim.DeletionTimestamp.IsZero() || c.clock.Since(nodeClaim.DeletionTimestamp.Time) < timeout {
+		return false, nil
+	}
+	providerID := lo.Ternary(nodeClaim.Status.ProviderID != "", nodeClaim.Status.ProviderID, nodeClaim.Annotations[v1alpha5.MachineLinkedAnnotationKey])
+	_, err := c.cloudProvider.Get(ctx, providerID)
+	if err == nil {
+		// The instance still exists, so we keep retrying its deletion
+		return false, nil
+	}
+	if !cloudprovider.IsMachineNotFoundError(err) {
+		return false, fmt.Errorf("getting cloudprovider instance, %w", err)
+	}
+	c.recorder.Publish(ForceDeletedEvent(nodeClaim, timeout, deleteErr))
+	logging.FromContext(ctx).With("timeout", timeout).Errorf("removing termination finalizer after the instance wasn't found, %s", deleteErr)
+	nodeclaimutil.ForceDeletedCounter(nodeClaim).Inc()
+	return true, nil
+}
+
 var _ corecontroller.FinalizingTypedController[*v1beta1.NodeClaim] = (*NodeClaimController)(nil)
 
 type NodeClaimController struct {
 	*Controller
 }
 
-func NewNodeClaimController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) corecontroller.Controller {
+func NewNodeClaimController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, recorder events.Recorder) corecontroller.Controller {
 	return corecontroller.Typed[*v1beta1.NodeClaim](kubeClient, &NodeClaimController{
-		Controller: NewController(kubeClient, cloudProvider),
+		Controller: NewController(clk, kubeClient, cloudProvider, recorder),
 	})
 }
 
@@ -158,9 +197,9 @@ type MachineController struct {
 	*Controller
 }
 
-func NewMachineController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) corecontroller.Controller {
+func NewMachineController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, recorder events.Recorder) corecontroller.Controller {
 	return corecontroller.Typed[*v1alpha5.Machine](kubeClient, &MachineController{
-		Controller: NewController(kubeClient, cloudProvider),
+		Controller: NewController(clk, kubeClient, cloudProvider, recorder),
 	})
 }
 
@@ -16,6 +16,7 @@ package termination_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -38,6 +39,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	nodeclaimlifecycle "github.com/aws/karpenter-core/pkg/controllers/machine/lifecycle"
 	nodeclaimtermination "github.com/aws/karpenter-core/pkg/controllers/machine/termination"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/scheme"
@@ -50,6 +52,7 @@ var ctx context.Context
 var env *test.Environment
 var fakeClock *clock.FakeClock
 var cloudProvider *fake.CloudProvider
+var cluster *state.Cluster
 var machineController controller.Controller
 var terminationController controller.Controller
 
@@ -68,8 +71,9 @@ var _ = BeforeSuite(func() {
 	}))
 	ctx = settings.ToContext(ctx, test.Settings())
 	cloudProvider = fake.NewCloudProvider()
-	machineController = nodeclaimlifecycle.NewMachineController(fakeClock, env.Client, cloudProvider, events.NewRecorder(&record.FakeRecorder{}))
-	terminationController = nodeclaimtermination.NewMachineController(env.Client, cloudProvider)
+	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
+	machineController = nodeclaimlifecycle.NewMachineController(ctx, fakeClock, env.Client, cloudProvider, cluster, events.NewRecorder(&record.FakeRecorder{}))
+	terminationController = nodeclaimtermination.NewMachineController(fakeClock, env.Client, cloudProvider, events.NewRecorder(&record.FakeRecorder{}))
 })
 
 var _ = AfterSuite(func() {
@@ -80,6 +84,7 @@ var _ = AfterEach(func() {
 	fakeClock.SetTime(time.Now())
 	ExpectCleanedUp(ctx, env.Client)
 	cloudProvider.Reset()
+	cluster.Reset()
 })
 
 var _ = Describe("Termination", func() {
@@ -132,6 +137,10 @@ var _ = Describe("Termination", func() {
 		// Expect the machine to be gone from the cloudprovider
 		_, err = cloudProvider.Get(ctx, machine.Status.ProviderID)
 		Expect(cloudprovider.IsMachineNotFoundError(err)).To(BeTrue())
+
+		m, found := FindMetricWithLabelValues("karpenter_machines_phase_duration_seconds", map[string]string{"phase": "terminated", "provisioner": provisioner.Name})
+		Expect(found).To(BeTrue())
+		Expect(m.GetHistogram().GetSampleCount()).To(BeNumerically("==", 1))
 	})
 	It("should delete multiple Nodes if multiple Nodes map to the Machine", func() {
 		ExpectApplied(ctx, env.Client, provisioner, machine)
@@ -229,4 +238,50 @@ var _ = Describe("Termination", func() {
 			ExpectExists(ctx, env.Client, node)
 		}
 	})
+	It("should remove the finalizer after the termination timeout if the instance no longer exists", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{MachineTerminationTimeout: time.Minute * 15}))
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		cloudProvider.DeleteErr = fmt.Errorf("failed to terminate instance")
+		Expect(env.Client.Delete(ctx, machine)).To(Succeed())
+		ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(machine))
+		ExpectExists(ctx, env.Client, machine)
+
+		// The instance still exists, so the machine keeps waiting for it to terminate
+		fakeClock.Step(time.Minute * 20)
+		ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(machine))
+		ExpectExists(ctx, env.Client, machine)
+
+		// Once the instance is gone, the finalizer is removed even though Delete() still fails
+		delete(cloudProvider.CreatedMachines, machine.Status.ProviderID)
+		ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(machine))
+		ExpectNotFound(ctx, env.Client, machine)
+	})
+	It("should not remove the finalizer before the termination timeout", func() {
+		ctx := settings.ToContext(ctx, test.Settings(settings.Settings{MachineTerminationTimeout: time.Minute * 15}))
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		cloudProvider.DeleteErr = fmt.Errorf("failed to terminate instance")
+		delete(cloudProvider.CreatedMachines, machine.Status.ProviderID)
+		Expect(env.Client.Delete(ctx, machine)).To(Succeed())
+		fakeClock.Step(time.Minute * 10)
+		ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(machine))
+		ExpectExists(ctx, env.Client, machine)
+	})
+	It("should not remove the finalizer when the termination timeout is disabled", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		machine = ExpectExists(ctx, env.Client, machine)
+
+		cloudProvider.DeleteErr = fmt.Errorf("failed to terminate instance")
+		delete(cloudProvider.CreatedMachines, machine.Status.ProviderID)
+		Expect(env.Client.Delete(ctx, machine)).To(Succeed())
+		fakeClock.Step(time.Hour * 24)
+		ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(machine))
+		ExpectExists(ctx, env.Client, machine)
+	})
 })
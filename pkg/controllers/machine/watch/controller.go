@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/controllers/termination/terminator"
+	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+)
+
+// maxBatchSize is the maximum number of events that are handled in parallel in a single reconcile
+const maxBatchSize = 100
+
+// Controller consumes the machine events that the cloudprovider pushes and deletes the machines whose instances were
+// terminated or are being interrupted as soon as it's notified, rather than waiting for garbage collection or the
+// registration TTL to notice that the instance is gone.
+type Controller struct {
+	clock         clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+	watcher       cloudprovider.MachineWatcher
+	terminator    *terminator.Terminator
+
+	once   sync.Once
+	events <-chan cloudprovider.MachineEvent
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, watcher cloudprovider.MachineWatcher, terminator *terminator.Terminator) corecontroller.Controller {
+	return &Controller{
+		clock:         clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		watcher:       watcher,
+		terminator:    terminator,
+	}
+}
+
+func (c *Controller) Name() string {
+	return "machine.watch"
+}
+
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	c.once.Do(func() { c.events = c.watcher.WatchMachines(ctx) })
+
+	events, err := c.receive(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(events) == 0 {
+		return reconcile.Result{}, nil
+	}
+	nodeClaimList, err := nodeclaimutil.List(ctx, c.kubeClient)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing machines, %w", err)
+	}
+	nodeClaims := lo.SliceToMap(lo.Filter(lo.ToSlicePtr(nodeClaimList.Items), func(n *v1beta1.NodeClaim, _ int) bool {
+		return n.Status.ProviderID != ""
+	}), func(n *v1beta1.NodeClaim) (string, *v1beta1.NodeClaim) {
+		return cloudprovider.NormalizeProviderID(c.cloudProvider, n.Status.ProviderID), n
+	})
+	errs := make([]error, len(events))
+	workqueue.ParallelizeUntil(ctx, 10, len(events), func(i int) {
+		errs[i] = c.handleEvent(ctx, events[i], nodeClaims[cloudprovider.NormalizeProviderID(c.cloudProvider, events[i].ProviderID)])
+	})
+	return reconcile.Result{}, multierr.Combine(errs...)
+}
+
+// receive waits for an event and returns it along with the events that are already waiting, up to maxBatchSize
+func (c *Controller) receive(ctx context.Context) ([]cloudprovider.MachineEvent, error) {
+	var events []cloudprovider.MachineEvent
+	select {
+	case event, ok := <-c.events:
+		if !ok {
+			return nil, fmt.Errorf("machine event source was closed")
+		}
+		events = append(events, event)
+	case <-ctx.Done():
+		return nil, nil
+	}
+	for done := false; !done && len(events) < maxBatchSize; {
+		select {
+		case event, ok := <-c.events:
+			if ok {
+				events = append(events, event)
+			}
+			done = !ok
+		default:
+			done = true
+		}
+	}
+	return events, nil
+}
+
+func (c *Controller) handleEvent(ctx context.Context, event cloudprovider.MachineEvent, nodeClaim *v1beta1.NodeClaim) error {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("event-kind", event.Kind, "provider-id", event.ProviderID))
+	ReceivedEventsCounter.WithLabelValues(string(event.Kind)).Inc()
+	if !event.Time.IsZero() {
+		EventLatency.Observe(c.clock.Since(event.Time).Seconds())
+	}
+	// Launches are already observed through Create, we only need to act on machines that are going away
+	if event.Kind != cloudprovider.MachineTerminated && event.Kind != cloudprovider.MachineInterrupted {
+		return nil
+	}
+	// Karpenter doesn't manage the instance or is already terminating it, so there's nothing for us to do
+	if nodeClaim == nil || !nodeClaim.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), nodeClaim.Name))
+	// Interrupted machines are cordoned right away so that no new pods land on them while they're waiting to drain
+	if event.Kind == cloudprovider.MachineInterrupted {
+		node, err := nodeclaimutil.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+		if nodeclaimutil.IgnoreNodeNotFoundError(err) != nil {
+			return fmt.Errorf("getting node, %w", err)
+		}
+		if node != nil {
+			if err = c.cordon(ctx, node); err != nil {
+				return fmt.Errorf("cordoning node, %w", err)
+			}
+		}
+	}
+	if err := nodeclaimutil.Delete(ctx, c.kubeClient, nodeClaim); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("deleting %s, %w", lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"), err)
+	}
+	logging.FromContext(ctx).Infof("initiating delete for %s %s", lo.Ternary(event.Kind == cloudprovider.MachineTerminated, "terminated", "interrupted"), lo.Ternary(nodeClaim.IsMachine, "machine", "nodeclaim"))
+	nodeclaimutil.TerminatedCounter(nodeClaim, lo.Ternary(event.Kind == cloudprovider.MachineTerminated, "cloudprovider_terminated", "interrupted")).Inc()
+	return nil
+}
+
+func (c *Controller) cordon(ctx context.Context, node *v1.Node) error {
+	if !node.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	return client.IgnoreNotFound(c.terminator.Cordon(ctx, node))
+}
+
+func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.NewSingletonManagedBy(m)
+}
@@ -0,0 +1,52 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+const (
+	watchSubsystem = "machine_watch"
+	eventKindLabel = "event_kind"
+)
+
+var (
+	ReceivedEventsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: watchSubsystem,
+			Name:      "received_events",
+			Help:      "Count of machine events received from the cloudprovider. Labeled by event kind.",
+		},
+		[]string{eventKindLabel},
+	)
+	EventLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: watchSubsystem,
+			Name:      "event_latency_time_seconds",
+			Help:      "Length of time between the cloudprovider observing a machine event and Karpenter handling it.",
+			Buckets:   metrics.DurationBuckets(),
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(ReceivedEventsCounter, EventLatency)
+}
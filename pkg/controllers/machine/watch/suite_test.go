@@ -0,0 +1,196 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/machine/watch"
+	"github.com/aws/karpenter-core/pkg/controllers/termination/terminator"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/operator/controller"
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "knative.dev/pkg/logging/testing"
+
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var ctx context.Context
+var watchController controller.Controller
+var env *test.Environment
+var fakeClock *clock.FakeClock
+var cloudProvider *fake.CloudProvider
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MachineWatch")
+}
+
+var _ = BeforeSuite(func() {
+	ctx = settings.ToContext(ctx, test.Settings())
+	fakeClock = clock.NewFakeClock(time.Now())
+	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...), test.WithFieldIndexers(func(c cache.Cache) error {
+		return c.IndexField(ctx, &v1.Node{}, "spec.providerID", func(obj client.Object) []string {
+			return []string{obj.(*v1.Node).Spec.ProviderID}
+		})
+	}))
+
+	cloudProvider = fake.NewCloudProvider()
+	recorder := events.NewRecorder(&record.FakeRecorder{})
+	evictionQueue := terminator.NewEvictionQueue(ctx, fakeClock, env.KubernetesInterface.CoreV1(), recorder)
+	watchController = watch.NewController(fakeClock, env.Client, cloudProvider, cloudProvider, terminator.NewTerminator(fakeClock, env.Client, evictionQueue, recorder))
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	fakeClock.SetTime(time.Now())
+	ExpectCleanedUp(ctx, env.Client)
+	cloudProvider.Reset()
+
+	watch.ReceivedEventsCounter.Reset()
+})
+
+var _ = Describe("MachineWatch", func() {
+	var provisioner *v1alpha5.Provisioner
+	var machine *v1alpha5.Machine
+	var node *v1.Node
+
+	BeforeEach(func() {
+		provisioner = test.Provisioner()
+		machine, node = test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+				Finalizers: []string{v1alpha5.TerminationFinalizer},
+			},
+		})
+	})
+	It("should delete the machine when its instance is terminated", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.MachineTerminated,
+			Time:       fakeClock.Now(),
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+
+		m, ok := FindMetricWithLabelValues("karpenter_machine_watch_received_events", map[string]string{"event_kind": string(cloudprovider.MachineTerminated)})
+		Expect(ok).To(BeTrue())
+		Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+	})
+	It("should delete the machine when its node hasn't registered", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.MachineTerminated,
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+	})
+	It("should delete the machine when the event uses a different provider ID format", func() {
+		cloudProvider.ProviderIDFormat = func(id string) string { return strings.Replace(id, "legacy:///", "fake:///", 1) }
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: strings.Replace(machine.Status.ProviderID, "fake:///", "legacy:///", 1),
+			Kind:       cloudprovider.MachineTerminated,
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+	})
+	It("should cordon the node and delete the machine when its instance is interrupted", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.MachineInterrupted,
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeTrue())
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+	})
+	It("should batch the events that are waiting", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.MachineRunning,
+		}
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.MachineTerminated,
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		Expect(cloudProvider.MachineEvents).To(BeEmpty())
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+	})
+	It("should not act on machines that were launched or are running", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: machine.Status.ProviderID,
+			Kind:       cloudprovider.MachineLaunched,
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		node = ExpectNodeExists(ctx, env.Client, node.Name)
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+	It("should ignore events for instances that aren't managed by Karpenter", func() {
+		ExpectApplied(ctx, env.Client, provisioner, machine, node)
+		cloudProvider.MachineEvents <- cloudprovider.MachineEvent{
+			ProviderID: test.RandomProviderID(),
+			Kind:       cloudprovider.MachineTerminated,
+		}
+		ExpectReconcileSucceeded(ctx, watchController, client.ObjectKey{})
+
+		machine = ExpectExists(ctx, env.Client, machine)
+		Expect(machine.DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+})
@@ -16,12 +16,14 @@ package node
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -39,6 +41,7 @@ const (
 	nodeName        = "node_name"
 	nodeProvisioner = "provisioner"
 	nodePhase       = "phase"
+	capacityType    = "capacity_type"
 )
 
 var (
@@ -102,6 +105,39 @@ var (
 		nodeLabelNames(),
 	)
 
+	provisionerAllocatableGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "karpenter",
+			Subsystem: "provisioner",
+			Name:      "nodes_allocatable",
+			Help:      "The resources allocatable by the nodes launched for a provisioner. Labeled by provisioner name, capacity type and resource type.",
+		},
+		provisionerLabelNames(),
+	)
+
+	provisionerPodRequestsGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "karpenter",
+			Subsystem: "provisioner",
+			Name:      "nodes_pod_requests",
+			Help:      "The resources requested by pods bound to the nodes launched for a provisioner, including DaemonSet pods. Labeled by provisioner name, capacity type and resource type.",
+		},
+		provisionerLabelNames(),
+	)
+
+	provisionerUtilizationGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "karpenter",
+			Subsystem: "provisioner",
+			Name:      "nodes_utilization_pct",
+			Help:      "The percentage of the resources allocatable by the nodes launched for a provisioner that are requested by pods in the range [0,100]. Labeled by provisioner name, capacity type and resource type.",
+		},
+		provisionerLabelNames(),
+	)
+
+	// utilizationResources are the resources whose utilization is aggregated per provisioner
+	utilizationResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourcePods}
+
 	wellKnownLabels = getWellKnownLabels()
 )
 
@@ -115,6 +151,14 @@ func nodeLabelNames() []string {
 	)
 }
 
+func provisionerLabelNames() []string {
+	return []string{
+		resourceType,
+		nodeProvisioner,
+		capacityType,
+	}
+}
+
 func init() {
 	crmetrics.Registry.MustRegister(
 		allocatableGaugeVec,
@@ -123,7 +167,19 @@ func init() {
 		daemonRequestsGaugeVec,
 		daemonLimitsGaugeVec,
 		overheadGaugeVec,
+		provisionerAllocatableGaugeVec,
+		provisionerPodRequestsGaugeVec,
+		provisionerUtilizationGaugeVec,
 	)
+	metrics.RegisterFamily("karpenter_nodes_allocatable", allocatableGaugeVec)
+	metrics.RegisterFamily("karpenter_nodes_total_pod_requests", podRequestsGaugeVec)
+	metrics.RegisterFamily("karpenter_nodes_total_pod_limits", podLimitsGaugeVec)
+	metrics.RegisterFamily("karpenter_nodes_total_daemon_requests", daemonRequestsGaugeVec)
+	metrics.RegisterFamily("karpenter_nodes_total_daemon_limits", daemonLimitsGaugeVec)
+	metrics.RegisterFamily("karpenter_nodes_system_overhead", overheadGaugeVec)
+	metrics.RegisterFamily("karpenter_provisioner_nodes_allocatable", provisionerAllocatableGaugeVec)
+	metrics.RegisterFamily("karpenter_provisioner_nodes_pod_requests", provisionerPodRequestsGaugeVec)
+	metrics.RegisterRatioFamily("karpenter_provisioner_nodes_utilization_pct", provisionerUtilizationGaugeVec)
 }
 
 type Controller struct {
@@ -142,12 +198,15 @@ func (c *Controller) Name() string {
 	return "metric_scraper"
 }
 
-func (c *Controller) Reconcile(_ context.Context, _ reconcile.Request) (reconcile.Result, error) {
+func (c *Controller) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
 	nodes := lo.Reject(c.cluster.Nodes(), func(n *state.StateNode, _ int) bool {
 		return n.Node == nil
 	})
-	c.metricStore.ReplaceAll(lo.SliceToMap(nodes, func(n *state.StateNode) (string, []*metrics.StoreMetric) {
+	nodeMetrics := lo.SliceToMap(nodes, func(n *state.StateNode) (string, []*metrics.StoreMetric) {
 		return client.ObjectKeyFromObject(n.Node).String(), buildMetrics(n)
+	})
+	c.metricStore.ReplaceAll(lo.MapValues(lo.Assign(nodeMetrics, buildProvisionerMetrics(nodes)), func(storeMetrics []*metrics.StoreMetric, _ string) []*metrics.StoreMetric {
+		return metrics.WithCardinalityControls(ctx, storeMetrics)
 	}))
 	return reconcile.Result{RequeueAfter: time.Second * 5}, nil
 }
@@ -176,6 +235,49 @@ func buildMetrics(n *state.StateNode) (res []*metrics.StoreMetric) {
 	return res
 }
 
+// buildProvisionerMetrics aggregates the allocatable resources and pod requests of the nodes per provisioner and
+// capacity type, keyed so that they can't collide with the per-node metrics in the store
+func buildProvisionerMetrics(nodes []*state.StateNode) map[string][]*metrics.StoreMetric {
+	type key struct{ provisioner, capacityType string }
+	allocatable := map[key]v1.ResourceList{}
+	requests := map[key]v1.ResourceList{}
+	for _, n := range nodes {
+		k := key{provisioner: n.Node.Labels[v1alpha5.ProvisionerNameLabelKey], capacityType: n.Node.Labels[v1alpha5.LabelCapacityType]}
+		if k.provisioner == "" {
+			continue
+		}
+		allocatable[k] = resources.Merge(allocatable[k], n.Node.Status.Allocatable)
+		requests[k] = resources.Merge(requests[k], n.PodRequests())
+	}
+	res := map[string][]*metrics.StoreMetric{}
+	for k := range allocatable {
+		var storeMetrics []*metrics.StoreMetric
+		for _, resourceName := range utilizationResources {
+			labels := prometheus.Labels{
+				resourceType:    strings.ReplaceAll(strings.ToLower(string(resourceName)), "-", "_"),
+				nodeProvisioner: k.provisioner,
+				capacityType:    k.capacityType,
+			}
+			allocatableValue := allocatable[k][resourceName]
+			requestsValue := requests[k][resourceName]
+			storeMetrics = append(storeMetrics,
+				&metrics.StoreMetric{GaugeVec: provisionerAllocatableGaugeVec, Value: allocatableValue.AsApproximateFloat64(), Labels: labels},
+				&metrics.StoreMetric{GaugeVec: provisionerPodRequestsGaugeVec, Value: requestsValue.AsApproximateFloat64(), Labels: labels},
+				&metrics.StoreMetric{GaugeVec: provisionerUtilizationGaugeVec, Value: utilization(requestsValue, allocatableValue), Labels: labels},
+			)
+		}
+		res[fmt.Sprintf("provisioner/%s/%s", k.provisioner, k.capacityType)] = storeMetrics
+	}
+	return res
+}
+
+func utilization(requests, allocatable resource.Quantity) float64 {
+	if allocatable.IsZero() {
+		return 0
+	}
+	return requests.AsApproximateFloat64() / allocatable.AsApproximateFloat64() * 100
+}
+
 func getNodeLabels(node *v1.Node, resourceTypeName string) prometheus.Labels {
 	metricLabels := prometheus.Labels{}
 	metricLabels[resourceType] = resourceTypeName
@@ -128,4 +128,49 @@ var _ = Describe("Node Metrics", func() {
 		})
 		Expect(found).To(BeFalse())
 	})
+	It("should aggregate the allocatable resources per provisioner and capacity type", func() {
+		resources := v1.ResourceList{
+			v1.ResourcePods:   resource.MustParse("100"),
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("32Gi"),
+		}
+		nodeOpts := test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+					v1alpha5.LabelCapacityType:       v1alpha5.CapacityTypeSpot,
+				},
+			},
+			Allocatable: resources,
+		}
+		nodes := []*v1.Node{test.Node(nodeOpts), test.Node(nodeOpts)}
+		for _, node := range nodes {
+			ExpectApplied(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		}
+		ExpectReconcileSucceeded(ctx, metricsStateController, types.NamespacedName{})
+
+		for k, v := range resources {
+			metric, found := FindMetricWithLabelValues("karpenter_provisioner_nodes_allocatable", map[string]string{
+				"provisioner":   provisioner.Name,
+				"capacity_type": v1alpha5.CapacityTypeSpot,
+				"resource_type": k.String(),
+			})
+			Expect(found).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("~", 2*v.AsApproximateFloat64()))
+		}
+		metric, found := FindMetricWithLabelValues("karpenter_provisioner_nodes_utilization_pct", map[string]string{
+			"provisioner":   provisioner.Name,
+			"capacity_type": v1alpha5.CapacityTypeSpot,
+			"resource_type": "cpu",
+		})
+		Expect(found).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 0))
+
+		for _, node := range nodes {
+			ExpectDeleted(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		}
+		ExpectReconcileSucceeded(ctx, metricsStateController, types.NamespacedName{})
+	})
 })
@@ -84,6 +84,9 @@ var (
 
 func init() {
 	crmetrics.Registry.MustRegister(limitGaugeVec, usageGaugeVec, usagePctGaugeVec)
+	metrics.RegisterFamily("karpenter_nodepool_limit", limitGaugeVec)
+	metrics.RegisterFamily("karpenter_nodepool_usage", usageGaugeVec)
+	metrics.RegisterRatioFamily("karpenter_nodepool_usage_pct", usagePctGaugeVec)
 }
 
 type Controller struct {
@@ -113,7 +116,7 @@ func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		}
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
-	c.metricStore.Update(req.NamespacedName.String(), buildMetrics(nodePool))
+	c.metricStore.Update(req.NamespacedName.String(), metrics.WithCardinalityControls(ctx, buildMetrics(nodePool)))
 	// periodically update our metrics per nodepool even if nothing has changed
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
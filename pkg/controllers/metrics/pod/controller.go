@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
@@ -25,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,8 +35,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/metrics"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
 )
 
 const (
@@ -71,19 +75,37 @@ var (
 			Objectives: metrics.SummaryObjectives(),
 		},
 	)
+	podProvisioningDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "karpenter",
+			Subsystem: "pods",
+			Name:      "provisioning_duration_seconds",
+			Help:      "The time from a pod first being seen as unschedulable until it is bound to an initialized Karpenter node. Labeled by the provisioner or nodepool of the node.",
+			Buckets:   metrics.DurationBuckets(),
+		},
+		[]string{metrics.ProvisionerLabel, metrics.NodePoolLabel},
+	)
 )
 
 // Controller for the resource
 type Controller struct {
+	clock       clock.Clock
 	kubeClient  client.Client
 	metricStore *metrics.Store
 
 	pendingPods sets.Set[string]
+	// unschedulablePods tracks when pods were first seen as unschedulable so that the provisioning duration can be
+	// observed once they are bound to an initialized node
+	unschedulablePods map[string]time.Time
 }
 
 func init() {
 	crmetrics.Registry.MustRegister(podGaugeVec)
 	crmetrics.Registry.MustRegister(podStartupTimeSummary)
+	crmetrics.Registry.MustRegister(podProvisioningDurationHistogram)
+	metrics.RegisterFamily("karpenter_pods_state", podGaugeVec)
+	metrics.RegisterFamily("karpenter_pods_startup_time_seconds", podStartupTimeSummary)
+	metrics.RegisterFamily("karpenter_pods_provisioning_duration_seconds", podProvisioningDurationHistogram)
 }
 
 func labelNames() []string {
@@ -102,11 +124,13 @@ func labelNames() []string {
 }
 
 // NewController constructs a podController instance
-func NewController(kubeClient client.Client) controller.Controller {
+func NewController(clk clock.Clock, kubeClient client.Client) controller.Controller {
 	return &Controller{
-		kubeClient:  kubeClient,
-		metricStore: metrics.NewStore(),
-		pendingPods: sets.New[string](),
+		clock:             clk,
+		kubeClient:        kubeClient,
+		metricStore:       metrics.NewStore(),
+		pendingPods:       sets.New[string](),
+		unschedulablePods: map[string]time.Time{},
 	}
 }
 
@@ -121,42 +145,104 @@ func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	if err := c.kubeClient.Get(ctx, req.NamespacedName, pod); err != nil {
 		if errors.IsNotFound(err) {
 			c.pendingPods.Delete(req.NamespacedName.String())
+			delete(c.unschedulablePods, req.NamespacedName.String())
 			c.metricStore.Delete(req.NamespacedName.String())
 		}
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
-	labels, err := c.makeLabels(ctx, pod)
-	if err != nil {
-		return reconcile.Result{}, err
+	node := &v1.Node{}
+	if pod.Spec.NodeName != "" {
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, err
+		}
 	}
-	c.metricStore.Update(client.ObjectKeyFromObject(pod).String(), []*metrics.StoreMetric{
+	labels := c.makeLabels(pod, node)
+	c.metricStore.Update(client.ObjectKeyFromObject(pod).String(), metrics.WithCardinalityControls(ctx, []*metrics.StoreMetric{
 		{
 			GaugeVec: podGaugeVec,
 			Value:    1,
 			Labels:   labels,
 		},
-	})
-	c.recordPodStartupMetric(pod)
+	}))
+	c.recordPodStartupMetric(ctx, pod)
+	return c.recordPodProvisioningMetric(ctx, pod, node)
+}
+
+// recordPodProvisioningMetric observes the time from the pod first being seen as unschedulable until it is bound to
+// a Karpenter node and that node's machine or nodeclaim is initialized, whichever of the two happens last
+func (c *Controller) recordPodProvisioningMetric(ctx context.Context, pod *v1.Pod, node *v1.Node) (reconcile.Result, error) {
+	key := client.ObjectKeyFromObject(pod).String()
+	if pod.Spec.NodeName == "" {
+		if _, ok := c.unschedulablePods[key]; ok {
+			return reconcile.Result{}, nil
+		}
+		if cond, ok := podCondition(pod, v1.PodScheduled); ok && cond.Status == v1.ConditionFalse && cond.Reason == v1.PodReasonUnschedulable {
+			c.unschedulablePods[key] = lo.Ternary(cond.LastTransitionTime.IsZero(), c.clock.Now(), cond.LastTransitionTime.Time)
+		}
+		return reconcile.Result{}, nil
+	}
+	start, ok := c.unschedulablePods[key]
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+	owner := nodeclaimutil.OwnerKey(node)
+	if owner.Name == "" {
+		// the pod was bound to a node that Karpenter doesn't manage, so there is nothing to measure
+		delete(c.unschedulablePods, key)
+		return reconcile.Result{}, nil
+	}
+	if node.Labels[v1beta1.NodeInitializedLabelKey] != "true" {
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	var end time.Time
+	if cond, ok := podCondition(pod, v1.PodScheduled); ok && cond.Status == v1.ConditionTrue {
+		end = cond.LastTransitionTime.Time
+	}
+	nodeClaimList, err := nodeclaimutil.List(ctx, c.kubeClient, client.MatchingFields{"status.providerID": node.Spec.ProviderID})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	for i := range nodeClaimList.Items {
+		if cond := nodeClaimList.Items[i].StatusConditions().GetCondition(v1beta1.NodeInitialized); cond != nil && cond.LastTransitionTime.Inner.After(end) {
+			end = cond.LastTransitionTime.Inner.Time
+		}
+	}
+	if end.IsZero() {
+		end = c.clock.Now()
+	}
+	if metrics.Enabled(ctx, podProvisioningDurationHistogram) {
+		podProvisioningDurationHistogram.With(prometheus.Labels{
+			metrics.ProvisionerLabel: lo.Ternary(owner.IsProvisioner, owner.Name, ""),
+			metrics.NodePoolLabel:    lo.Ternary(owner.IsProvisioner, "", owner.Name),
+		}).Observe(lo.Max([]float64{end.Sub(start).Seconds(), 0}))
+	}
+	delete(c.unschedulablePods, key)
 	return reconcile.Result{}, nil
 }
 
-func (c *Controller) recordPodStartupMetric(pod *v1.Pod) {
+func podCondition(pod *v1.Pod, conditionType v1.PodConditionType) (v1.PodCondition, bool) {
+	return lo.Find(pod.Status.Conditions, func(c v1.PodCondition) bool {
+		return c.Type == conditionType
+	})
+}
+
+func (c *Controller) recordPodStartupMetric(ctx context.Context, pod *v1.Pod) {
 	key := client.ObjectKeyFromObject(pod).String()
 	if pod.Status.Phase == phasePending {
 		c.pendingPods.Insert(key)
 		return
 	}
-	cond, ok := lo.Find(pod.Status.Conditions, func(c v1.PodCondition) bool {
-		return c.Type == v1.PodReady
-	})
+	cond, ok := podCondition(pod, v1.PodReady)
 	if c.pendingPods.Has(key) && ok {
-		podStartupTimeSummary.Observe(cond.LastTransitionTime.Sub(pod.CreationTimestamp.Time).Seconds())
+		if metrics.Enabled(ctx, podStartupTimeSummary) {
+			podStartupTimeSummary.Observe(cond.LastTransitionTime.Sub(pod.CreationTimestamp.Time).Seconds())
+		}
 		c.pendingPods.Delete(key)
 	}
 }
 
 // makeLabels creates the makeLabels using the current state of the pod
-func (c *Controller) makeLabels(ctx context.Context, pod *v1.Pod) (prometheus.Labels, error) {
+func (c *Controller) makeLabels(pod *v1.Pod, node *v1.Node) prometheus.Labels {
 	metricLabels := prometheus.Labels{}
 	metricLabels[podName] = pod.Name
 	metricLabels[podNameSpace] = pod.Namespace
@@ -170,19 +256,12 @@ func (c *Controller) makeLabels(ctx context.Context, pod *v1.Pod) (prometheus.La
 	metricLabels[ownerSelfLink] = selflink
 	metricLabels[podHostName] = pod.Spec.NodeName
 	metricLabels[podPhase] = string(pod.Status.Phase)
-
-	node := &v1.Node{}
-	if pod.Spec.NodeName != "" {
-		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); client.IgnoreNotFound(err) != nil {
-			return nil, err
-		}
-	}
 	metricLabels[podHostZone] = node.Labels[v1.LabelTopologyZone]
 	metricLabels[podHostArchitecture] = node.Labels[v1.LabelArchStable]
 	metricLabels[podHostCapacityType] = node.Labels[v1alpha5.LabelCapacityType]
 	metricLabels[podHostInstanceType] = node.Labels[v1.LabelInstanceTypeStable]
 	metricLabels[podProvisioner] = node.Labels[v1alpha5.ProvisionerNameLabelKey]
-	return metricLabels, nil
+	return metricLabels
 }
 
 func (c *Controller) Builder(_ context.Context, m manager.Manager) controller.Builder {
@@ -17,13 +17,21 @@ package pod_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
 	. "knative.dev/pkg/logging/testing"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/controllers/metrics/pod"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/scheme"
@@ -34,6 +42,7 @@ import (
 var podController controller.Controller
 var ctx context.Context
 var env *test.Environment
+var fakeClock *clock.FakeClock
 
 func TestAPIs(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -42,8 +51,14 @@ func TestAPIs(t *testing.T) {
 }
 
 var _ = BeforeSuite(func() {
-	env = test.NewEnvironment(scheme.Scheme)
-	podController = pod.NewController(env.Client)
+	ctx = settings.ToContext(ctx, test.Settings())
+	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...), test.WithFieldIndexers(func(c cache.Cache) error {
+		return c.IndexField(ctx, &v1alpha5.Machine{}, "status.providerID", func(obj client.Object) []string {
+			return []string{obj.(*v1alpha5.Machine).Status.ProviderID}
+		})
+	}))
+	fakeClock = clock.NewFakeClock(time.Now())
+	podController = pod.NewController(fakeClock, env.Client)
 })
 
 var _ = AfterSuite(func() {
@@ -98,4 +113,100 @@ var _ = Describe("Pod Metrics", func() {
 		})
 		Expect(found).To(BeFalse())
 	})
+	Context("Cardinality Controls", func() {
+		It("should drop the values of dropped labels", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{MetricsDroppedLabels: []string{"name"}}))
+			p := test.Pod()
+			ExpectApplied(ctx, env.Client, p)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			_, found := FindMetricWithLabelValues("karpenter_pods_state", map[string]string{
+				"name":      "",
+				"namespace": p.GetNamespace(),
+			})
+			Expect(found).To(BeTrue())
+			_, found = FindMetricWithLabelValues("karpenter_pods_state", map[string]string{
+				"name": p.GetName(),
+			})
+			Expect(found).To(BeFalse())
+
+			ExpectDeleted(ctx, env.Client, p)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+		})
+		It("should not emit disabled metric families", func() {
+			ctx := settings.ToContext(ctx, test.Settings(settings.Settings{MetricsDisabled: []string{"karpenter_pods_state"}}))
+			p := test.Pod()
+			ExpectApplied(ctx, env.Client, p)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			_, found := FindMetricWithLabelValues("karpenter_pods_state", map[string]string{
+				"name":      p.GetName(),
+				"namespace": p.GetNamespace(),
+			})
+			Expect(found).To(BeFalse())
+		})
+	})
+	Context("Provisioning Duration", func() {
+		It("should record the provisioning duration once the pod is bound to an initialized Karpenter node", func() {
+			provisioner := test.Provisioner()
+			machine, node := test.MachineAndNode(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+						v1alpha5.LabelNodeInitialized:    "true",
+					},
+				},
+			})
+			machine.StatusConditions().MarkTrue(v1alpha5.MachineInitialized)
+			p := test.UnschedulablePod()
+			ExpectApplied(ctx, env.Client, provisioner, machine, node, p)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			ExpectManualBinding(ctx, env.Client, p, node)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			_, found := FindMetricWithLabelValues("karpenter_pods_provisioning_duration_seconds", map[string]string{
+				"provisioner": provisioner.Name,
+			})
+			Expect(found).To(BeTrue())
+		})
+		It("should record the provisioning duration for pods bound to nodes of a nodepool", func() {
+			nodePool := test.NodePool()
+			node := test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						v1beta1.NodePoolLabelKey:        nodePool.Name,
+						v1beta1.NodeInitializedLabelKey: "true",
+					},
+				},
+			})
+			p := test.UnschedulablePod()
+			ExpectApplied(ctx, env.Client, nodePool, node, p)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			fakeClock.Step(time.Second * 30)
+			ExpectManualBinding(ctx, env.Client, p, node)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			m, found := FindMetricWithLabelValues("karpenter_pods_provisioning_duration_seconds", map[string]string{
+				"nodepool": nodePool.Name,
+			})
+			Expect(found).To(BeTrue())
+			Expect(m.GetHistogram().GetSampleSum()).To(BeNumerically("==", 30))
+		})
+		It("should not record the provisioning duration for pods bound to nodes not managed by Karpenter", func() {
+			node := test.Node()
+			p := test.UnschedulablePod()
+			ExpectApplied(ctx, env.Client, node, p)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			ExpectManualBinding(ctx, env.Client, p, node)
+			ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(p))
+
+			_, found := FindMetricWithLabelValues("karpenter_pods_provisioning_duration_seconds", map[string]string{
+				"provisioner": "",
+			})
+			Expect(found).To(BeFalse())
+		})
+	})
 })
@@ -22,6 +22,7 @@ import (
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -74,6 +75,9 @@ var (
 
 func init() {
 	crmetrics.Registry.MustRegister(limitGaugeVec, usageGaugeVec, usagePctGaugeVec)
+	metrics.RegisterFamily("karpenter_provisioner_limit", limitGaugeVec)
+	metrics.RegisterFamily("karpenter_provisioner_usage", usageGaugeVec)
+	metrics.RegisterRatioFamily("karpenter_provisioner_usage_pct", usagePctGaugeVec)
 }
 
 func labelNames() []string {
@@ -84,13 +88,15 @@ func labelNames() []string {
 }
 
 type Controller struct {
+	clock       clock.Clock
 	kubeClient  client.Client
 	metricStore *metrics.Store
 }
 
 // NewController constructs a controller instance
-func NewController(kubeClient client.Client) corecontroller.Controller {
+func NewController(clk clock.Clock, kubeClient client.Client) corecontroller.Controller {
 	return &Controller{
+		clock:       clk,
 		kubeClient:  kubeClient,
 		metricStore: metrics.NewStore(),
 	}
@@ -110,7 +116,11 @@ func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reco
 		}
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
-	c.metricStore.Update(req.NamespacedName.String(), buildMetrics(provisioner))
+	disruptionMetrics, err := c.buildDisruptionMetrics(ctx, provisioner)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	c.metricStore.Update(req.NamespacedName.String(), metrics.WithCardinalityControls(ctx, append(buildMetrics(provisioner), disruptionMetrics...)))
 	// periodically update our metrics per provisioner even if nothing has changed
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
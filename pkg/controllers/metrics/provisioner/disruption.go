@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/metrics"
+	machineutil "github.com/aws/karpenter-core/pkg/utils/machine"
+	nodeutil "github.com/aws/karpenter-core/pkg/utils/node"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+	"github.com/aws/karpenter-core/pkg/utils/pdb"
+	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
+)
+
+const (
+	conditionLabel = "condition"
+	reasonLabel    = "reason"
+
+	blockedByBudget     = "budget"
+	blockedByDoNotEvict = "do_not_evict"
+	blockedByPDB        = "pdb"
+)
+
+var (
+	disruptionConditions = []apis.ConditionType{v1alpha5.MachineDrifted, v1alpha5.MachineEmpty, v1alpha5.MachineExpired}
+	blockedReasons       = []string{blockedByBudget, blockedByDoNotEvict, blockedByPDB}
+
+	machinesDisruptionEligibleGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "provisioner",
+			Name:      "machines_disruption_eligible",
+			Help:      "Number of machines that currently hold a disruption condition. Labeled by provisioner name and condition.",
+		},
+		[]string{provisionerName, conditionLabel},
+	)
+	machinesDisruptionBlockedGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "provisioner",
+			Name:      "machines_disruption_blocked",
+			Help:      "Number of machines holding a disruption condition that are blocked from being disrupted. A machine is counted for each reason that blocks it. Labeled by provisioner name and the reason they are blocked.",
+		},
+		[]string{provisionerName, reasonLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(machinesDisruptionEligibleGaugeVec, machinesDisruptionBlockedGaugeVec)
+	metrics.RegisterFamily("karpenter_provisioner_machines_disruption_eligible", machinesDisruptionEligibleGaugeVec)
+	metrics.RegisterFamily("karpenter_provisioner_machines_disruption_blocked", machinesDisruptionBlockedGaugeVec)
+}
+
+// buildDisruptionMetrics counts the machines of the provisioner that hold each disruption condition and the machines
+// that hold any of them but are currently blocked by the provisioner's disruption budgets, do-not-evict pods or PDBs
+func (c *Controller) buildDisruptionMetrics(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*metrics.StoreMetric, error) {
+	machineList := &v1alpha5.MachineList{}
+	if err := c.kubeClient.List(ctx, machineList, client.MatchingLabels{v1alpha5.ProvisionerNameLabelKey: provisioner.Name}); err != nil {
+		return nil, fmt.Errorf("listing machines, %w", err)
+	}
+	pdbs, err := pdb.NewLimits(ctx, c.kubeClient)
+	if err != nil {
+		return nil, fmt.Errorf("tracking PodDisruptionBudgets, %w", err)
+	}
+	nodePool := nodepoolutil.New(provisioner)
+	allowed, err := nodePool.AllowedDisruptions(c.clock, len(machineList.Items))
+	if err != nil {
+		// Budgets are validated at admission, so we should never get here
+		logging.FromContext(ctx).Errorf("computing allowed disruptions, %s", err)
+		allowed = 0
+	}
+	allowed -= lo.CountBy(machineList.Items, func(m v1alpha5.Machine) bool { return !m.DeletionTimestamp.IsZero() })
+
+	eligible := lo.SliceToMap(disruptionConditions, func(t apis.ConditionType) (apis.ConditionType, int) { return t, 0 })
+	blocked := lo.SliceToMap(blockedReasons, func(r string) (string, int) { return r, 0 })
+	for i := range machineList.Items {
+		machine := &machineList.Items[i]
+		if !machine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		held := lo.Filter(disruptionConditions, func(t apis.ConditionType, _ int) bool {
+			return machine.StatusConditions().GetCondition(t).IsTrue()
+		})
+		if len(held) == 0 {
+			continue
+		}
+		for _, t := range held {
+			eligible[t]++
+		}
+		// Each eligible machine takes up one of the allowed disruptions, and the machines beyond them are blocked
+		if allowed > 0 {
+			allowed--
+		} else {
+			blocked[blockedByBudget]++
+		}
+		node, err := machineutil.NodeForMachine(ctx, c.kubeClient, machine)
+		if err != nil {
+			// Machines without a node have no pods that could block their disruption
+			continue
+		}
+		// Nodes past their MaxNodeLifetime and ForceExpirationGracePeriod are deprovisioned regardless of PDBs and do-not-evict pods
+		if _, forceAt, ok := nodepoolutil.MaxNodeLifetimeDeadlines(nodePool, node); ok && !c.clock.Now().Before(forceAt) {
+			continue
+		}
+		pods, err := nodeutil.GetNodePods(ctx, c.kubeClient, node)
+		if err != nil {
+			return nil, fmt.Errorf("getting pods for node, %w", err)
+		}
+		if lo.ContainsBy(pods, func(p *v1.Pod) bool { return podutil.HasDoNotEvict(p) || podutil.HasDoNotDisrupt(p) }) {
+			blocked[blockedByDoNotEvict]++
+		}
+		if _, ok := pdbs.CanEvictPods(pods); !ok {
+			blocked[blockedByPDB]++
+		}
+	}
+
+	var res []*metrics.StoreMetric
+	for t, count := range eligible {
+		res = append(res, &metrics.StoreMetric{
+			GaugeVec: machinesDisruptionEligibleGaugeVec,
+			Labels:   prometheus.Labels{provisionerName: provisioner.Name, conditionLabel: string(t)},
+			Value:    float64(count),
+		})
+	}
+	for reason, count := range blocked {
+		res = append(res, &metrics.StoreMetric{
+			GaugeVec: machinesDisruptionBlockedGaugeVec,
+			Labels:   prometheus.Labels{provisionerName: provisioner.Name, reasonLabel: reason},
+			Value:    float64(count),
+		})
+	}
+	return res, nil
+}
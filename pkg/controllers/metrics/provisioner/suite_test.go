@@ -18,15 +18,20 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
 	. "knative.dev/pkg/logging/testing"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/controllers/metrics/provisioner"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
@@ -36,6 +41,7 @@ import (
 )
 
 var provisionerController controller.Controller
+var fakeClock *clock.FakeClock
 var ctx context.Context
 var env *test.Environment
 
@@ -46,14 +52,24 @@ func TestAPIs(t *testing.T) {
 }
 
 var _ = BeforeSuite(func() {
-	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...))
-	provisionerController = provisioner.NewController(env.Client)
+	ctx = settings.ToContext(ctx, test.Settings())
+	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...), test.WithFieldIndexers(func(c cache.Cache) error {
+		return c.IndexField(ctx, &v1.Node{}, "spec.providerID", func(obj client.Object) []string {
+			return []string{obj.(*v1.Node).Spec.ProviderID}
+		})
+	}))
+	fakeClock = clock.NewFakeClock(time.Now())
+	provisionerController = provisioner.NewController(fakeClock, env.Client)
 })
 
 var _ = AfterSuite(func() {
 	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
 })
 
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
 var _ = Describe("Metrics", func() {
 	It("should update the provisioner limit metrics", func() {
 		limits := v1.ResourceList{
@@ -166,4 +182,52 @@ var _ = Describe("Metrics", func() {
 			Expect(found).To(BeFalse())
 		}
 	})
+	It("should update the provisioner disruption metrics", func() {
+		provisioner := test.Provisioner()
+		provisioner.Spec.Disruption = &v1alpha5.Disruption{Budgets: []v1alpha5.Budget{{Nodes: "1"}}}
+		var machines []*v1alpha5.Machine
+		var nodes []*v1.Node
+		for i := 0; i < 3; i++ {
+			machine, node := test.MachineAndNode(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+				},
+				Status: v1alpha5.MachineStatus{ProviderID: test.RandomProviderID()},
+			})
+			machines = append(machines, machine)
+			nodes = append(nodes, node)
+		}
+		machines[0].StatusConditions().MarkTrue(v1alpha5.MachineDrifted)
+		machines[1].StatusConditions().MarkTrue(v1alpha5.MachineDrifted)
+		machines[1].StatusConditions().MarkTrue(v1alpha5.MachineExpired)
+		pod := test.Pod(test.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1alpha5.DoNotEvictPodAnnotationKey: "true"},
+			},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, pod)
+		for i := range machines {
+			ExpectApplied(ctx, env.Client, machines[i], nodes[i])
+		}
+		ExpectManualBinding(ctx, env.Client, pod, nodes[0])
+		ExpectReconcileSucceeded(ctx, provisionerController, client.ObjectKeyFromObject(provisioner))
+
+		for condition, expected := range map[string]float64{"MachineDrifted": 2, "MachineExpired": 1, "MachineEmpty": 0} {
+			m, found := FindMetricWithLabelValues("karpenter_provisioner_machines_disruption_eligible", map[string]string{
+				"provisioner": provisioner.Name,
+				"condition":   condition,
+			})
+			Expect(found).To(BeTrue())
+			Expect(m.GetGauge().GetValue()).To(BeNumerically("==", expected))
+		}
+		// The budget allows one of the two eligible machines to be disrupted
+		for reason, expected := range map[string]float64{"budget": 1, "do_not_evict": 1, "pdb": 0} {
+			m, found := FindMetricWithLabelValues("karpenter_provisioner_machines_disruption_blocked", map[string]string{
+				"provisioner": provisioner.Name,
+				"reason":      reason,
+			})
+			Expect(found).To(BeTrue())
+			Expect(m.GetGauge().GetValue()).To(BeNumerically("==", expected))
+		}
+	})
 })
@@ -66,7 +66,19 @@ func (c *Controller) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool)
 	}
 	stored := nodePool.DeepCopy()
 	// Determine resource usage and update provisioner.status.resources
-	nodePool.Status.Resources = c.resourceCountsFor(lo.Ternary(nodePool.IsProvisioner, v1alpha5.ProvisionerNameLabelKey, v1beta1.NodePoolLabelKey), nodePool.Name)
+	ownerLabel := lo.Ternary(nodePool.IsProvisioner, v1alpha5.ProvisionerNameLabelKey, v1beta1.NodePoolLabelKey)
+	nodePool.Status.Resources = c.resourceCountsFor(ownerLabel, nodePool.Name, func(*state.StateNode) bool { return true })
+	nodePool.Status.ScopedResources = nil
+	for _, scopedLimit := range nodePool.Spec.ScopedLimits {
+		scopedLimit := scopedLimit
+		nodePool.Status.ScopedResources = append(nodePool.Status.ScopedResources, v1beta1.ScopedResources{
+			Zone:         scopedLimit.Zone,
+			CapacityType: scopedLimit.CapacityType,
+			Resources: c.resourceCountsFor(ownerLabel, nodePool.Name, func(n *state.StateNode) bool {
+				return scopedLimit.Matches(n.Labels()[v1.LabelTopologyZone], n.Labels()[v1beta1.CapacityTypeLabelKey])
+			}),
+		})
+	}
 	if !equality.Semantic.DeepEqual(stored, nodePool) {
 		if err := nodepoolutil.PatchStatus(ctx, c.kubeClient, stored, nodePool); err != nil {
 			return reconcile.Result{}, client.IgnoreNotFound(err)
@@ -75,8 +87,9 @@ func (c *Controller) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool)
 	return reconcile.Result{}, nil
 }
 
-func (c *Controller) resourceCountsFor(ownerLabel string, ownerName string) v1.ResourceList {
+func (c *Controller) resourceCountsFor(ownerLabel string, ownerName string, matches func(*state.StateNode) bool) v1.ResourceList {
 	var res v1.ResourceList
+	nodes := int64(0)
 	// Record all resources provisioned by the provisioners, we look at the cluster state nodes as their capacity
 	// is accurately reported even for nodes that haven't fully started yet. This allows us to update our provisioner
 	// status immediately upon node creation instead of waiting for the node to become ready.
@@ -86,11 +99,14 @@ func (c *Controller) resourceCountsFor(ownerLabel string, ownerName string) v1.R
 		if n.MarkedForDeletion() {
 			return true
 		}
-		if n.Labels()[ownerLabel] == ownerName {
+		if n.Labels()[ownerLabel] == ownerName && matches(n) {
 			res = resources.MergeInto(res, n.Capacity())
+			nodes++
 		}
 		return true
 	})
+	// Record the number of nodes alongside the resources so that node count limits can be enforced
+	res = resources.MergeInto(res, v1.ResourceList{v1beta1.ResourceNodes: *resource.NewQuantity(nodes, resource.DecimalSI)})
 	return functional.FilterMap(res, func(_ v1.ResourceName, v resource.Quantity) bool { return !v.IsZero() })
 }
 
@@ -0,0 +1,208 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicas
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/metrics"
+	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+	provisionerutil "github.com/aws/karpenter-core/pkg/utils/provisioner"
+)
+
+// Controller maintains the minimum number of nodes configured through the replicas of a NodePool by launching
+// empty nodes whenever the NodePool owns fewer nodes than its replicas
+type Controller struct {
+	kubeClient    client.Client
+	cluster       *state.Cluster
+	cloudProvider cloudprovider.CloudProvider
+	provisioner   *provisioning.Provisioner
+}
+
+// NewController is a constructor
+func NewController(kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider, provisioner *provisioning.Provisioner) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cluster:       cluster,
+		cloudProvider: cloudProvider,
+		provisioner:   provisioner,
+	}
+}
+
+// Reconcile launches the nodes that are missing to reach the replicas of the NodePool
+func (c *Controller) Reconcile(ctx context.Context, nodePool *v1beta1.NodePool) (reconcile.Result, error) {
+	if nodePool.Spec.Replicas == nil || !nodePool.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	// We need to ensure that our internal cluster state mechanism is synced before we proceed
+	// Otherwise, we may count fewer nodes than the NodePool owns and launch more than its replicas
+	if !c.cluster.Synced(ctx) {
+		return reconcile.Result{RequeueAfter: time.Second}, nil
+	}
+	missing := int(*nodePool.Spec.Replicas) - c.nodeCount(nodePool)
+	if missing <= 0 {
+		return reconcile.Result{}, nil
+	}
+	instanceTypes, err := c.instanceTypes(ctx, nodePool)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	logging.FromContext(ctx).With(lo.Ternary(nodePool.IsProvisioner, "provisioner", "nodepool"), nodePool.Name).Infof("launching %d node(s) to maintain %d replica(s)", missing, *nodePool.Spec.Replicas)
+	nodeClaims := lo.Times(missing, func(_ int) *scheduling.NodeClaim {
+		// Each NodeClaim gets its own template as launching adds the instance type requirement to it
+		nodeClaimTemplate := scheduling.NewNodeClaimTemplate(nodePool)
+		nodeClaimTemplate.InstanceTypeOptions = instanceTypes
+		return &scheduling.NodeClaim{NodeClaimTemplate: *nodeClaimTemplate}
+	})
+	if _, err = c.provisioner.CreateNodeClaims(ctx, nodeClaims, provisioning.WithReason(metrics.ReplicasReason)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("launching replicas, %w", err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// nodeCount returns the number of nodes owned by the NodePool that aren't being deleted, including the nodes that are
+// still launching
+func (c *Controller) nodeCount(nodePool *v1beta1.NodePool) int {
+	ownerLabel := lo.Ternary(nodePool.IsProvisioner, v1alpha5.ProvisionerNameLabelKey, v1beta1.NodePoolLabelKey)
+	count := 0
+	c.cluster.ForEachNode(func(n *state.StateNode) bool {
+		if !n.MarkedForDeletion() && n.Labels()[ownerLabel] == nodePool.Name {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// instanceTypes returns the instance types that are compatible with the requirements of the NodePool and have an
+// available offering
+func (c *Controller) instanceTypes(ctx context.Context, nodePool *v1beta1.NodePool) (cloudprovider.InstanceTypes, error) {
+	instanceTypes, err := c.cloudProvider.GetInstanceTypes(ctx, provisionerutil.New(nodePool))
+	if err != nil {
+		return nil, fmt.Errorf("getting instance types, %w", err)
+	}
+	requirements := scheduling.NewNodeClaimTemplate(nodePool).Requirements
+	instanceTypes = lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+		return it.Requirements.Intersects(requirements) == nil &&
+			len(it.Offerings.Requirements(requirements).Available()) > 0
+	})
+	if len(instanceTypes) == 0 {
+		return nil, fmt.Errorf("no instance types satisfy the requirements of %s %q", lo.Ternary(nodePool.IsProvisioner, "provisioner", "nodepool"), nodePool.Name)
+	}
+	return instanceTypes, nil
+}
+
+type NodePoolController struct {
+	*Controller
+}
+
+func NewNodePoolController(kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider, provisioner *provisioning.Provisioner) corecontroller.Controller {
+	return corecontroller.Typed[*v1beta1.NodePool](kubeClient, &NodePoolController{
+		Controller: NewController(kubeClient, cluster, cloudProvider, provisioner),
+	})
+}
+
+func (c *NodePoolController) Name() string {
+	return "replicas"
+}
+
+func (c *NodePoolController) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.Adapt(controllerruntime.
+		NewControllerManagedBy(m).
+		For(&v1beta1.NodePool{}).
+		Watches(
+			&source.Kind{Type: &v1beta1.NodeClaim{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				if name, ok := o.GetLabels()[v1beta1.NodePoolLabelKey]; ok {
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+				}
+				return nil
+			}),
+		).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				if name, ok := o.GetLabels()[v1beta1.NodePoolLabelKey]; ok {
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+				}
+				return nil
+			}),
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}))
+}
+
+type ProvisionerController struct {
+	*Controller
+}
+
+func NewProvisionerController(kubeClient client.Client, cluster *state.Cluster, cloudProvider cloudprovider.CloudProvider, provisioner *provisioning.Provisioner) corecontroller.Controller {
+	return corecontroller.Typed[*v1alpha5.Provisioner](kubeClient, &ProvisionerController{
+		Controller: NewController(kubeClient, cluster, cloudProvider, provisioner),
+	})
+}
+
+func (c *ProvisionerController) Reconcile(ctx context.Context, provisioner *v1alpha5.Provisioner) (reconcile.Result, error) {
+	return c.Controller.Reconcile(ctx, nodepoolutil.New(provisioner))
+}
+
+func (c *ProvisionerController) Name() string {
+	return "replicas"
+}
+
+func (c *ProvisionerController) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+	return corecontroller.Adapt(controllerruntime.
+		NewControllerManagedBy(m).
+		For(&v1alpha5.Provisioner{}).
+		Watches(
+			&source.Kind{Type: &v1alpha5.Machine{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				if name, ok := o.GetLabels()[v1alpha5.ProvisionerNameLabelKey]; ok {
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+				}
+				return nil
+			}),
+		).
+		Watches(
+			&source.Kind{Type: &v1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				if name, ok := o.GetLabels()[v1alpha5.ProvisionerNameLabelKey]; ok {
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+				}
+				return nil
+			}),
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}))
+}
@@ -0,0 +1,213 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicas_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioner/replicas"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/controllers/state/informer"
+	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/operator/controller"
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/test"
+	. "github.com/aws/karpenter-core/pkg/test/expectations"
+)
+
+var ctx context.Context
+var env *test.Environment
+var fakeClock *clock.FakeClock
+var cluster *state.Cluster
+var cloudProvider *fake.CloudProvider
+var replicasController controller.Controller
+var nodeStateController controller.Controller
+var machineStateController controller.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replicas")
+}
+
+var _ = BeforeSuite(func() {
+	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...))
+	ctx = settings.ToContext(ctx, test.Settings())
+	cloudProvider = fake.NewCloudProvider()
+	fakeClock = clock.NewFakeClock(time.Now())
+	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
+	nodeStateController = informer.NewNodeController(env.Client, cluster)
+	machineStateController = informer.NewMachineController(env.Client, cluster)
+	prov := provisioning.NewProvisioner(env.Client, corev1.NewForConfigOrDie(env.Config), events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster)
+	replicasController = replicas.NewProvisionerController(env.Client, cluster, cloudProvider, prov)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	cloudProvider.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+	cluster.Reset()
+})
+
+var _ = Describe("Replicas", func() {
+	var provisioner *v1alpha5.Provisioner
+
+	BeforeEach(func() {
+		provisioner = test.Provisioner()
+		provisioner.Spec.Replicas = ptr.Int32(3)
+	})
+	// existingMachines creates initialized machines and nodes for the provisioner and informs cluster state about them
+	existingMachines := func(count int) []*v1alpha5.Machine {
+		var machines []*v1alpha5.Machine
+		for i := 0; i < count; i++ {
+			machine, node := test.MachineAndNode(v1alpha5.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name},
+				},
+			})
+			ExpectApplied(ctx, env.Client, machine, node)
+			ExpectMakeNodesInitialized(ctx, env.Client, node)
+			ExpectMakeMachinesInitialized(ctx, env.Client, machine)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			ExpectReconcileSucceeded(ctx, machineStateController, client.ObjectKeyFromObject(machine))
+			machines = append(machines, machine)
+		}
+		return machines
+	}
+
+	It("should launch machines to reach the replicas", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		machines := ExpectMachines(ctx, env.Client)
+		Expect(machines).To(HaveLen(3))
+		for _, m := range machines {
+			Expect(m.Labels).To(HaveKeyWithValue(v1alpha5.ProvisionerNameLabelKey, provisioner.Name))
+		}
+	})
+	It("should only launch the machines that are missing", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		existingMachines(2)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(3))
+	})
+	It("should scale up when the replicas are increased", func() {
+		provisioner.Spec.Replicas = ptr.Int32(1)
+		ExpectApplied(ctx, env.Client, provisioner)
+		existingMachines(1)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(1))
+
+		provisioner.Spec.Replicas = ptr.Int32(4)
+		ExpectApplied(ctx, env.Client, provisioner)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(4))
+	})
+	It("should not remove machines when the replicas are decreased", func() {
+		provisioner.Spec.Replicas = ptr.Int32(1)
+		ExpectApplied(ctx, env.Client, provisioner)
+		machines := existingMachines(3)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		// Scaling down is left to deprovisioning, so the machines beyond the replicas are kept
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(3))
+		for _, m := range machines {
+			Expect(ExpectExists(ctx, env.Client, m).DeletionTimestamp.IsZero()).To(BeTrue())
+		}
+	})
+	It("should replace machines that are marked for deletion", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		machines := existingMachines(3)
+		cluster.MarkForDeletion(machines[0].Status.ProviderID)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(4))
+	})
+	It("should not count machines of other provisioners", func() {
+		other := test.Provisioner()
+		ExpectApplied(ctx, env.Client, provisioner, other)
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{v1alpha5.ProvisionerNameLabelKey: other.Name},
+			},
+		})
+		ExpectApplied(ctx, env.Client, machine, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		ExpectReconcileSucceeded(ctx, machineStateController, client.ObjectKeyFromObject(machine))
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(4))
+	})
+	It("should not launch machines when the replicas aren't set", func() {
+		provisioner.Spec.Replicas = nil
+		ExpectApplied(ctx, env.Client, provisioner)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(0))
+	})
+	It("should not launch machines when the provisioner is deleting", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		ExpectDeletionTimestampSet(ctx, env.Client, provisioner)
+		ExpectReconcileSucceeded(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(0))
+	})
+	It("should not launch machines when the provisioner limits are exceeded", func() {
+		provisioner = test.Provisioner(test.ProvisionerOptions{
+			Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			Status: v1alpha5.ProvisionerStatus{Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}},
+		})
+		provisioner.Spec.Replicas = ptr.Int32(3)
+		ExpectApplied(ctx, env.Client, provisioner)
+		ExpectReconcileFailed(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(0))
+	})
+	It("should not launch machines when no instance type satisfies the provisioner requirements", func() {
+		provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
+			{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"unknown-instance-type"}},
+		}
+		ExpectApplied(ctx, env.Client, provisioner)
+		ExpectReconcileFailed(ctx, replicasController, client.ObjectKeyFromObject(provisioner))
+
+		Expect(ExpectMachines(ctx, env.Client)).To(HaveLen(0))
+	})
+})
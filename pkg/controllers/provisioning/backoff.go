@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// unschedulableBaseBackoff is how long we wait before simulating a pod again after it first failed to schedule
+	unschedulableBaseBackoff = 10 * time.Second
+	// unschedulableMaxBackoff is the longest we wait before simulating a pod that keeps failing to schedule again
+	unschedulableMaxBackoff = 5 * time.Minute
+)
+
+// UnschedulablePods tracks the pending pods that fail to schedule and backs off simulating them exponentially, so that
+// pods with requirements that can never be satisfied don't get simulated in every provisioning loop. A pod is
+// simulated again as soon as it is updated.
+type UnschedulablePods struct {
+	clock clock.Clock
+
+	mu   sync.Mutex
+	pods map[types.UID]*unschedulablePod
+}
+
+type unschedulablePod struct {
+	resourceVersion string
+	failures        int
+	retryAt         time.Time
+}
+
+func NewUnschedulablePods(clk clock.Clock) *UnschedulablePods {
+	return &UnschedulablePods{
+		clock: clk,
+		pods:  map[types.UID]*unschedulablePod{},
+	}
+}
+
+// Filter returns the pods that aren't backed off. Pods that are no longer pending are forgotten.
+func (u *UnschedulablePods) Filter(pods []*v1.Pod) []*v1.Pod {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	pending := lo.SliceToMap(pods, func(p *v1.Pod) (types.UID, *v1.Pod) { return p.UID, p })
+	for uid := range u.pods {
+		if _, ok := pending[uid]; !ok {
+			delete(u.pods, uid)
+		}
+	}
+	return lo.Filter(pods, func(p *v1.Pod, _ int) bool {
+		entry, ok := u.pods[p.UID]
+		return !ok || entry.resourceVersion != p.ResourceVersion || !u.clock.Now().Before(entry.retryAt)
+	})
+}
+
+// Failed records that the pod failed to schedule and returns the number of consecutive failures and how long the pod
+// is backed off for
+func (u *UnschedulablePods) Failed(p *v1.Pod) (int, time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry, ok := u.pods[p.UID]
+	if !ok || entry.resourceVersion != p.ResourceVersion {
+		entry = &unschedulablePod{resourceVersion: p.ResourceVersion}
+		u.pods[p.UID] = entry
+	}
+	entry.failures++
+	backoff := backoffFor(entry.failures)
+	entry.retryAt = u.clock.Now().Add(backoff)
+	return entry.failures, backoff
+}
+
+// Scheduled forgets the failures of the pod
+func (u *UnschedulablePods) Scheduled(p *v1.Pod) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.pods, p.UID)
+}
+
+// Unsatisfiable returns the number of pods that have reached the maximum backoff
+func (u *UnschedulablePods) Unsatisfiable() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return lo.CountBy(lo.Values(u.pods), func(entry *unschedulablePod) bool {
+		return backoffFor(entry.failures) == unschedulableMaxBackoff
+	})
+}
+
+func backoffFor(failures int) time.Duration {
+	backoff := unschedulableBaseBackoff
+	for i := 1; i < failures && backoff < unschedulableMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return lo.Min([]time.Duration{backoff, unschedulableMaxBackoff})
+}
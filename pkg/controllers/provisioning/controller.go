@@ -16,15 +16,24 @@ package provisioning
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/logging"
 	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	scheduler "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/events"
 	corecontroller "github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/utils/pod"
@@ -54,6 +63,16 @@ func (c *Controller) Name() string {
 
 // Reconcile the resource
 func (c *Controller) Reconcile(_ context.Context, p *v1.Pod) (reconcile.Result, error) {
+	// Gated pods are re-evaluated when the pod is updated to remove its gates
+	if pod.IsSchedulingGated(p) && !pod.IsScheduled(p) {
+		c.recorder.Publish(scheduler.PodSchedulingGatedEvent(p))
+		return reconcile.Result{}, nil
+	}
+	// We can't satisfy resource claims, so we surface why no capacity is launched rather than silently ignoring the pod
+	if pod.HasResourceClaims(p) && !pod.IsScheduled(p) && pod.FailedToSchedule(p) {
+		c.recorder.Publish(scheduler.PodResourceClaimsUnsupportedEvent(p))
+		return reconcile.Result{}, nil
+	}
 	if !pod.IsProvisionable(p) {
 		return reconcile.Result{}, nil
 	}
@@ -65,10 +84,50 @@ func (c *Controller) Reconcile(_ context.Context, p *v1.Pod) (reconcile.Result,
 	return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 }
 
-func (c *Controller) Builder(_ context.Context, m manager.Manager) corecontroller.Builder {
+func (c *Controller) Builder(ctx context.Context, m manager.Manager) corecontroller.Builder {
 	return corecontroller.Adapt(controllerruntime.
 		NewControllerManagedBy(m).
 		For(&v1.Pod{}).
+		// Pods with volumes that wait for their first consumer are provisioned against the topology of their storage
+		// class, so we re-evaluate them once their claims bind to volumes with a known topology
+		Watches(
+			&source.Kind{Type: &v1.PersistentVolumeClaim{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return c.podsForPersistentVolumeClaim(ctx, o.(*v1.PersistentVolumeClaim))
+			}),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(e event.CreateEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectOld.(*v1.PersistentVolumeClaim).Spec.VolumeName == "" && e.ObjectNew.(*v1.PersistentVolumeClaim).Spec.VolumeName != ""
+				},
+				DeleteFunc: func(e event.DeleteEvent) bool { return false },
+			}),
+		).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 10}),
 	)
 }
+
+// podsForPersistentVolumeClaim returns requests for the provisionable pods that mount the persistent volume claim,
+// either directly or through a generic ephemeral volume
+func (c *Controller) podsForPersistentVolumeClaim(ctx context.Context, pvc *v1.PersistentVolumeClaim) []reconcile.Request {
+	podList := &v1.PodList{}
+	if err := c.kubeClient.List(ctx, podList, client.InNamespace(pvc.Namespace)); err != nil {
+		logging.FromContext(ctx).Errorf("listing pods for persistent volume claim, %s", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range podList.Items {
+		p := &podList.Items[i]
+		if !pod.IsProvisionable(p) {
+			continue
+		}
+		if lo.ContainsBy(p.Spec.Volumes, func(volume v1.Volume) bool {
+			return (volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == pvc.Name) ||
+				// generated name per https://kubernetes.io/docs/concepts/storage/ephemeral-volumes/#persistentvolumeclaim-naming
+				(volume.Ephemeral != nil && fmt.Sprintf("%s-%s", p.Name, volume.Name) == pvc.Name)
+		}) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(p)})
+		}
+	}
+	return requests
+}
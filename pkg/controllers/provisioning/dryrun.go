@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	scheduler "github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
+)
+
+const (
+	// DryRunPath is the path on the metrics endpoint that provisioning dry runs are served on when enabled
+	DryRunPath = "/provisioning/dry-run"
+	// maxDryRunRequestBytes is the largest DryRunRequest body that's accepted
+	maxDryRunRequestBytes = 10 << 20
+	// maxConcurrentDryRuns is the number of dry runs that are simulated at once, further requests are rejected since
+	// each simulation is as expensive as a provisioning loop
+	maxConcurrentDryRuns = 1
+)
+
+// DryRunRequest is the body of a provisioning dry run request
+type DryRunRequest struct {
+	Pods []*v1.Pod `json:"pods"`
+}
+
+// DryRunResponse describes the capacity that would be provisioned for the pods of a DryRunRequest
+type DryRunResponse struct {
+	// NodeClaims are the machines or node claims that would be launched
+	NodeClaims []DryRunNodeClaim `json:"nodeClaims"`
+	// ExistingNodes maps the names of existing nodes to the pods that would schedule to them
+	ExistingNodes map[string][]string `json:"existingNodes,omitempty"`
+	// PodErrors maps the pods that couldn't be scheduled to the reason why
+	PodErrors map[string]string `json:"podErrors,omitempty"`
+}
+
+type DryRunNodeClaim struct {
+	OwnerKind     string                       `json:"ownerKind"`
+	Owner         string                       `json:"owner"`
+	InstanceTypes []string                     `json:"instanceTypes"`
+	Requirements  []v1.NodeSelectorRequirement `json:"requirements"`
+	Requests      v1.ResourceList              `json:"requests"`
+	Pods          []string                     `json:"pods"`
+}
+
+// DryRun simulates provisioning for the pods against the current cluster state and Provisioners, without launching
+// anything. Pods without a namespace, name or UID are defaulted.
+func (p *Provisioner) DryRun(ctx context.Context, pods ...*v1.Pod) (*DryRunResponse, error) {
+	response := &DryRunResponse{ExistingNodes: map[string][]string{}, PodErrors: map[string]string{}}
+	var schedulable []*v1.Pod
+	for i, pod := range pods {
+		pod = pod.DeepCopy()
+		pod.Namespace = lo.Ternary(pod.Namespace == "", "default", pod.Namespace)
+		pod.Name = lo.Ternary(pod.Name == "", fmt.Sprintf("dry-run-%d", i), pod.Name)
+		pod.UID = lo.Ternary(pod.UID == "", uuid.NewUUID(), pod.UID)
+		if err := p.Validate(ctx, pod); err != nil {
+			response.PodErrors[client.ObjectKeyFromObject(pod).String()] = err.Error()
+			continue
+		}
+		schedulable = append(schedulable, pod)
+	}
+	if len(schedulable) == 0 {
+		return response, nil
+	}
+	s, err := p.NewScheduler(ctx, schedulable, p.cluster.Nodes().Active(), scheduler.SchedulerOptions{SimulationMode: true})
+	if err != nil {
+		return nil, fmt.Errorf("creating scheduler, %w", err)
+	}
+	results, err := s.Solve(ctx, schedulable)
+	if err != nil {
+		return nil, fmt.Errorf("scheduling pods, %w", err)
+	}
+	podKey := func(pod *v1.Pod, _ int) string { return client.ObjectKeyFromObject(pod).String() }
+	for _, n := range results.NewNodeClaims {
+		response.NodeClaims = append(response.NodeClaims, DryRunNodeClaim{
+			OwnerKind:     n.OwnerKind(),
+			Owner:         n.OwnerKey.Name,
+			InstanceTypes: lo.Map(n.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }),
+			Requirements:  n.Requirements.NodeSelectorRequirements(),
+			Requests:      n.Spec.Resources.Requests,
+			Pods:          lo.Map(n.Pods, podKey),
+		})
+	}
+	for _, n := range results.ExistingNodes {
+		if len(n.Pods) > 0 {
+			response.ExistingNodes[n.Name()] = lo.Map(n.Pods, podKey)
+		}
+	}
+	for pod, err := range results.PodErrors {
+		response.PodErrors[podKey(pod, 0)] = err.Error()
+	}
+	return response, nil
+}
+
+// DryRunHandler serves provisioning dry runs, accepting a DryRunRequest and responding with a DryRunResponse. The
+// metrics endpoint that it's served on isn't authenticated, so anyone who can reach it is able to run simulations and
+// learn about the cluster's nodes and Provisioners. Requests are bounded in size and rejected while another dry run is
+// in progress so that they can't starve the provisioning loop.
+func (p *Provisioner) DryRunHandler(ctx context.Context) http.Handler {
+	inflight := make(chan struct{}, maxConcurrentDryRuns)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case inflight <- struct{}{}:
+			defer func() { <-inflight }()
+		default:
+			http.Error(w, "a provisioning dry run is already in progress", http.StatusTooManyRequests)
+			return
+		}
+		request := &DryRunRequest{}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDryRunRequestBytes)).Decode(request); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, fmt.Sprintf("decoding request, %s", err), http.StatusBadRequest)
+			return
+		}
+		response, err := p.DryRun(ctx, request.Pods...)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("provisioning dry run, %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logging.FromContext(ctx).Errorf("encoding provisioning dry run response, %s", err)
+		}
+	})
+}
@@ -22,7 +22,7 @@ import (
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(schedulingDuration)
+	crmetrics.Registry.MustRegister(schedulingDuration, unsatisfiablePods)
 }
 
 var schedulingDuration = prometheus.NewHistogram(
@@ -34,3 +34,12 @@ var schedulingDuration = prometheus.NewHistogram(
 		Buckets:   metrics.DurationBuckets(),
 	},
 )
+
+var unsatisfiablePods = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "provisioner",
+		Name:      "unsatisfiable_pods",
+		Help:      "Number of pending pods that repeatedly failed to schedule and are retried at the maximum backoff.",
+	},
+)
@@ -26,10 +26,14 @@ import (
 	"go.uber.org/multierr"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -42,6 +46,7 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
+	"github.com/aws/karpenter-core/pkg/operator/injection"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/utils/functional"
 	"github.com/aws/karpenter-core/pkg/utils/pretty"
@@ -84,19 +89,25 @@ type Provisioner struct {
 	cluster        *state.Cluster
 	recorder       events.Recorder
 	cm             *pretty.ChangeMonitor
+	// schedulingCache holds the scheduling state of existing nodes between provisioning batches
+	schedulingCache *scheduler.Cache
+	// unschedulablePods backs off simulating pending pods that repeatedly fail to schedule
+	unschedulablePods *UnschedulablePods
 }
 
 func NewProvisioner(kubeClient client.Client, coreV1Client corev1.CoreV1Interface,
 	recorder events.Recorder, cloudProvider cloudprovider.CloudProvider, cluster *state.Cluster) *Provisioner {
 	p := &Provisioner{
-		batcher:        NewBatcher(),
-		cloudProvider:  cloudProvider,
-		kubeClient:     kubeClient,
-		coreV1Client:   coreV1Client,
-		volumeTopology: scheduler.NewVolumeTopology(kubeClient),
-		cluster:        cluster,
-		recorder:       recorder,
-		cm:             pretty.NewChangeMonitor(),
+		batcher:           NewBatcher(),
+		cloudProvider:     cloudProvider,
+		kubeClient:        kubeClient,
+		coreV1Client:      coreV1Client,
+		volumeTopology:    scheduler.NewVolumeTopology(kubeClient),
+		cluster:           cluster,
+		recorder:          recorder,
+		cm:                pretty.NewChangeMonitor(),
+		schedulingCache:   scheduler.NewCache(),
+		unschedulablePods: NewUnschedulablePods(clock.RealClock{}),
 	}
 	return p
 }
@@ -109,7 +120,10 @@ func (p *Provisioner) Trigger() {
 	p.batcher.Trigger()
 }
 
-func (p *Provisioner) Builder(_ context.Context, mgr manager.Manager) controller.Builder {
+func (p *Provisioner) Builder(ctx context.Context, mgr manager.Manager) controller.Builder {
+	if injection.GetOptions(ctx).EnableProvisioningDryRun {
+		lo.Must0(mgr.AddMetricsExtraHandler(DryRunPath, p.DryRunHandler(ctx)), "setting up provisioning dry run")
+	}
 	return controller.NewSingletonManagedBy(mgr)
 }
 
@@ -127,7 +141,7 @@ func (p *Provisioner) Reconcile(ctx context.Context, _ reconcile.Request) (resul
 	}
 
 	// Schedule pods to potential nodes, exit if nothing to do
-	results, err := p.Schedule(ctx)
+	results, err := p.schedule(ctx, true)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -227,13 +241,24 @@ func (p *Provisioner) NewScheduler(ctx context.Context, pods []*v1.Pod, stateNod
 
 	for i := range nodePoolList.Items {
 		nodePool := &nodePoolList.Items[i]
-		// Create node template
-		nodeClaimTemplates = append(nodeClaimTemplates, scheduler.NewNodeClaimTemplate(nodePool))
-		// Get instance type options
-		instanceTypeOptions, err := p.cloudProvider.GetInstanceTypes(ctx, provisionerutil.New(nodePool))
+		nodeClaimTemplate := scheduler.NewNodeClaimTemplate(nodePool)
+		// Get instance type options, CloudProviders may pre-filter them with the requirements of the NodePool
+		instanceTypeOptions, err := p.cloudProvider.GetInstanceTypes(cloudprovider.WithRequirements(ctx, nodeClaimTemplate.Requirements), provisionerutil.New(nodePool))
 		if err != nil {
+			// A misconfigured NodePool shouldn't block provisioning for the other NodePools
+			if cloudprovider.IsTerminalError(err) {
+				logging.FromContext(ctx).With(lo.Ternary(nodePool.IsProvisioner, "provisioner", "nodepool"), nodePool.Name).Errorf("skipping, getting instance types, %s", err)
+				continue
+			}
 			return nil, fmt.Errorf("getting instance types, %w", err)
 		}
+		// CloudProviders that ignore the requirements return every instance type, so incompatible ones are dropped here
+		instanceTypeOptions = lo.Filter(instanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+			return it.Requirements.Intersects(nodeClaimTemplate.Requirements) == nil
+		})
+		instanceTypeOptions = cloudprovider.WithPrices(p.cloudProvider, instanceTypeOptions)
+		// Create node template
+		nodeClaimTemplates = append(nodeClaimTemplates, nodeClaimTemplate)
 		if len(instanceTypeOptions) == 0 {
 			logging.FromContext(ctx).With(lo.Ternary(nodePool.IsProvisioner, "provisioner", "nodepool"), nodePool.Name).Info("skipping, no resolved instance types found")
 			continue
@@ -284,10 +309,19 @@ func (p *Provisioner) NewScheduler(ctx context.Context, pods []*v1.Pod, stateNod
 	if err != nil {
 		return nil, fmt.Errorf("getting daemon pods, %w", err)
 	}
+	if opts.Cache == nil {
+		opts.Cache = p.schedulingCache
+	}
 	return scheduler.NewScheduler(ctx, p.kubeClient, nodeClaimTemplates, nodePoolList.Items, p.cluster, stateNodes, topology, instanceTypes, daemonSetPods, p.recorder, opts), nil
 }
 
 func (p *Provisioner) Schedule(ctx context.Context) (*scheduler.Results, error) {
+	return p.schedule(ctx, false)
+}
+
+// schedule simulates scheduling the pending pods and the pods on deleting nodes. If backoff is set, pending pods that
+// repeatedly failed to schedule are skipped until their backoff expires.
+func (p *Provisioner) schedule(ctx context.Context, backoff bool) (*scheduler.Results, error) {
 	defer metrics.Measure(schedulingDuration)()
 
 	// We collect the nodes with their used capacities before we get the list of pending pods. This ensures that
@@ -306,6 +340,14 @@ func (p *Provisioner) Schedule(ctx context.Context) (*scheduler.Results, error)
 	if err != nil {
 		return nil, err
 	}
+	// Pods that a previous pass launched a node claim for are skipped until the node claim launches, so that we don't
+	// provision for them twice. They're released if the node claim fails to launch.
+	pendingPods = lo.Reject(pendingPods, func(pod *v1.Pod, _ int) bool {
+		return p.cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))
+	})
+	if backoff {
+		pendingPods = p.unschedulablePods.Filter(pendingPods)
+	}
 	// Get pods from nodes that are preparing for deletion
 	// We do this after getting the pending pods so that we undershoot if pods are
 	// actively migrating from a node that is being deleted
@@ -327,7 +369,27 @@ func (p *Provisioner) Schedule(ctx context.Context) (*scheduler.Results, error)
 		}
 		return nil, fmt.Errorf("creating scheduler, %w", err)
 	}
-	return s.Solve(ctx, pods)
+	results, err := s.Solve(ctx, pods)
+	if err != nil {
+		return nil, err
+	}
+	if backoff {
+		p.recordUnschedulablePods(pendingPods, results)
+	}
+	return results, nil
+}
+
+// recordUnschedulablePods backs off the pending pods that failed to schedule and forgets the ones that scheduled
+func (p *Provisioner) recordUnschedulablePods(pendingPods []*v1.Pod, results *scheduler.Results) {
+	for _, pod := range pendingPods {
+		if _, ok := results.PodErrors[pod]; !ok {
+			p.unschedulablePods.Scheduled(pod)
+			continue
+		}
+		failures, backoff := p.unschedulablePods.Failed(pod)
+		p.recorder.Publish(scheduler.PodSchedulingBackoffEvent(pod, failures, backoff))
+	}
+	unsatisfiablePods.Set(float64(p.unschedulablePods.Unsatisfiable()))
 }
 
 func (p *Provisioner) Launch(ctx context.Context, n *scheduler.NodeClaim, opts ...functional.Option[LaunchOptions]) (nodeclaimutil.Key, error) {
@@ -357,12 +419,14 @@ func (p *Provisioner) launchMachine(ctx context.Context, n *scheduler.NodeClaim,
 		metrics.ReasonLabel:      options.Reason,
 		metrics.ProvisionerLabel: machine.Labels[v1alpha5.ProvisionerNameLabelKey],
 	}).Inc()
+	key := nodeclaimutil.Key{Name: machine.Name, IsMachine: true}
 	if functional.ResolveOptions(opts...).RecordPodNomination {
+		p.cluster.NominatePodsForNodeClaim(ctx, key, n.Pods...)
 		for _, pod := range n.Pods {
 			p.recorder.Publish(scheduler.NominatePodEvent(pod, nil, nodeclaimutil.New(machine)))
 		}
 	}
-	return nodeclaimutil.Key{Name: machine.Name, IsMachine: true}, nil
+	return key, nil
 }
 
 func (p *Provisioner) launchNodeClaim(ctx context.Context, n *scheduler.NodeClaim, opts ...functional.Option[LaunchOptions]) (nodeclaimutil.Key, error) {
@@ -385,12 +449,14 @@ func (p *Provisioner) launchNodeClaim(ctx context.Context, n *scheduler.NodeClai
 		metrics.ReasonLabel:   options.Reason,
 		metrics.NodePoolLabel: nodeClaim.Labels[v1beta1.NodePoolLabelKey],
 	}).Inc()
+	key := nodeclaimutil.Key{Name: nodeClaim.Name}
 	if functional.ResolveOptions(opts...).RecordPodNomination {
+		p.cluster.NominatePodsForNodeClaim(ctx, key, n.Pods...)
 		for _, pod := range n.Pods {
 			p.recorder.Publish(scheduler.NominatePodEvent(pod, nil, nodeClaim))
 		}
 	}
-	return nodeclaimutil.Key{Name: nodeClaim.Name}, nil
+	return key, nil
 }
 
 func instanceTypeList(names []string) string {
@@ -414,13 +480,40 @@ func (p *Provisioner) getDaemonSetPods(ctx context.Context) ([]*v1.Pod, error) {
 		return nil, fmt.Errorf("listing daemonsets, %w", err)
 	}
 
-	return lo.Map(daemonSetList.Items, func(d appsv1.DaemonSet, _ int) *v1.Pod {
-		pod := p.cluster.GetDaemonSetPod(&d)
+	var pods []*v1.Pod
+	for i := range daemonSetList.Items {
+		pod := p.cluster.GetDaemonSetPod(&daemonSetList.Items[i])
 		if pod == nil {
-			pod = &v1.Pod{Spec: d.Spec.Template.Spec}
+			d := daemonSetList.Items[i]
+			// the daemonset's identity is used to track the pod's host port usage during scheduling
+			pod = &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: d.Name, Namespace: d.Namespace}, Spec: *d.Spec.Template.Spec.DeepCopy()}
+			if err := p.injectPodOverhead(ctx, pod); err != nil {
+				return nil, err
+			}
 		}
-		return pod
-	}), nil
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// injectPodOverhead sets the pod overhead from the pod's RuntimeClass. This is normally done by the RuntimeClass
+// admission controller when the pod is created, so pods that we construct from a template won't have it set.
+func (p *Provisioner) injectPodOverhead(ctx context.Context, pod *v1.Pod) error {
+	if pod.Spec.RuntimeClassName == nil || pod.Spec.Overhead != nil {
+		return nil
+	}
+	runtimeClass := &nodev1.RuntimeClass{}
+	if err := p.kubeClient.Get(ctx, types.NamespacedName{Name: *pod.Spec.RuntimeClassName}, runtimeClass); err != nil {
+		// the daemonset's pods will fail to be admitted until the RuntimeClass exists
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("getting runtime class, %w", err)
+	}
+	if runtimeClass.Overhead != nil {
+		pod.Spec.Overhead = runtimeClass.Overhead.PodFixed
+	}
+	return nil
 }
 
 func (p *Provisioner) Validate(ctx context.Context, pod *v1.Pod) error {
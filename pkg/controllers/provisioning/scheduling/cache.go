@@ -0,0 +1,175 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduling
+
+import (
+	"sync"
+
+	"github.com/mitchellh/hashstructure/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+)
+
+// Cache stores the scheduling state of existing nodes that doesn't change between provisioning batches, so that
+// each batch only recomputes it for the nodes and pods that have changed. Entries are keyed by node name and
+// invalidated whenever the node's labels or taints change. A nil Cache disables caching.
+type Cache struct {
+	mu    sync.Mutex
+	nodes map[string]*cachedNode
+}
+
+type cachedNode struct {
+	// fingerprint is the hash of the node labels and taints that the entry was computed from
+	fingerprint uint64
+	// daemonFingerprint is the hash of the daemonset pods that daemonRequests was computed from
+	daemonFingerprint uint64
+	daemonRequests    v1.ResourceList
+	// incompatible maps a pod fingerprint to the error returned when checking the pod against the node labels and taints
+	incompatible map[uint64]error
+}
+
+func NewCache() *Cache {
+	return &Cache{nodes: map[string]*cachedNode{}}
+}
+
+// Register returns the fingerprint of the node, dropping any cached state computed for a previous version of it
+func (c *Cache) Register(node *state.StateNode) (uint64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	fingerprint, err := hashstructure.Hash(struct {
+		Labels map[string]string
+		Taints []v1.Taint
+	}{
+		Labels: node.Labels(),
+		Taints: node.Taints(),
+	}, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.nodes[node.Name()]; !ok || entry.fingerprint != fingerprint {
+		c.nodes[node.Name()] = &cachedNode{fingerprint: fingerprint, incompatible: map[uint64]error{}}
+	}
+	return fingerprint, true
+}
+
+// DaemonRequests returns the requests of the daemonset pods that schedule to the node, only calling compute if the
+// node or the daemonset pods have changed since they were last computed
+func (c *Cache) DaemonRequests(name string, fingerprint uint64, daemonFingerprint uint64, compute func() v1.ResourceList) v1.ResourceList {
+	entry, ok := c.entry(name, fingerprint)
+	if !ok {
+		return compute()
+	}
+	c.mu.Lock()
+	if entry.daemonRequests != nil && entry.daemonFingerprint == daemonFingerprint {
+		defer c.mu.Unlock()
+		return entry.daemonRequests.DeepCopy()
+	}
+	c.mu.Unlock()
+
+	requests := compute()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.daemonFingerprint = daemonFingerprint
+	entry.daemonRequests = requests.DeepCopy()
+	return requests
+}
+
+// Compatible checks the pod against the node labels and taints, returning a cached error if the pod has already
+// been found to be incompatible with the node. Compatible pods are always re-checked by ExistingNode.Add.
+func (c *Cache) Compatible(node *ExistingNode, pod *v1.Pod, podFingerprint uint64) error {
+	if !node.cacheable {
+		return node.compatible(pod)
+	}
+	entry, ok := c.entry(node.Name(), node.fingerprint)
+	if !ok {
+		return node.compatible(pod)
+	}
+	c.mu.Lock()
+	err, found := entry.incompatible[podFingerprint]
+	c.mu.Unlock()
+	if found {
+		return err
+	}
+	if err = node.compatible(pod); err != nil {
+		c.mu.Lock()
+		entry.incompatible[podFingerprint] = err
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// Prune removes the cached state of nodes that are no longer in the cluster
+func (c *Cache) Prune(nodes []*state.StateNode) {
+	if c == nil {
+		return
+	}
+	names := sets.NewString()
+	for _, node := range nodes {
+		names.Insert(node.Name())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.nodes {
+		if !names.Has(name) {
+			delete(c.nodes, name)
+		}
+	}
+}
+
+func (c *Cache) entry(name string, fingerprint uint64) (*cachedNode, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.nodes[name]
+	if !ok || entry.fingerprint != fingerprint {
+		return nil, false
+	}
+	return entry, true
+}
+
+// podFingerprint hashes the fields of the pod that determine whether it is compatible with a node's labels and taints
+func podFingerprint(pod *v1.Pod) (uint64, bool) {
+	var nodeAffinity *v1.NodeAffinity
+	if pod.Spec.Affinity != nil {
+		nodeAffinity = pod.Spec.Affinity.NodeAffinity
+	}
+	fingerprint, err := hashstructure.Hash(struct {
+		NodeSelector map[string]string
+		NodeAffinity *v1.NodeAffinity
+		Tolerations  []v1.Toleration
+	}{
+		NodeSelector: pod.Spec.NodeSelector,
+		NodeAffinity: nodeAffinity,
+		Tolerations:  pod.Spec.Tolerations,
+	}, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	return fingerprint, err == nil
+}
+
+// daemonSetPodsFingerprint hashes the daemonset pods that are used to compute the daemon requests of existing nodes
+func daemonSetPodsFingerprint(daemonSetPods []*v1.Pod) (uint64, bool) {
+	specs := make([]v1.PodSpec, 0, len(daemonSetPods))
+	for _, p := range daemonSetPods {
+		specs = append(specs, p.Spec)
+	}
+	fingerprint, err := hashstructure.Hash(specs, hashstructure.FormatV2, nil)
+	return fingerprint, err == nil
+}
@@ -48,6 +48,63 @@ func NominatePodEvent(pod *v1.Pod, node *v1.Node, nodeClaim *v1beta1.NodeClaim)
 	}
 }
 
+func PodSchedulingGatedEvent(pod *v1.Pod) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeNormal,
+		Reason:         "SchedulingGated",
+		Message: fmt.Sprintf("Not provisioning capacity until scheduling gates are removed: %s",
+			strings.Join(lo.Map(pod.Spec.SchedulingGates, func(g v1.PodSchedulingGate, _ int) string { return g.Name }), ", ")),
+		DedupeValues:  []string{string(pod.UID)},
+		DedupeTimeout: 5 * time.Minute,
+	}
+}
+
+func PodResourceClaimsUnsupportedEvent(pod *v1.Pod) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ResourceClaimsUnsupported",
+		Message: fmt.Sprintf("Not provisioning capacity since dynamic resource allocation isn't supported, pod references resource claims: %s",
+			strings.Join(lo.Map(pod.Spec.ResourceClaims, func(c v1.PodResourceClaim, _ int) string { return c.Name }), ", ")),
+		DedupeValues:  []string{string(pod.UID)},
+		DedupeTimeout: 5 * time.Minute,
+	}
+}
+
+func PodPreferenceRelaxedEvent(pod *v1.Pod, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeNormal,
+		Reason:         "PreferenceRelaxed",
+		Message:        fmt.Sprintf("Relaxed preference since the pod couldn't be scheduled, %s", reason),
+		DedupeValues:   []string{string(pod.UID), reason},
+	}
+}
+
+func PodSchedulingExplanationEvent(pod *v1.Pod, explanation *Explanation) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "SchedulingExplanation",
+		Message:        truncateMessage(fmt.Sprintf("Couldn't launch capacity for pod: %s", explanation)),
+		DedupeValues:   []string{string(pod.UID)},
+		DedupeTimeout:  5 * time.Minute,
+	}
+}
+
+func PodFallbackEvent(pod *v1.Pod, nodeClaim *NodeClaim) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeNormal,
+		Reason:         "FallbackScheduling",
+		Message: truncateMessage(fmt.Sprintf("Falling back to %s %q, %s",
+			nodeClaim.OwnerKind(), nodeClaim.OwnerKey.Name, strings.Join(nodeClaim.fallbacks, "; "))),
+		DedupeValues:  []string{string(pod.UID)},
+		DedupeTimeout: 5 * time.Minute,
+	}
+}
+
 func PodFailedToScheduleEvent(pod *v1.Pod, err error) events.Event {
 	return events.Event{
 		InvolvedObject: pod,
@@ -58,3 +115,23 @@ func PodFailedToScheduleEvent(pod *v1.Pod, err error) events.Event {
 		DedupeTimeout:  5 * time.Minute,
 	}
 }
+
+func PodSchedulingBackoffEvent(pod *v1.Pod, failures int, backoff time.Duration) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "SchedulingBackoff",
+		Message:        fmt.Sprintf("Pod failed to schedule %d times, retrying in %s", failures, backoff),
+		DedupeValues:   []string{string(pod.UID)},
+		DedupeTimeout:  5 * time.Minute,
+	}
+}
+
+// truncateMessage truncates an event message to the maximum length that the API server accepts
+func truncateMessage(msg string) string {
+	const maxLength = 1024
+	if len(msg) <= maxLength {
+		return msg
+	}
+	return msg[:maxLength-3] + "..."
+}
@@ -17,22 +17,37 @@ package scheduling
 import (
 	"context"
 	"fmt"
+	"sort"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/scheduling"
+	podutils "github.com/aws/karpenter-core/pkg/utils/pod"
 	"github.com/aws/karpenter-core/pkg/utils/resources"
 )
 
 type ExistingNode struct {
 	*state.StateNode
 
-	Pods         []*v1.Pod
-	topology     *Topology
-	requests     v1.ResourceList
-	requirements scheduling.Requirements
+	Pods []*v1.Pod
+	// Preempted are the lower priority pods bound to the node that kube-scheduler is expected to preempt so that
+	// Pods can schedule
+	Preempted []*v1.Pod
+
+	topology          *Topology
+	requests          v1.ResourceList
+	requirements      scheduling.Requirements
+	labelRequirements scheduling.Requirements
+	// fingerprint identifies the node's labels and taints in the scheduling Cache
+	fingerprint uint64
+	cacheable   bool
+	// boundPods are the pods bound to the node, which are lazily loaded when simulating preemption
+	boundPods         []*v1.Pod
+	boundPodsLoaded   bool
+	preemptedRequests v1.ResourceList
 }
 
 func NewExistingNode(n *state.StateNode, topology *Topology, daemonResources v1.ResourceList) *ExistingNode {
@@ -55,10 +70,19 @@ func NewExistingNode(n *state.StateNode, topology *Topology, daemonResources v1.
 		requirements: scheduling.NewLabelRequirements(n.Labels()),
 	}
 	node.requirements.Add(scheduling.NewRequirement(v1.LabelHostname, v1.NodeSelectorOpIn, n.HostName()))
+	node.labelRequirements = node.requirements
 	topology.Register(v1.LabelHostname, n.HostName())
 	return node
 }
 
+// compatible checks the pod against the node's taints and labels, which don't change as pods are added to the node
+func (n *ExistingNode) compatible(pod *v1.Pod) error {
+	if err := scheduling.Taints(n.Taints()).Tolerates(pod); err != nil {
+		return err
+	}
+	return n.labelRequirements.StrictlyCompatible(scheduling.NewPodRequirements(pod))
+}
+
 func (n *ExistingNode) Add(ctx context.Context, kubeClient client.Client, pod *v1.Pod) error {
 	// Check Taints
 	if err := scheduling.Taints(n.Taints()).Tolerates(pod); err != nil {
@@ -82,7 +106,7 @@ func (n *ExistingNode) Add(ctx context.Context, kubeClient client.Client, pod *v
 	// node, which at this point can't be increased in size
 	requests := resources.Merge(n.requests, resources.RequestsForPods(pod))
 
-	if !resources.Fits(requests, n.Available()) {
+	if !resources.Fits(requests, n.available()) {
 		return fmt.Errorf("exceeds node resources")
 	}
 
@@ -115,8 +139,69 @@ func (n *ExistingNode) Add(ctx context.Context, kubeClient client.Client, pod *v
 	n.Pods = append(n.Pods, pod)
 	n.requests = requests
 	n.requirements = nodeRequirements
-	n.topology.Record(pod, nodeRequirements)
+	n.topology.Record(pod, n.Taints(), nodeRequirements)
 	n.HostPortUsage().Add(pod, hostPorts)
 	n.VolumeUsage().Add(pod, volumes)
 	return nil
 }
+
+// Preempt attempts to add the pod to the node by simulating kube-scheduler preempting the lowest priority pods bound
+// to the node until the pod fits. The node is only updated if the pod can be added.
+func (n *ExistingNode) Preempt(ctx context.Context, kubeClient client.Client, pod *v1.Pod) error {
+	if lo.FromPtr(pod.Spec.PreemptionPolicy) == v1.PreemptNever {
+		return fmt.Errorf("preemption policy is %s", v1.PreemptNever)
+	}
+	if err := n.compatible(pod); err != nil {
+		return err
+	}
+	candidates, err := n.preemptionCandidates(ctx, kubeClient, pod)
+	if err != nil {
+		return err
+	}
+	requests := resources.Merge(n.requests, resources.RequestsForPods(pod))
+	freed := v1.ResourceList{}
+	for i, candidate := range candidates {
+		freed = resources.Merge(freed, resources.RequestsForPods(candidate))
+		if !resources.Fits(requests, resources.Merge(n.available(), freed)) {
+			continue
+		}
+		preemptedRequests := n.preemptedRequests
+		n.preemptedRequests = resources.Merge(n.preemptedRequests, freed)
+		if err = n.Add(ctx, kubeClient, pod); err != nil {
+			n.preemptedRequests = preemptedRequests
+			return err
+		}
+		n.Preempted = append(n.Preempted, candidates[:i+1]...)
+		return nil
+	}
+	return fmt.Errorf("exceeds node resources after preempting %d lower priority pod(s)", len(candidates))
+}
+
+// preemptionCandidates returns the pods bound to the node that have a lower priority than the pod, ordered from
+// lowest to highest priority
+func (n *ExistingNode) preemptionCandidates(ctx context.Context, kubeClient client.Client, pod *v1.Pod) ([]*v1.Pod, error) {
+	if !n.boundPodsLoaded {
+		boundPods, err := n.StateNode.Pods(ctx, kubeClient)
+		if err != nil {
+			return nil, fmt.Errorf("listing pods bound to node, %w", err)
+		}
+		n.boundPods, n.boundPodsLoaded = boundPods, true
+	}
+	priority := lo.FromPtr(pod.Spec.Priority)
+	candidates := lo.Filter(n.boundPods, func(p *v1.Pod, _ int) bool {
+		return lo.FromPtr(p.Spec.Priority) < priority &&
+			!podutils.IsTerminal(p) &&
+			!podutils.IsTerminating(p) &&
+			!podutils.IsOwnedByDaemonSet(p) &&
+			!lo.Contains(n.Preempted, p)
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lo.FromPtr(candidates[i].Spec.Priority) < lo.FromPtr(candidates[j].Spec.Priority)
+	})
+	return candidates, nil
+}
+
+// available returns the node's available resources, including the resources freed by preempting pods
+func (n *ExistingNode) available() v1.ResourceList {
+	return resources.Merge(n.Available(), n.preemptedRequests)
+}
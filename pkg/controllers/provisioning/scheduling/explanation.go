@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduling
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+)
+
+// maxExplainedInstanceTypes bounds the number of instance type names that are included in an explanation
+const maxExplainedInstanceTypes = 5
+
+// Explanation describes why a pod couldn't be scheduled to new capacity from any of the NodePools that were considered
+type Explanation struct {
+	NodePools []NodePoolExplanation `json:"nodePools"`
+}
+
+// NodePoolExplanation describes why a pod couldn't be scheduled to new capacity from a single NodePool
+type NodePoolExplanation struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// InstanceTypes are the names of up to maxExplainedInstanceTypes instance types that remained after filtering by
+	// limits, and InstanceTypeCount is the total number of them
+	InstanceTypes     []string `json:"instanceTypes,omitempty"`
+	InstanceTypeCount int      `json:"instanceTypeCount"`
+	// Reason is the requirement, limit or resource constraint that eliminated the NodePool
+	Reason string `json:"reason"`
+}
+
+func (e *Explanation) add(nodeClaimTemplate *NodeClaimTemplate, instanceTypes []*cloudprovider.InstanceType, err error) {
+	e.NodePools = append(e.NodePools, NodePoolExplanation{
+		Kind: nodeClaimTemplate.OwnerKind(),
+		Name: nodeClaimTemplate.OwnerKey.Name,
+		InstanceTypes: lo.Map(lo.Slice(instanceTypes, 0, maxExplainedInstanceTypes), func(it *cloudprovider.InstanceType, _ int) string {
+			return it.Name
+		}),
+		InstanceTypeCount: len(instanceTypes),
+		Reason:            err.Error(),
+	})
+}
+
+func (e *Explanation) String() string {
+	if len(e.NodePools) == 0 {
+		return "no provisioners or nodepools were considered"
+	}
+	return strings.Join(lo.Map(e.NodePools, func(np NodePoolExplanation, _ int) string {
+		instanceTypes := fmt.Sprintf("%d instance type(s)", np.InstanceTypeCount)
+		if np.InstanceTypeCount > len(np.InstanceTypes) && len(np.InstanceTypes) > 0 {
+			instanceTypes = fmt.Sprintf("%s including %s", instanceTypes, strings.Join(np.InstanceTypes, ", "))
+		} else if len(np.InstanceTypes) > 0 {
+			instanceTypes = fmt.Sprintf("%s: %s", instanceTypes, strings.Join(np.InstanceTypes, ", "))
+		}
+		return fmt.Sprintf("%s %q considered %s, %s", np.Kind, np.Name, instanceTypes, np.Reason)
+	}), "; ")
+}
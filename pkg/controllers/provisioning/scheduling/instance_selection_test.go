@@ -593,6 +593,50 @@ var _ = Describe("Instance Type Selection", func() {
 		node := ExpectScheduled(ctx, env.Client, pod)
 		Expect(node.Labels[v1.LabelInstanceTypeStable]).To(Equal("test-instance1"))
 	})
+	It("should schedule on reserved capacity before cheaper spot and on-demand capacity", func() {
+		cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name:             "test-instance1",
+				Architecture:     "amd64",
+				OperatingSystems: sets.New(string(v1.Linux)),
+				Resources: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 0.5, Available: true},
+					{CapacityType: v1alpha5.CapacityTypeSpot, Zone: "test-zone-1a", Price: 0.1, Available: true},
+				},
+			}),
+			fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name:             "test-instance2",
+				Architecture:     "amd64",
+				OperatingSystems: sets.New(string(v1.Linux)),
+				Resources: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+				Offerings: []cloudprovider.Offering{
+					{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1a", Price: 1.0, Available: true},
+					{CapacityType: v1alpha5.CapacityTypeReserved, Zone: "test-zone-1a", Price: 1.0, Available: true},
+				},
+			}),
+		}
+		provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
+			{
+				Key:      v1alpha5.LabelCapacityType,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{v1alpha5.CapacityTypeReserved, v1alpha5.CapacityTypeSpot, v1alpha5.CapacityTypeOnDemand},
+			},
+		}
+
+		ExpectApplied(ctx, env.Client, provisioner)
+		pod := test.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels[v1.LabelInstanceTypeStable]).To(Equal("test-instance2"))
+		Expect(node.Labels[v1alpha5.LabelCapacityType]).To(Equal(v1alpha5.CapacityTypeReserved))
+	})
 })
 
 func supportedInstanceTypes(machine *v1alpha5.Machine) (res []*cloudprovider.InstanceType) {
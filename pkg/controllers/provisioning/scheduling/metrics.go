@@ -22,7 +22,7 @@ import (
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(schedulingSimulationDuration)
+	crmetrics.Registry.MustRegister(schedulingSimulationDuration, schedulingBatchPods, schedulingBatchNodes, schedulingBatchInstanceTypes)
 }
 
 var schedulingSimulationDuration = prometheus.NewHistogram(
@@ -34,3 +34,38 @@ var schedulingSimulationDuration = prometheus.NewHistogram(
 		Buckets:   metrics.DurationBuckets(),
 	},
 )
+
+var schedulingBatchPods = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "provisioner",
+		Name:      "scheduling_batch_pods",
+		Help:      "Number of pods considered by the scheduler in a single provisioning round.",
+		Buckets:   countBuckets(),
+	},
+)
+
+var schedulingBatchNodes = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "provisioner",
+		Name:      "scheduling_batch_nodes",
+		Help:      "Number of existing and in-flight nodes simulated by the scheduler in a single provisioning round.",
+		Buckets:   countBuckets(),
+	},
+)
+
+var schedulingBatchInstanceTypes = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "provisioner",
+		Name:      "scheduling_batch_instance_types",
+		Help:      "Number of instance types considered across all provisioners by the scheduler in a single provisioning round.",
+		Buckets:   countBuckets(),
+	},
+)
+
+// countBuckets returns exponential buckets from 1 to 4096 for histograms that observe the size of a scheduling round
+func countBuckets() []float64 {
+	return prometheus.ExponentialBuckets(1, 2, 13)
+}
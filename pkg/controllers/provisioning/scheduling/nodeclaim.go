@@ -16,13 +16,17 @@ package scheduling
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync/atomic"
 
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/utils/resources"
@@ -33,15 +37,24 @@ import (
 type NodeClaim struct {
 	NodeClaimTemplate
 
-	Pods            []*v1.Pod
-	topology        *Topology
-	hostPortUsage   *scheduling.HostPortUsage
-	daemonResources v1.ResourceList
+	Pods          []*v1.Pod
+	topology      *Topology
+	hostPortUsage *scheduling.HostPortUsage
+	// daemonResources is the daemonset overhead of each instance type option, keyed by instance type name
+	daemonResources map[string]v1.ResourceList
+	podRequests     v1.ResourceList
+	// restrictOfferings is set when offerings of the instance type options were filtered out by scoped limits
+	restrictOfferings bool
+	// fallbacks describes the higher weight owners that couldn't be used when this NodeClaim was created
+	fallbacks []string
 }
 
 var nodeID int64
 
-func NewNodeClaim(nodeClaimTemplate *NodeClaimTemplate, topology *Topology, daemonResources v1.ResourceList, instanceTypes []*cloudprovider.InstanceType) *NodeClaim {
+// capacitySpreadIndex is used to assign capacity spread values to launched NodeClaims in a round-robin fashion
+var capacitySpreadIndex int64
+
+func NewNodeClaim(nodeClaimTemplate *NodeClaimTemplate, topology *Topology, daemonResources map[string]v1.ResourceList, daemonHostPortUsage *scheduling.HostPortUsage, instanceTypes []*cloudprovider.InstanceType) *NodeClaim {
 	// Copy the template, and add hostname
 	hostname := fmt.Sprintf("hostname-placeholder-%04d", atomic.AddInt64(&nodeID, 1))
 	topology.Register(v1.LabelHostname, hostname)
@@ -50,13 +63,14 @@ func NewNodeClaim(nodeClaimTemplate *NodeClaimTemplate, topology *Topology, daem
 	template.Requirements.Add(nodeClaimTemplate.Requirements.Values()...)
 	template.Requirements.Add(scheduling.NewRequirement(v1.LabelHostname, v1.NodeSelectorOpIn, hostname))
 	template.InstanceTypeOptions = instanceTypes
-	template.Spec.Resources.Requests = daemonResources
+	template.Spec.Resources.Requests = minDaemonResources(instanceTypes, daemonResources)
 
 	return &NodeClaim{
 		NodeClaimTemplate: template,
-		hostPortUsage:     scheduling.NewHostPortUsage(),
+		hostPortUsage:     daemonHostPortUsage.DeepCopy(),
 		topology:          topology,
 		daemonResources:   daemonResources,
+		podRequests:       v1.ResourceList{},
 	}
 }
 
@@ -98,20 +112,21 @@ func (n *NodeClaim) Add(pod *v1.Pod) error {
 	nodeClaimRequirements.Add(topologyRequirements.Values()...)
 
 	// Check instance type combinations
-	requests := resources.Merge(n.Spec.Resources.Requests, resources.RequestsForPods(pod))
-	filtered := filterInstanceTypesByRequirements(n.InstanceTypeOptions, nodeClaimRequirements, requests)
+	podRequests := resources.Merge(n.podRequests, resources.RequestsForPods(pod))
+	filtered := filterInstanceTypesByRequirements(n.InstanceTypeOptions, nodeClaimRequirements, podRequests, n.daemonResources, n.Spec.KubeletConfiguration)
 	if len(filtered.remaining) == 0 {
 		// log the total resources being requested (daemonset + the pod)
-		cumulativeResources := resources.Merge(n.daemonResources, resources.RequestsForPods(pod))
+		cumulativeResources := resources.Merge(minDaemonResources(n.InstanceTypeOptions, n.daemonResources), resources.RequestsForPods(pod))
 		return fmt.Errorf("no instance type satisfied resources %s and requirements %s (%s)", resources.String(cumulativeResources), nodeClaimRequirements, filtered.FailureReason())
 	}
 
 	// Update node
 	n.Pods = append(n.Pods, pod)
 	n.InstanceTypeOptions = filtered.remaining
-	n.Spec.Resources.Requests = requests
+	n.podRequests = podRequests
+	n.Spec.Resources.Requests = resources.Merge(minDaemonResources(filtered.remaining, n.daemonResources), podRequests)
 	n.Requirements = nodeClaimRequirements
-	n.topology.Record(pod, nodeClaimRequirements)
+	n.topology.Record(pod, n.Spec.Taints, nodeClaimRequirements)
 	n.hostPortUsage.Add(pod, hostPorts)
 	return nil
 }
@@ -122,6 +137,46 @@ func (n *NodeClaim) FinalizeScheduling() {
 	// We need nodes to have hostnames for topology purposes, but we don't want to pass that node name on to consumers
 	// of the node as it will be displayed in error messages
 	delete(n.Requirements, v1.LabelHostname)
+	n.assignCapacitySpread()
+	if n.restrictOfferings {
+		n.restrictToOfferings()
+	}
+}
+
+// restrictToOfferings narrows the zone and capacity type requirements to the available offerings of the instance type
+// options so that offerings which were filtered out by scoped limits aren't chosen when the NodeClaim is launched
+func (n *NodeClaim) restrictToOfferings() {
+	zones, capacityTypes := sets.New[string](), sets.New[string]()
+	for _, it := range n.InstanceTypeOptions {
+		for _, o := range it.Offerings.Available() {
+			if n.Requirements.Get(v1.LabelTopologyZone).Has(o.Zone) && n.Requirements.Get(v1alpha5.LabelCapacityType).Has(o.CapacityType) {
+				zones.Insert(o.Zone)
+				capacityTypes.Insert(o.CapacityType)
+			}
+		}
+	}
+	n.Requirements.Add(
+		scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, sets.List(zones)...),
+		scheduling.NewRequirement(v1alpha5.LabelCapacityType, v1.NodeSelectorOpIn, sets.List(capacityTypes)...),
+	)
+}
+
+// assignCapacitySpread picks one of the allowed capacity spread values in a round-robin fashion if the value hasn't
+// already been narrowed down to a single value by topology or pod requirements. The label is set directly on the
+// NodeClaim since it's not resolved by the cloudprovider.
+func (n *NodeClaim) assignCapacitySpread() {
+	if !n.Requirements.Has(v1beta1.CapacitySpreadLabelKey) {
+		return
+	}
+	requirement := n.Requirements.Get(v1beta1.CapacitySpreadLabelKey)
+	if requirement.Operator() != v1.NodeSelectorOpIn {
+		return
+	}
+	values := requirement.Values()
+	sort.Strings(values)
+	value := values[(atomic.AddInt64(&capacitySpreadIndex, 1)-1)%int64(len(values))]
+	n.Requirements.Add(scheduling.NewRequirement(v1beta1.CapacitySpreadLabelKey, v1.NodeSelectorOpIn, value))
+	n.Labels = lo.Assign(n.Labels, map[string]string{v1beta1.CapacitySpreadLabelKey: value})
 }
 
 func InstanceTypeList(instanceTypeOptions []*cloudprovider.InstanceType) string {
@@ -220,7 +275,8 @@ func (r filterResults) FailureReason() string {
 }
 
 //nolint:gocyclo
-func filterInstanceTypesByRequirements(instanceTypes []*cloudprovider.InstanceType, requirements scheduling.Requirements, requests v1.ResourceList) filterResults {
+func filterInstanceTypesByRequirements(instanceTypes []*cloudprovider.InstanceType, requirements scheduling.Requirements, requests v1.ResourceList,
+	daemonResources map[string]v1.ResourceList, kubelet *v1beta1.KubeletConfiguration) filterResults {
 	results := filterResults{
 		requests:        requests,
 		requirementsMet: false,
@@ -235,7 +291,7 @@ func filterInstanceTypesByRequirements(instanceTypes []*cloudprovider.InstanceTy
 		// the tradeoff to not short circuiting on the filtering is that we can report much better error messages
 		// about why scheduling failed
 		itCompat := compatible(it, requirements)
-		itFits := fits(it, requests)
+		itFits := fits(it, requestsWithDaemonResources(it, requests, daemonResources), kubelet)
 		itHasOffering := hasOffering(it, requirements)
 
 		// track if any single instance type met a single criteria
@@ -257,12 +313,43 @@ func filterInstanceTypesByRequirements(instanceTypes []*cloudprovider.InstanceTy
 	return results
 }
 
+// requestsWithDaemonResources returns the requests with the daemonset overhead of the instance type added
+func requestsWithDaemonResources(instanceType *cloudprovider.InstanceType, requests v1.ResourceList, daemonResources map[string]v1.ResourceList) v1.ResourceList {
+	if overhead := daemonResources[instanceType.Name]; len(overhead) > 0 {
+		return resources.Merge(requests, overhead)
+	}
+	return requests
+}
+
+// minDaemonResources returns the smallest daemonset overhead across the instance types. This is used as the overhead
+// of the NodeClaim's requests so that they never exclude an instance type that fits once its own overhead is added.
+func minDaemonResources(instanceTypes []*cloudprovider.InstanceType, daemonResources map[string]v1.ResourceList) v1.ResourceList {
+	if len(instanceTypes) == 0 {
+		return v1.ResourceList{}
+	}
+	result := daemonResources[instanceTypes[0].Name].DeepCopy()
+	if result == nil {
+		return v1.ResourceList{}
+	}
+	for _, it := range instanceTypes[1:] {
+		overhead := daemonResources[it.Name]
+		for resourceName, quantity := range result {
+			if value, ok := overhead[resourceName]; !ok {
+				delete(result, resourceName)
+			} else if value.Cmp(quantity) < 0 {
+				result[resourceName] = value
+			}
+		}
+	}
+	return result
+}
+
 func compatible(instanceType *cloudprovider.InstanceType, requirements scheduling.Requirements) bool {
 	return instanceType.Requirements.Intersects(requirements) == nil
 }
 
-func fits(instanceType *cloudprovider.InstanceType, requests v1.ResourceList) bool {
-	return resources.Fits(requests, instanceType.Allocatable())
+func fits(instanceType *cloudprovider.InstanceType, requests v1.ResourceList, kubelet *v1beta1.KubeletConfiguration) bool {
+	return resources.Fits(requests, instanceType.AllocatableWithKubelet(kubelet))
 }
 
 func hasOffering(instanceType *cloudprovider.InstanceType, requirements scheduling.Requirements) bool {
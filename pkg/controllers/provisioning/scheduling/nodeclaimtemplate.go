@@ -61,8 +61,8 @@ func (i *NodeClaimTemplate) OwnerKind() string {
 }
 
 func (i *NodeClaimTemplate) ToNodeClaim(nodePool *v1beta1.NodePool) *v1beta1.NodeClaim {
-	// Order the instance types by price and only take the first 100 of them to decrease the instance type size in the requirements
-	instanceTypes := lo.Slice(i.InstanceTypeOptions.OrderByPrice(i.Requirements), 0, 100)
+	// Order the instance types by preference and then price and only take the first 100 of them to decrease the instance type size in the requirements
+	instanceTypes := lo.Slice(i.InstanceTypeOptions.OrderByPreference(i.Spec.Preferences, i.Requirements), 0, 100)
 	i.Requirements.Add(scheduling.NewRequirement(v1.LabelInstanceTypeStable, v1.NodeSelectorOpIn, lo.Map(instanceTypes, func(i *cloudprovider.InstanceType, _ int) string {
 		return i.Name
 	})...))
@@ -89,8 +89,8 @@ func (i *NodeClaimTemplate) ToNodeClaim(nodePool *v1beta1.NodePool) *v1beta1.Nod
 }
 
 func (i *NodeClaimTemplate) ToMachine(provisioner *v1alpha5.Provisioner) *v1alpha5.Machine {
-	// Order the instance types by price and only take the first 100 of them to decrease the instance type size in the requirements
-	instanceTypes := lo.Slice(i.InstanceTypeOptions.OrderByPrice(i.Requirements), 0, 100)
+	// Order the instance types by preference and then price and only take the first 100 of them to decrease the instance type size in the requirements
+	instanceTypes := lo.Slice(i.InstanceTypeOptions.OrderByPreference(i.Spec.Preferences, i.Requirements), 0, 100)
 	i.Requirements.Add(scheduling.NewRequirement(v1.LabelInstanceTypeStable, v1.NodeSelectorOpIn, lo.Map(instanceTypes, func(i *cloudprovider.InstanceType, _ int) string {
 		return i.Name
 	})...))
@@ -114,9 +114,11 @@ func (i *NodeClaimTemplate) ToMachine(provisioner *v1alpha5.Provisioner) *v1alph
 			Taints:        i.NodeClaimTemplate.Spec.Taints,
 			StartupTaints: i.NodeClaimTemplate.Spec.StartupTaints,
 			Requirements:  i.Requirements.NodeSelectorRequirements(),
+			Preferences:   i.NodeClaimTemplate.Spec.Preferences,
 			Resources: v1alpha5.ResourceRequirements{
 				Requests: i.NodeClaimTemplate.Spec.Resources.Requests,
 			},
+			CloudProvider: i.NodeClaimTemplate.Spec.CloudProvider,
 		},
 	}
 	if i.NodeClaimTemplate.Spec.KubeletConfiguration != nil {
@@ -24,37 +24,77 @@ import (
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/utils/pretty"
 )
 
+// PreferencePolicy controls whether the scheduler may relax a pod's preferences when they can't be satisfied
+type PreferencePolicy string
+
+const (
+	// PreferencePolicyRelax drops preferences one at a time until the pod can schedule
+	PreferencePolicyRelax PreferencePolicy = "Relax"
+	// PreferencePolicyRelaxAndLog drops preferences like PreferencePolicyRelax, but records an event and logs each
+	// preference that was dropped
+	PreferencePolicyRelaxAndLog PreferencePolicy = "RelaxAndLog"
+	// PreferencePolicyStrict treats preferences as requirements and never drops them
+	PreferencePolicyStrict PreferencePolicy = "Strict"
+)
+
 type Preferences struct {
 	// ToleratePreferNoSchedule controls if preference relaxation adds a toleration for PreferNoSchedule taints.  This only
 	// helps if there is a corresponding taint, so we don't always add it.
 	ToleratePreferNoSchedule bool
+	// Recorder publishes events for relaxed preferences of pods with the RelaxAndLog policy. Events aren't published if
+	// it's nil.
+	Recorder events.Recorder
 }
 
 func (p *Preferences) Relax(ctx context.Context, pod *v1.Pod) bool {
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(pod)))
-	relaxations := []func(*v1.Pod) *string{
-		p.removeRequiredNodeAffinityTerm,
-		p.removePreferredPodAffinityTerm,
-		p.removePreferredPodAntiAffinityTerm,
-		p.removePreferredNodeAffinityTerm,
-		p.removeTopologySpreadScheduleAnyway}
-
-	if p.ToleratePreferNoSchedule {
-		relaxations = append(relaxations, p.toleratePreferNoScheduleTaints)
+	policy := GetPreferencePolicy(pod)
+	// Removing a term from required node affinity doesn't drop any preferences since the terms are ORed, so it's
+	// allowed regardless of the policy
+	relaxations := []func(*v1.Pod) *string{p.removeRequiredNodeAffinityTerm}
+	if policy != PreferencePolicyStrict {
+		relaxations = append(relaxations,
+			p.removePreferredPodAffinityTerm,
+			p.removePreferredPodAntiAffinityTerm,
+			p.removePreferredNodeAffinityTerm,
+			p.removeTopologySpreadScheduleAnyway)
+		if p.ToleratePreferNoSchedule {
+			relaxations = append(relaxations, p.toleratePreferNoScheduleTaints)
+		}
 	}
 
 	for _, relaxFunc := range relaxations {
 		if reason := relaxFunc(pod); reason != nil {
-			logging.FromContext(ctx).Debugf("relaxing soft constraints for pod since it previously failed to schedule, %s", ptr.StringValue(reason))
+			if policy != PreferencePolicyRelaxAndLog {
+				logging.FromContext(ctx).Debugf("relaxing soft constraints for pod since it previously failed to schedule, %s", ptr.StringValue(reason))
+				return true
+			}
+			logging.FromContext(ctx).Infof("relaxing soft constraints for pod since it previously failed to schedule, %s", ptr.StringValue(reason))
+			if p.Recorder != nil {
+				p.Recorder.Publish(PodPreferenceRelaxedEvent(pod, ptr.StringValue(reason)))
+			}
 			return true
 		}
 	}
 	return false
 }
 
+// GetPreferencePolicy returns the preference policy for the pod, defaulting to PreferencePolicyRelax if the
+// annotation is missing or invalid
+func GetPreferencePolicy(pod *v1.Pod) PreferencePolicy {
+	switch policy := PreferencePolicy(pod.Annotations[v1beta1.PreferencePolicyAnnotationKey]); policy {
+	case PreferencePolicyRelaxAndLog, PreferencePolicyStrict:
+		return policy
+	default:
+		return PreferencePolicyRelax
+	}
+}
+
 func (p *Preferences) removePreferredNodeAffinityTerm(pod *v1.Pod) *string {
 	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil || len(pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution) == 0 {
 		return nil
@@ -23,9 +23,11 @@ import (
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
@@ -41,6 +43,8 @@ import (
 type SchedulerOptions struct {
 	// SimulationMode if true will prevent recording of the pod nomination decisions as events
 	SimulationMode bool
+	// Cache if set is used to reuse the scheduling state of existing nodes across batches
+	Cache *Cache
 }
 
 func NewScheduler(ctx context.Context, kubeClient client.Client, nodeClaimTemplates []*NodeClaimTemplate,
@@ -59,40 +63,74 @@ func NewScheduler(ctx context.Context, kubeClient client.Client, nodeClaimTempla
 		}
 	}
 
+	// nodeClaimTemplates are ordered by weight, so we move the templates of owners that were demoted after failing to
+	// launch capacity behind all other templates to fall back to lower weight owners while keeping them as a last resort
+	weightOrder := map[*NodeClaimTemplate]int{}
+	launchFailures := map[nodepoolutil.Key]*state.LaunchFailure{}
+	for i, nodeClaimTemplate := range nodeClaimTemplates {
+		weightOrder[nodeClaimTemplate] = i
+		if failure := cluster.LaunchFailure(nodeClaimTemplate.OwnerKey); failure != nil {
+			launchFailures[nodeClaimTemplate.OwnerKey] = failure
+		}
+	}
+	launchFailed := func(nodeClaimTemplate *NodeClaimTemplate, _ int) bool {
+		failure, ok := launchFailures[nodeClaimTemplate.OwnerKey]
+		return ok && failure.Demoted
+	}
+	launchFailedTemplates := lo.Filter(nodeClaimTemplates, launchFailed)
+	nodeClaimTemplates = append(lo.Reject(nodeClaimTemplates, launchFailed), launchFailedTemplates...)
+
+	daemons := getDaemons(nodeClaimTemplates, daemonSetPods)
 	s := &Scheduler{
-		ctx:                ctx,
-		kubeClient:         kubeClient,
-		nodeClaimTemplates: nodeClaimTemplates,
-		topology:           topology,
-		cluster:            cluster,
-		instanceTypes:      instanceTypes,
-		daemonOverhead:     getDaemonOverhead(nodeClaimTemplates, daemonSetPods),
-		recorder:           recorder,
-		opts:               opts,
-		preferences:        &Preferences{ToleratePreferNoSchedule: toleratePreferNoSchedule},
-		remainingResources: map[nodepoolutil.Key]v1.ResourceList{},
+		ctx:                 ctx,
+		kubeClient:          kubeClient,
+		nodeClaimTemplates:  nodeClaimTemplates,
+		weightOrder:         weightOrder,
+		launchFailures:      launchFailures,
+		launchFailed:        launchFailedTemplates,
+		topology:            topology,
+		cluster:             cluster,
+		instanceTypes:       instanceTypes,
+		daemonOverhead:      getDaemonOverhead(daemons, instanceTypes),
+		daemonHostPortUsage: getDaemonHostPortUsage(daemons),
+		recorder:            recorder,
+		opts:                opts,
+		preferences:         &Preferences{ToleratePreferNoSchedule: toleratePreferNoSchedule, Recorder: lo.Ternary[events.Recorder](opts.SimulationMode, nil, recorder)},
+		remainingResources:  map[nodepoolutil.Key]v1.ResourceList{},
+		remainingScoped:     map[nodepoolutil.Key][]*scopedRemainingResources{},
+		explanations:        map[*v1.Pod]*Explanation{},
 	}
 	for _, nodePool := range nodePools {
-		s.remainingResources[nodepoolutil.Key{Name: nodePool.Name, IsProvisioner: nodePool.IsProvisioner}] = v1.ResourceList(nodePool.Spec.Limits)
+		key := nodepoolutil.Key{Name: nodePool.Name, IsProvisioner: nodePool.IsProvisioner}
+		s.remainingResources[key] = v1.ResourceList(nodePool.Spec.Limits)
+		for _, scopedLimit := range nodePool.Spec.ScopedLimits {
+			s.remainingScoped[key] = append(s.remainingScoped[key], &scopedRemainingResources{ScopedLimit: scopedLimit, remaining: v1.ResourceList(scopedLimit.Limits)})
+		}
 	}
 	s.calculateExistingNodeClaims(stateNodes, daemonSetPods)
 	return s
 }
 
 type Scheduler struct {
-	ctx                context.Context
-	newNodeClaims      []*NodeClaim
-	existingNodes      []*ExistingNode
-	nodeClaimTemplates []*NodeClaimTemplate
-	remainingResources map[nodepoolutil.Key]v1.ResourceList               // (NodePool name, isProvisioner) -> remaining resources for that NodePool
-	instanceTypes      map[nodepoolutil.Key][]*cloudprovider.InstanceType // (NodePool name, isProvisioner) -> instance types for NodePool
-	daemonOverhead     map[*NodeClaimTemplate]v1.ResourceList
-	preferences        *Preferences
-	topology           *Topology
-	cluster            *state.Cluster
-	recorder           events.Recorder
-	opts               SchedulerOptions
-	kubeClient         client.Client
+	ctx                 context.Context
+	newNodeClaims       []*NodeClaim
+	existingNodes       []*ExistingNode
+	nodeClaimTemplates  []*NodeClaimTemplate
+	weightOrder         map[*NodeClaimTemplate]int                         // NodeClaimTemplate -> position when ordered by weight
+	launchFailures      map[nodepoolutil.Key]*state.LaunchFailure          // (NodePool name, isProvisioner) -> last launch failure of that NodePool
+	launchFailed        []*NodeClaimTemplate                               // templates of owners that were demoted after failing to launch capacity, ordered by weight
+	remainingResources  map[nodepoolutil.Key]v1.ResourceList               // (NodePool name, isProvisioner) -> remaining resources for that NodePool
+	remainingScoped     map[nodepoolutil.Key][]*scopedRemainingResources   // (NodePool name, isProvisioner) -> remaining resources for each scoped limit of that NodePool
+	instanceTypes       map[nodepoolutil.Key][]*cloudprovider.InstanceType // (NodePool name, isProvisioner) -> instance types for NodePool
+	daemonOverhead      map[*NodeClaimTemplate]map[string]v1.ResourceList  // NodeClaimTemplate -> instance type name -> daemonset overhead
+	daemonHostPortUsage map[*NodeClaimTemplate]*scheduling.HostPortUsage
+	preferences         *Preferences
+	explanations        map[*v1.Pod]*Explanation // pod -> explanation of why the pod couldn't be scheduled to new capacity
+	topology            *Topology
+	cluster             *state.Cluster
+	recorder            events.Recorder
+	opts                SchedulerOptions
+	kubeClient          client.Client
 }
 
 // Results contains the results of the scheduling operation
@@ -100,6 +138,8 @@ type Results struct {
 	NewNodeClaims []*NodeClaim
 	ExistingNodes []*ExistingNode
 	PodErrors     map[*v1.Pod]error
+	// PodExplanations describe why each pod in PodErrors couldn't be scheduled to new capacity
+	PodExplanations map[*v1.Pod]*Explanation
 }
 
 // AllNonPendingPodsScheduled returns true if all of the non-pending pods scheduled.  This is useful in consolidation as
@@ -142,7 +182,17 @@ func (s *Scheduler) Solve(ctx context.Context, pods []*v1.Pod) (*Results, error)
 	// had 5xA pods and 5xB pods were they have a zonal topology spread, but A can only go in one zone and B in another.
 	// We need to schedule them alternating, A, B, A, B, .... and this solution also solves that as well.
 	errors := map[*v1.Pod]error{}
-	q := NewQueue(pods...)
+	// pods that reference resource claims can't be placed without knowing how their claims will be allocated, so they
+	// fail on their own without being relaxed or affecting the other pods, which also prevents consolidation from
+	// assuming that they can be rescheduled
+	schedulable := lo.Filter(pods, func(p *v1.Pod, _ int) bool {
+		if pod.HasResourceClaims(p) {
+			errors[p] = fmt.Errorf("pod references resource claims, dynamic resource allocation isn't supported")
+			return false
+		}
+		return true
+	})
+	q := NewQueue(schedulable...)
 	for {
 		// Try the next pod
 		pod, ok := q.Pop()
@@ -170,6 +220,7 @@ func (s *Scheduler) Solve(ctx context.Context, pods []*v1.Pod) (*Results, error)
 	}
 	if !s.opts.SimulationMode {
 		s.recordSchedulingResults(ctx, pods, q.List(), errors)
+		s.recordBatchMetrics(pods)
 	}
 	// clear any nil errors so we can know that len(PodErrors) == 0 => all pods scheduled
 	for k, v := range errors {
@@ -178,16 +229,34 @@ func (s *Scheduler) Solve(ctx context.Context, pods []*v1.Pod) (*Results, error)
 		}
 	}
 	return &Results{
-		NewNodeClaims: s.newNodeClaims,
-		ExistingNodes: s.existingNodes,
-		PodErrors:     errors,
+		NewNodeClaims:   s.newNodeClaims,
+		ExistingNodes:   s.existingNodes,
+		PodErrors:       errors,
+		PodExplanations: lo.PickByKeys(s.explanations, lo.Keys(errors)),
 	}, nil
 }
 
+// recordBatchMetrics observes the size of a provisioning round so that large batches or a sprawl of provisioners and
+// instance types can be correlated with slow scheduling
+func (s *Scheduler) recordBatchMetrics(pods []*v1.Pod) {
+	schedulingBatchPods.Observe(float64(len(pods)))
+	schedulingBatchNodes.Observe(float64(len(s.existingNodes)))
+	instanceTypes := 0
+	for _, its := range s.instanceTypes {
+		instanceTypes += len(its)
+	}
+	schedulingBatchInstanceTypes.Observe(float64(instanceTypes))
+}
+
 func (s *Scheduler) recordSchedulingResults(ctx context.Context, pods []*v1.Pod, failedToSchedule []*v1.Pod, errors map[*v1.Pod]error) {
 	// Report failures and nominations
 	for _, pod := range failedToSchedule {
-		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(pod)).Errorf("Could not schedule pod, %s", errors[pod])
+		log := logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(pod))
+		if explanation, ok := s.explanations[pod]; ok {
+			log = log.With("explanation", explanation.NodePools)
+			s.recorder.Publish(PodSchedulingExplanationEvent(pod, explanation))
+		}
+		log.Errorf("Could not schedule pod, %s", errors[pod])
 		s.recorder.Publish(PodFailedToScheduleEvent(pod, errors[pod]))
 	}
 
@@ -198,6 +267,9 @@ func (s *Scheduler) recordSchedulingResults(ctx context.Context, pods []*v1.Pod,
 		for _, pod := range existing.Pods {
 			s.recorder.Publish(NominatePodEvent(pod, existing.Node, existing.NodeClaim))
 		}
+		if len(existing.Preempted) > 0 {
+			logging.FromContext(ctx).With("node", existing.Name(), "pods", len(existing.Preempted)).Infof("computed lower priority pod(s) will be preempted to fit pod(s)")
+		}
 	}
 
 	// Report new nodes, or exit to avoid log spam
@@ -208,6 +280,15 @@ func (s *Scheduler) recordSchedulingResults(ctx context.Context, pods []*v1.Pod,
 	if newCount == 0 {
 		return
 	}
+	for _, nodeClaim := range s.newNodeClaims {
+		if len(nodeClaim.fallbacks) == 0 {
+			continue
+		}
+		logging.FromContext(ctx).With(nodeClaim.OwnerKind(), nodeClaim.OwnerKey.Name, "fallbacks", nodeClaim.fallbacks).Infof("falling back to lower weight %s", nodeClaim.OwnerKind())
+		for _, pod := range nodeClaim.Pods {
+			s.recorder.Publish(PodFallbackEvent(pod, nodeClaim))
+		}
+	}
 	logging.FromContext(ctx).With("pods", len(pods)).Infof("found provisionable pod(s)")
 	logging.FromContext(ctx).With("machines", len(s.newNodeClaims), "pods", newCount).Infof("computed new machine(s) to fit pod(s)")
 	// Report in flight newNodes, or exit to avoid log spam
@@ -224,8 +305,18 @@ func (s *Scheduler) recordSchedulingResults(ctx context.Context, pods []*v1.Pod,
 }
 
 func (s *Scheduler) add(ctx context.Context, pod *v1.Pod) error {
-	// first try to schedule against an in-flight real node
+	// first try to schedule against an in-flight real node, skipping nodes whose labels and taints are already known
+	// to be incompatible with the pod
+	fingerprint, cacheable := uint64(0), false
+	if s.opts.Cache != nil {
+		fingerprint, cacheable = podFingerprint(pod)
+	}
 	for _, node := range s.existingNodes {
+		if cacheable {
+			if err := s.opts.Cache.Compatible(node, pod, fingerprint); err != nil {
+				continue
+			}
+		}
 		if err := node.Add(ctx, s.kubeClient, pod); err == nil {
 			return nil
 		}
@@ -241,59 +332,163 @@ func (s *Scheduler) add(ctx context.Context, pod *v1.Pod) error {
 		}
 	}
 
+	// Simulate kube-scheduler preempting lower priority pods on existing nodes before launching new capacity. We
+	// don't simulate preemption for consolidation as it would disrupt the pods that are preempted.
+	if !s.opts.SimulationMode && settings.FromContext(ctx).PreemptionAwareProvisioning {
+		for _, node := range s.existingNodes {
+			if err := node.Preempt(ctx, s.kubeClient, pod); err == nil {
+				return nil
+			}
+		}
+	}
+
 	// Create new node
 	var errs error
+	explanation := &Explanation{}
+	var fallbacks []fallback
 	for _, nodeClaimTemplate := range s.nodeClaimTemplates {
 		instanceTypes := s.instanceTypes[nodeClaimTemplate.OwnerKey]
 		// if limits have been applied to the provisioner, ensure we filter instance types to avoid violating those limits
 		if remaining, ok := s.remainingResources[nodeClaimTemplate.OwnerKey]; ok {
 			instanceTypes = filterByRemainingResources(s.instanceTypes[nodeClaimTemplate.OwnerKey], remaining)
 			if len(instanceTypes) == 0 {
-				errs = multierr.Append(errs, fmt.Errorf("all available instance types exceed limits for %s: %q", nodeClaimTemplate.OwnerKind(), nodeClaimTemplate.OwnerKey.Name))
+				err := fmt.Errorf("all available instance types exceed limits for %s: %q", nodeClaimTemplate.OwnerKind(), nodeClaimTemplate.OwnerKey.Name)
+				errs = multierr.Append(errs, err)
+				explanation.add(nodeClaimTemplate, instanceTypes, err)
+				fallbacks = append(fallbacks, fallback{nodeClaimTemplate: nodeClaimTemplate, reason: err.Error()})
 				continue
 			} else if len(s.instanceTypes[nodeClaimTemplate.OwnerKey]) != len(instanceTypes) && !s.opts.SimulationMode {
 				logging.FromContext(ctx).With(nodeClaimTemplate.OwnerKind(), nodeClaimTemplate.OwnerKey.Name).Debugf("%d out of %d instance types were excluded because they would breach limits",
 					len(s.instanceTypes[nodeClaimTemplate.OwnerKey])-len(instanceTypes), len(s.instanceTypes[nodeClaimTemplate.OwnerKey]))
 			}
 		}
-		nodeClaim := NewNodeClaim(nodeClaimTemplate, s.topology, s.daemonOverhead[nodeClaimTemplate], instanceTypes)
+		restricted := false
+		if scoped := s.remainingScoped[nodeClaimTemplate.OwnerKey]; len(scoped) > 0 {
+			instanceTypes, restricted = filterByScopedRemainingResources(instanceTypes, scoped)
+			if len(instanceTypes) == 0 {
+				err := fmt.Errorf("all available instance type offerings exceed scoped limits for %s: %q", nodeClaimTemplate.OwnerKind(), nodeClaimTemplate.OwnerKey.Name)
+				errs = multierr.Append(errs, err)
+				explanation.add(nodeClaimTemplate, instanceTypes, err)
+				fallbacks = append(fallbacks, fallback{nodeClaimTemplate: nodeClaimTemplate, reason: err.Error()})
+				continue
+			}
+		}
+		nodeClaim := NewNodeClaim(nodeClaimTemplate, s.topology, s.daemonOverhead[nodeClaimTemplate], s.daemonHostPortUsage[nodeClaimTemplate], instanceTypes)
+		nodeClaim.restrictOfferings = restricted
 		if err := nodeClaim.Add(pod); err != nil {
 			errs = multierr.Append(errs, fmt.Errorf("incompatible with %s %q, daemonset overhead=%s, %w",
 				nodeClaimTemplate.OwnerKind(),
 				nodeClaimTemplate.OwnerKey.Name,
-				resources.String(s.daemonOverhead[nodeClaimTemplate]),
+				resources.String(minDaemonResources(instanceTypes, s.daemonOverhead[nodeClaimTemplate])),
 				err))
+			explanation.add(nodeClaimTemplate, instanceTypes, err)
+			if podCompatible(nodeClaimTemplate, pod) {
+				fallbacks = append(fallbacks, fallback{nodeClaimTemplate: nodeClaimTemplate, reason: s.fallbackReason(nodeClaimTemplate, err)})
+			}
 			continue
 		}
+		nodeClaim.fallbacks = s.fallbacks(nodeClaimTemplate, pod, fallbacks)
 		// we will launch this nodeClaim and need to track its maximum possible resource usage against our remaining resources
 		s.newNodeClaims = append(s.newNodeClaims, nodeClaim)
 		s.remainingResources[nodeClaimTemplate.OwnerKey] = subtractMax(s.remainingResources[nodeClaimTemplate.OwnerKey], nodeClaim.InstanceTypeOptions)
+		for _, scoped := range s.remainingScoped[nodeClaimTemplate.OwnerKey] {
+			scoped.remaining = subtractMax(scoped.remaining, lo.Filter(nodeClaim.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+				return lo.SomeBy(it.Offerings.Available(), func(o cloudprovider.Offering) bool { return scoped.Matches(o.Zone, o.CapacityType) })
+			}))
+		}
 		return nil
 	}
+	s.explanations[pod] = explanation
 	return errs
 }
 
+// fallback is a template with a higher weight than the template that a pod was scheduled to and the reason that it
+// couldn't be used
+type fallback struct {
+	nodeClaimTemplate *NodeClaimTemplate
+	reason            string
+}
+
+// fallbacks describes why the owners with a higher weight than the owner of the template that the pod was scheduled to
+// couldn't be used, ordered by weight
+func (s *Scheduler) fallbacks(nodeClaimTemplate *NodeClaimTemplate, p *v1.Pod, tried []fallback) []string {
+	// demoted templates are tried last, so those with a higher weight weren't tried if the pod was scheduled before them
+	for _, failed := range s.launchFailed {
+		if podCompatible(failed, p) && !lo.ContainsBy(tried, func(f fallback) bool { return f.nodeClaimTemplate == failed }) {
+			tried = append(tried, fallback{nodeClaimTemplate: failed, reason: s.fallbackReason(failed, nil)})
+		}
+	}
+	higherWeight := lo.Filter(tried, func(f fallback, _ int) bool {
+		return s.weightOrder[f.nodeClaimTemplate] < s.weightOrder[nodeClaimTemplate]
+	})
+	sort.SliceStable(higherWeight, func(i, j int) bool {
+		return s.weightOrder[higherWeight[i].nodeClaimTemplate] < s.weightOrder[higherWeight[j].nodeClaimTemplate]
+	})
+	return lo.Map(higherWeight, func(f fallback, _ int) string { return f.reason })
+}
+
+// fallbackReason describes why the template couldn't be used for a pod that it's compatible with, preferring the last
+// launch failure of its owner as that's why its offerings are unavailable
+func (s *Scheduler) fallbackReason(nodeClaimTemplate *NodeClaimTemplate, err error) string {
+	if failure, ok := s.launchFailures[nodeClaimTemplate.OwnerKey]; ok {
+		return fmt.Sprintf("%s %q failed to launch capacity, %s", nodeClaimTemplate.OwnerKind(), nodeClaimTemplate.OwnerKey.Name, failure.Err)
+	}
+	return fmt.Sprintf("%s %q couldn't schedule the pod, %s", nodeClaimTemplate.OwnerKind(), nodeClaimTemplate.OwnerKey.Name, err)
+}
+
+// podCompatible returns true if the pod tolerates the taints of the template and its requirements are compatible with the
+// template's requirements
+func podCompatible(nodeClaimTemplate *NodeClaimTemplate, p *v1.Pod) bool {
+	return scheduling.Taints(nodeClaimTemplate.Spec.Taints).Tolerates(p) == nil &&
+		nodeClaimTemplate.Requirements.Compatible(scheduling.NewPodRequirements(p)) == nil
+}
+
 func (s *Scheduler) calculateExistingNodeClaims(stateNodes []*state.StateNode, daemonSetPods []*v1.Pod) {
+	// nodes that have left the cluster won't be seen again, so their cached state can be dropped. Simulations only
+	// see a subset of the nodes, so we only prune when scheduling against the whole cluster.
+	if !s.opts.SimulationMode {
+		s.opts.Cache.Prune(stateNodes)
+	}
+	daemonFingerprint, daemonsCacheable := uint64(0), false
+	if s.opts.Cache != nil {
+		daemonFingerprint, daemonsCacheable = daemonSetPodsFingerprint(daemonSetPods)
+	}
 	// create our existing nodes
 	for _, node := range stateNodes {
 		// Calculate any daemonsets that should schedule to the inflight node
-		var daemons []*v1.Pod
-		for _, p := range daemonSetPods {
-			if err := scheduling.Taints(node.Taints()).Tolerates(p); err != nil {
-				continue
-			}
-			if err := scheduling.NewLabelRequirements(node.Labels()).StrictlyCompatible(scheduling.NewPodRequirements(p)); err != nil {
-				continue
+		daemonRequests := func() v1.ResourceList {
+			var daemons []*v1.Pod
+			for _, p := range daemonSetPods {
+				if err := scheduling.Taints(node.Taints()).Tolerates(p); err != nil {
+					continue
+				}
+				if err := scheduling.NewLabelRequirements(node.Labels()).StrictlyCompatible(scheduling.NewPodRequirements(p)); err != nil {
+					continue
+				}
+				daemons = append(daemons, p)
 			}
-			daemons = append(daemons, p)
+			return resources.RequestsForPods(daemons...)
 		}
-		s.existingNodes = append(s.existingNodes, NewExistingNode(node, s.topology, resources.RequestsForPods(daemons...)))
+		fingerprint, cacheable := s.opts.Cache.Register(node)
+		var existingNode *ExistingNode
+		if cacheable && daemonsCacheable {
+			existingNode = NewExistingNode(node, s.topology, s.opts.Cache.DaemonRequests(node.Name(), fingerprint, daemonFingerprint, daemonRequests))
+		} else {
+			existingNode = NewExistingNode(node, s.topology, daemonRequests())
+		}
+		existingNode.fingerprint, existingNode.cacheable = fingerprint, cacheable
+		s.existingNodes = append(s.existingNodes, existingNode)
 
 		// We don't use the status field and instead recompute the remaining resources to ensure we have a consistent view
 		// of the cluster during scheduling.  Depending on how node creation falls out, this will also work for cases where
 		// we don't create NodeClaim resources.
 		if _, ok := s.remainingResources[node.OwnerKey()]; ok {
-			s.remainingResources[node.OwnerKey()] = resources.Subtract(s.remainingResources[node.OwnerKey()], node.Capacity())
+			s.remainingResources[node.OwnerKey()] = resources.Subtract(s.remainingResources[node.OwnerKey()], limitedResources(node.Capacity()))
+		}
+		for _, scoped := range s.remainingScoped[node.OwnerKey()] {
+			if scoped.Matches(node.Labels()[v1.LabelTopologyZone], node.Labels()[v1beta1.CapacityTypeLabelKey]) {
+				scoped.remaining = resources.Subtract(scoped.remaining, limitedResources(node.Capacity()))
+			}
 		}
 	}
 	// Order the existing nodes for scheduling with initialized nodes first
@@ -310,11 +505,11 @@ func (s *Scheduler) calculateExistingNodeClaims(stateNodes []*state.StateNode, d
 	})
 }
 
-func getDaemonOverhead(nodeClaimTemplates []*NodeClaimTemplate, daemonSetPods []*v1.Pod) map[*NodeClaimTemplate]v1.ResourceList {
-	overhead := map[*NodeClaimTemplate]v1.ResourceList{}
-
+// getDaemons returns the daemonset pods that are expected to schedule to nodes launched from each NodeClaimTemplate
+func getDaemons(nodeClaimTemplates []*NodeClaimTemplate, daemonSetPods []*v1.Pod) map[*NodeClaimTemplate][]*v1.Pod {
+	daemons := map[*NodeClaimTemplate][]*v1.Pod{}
 	for _, nodeClaimTemplate := range nodeClaimTemplates {
-		var daemons []*v1.Pod
+		daemons[nodeClaimTemplate] = []*v1.Pod{}
 		for _, p := range daemonSetPods {
 			if err := scheduling.Taints(nodeClaimTemplate.Spec.Taints).Tolerates(p); err != nil {
 				continue
@@ -322,13 +517,48 @@ func getDaemonOverhead(nodeClaimTemplates []*NodeClaimTemplate, daemonSetPods []
 			if err := nodeClaimTemplate.Requirements.Compatible(scheduling.NewPodRequirements(p)); err != nil {
 				continue
 			}
-			daemons = append(daemons, p)
+			daemons[nodeClaimTemplate] = append(daemons[nodeClaimTemplate], p)
+		}
+	}
+	return daemons
+}
+
+// getDaemonOverhead returns the resources requested by the daemonset pods that are expected to schedule to nodes
+// launched from each NodeClaimTemplate, keyed by instance type name. A daemonset pod only counts against the instance
+// types whose labels are compatible with its node selector and node affinity, so daemonsets that only target some
+// instance types or zones (e.g. GPU device plugins) don't inflate the overhead of every instance type.
+func getDaemonOverhead(daemons map[*NodeClaimTemplate][]*v1.Pod, instanceTypes map[nodepoolutil.Key][]*cloudprovider.InstanceType) map[*NodeClaimTemplate]map[string]v1.ResourceList {
+	overhead := map[*NodeClaimTemplate]map[string]v1.ResourceList{}
+	for nodeClaimTemplate, pods := range daemons {
+		podRequirements := lo.Map(pods, func(p *v1.Pod, _ int) scheduling.Requirements { return scheduling.NewPodRequirements(p) })
+		overhead[nodeClaimTemplate] = map[string]v1.ResourceList{}
+		for _, it := range instanceTypes[nodeClaimTemplate.OwnerKey] {
+			requirements := scheduling.NewRequirements(nodeClaimTemplate.Requirements.Values()...)
+			requirements.Add(it.Requirements.Values()...)
+			var itPods []*v1.Pod
+			for i, p := range pods {
+				if requirements.Compatible(podRequirements[i]) == nil {
+					itPods = append(itPods, p)
+				}
+			}
+			overhead[nodeClaimTemplate][it.Name] = resources.RequestsForPods(itPods...)
 		}
-		overhead[nodeClaimTemplate] = resources.RequestsForPods(daemons...)
 	}
 	return overhead
 }
 
+// getDaemonHostPortUsage returns the host ports that are reserved by daemonset pods on nodes launched from each
+// NodeClaimTemplate so that pods with conflicting host ports aren't packed onto these nodes
+func getDaemonHostPortUsage(daemons map[*NodeClaimTemplate][]*v1.Pod) map[*NodeClaimTemplate]*scheduling.HostPortUsage {
+	return lo.MapValues(daemons, func(pods []*v1.Pod, _ *NodeClaimTemplate) *scheduling.HostPortUsage {
+		usage := scheduling.NewHostPortUsage()
+		for _, p := range pods {
+			usage.Add(p, scheduling.GetHostPorts(p))
+		}
+		return usage
+	})
+}
+
 // subtractMax returns the remaining resources after subtracting the max resource quantity per instance type. To avoid
 // overshooting out, we need to pessimistically assume that if e.g. we request a 2, 4 or 8 CPU instance type
 // that the 8 CPU instance type is all that will be available.  This could cause a batch of pods to take multiple rounds
@@ -340,7 +570,7 @@ func subtractMax(remaining v1.ResourceList, instanceTypes []*cloudprovider.Insta
 	}
 	var allInstanceResources []v1.ResourceList
 	for _, it := range instanceTypes {
-		allInstanceResources = append(allInstanceResources, it.Capacity)
+		allInstanceResources = append(allInstanceResources, limitedResources(it.Capacity))
 	}
 	result := v1.ResourceList{}
 	itResources := resources.MaxResources(allInstanceResources...)
@@ -352,21 +582,68 @@ func subtractMax(remaining v1.ResourceList, instanceTypes []*cloudprovider.Insta
 	return result
 }
 
+// limitedResources returns the resources that a node with the given capacity counts against the NodePool limits,
+// which includes the node itself
+func limitedResources(capacity v1.ResourceList) v1.ResourceList {
+	return resources.Merge(capacity, v1.ResourceList{v1beta1.ResourceNodes: resource.MustParse("1")})
+}
+
 // filterByRemainingResources is used to filter out instance types that if launched would exceed the provisioner limits
 func filterByRemainingResources(instanceTypes []*cloudprovider.InstanceType, remaining v1.ResourceList) []*cloudprovider.InstanceType {
 	var filtered []*cloudprovider.InstanceType
 	for _, it := range instanceTypes {
-		itResources := it.Capacity
-		viableInstance := true
-		for resourceName, remainingQuantity := range remaining {
-			// if the instance capacity is greater than the remaining quantity for this resource
-			if resources.Cmp(itResources[resourceName], remainingQuantity) > 0 {
-				viableInstance = false
-			}
-		}
-		if viableInstance {
+		if !exceedsRemaining(limitedResources(it.Capacity), remaining) {
 			filtered = append(filtered, it)
 		}
 	}
 	return filtered
 }
+
+// exceedsRemaining returns true if the instance resources are greater than the remaining quantity for any resource
+func exceedsRemaining(itResources v1.ResourceList, remaining v1.ResourceList) bool {
+	for resourceName, remainingQuantity := range remaining {
+		if resources.Cmp(itResources[resourceName], remainingQuantity) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedRemainingResources tracks the resources that remain for a NodePool limit that is scoped to a zone and/or
+// capacity type
+type scopedRemainingResources struct {
+	v1beta1.ScopedLimit
+	remaining v1.ResourceList
+}
+
+// filterByScopedRemainingResources is used to filter out the offerings of instance types that if launched would exceed
+// one of the scoped limits. Instance types that have no offerings left are removed. It also returns whether any
+// offerings were filtered out.
+func filterByScopedRemainingResources(instanceTypes []*cloudprovider.InstanceType, scoped []*scopedRemainingResources) ([]*cloudprovider.InstanceType, bool) {
+	var filtered []*cloudprovider.InstanceType
+	restricted := false
+	for _, it := range instanceTypes {
+		itResources := limitedResources(it.Capacity)
+		exceeded := lo.Filter(scoped, func(s *scopedRemainingResources, _ int) bool { return exceedsRemaining(itResources, s.remaining) })
+		if len(exceeded) == 0 {
+			filtered = append(filtered, it)
+			continue
+		}
+		offerings := lo.Reject(it.Offerings, func(o cloudprovider.Offering, _ int) bool {
+			return lo.SomeBy(exceeded, func(s *scopedRemainingResources) bool { return s.Matches(o.Zone, o.CapacityType) })
+		})
+		restricted = restricted || len(offerings) != len(it.Offerings)
+		if len(offerings) == 0 {
+			continue
+		}
+		// the instance type is shared across scheduling simulations, so we create a copy with the remaining offerings
+		filtered = append(filtered, &cloudprovider.InstanceType{
+			Name:         it.Name,
+			Requirements: it.Requirements,
+			Offerings:    offerings,
+			Capacity:     it.Capacity,
+			Overhead:     it.Overhead,
+		})
+	}
+	return filtered, restricted
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
@@ -38,6 +39,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/events"
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
 	"github.com/aws/karpenter-core/pkg/test"
 	"github.com/aws/karpenter-core/pkg/utils/nodepool"
 
@@ -75,6 +77,18 @@ func BenchmarkScheduling2000(b *testing.B) {
 func BenchmarkScheduling5000(b *testing.B) {
 	benchmarkScheduler(b, 400, 5000)
 }
+func BenchmarkSchedulingExistingNodes5000(b *testing.B) {
+	benchmarkExistingNodes(b, 500, 5000, 0, nil)
+}
+func BenchmarkSchedulingExistingNodesCached5000(b *testing.B) {
+	benchmarkExistingNodes(b, 500, 5000, 0, scheduling.NewCache())
+}
+func BenchmarkSchedulingExistingNodesMixed5000(b *testing.B) {
+	benchmarkExistingNodes(b, 500, 5000, 2500, nil)
+}
+func BenchmarkSchedulingExistingNodesMixedCached5000(b *testing.B) {
+	benchmarkExistingNodes(b, 500, 5000, 2500, scheduling.NewCache())
+}
 
 // TestSchedulingProfile is used to gather profiling metrics, benchmarking is primarily done with standard
 // Go benchmark functions
@@ -185,6 +199,55 @@ func benchmarkScheduler(b *testing.B, instanceCount, podCount int) {
 	}
 }
 
+// benchmarkExistingNodes schedules pods across successive batches against existing nodes that only the first
+// compatibleCount pods tolerate, one team's node each, which forces the other pods to be checked against every node
+func benchmarkExistingNodes(b *testing.B, nodeCount, podCount, compatibleCount int, cache *scheduling.Cache) {
+	// disable logging
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	ctx = settings.ToContext(ctx, test.Settings())
+	provisioner = test.Provisioner(test.ProvisionerOptions{Limits: map[v1.ResourceName]resource.Quantity{}})
+
+	instanceTypes := fake.InstanceTypes(400)
+	cloudProvider = fake.NewCloudProvider()
+	cloudProvider.InstanceTypes = instanceTypes
+	// compatible pods are added to existing nodes, which looks up their volumes through the kube client
+	kubeClient := crfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	cluster := state.NewCluster(&clock.RealClock{}, kubeClient, cloudProvider)
+	var stateNodes []*state.StateNode
+	for i := 0; i < nodeCount; i++ {
+		stateNode := state.NewNode()
+		stateNode.Node = test.Node(test.NodeOptions{
+			Taints: []v1.Taint{{Key: "dedicated", Value: fmt.Sprintf("team-%d", i), Effect: v1.TaintEffectNoSchedule}},
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("16"),
+				v1.ResourceMemory: resource.MustParse("64Gi"),
+				v1.ResourcePods:   resource.MustParse("110"),
+			},
+		})
+		stateNodes = append(stateNodes, stateNode)
+	}
+	pods := makeGenericPods(podCount)
+	for i, pod := range pods[:compatibleCount] {
+		pod.Spec.Tolerations = []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: fmt.Sprintf("team-%d", i%nodeCount), Effect: v1.TaintEffectNoSchedule}}
+	}
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		// each iteration is a new provisioning batch, so the scheduler is rebuilt from the same cluster state
+		scheduler := scheduling.NewScheduler(ctx, kubeClient, []*scheduling.NodeClaimTemplate{scheduling.NewNodeClaimTemplate(nodepool.New(provisioner))},
+			nil, cluster, stateNodes, &scheduling.Topology{},
+			map[nodepool.Key][]*cloudprovider.InstanceType{nodepool.Key{Name: provisioner.Name, IsProvisioner: true}: instanceTypes}, nil,
+			events.NewRecorder(&record.FakeRecorder{}),
+			scheduling.SchedulerOptions{Cache: cache})
+		if _, err := scheduler.Solve(ctx, pods); err != nil {
+			b.FailNow()
+		}
+	}
+	duration := time.Since(start)
+	b.ReportMetric(float64(len(pods))/(duration.Seconds()/float64(b.N)), "pods/sec")
+}
+
 func makeDiversePods(count int) []*v1.Pod {
 	var pods []*v1.Pod
 	pods = append(pods, makeGenericPods(count/7)...)
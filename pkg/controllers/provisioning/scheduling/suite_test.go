@@ -23,8 +23,10 @@ import (
 	"testing"
 	"time"
 
+	prometheus "github.com/prometheus/client_model/go"
 	"github.com/samber/lo"
 	nodev1 "k8s.io/api/node/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -41,6 +43,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/apis"
 	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
@@ -1098,6 +1101,44 @@ var _ = Describe("Preferential Fallback", func() {
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 			ExpectScheduled(ctx, env.Client, pod)
 		})
+		It("should not relax preferences for pods with the Strict preference policy", func() {
+			pod := test.UnschedulablePod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1beta1.PreferencePolicyAnnotationKey: string(scheduling.PreferencePolicyStrict),
+			}}})
+			pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+				{
+					Weight: 1, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"invalid"}},
+					}},
+				},
+			}}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should publish an event for each relaxed preference for pods with the RelaxAndLog preference policy", func() {
+			recorder := test.NewEventRecorder()
+			preferences := &scheduling.Preferences{Recorder: recorder}
+			pod := test.UnschedulablePod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				v1beta1.PreferencePolicyAnnotationKey: string(scheduling.PreferencePolicyRelaxAndLog),
+			}}})
+			pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+				{
+					Weight: 1, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"invalid"}},
+					}},
+				},
+				{
+					Weight: 1, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"invalid"}},
+					}},
+				},
+			}}}
+			Expect(preferences.Relax(ctx, pod)).To(BeTrue())
+			Expect(preferences.Relax(ctx, pod)).To(BeTrue())
+			Expect(preferences.Relax(ctx, pod)).To(BeFalse())
+			Expect(recorder.Calls("PreferenceRelaxed")).To(Equal(2))
+		})
 		It("should relax to use lighter weights", func() {
 			provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
 				{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1", "test-zone-2"}}}
@@ -1158,7 +1199,184 @@ var _ = Describe("Preferential Fallback", func() {
 	})
 })
 
+var _ = Describe("Dynamic Resource Allocation", func() {
+	It("should not schedule pods that reference resource claims", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		pod := test.UnschedulablePod()
+		pod.Spec.ResourceClaims = []v1.PodResourceClaim{{Name: "gpu", Source: v1.ClaimSource{ResourceClaimName: lo.ToPtr("gpu-claim")}}}
+		s, err := prov.NewScheduler(ctx, []*v1.Pod{pod}, nil, scheduling.SchedulerOptions{SimulationMode: true})
+		Expect(err).ToNot(HaveOccurred())
+		results, err := s.Solve(ctx, []*v1.Pod{pod})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.NewNodeClaims).To(BeEmpty())
+		Expect(results.PodErrors).To(HaveKey(pod))
+		Expect(results.PodErrors[pod].Error()).To(ContainSubstring("resource claims"))
+	})
+	It("should schedule pods without resource claims alongside pods that reference them", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		claimed := test.UnschedulablePod()
+		claimed.Spec.ResourceClaims = []v1.PodResourceClaim{{Name: "gpu", Source: v1.ClaimSource{ResourceClaimName: lo.ToPtr("gpu-claim")}}}
+		unclaimed := test.UnschedulablePod()
+		s, err := prov.NewScheduler(ctx, []*v1.Pod{claimed, unclaimed}, nil, scheduling.SchedulerOptions{SimulationMode: true})
+		Expect(err).ToNot(HaveOccurred())
+		results, err := s.Solve(ctx, []*v1.Pod{claimed, unclaimed})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.NewNodeClaims).To(HaveLen(1))
+		Expect(results.NewNodeClaims[0].Pods).To(ConsistOf(unclaimed))
+		Expect(results.PodErrors).To(HaveKey(claimed))
+	})
+	It("should not relax the preferences of pods that reference resource claims", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		pod := test.UnschedulablePod(test.PodOptions{NodePreferences: []v1.NodeSelectorRequirement{
+			{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"invalid"}},
+		}})
+		pod.Spec.ResourceClaims = []v1.PodResourceClaim{{Name: "gpu", Source: v1.ClaimSource{ResourceClaimName: lo.ToPtr("gpu-claim")}}}
+		expected := pod.DeepCopy()
+		s, err := prov.NewScheduler(ctx, []*v1.Pod{pod}, nil, scheduling.SchedulerOptions{SimulationMode: true})
+		Expect(err).ToNot(HaveOccurred())
+		results, err := s.Solve(ctx, []*v1.Pod{pod})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.PodErrors).To(HaveKey(pod))
+		Expect(pod.Spec.Affinity).To(Equal(expected.Spec.Affinity))
+	})
+})
+
+var _ = Describe("Scheduling Explanations", func() {
+	It("should explain which requirement eliminated each provisioner", func() {
+		provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
+			{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.ArchitectureAmd64}},
+		}
+		pod := test.UnschedulablePod(test.PodOptions{
+			NodeSelector: map[string]string{v1.LabelArchStable: v1alpha5.ArchitectureArm64},
+		})
+		ExpectApplied(ctx, env.Client, provisioner, pod)
+		results, err := prov.Schedule(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.PodExplanations).To(HaveLen(1))
+		explanation := lo.Values(results.PodExplanations)[0]
+		Expect(explanation.NodePools).To(HaveLen(1))
+		Expect(explanation.NodePools[0].Kind).To(Equal("provisioner"))
+		Expect(explanation.NodePools[0].Name).To(Equal(provisioner.Name))
+		Expect(explanation.NodePools[0].InstanceTypeCount).To(BeNumerically(">", 0))
+		Expect(explanation.NodePools[0].InstanceTypes).ToNot(BeEmpty())
+		Expect(explanation.NodePools[0].Reason).To(ContainSubstring(v1.LabelArchStable))
+		Expect(explanation.String()).To(ContainSubstring(provisioner.Name))
+	})
+	It("should explain that limits eliminated a provisioner", func() {
+		provisioner.Spec.Limits = &v1alpha5.Limits{Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("0")}}
+		pod := test.UnschedulablePod()
+		ExpectApplied(ctx, env.Client, provisioner, pod)
+		results, err := prov.Schedule(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.PodExplanations).To(HaveLen(1))
+		explanation := lo.Values(results.PodExplanations)[0]
+		Expect(explanation.NodePools).To(HaveLen(1))
+		Expect(explanation.NodePools[0].InstanceTypeCount).To(Equal(0))
+		Expect(explanation.NodePools[0].Reason).To(ContainSubstring("exceed limits"))
+	})
+	It("should not explain pods that schedule", func() {
+		pod := test.UnschedulablePod()
+		ExpectApplied(ctx, env.Client, provisioner, pod)
+		results, err := prov.Schedule(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.PodExplanations).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Metrics", func() {
+	It("should observe the size of each provisioning round", func() {
+		provisioner.Spec.Requirements = append(provisioner.Spec.Requirements, v1.NodeSelectorRequirement{
+			Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"default-instance-type"},
+		})
+		node := test.Node(test.NodeOptions{
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("0"), v1.ResourcePods: resource.MustParse("0")},
+		})
+		pods := test.UnschedulablePods(test.PodOptions{}, 3)
+		ExpectApplied(ctx, env.Client, provisioner, node)
+		ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+		for _, pod := range pods {
+			ExpectApplied(ctx, env.Client, pod)
+		}
+		batchPods, batchNodes, batchInstanceTypes := ExpectHistogram("karpenter_provisioner_scheduling_batch_pods"),
+			ExpectHistogram("karpenter_provisioner_scheduling_batch_nodes"), ExpectHistogram("karpenter_provisioner_scheduling_batch_instance_types")
+		_, err := prov.Schedule(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_pods").GetSampleCount()).To(Equal(batchPods.GetSampleCount() + 1))
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_pods").GetSampleSum()).To(BeNumerically("==", batchPods.GetSampleSum()+3))
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_nodes").GetSampleCount()).To(Equal(batchNodes.GetSampleCount() + 1))
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_nodes").GetSampleSum()).To(BeNumerically("==", batchNodes.GetSampleSum()+1))
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_instance_types").GetSampleCount()).To(Equal(batchInstanceTypes.GetSampleCount() + 1))
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_instance_types").GetSampleSum()).To(BeNumerically("==", batchInstanceTypes.GetSampleSum()+1))
+	})
+	It("should not observe dry runs", func() {
+		pod := test.UnschedulablePod()
+		ExpectApplied(ctx, env.Client, provisioner)
+		batchPods := ExpectHistogram("karpenter_provisioner_scheduling_batch_pods")
+		_, err := prov.DryRun(ctx, pod)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ExpectHistogram("karpenter_provisioner_scheduling_batch_pods").GetSampleCount()).To(Equal(batchPods.GetSampleCount()))
+	})
+})
+
 var _ = Describe("Instance Type Compatibility", func() {
+	Context("Preferences", func() {
+		It("should launch the preferred instance type before the cheapest instance type", func() {
+			provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+				{Weight: 10, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.ArchitectureArm64}},
+				}}},
+			}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "arm-instance-type"))
+		})
+		It("should launch the instance type matching the highest summed weight of preferences", func() {
+			provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+				{Weight: 10, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.ArchitectureArm64}},
+				}}},
+				{Weight: 20, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"default-instance-type"}},
+				}}},
+			}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "default-instance-type"))
+		})
+		It("should pass preferences through to the cloudprovider", func() {
+			provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+				{Weight: 10, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.ArchitectureArm64}},
+				}}},
+			}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(cloudProvider.CreateCalls).To(HaveLen(1))
+			Expect(cloudProvider.CreateCalls[0].Spec.Preferences).To(Equal(provisioner.Spec.Preferences))
+		})
+		It("should not prefer instance types that don't satisfy a preference", func() {
+			provisioner.Spec.Preferences = []v1.PreferredSchedulingTerm{
+				{Weight: 10, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.ArchitectureArm64}},
+				}}},
+			}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(test.PodOptions{
+				NodeSelector: map[string]string{v1.LabelArchStable: v1alpha5.ArchitectureAmd64},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelArchStable, v1alpha5.ArchitectureAmd64))
+		})
+	})
 	It("should not schedule if requesting more resources than any instance type has", func() {
 		ExpectApplied(ctx, env.Client, provisioner)
 		pod := test.UnschedulablePod(test.PodOptions{
@@ -1419,6 +1637,128 @@ var _ = Describe("Instance Type Compatibility", func() {
 })
 
 var _ = Describe("Binpacking", func() {
+	Context("Kubelet Pod Density", func() {
+		BeforeEach(func() {
+			cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+				fake.NewInstanceType(fake.InstanceTypeOptions{
+					Name: "large",
+					Resources: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("4"),
+						v1.ResourceMemory: resource.MustParse("16Gi"),
+						v1.ResourcePods:   resource.MustParse("100"),
+					},
+				}),
+			}
+		})
+		expectNodeCount := func(pods []*v1.Pod, count int) {
+			nodeNames := sets.NewString()
+			for _, pod := range pods {
+				nodeNames.Insert(ExpectScheduled(ctx, env.Client, pod).Name)
+			}
+			Expect(nodeNames.Len()).To(Equal(count))
+		}
+		opts := test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m")},
+		}}
+		It("should not pack more pods than the kubelet maxPods onto a node", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{MaxPods: ptr.Int32(3)}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pods := test.UnschedulablePods(opts, 7)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			expectNodeCount(pods, 3)
+		})
+		It("should not pack more pods than the kubelet podsPerCore allows onto a node", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{PodsPerCore: ptr.Int32(2)}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pods := test.UnschedulablePods(opts, 10)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			expectNodeCount(pods, 2)
+		})
+		It("should use the lower of the kubelet maxPods and podsPerCore", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{MaxPods: ptr.Int32(5), PodsPerCore: ptr.Int32(2)}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pods := test.UnschedulablePods(opts, 10)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			expectNodeCount(pods, 2)
+		})
+		It("should pack pods up to the instance type pod capacity without a kubelet configuration", func() {
+			ExpectApplied(ctx, env.Client, provisioner)
+			pods := test.UnschedulablePods(opts, 10)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			expectNodeCount(pods, 1)
+		})
+	})
+	Context("Kubelet Reserved Resources", func() {
+		BeforeEach(func() {
+			cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+				fake.NewInstanceType(fake.InstanceTypeOptions{
+					Name: "large",
+					Resources: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("4"),
+						v1.ResourceMemory: resource.MustParse("16Gi"),
+						v1.ResourcePods:   resource.MustParse("100"),
+					},
+				}),
+			}
+		})
+		opts := test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("3.5")},
+		}}
+		It("should schedule when the instance type's default overhead leaves room", func() {
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+		})
+		It("should not schedule when the kubelet kubeReserved exceeds the remaining capacity", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{KubeReserved: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should not schedule when the kubelet systemReserved exceeds the remaining capacity", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{SystemReserved: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should not schedule when the kubelet hard eviction threshold exceeds the remaining memory", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{EvictionHard: map[string]string{"memory.available": "10%"}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("15Gi")},
+			}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should not schedule when the kubelet soft eviction threshold exceeds the remaining memory", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{
+				EvictionHard: map[string]string{"memory.available": "100Mi"},
+				EvictionSoft: map[string]string{"memory.available": "10%"},
+			}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("15Gi")},
+			}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should apply a changed kubelet configuration to instance types that were already used", func() {
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{KubeReserved: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+
+			provisioner.Spec.KubeletConfiguration = &v1alpha5.KubeletConfiguration{KubeReserved: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod = test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+	})
 	It("should schedule a small pod on the smallest instance", func() {
 		ExpectApplied(ctx, env.Client, provisioner)
 		pod := test.UnschedulablePod(
@@ -1471,6 +1811,33 @@ var _ = Describe("Binpacking", func() {
 		// would
 		Expect(node.Labels[v1.LabelInstanceTypeStable]).To(Equal("default-instance-type"))
 	})
+	It("should take daemonset runtime class into consideration", func() {
+		// the daemonset has overhead of 2 CPUs
+		runtimeClass := &nodev1.RuntimeClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "my-runtime-class",
+			},
+			Handler: "default",
+			Overhead: &nodev1.Overhead{
+				PodFixed: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("2"),
+				},
+			},
+		}
+		ds := test.DaemonSet()
+		ds.Spec.Template.Spec.RuntimeClassName = &runtimeClass.Name
+		ExpectApplied(ctx, env.Client, provisioner, runtimeClass, ds)
+		pod := test.UnschedulablePod(
+			test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU: resource.MustParse("1"),
+				},
+			}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		// daemonset overhead of 2 + request of 1 = at least 3 CPUs, so it won't fit on small-instance-type
+		Expect(node.Labels[v1.LabelInstanceTypeStable]).To(Equal("default-instance-type"))
+	})
 	It("should schedule multiple small pods on the smallest possible instance type", func() {
 		opts := test.PodOptions{
 			Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Reason: v1.PodReasonUnschedulable, Status: v1.ConditionFalse}},
@@ -1510,6 +1877,25 @@ var _ = Describe("Binpacking", func() {
 		}
 		Expect(nodeNames).To(HaveLen(20))
 	})
+	It("should create new nodes when pods have conflicting host ports", func() {
+		ExpectApplied(ctx, env.Client, provisioner)
+		pods := test.UnschedulablePods(test.PodOptions{HostPorts: []int32{8080}}, 3)
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+		nodeNames := sets.NewString()
+		for _, p := range pods {
+			nodeNames.Insert(ExpectScheduled(ctx, env.Client, p).Name)
+		}
+		Expect(nodeNames).To(HaveLen(3))
+	})
+	It("should not schedule pods with host ports that conflict with a daemonset", func() {
+		ds := test.DaemonSet(test.DaemonSetOptions{PodOptions: test.PodOptions{HostPorts: []int32{8080}}})
+		ExpectApplied(ctx, env.Client, provisioner, ds)
+		conflicting := test.UnschedulablePod(test.PodOptions{HostPorts: []int32{8080}})
+		compatible := test.UnschedulablePod(test.PodOptions{HostPorts: []int32{8081}})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, conflicting, compatible)
+		ExpectNotScheduled(ctx, env.Client, conflicting)
+		ExpectScheduled(ctx, env.Client, compatible)
+	})
 	It("should pack small and large pods together", func() {
 		largeOpts := test.PodOptions{
 			NodeSelector: map[string]string{v1.LabelArchStable: "amd64"},
@@ -2337,6 +2723,130 @@ var _ = Describe("In-Flight Nodes", func() {
 })
 
 var _ = Describe("Existing Nodes", func() {
+	Context("Preemption", func() {
+		var node *v1.Node
+		var low, high, highNever *schedulingv1.PriorityClass
+		BeforeEach(func() {
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{PreemptionAwareProvisioning: true}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+
+			low = &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()}, Value: 100}
+			high = &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()}, Value: 1000}
+			highNever = &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()}, Value: 1000, PreemptionPolicy: lo.ToPtr(v1.PreemptNever)}
+			node = test.Node(test.NodeOptions{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("10Gi"),
+					v1.ResourcePods:   resource.MustParse("110"),
+				},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, low, high, highNever, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+
+			// fill the node with a low priority pod
+			bound := test.Pod(test.PodOptions{
+				PriorityClassName: low.Name,
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("900m")},
+				},
+			})
+			ExpectApplied(ctx, env.Client, bound)
+			ExpectManualBinding(ctx, env.Client, bound, node)
+			ExpectReconcileSucceeded(ctx, podStateController, client.ObjectKeyFromObject(bound))
+		})
+		AfterEach(func() {
+			ExpectDeleted(ctx, env.Client, low, high, highNever)
+		})
+		It("should schedule a higher priority pod to an existing node by preempting lower priority pods", func() {
+			pod := test.UnschedulablePod(test.PodOptions{
+				PriorityClassName: high.Name,
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Name).To(Equal(node.Name))
+			Expect(cloudProvider.CreateCalls).To(HaveLen(0))
+		})
+		It("should launch a new node for a pod that can't preempt the pods on existing nodes", func() {
+			pod := test.UnschedulablePod(test.PodOptions{
+				PriorityClassName: low.Name,
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Name).ToNot(Equal(node.Name))
+		})
+		It("should launch a new node for a pod with a preemption policy of Never", func() {
+			pod := test.UnschedulablePod(test.PodOptions{
+				PriorityClassName: highNever.Name,
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Name).ToNot(Equal(node.Name))
+		})
+		It("should launch a new node when preemption aware provisioning is disabled", func() {
+			ctx = settings.ToContext(ctx, test.Settings())
+			pod := test.UnschedulablePod(test.PodOptions{
+				PriorityClassName: high.Name,
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Name).ToNot(Equal(node.Name))
+		})
+	})
+	Context("Cache", func() {
+		BeforeEach(func() {
+			// prevent launching new capacity so that pods can only schedule to the existing node
+			provisioner = test.Provisioner(test.ProvisionerOptions{Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("0")}})
+		})
+		It("should schedule to an existing node once its taints change between batches", func() {
+			node := test.Node(test.NodeOptions{
+				Taints: []v1.Taint{{Key: "test-key", Value: "test-value", Effect: v1.TaintEffectNoSchedule}},
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("10"),
+					v1.ResourceMemory: resource.MustParse("10Gi"),
+					v1.ResourcePods:   resource.MustParse("110"),
+				},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+
+			node.Spec.Taints = nil
+			ExpectApplied(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Name).To(Equal(node.Name))
+		})
+		It("should schedule to an existing node once its labels change between batches", func() {
+			node := test.Node(test.NodeOptions{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("10"),
+					v1.ResourceMemory: resource.MustParse("10Gi"),
+					v1.ResourcePods:   resource.MustParse("110"),
+				},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			pod := test.UnschedulablePod(test.PodOptions{NodeSelector: map[string]string{"test-key": "test-value"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+
+			node.Labels = lo.Assign(node.Labels, map[string]string{"test-key": "test-value"})
+			ExpectApplied(ctx, env.Client, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Name).To(Equal(node.Name))
+		})
+	})
 	It("should schedule a pod to an existing node unowned by Karpenter", func() {
 		node := test.Node(test.NodeOptions{
 			Allocatable: v1.ResourceList{
@@ -3237,6 +3747,13 @@ var _ = Describe("VolumeUsage", func() {
 })
 
 // nolint:gocyclo
+// ExpectHistogram returns the histogram of a metric without labels
+func ExpectHistogram(name string) *prometheus.Histogram {
+	m, ok := FindMetricWithLabelValues(name, map[string]string{})
+	ExpectWithOffset(1, ok).To(BeTrue())
+	return m.GetHistogram()
+}
+
 func ExpectMaxSkew(ctx context.Context, c client.Client, namespace string, constraint *v1.TopologySpreadConstraint) Assertion {
 	nodes := &v1.NodeList{}
 	ExpectWithOffset(1, c.List(ctx, nodes)).To(Succeed())
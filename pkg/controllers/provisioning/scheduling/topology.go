@@ -117,10 +117,10 @@ func (t *Topology) Update(ctx context.Context, p *v1.Pod) error {
 }
 
 // Record records the topology changes given that pod p schedule on a node with the given requirements
-func (t *Topology) Record(p *v1.Pod, requirements scheduling.Requirements) {
+func (t *Topology) Record(p *v1.Pod, taints []v1.Taint, requirements scheduling.Requirements) {
 	// once we've committed to a domain, we record the usage in every topology that cares about it
 	for _, tc := range t.topologies {
-		if tc.Counts(p, requirements) {
+		if tc.Counts(p, taints, requirements) {
 			domains := requirements.Get(tc.Key)
 			if tc.Type == TopologyTypePodAntiAffinity {
 				// for anti-affinity topologies we need to block out all possible domains that the pod could land in
@@ -210,7 +210,7 @@ func (t *Topology) updateInverseAntiAffinity(ctx context.Context, pod *v1.Pod, d
 			return err
 		}
 
-		tg := NewTopologyGroup(TopologyTypePodAntiAffinity, term.TopologyKey, pod, namespaces, term.LabelSelector, math.MaxInt32, nil, t.domains[term.TopologyKey])
+		tg := NewTopologyGroup(TopologyTypePodAntiAffinity, term.TopologyKey, pod, namespaces, term.LabelSelector, math.MaxInt32, nil, nil, nil, t.domains[term.TopologyKey])
 
 		hash := tg.Hash()
 		if existing, ok := t.inverseTopologies[hash]; !ok {
@@ -278,11 +278,35 @@ func (t *Topology) countDomains(ctx context.Context, tg *TopologyGroup) error {
 func (t *Topology) newForTopologies(p *v1.Pod) []*TopologyGroup {
 	var topologyGroups []*TopologyGroup
 	for _, cs := range p.Spec.TopologySpreadConstraints {
-		topologyGroups = append(topologyGroups, NewTopologyGroup(TopologyTypeSpread, cs.TopologyKey, p, sets.New(p.Namespace), cs.LabelSelector, cs.MaxSkew, cs.MinDomains, t.domains[cs.TopologyKey]))
+		topologyGroups = append(topologyGroups, NewTopologyGroup(TopologyTypeSpread, cs.TopologyKey, p, sets.New(p.Namespace), topologySpreadSelector(p, cs), cs.MaxSkew, cs.MinDomains,
+			cs.NodeAffinityPolicy, cs.NodeTaintsPolicy, t.domains[cs.TopologyKey]))
 	}
 	return topologyGroups
 }
 
+// topologySpreadSelector returns the label selector of the topology spread constraint, narrowed to the pod's values
+// for each of the constraint's matchLabelKeys. This allows pods from different revisions of a deployment to spread
+// independently of one another during a rolling update.
+// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/#spread-constraint-definition
+func topologySpreadSelector(p *v1.Pod, cs v1.TopologySpreadConstraint) *metav1.LabelSelector {
+	// matchLabelKeys are ignored when there's no label selector, which selects no pods
+	if cs.LabelSelector == nil || len(cs.MatchLabelKeys) == 0 {
+		return cs.LabelSelector
+	}
+	selector := cs.LabelSelector.DeepCopy()
+	for _, key := range cs.MatchLabelKeys {
+		// keys that the pod doesn't have a label for are ignored
+		if value, ok := p.Labels[key]; ok {
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      key,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   []string{value},
+			})
+		}
+	}
+	return selector
+}
+
 // newForAffinities returns a list of topology groups that have been constructed based on the input pod and required/preferred affinity terms
 func (t *Topology) newForAffinities(ctx context.Context, p *v1.Pod) ([]*TopologyGroup, error) {
 	var topologyGroups []*TopologyGroup
@@ -315,7 +339,7 @@ func (t *Topology) newForAffinities(ctx context.Context, p *v1.Pod) ([]*Topology
 			if err != nil {
 				return nil, err
 			}
-			topologyGroups = append(topologyGroups, NewTopologyGroup(topologyType, term.TopologyKey, p, namespaces, term.LabelSelector, math.MaxInt32, nil, t.domains[term.TopologyKey]))
+			topologyGroups = append(topologyGroups, NewTopologyGroup(topologyType, term.TopologyKey, p, namespaces, term.LabelSelector, math.MaxInt32, nil, nil, nil, t.domains[term.TopologyKey]))
 		}
 	}
 	return topologyGroups, nil
@@ -355,8 +379,9 @@ func (t *Topology) getMatchingTopologies(p *v1.Pod, requirements scheduling.Requ
 			matchingTopologies = append(matchingTopologies, tc)
 		}
 	}
+	// inverse topologies are only used for anti-affinities, which count across all nodes regardless of their taints
 	for _, tc := range t.inverseTopologies {
-		if tc.Counts(p, requirements) {
+		if tc.Counts(p, nil, requirements) {
 			matchingTopologies = append(matchingTopologies, tc)
 		}
 	}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
 	"github.com/aws/karpenter-core/pkg/test"
 	. "github.com/aws/karpenter-core/pkg/test/expectations"
 )
@@ -446,6 +448,73 @@ var _ = Describe("Topology", func() {
 			)
 			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(4, 4, 3))
 		})
+		It("should count existing pods towards minDomains constraints", func() {
+			if env.Version.Minor() < 24 {
+				Skip("MinDomains TopologySpreadConstraint is only available starting in K8s >= 1.24.x")
+			}
+			provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
+				{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1", "test-zone-2"}}}
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelTopologyZone,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+			}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: labels}, TopologySpreadConstraints: topology}, 2)...,
+			)
+			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(1, 1))
+
+			// With only two of the three required domains available, the global minimum is zero and each domain
+			// already has maxSkew pods
+			var minDomains int32 = 3
+			topology[0].MinDomains = &minDomains
+			pods := test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: labels}, TopologySpreadConstraints: topology}, 2)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			for _, p := range pods {
+				ExpectNotScheduled(ctx, env.Client, p)
+			}
+			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(1, 1))
+		})
+		It("should spread pods with different values for matchLabelKeys independently", func() {
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelTopologyZone,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+				MatchLabelKeys:    []string{"pod-template-hash"},
+			}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			// the pods of the previous revision are all in a single zone
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{
+					ObjectMeta:                metav1.ObjectMeta{Labels: lo.Assign(labels, map[string]string{"pod-template-hash": "a"})},
+					NodeSelector:              map[string]string{v1.LabelTopologyZone: "test-zone-1"},
+					TopologySpreadConstraints: topology,
+				}, 2)...,
+			)
+			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(2))
+
+			// the pods of the new revision are spread without regard to the pods of the previous revision
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{
+					ObjectMeta:                metav1.ObjectMeta{Labels: lo.Assign(labels, map[string]string{"pod-template-hash": "b"})},
+					TopologySpreadConstraints: topology,
+				}, 3)...,
+			)
+			ExpectSkew(ctx, env.Client, "default", &v1.TopologySpreadConstraint{
+				TopologyKey:   v1.LabelTopologyZone,
+				LabelSelector: &metav1.LabelSelector{MatchLabels: lo.Assign(labels, map[string]string{"pod-template-hash": "b"})},
+			}).To(ConsistOf(1, 1, 1))
+		})
+		It("should track topology spread constraints that only differ by minDomains separately", func() {
+			tg := scheduling.NewTopologyGroup(scheduling.TopologyTypeSpread, v1.LabelTopologyZone, test.Pod(), sets.New("default"),
+				&metav1.LabelSelector{MatchLabels: labels}, 1, nil, nil, nil, sets.New("test-zone-1"))
+			minDomainsTG := scheduling.NewTopologyGroup(scheduling.TopologyTypeSpread, v1.LabelTopologyZone, test.Pod(), sets.New("default"),
+				&metav1.LabelSelector{MatchLabels: labels}, 1, lo.ToPtr[int32](3), nil, nil, sets.New("test-zone-1"))
+			Expect(tg.Hash()).ToNot(Equal(minDomainsTG.Hash()))
+		})
 	})
 
 	Context("Hostname", func() {
@@ -844,6 +913,156 @@ var _ = Describe("Topology", func() {
 		})
 	})
 
+	Context("CapacitySpread", func() {
+		BeforeEach(func() {
+			provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
+				{Key: v1alpha5.LabelCapacitySpread, Operator: v1.NodeSelectorOpIn, Values: []string{"1", "2", "3"}}}
+		})
+		It("should balance pods across capacity spread values", func() {
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1alpha5.LabelCapacitySpread,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+			}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: labels}, TopologySpreadConstraints: topology}, 6)...,
+			)
+			ExpectSkew(ctx, env.Client, "default", &topology[0]).To(ConsistOf(2, 2, 2))
+		})
+		It("should assign capacity spread values to nodes in a round-robin fashion", func() {
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelHostname,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+			}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pods := test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: labels}, TopologySpreadConstraints: topology}, 3)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			values := sets.NewString()
+			for _, pod := range pods {
+				values.Insert(ExpectScheduled(ctx, env.Client, pod).Labels[v1alpha5.LabelCapacitySpread])
+			}
+			Expect(values.List()).To(ConsistOf("1", "2", "3"))
+		})
+		It("should only launch nodes with a capacity spread value that's required by the pod", func() {
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(test.PodOptions{NodeSelector: map[string]string{v1alpha5.LabelCapacitySpread: "2"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(ExpectScheduled(ctx, env.Client, pod).Labels).To(HaveKeyWithValue(v1alpha5.LabelCapacitySpread, "2"))
+		})
+	})
+
+	Context("Node Inclusion Policies", func() {
+		var newLabels map[string]string
+		BeforeEach(func() {
+			newLabels = lo.Assign(labels, map[string]string{"new": "true"})
+			provisioner.Spec.Requirements = []v1.NodeSelectorRequirement{
+				{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1", "test-zone-2"}}}
+		})
+		// createTaintedPods creates a node in test-zone-1 with a taint that's only tolerated by the pods that are bound to it
+		createTaintedPods := func(effect v1.TaintEffect) {
+			taint := v1.Taint{Key: "example.com/dedicated", Value: "true", Effect: effect}
+			node := test.Node(test.NodeOptions{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{v1.LabelTopologyZone: "test-zone-1"}},
+				Taints:     []v1.Taint{taint},
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			for i := 0; i < 2; i++ {
+				ExpectApplied(ctx, env.Client, test.Pod(test.PodOptions{
+					ObjectMeta:  metav1.ObjectMeta{Labels: labels},
+					NodeName:    node.Name,
+					Tolerations: []v1.Toleration{{Key: taint.Key, Operator: v1.TolerationOpExists}},
+				}))
+			}
+		}
+		It("should count pods on nodes with taints that aren't tolerated by default", func() {
+			createTaintedPods(v1.TaintEffectNoSchedule)
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelTopologyZone,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+			}}
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: newLabels}, TopologySpreadConstraints: topology}, 2)...,
+			)
+			// test-zone-1 already has two pods, so both new pods are scheduled to test-zone-2
+			ExpectSkew(ctx, env.Client, "default", &v1.TopologySpreadConstraint{
+				TopologyKey:   v1.LabelTopologyZone,
+				LabelSelector: &metav1.LabelSelector{MatchLabels: newLabels},
+			}).To(ConsistOf(2))
+		})
+		It("should not count pods on nodes with taints that aren't tolerated when nodeTaintsPolicy is Honor", func() {
+			createTaintedPods(v1.TaintEffectNoSchedule)
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelTopologyZone,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+				NodeTaintsPolicy:  lo.ToPtr(v1.NodeInclusionPolicyHonor),
+			}}
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: newLabels}, TopologySpreadConstraints: topology}, 2)...,
+			)
+			ExpectSkew(ctx, env.Client, "default", &v1.TopologySpreadConstraint{
+				TopologyKey:   v1.LabelTopologyZone,
+				LabelSelector: &metav1.LabelSelector{MatchLabels: newLabels},
+			}).To(ConsistOf(1, 1))
+		})
+		It("should count pods on nodes with PreferNoSchedule taints that aren't tolerated when nodeTaintsPolicy is Honor", func() {
+			createTaintedPods(v1.TaintEffectPreferNoSchedule)
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:       v1.LabelTopologyZone,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:           1,
+				NodeTaintsPolicy:  lo.ToPtr(v1.NodeInclusionPolicyHonor),
+			}}
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: newLabels}, TopologySpreadConstraints: topology}, 2)...,
+			)
+			// PreferNoSchedule taints don't exclude the node, so test-zone-1's two pods are still counted
+			ExpectSkew(ctx, env.Client, "default", &v1.TopologySpreadConstraint{
+				TopologyKey:   v1.LabelTopologyZone,
+				LabelSelector: &metav1.LabelSelector{MatchLabels: newLabels},
+			}).To(ConsistOf(2))
+		})
+		It("should count pods on nodes that don't match the pod's node affinity when nodeAffinityPolicy is Ignore", func() {
+			node := test.Node(test.NodeOptions{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				v1.LabelTopologyZone:       "test-zone-1",
+				v1alpha5.LabelCapacityType: v1alpha5.CapacityTypeOnDemand,
+			}}})
+			ExpectApplied(ctx, env.Client, provisioner, node)
+			ExpectReconcileSucceeded(ctx, nodeStateController, client.ObjectKeyFromObject(node))
+			for i := 0; i < 2; i++ {
+				ExpectApplied(ctx, env.Client, test.Pod(test.PodOptions{ObjectMeta: metav1.ObjectMeta{Labels: labels}, NodeName: node.Name}))
+			}
+			topology := []v1.TopologySpreadConstraint{{
+				TopologyKey:        v1.LabelTopologyZone,
+				WhenUnsatisfiable:  v1.DoNotSchedule,
+				LabelSelector:      &metav1.LabelSelector{MatchLabels: labels},
+				MaxSkew:            1,
+				NodeAffinityPolicy: lo.ToPtr(v1.NodeInclusionPolicyIgnore),
+			}}
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov,
+				test.UnschedulablePods(test.PodOptions{
+					ObjectMeta:                metav1.ObjectMeta{Labels: newLabels},
+					NodeRequirements:          []v1.NodeSelectorRequirement{{Key: v1alpha5.LabelCapacityType, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.CapacityTypeSpot}}},
+					TopologySpreadConstraints: topology,
+				}, 2)...,
+			)
+			// the on-demand node's pods are counted even though the new pods can only schedule to spot nodes
+			ExpectSkew(ctx, env.Client, "default", &v1.TopologySpreadConstraint{
+				TopologyKey:   v1.LabelTopologyZone,
+				LabelSelector: &metav1.LabelSelector{MatchLabels: newLabels},
+			}).To(ConsistOf(2))
+		})
+	})
+
 	Context("Combined Hostname and Zonal Topology", func() {
 		It("should spread pods while respecting both constraints (hostname and zonal)", func() {
 			topology := []v1.TopologySpreadConstraint{{
@@ -64,15 +64,16 @@ type TopologyGroup struct {
 	domains map[string]int32       // TODO(ellistarn) explore replacing with a minheap
 }
 
-func NewTopologyGroup(topologyType TopologyType, topologyKey string, pod *v1.Pod, namespaces sets.Set[string], labelSelector *metav1.LabelSelector, maxSkew int32, minDomains *int32, domains sets.Set[string]) *TopologyGroup {
+func NewTopologyGroup(topologyType TopologyType, topologyKey string, pod *v1.Pod, namespaces sets.Set[string], labelSelector *metav1.LabelSelector, maxSkew int32, minDomains *int32,
+	affinityPolicy, taintPolicy *v1.NodeInclusionPolicy, domains sets.Set[string]) *TopologyGroup {
 	domainCounts := map[string]int32{}
 	for domain := range domains {
 		domainCounts[domain] = 0
 	}
-	// the nil *TopologyNodeFilter always passes which is what we need for affinity/anti-affinity
+	// the zero-value TopologyNodeFilter always passes which is what we need for affinity/anti-affinity
 	var nodeSelector TopologyNodeFilter
 	if topologyType == TopologyTypeSpread {
-		nodeSelector = MakeTopologyNodeFilter(pod)
+		nodeSelector = MakeTopologyNodeFilter(pod, affinityPolicy, taintPolicy)
 	}
 	return &TopologyGroup{
 		Type:       topologyType,
@@ -107,9 +108,9 @@ func (t *TopologyGroup) Record(domains ...string) {
 }
 
 // Counts returns true if the pod would count for the topology, given that it schedule to a node with the provided
-// requirements
-func (t *TopologyGroup) Counts(pod *v1.Pod, requirements scheduling.Requirements) bool {
-	return t.selects(pod) && t.nodeFilter.MatchesRequirements(requirements)
+// taints and requirements
+func (t *TopologyGroup) Counts(pod *v1.Pod, taints []v1.Taint, requirements scheduling.Requirements) bool {
+	return t.selects(pod) && t.nodeFilter.MatchesRequirements(taints, requirements)
 }
 
 // Register ensures that the topology is aware of the given domain names.
@@ -143,6 +144,7 @@ func (t *TopologyGroup) Hash() uint64 {
 		Namespaces    sets.Set[string]
 		LabelSelector *metav1.LabelSelector
 		MaxSkew       int32
+		MinDomains    *int32
 		NodeFilter    TopologyNodeFilter
 	}{
 		TopologyKey:   t.Key,
@@ -150,6 +152,7 @@ func (t *TopologyGroup) Hash() uint64 {
 		Namespaces:    t.namespaces,
 		LabelSelector: t.selector,
 		MaxSkew:       t.maxSkew,
+		MinDomains:    t.minDomains,
 		NodeFilter:    t.nodeFilter,
 	}, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true}))
 }
@@ -203,6 +206,9 @@ func (t *TopologyGroup) domainMinCount(domains *scheduling.Requirement) int32 {
 			}
 		}
 	}
+	// when there are fewer eligible domains than minDomains, kube-scheduler treats the global minimum as zero so that
+	// pods are spread into new domains rather than packed into the existing ones
+	// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/#spread-constraint-definition
 	if t.minDomains != nil && numPodSupportedDomains < *t.minDomains {
 		min = 0
 	}
@@ -15,53 +15,81 @@ limitations under the License.
 package scheduling
 
 import (
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/aws/karpenter-core/pkg/scheduling"
 )
 
 // TopologyNodeFilter is used to determine if a given actual node or scheduling node matches the pod's node selectors
-// and required node affinity terms.  This is used with topology spread constraints to determine if the node should be
-// included for topology counting purposes. This is only used with topology spread constraints as affinities/anti-affinities
-// always count across all nodes. A nil or zero-value TopologyNodeFilter behaves well and the filter returns true for
-// all nodes.
-type TopologyNodeFilter []scheduling.Requirements
+// and required node affinity terms, and tolerates the node's taints, according to the nodeAffinityPolicy and
+// nodeTaintsPolicy of the topology spread constraint. This is used with topology spread constraints to determine if the
+// node should be included for topology counting purposes. This is only used with topology spread constraints as
+// affinities/anti-affinities always count across all nodes. A zero-value TopologyNodeFilter behaves well and the filter
+// returns true for all nodes.
+type TopologyNodeFilter struct {
+	Requirements   []scheduling.Requirements
+	AffinityPolicy v1.NodeInclusionPolicy
+	TaintPolicy    v1.NodeInclusionPolicy
+	Tolerations    []v1.Toleration
+}
 
-func MakeTopologyNodeFilter(p *v1.Pod) TopologyNodeFilter {
+// MakeTopologyNodeFilter constructs a TopologyNodeFilter for the pod. As with kube-scheduler, the pod's node affinity is
+// honored and the node's taints are ignored when the policies aren't set.
+// https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/#spread-constraint-definition
+func MakeTopologyNodeFilter(p *v1.Pod, affinityPolicy, taintPolicy *v1.NodeInclusionPolicy) TopologyNodeFilter {
+	filter := TopologyNodeFilter{
+		AffinityPolicy: lo.FromPtrOr(affinityPolicy, v1.NodeInclusionPolicyHonor),
+		TaintPolicy:    lo.FromPtrOr(taintPolicy, v1.NodeInclusionPolicyIgnore),
+	}
+	if filter.TaintPolicy == v1.NodeInclusionPolicyHonor {
+		filter.Tolerations = p.Spec.Tolerations
+	}
+	if filter.AffinityPolicy != v1.NodeInclusionPolicyHonor {
+		return filter
+	}
 	nodeSelectorRequirements := scheduling.NewLabelRequirements(p.Spec.NodeSelector)
 	// if we only have a label selector, that's the only requirement that must match
 	if p.Spec.Affinity == nil || p.Spec.Affinity.NodeAffinity == nil || p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
-		return TopologyNodeFilter{nodeSelectorRequirements}
+		filter.Requirements = []scheduling.Requirements{nodeSelectorRequirements}
+		return filter
 	}
 
 	// otherwise, we need to match the combination of label selector and any term of the required node affinities since
 	// those terms are OR'd together
-	var filter TopologyNodeFilter
 	for _, term := range p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
 		requirements := scheduling.NewRequirements()
 		requirements.Add(nodeSelectorRequirements.Values()...)
 		requirements.Add(scheduling.NewNodeSelectorRequirements(term.MatchExpressions...).Values()...)
-		filter = append(filter, requirements)
+		filter.Requirements = append(filter.Requirements, requirements)
 	}
-
 	return filter
 }
 
 // Matches returns true if the TopologyNodeFilter doesn't prohibit node from the participating in the topology
 func (t TopologyNodeFilter) Matches(node *v1.Node) bool {
-	return t.MatchesRequirements(scheduling.NewLabelRequirements(node.Labels))
+	return t.MatchesRequirements(node.Spec.Taints, scheduling.NewLabelRequirements(node.Labels))
 }
 
-// MatchesRequirements returns true if the TopologyNodeFilter doesn't prohibit a node with the requirements from
-// participating in the topology. This method allows checking the requirements from a scheduling.NodeClaim to see if the
-// node we will soon create participates in this topology.
-func (t TopologyNodeFilter) MatchesRequirements(requirements scheduling.Requirements) bool {
+// MatchesRequirements returns true if the TopologyNodeFilter doesn't prohibit a node with the taints and requirements
+// from participating in the topology. This method allows checking the requirements from a scheduling.NodeClaim to see
+// if the node we will soon create participates in this topology. Like the kube-scheduler, only NoSchedule and NoExecute
+// taints are considered when the taint policy is Honor.
+func (t TopologyNodeFilter) MatchesRequirements(taints []v1.Taint, requirements scheduling.Requirements) bool {
+	if t.TaintPolicy == v1.NodeInclusionPolicyHonor {
+		taints = lo.Filter(taints, func(taint v1.Taint, _ int) bool {
+			return taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute
+		})
+		if err := scheduling.Taints(taints).Tolerates(&v1.Pod{Spec: v1.PodSpec{Tolerations: t.Tolerations}}); err != nil {
+			return false
+		}
+	}
 	// no requirements, so it always matches
-	if len(t) == 0 {
+	if len(t.Requirements) == 0 {
 		return true
 	}
 	// these are an OR, so if any passes the filter passes
-	for _, req := range t {
+	for _, req := range t.Requirements {
 		if err := requirements.Compatible(req); err == nil {
 			return true
 		}
@@ -21,7 +21,9 @@ import (
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -49,6 +51,43 @@ func (v *VolumeTopology) Inject(ctx context.Context, pod *v1.Pod) error {
 	if len(requirements) == 0 {
 		return nil
 	}
+	injectRequirements(pod, requirements)
+
+	logging.FromContext(ctx).
+		With("pod", client.ObjectKeyFromObject(pod)).
+		Debugf("adding requirements derived from pod volumes, %s", requirements)
+	return nil
+}
+
+// InjectZone constrains a running pod to its current zone if it mounts a persistent volume with node affinity. The
+// volume is known to be attachable in the zone the pod is running in, so this keeps the pod from being rescheduled
+// to a zone where its volume can't follow when the node it's running on is replaced.
+func (v *VolumeTopology) InjectZone(ctx context.Context, pod *v1.Pod, zone string) error {
+	for _, volume := range pod.Spec.Volumes {
+		pvc, err := v.getPersistentVolumeClaim(ctx, pod, volume)
+		if err != nil {
+			return err
+		}
+		if pvc == nil || pvc.Spec.VolumeName == "" {
+			continue
+		}
+		pv := &v1.PersistentVolume{}
+		if err := v.kubeClient.Get(ctx, types.NamespacedName{Name: pvc.Spec.VolumeName}, pv); err != nil {
+			return fmt.Errorf("getting persistent volume %q, %w", pvc.Spec.VolumeName, err)
+		}
+		if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+			continue
+		}
+		injectRequirements(pod, []v1.NodeSelectorRequirement{{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{zone}}})
+		logging.FromContext(ctx).
+			With("pod", client.ObjectKeyFromObject(pod)).
+			Debugf("constraining pod to zone %s of persistent volume %s", zone, pv.Name)
+		return nil
+	}
+	return nil
+}
+
+func injectRequirements(pod *v1.Pod, requirements []v1.NodeSelectorRequirement) {
 	if pod.Spec.Affinity == nil {
 		pod.Spec.Affinity = &v1.Affinity{}
 	}
@@ -68,11 +107,6 @@ func (v *VolumeTopology) Inject(ctx context.Context, pod *v1.Pod) error {
 		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[i].MatchExpressions = append(
 			pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[i].MatchExpressions, requirements...)
 	}
-
-	logging.FromContext(ctx).
-		With("pod", client.ObjectKeyFromObject(pod)).
-		Debugf("adding requirements derived from pod volumes, %s", requirements)
-	return nil
 }
 
 func (v *VolumeTopology) getRequirements(ctx context.Context, pod *v1.Pod, volume v1.Volume) ([]v1.NodeSelectorRequirement, error) {
@@ -110,7 +144,7 @@ func (v *VolumeTopology) getRequirements(ctx context.Context, pod *v1.Pod, volum
 	}
 	// Storage Class Requirements
 	if storageClassName != "" {
-		requirements, err := v.getStorageClassRequirements(ctx, storageClassName)
+		requirements, err := v.getStorageClassRequirements(ctx, storageClassName, pvc)
 		if err != nil {
 			return nil, err
 		}
@@ -119,21 +153,107 @@ func (v *VolumeTopology) getRequirements(ctx context.Context, pod *v1.Pod, volum
 	return nil, nil
 }
 
-func (v *VolumeTopology) getStorageClassRequirements(ctx context.Context, storageClassName string) ([]v1.NodeSelectorRequirement, error) {
+func (v *VolumeTopology) getStorageClassRequirements(ctx context.Context, storageClassName string, pvc *v1.PersistentVolumeClaim) ([]v1.NodeSelectorRequirement, error) {
 	storageClass := &storagev1.StorageClass{}
 	if err := v.kubeClient.Get(ctx, types.NamespacedName{Name: storageClassName}, storageClass); err != nil {
 		return nil, fmt.Errorf("getting storage class %q, %w", storageClassName, err)
 	}
+	requirements := unionTerms(lo.Map(storageClass.AllowedTopologies, func(term v1.TopologySelectorTerm, _ int) []v1.NodeSelectorRequirement {
+		return lo.Map(term.MatchLabelExpressions, func(requirement v1.TopologySelectorLabelRequirement, _ int) v1.NodeSelectorRequirement {
+			return v1.NodeSelectorRequirement{Key: requirement.Key, Operator: v1.NodeSelectorOpIn, Values: requirement.Values}
+		})
+	}))
+	capacityRequirements, err := v.getStorageCapacityRequirements(ctx, storageClass, pvc)
+	if err != nil {
+		return nil, err
+	}
+	return append(requirements, capacityRequirements...), nil
+}
+
+// getStorageCapacityRequirements keeps an unbound volume out of the topology segments that its CSI driver reports as
+// not having enough capacity to provision it. Capacity is only tracked for drivers that opt in with
+// CSIDriver.spec.storageCapacity and for storage classes that delay binding until a pod using the volume is scheduled.
+// Segments without a CSIStorageCapacity have an unknown capacity, so they aren't excluded. Only segments that are
+// selected by a single label can be excluded since a node selector can't exclude a combination of labels.
+func (v *VolumeTopology) getStorageCapacityRequirements(ctx context.Context, storageClass *storagev1.StorageClass, pvc *v1.PersistentVolumeClaim) ([]v1.NodeSelectorRequirement, error) {
+	if lo.FromPtr(storageClass.VolumeBindingMode) != storagev1.VolumeBindingWaitForFirstConsumer {
+		return nil, nil
+	}
+	csiDriver := &storagev1.CSIDriver{}
+	if err := v.kubeClient.Get(ctx, types.NamespacedName{Name: storageClass.Provisioner}, csiDriver); err != nil {
+		return nil, client.IgnoreNotFound(fmt.Errorf("getting csi driver %q, %w", storageClass.Provisioner, err))
+	}
+	if !lo.FromPtr(csiDriver.Spec.StorageCapacity) {
+		return nil, nil
+	}
+	storageCapacities := &storagev1.CSIStorageCapacityList{}
+	if err := v.kubeClient.List(ctx, storageCapacities); err != nil {
+		return nil, fmt.Errorf("listing csi storage capacities, %w", err)
+	}
+	request := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	// Several CSIStorageCapacities can report on the same segment, which has enough capacity if any of them does
+	excluded := map[string]sets.Set[string]{}
+	included := map[string]sets.Set[string]{}
+	for _, storageCapacity := range storageCapacities.Items {
+		if storageCapacity.StorageClassName != storageClass.Name || storageCapacity.NodeTopology == nil || len(storageCapacity.NodeTopology.MatchLabels) != 1 {
+			continue
+		}
+		segments := lo.Ternary(hasStorageCapacity(storageCapacity, request), included, excluded)
+		for key, value := range storageCapacity.NodeTopology.MatchLabels {
+			if _, ok := segments[key]; !ok {
+				segments[key] = sets.New[string]()
+			}
+			segments[key].Insert(value)
+		}
+	}
 	var requirements []v1.NodeSelectorRequirement
-	if len(storageClass.AllowedTopologies) > 0 {
-		// Terms are ORed, only use the first term
-		for _, requirement := range storageClass.AllowedTopologies[0].MatchLabelExpressions {
-			requirements = append(requirements, v1.NodeSelectorRequirement{Key: requirement.Key, Operator: v1.NodeSelectorOpIn, Values: requirement.Values})
+	for key, values := range excluded {
+		if values = values.Difference(included[key]); values.Len() > 0 {
+			requirements = append(requirements, v1.NodeSelectorRequirement{Key: key, Operator: v1.NodeSelectorOpNotIn, Values: sets.List(values)})
 		}
 	}
 	return requirements, nil
 }
 
+// unionTerms returns requirements that are met by a node that meets any of the ORed terms. Only the keys that every
+// term constrains with the In operator can be constrained, to the union of their values, since a node could otherwise
+// meet one of the terms without a matching value for the key.
+func unionTerms(terms [][]v1.NodeSelectorRequirement) []v1.NodeSelectorRequirement {
+	if len(terms) == 0 {
+		return nil
+	}
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	var requirements []v1.NodeSelectorRequirement
+	for _, requirement := range lo.Filter(terms[0], func(r v1.NodeSelectorRequirement, _ int) bool { return r.Operator == v1.NodeSelectorOpIn }) {
+		var values []string
+		if !lo.EveryBy(terms, func(term []v1.NodeSelectorRequirement) bool {
+			r, ok := lo.Find(term, func(r v1.NodeSelectorRequirement) bool {
+				return r.Key == requirement.Key && r.Operator == v1.NodeSelectorOpIn
+			})
+			values = append(values, r.Values...)
+			return ok
+		}) {
+			continue
+		}
+		requirements = append(requirements, v1.NodeSelectorRequirement{Key: requirement.Key, Operator: v1.NodeSelectorOpIn, Values: lo.Uniq(values)})
+	}
+	return requirements
+}
+
+// hasStorageCapacity returns true if a volume of the requested size can be provisioned according to the
+// CSIStorageCapacity. The maximum volume size takes precedence over the total capacity when it's reported.
+func hasStorageCapacity(storageCapacity storagev1.CSIStorageCapacity, request resource.Quantity) bool {
+	if storageCapacity.MaximumVolumeSize != nil {
+		return storageCapacity.MaximumVolumeSize.Cmp(request) >= 0
+	}
+	if storageCapacity.Capacity != nil {
+		return storageCapacity.Capacity.Cmp(request) >= 0
+	}
+	return false
+}
+
 func (v *VolumeTopology) getPersistentVolumeRequirements(ctx context.Context, pod *v1.Pod, volumeName string) ([]v1.NodeSelectorRequirement, error) {
 	pv := &v1.PersistentVolume{}
 	if err := v.kubeClient.Get(ctx, types.NamespacedName{Name: volumeName, Namespace: pod.Namespace}, pv); err != nil {
@@ -145,12 +265,9 @@ func (v *VolumeTopology) getPersistentVolumeRequirements(ctx context.Context, po
 	if pv.Spec.NodeAffinity.Required == nil {
 		return nil, nil
 	}
-	var requirements []v1.NodeSelectorRequirement
-	if len(pv.Spec.NodeAffinity.Required.NodeSelectorTerms) > 0 {
-		// Terms are ORed, only use the first term
-		requirements = append(requirements, pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions...)
-	}
-	return requirements, nil
+	return unionTerms(lo.Map(pv.Spec.NodeAffinity.Required.NodeSelectorTerms, func(term v1.NodeSelectorTerm, _ int) []v1.NodeSelectorRequirement {
+		return term.MatchExpressions
+	})), nil
 }
 
 func (v *VolumeTopology) getPersistentVolumeClaim(ctx context.Context, pod *v1.Pod, volume v1.Volume) (*v1.PersistentVolumeClaim, error) {
@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
+)
+
+var errReadOnly = fmt.Errorf("the simulator can't write to the cluster")
+
+// readOnlyClient adapts a client.Reader to the client.Client that the provisioner and cluster state expect. Scheduling
+// simulations only read from the cluster, so every write fails.
+type readOnlyClient struct {
+	client.Reader
+}
+
+func (c *readOnlyClient) Create(context.Context, client.Object, ...client.CreateOption) error {
+	return errReadOnly
+}
+
+func (c *readOnlyClient) Delete(context.Context, client.Object, ...client.DeleteOption) error {
+	return errReadOnly
+}
+
+func (c *readOnlyClient) Update(context.Context, client.Object, ...client.UpdateOption) error {
+	return errReadOnly
+}
+
+func (c *readOnlyClient) Patch(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return errReadOnly
+}
+
+func (c *readOnlyClient) DeleteAllOf(context.Context, client.Object, ...client.DeleteAllOfOption) error {
+	return errReadOnly
+}
+
+func (c *readOnlyClient) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c *readOnlyClient) SubResource(string) client.SubResourceClient {
+	return readOnlySubResourceClient{}
+}
+
+func (c *readOnlyClient) Scheme() *runtime.Scheme {
+	return scheme.Scheme
+}
+
+func (c *readOnlyClient) RESTMapper() meta.RESTMapper {
+	return nil
+}
+
+type readOnlySubResourceClient struct{}
+
+func (readOnlySubResourceClient) Get(context.Context, client.Object, client.Object, ...client.SubResourceGetOption) error {
+	return errReadOnly
+}
+
+func (readOnlySubResourceClient) Create(context.Context, client.Object, client.Object, ...client.SubResourceCreateOption) error {
+	return errReadOnly
+}
+
+func (readOnlySubResourceClient) Update(context.Context, client.Object, ...client.SubResourceUpdateOption) error {
+	return errReadOnly
+}
+
+func (readOnlySubResourceClient) Patch(context.Context, client.Object, client.Patch, ...client.SubResourcePatchOption) error {
+	return errReadOnly
+}
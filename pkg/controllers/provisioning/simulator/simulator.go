@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package simulator runs Karpenter's scheduling logic against the state of a cluster without running any
+// controllers. It's intended for tooling such as capacity planners and cost estimators that need to know which
+// nodes Karpenter would launch for a set of pods.
+package simulator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-core/pkg/cloudprovider"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning/scheduling"
+	"github.com/aws/karpenter-core/pkg/controllers/state"
+	"github.com/aws/karpenter-core/pkg/events"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+)
+
+// NodeClaim is a node that would be launched along with the pods that would schedule to it
+type NodeClaim struct {
+	*v1beta1.NodeClaim
+	// InstanceTypes are the instance types that could fulfill the NodeClaim
+	InstanceTypes []*cloudprovider.InstanceType
+	Pods          []*v1.Pod
+}
+
+// Results of the simulation
+type Results struct {
+	// NodeClaims are the new nodes that would be launched
+	NodeClaims []*NodeClaim
+	// ExistingNodes maps the name of existing nodes to the pods that would schedule to them
+	ExistingNodes map[string][]*v1.Pod
+	// PodErrors are the reasons that pods couldn't be scheduled
+	PodErrors map[*v1.Pod]error
+}
+
+// Simulate returns the nodes that Karpenter would launch to schedule the pods given the cluster state read from the
+// kubeClient. The kubeClient is never written to and the pods aren't modified. The kubeClient must index the same
+// fields as the manager's cache: pods by spec.nodeName, nodes by spec.providerID and machines by status.providerID.
+func Simulate(ctx context.Context, kubeClient client.Reader, cloudProvider cloudprovider.CloudProvider, pods ...*v1.Pod) (*Results, error) {
+	c := &readOnlyClient{Reader: kubeClient}
+	cluster := state.NewCluster(clock.RealClock{}, c, cloudProvider)
+	if err := populateCluster(ctx, kubeClient, cluster); err != nil {
+		return nil, err
+	}
+	provisioner := provisioning.NewProvisioner(c, nil, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster)
+
+	results := &Results{ExistingNodes: map[string][]*v1.Pod{}, PodErrors: map[*v1.Pod]error{}}
+	// Simulated pods are copied since scheduling mutates them when relaxing preferences and injecting volume topology
+	copies := map[*v1.Pod]*v1.Pod{}
+	for _, pod := range pods {
+		if err := provisioner.Validate(ctx, pod); err != nil {
+			results.PodErrors[pod] = err
+			continue
+		}
+		copies[pod.DeepCopy()] = pod
+	}
+	if len(copies) == 0 {
+		return results, nil
+	}
+	schedulable := lo.Keys(copies)
+	s, err := provisioner.NewScheduler(ctx, schedulable, cluster.Nodes().Active(), scheduling.SchedulerOptions{SimulationMode: true})
+	if err != nil {
+		return nil, fmt.Errorf("creating scheduler, %w", err)
+	}
+	schedulingResults, err := s.Solve(ctx, schedulable)
+	if err != nil {
+		return nil, fmt.Errorf("scheduling pods, %w", err)
+	}
+
+	original := func(p *v1.Pod, _ int) *v1.Pod { return copies[p] }
+	for _, n := range schedulingResults.NewNodeClaims {
+		results.NodeClaims = append(results.NodeClaims, &NodeClaim{
+			NodeClaim:     toNodeClaim(n),
+			InstanceTypes: n.InstanceTypeOptions,
+			Pods:          lo.Map(n.Pods, original),
+		})
+	}
+	for _, n := range schedulingResults.ExistingNodes {
+		if len(n.Pods) > 0 {
+			results.ExistingNodes[n.Name()] = lo.Map(n.Pods, original)
+		}
+	}
+	for p, err := range schedulingResults.PodErrors {
+		results.PodErrors[copies[p]] = err
+	}
+	return results, nil
+}
+
+// populateCluster tracks the nodes, bound pods, daemonsets, machines and nodeclaims in the cluster state
+func populateCluster(ctx context.Context, kubeClient client.Reader, cluster *state.Cluster) error {
+	machineList := &v1alpha5.MachineList{}
+	if err := kubeClient.List(ctx, machineList); err != nil {
+		return fmt.Errorf("listing machines, %w", err)
+	}
+	for i := range machineList.Items {
+		cluster.UpdateNodeClaim(nodeclaimutil.New(&machineList.Items[i]))
+	}
+	nodeClaimList := &v1beta1.NodeClaimList{}
+	if err := kubeClient.List(ctx, nodeClaimList); err != nil {
+		return fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	for i := range nodeClaimList.Items {
+		cluster.UpdateNodeClaim(&nodeClaimList.Items[i])
+	}
+	nodeList := &v1.NodeList{}
+	if err := kubeClient.List(ctx, nodeList); err != nil {
+		return fmt.Errorf("listing nodes, %w", err)
+	}
+	for i := range nodeList.Items {
+		if err := cluster.UpdateNode(ctx, &nodeList.Items[i]); err != nil {
+			return fmt.Errorf("tracking node %q, %w", nodeList.Items[i].Name, err)
+		}
+	}
+	podList := &v1.PodList{}
+	if err := kubeClient.List(ctx, podList); err != nil {
+		return fmt.Errorf("listing pods, %w", err)
+	}
+	for i := range podList.Items {
+		if podList.Items[i].Spec.NodeName == "" {
+			continue
+		}
+		if err := cluster.UpdatePod(ctx, &podList.Items[i]); err != nil {
+			return fmt.Errorf("tracking pod %q, %w", client.ObjectKeyFromObject(&podList.Items[i]), err)
+		}
+	}
+	daemonSetList := &appsv1.DaemonSetList{}
+	if err := kubeClient.List(ctx, daemonSetList); err != nil {
+		return fmt.Errorf("listing daemonsets, %w", err)
+	}
+	for i := range daemonSetList.Items {
+		if err := cluster.UpdateDaemonSet(ctx, &daemonSetList.Items[i]); err != nil {
+			return fmt.Errorf("tracking daemonset %q, %w", client.ObjectKeyFromObject(&daemonSetList.Items[i]), err)
+		}
+	}
+	return nil
+}
+
+// toNodeClaim converts the scheduling NodeClaim to the NodeClaim that would be created for it
+func toNodeClaim(n *scheduling.NodeClaim) *v1beta1.NodeClaim {
+	nodeClaim := &v1beta1.NodeClaim{
+		ObjectMeta: *n.ObjectMeta.DeepCopy(),
+		Spec:       *n.Spec.DeepCopy(),
+		IsMachine:  n.OwnerKey.IsProvisioner,
+	}
+	nodeClaim.GenerateName = fmt.Sprintf("%s-", n.OwnerKey.Name)
+	nodeClaim.Spec.Requirements = n.Requirements.NodeSelectorRequirements()
+	return nodeClaim
+}
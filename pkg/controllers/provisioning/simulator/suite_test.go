@@ -0,0 +1,132 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	. "knative.dev/pkg/logging/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
+	"github.com/aws/karpenter-core/pkg/controllers/provisioning/simulator"
+	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/test"
+)
+
+var ctx context.Context
+var cloudProvider *fake.CloudProvider
+
+func TestSimulator(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Provisioning/Simulator")
+}
+
+var _ = BeforeSuite(func() {
+	ctx = settings.ToContext(ctx, test.Settings())
+	cloudProvider = fake.NewCloudProvider()
+})
+
+var _ = BeforeEach(func() {
+	cloudProvider.Reset()
+})
+
+var _ = Describe("Simulator", func() {
+	var provisioner *v1alpha5.Provisioner
+	BeforeEach(func() {
+		provisioner = test.Provisioner()
+	})
+	It("should return the nodes that would be launched for pending pods", func() {
+		pods := test.UnschedulablePods(test.PodOptions{}, 3)
+		results, err := simulator.Simulate(ctx, newClient(provisioner), cloudProvider, pods...)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.PodErrors).To(BeEmpty())
+		Expect(results.NodeClaims).To(HaveLen(1))
+		Expect(results.NodeClaims[0].Pods).To(ConsistOf(pods[0], pods[1], pods[2]))
+		Expect(results.NodeClaims[0].Labels).To(HaveKeyWithValue(v1alpha5.ProvisionerNameLabelKey, provisioner.Name))
+		Expect(results.NodeClaims[0].InstanceTypes).ToNot(BeEmpty())
+		Expect(results.NodeClaims[0].IsMachine).To(BeTrue())
+	})
+	It("should schedule pods to existing nodes with available capacity", func() {
+		node := test.Node(test.NodeOptions{
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10"), v1.ResourceMemory: resource.MustParse("10Gi"), v1.ResourcePods: resource.MustParse("10")},
+		})
+		pod := test.UnschedulablePod()
+		results, err := simulator.Simulate(ctx, newClient(node, provisioner), cloudProvider, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.NodeClaims).To(BeEmpty())
+		Expect(results.ExistingNodes).To(HaveKeyWithValue(node.Name, ConsistOf(pod)))
+	})
+	It("should account for pods that are bound to existing nodes", func() {
+		node := test.Node(test.NodeOptions{
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("10Gi"), v1.ResourcePods: resource.MustParse("10")},
+		})
+		bound := test.Pod(test.PodOptions{
+			NodeName:             node.Name,
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}},
+		})
+		pod := test.UnschedulablePod(test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		})
+		results, err := simulator.Simulate(ctx, newClient(node, bound, provisioner), cloudProvider, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.ExistingNodes).To(BeEmpty())
+		Expect(results.NodeClaims).To(HaveLen(1))
+		Expect(results.NodeClaims[0].Pods).To(ConsistOf(pod))
+	})
+	It("should return errors for pods that can't be scheduled", func() {
+		pod := test.UnschedulablePod(test.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "invalid"}})
+		results, err := simulator.Simulate(ctx, newClient(provisioner), cloudProvider, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.NodeClaims).To(BeEmpty())
+		Expect(results.PodErrors).To(HaveKey(pod))
+	})
+	It("should not modify the pods that are simulated", func() {
+		pod := test.UnschedulablePod(test.PodOptions{NodePreferences: []v1.NodeSelectorRequirement{
+			{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"invalid"}},
+		}})
+		expected := pod.DeepCopy()
+		results, err := simulator.Simulate(ctx, newClient(provisioner), cloudProvider, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results.NodeClaims).To(HaveLen(1))
+		Expect(pod).To(Equal(expected))
+	})
+})
+
+// newClient returns an in-memory client seeded with the objects that's indexed in the same way as the manager's cache
+func newClient(objects ...client.Object) client.Client {
+	return crfake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objects...).
+		WithIndex(&v1.Pod{}, "spec.nodeName", func(o client.Object) []string {
+			return []string{o.(*v1.Pod).Spec.NodeName}
+		}).
+		WithIndex(&v1.Node{}, "spec.providerID", func(o client.Object) []string {
+			return []string{o.(*v1.Node).Spec.ProviderID}
+		}).
+		WithIndex(&v1alpha5.Machine{}, "status.providerID", func(o client.Object) []string {
+			return []string{o.(*v1alpha5.Machine).Status.ProviderID}
+		}).
+		Build()
+}
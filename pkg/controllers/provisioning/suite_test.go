@@ -15,9 +15,13 @@ limitations under the License.
 package provisioning_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,7 +47,10 @@ import (
 	"github.com/aws/karpenter-core/pkg/events"
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/scheme"
+	"github.com/aws/karpenter-core/pkg/scheduling"
 	"github.com/aws/karpenter-core/pkg/test"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
+	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
 	"github.com/aws/karpenter-core/pkg/utils/sets"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -131,6 +138,33 @@ var _ = Describe("Provisioning", func() {
 		Expect(len(nodes.Items)).To(Equal(0))
 		ExpectNotScheduled(ctx, env.Client, pod)
 	})
+	It("should not provision nodes for pods with scheduling gates", func() {
+		ExpectApplied(ctx, env.Client, test.Provisioner())
+		pod := test.UnschedulablePod()
+		pod.Spec.SchedulingGates = []v1.PodSchedulingGate{{Name: "example.com/gate"}}
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		nodes := &v1.NodeList{}
+		Expect(env.Client.List(ctx, nodes)).To(Succeed())
+		Expect(len(nodes.Items)).To(Equal(0))
+		ExpectNotScheduled(ctx, env.Client, pod)
+	})
+	It("should not consider pods that reference resource claims provisionable", func() {
+		pod := test.UnschedulablePod()
+		Expect(podutil.IsProvisionable(pod)).To(BeTrue())
+		pod.Spec.ResourceClaims = []v1.PodResourceClaim{{Name: "gpu", Source: v1.ClaimSource{ResourceClaimName: lo.ToPtr("gpu-claim")}}}
+		Expect(podutil.IsProvisionable(pod)).To(BeFalse())
+	})
+	It("should provision nodes for pods once their scheduling gates are removed", func() {
+		ExpectApplied(ctx, env.Client, test.Provisioner())
+		pod := test.UnschedulablePod()
+		pod.Spec.SchedulingGates = []v1.PodSchedulingGate{{Name: "example.com/gate"}}
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectNotScheduled(ctx, env.Client, pod)
+
+		pod.Spec.SchedulingGates = nil
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectScheduled(ctx, env.Client, pod)
+	})
 	It("should provision nodes for pods with supported node selectors", func() {
 		provisioner := test.Provisioner()
 		schedulable := []*v1.Pod{
@@ -377,6 +411,71 @@ var _ = Describe("Provisioning", func() {
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 			ExpectNotScheduled(ctx, env.Client, pod)
 		})
+		It("should not launch more nodes than the maxNodes limit", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.Limits.MaxNodes = ptr.Int32(1)
+			ExpectApplied(ctx, env.Client, provisioner)
+
+			// prevent these pods from scheduling on the same node
+			opts := test.PodOptions{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "foo"}},
+				PodAntiRequirements: []v1.PodAffinityTerm{{
+					TopologyKey:   v1.LabelHostname,
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+				}},
+			}
+			pods := []*v1.Pod{test.UnschedulablePod(opts), test.UnschedulablePod(opts)}
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+			scheduled := lo.Filter(pods, func(p *v1.Pod, _ int) bool {
+				return ExpectPodExists(ctx, env.Client, p.Name, p.Namespace).Spec.NodeName != ""
+			})
+			Expect(scheduled).To(HaveLen(1))
+		})
+		It("should not launch nodes after a scheduling round if the maxNodes limit would be exceeded", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.Limits.MaxNodes = ptr.Int32(1)
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod(test.PodOptions{NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "small-instance-type"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+
+			// This pod can't schedule to the existing node, so it would require a second node
+			pod = test.UnschedulablePod(test.PodOptions{NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "default-instance-type"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should not launch offerings that would exceed a scoped limit", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.Limits.Scoped = []v1alpha5.ScopedLimit{{CapacityType: v1alpha5.CapacityTypeSpot, MaxNodes: ptr.Int32(0)}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1alpha5.LabelCapacityType, v1alpha5.CapacityTypeOnDemand))
+		})
+		It("should count existing nodes against a scoped limit", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.Limits.Scoped = []v1alpha5.ScopedLimit{{Zone: "test-zone-1", MaxNodes: ptr.Int32(1)}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			opts := test.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1", v1.LabelInstanceTypeStable: "small-instance-type"}}
+			pod := test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+
+			// This pod can't schedule to the existing node, so it would require a second node in the zone
+			opts.NodeSelector[v1.LabelInstanceTypeStable] = "default-instance-type"
+			pod = test.UnschedulablePod(opts)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should not schedule if all offerings would exceed a scoped limit", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.Limits.Scoped = []v1alpha5.ScopedLimit{{Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}}
+			ExpectApplied(ctx, env.Client, provisioner)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
 	})
 	Context("Daemonsets and Node Overhead", func() {
 		It("should account for overhead", func() {
@@ -598,6 +697,37 @@ var _ = Describe("Provisioning", func() {
 			Expect(*allocatable.Cpu()).To(Equal(resource.MustParse("4")))
 			Expect(*allocatable.Memory()).To(Equal(resource.MustParse("4Gi")))
 		})
+		It("should only account for daemonsets against the instance types they can schedule to", func() {
+			ExpectApplied(ctx, env.Client, test.Provisioner(), test.DaemonSet(
+				test.DaemonSetOptions{PodOptions: test.PodOptions{
+					NodeSelector:         map[string]string{fake.LabelInstanceSize: "large"},
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10000"), v1.ResourceMemory: resource.MustParse("10000Gi")}},
+				}},
+			))
+			pod := test.UnschedulablePod(
+				test.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")}},
+				},
+			)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1.LabelInstanceTypeStable]).ToNot(Equal("arm-instance-type"))
+		})
+		It("should account for daemonsets against the instance types they can schedule to", func() {
+			ExpectApplied(ctx, env.Client, test.Provisioner(), test.DaemonSet(
+				test.DaemonSetOptions{PodOptions: test.PodOptions{
+					NodeSelector:         map[string]string{fake.LabelInstanceSize: "large"},
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10000"), v1.ResourceMemory: resource.MustParse("10000Gi")}},
+				}},
+			))
+			pod := test.UnschedulablePod(
+				test.PodOptions{
+					NodeSelector: map[string]string{fake.LabelInstanceSize: "large"},
+				},
+			)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
 	})
 	Context("Annotations", func() {
 		It("should annotate nodes", func() {
@@ -1102,6 +1232,50 @@ var _ = Describe("Volume Topology Requirements", func() {
 		node := ExpectScheduled(ctx, env.Client, pod)
 		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-3"))
 	})
+	It("should schedule to any of the zones of the storage class allowed topologies", func() {
+		storageClass.AllowedTopologies = []v1.TopologySelectorTerm{
+			{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: v1.LabelTopologyZone, Values: []string{"test-zone-1"}}}},
+			{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: v1.LabelTopologyZone, Values: []string{"test-zone-3"}}}},
+		}
+		persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+		ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, persistentVolumeClaim)
+		pod := test.UnschedulablePod(test.PodOptions{
+			PersistentVolumeClaims: []string{persistentVolumeClaim.Name},
+			NodeSelector:           map[string]string{v1.LabelTopologyZone: "test-zone-3"},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-3"))
+	})
+	It("should not schedule outside of the zones of the storage class allowed topologies", func() {
+		storageClass.AllowedTopologies = []v1.TopologySelectorTerm{
+			{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: v1.LabelTopologyZone, Values: []string{"test-zone-1"}}}},
+			{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: v1.LabelTopologyZone, Values: []string{"test-zone-3"}}}},
+		}
+		persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+		ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, persistentVolumeClaim)
+		pod := test.UnschedulablePod(test.PodOptions{
+			PersistentVolumeClaims: []string{persistentVolumeClaim.Name},
+			NodeSelector:           map[string]string{v1.LabelTopologyZone: "test-zone-2"},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectNotScheduled(ctx, env.Client, pod)
+	})
+	It("should schedule to any of the zones of the volume node affinity terms", func() {
+		persistentVolume := test.PersistentVolume(test.PersistentVolumeOptions{Zones: []string{"test-zone-1"}})
+		persistentVolume.Spec.NodeAffinity.Required.NodeSelectorTerms = append(persistentVolume.Spec.NodeAffinity.Required.NodeSelectorTerms, v1.NodeSelectorTerm{
+			MatchExpressions: []v1.NodeSelectorRequirement{{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-3"}}},
+		})
+		persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{VolumeName: persistentVolume.Name, StorageClassName: &storageClass.Name})
+		ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, persistentVolumeClaim, persistentVolume)
+		pod := test.UnschedulablePod(test.PodOptions{
+			PersistentVolumeClaims: []string{persistentVolumeClaim.Name},
+			NodeSelector:           map[string]string{v1.LabelTopologyZone: "test-zone-3"},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-3"))
+	})
 	It("should schedule to volume zones if volume already bound (ephemeral volume)", func() {
 		pod := test.UnschedulablePod(test.PodOptions{
 			EphemeralVolumeTemplates: []test.EphemeralVolumeTemplateOptions{
@@ -1192,6 +1366,66 @@ var _ = Describe("Volume Topology Requirements", func() {
 		node := ExpectScheduled(ctx, env.Client, pod)
 		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-3"))
 	})
+	Context("Storage Capacity", func() {
+		var csiDriver *storagev1.CSIDriver
+		BeforeEach(func() {
+			storageClass = test.StorageClass(test.StorageClassOptions{VolumeBindingMode: lo.ToPtr(storagev1.VolumeBindingWaitForFirstConsumer)})
+			csiDriver = &storagev1.CSIDriver{
+				ObjectMeta: metav1.ObjectMeta{Name: storageClass.Provisioner},
+				Spec:       storagev1.CSIDriverSpec{StorageCapacity: lo.ToPtr(true)},
+			}
+		})
+		storageCapacity := func(zone string, capacity string) *storagev1.CSIStorageCapacity {
+			return &storagev1.CSIStorageCapacity{
+				ObjectMeta:       test.NamespacedObjectMeta(metav1.ObjectMeta{}),
+				StorageClassName: storageClass.Name,
+				NodeTopology:     &metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelTopologyZone: zone}},
+				Capacity:         lo.ToPtr(resource.MustParse(capacity)),
+			}
+		}
+		It("should schedule to zones with enough storage capacity for the volume", func() {
+			persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+			ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, csiDriver, persistentVolumeClaim,
+				storageCapacity("test-zone-1", "500Mi"), storageCapacity("test-zone-2", "10Gi"), storageCapacity("test-zone-3", "500Mi"))
+			pod := test.UnschedulablePod(test.PodOptions{PersistentVolumeClaims: []string{persistentVolumeClaim.Name}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-2"))
+		})
+		It("should not schedule if no zone has enough storage capacity for the volume", func() {
+			persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+			ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, csiDriver, persistentVolumeClaim,
+				storageCapacity("test-zone-1", "500Mi"), storageCapacity("test-zone-2", "500Mi"), storageCapacity("test-zone-3", "500Mi"))
+			pod := test.UnschedulablePod(test.PodOptions{PersistentVolumeClaims: []string{persistentVolumeClaim.Name}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should schedule to zones without a reported storage capacity", func() {
+			persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+			ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, csiDriver, persistentVolumeClaim,
+				storageCapacity("test-zone-1", "500Mi"), storageCapacity("test-zone-2", "500Mi"))
+			pod := test.UnschedulablePod(test.PodOptions{PersistentVolumeClaims: []string{persistentVolumeClaim.Name}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-3"))
+		})
+		It("should not constrain the volume if no storage capacity is reported", func() {
+			persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+			ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, csiDriver, persistentVolumeClaim)
+			pod := test.UnschedulablePod(test.PodOptions{PersistentVolumeClaims: []string{persistentVolumeClaim.Name}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+		})
+		It("should ignore storage capacity if the csi driver doesn't track it", func() {
+			csiDriver.Spec.StorageCapacity = lo.ToPtr(false)
+			persistentVolumeClaim := test.PersistentVolumeClaim(test.PersistentVolumeClaimOptions{StorageClassName: &storageClass.Name})
+			ExpectApplied(ctx, env.Client, test.Provisioner(), storageClass, csiDriver, persistentVolumeClaim,
+				storageCapacity("test-zone-1", "500Mi"))
+			pod := test.UnschedulablePod(test.PodOptions{PersistentVolumeClaims: []string{persistentVolumeClaim.Name}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+		})
+	})
 })
 
 var _ = Describe("Preferential Fallback", func() {
@@ -1316,6 +1550,16 @@ var _ = Describe("Multiple Provisioners", func() {
 		node := ExpectScheduled(ctx, env.Client, pod)
 		Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioner.Name))
 	})
+	It("should skip provisioners whose instance types can't be resolved due to a terminal error", func() {
+		misconfigured := test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100)})
+		provisioner := test.Provisioner()
+		cloudProvider.ErrorsForProvisioner[misconfigured.Name] = cloudprovider.NewInvalidTemplateError(fmt.Errorf("node template not found"))
+		ExpectApplied(ctx, env.Client, misconfigured, provisioner)
+		pod := test.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioner.Name))
+	})
 	It("should not match provisioner with PreferNoSchedule taint when other provisioner match", func() {
 		provisioner := test.Provisioner(test.ProvisionerOptions{Taints: []v1.Taint{{Key: "foo", Value: "bar", Effect: v1.TaintEffectPreferNoSchedule}}})
 		ExpectApplied(ctx, env.Client, provisioner, test.Provisioner())
@@ -1354,6 +1598,260 @@ var _ = Describe("Multiple Provisioners", func() {
 			node := ExpectScheduled(ctx, env.Client, pod)
 			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(targetedProvisioner.Name))
 		})
+		It("should fall back to a lower weight provisioner while the highest weight provisioner fails to launch capacity", func() {
+			provisioners := []client.Object{
+				test.Provisioner(),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(20)}),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100)}),
+			}
+			ExpectApplied(ctx, env.Client, provisioners...)
+			cluster.MarkLaunchFailed(nodepoolutil.Key{Name: provisioners[2].GetName(), IsProvisioner: true}, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all instance types were unavailable")), true)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioners[1].GetName()))
+		})
+		It("should schedule to the highest weight provisioner again once its launch failure expires", func() {
+			provisioners := []client.Object{
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(20)}),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100)}),
+			}
+			ExpectApplied(ctx, env.Client, provisioners...)
+			cluster.MarkLaunchFailed(nodepoolutil.Key{Name: provisioners[1].GetName(), IsProvisioner: true}, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all instance types were unavailable")), true)
+			fakeClock.Step(state.LaunchFailureTTL)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioners[1].GetName()))
+		})
+		It("should schedule to a provisioner that failed to launch capacity if no other provisioner is compatible", func() {
+			targetedProvisioner := test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100)})
+			provisioners := []client.Object{
+				targetedProvisioner,
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(20)}),
+			}
+			ExpectApplied(ctx, env.Client, provisioners...)
+			cluster.MarkLaunchFailed(nodepoolutil.Key{Name: targetedProvisioner.Name, IsProvisioner: true}, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all instance types were unavailable")), true)
+			pod := test.UnschedulablePod(test.PodOptions{NodeSelector: map[string]string{v1alpha5.ProvisionerNameLabelKey: targetedProvisioner.Name}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(targetedProvisioner.Name))
+		})
+		It("should fall back to a lower weight provisioner while the offerings of the highest weight provisioner are unavailable", func() {
+			cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+				fake.NewInstanceType(fake.InstanceTypeOptions{
+					Name: "default-instance-type",
+					Offerings: []cloudprovider.Offering{
+						{CapacityType: v1alpha5.CapacityTypeSpot, Zone: "test-zone-1", Price: 1, Available: false},
+						{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 2, Available: true},
+					},
+				}),
+			}
+			DeferCleanup(func() { cloudProvider.InstanceTypes = nil })
+			provisioners := []client.Object{
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(20)}),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100), Requirements: []v1.NodeSelectorRequirement{
+					{Key: v1alpha5.LabelCapacityType, Operator: v1.NodeSelectorOpIn, Values: []string{v1alpha5.CapacityTypeSpot}},
+				}}),
+			}
+			ExpectApplied(ctx, env.Client, provisioners...)
+			// the offering ran out of capacity, so only the offering is unavailable and the provisioner isn't demoted
+			cluster.MarkLaunchFailed(nodepoolutil.Key{Name: provisioners[1].GetName(), IsProvisioner: true}, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("spot capacity was unavailable")), false)
+			recorder := test.NewEventRecorder()
+			p := provisioning.NewProvisioner(env.Client, corev1.NewForConfigOrDie(env.Config), recorder, cloudProvider, cluster)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, p, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioners[0].GetName()))
+			Expect(recorder.Calls("FallbackScheduling")).To(Equal(1))
+			Expect(recorder.DetectedEvent(fmt.Sprintf("Falling back to provisioner %q, provisioner %q failed to launch capacity, insufficient capacity, spot capacity was unavailable",
+				provisioners[0].GetName(), provisioners[1].GetName()))).To(BeTrue())
+		})
+		It("should record the fallback chain in the order of the provisioner weights", func() {
+			provisioners := []client.Object{
+				test.Provisioner(),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(20), Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("0")}}),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100)}),
+			}
+			ExpectApplied(ctx, env.Client, provisioners...)
+			cluster.MarkLaunchFailed(nodepoolutil.Key{Name: provisioners[2].GetName(), IsProvisioner: true}, cloudprovider.NewQuotaExceededError(fmt.Errorf("vcpu limit exceeded")), true)
+			recorder := test.NewEventRecorder()
+			p := provisioning.NewProvisioner(env.Client, corev1.NewForConfigOrDie(env.Config), recorder, cloudProvider, cluster)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, p, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioners[0].GetName()))
+			Expect(recorder.DetectedEvent(fmt.Sprintf("Falling back to provisioner %q, provisioner %q failed to launch capacity, quota exceeded, vcpu limit exceeded; all available instance types exceed limits for provisioner: %q",
+				provisioners[0].GetName(), provisioners[2].GetName(), provisioners[1].GetName()))).To(BeTrue())
+		})
+		It("should fall back to a lower weight provisioner when the highest weight provisioner exceeds its limits", func() {
+			provisioners := []client.Object{
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(20)}),
+				test.Provisioner(test.ProvisionerOptions{Weight: ptr.Int32(100), Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("0")}}),
+			}
+			ExpectApplied(ctx, env.Client, provisioners...)
+			pod := test.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1alpha5.ProvisionerNameLabelKey]).To(Equal(provisioners[0].GetName()))
+		})
+	})
+})
+
+var _ = Describe("Dry Run", func() {
+	It("should return the machines that would be launched without launching them", func() {
+		provisioner := test.Provisioner()
+		ExpectApplied(ctx, env.Client, provisioner)
+		pod := test.UnschedulablePod()
+		response, err := prov.DryRun(ctx, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.NodeClaims).To(HaveLen(1))
+		Expect(response.NodeClaims[0].Owner).To(Equal(provisioner.Name))
+		Expect(response.NodeClaims[0].InstanceTypes).ToNot(BeEmpty())
+		Expect(response.NodeClaims[0].Pods).To(ConsistOf(client.ObjectKeyFromObject(pod).String()))
+		Expect(response.PodErrors).To(BeEmpty())
+
+		machines := &v1alpha5.MachineList{}
+		Expect(env.Client.List(ctx, machines)).To(Succeed())
+		Expect(machines.Items).To(BeEmpty())
+		Expect(cloudProvider.CreateCalls).To(BeEmpty())
+	})
+	It("should default the namespace and name of pods", func() {
+		ExpectApplied(ctx, env.Client, test.Provisioner())
+		pod := test.UnschedulablePod()
+		pod.Namespace = ""
+		pod.Name = ""
+		response, err := prov.DryRun(ctx, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.NodeClaims).To(HaveLen(1))
+		Expect(response.NodeClaims[0].Pods).To(ConsistOf("default/dry-run-0"))
+	})
+	It("should return the reason that pods can't be scheduled", func() {
+		ExpectApplied(ctx, env.Client, test.Provisioner())
+		pod := test.UnschedulablePod(test.PodOptions{
+			NodeSelector: map[string]string{v1.LabelTopologyZone: "unknown-zone"},
+		})
+		response, err := prov.DryRun(ctx, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.NodeClaims).To(BeEmpty())
+		Expect(response.PodErrors).To(HaveKey(client.ObjectKeyFromObject(pod).String()))
+	})
+	It("should serve dry runs that are POSTed to the handler", func() {
+		provisioner := test.Provisioner()
+		ExpectApplied(ctx, env.Client, provisioner)
+		pod := test.UnschedulablePod()
+		body, err := json.Marshal(provisioning.DryRunRequest{Pods: []*v1.Pod{pod}})
+		Expect(err).ToNot(HaveOccurred())
+		recorder := httptest.NewRecorder()
+		prov.DryRunHandler(ctx).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, provisioning.DryRunPath, bytes.NewReader(body)))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		response := &provisioning.DryRunResponse{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), response)).To(Succeed())
+		Expect(response.NodeClaims).To(HaveLen(1))
+		Expect(response.NodeClaims[0].Owner).To(Equal(provisioner.Name))
+	})
+	It("should reject dry runs that aren't POSTed", func() {
+		recorder := httptest.NewRecorder()
+		prov.DryRunHandler(ctx).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, provisioning.DryRunPath, nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+	It("should reject dry run requests that are too large", func() {
+		body := fmt.Sprintf(`{"pods": [{"metadata": {"name": %q}}]}`, strings.Repeat("a", 11<<20))
+		recorder := httptest.NewRecorder()
+		prov.DryRunHandler(ctx).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, provisioning.DryRunPath, strings.NewReader(body)))
+		Expect(recorder.Code).To(Equal(http.StatusRequestEntityTooLarge))
+	})
+})
+
+var _ = Describe("Instance Type Requirements", func() {
+	It("should pass the provisioner requirements to the cloudprovider and drop incompatible instance types", func() {
+		recordingCloudProvider := &requirementsRecordingCloudProvider{CloudProvider: fake.NewCloudProvider()}
+		recordingCloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+			fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name:      "zone-1-instance-type",
+				Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-1", Price: 1, Available: true}},
+			}),
+			fake.NewInstanceType(fake.InstanceTypeOptions{
+				Name:      "zone-2-instance-type",
+				Offerings: []cloudprovider.Offering{{CapacityType: v1alpha5.CapacityTypeOnDemand, Zone: "test-zone-2", Price: 1, Available: true}},
+			}),
+		}
+		p := provisioning.NewProvisioner(env.Client, corev1.NewForConfigOrDie(env.Config), events.NewRecorder(&record.FakeRecorder{}), recordingCloudProvider, cluster)
+		ExpectApplied(ctx, env.Client, test.Provisioner(test.ProvisionerOptions{
+			Requirements: []v1.NodeSelectorRequirement{{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-2"}}},
+		}))
+
+		response, err := p.DryRun(ctx, test.UnschedulablePod())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.NodeClaims).To(HaveLen(1))
+		Expect(response.NodeClaims[0].InstanceTypes).To(ConsistOf("zone-2-instance-type"))
+
+		Expect(recordingCloudProvider.requirements).To(HaveLen(1))
+		Expect(recordingCloudProvider.requirements[0].Get(v1.LabelTopologyZone).Values()).To(ConsistOf("test-zone-2"))
+	})
+})
+
+// requirementsRecordingCloudProvider records the requirements that GetInstanceTypes is called with and ignores them
+type requirementsRecordingCloudProvider struct {
+	*fake.CloudProvider
+	requirements []scheduling.Requirements
+}
+
+func (c *requirementsRecordingCloudProvider) GetInstanceTypes(ctx context.Context, provisioner *v1alpha5.Provisioner) ([]*cloudprovider.InstanceType, error) {
+	if requirements, ok := cloudprovider.RequirementsFromContext(ctx); ok {
+		c.requirements = append(c.requirements, requirements)
+	}
+	return c.CloudProvider.GetInstanceTypes(ctx, provisioner)
+}
+
+var _ = Describe("Unschedulable Pods", func() {
+	var unschedulablePods *provisioning.UnschedulablePods
+	var pod *v1.Pod
+	BeforeEach(func() {
+		unschedulablePods = provisioning.NewUnschedulablePods(fakeClock)
+		pod = test.UnschedulablePod()
+	})
+	It("should not filter pods that haven't failed to schedule", func() {
+		Expect(unschedulablePods.Filter([]*v1.Pod{pod})).To(ConsistOf(pod))
+	})
+	It("should back off pods exponentially after they fail to schedule", func() {
+		failures, backoff := unschedulablePods.Failed(pod)
+		Expect(failures).To(Equal(1))
+		Expect(backoff).To(Equal(10 * time.Second))
+		Expect(unschedulablePods.Filter([]*v1.Pod{pod})).To(BeEmpty())
+
+		fakeClock.Step(backoff)
+		Expect(unschedulablePods.Filter([]*v1.Pod{pod})).To(ConsistOf(pod))
+		failures, backoff = unschedulablePods.Failed(pod)
+		Expect(failures).To(Equal(2))
+		Expect(backoff).To(Equal(20 * time.Second))
+	})
+	It("should cap the backoff and count the pod as unsatisfiable", func() {
+		var backoff time.Duration
+		for i := 0; i < 10; i++ {
+			_, backoff = unschedulablePods.Failed(pod)
+		}
+		Expect(backoff).To(Equal(5 * time.Minute))
+		Expect(unschedulablePods.Unsatisfiable()).To(Equal(1))
+	})
+	It("should retry pods immediately once they are updated", func() {
+		unschedulablePods.Failed(pod)
+		updated := pod.DeepCopy()
+		updated.ResourceVersion = "2"
+		Expect(unschedulablePods.Filter([]*v1.Pod{updated})).To(ConsistOf(updated))
+		failures, _ := unschedulablePods.Failed(updated)
+		Expect(failures).To(Equal(1))
+	})
+	It("should forget pods once they schedule or are no longer pending", func() {
+		unschedulablePods.Failed(pod)
+		unschedulablePods.Scheduled(pod)
+		Expect(unschedulablePods.Filter([]*v1.Pod{pod})).To(ConsistOf(pod))
+
+		unschedulablePods.Failed(pod)
+		Expect(unschedulablePods.Filter(nil)).To(BeEmpty())
+		Expect(unschedulablePods.Unsatisfiable()).To(Equal(0))
+		Expect(unschedulablePods.Filter([]*v1.Pod{pod})).To(ConsistOf(pod))
 	})
 })
 
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	appsv1 "k8s.io/api/apps/v1"
@@ -34,17 +36,31 @@ import (
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/cloudprovider"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 	podutils "github.com/aws/karpenter-core/pkg/utils/pod"
 	provisionerutil "github.com/aws/karpenter-core/pkg/utils/provisioner"
 	"github.com/aws/karpenter-core/pkg/utils/sets"
 )
 
+// LaunchFailureTTL is how long a launch failure of a NodePool is considered for scheduling
+const LaunchFailureTTL = 3 * time.Minute
+
+// LaunchFailure is the last failure to launch capacity for a NodePool
+type LaunchFailure struct {
+	Err error
+	// Demoted is true if the failure couldn't be attributed to specific offerings, so the NodePool is deprioritized
+	// for scheduling instead
+	Demoted  bool
+	FailedAt time.Time
+}
+
 // Cluster maintains cluster state that is often needed but expensive to compute.
 type Cluster struct {
 	kubeClient    client.Client
@@ -52,11 +68,13 @@ type Cluster struct {
 	clock         clock.Clock
 
 	mu                       sync.RWMutex
-	nodes                    map[string]*StateNode           // provider id -> cached node
-	bindings                 map[types.NamespacedName]string // pod namespaced named -> node name
-	nodeNameToProviderID     map[string]string               // node name -> provider id
-	nodeClaimKeyToProviderID map[nodeclaimutil.Key]string    // node claim key -> provider id
-	daemonSetPods            sync.Map                        // daemonSet -> existing pod
+	nodes                    map[string]*StateNode                  // provider id -> cached node
+	bindings                 map[types.NamespacedName]string        // pod namespaced named -> node name
+	nodeNameToProviderID     map[string]string                      // node name -> provider id
+	nodeClaimKeyToProviderID map[nodeclaimutil.Key]string           // node claim key -> provider id
+	podNominations           map[types.NamespacedName]podNomination // pod namespaced name -> node claim it was launched for
+	deprovisioningCommands   map[types.UID][]string                 // deprovisioning command id -> provider ids of its candidates
+	daemonSetPods            sync.Map                               // daemonSet -> existing pod
 
 	clusterStateMu sync.RWMutex // Separate mutex as this is called in some places that mu is held
 	// A monotonically increasing timestamp representing the time state of the
@@ -66,10 +84,16 @@ type Cluster struct {
 	// optimize and not try to deprovision if nothing about the cluster has changed.
 	clusterState     time.Time
 	antiAffinityPods sync.Map // pod namespaced name -> *v1.Pod of pods that have required anti affinities
+	launchFailures   sync.Map // nodepool key -> *LaunchFailure of the last launch that failed due to insufficient capacity
+
+	emptyNodeWatchersMu sync.RWMutex
+	emptyNodeWatchers   []chan event.GenericEvent // channels notified when the last non-daemonset pod leaves a node
+
+	lastSynced atomic.Int64 // unix nanoseconds of the last time Synced() found cluster state to be synced
 }
 
 func NewCluster(clk clock.Clock, client client.Client, cp cloudprovider.CloudProvider) *Cluster {
-	return &Cluster{
+	c := &Cluster{
 		clock:                    clk,
 		kubeClient:               client,
 		cloudProvider:            cp,
@@ -78,7 +102,17 @@ func NewCluster(clk clock.Clock, client client.Client, cp cloudprovider.CloudPro
 		daemonSetPods:            sync.Map{},
 		nodeNameToProviderID:     map[string]string{},
 		nodeClaimKeyToProviderID: map[nodeclaimutil.Key]string{},
+		podNominations:           map[types.NamespacedName]podNomination{},
+		deprovisioningCommands:   map[types.UID][]string{},
 	}
+	c.lastSynced.Store(clk.Now().UnixNano())
+	return c
+}
+
+// podNomination records the node claim that a provisioning pass launched for a pod
+type podNomination struct {
+	nodeClaimKey nodeclaimutil.Key
+	until        time.Time
 }
 
 // Synced validates that the Machines and the Nodes that are stored in the apiserver
@@ -90,11 +124,13 @@ func (c *Cluster) Synced(ctx context.Context) bool {
 	machineList := &v1alpha5.MachineList{}
 	if err := c.kubeClient.List(ctx, machineList); err != nil {
 		logging.FromContext(ctx).Errorf("checking cluster state sync, %v", err)
+		c.recordSynced(false)
 		return false
 	}
 	nodeList := &v1.NodeList{}
 	if err := c.kubeClient.List(ctx, nodeList); err != nil {
 		logging.FromContext(ctx).Errorf("checking cluster state sync, %v", err)
+		c.recordSynced(false)
 		return false
 	}
 	c.mu.RLock()
@@ -110,23 +146,38 @@ func (c *Cluster) Synced(ctx context.Context) bool {
 	stateNodeNames := sets.New(lo.Keys(c.nodeNameToProviderID)...)
 	c.mu.RUnlock()
 
+	// If a machine hasn't resolved its provider id, then it hasn't resolved its status
+	resolved := true
 	machineNames := sets.New[string]()
 	for _, machine := range machineList.Items {
-		// If the machine hasn't resolved its provider id, then it hasn't resolved its status
-		if machine.Status.ProviderID == "" {
-			return false
-		}
+		resolved = resolved && machine.Status.ProviderID != ""
 		machineNames.Insert(machine.Name)
 	}
 	nodeNames := sets.New[string]()
 	for _, node := range nodeList.Items {
 		nodeNames.Insert(node.Name)
 	}
+	clusterStateMachineCount.With(prometheus.Labels{sourceLabel: sourceState}).Set(float64(len(stateMachineNames)))
+	clusterStateMachineCount.With(prometheus.Labels{sourceLabel: sourceInformer}).Set(float64(len(machineNames)))
+	clusterStateNodeCount.With(prometheus.Labels{sourceLabel: sourceState}).Set(float64(len(stateNodeNames)))
+	clusterStateNodeCount.With(prometheus.Labels{sourceLabel: sourceInformer}).Set(float64(len(nodeNames)))
+
 	// The names tracked in-memory should at least have all the data that is in the api-server
 	// This doesn't ensure that the two states are exactly aligned (we could still not be tracking a node
 	// that exists in the cluster state but not in the apiserver) but it ensures that we have a state
 	// representation for every node/machine that exists on the apiserver
-	return stateMachineNames.IsSuperset(machineNames) && stateNodeNames.IsSuperset(nodeNames)
+	synced := resolved && stateMachineNames.IsSuperset(machineNames) && stateNodeNames.IsSuperset(nodeNames)
+	c.recordSynced(synced)
+	return synced
+}
+
+// recordSynced publishes the result of a sync check along with the time since cluster state was last synced
+func (c *Cluster) recordSynced(synced bool) {
+	if synced {
+		c.lastSynced.Store(c.clock.Now().UnixNano())
+	}
+	clusterStateSynced.Set(lo.Ternary(synced, 1.0, 0.0))
+	clusterStateSecondsSinceLastSync.Set(c.clock.Since(time.Unix(0, c.lastSynced.Load())).Seconds())
 }
 
 // ForPodsWithAntiAffinity calls the supplied function once for each pod with required anti affinity terms that is
@@ -196,6 +247,32 @@ func (c *Cluster) NominateNodeForPod(ctx context.Context, providerID string) {
 	}
 }
 
+// NominatePodsForNodeClaim records that the node claim was launched for the pods. The pods aren't provisioned for again
+// until the node claim launches, the nomination expires, or the node claim is deleted.
+func (c *Cluster) NominatePodsForNodeClaim(ctx context.Context, key nodeclaimutil.Key, pods ...*v1.Pod) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	until := c.clock.Now().Add(nominationWindow(ctx))
+	for _, pod := range pods {
+		c.podNominations[client.ObjectKeyFromObject(pod)] = podNomination{nodeClaimKey: key, until: until}
+	}
+}
+
+// IsPodNominatedToLaunchingNodeClaim returns true if the pod was nominated to a node claim that hasn't launched yet.
+// Once the node claim launches, the pod is scheduled against it like against any other in-flight node.
+func (c *Cluster) IsPodNominatedToLaunchingNodeClaim(podKey types.NamespacedName) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nomination, ok := c.podNominations[podKey]
+	if !ok || !c.clock.Now().Before(nomination.until) {
+		return false
+	}
+	_, launched := c.nodeClaimKeyToProviderID[nomination.nodeClaimKey]
+	return !launched
+}
+
 // UnmarkForDeletion removes the marking on the node as a node the controller intends to delete
 func (c *Cluster) UnmarkForDeletion(providerIDs ...string) {
 	c.mu.Lock()
@@ -220,6 +297,64 @@ func (c *Cluster) MarkForDeletion(providerIDs ...string) {
 	}
 }
 
+// StartDeprovisioningCommand tracks a deprovisioning command that is executing and marks its candidates as
+// disruption targets until the command finishes
+func (c *Cluster) StartDeprovisioningCommand(id types.UID, providerIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deprovisioningCommands[id] = providerIDs
+	for _, providerID := range providerIDs {
+		if n, ok := c.nodes[providerID]; ok {
+			n.disruptionTarget = true
+		}
+	}
+}
+
+// FinishDeprovisioningCommand stops tracking the deprovisioning command and removes the marking on its candidates as
+// disruption targets
+func (c *Cluster) FinishDeprovisioningCommand(id types.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, providerID := range c.deprovisioningCommands[id] {
+		if n, ok := c.nodes[providerID]; ok {
+			n.disruptionTarget = false
+		}
+	}
+	delete(c.deprovisioningCommands, id)
+}
+
+// DeprovisioningCommands returns the number of deprovisioning commands that are executing
+func (c *Cluster) DeprovisioningCommands() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.deprovisioningCommands)
+}
+
+// DisruptionCounts returns the number of managed nodes owned by each NodePool along with the number of those nodes
+// that are currently being disrupted, either because they are targets of an executing deprovisioning command, are
+// marked for deletion or are actively deleting
+func (c *Cluster) DisruptionCounts() (nodes map[nodepoolutil.Key]int, disrupting map[nodepoolutil.Key]int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nodes = map[nodepoolutil.Key]int{}
+	disrupting = map[nodepoolutil.Key]int{}
+	for _, n := range c.nodes {
+		if !n.Managed() {
+			continue
+		}
+		key := n.OwnerKey()
+		nodes[key]++
+		if n.MarkedForDeletion() || n.DisruptionTarget() {
+			disrupting[key]++
+		}
+	}
+	return nodes, disrupting
+}
+
 func (c *Cluster) UpdateNodeClaim(nodeClaim *v1beta1.NodeClaim) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -237,6 +372,7 @@ func (c *Cluster) DeleteNodeClaim(key nodeclaimutil.Key) {
 	defer c.mu.Unlock()
 
 	c.cleanupNodeClaim(key)
+	c.releasePodNominations(key)
 }
 
 func (c *Cluster) UpdateNode(ctx context.Context, node *v1.Node) error {
@@ -270,6 +406,9 @@ func (c *Cluster) UpdatePod(ctx context.Context, pod *v1.Pod) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if pod.Spec.NodeName != "" || podutils.IsTerminal(pod) {
+		delete(c.podNominations, client.ObjectKeyFromObject(pod))
+	}
 	var err error
 	if podutils.IsTerminal(pod) {
 		c.updateNodeUsageFromPodCompletion(client.ObjectKeyFromObject(pod))
@@ -285,10 +424,39 @@ func (c *Cluster) DeletePod(podKey types.NamespacedName) {
 	defer c.mu.Unlock()
 
 	c.antiAffinityPods.Delete(podKey)
+	delete(c.podNominations, podKey)
 	c.updateNodeUsageFromPodCompletion(podKey)
 	c.MarkUnconsolidated()
 }
 
+// WatchEmptyNodes returns a channel that receives an event for a node whenever the last non-daemonset pod that is
+// bound to it completes or is deleted. This allows controllers to react to emptiness without polling.
+func (c *Cluster) WatchEmptyNodes() <-chan event.GenericEvent {
+	c.emptyNodeWatchersMu.Lock()
+	defer c.emptyNodeWatchersMu.Unlock()
+
+	ch := make(chan event.GenericEvent, 1000)
+	c.emptyNodeWatchers = append(c.emptyNodeWatchers, ch)
+	return ch
+}
+
+// notifyEmptyNode sends an event for the node to all empty node watchers. Sends are non-blocking since
+// watchers also reconcile emptiness through their own watches, so a dropped event only delays detection.
+func (c *Cluster) notifyEmptyNode(n *StateNode) {
+	if n.Node == nil {
+		return
+	}
+	c.emptyNodeWatchersMu.RLock()
+	defer c.emptyNodeWatchersMu.RUnlock()
+
+	for _, ch := range c.emptyNodeWatchers {
+		select {
+		case ch <- event.GenericEvent{Object: n.Node}:
+		default:
+		}
+	}
+}
+
 // MarkUnconsolidated marks the cluster state as being unconsolidated.  This should be called in any situation where
 // something in the cluster has changed such that the cluster may have moved from a non-consolidatable to a consolidatable
 // state.
@@ -320,6 +488,27 @@ func (c *Cluster) ConsolidationState() time.Time {
 	return c.MarkUnconsolidated()
 }
 
+// MarkLaunchFailed records that launching capacity for the NodePool failed due to insufficient capacity. If demote is
+// true, the scheduler falls back to lower weight NodePools for pods that the NodePool could otherwise schedule until
+// the failure expires.
+func (c *Cluster) MarkLaunchFailed(key nodepoolutil.Key, err error, demote bool) {
+	c.launchFailures.Store(key, &LaunchFailure{Err: err, Demoted: demote, FailedAt: c.clock.Now()})
+}
+
+// LaunchFailure returns the last failure to launch capacity for the NodePool within the last LaunchFailureTTL, or nil
+// if there wasn't one
+func (c *Cluster) LaunchFailure(key nodepoolutil.Key) *LaunchFailure {
+	failure, ok := c.launchFailures.Load(key)
+	if !ok {
+		return nil
+	}
+	if c.clock.Since(failure.(*LaunchFailure).FailedAt) >= LaunchFailureTTL {
+		c.launchFailures.Delete(key)
+		return nil
+	}
+	return failure.(*LaunchFailure)
+}
+
 // Reset the cluster state for unit testing
 func (c *Cluster) Reset() {
 	c.mu.Lock()
@@ -327,9 +516,12 @@ func (c *Cluster) Reset() {
 	c.nodes = map[string]*StateNode{}
 	c.nodeNameToProviderID = map[string]string{}
 	c.nodeClaimKeyToProviderID = map[nodeclaimutil.Key]string{}
+	c.podNominations = map[types.NamespacedName]podNomination{}
+	c.deprovisioningCommands = map[types.UID][]string{}
 	c.bindings = map[types.NamespacedName]string{}
 	c.antiAffinityPods = sync.Map{}
 	c.daemonSetPods = sync.Map{}
+	c.launchFailures = sync.Map{}
 }
 
 func (c *Cluster) GetDaemonSetPod(daemonset *appsv1.DaemonSet) *v1.Pod {
@@ -389,7 +581,9 @@ func (c *Cluster) newStateFromNodeClaim(nodeClaim *v1beta1.NodeClaim, oldNode *S
 		hostPortUsage:            oldNode.hostPortUsage,
 		volumeUsage:              oldNode.volumeUsage,
 		markedForDeletion:        oldNode.markedForDeletion,
+		disruptionTarget:         oldNode.disruptionTarget,
 		nominatedUntil:           oldNode.nominatedUntil,
+		lastPodEventTime:         oldNode.lastPodEventTime,
 	}
 	// Cleanup the old nodeClaim with its old providerID if its providerID changes
 	// This can happen since nodes don't get created with providerIDs. Rather, CCM picks up the
@@ -413,6 +607,16 @@ func (c *Cluster) cleanupNodeClaim(key nodeclaimutil.Key) {
 	}
 }
 
+// releasePodNominations releases the pods that were nominated to a node claim, e.g. because it failed to launch, so
+// that they're provisioned for again in the next provisioning pass
+func (c *Cluster) releasePodNominations(key nodeclaimutil.Key) {
+	for podKey, nomination := range c.podNominations {
+		if nomination.nodeClaimKey == key {
+			delete(c.podNominations, podKey)
+		}
+	}
+}
+
 func (c *Cluster) newStateFromNode(ctx context.Context, node *v1.Node, oldNode *StateNode) (*StateNode, error) {
 	if oldNode == nil {
 		oldNode = NewNode()
@@ -432,7 +636,9 @@ func (c *Cluster) newStateFromNode(ctx context.Context, node *v1.Node, oldNode *
 		hostPortUsage:            scheduling.NewHostPortUsage(),
 		volumeUsage:              scheduling.NewVolumeUsage(),
 		markedForDeletion:        oldNode.markedForDeletion,
+		disruptionTarget:         oldNode.disruptionTarget,
 		nominatedUntil:           oldNode.nominatedUntil,
+		lastPodEventTime:         oldNode.lastPodEventTime,
 	}
 	if err := multierr.Combine(
 		c.populateStartupTaints(ctx, n),
@@ -508,7 +714,7 @@ func (c *Cluster) populateInflight(ctx context.Context, n *StateNode) error {
 	}
 	n.inflightInitialized = true
 	n.inflightCapacity = instanceType.Capacity
-	n.inflightAllocatable = instanceType.Allocatable()
+	n.inflightAllocatable = instanceType.AllocatableWithKubelet(owner.Spec.Template.Spec.KubeletConfiguration)
 	return nil
 }
 
@@ -539,6 +745,9 @@ func (c *Cluster) populateResourceRequests(ctx context.Context, n *StateNode) er
 		if err := n.updateForPod(ctx, c.kubeClient, pod); err != nil {
 			return err
 		}
+		if c.bindings[client.ObjectKeyFromObject(pod)] != pod.Spec.NodeName {
+			n.lastPodEventTime = metav1.Time{Time: c.clock.Now()}
+		}
 		c.cleanupOldBindings(pod)
 		c.bindings[client.ObjectKeyFromObject(pod)] = pod.Spec.NodeName
 	}
@@ -561,6 +770,9 @@ func (c *Cluster) updateNodeUsageFromPod(ctx context.Context, pod *v1.Pod) error
 	if err := n.updateForPod(ctx, c.kubeClient, pod); err != nil {
 		return err
 	}
+	if c.bindings[client.ObjectKeyFromObject(pod)] != pod.Spec.NodeName {
+		n.lastPodEventTime = metav1.Time{Time: c.clock.Now()}
+	}
 	c.cleanupOldBindings(pod)
 	c.bindings[client.ObjectKeyFromObject(pod)] = pod.Spec.NodeName
 	return nil
@@ -579,7 +791,14 @@ func (c *Cluster) updateNodeUsageFromPodCompletion(podKey types.NamespacedName)
 		// we weren't tracking the node yet, so nothing to do
 		return
 	}
+	wasEmpty := n.empty()
 	n.cleanupForPod(podKey)
+	n.lastPodEventTime = metav1.Time{Time: c.clock.Now()}
+	if !wasEmpty && n.empty() {
+		c.notifyEmptyNode(n)
+	}
+	// Capacity was freed on the node, which may allow it to be consolidated
+	c.MarkUnconsolidated()
 }
 
 func (c *Cluster) cleanupOldBindings(pod *v1.Pod) {
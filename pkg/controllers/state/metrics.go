@@ -0,0 +1,73 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+const (
+	stateSubsystem = "cluster_state"
+	sourceLabel    = "source"
+
+	// sourceState is the count of objects tracked in-memory by the cluster state
+	sourceState = "state"
+	// sourceInformer is the count of objects listed from the informer cache
+	sourceInformer = "informer"
+)
+
+var (
+	clusterStateSynced = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: stateSubsystem,
+			Name:      "synced",
+			Help:      "Returns 1 if cluster state is synced and 0 otherwise. Deprovisioning doesn't act while cluster state is unsynced.",
+		},
+	)
+	clusterStateSecondsSinceLastSync = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: stateSubsystem,
+			Name:      "seconds_since_last_sync",
+			Help:      "The time in seconds since cluster state was last found to be synced, or since startup if it has never synced.",
+		},
+	)
+	clusterStateNodeCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: stateSubsystem,
+			Name:      "node_count",
+			Help:      "Number of nodes as of the last cluster state sync check. Labeled by whether the nodes are tracked by cluster state or listed from the informer cache.",
+		},
+		[]string{sourceLabel},
+	)
+	clusterStateMachineCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: stateSubsystem,
+			Name:      "machine_count",
+			Help:      "Number of machines as of the last cluster state sync check. Labeled by whether the machines are tracked by cluster state or listed from the informer cache.",
+		},
+		[]string{sourceLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(clusterStateSynced, clusterStateSecondsSinceLastSync, clusterStateNodeCount, clusterStateMachineCount)
+}
@@ -96,7 +96,11 @@ type StateNode struct {
 	volumeUsage   *scheduling.VolumeUsage
 
 	markedForDeletion bool
-	nominatedUntil    metav1.Time
+	// disruptionTarget is set while the node is a candidate of a deprovisioning command that is executing
+	disruptionTarget bool
+	nominatedUntil   metav1.Time
+	// lastPodEventTime is the last time a pod was bound to or removed from the node
+	lastPodEventTime metav1.Time
 }
 
 func NewNode() *StateNode {
@@ -348,6 +352,12 @@ func (in *StateNode) MarkedForDeletion() bool {
 		(in.Node != nil && in.NodeClaim == nil && !in.Node.DeletionTimestamp.IsZero())
 }
 
+// DisruptionTarget returns true if the node is a candidate of a deprovisioning command that is executing, so
+// scheduling simulations shouldn't consider it as a destination for pods
+func (in *StateNode) DisruptionTarget() bool {
+	return in.disruptionTarget
+}
+
 func (in *StateNode) Nominate(ctx context.Context) {
 	in.nominatedUntil = metav1.Time{Time: time.Now().Add(nominationWindow(ctx))}
 }
@@ -356,6 +366,12 @@ func (in *StateNode) Nominated() bool {
 	return in.nominatedUntil.After(time.Now())
 }
 
+// LastPodEventTime returns the last time a pod was bound to or removed from the node, or the zero time if
+// no pod events have been observed
+func (in *StateNode) LastPodEventTime() time.Time {
+	return in.lastPodEventTime.Time
+}
+
 func (in *StateNode) Managed() bool {
 	return in.NodeClaim != nil ||
 		(in.Node != nil && in.Node.Labels[v1alpha5.ProvisionerNameLabelKey] != "") ||
@@ -381,6 +397,11 @@ func (in *StateNode) updateForPod(ctx context.Context, kubeClient client.Client,
 	return nil
 }
 
+// empty returns whether there are no non-daemonset pods bound to the node
+func (in *StateNode) empty() bool {
+	return len(in.podRequests) == len(in.daemonSetRequests)
+}
+
 func (in *StateNode) cleanupForPod(podKey types.NamespacedName) {
 	in.hostPortUsage.DeletePod(podKey)
 	in.volumeUsage.DeletePod(podKey)
@@ -391,6 +412,9 @@ func (in *StateNode) cleanupForPod(podKey types.NamespacedName) {
 }
 
 func nominationWindow(ctx context.Context) time.Duration {
+	if ttl := settings.FromContext(ctx).NominationTTL; ttl > 0 {
+		return ttl
+	}
 	nominationPeriod := 2 * settings.FromContext(ctx).BatchMaxDuration
 	if nominationPeriod < 10*time.Second {
 		nominationPeriod = 10 * time.Second
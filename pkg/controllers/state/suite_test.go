@@ -22,8 +22,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/samber/lo"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	cloudproviderapi "k8s.io/cloud-provider/api"
 	clock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/ptr"
@@ -35,6 +37,8 @@ import (
 	"github.com/aws/karpenter-core/pkg/operator/controller"
 	"github.com/aws/karpenter-core/pkg/operator/scheme"
 	"github.com/aws/karpenter-core/pkg/scheduling"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 
@@ -42,6 +46,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/aws/karpenter-core/pkg/controllers/state"
 	"github.com/aws/karpenter-core/pkg/test"
@@ -1017,6 +1022,75 @@ var _ = Describe("Inflight Nodes", func() {
 		ExpectStateNodeCount("==", 1)
 		Expect(ExpectStateNodeExists(node).MarkedForDeletion()).To(BeTrue())
 	})
+	It("should continue tracking disruption targets when an inflight node becomes a real node", func() {
+		machine := test.Machine(v1alpha5.Machine{
+			Status: v1alpha5.MachineStatus{
+				ProviderID: test.RandomProviderID(),
+			},
+		})
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectStateNodeCount("==", 1)
+		id := uuid.NewUUID()
+		cluster.StartDeprovisioningCommand(id, machine.Status.ProviderID)
+		Expect(ExpectStateNodeExistsForMachine(machine).DisruptionTarget()).To(BeTrue())
+
+		node := test.Node(test.NodeOptions{
+			ProviderID: machine.Status.ProviderID,
+		})
+		node.Spec.ProviderID = machine.Status.ProviderID
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		ExpectStateNodeCount("==", 1)
+		Expect(ExpectStateNodeExists(node).DisruptionTarget()).To(BeTrue())
+
+		cluster.FinishDeprovisioningCommand(id)
+		Expect(ExpectStateNodeExists(node).DisruptionTarget()).To(BeFalse())
+	})
+	It("should count disruption targets as disrupting without marking them for deletion", func() {
+		machine, node := test.MachineAndNode(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+			}},
+			Status: v1alpha5.MachineStatus{
+				ProviderID: test.RandomProviderID(),
+			},
+		})
+		ExpectApplied(ctx, env.Client, machine, node)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		cluster.StartDeprovisioningCommand(uuid.NewUUID(), machine.Status.ProviderID)
+
+		// Provisioning still schedules the node's pods on the node until the command marks it for deletion
+		Expect(ExpectStateNodeExists(node).MarkedForDeletion()).To(BeFalse())
+		nodes, disrupting := cluster.DisruptionCounts()
+		key := nodepoolutil.Key{Name: provisioner.Name, IsProvisioner: true}
+		Expect(nodes[key]).To(Equal(1))
+		Expect(disrupting[key]).To(Equal(1))
+	})
+	It("should track each deprovisioning command until it finishes", func() {
+		machines := lo.Times(3, func(_ int) *v1alpha5.Machine {
+			return test.Machine(v1alpha5.Machine{Status: v1alpha5.MachineStatus{ProviderID: test.RandomProviderID()}})
+		})
+		for _, m := range machines {
+			ExpectApplied(ctx, env.Client, m)
+			ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(m))
+		}
+		first, second := uuid.NewUUID(), uuid.NewUUID()
+		cluster.StartDeprovisioningCommand(first, machines[0].Status.ProviderID, machines[1].Status.ProviderID)
+		cluster.StartDeprovisioningCommand(second, machines[2].Status.ProviderID)
+		Expect(cluster.DeprovisioningCommands()).To(Equal(2))
+
+		// Finishing a command only releases its own candidates
+		cluster.FinishDeprovisioningCommand(first)
+		Expect(cluster.DeprovisioningCommands()).To(Equal(1))
+		Expect(ExpectStateNodeExistsForMachine(machines[0]).DisruptionTarget()).To(BeFalse())
+		Expect(ExpectStateNodeExistsForMachine(machines[1]).DisruptionTarget()).To(BeFalse())
+		Expect(ExpectStateNodeExistsForMachine(machines[2]).DisruptionTarget()).To(BeTrue())
+
+		cluster.FinishDeprovisioningCommand(second)
+		Expect(cluster.DeprovisioningCommands()).To(Equal(0))
+	})
 })
 
 var _ = Describe("Node Deletion", func() {
@@ -1194,6 +1268,96 @@ var _ = Describe("Node Resource Level", func() {
 		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod1))
 		ExpectResources(v1.ResourceList{v1.ResourceCPU: resource.MustParse("0")}, ExpectStateNodeExists(node).PodRequests())
 	})
+	It("should track the last time a pod was bound to or removed from a node", func() {
+		pod := test.UnschedulablePod()
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				v1.LabelInstanceTypeStable:       cloudProvider.InstanceTypes[0].Name,
+			}},
+			ProviderID: test.RandomProviderID(),
+		})
+		ExpectApplied(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		Expect(ExpectStateNodeExists(node).LastPodEventTime().IsZero()).To(BeTrue())
+
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod))
+		Expect(ExpectStateNodeExists(node).LastPodEventTime()).To(Equal(fakeClock.Now()))
+
+		// reconciling an already tracked pod binding isn't a new pod event
+		fakeClock.Step(time.Minute)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod))
+		Expect(ExpectStateNodeExists(node).LastPodEventTime()).To(Equal(fakeClock.Now().Add(-time.Minute)))
+
+		ExpectDeleted(ctx, env.Client, pod)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod))
+		Expect(ExpectStateNodeExists(node).LastPodEventTime()).To(Equal(fakeClock.Now()))
+	})
+	It("should notify empty node watchers when the last pod is deleted", func() {
+		emptyNodes := cluster.WatchEmptyNodes()
+		pod1 := test.UnschedulablePod()
+		pod2 := test.UnschedulablePod()
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				v1.LabelInstanceTypeStable:       cloudProvider.InstanceTypes[0].Name,
+			}},
+			ProviderID: test.RandomProviderID(),
+		})
+		ExpectApplied(ctx, env.Client, pod1, pod2, node)
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod1, node)
+		ExpectManualBinding(ctx, env.Client, pod2, node)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod1))
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod2))
+
+		ExpectDeleted(ctx, env.Client, pod1)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod1))
+		Consistently(emptyNodes).ShouldNot(Receive())
+
+		ExpectDeleted(ctx, env.Client, pod2)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod2))
+		var e event.GenericEvent
+		Eventually(emptyNodes).Should(Receive(&e))
+		Expect(e.Object.GetName()).To(Equal(node.Name))
+	})
+	It("should release requests and notify empty node watchers when the last pod completes", func() {
+		emptyNodes := cluster.WatchEmptyNodes()
+		pod := test.UnschedulablePod(test.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU: resource.MustParse("1"),
+				}},
+		})
+		node := test.Node(test.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				v1.LabelInstanceTypeStable:       cloudProvider.InstanceTypes[0].Name,
+			}},
+			Allocatable: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU: resource.MustParse("4"),
+			},
+			ProviderID: test.RandomProviderID(),
+		})
+		ExpectApplied(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod))
+		ExpectResources(v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}, ExpectStateNodeExists(node).PodRequests())
+
+		state := cluster.ConsolidationState()
+		fakeClock.Step(time.Second)
+		pod.Status.Phase = v1.PodSucceeded
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod))
+
+		ExpectResources(v1.ResourceList{v1.ResourceCPU: resource.MustParse("0")}, ExpectStateNodeExists(node).PodRequests())
+		Expect(cluster.ConsolidationState()).ToNot(Equal(state))
+		var e event.GenericEvent
+		Eventually(emptyNodes).Should(Receive(&e))
+		Expect(e.Object.GetName()).To(Equal(node.Name))
+	})
 	It("should not add requests if the pod is terminal", func() {
 		pod1 := test.UnschedulablePod(test.PodOptions{
 			ResourceRequirements: v1.ResourceRequirements{
@@ -1620,6 +1784,57 @@ var _ = Describe("Node Resource Level", func() {
 	})
 })
 
+var _ = Describe("Pod Nominations", func() {
+	var machine *v1alpha5.Machine
+	var pod *v1.Pod
+	BeforeEach(func() {
+		machine = test.Machine(v1alpha5.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha5.ProvisionerNameLabelKey: provisioner.Name,
+				},
+			},
+		})
+		machine.Status.ProviderID = ""
+		pod = test.UnschedulablePod()
+		ExpectApplied(ctx, env.Client, machine, pod)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+	})
+	It("should nominate pods to a machine until it launches", func() {
+		cluster.NominatePodsForNodeClaim(ctx, nodeclaimutil.Key{Name: machine.Name, IsMachine: true}, pod)
+		Expect(cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))).To(BeTrue())
+
+		machine.Status.ProviderID = test.RandomProviderID()
+		ExpectApplied(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))).To(BeFalse())
+	})
+	It("should release pods when the machine they're nominated to is deleted", func() {
+		cluster.NominatePodsForNodeClaim(ctx, nodeclaimutil.Key{Name: machine.Name, IsMachine: true}, pod)
+		ExpectDeleted(ctx, env.Client, machine)
+		ExpectReconcileSucceeded(ctx, machineController, client.ObjectKeyFromObject(machine))
+		Expect(cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))).To(BeFalse())
+	})
+	It("should release pods once the nomination TTL passes", func() {
+		ctx = settings.ToContext(ctx, test.Settings(settings.Settings{NominationTTL: time.Minute}))
+		cluster.NominatePodsForNodeClaim(ctx, nodeclaimutil.Key{Name: machine.Name, IsMachine: true}, pod)
+		fakeClock.Step(30 * time.Second)
+		Expect(cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))).To(BeTrue())
+		fakeClock.Step(30 * time.Second)
+		Expect(cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))).To(BeFalse())
+	})
+	It("should release pods once they bind", func() {
+		node := test.Node(test.NodeOptions{ProviderID: test.RandomProviderID()})
+		ExpectApplied(ctx, env.Client, node)
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+
+		cluster.NominatePodsForNodeClaim(ctx, nodeclaimutil.Key{Name: machine.Name, IsMachine: true}, pod)
+		ExpectManualBinding(ctx, env.Client, pod, node)
+		ExpectReconcileSucceeded(ctx, podController, client.ObjectKeyFromObject(pod))
+		Expect(cluster.IsPodNominatedToLaunchingNodeClaim(client.ObjectKeyFromObject(pod))).To(BeFalse())
+	})
+})
+
 var _ = Describe("Pod Anti-Affinity", func() {
 	It("should track pods with required anti-affinity", func() {
 		pod := test.UnschedulablePod(test.PodOptions{
@@ -1968,6 +2183,44 @@ var _ = Describe("Cluster State Sync", func() {
 		}
 		Expect(cluster.Synced(ctx)).To(BeFalse())
 	})
+	It("should publish the sync status and the tracked counts of nodes", func() {
+		nodes := lo.Times(10, func(_ int) *v1.Node {
+			return test.Node(test.NodeOptions{
+				ProviderID: test.RandomProviderID(),
+			})
+		})
+		for i, node := range nodes {
+			ExpectApplied(ctx, env.Client, node)
+			// One of them doesn't get synced with the reconciliation
+			if i != 9 {
+				ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(node))
+			}
+		}
+		fakeClock.Step(time.Minute)
+		Expect(cluster.Synced(ctx)).To(BeFalse())
+
+		m, found := FindMetricWithLabelValues("karpenter_cluster_state_synced", map[string]string{})
+		Expect(found).To(BeTrue())
+		Expect(m.GetGauge().GetValue()).To(BeNumerically("==", 0))
+		m, found = FindMetricWithLabelValues("karpenter_cluster_state_node_count", map[string]string{"source": "state"})
+		Expect(found).To(BeTrue())
+		Expect(m.GetGauge().GetValue()).To(BeNumerically("==", 9))
+		m, found = FindMetricWithLabelValues("karpenter_cluster_state_node_count", map[string]string{"source": "informer"})
+		Expect(found).To(BeTrue())
+		Expect(m.GetGauge().GetValue()).To(BeNumerically("==", 10))
+		m, found = FindMetricWithLabelValues("karpenter_cluster_state_seconds_since_last_sync", map[string]string{})
+		Expect(found).To(BeTrue())
+		Expect(m.GetGauge().GetValue()).To(BeNumerically(">=", time.Minute.Seconds()))
+
+		ExpectReconcileSucceeded(ctx, nodeController, client.ObjectKeyFromObject(nodes[9]))
+		Expect(cluster.Synced(ctx)).To(BeTrue())
+		m, found = FindMetricWithLabelValues("karpenter_cluster_state_synced", map[string]string{})
+		Expect(found).To(BeTrue())
+		Expect(m.GetGauge().GetValue()).To(BeNumerically("==", 1))
+		m, found = FindMetricWithLabelValues("karpenter_cluster_state_seconds_since_last_sync", map[string]string{})
+		Expect(found).To(BeTrue())
+		Expect(m.GetGauge().GetValue()).To(BeNumerically("==", 0))
+	})
 })
 
 var _ = Describe("DaemonSet Controller", func() {
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
 	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -82,7 +83,35 @@ func (c *Controller) Finalize(ctx context.Context, node *v1.Node) (reconcile.Res
 	if err := c.terminator.Cordon(ctx, node); err != nil {
 		return reconcile.Result{}, fmt.Errorf("cordoning node, %w", err)
 	}
-	if err := c.terminator.Drain(ctx, node); err != nil {
+	if err := c.preDrain(ctx, node); err != nil {
+		return reconcile.Result{}, err
+	}
+	drainTimeoutExceeded, err := c.terminator.DrainTimeoutExceeded(ctx, node)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("determining drain timeout, %w", err)
+	}
+	if drainTimeoutExceeded {
+		fallback, err := c.terminator.NoExecuteFallback(ctx, node)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("applying NoExecute fallback, %w", err)
+		}
+		if !fallback {
+			if err := c.forceTerminate(ctx, node); err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{}, c.removeFinalizer(ctx, node)
+		}
+	}
+	forceDrain, err := c.terminator.ShouldForceDrain(ctx, node)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("determining forced drain, %w", err)
+	}
+	drain := c.terminator.Drain
+	if forceDrain {
+		c.recorder.Publish(terminatorevents.NodeForceDrain(node))
+		drain = c.terminator.ForceDrain
+	}
+	if err := drain(ctx, node); err != nil {
 		if !terminator.IsNodeDrainError(err) {
 			return reconcile.Result{}, fmt.Errorf("draining node, %w", err)
 		}
@@ -97,12 +126,59 @@ func (c *Controller) Finalize(ctx context.Context, node *v1.Node) (reconcile.Res
 		return reconcile.Result{RequeueAfter: 1 * time.Second}, nil
 	}
 
+	if err := c.terminator.TaintOutOfService(ctx, node); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := c.terminator.InvokeHooks(ctx, terminator.PreTermination, node); err != nil {
+		return reconcile.Result{}, err
+	}
 	if err := c.cloudProvider.Delete(ctx, machineutil.NewFromNode(node)); cloudprovider.IgnoreMachineNotFoundError(err) != nil {
 		return reconcile.Result{}, fmt.Errorf("terminating cloudprovider instance, %w", err)
 	}
 	return reconcile.Result{}, c.removeFinalizer(ctx, node)
 }
 
+// preDrain invokes the pre-drain hooks once for the node, recording that they've completed on the node so that they
+// aren't invoked again while it drains
+func (c *Controller) preDrain(ctx context.Context, node *v1.Node) error {
+	if node.Annotations[v1alpha5.PreDrainHookCompletedAnnotationKey] == "true" {
+		return nil
+	}
+	if err := c.terminator.InvokeHooks(ctx, terminator.PreDrain, node); err != nil {
+		return err
+	}
+	stored := node.DeepCopy()
+	node.Annotations = lo.Assign(node.Annotations, map[string]string{v1alpha5.PreDrainHookCompletedAnnotationKey: "true"})
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return fmt.Errorf("patching node, %w", err)
+	}
+	return nil
+}
+
+// forceTerminate deletes the node's remaining pods without waiting for them to shut down and then terminates its
+// instance, so that a node that can't be drained doesn't block termination indefinitely
+func (c *Controller) forceTerminate(ctx context.Context, node *v1.Node) error {
+	c.recorder.Publish(terminatorevents.NodeDrainTimeoutExceeded(node))
+	pods, err := c.terminator.ForceDeletePods(ctx, node)
+	for _, p := range pods {
+		c.recorder.Publish(terminatorevents.PodForceDeleted(p))
+	}
+	if err != nil {
+		return fmt.Errorf("force deleting pods, %w", err)
+	}
+	if err := c.terminator.InvokeHooks(ctx, terminator.PreTermination, node); err != nil {
+		return err
+	}
+	if err := c.cloudProvider.Delete(ctx, machineutil.NewFromNode(node)); cloudprovider.IgnoreMachineNotFoundError(err) != nil {
+		return fmt.Errorf("terminating cloudprovider instance, %w", err)
+	}
+	ForcedDrainsCounter.With(prometheus.Labels{
+		metrics.NodePoolLabel:    node.Labels[v1beta1.NodePoolLabelKey],
+		metrics.ProvisionerLabel: node.Labels[v1alpha5.ProvisionerNameLabelKey],
+	}).Inc()
+	return nil
+}
+
 func (c *Controller) deleteAllMachines(ctx context.Context, node *v1.Node) error {
 	machineList := &v1alpha5.MachineList{}
 	if err := c.kubeClient.List(ctx, machineList, client.MatchingFields{"status.providerID": node.Spec.ProviderID}); err != nil {
@@ -32,8 +32,17 @@ var (
 		},
 		[]string{metrics.ProvisionerLabel},
 	)
+	ForcedDrainsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "karpenter",
+			Subsystem: "nodes",
+			Name:      "forced_drains",
+			Help:      "Number of nodes whose remaining pods were force deleted after exceeding their drain timeout. Labeled by the nodepool or provisioner that owns the node.",
+		},
+		[]string{metrics.NodePoolLabel, metrics.ProvisionerLabel},
+	)
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(TerminationSummary)
+	crmetrics.Registry.MustRegister(TerminationSummary, ForcedDrainsCounter)
 }
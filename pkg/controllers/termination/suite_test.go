@@ -16,7 +16,10 @@ package termination_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -29,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter-core/pkg/apis"
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/cloudprovider/fake"
 	"github.com/aws/karpenter-core/pkg/controllers/termination"
@@ -42,6 +46,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	. "knative.dev/pkg/logging/testing"
@@ -64,6 +70,7 @@ func TestAPIs(t *testing.T) {
 }
 
 var _ = BeforeSuite(func() {
+	ctx = settings.ToContext(ctx, test.Settings())
 	fakeClock = clock.NewFakeClock(time.Now())
 	env = test.NewEnvironment(scheme.Scheme, test.WithCRDs(apis.CRDs...), test.WithFieldIndexers(func(c cache.Cache) error {
 		return c.IndexField(ctx, &v1alpha5.Machine{}, "status.providerID", func(obj client.Object) []string {
@@ -72,8 +79,8 @@ var _ = BeforeSuite(func() {
 	}))
 
 	cloudProvider = fake.NewCloudProvider()
-	evictionQueue = terminator.NewEvictionQueue(ctx, env.KubernetesInterface.CoreV1(), events.NewRecorder(&record.FakeRecorder{}))
-	terminationController = termination.NewController(env.Client, cloudProvider, terminator.NewTerminator(fakeClock, env.Client, evictionQueue), events.NewRecorder(&record.FakeRecorder{}))
+	evictionQueue = terminator.NewEvictionQueue(ctx, fakeClock, env.KubernetesInterface.CoreV1(), events.NewRecorder(&record.FakeRecorder{}))
+	terminationController = termination.NewController(env.Client, cloudProvider, terminator.NewTerminator(fakeClock, env.Client, evictionQueue, events.NewRecorder(&record.FakeRecorder{})), events.NewRecorder(&record.FakeRecorder{}))
 })
 
 var _ = AfterSuite(func() {
@@ -97,6 +104,9 @@ var _ = Describe("Termination", func() {
 		// Reset the metrics collectors
 		metrics.NodesTerminatedCounter.Reset()
 		termination.TerminationSummary.Reset()
+		termination.ForcedDrainsCounter.Reset()
+		terminator.EvictionsBlockedCounter.Reset()
+		terminator.EvictionErrorsCounter.Reset()
 	})
 
 	Context("Reconciliation", func() {
@@ -107,6 +117,97 @@ var _ = Describe("Termination", func() {
 			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
 			ExpectNotFound(ctx, env.Client, node)
 		})
+		It("should invoke the pre-drain hook once and the pre-termination hook before terminating the node", func() {
+			var mu sync.Mutex
+			var phases []terminator.HookPhase
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				req := terminator.HookRequest{}
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				Expect(req.Node).To(Equal(node.Name))
+				mu.Lock()
+				defer mu.Unlock()
+				phases = append(phases, req.Phase)
+			}))
+			defer server.Close()
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{PreDrainHookURL: server.URL, PreTerminationHookURL: server.URL}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			ExpectApplied(ctx, env.Client, node, pod)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectDeleted(ctx, env.Client, pod)
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+			Expect(phases).To(Equal([]terminator.HookPhase{terminator.PreDrain, terminator.PreTermination}))
+		})
+		It("should not drain the node when the pre-drain hook fails", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{PreDrainHookURL: server.URL, TerminationHookFailurePolicy: settings.TerminationHookFailurePolicyFail}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			ExpectApplied(ctx, env.Client, node, pod)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			// Expect the node to remain without any pods being evicted
+			ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectNotEnqueuedForEviction(evictionQueue, pod)
+		})
+		It("should drain the node when the pre-drain hook fails and the failure policy is Ignore", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{PreDrainHookURL: server.URL}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+			pod := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			ExpectApplied(ctx, env.Client, node, pod)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectEvicted(env.Client, pod)
+		})
+		It("should not block termination on a failing hook once the node exceeds its drain timeout", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{PreDrainHookURL: server.URL, TerminationHookFailurePolicy: settings.TerminationHookFailurePolicyFail}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+			provisioner := test.Provisioner()
+			provisioner.Spec.DrainTimeoutSeconds = lo.ToPtr[int64](60)
+			node.Labels = lo.Assign(node.Labels, map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name})
+			ExpectApplied(ctx, env.Client, provisioner, node)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			// Step past the drain timeout
+			fakeClock.Step(2 * time.Minute)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
 		It("should delete machines associated with nodes", func() {
 			ExpectApplied(ctx, env.Client, node, machine)
 			Expect(env.Client.Delete(ctx, node)).To(Succeed())
@@ -275,6 +376,328 @@ var _ = Describe("Termination", func() {
 			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
 			ExpectNotFound(ctx, env.Client, node)
 		})
+		It("should delete pods that violate a PDB once the node exceeds its max node lifetime", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.MaxNodeLifetimeSeconds = lo.ToPtr[int64](60)
+			node.Labels = lo.Assign(node.Labels, map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name})
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node, podNoEvict, pdb)
+
+			// Step past the max node lifetime
+			fakeClock.SetTime(time.Now().Add(time.Hour))
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			// Expect the pod to be deleted directly rather than evicted
+			ExpectNotEnqueuedForEviction(evictionQueue, podNoEvict)
+			podNoEvict = ExpectPodExists(ctx, env.Client, podNoEvict.Name, podNoEvict.Namespace)
+			Expect(podNoEvict.DeletionTimestamp.IsZero()).To(BeFalse())
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should track the eviction queue depth of draining nodes", func() {
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, node, podNoEvict, pdb)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			m, found := FindMetricWithLabelValues("karpenter_nodes_eviction_queue_depth", map[string]string{"node": node.Name})
+			Expect(found).To(BeTrue())
+			Expect(m.GetGauge().GetValue()).To(BeNumerically("==", 1))
+
+			// Delete pod to simulate successful eviction
+			ExpectDeleted(ctx, env.Client, podNoEvict)
+			Eventually(func() bool {
+				_, found := FindMetricWithLabelValues("karpenter_nodes_eviction_queue_depth", map[string]string{"node": node.Name})
+				return found
+			}).Should(BeFalse())
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should record eviction errors from the eviction API", func() {
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			// The eviction API rejects evicting a pod that's selected by more than one PodDisruptionBudget
+			pdb := test.PodDisruptionBudget(test.PDBOptions{Labels: labelSelector})
+			otherPDB := test.PodDisruptionBudget(test.PDBOptions{Labels: labelSelector})
+			pod := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, node, pod, pdb, otherPDB)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			Eventually(func() bool {
+				_, found := FindMetricWithLabelValues("karpenter_pods_eviction_errors", map[string]string{"namespace": pod.Namespace, "reason": string(metav1.StatusReasonInternalError)})
+				return found
+			}).Should(BeTrue())
+			_, found := FindMetricWithLabelValues("karpenter_pods_evictions_blocked", map[string]string{"namespace": pod.Namespace})
+			Expect(found).To(BeFalse())
+		})
+		It("should delete pods whose eviction has been blocked by a PDB for longer than force evict after", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.ForceEvictAfterSeconds = lo.ToPtr[int64](60)
+			node.Labels = lo.Assign(node.Labels, map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name})
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node, podNoEvict, pdb)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			// Expect the eviction to be blocked by the PDB
+			Eventually(func() bool {
+				_, ok := evictionQueue.BlockedSince(client.ObjectKeyFromObject(podNoEvict))
+				return ok
+			}).Should(BeTrue())
+			m, found := FindMetricWithLabelValues("karpenter_pods_evictions_blocked", map[string]string{"namespace": podNoEvict.Namespace, "pdb": pdb.Name})
+			Expect(found).To(BeTrue())
+			Expect(m.GetCounter().GetValue()).To(BeNumerically(">=", 1))
+			// Expect when the eviction was first blocked to be checkpointed to the pod
+			Eventually(func() map[string]string {
+				return ExpectPodExists(ctx, env.Client, podNoEvict.Name, podNoEvict.Namespace).Annotations
+			}).Should(HaveKey(v1alpha5.EvictionBlockedSinceAnnotationKey))
+
+			// The pod isn't deleted until it's been blocked for longer than force evict after
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectPodExists(ctx, env.Client, podNoEvict.Name, podNoEvict.Namespace).DeletionTimestamp.IsZero()).To(BeTrue())
+
+			fakeClock.Step(2 * time.Minute)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectPodExists(ctx, env.Client, podNoEvict.Name, podNoEvict.Namespace).DeletionTimestamp.IsZero()).To(BeFalse())
+
+			// Delete pod to simulate it shutting down
+			ExpectDeleted(ctx, env.Client, podNoEvict)
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should restore when evictions were first blocked from the pod after a restart", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.ForceEvictAfterSeconds = lo.ToPtr[int64](60)
+			node.Labels = lo.Assign(node.Labels, map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name})
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			// The pod's eviction was blocked before the restart
+			blockedSince := fakeClock.Now().Add(-2 * time.Minute).Truncate(time.Second)
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					Annotations:     map[string]string{v1alpha5.EvictionBlockedSinceAnnotationKey: blockedSince.Format(time.RFC3339)},
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node, podNoEvict, pdb)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			restored, ok := evictionQueue.BlockedSince(client.ObjectKeyFromObject(podNoEvict))
+			Expect(ok).To(BeTrue())
+			Expect(restored.Equal(blockedSince)).To(BeTrue())
+
+			// The pod is deleted without waiting for force evict after again
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectPodExists(ctx, env.Client, podNoEvict.Name, podNoEvict.Namespace).DeletionTimestamp.IsZero()).To(BeFalse())
+		})
+		It("should taint the node with a NoExecute taint instead of force deleting its pods once it exceeds its drain timeout", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.DrainTimeoutSeconds = lo.ToPtr[int64](60)
+			provisioner.Spec.NoExecuteFallback = lo.ToPtr(true)
+			node.Labels = lo.Assign(node.Labels, map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name})
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node, podNoEvict, pdb)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Taints).ToNot(ContainElement(HaveField("Key", v1alpha5.TerminatingNoExecuteTaintKey)))
+
+			// Step past the drain timeout
+			fakeClock.Step(2 * time.Minute)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Taints).To(ContainElement(v1.Taint{Key: v1alpha5.TerminatingNoExecuteTaintKey, Effect: v1.TaintEffectNoExecute}))
+			// Expect the pod to be left to the taint manager rather than force deleted
+			ExpectExists(ctx, env.Client, podNoEvict)
+		})
+		It("should not taint the node with a NoExecute taint when its provisioner doesn't opt in", func() {
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, node, podNoEvict, pdb)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			fakeClock.Step(time.Hour)
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Taints).ToNot(ContainElement(HaveField("Key", v1alpha5.TerminatingNoExecuteTaintKey)))
+		})
+		It("should apply the out-of-service taint once the node is drained", func() {
+			// Fail the pre-termination hook so that the node isn't deleted after it's tainted
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{
+				OutOfServiceTaintEnabled:     true,
+				PreTerminationHookURL:        server.URL,
+				TerminationHookFailurePolicy: settings.TerminationHookFailurePolicyFail,
+			}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+			ExpectApplied(ctx, env.Client, node)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileFailed(ctx, terminationController, client.ObjectKeyFromObject(node))
+			Expect(ExpectNodeExists(ctx, env.Client, node.Name).Spec.Taints).To(ContainElement(v1.Taint{Key: v1.TaintNodeOutOfService, Value: "nodeshutdown", Effect: v1.TaintEffectNoExecute}))
+		})
+		It("should force delete remaining pods and the node once the node exceeds its drain timeout", func() {
+			provisioner := test.Provisioner()
+			provisioner.Spec.DrainTimeoutSeconds = lo.ToPtr[int64](60)
+			node.Labels = lo.Assign(node.Labels, map[string]string{v1alpha5.ProvisionerNameLabelKey: provisioner.Name})
+			minAvailable := intstr.FromInt(1)
+			labelSelector := map[string]string{test.RandomName(): test.RandomName()}
+			pdb := test.PodDisruptionBudget(test.PDBOptions{
+				Labels: labelSelector,
+				// Don't let any pod evict
+				MinAvailable: &minAvailable,
+			})
+			podNoEvict := test.Pod(test.PodOptions{
+				NodeName: node.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:          labelSelector,
+					OwnerReferences: defaultOwnerRefs,
+				},
+				Phase: v1.PodRunning,
+			})
+			ExpectApplied(ctx, env.Client, provisioner, node, podNoEvict, pdb)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			// Expect node to exist and be draining
+			ExpectNodeDraining(env.Client, node.Name)
+
+			// Step past the drain timeout
+			fakeClock.SetTime(time.Now().Add(time.Hour))
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			// Expect the pod to be force deleted and the node to be terminated without waiting on it
+			ExpectNotFound(ctx, env.Client, podNoEvict, node)
+			m, found := FindMetricWithLabelValues("karpenter_nodes_forced_drains", map[string]string{"provisioner": provisioner.Name})
+			Expect(found).To(BeTrue())
+			Expect(m.GetCounter().GetValue()).To(BeNumerically("==", 1))
+		})
 		It("should evict non-critical pods first", func() {
 			podEvict := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
 			podNodeCritical := test.Pod(test.PodOptions{NodeName: node.Name, PriorityClassName: "system-node-critical", ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
@@ -307,6 +730,88 @@ var _ = Describe("Termination", func() {
 			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
 			ExpectNotFound(ctx, env.Client, node)
 		})
+		It("should evict pods in order of priority and then QoS class", func() {
+			low := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()}, Value: 100}
+			high := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: test.RandomName()}, Value: 1000}
+			requests := v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}}
+			podBestEffort := test.Pod(test.PodOptions{NodeName: node.Name, PriorityClassName: low.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podBurstable := test.Pod(test.PodOptions{NodeName: node.Name, PriorityClassName: low.Name, ResourceRequirements: requests, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podHighPriority := test.Pod(test.PodOptions{NodeName: node.Name, PriorityClassName: high.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podCritical := test.Pod(test.PodOptions{NodeName: node.Name, PriorityClassName: "system-node-critical", ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+
+			ExpectApplied(ctx, env.Client, low, high)
+			ExpectApplied(ctx, env.Client, node, podBestEffort, podBurstable, podHighPriority, podCritical)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			ordered := []*v1.Pod{podBestEffort, podBurstable, podHighPriority, podCritical}
+			for i, pod := range ordered {
+				node = ExpectNodeExists(ctx, env.Client, node.Name)
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+				ExpectNodeDraining(env.Client, node.Name)
+
+				// Expect only the next pod in the eviction order to be evicting, and delete it
+				ExpectEvicted(env.Client, pod)
+				for _, remaining := range ordered[i+1:] {
+					Expect(ExpectPodExists(ctx, env.Client, remaining.Name, remaining.Namespace).DeletionTimestamp.IsZero()).To(BeTrue())
+				}
+				ExpectDeleted(ctx, env.Client, pod)
+				fakeClock.Step(time.Second)
+			}
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+			ExpectDeleted(ctx, env.Client, low, high)
+		})
+		It("should evict DaemonSet pods after all other pods when draining DaemonSet pods is enabled", func() {
+			ctx = settings.ToContext(ctx, test.Settings(settings.Settings{DrainDaemonSetPods: true}))
+			DeferCleanup(func() { ctx = settings.ToContext(ctx, test.Settings()) })
+			daemonSetOwnerRefs := []metav1.OwnerReference{{Kind: "DaemonSet", APIVersion: "apps/v1", Name: "ds", UID: "1234567890"}}
+			podEvict := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podCritical := test.Pod(test.PodOptions{NodeName: node.Name, PriorityClassName: "system-node-critical", ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
+			podDaemonSet := test.Pod(test.PodOptions{
+				NodeName:    node.Name,
+				ObjectMeta:  metav1.ObjectMeta{OwnerReferences: daemonSetOwnerRefs},
+				Tolerations: []v1.Toleration{{Key: v1.TaintNodeUnschedulable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+			})
+			ExpectApplied(ctx, env.Client, node, podEvict, podCritical, podDaemonSet)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			for i, pod := range []*v1.Pod{podEvict, podCritical, podDaemonSet} {
+				node = ExpectNodeExists(ctx, env.Client, node.Name)
+				ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+				ExpectNodeDraining(env.Client, node.Name)
+				if i < 2 {
+					ExpectNotEnqueuedForEviction(evictionQueue, podDaemonSet)
+				}
+				ExpectEvicted(env.Client, pod)
+				ExpectDeleted(ctx, env.Client, pod)
+			}
+
+			// Reconcile to delete node
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+			ExpectNotFound(ctx, env.Client, node)
+		})
+		It("should not evict DaemonSet pods when draining DaemonSet pods is disabled", func() {
+			podDaemonSet := test.Pod(test.PodOptions{
+				NodeName:    node.Name,
+				ObjectMeta:  metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", APIVersion: "apps/v1", Name: "ds", UID: "1234567890"}}},
+				Tolerations: []v1.Toleration{{Key: v1.TaintNodeUnschedulable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+			})
+			ExpectApplied(ctx, env.Client, node, podDaemonSet)
+
+			// Trigger Termination Controller
+			Expect(env.Client.Delete(ctx, node)).To(Succeed())
+			node = ExpectNodeExists(ctx, env.Client, node.Name)
+			ExpectReconcileSucceeded(ctx, terminationController, client.ObjectKeyFromObject(node))
+
+			ExpectNotEnqueuedForEviction(evictionQueue, podDaemonSet)
+			ExpectNotFound(ctx, env.Client, node)
+		})
 		It("should not evict static pods", func() {
 			ExpectApplied(ctx, env.Client, node)
 			podEvict := test.Pod(test.PodOptions{NodeName: node.Name, ObjectMeta: metav1.ObjectMeta{OwnerReferences: defaultOwnerRefs}})
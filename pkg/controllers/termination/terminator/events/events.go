@@ -16,6 +16,7 @@ package events
 
 import (
 	"fmt"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 
@@ -41,3 +42,53 @@ func NodeFailedToDrain(node *v1.Node, err error) events.Event {
 		DedupeValues:   []string{node.Name},
 	}
 }
+
+func NodeForceDrain(node *v1.Node) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ForceDraining",
+		Message:        "Node exceeded its max node lifetime, deleting pods regardless of PodDisruptionBudgets and do-not-evict annotations",
+		DedupeValues:   []string{node.Name},
+	}
+}
+
+func NodeDrainTimeoutExceeded(node *v1.Node) events.Event {
+	return events.Event{
+		InvolvedObject: node,
+		Type:           v1.EventTypeWarning,
+		Reason:         "DrainTimeoutExceeded",
+		Message:        "Node exceeded its drain timeout, force deleting remaining pods and terminating the instance",
+		DedupeValues:   []string{node.Name},
+	}
+}
+
+func PodForceDeleted(pod *v1.Pod) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ForceDeleted",
+		Message:        "Force deleted pod after its node exceeded its drain timeout",
+		DedupeValues:   []string{pod.Name},
+	}
+}
+
+func EvictionBlocked(pod *v1.Pod, pdb string, blockedFor time.Duration) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "EvictionBlocked",
+		Message:        fmt.Sprintf("Eviction has been blocked by PodDisruptionBudget %q for %s", pdb, blockedFor.Truncate(time.Second)),
+		DedupeValues:   []string{pod.Namespace, pod.Name},
+	}
+}
+
+func PodForceEvicted(pod *v1.Pod, blockedFor time.Duration) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ForceEvicted",
+		Message:        fmt.Sprintf("Deleted pod bypassing its PodDisruptionBudget after its eviction was blocked for %s", blockedFor.Truncate(time.Second)),
+		DedupeValues:   []string{pod.Namespace, pod.Name},
+	}
+}
@@ -16,11 +16,16 @@ package terminator
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	set "github.com/deckarep/golang-set"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -28,18 +33,16 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	terminatorevents "github.com/aws/karpenter-core/pkg/controllers/termination/terminator/events"
 	"github.com/aws/karpenter-core/pkg/events"
 )
 
-const (
-	evictionQueueBaseDelay = 100 * time.Millisecond
-	evictionQueueMaxDelay  = 10 * time.Second
-)
-
 type NodeDrainError struct {
 	error
 }
@@ -60,16 +63,38 @@ type EvictionQueue struct {
 	workqueue.RateLimitingInterface
 	set.Set
 
+	clock        clock.Clock
 	coreV1Client corev1.CoreV1Interface
 	recorder     events.Recorder
+
+	// limiter bounds the rate of eviction requests, evictions aren't rate limited when it's nil
+	limiter *rate.Limiter
+
+	mu sync.RWMutex
+	// blockedSince tracks when the eviction of each pod was first rejected by a PodDisruptionBudget. It's checkpointed
+	// to the pod so that it can be restored when the pod is queued again after a restart.
+	blockedSince map[types.NamespacedName]time.Time
+	// nodes tracks the node of each queued pod and depths the number of queued pods on each node
+	nodes  map[types.NamespacedName]string
+	depths map[string]int
 }
 
-func NewEvictionQueue(ctx context.Context, coreV1Client corev1.CoreV1Interface, recorder events.Recorder) *EvictionQueue {
+// NewEvictionQueue constructs an EvictionQueue that's rate limited and retries failed evictions according to the
+// eviction settings in the context
+func NewEvictionQueue(ctx context.Context, clk clock.Clock, coreV1Client corev1.CoreV1Interface, recorder events.Recorder) *EvictionQueue {
+	s := settings.FromContext(ctx)
 	queue := &EvictionQueue{
-		RateLimitingInterface: workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(evictionQueueBaseDelay, evictionQueueMaxDelay)),
+		RateLimitingInterface: workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(s.EvictionRetryBaseDelay, s.EvictionRetryMaxDelay)),
 		Set:                   set.NewSet(),
+		clock:                 clk,
 		coreV1Client:          coreV1Client,
 		recorder:              recorder,
+		blockedSince:          map[types.NamespacedName]time.Time{},
+		nodes:                 map[types.NamespacedName]string{},
+		depths:                map[string]int{},
+	}
+	if s.EvictionQPS > 0 {
+		queue.limiter = rate.NewLimiter(rate.Limit(s.EvictionQPS), s.EvictionBurst)
 	}
 	go queue.Start(logging.WithLogger(ctx, logging.FromContext(ctx).Named("eviction")))
 	return queue
@@ -80,6 +105,8 @@ func (e *EvictionQueue) Add(pods ...*v1.Pod) {
 	for _, pod := range pods {
 		if nn := client.ObjectKeyFromObject(pod); !e.Set.Contains(nn) {
 			e.Set.Add(nn)
+			e.restore(nn, pod)
+			e.track(nn, pod.Spec.NodeName)
 			e.RateLimitingInterface.Add(nn)
 		}
 	}
@@ -93,10 +120,18 @@ func (e *EvictionQueue) Start(ctx context.Context) {
 			break
 		}
 		nn := item.(types.NamespacedName)
+		// Wait for the rate limit so that draining many nodes at once doesn't overwhelm the API server
+		if e.limiter != nil {
+			if err := e.limiter.Wait(ctx); err != nil {
+				logging.FromContext(ctx).Errorf("waiting on eviction rate limit, %s", err)
+			}
+		}
 		// Evict pod
 		if e.evict(ctx, nn) {
 			e.RateLimitingInterface.Forget(nn)
 			e.Set.Remove(nn)
+			e.unblock(nn)
+			e.untrack(nn)
 			e.RateLimitingInterface.Done(nn)
 			continue
 		}
@@ -123,11 +158,131 @@ func (e *EvictionQueue) evict(ctx context.Context, nn types.NamespacedName) bool
 				Name:      nn.Name,
 				Namespace: nn.Namespace,
 			}}, fmt.Errorf("evicting pod %s/%s violates a PDB", nn.Namespace, nn.Name)))
+			pdb := blockingPDB(err)
+			EvictionsBlockedCounter.With(prometheus.Labels{namespaceLabel: nn.Namespace, pdbLabel: pdb}).Inc()
+			blockedSince, first := e.block(nn)
+			if first {
+				e.checkpoint(ctx, nn, blockedSince)
+			}
+			e.recorder.Publish(terminatorevents.EvictionBlocked(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace}},
+				pdb, e.clock.Since(blockedSince)))
 			return false
 		}
+		EvictionErrorsCounter.With(prometheus.Labels{namespaceLabel: nn.Namespace, reasonLabel: evictionErrorReason(err)}).Inc()
 		logging.FromContext(ctx).Errorf("evicting pod, %s", err)
 		return false
 	}
 	e.recorder.Publish(terminatorevents.EvictPod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace}}))
 	return true
 }
+
+// track records the node that a queued pod is being evicted from
+func (e *EvictionQueue) track(nn types.NamespacedName, nodeName string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nodes[nn] = nodeName
+	e.depths[nodeName]++
+	EvictionQueueDepth.With(prometheus.Labels{nodeLabel: nodeName}).Set(float64(e.depths[nodeName]))
+}
+
+func (e *EvictionQueue) untrack(nn types.NamespacedName) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	nodeName, ok := e.nodes[nn]
+	if !ok {
+		return
+	}
+	delete(e.nodes, nn)
+	if e.depths[nodeName]--; e.depths[nodeName] > 0 {
+		EvictionQueueDepth.With(prometheus.Labels{nodeLabel: nodeName}).Set(float64(e.depths[nodeName]))
+		return
+	}
+	delete(e.depths, nodeName)
+	EvictionQueueDepth.Delete(prometheus.Labels{nodeLabel: nodeName})
+}
+
+// BlockedSince returns when the eviction of the pod was first rejected by a PodDisruptionBudget. The second return
+// value is false if the pod's eviction isn't currently blocked.
+func (e *EvictionQueue) BlockedSince(nn types.NamespacedName) (time.Time, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	t, ok := e.blockedSince[nn]
+	return t, ok
+}
+
+// block records that the eviction of the pod was rejected and returns when it was first rejected, along with whether
+// this was the first rejection
+func (e *EvictionQueue) block(nn types.NamespacedName) (time.Time, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if t, ok := e.blockedSince[nn]; ok {
+		return t, false
+	}
+	e.blockedSince[nn] = e.clock.Now()
+	return e.blockedSince[nn], true
+}
+
+// checkpoint persists when the eviction of the pod was first rejected to the pod's annotations
+func (e *EvictionQueue) checkpoint(ctx context.Context, nn types.NamespacedName, blockedSince time.Time) {
+	patch := lo.Must(json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{v1alpha5.EvictionBlockedSinceAnnotationKey: blockedSince.Format(time.RFC3339)},
+		},
+	}))
+	if _, err := e.coreV1Client.Pods(nn.Namespace).Patch(ctx, nn.Name, types.MergePatchType, patch, metav1.PatchOptions{}); client.IgnoreNotFound(err) != nil {
+		logging.FromContext(ctx).Errorf("checkpointing blocked eviction, %s", err)
+	}
+}
+
+// restore recovers when the eviction of the pod was first rejected from the pod's annotations, in case the pod was
+// being evicted before Karpenter restarted
+func (e *EvictionQueue) restore(nn types.NamespacedName, pod *v1.Pod) {
+	v, ok := pod.Annotations[v1alpha5.EvictionBlockedSinceAnnotationKey]
+	if !ok {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.blockedSince[nn]; !ok {
+		e.blockedSince[nn] = t
+	}
+}
+
+func (e *EvictionQueue) unblock(nn types.NamespacedName) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.blockedSince, nn)
+}
+
+// blockingPDB returns the name of the PodDisruptionBudget that rejected an eviction, as reported in the causes of the
+// API server's response
+func blockingPDB(err error) string {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil {
+		return ""
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != policyv1.DisruptionBudgetCause {
+			continue
+		}
+		// e.g. "The disruption budget my-pdb needs 1 healthy pods and has 1 currently"
+		var name string
+		if _, err := fmt.Sscanf(cause.Message, "The disruption budget %s needs", &name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// evictionErrorReason returns the reason that the API server reported for a failed eviction, e.g. InternalError or
+// Forbidden, or Unknown if the error didn't come from the API server
+func evictionErrorReason(err error) string {
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+	return "Unknown"
+}
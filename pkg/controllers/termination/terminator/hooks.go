@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/logging"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+)
+
+// HookPhase is the point in a node's termination at which a LifecycleHook is invoked
+type HookPhase string
+
+const (
+	// PreDrain hooks are invoked once, before any pods are evicted from the node
+	PreDrain HookPhase = "PreDrain"
+	// PreTermination hooks are invoked after the node is drained and before its instance is terminated
+	PreTermination HookPhase = "PreTermination"
+)
+
+// LifecycleHook lets external systems react to a node's termination, e.g. deregistering it from load balancers or
+// databases. Hooks may be invoked more than once for the same phase and node, so they must be idempotent.
+type LifecycleHook interface {
+	Invoke(ctx context.Context, phase HookPhase, node *v1.Node) error
+}
+
+// HookRequest is the body that's POSTed to webhook lifecycle hooks
+type HookRequest struct {
+	Phase      HookPhase `json:"phase"`
+	Node       string    `json:"node"`
+	ProviderID string    `json:"providerID"`
+}
+
+// WebhookLifecycleHook calls the preDrainHookURL and preTerminationHookURL from the operator settings, treating any
+// non-2xx response as a failure. URLs are never read from the node so that users who can annotate nodes can't make
+// the controller send requests to arbitrary endpoints.
+type WebhookLifecycleHook struct {
+	client *http.Client
+}
+
+func NewWebhookLifecycleHook() *WebhookLifecycleHook {
+	return &WebhookLifecycleHook{client: &http.Client{}}
+}
+
+func (w *WebhookLifecycleHook) Invoke(ctx context.Context, phase HookPhase, node *v1.Node) error {
+	s := settings.FromContext(ctx)
+	url := map[HookPhase]string{PreDrain: s.PreDrainHookURL, PreTermination: s.PreTerminationHookURL}[phase]
+	if url == "" {
+		return nil
+	}
+	body, err := json.Marshal(HookRequest{Phase: phase, Node: node.Name, ProviderID: node.Spec.ProviderID})
+	if err != nil {
+		return fmt.Errorf("marshaling hook request, %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating hook request, %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling hook, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("calling hook, unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// InvokeHooks invokes each of the terminator's lifecycle hooks for the phase, bounding each by the
+// terminationHookTimeout and by the node's drain deadline. Hook failures are only returned if the
+// terminationHookFailurePolicy is Fail and the node hasn't exceeded its drain timeout, so that a failing hook can't
+// block termination forever.
+func (t *Terminator) InvokeHooks(ctx context.Context, phase HookPhase, node *v1.Node) error {
+	s := settings.FromContext(ctx)
+	deadline, hasDeadline, err := t.drainDeadline(ctx, node)
+	if err != nil {
+		return fmt.Errorf("determining drain timeout, %w", err)
+	}
+	for _, hook := range t.hooks {
+		timeout := s.TerminationHookTimeout
+		if hasDeadline {
+			timeout = lo.Clamp(deadline.Sub(t.clock.Now()), 0, timeout)
+		}
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := hook.Invoke(hookCtx, phase, node)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if s.TerminationHookFailurePolicy == settings.TerminationHookFailurePolicyIgnore || (hasDeadline && !t.clock.Now().Before(deadline)) {
+			logging.FromContext(ctx).Errorf("invoking %s hook, ignoring failure, %s", phase, err)
+			continue
+		}
+		return fmt.Errorf("invoking %s hook, %w", phase, err)
+	}
+	return nil
+}
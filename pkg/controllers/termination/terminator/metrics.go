@@ -0,0 +1,63 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/metrics"
+)
+
+const (
+	namespaceLabel = "namespace"
+	pdbLabel       = "pdb"
+	nodeLabel      = "node"
+	reasonLabel    = "reason"
+)
+
+var (
+	EvictionsBlockedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "pods",
+			Name:      "evictions_blocked",
+			Help:      "Number of pod evictions that were rejected because they would violate a PodDisruptionBudget. Labeled by namespace and the blocking PodDisruptionBudget.",
+		},
+		[]string{namespaceLabel, pdbLabel},
+	)
+	EvictionErrorsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "pods",
+			Name:      "eviction_errors",
+			Help:      "Number of pod evictions that failed with an error from the eviction API other than a PodDisruptionBudget violation. Labeled by namespace and the reason for the error.",
+		},
+		[]string{namespaceLabel, reasonLabel},
+	)
+	EvictionQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: metrics.NodeSubsystem,
+			Name:      "eviction_queue_depth",
+			Help:      "Number of pods that are waiting to be evicted from a draining node. Labeled by node.",
+		},
+		[]string{nodeLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(EvictionsBlockedCounter, EvictionErrorsCounter, EvictionQueueDepth)
+}
@@ -26,6 +26,12 @@ import (
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	terminatorevents "github.com/aws/karpenter-core/pkg/controllers/termination/terminator/events"
+	"github.com/aws/karpenter-core/pkg/events"
+	nodeclaimutil "github.com/aws/karpenter-core/pkg/utils/nodeclaim"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 	podutil "github.com/aws/karpenter-core/pkg/utils/pod"
 )
 
@@ -33,13 +39,19 @@ type Terminator struct {
 	clock         clock.Clock
 	kubeClient    client.Client
 	evictionQueue *EvictionQueue
+	recorder      events.Recorder
+	hooks         []LifecycleHook
 }
 
-func NewTerminator(clk clock.Clock, kubeClient client.Client, eq *EvictionQueue) *Terminator {
+// NewTerminator constructs a Terminator that invokes the webhooks configured in the operator settings, followed by any
+// additional lifecycle hooks, before draining and terminating nodes
+func NewTerminator(clk clock.Clock, kubeClient client.Client, eq *EvictionQueue, recorder events.Recorder, hooks ...LifecycleHook) *Terminator {
 	return &Terminator{
 		clock:         clk,
 		kubeClient:    kubeClient,
 		evictionQueue: eq,
+		recorder:      recorder,
+		hooks:         append([]LifecycleHook{NewWebhookLifecycleHook()}, hooks...),
 	}
 }
 
@@ -67,14 +79,186 @@ func (t *Terminator) Cordon(ctx context.Context, node *v1.Node) error {
 // Drain evicts pods from the node and returns true when all pods are evicted
 // https://kubernetes.io/docs/concepts/architecture/nodes/#graceful-node-shutdown
 func (t *Terminator) Drain(ctx context.Context, node *v1.Node) error {
-	// Get evictable pods
+	podsToEvict, err := t.getEvictablePods(ctx, node)
+	if err != nil {
+		return err
+	}
+	if err = t.forceEvictBlockedPods(ctx, node, podsToEvict); err != nil {
+		return err
+	}
+	// Enqueue for eviction
+	t.evict(podsToEvict)
+
+	if len(podsToEvict) > 0 {
+		return NewNodeDrainError(fmt.Errorf("%d pods are waiting to be evicted", len(podsToEvict)))
+	}
+	return nil
+}
+
+// forceEvictBlockedPods deletes the pods whose eviction has been blocked by a PodDisruptionBudget for longer than the
+// ForceEvictAfter of the node's owning NodePool. Pods are still given their terminationGracePeriodSeconds to shut down.
+func (t *Terminator) forceEvictBlockedPods(ctx context.Context, node *v1.Node, pods []*v1.Pod) error {
+	nodePool, err := nodeclaimutil.Owner(ctx, t.kubeClient, node)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	forceEvictAfter := nodePool.Spec.Deprovisioning.ForceEvictAfter
+	if forceEvictAfter == nil {
+		return nil
+	}
+	for _, p := range pods {
+		if !p.DeletionTimestamp.IsZero() {
+			continue
+		}
+		blockedSince, ok := t.evictionQueue.BlockedSince(client.ObjectKeyFromObject(p))
+		if !ok || t.clock.Since(blockedSince) < forceEvictAfter.Duration {
+			continue
+		}
+		if err := t.kubeClient.Delete(ctx, p); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting pod, %w", err)
+		}
+		t.recorder.Publish(terminatorevents.PodForceEvicted(p, t.clock.Since(blockedSince)))
+		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(p)).Infof("deleted pod after its eviction was blocked for %s", t.clock.Since(blockedSince))
+	}
+	return nil
+}
+
+// NoExecuteFallback taints the node with a NoExecute taint, if its owning NodePool opts into the NoExecuteFallback,
+// so that the taint manager removes its remaining pods once it has exceeded its drain timeout. It returns whether
+// the node was tainted, in which case its pods shouldn't be force deleted.
+func (t *Terminator) NoExecuteFallback(ctx context.Context, node *v1.Node) (bool, error) {
+	nodePool, err := nodeclaimutil.Owner(ctx, t.kubeClient, node)
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if !lo.FromPtr(nodePool.Spec.Deprovisioning.NoExecuteFallback) {
+		return false, nil
+	}
+	return true, t.taint(ctx, node, v1.Taint{Key: v1alpha5.TerminatingNoExecuteTaintKey, Effect: v1.TaintEffectNoExecute})
+}
+
+// TaintOutOfService applies the out-of-service taint to a drained node, if enabled, so that any volumes that are
+// still attached to it are force detached
+// https://kubernetes.io/docs/concepts/architecture/nodes/#non-graceful-node-shutdown
+func (t *Terminator) TaintOutOfService(ctx context.Context, node *v1.Node) error {
+	if !settings.FromContext(ctx).OutOfServiceTaintEnabled {
+		return nil
+	}
+	return t.taint(ctx, node, v1.Taint{Key: v1.TaintNodeOutOfService, Value: "nodeshutdown", Effect: v1.TaintEffectNoExecute})
+}
+
+func (t *Terminator) taint(ctx context.Context, node *v1.Node, taint v1.Taint) error {
+	if lo.ContainsBy(node.Spec.Taints, func(existing v1.Taint) bool { return existing.MatchTaint(&taint) }) {
+		return nil
+	}
+	stored := node.DeepCopy()
+	node.Spec.Taints = append(node.Spec.Taints, taint)
+	if err := t.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return fmt.Errorf("tainting node, %w", err)
+	}
+	logging.FromContext(ctx).With("taint", taint.ToString()).Infof("tainted node")
+	return nil
+}
+
+// ShouldForceDrain returns whether the node has exceeded the MaxNodeLifetime and ForceExpirationGracePeriod of
+// its owning NodePool, after which PodDisruptionBudgets and do-not-evict pods no longer block its termination
+func (t *Terminator) ShouldForceDrain(ctx context.Context, node *v1.Node) (bool, error) {
+	nodePool, err := nodeclaimutil.Owner(ctx, t.kubeClient, node)
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	_, forceAt, ok := nodepoolutil.MaxNodeLifetimeDeadlines(nodePool, node)
+	return ok && !t.clock.Now().Before(forceAt), nil
+}
+
+// ForceDrain deletes pods from the node directly rather than evicting them, bypassing PodDisruptionBudgets.
+// Pods are still given their terminationGracePeriodSeconds to shut down.
+func (t *Terminator) ForceDrain(ctx context.Context, node *v1.Node) error {
+	podsToDelete, err := t.getEvictablePods(ctx, node)
+	if err != nil {
+		return err
+	}
+	for _, p := range podsToDelete {
+		if !p.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := t.kubeClient.Delete(ctx, p); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting pod, %w", err)
+		}
+		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(p)).Infof("force deleted pod")
+	}
+	if len(podsToDelete) > 0 {
+		return NewNodeDrainError(fmt.Errorf("%d pods are waiting to be deleted", len(podsToDelete)))
+	}
+	return nil
+}
+
+// DrainTimeoutExceeded returns whether the node has been draining for longer than the DrainTimeout of its owning
+// NodePool, measured from when the node was deleted
+func (t *Terminator) DrainTimeoutExceeded(ctx context.Context, node *v1.Node) (bool, error) {
+	deadline, ok, err := t.drainDeadline(ctx, node)
+	if err != nil || !ok {
+		return false, err
+	}
+	return !t.clock.Now().Before(deadline), nil
+}
+
+// drainDeadline returns when the node exceeds the DrainTimeout of its owning NodePool, or false if the node isn't
+// deleted or its NodePool doesn't have a DrainTimeout
+func (t *Terminator) drainDeadline(ctx context.Context, node *v1.Node) (time.Time, bool, error) {
+	if node.DeletionTimestamp.IsZero() {
+		return time.Time{}, false, nil
+	}
+	nodePool, err := nodeclaimutil.Owner(ctx, t.kubeClient, node)
+	if err != nil {
+		return time.Time{}, false, client.IgnoreNotFound(err)
+	}
+	drainTimeout := nodePool.Spec.Deprovisioning.DrainTimeout
+	if drainTimeout == nil {
+		return time.Time{}, false, nil
+	}
+	return node.DeletionTimestamp.Add(drainTimeout.Duration), true, nil
+}
+
+// ForceDeletePods deletes the remaining pods on the node without waiting for their terminationGracePeriodSeconds
+// and returns the pods that were deleted
+func (t *Terminator) ForceDeletePods(ctx context.Context, node *v1.Node) ([]*v1.Pod, error) {
+	pods, err := t.getEvictablePods(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []*v1.Pod
+	for _, p := range pods {
+		if err := t.kubeClient.Delete(ctx, p, client.GracePeriodSeconds(0)); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return deleted, fmt.Errorf("force deleting pod, %w", err)
+			}
+			continue
+		}
+		logging.FromContext(ctx).With("pod", client.ObjectKeyFromObject(p)).Infof("force deleted pod after drain timeout")
+		deleted = append(deleted, p)
+	}
+	return deleted, nil
+}
+
+// getEvictablePods returns the pods on the node that need to be removed before the node can be terminated
+func (t *Terminator) getEvictablePods(ctx context.Context, node *v1.Node) ([]*v1.Pod, error) {
 	pods, err := t.getPods(ctx, node)
 	if err != nil {
-		return fmt.Errorf("listing pods for node, %w", err)
+		return nil, fmt.Errorf("listing pods for node, %w", err)
 	}
-	var podsToEvict []*v1.Pod
+	drainDaemonSetPods := settings.FromContext(ctx).DrainDaemonSetPods
+	var evictable []*v1.Pod
 	// Skip node due to pods that are not able to be evicted
 	for _, p := range pods {
+		// DaemonSet pods tolerate the unschedulable taint and are recreated on the node by the DaemonSet controller
+		// after they're evicted, so only the pods that were running before the node started draining are evicted
+		if drainDaemonSetPods && podutil.IsOwnedByDaemonSet(p) {
+			if node.DeletionTimestamp.IsZero() || !p.CreationTimestamp.After(node.DeletionTimestamp.Time) {
+				evictable = append(evictable, p)
+			}
+			continue
+		}
 		// Ignore if unschedulable is tolerated, since they will reschedule
 		if podutil.ToleratesUnschedulableTaint(p) {
 			continue
@@ -83,15 +267,9 @@ func (t *Terminator) Drain(ctx context.Context, node *v1.Node) error {
 		if podutil.IsOwnedByNode(p) {
 			continue
 		}
-		podsToEvict = append(podsToEvict, p)
-	}
-	// Enqueue for eviction
-	t.evict(podsToEvict)
-
-	if len(podsToEvict) > 0 {
-		return NewNodeDrainError(fmt.Errorf("%d pods are waiting to be evicted", len(podsToEvict)))
+		evictable = append(evictable, p)
 	}
-	return nil
+	return evictable, nil
 }
 
 // getPods returns a list of evictable pods for the node
@@ -115,25 +293,55 @@ func (t *Terminator) getPods(ctx context.Context, node *v1.Node) ([]*v1.Pod, err
 	return pods, nil
 }
 
+// evict enqueues the first group of pods to be evicted from the node. Pods are drained in groups ordered by
+// evictionOrder, and a group is only evicted once every group before it has been evicted.
+// https://kubernetes.io/docs/concepts/architecture/nodes/#graceful-node-shutdown
 func (t *Terminator) evict(pods []*v1.Pod) {
-	// 1. Prioritize noncritical pods https://kubernetes.io/docs/concepts/architecture/nodes/#graceful-node-shutdown
-	critical := []*v1.Pod{}
-	nonCritical := []*v1.Pod{}
-	for _, pod := range pods {
-		if !pod.DeletionTimestamp.IsZero() {
-			continue
-		}
-		if pod.Spec.PriorityClassName == "system-cluster-critical" || pod.Spec.PriorityClassName == "system-node-critical" {
-			critical = append(critical, pod)
-		} else {
-			nonCritical = append(nonCritical, pod)
-		}
+	pods = lo.Filter(pods, func(p *v1.Pod, _ int) bool { return p.DeletionTimestamp.IsZero() })
+	if len(pods) == 0 {
+		return
 	}
-	// 2. Evict critical pods if all noncritical are evicted
-	if len(nonCritical) == 0 {
-		t.evictionQueue.Add(critical...)
-	} else {
-		t.evictionQueue.Add(nonCritical...)
+	first := lo.MinBy(pods, func(a, b *v1.Pod) bool { return evictionOrder(a).before(evictionOrder(b)) })
+	t.evictionQueue.Add(lo.Filter(pods, func(p *v1.Pod, _ int) bool { return evictionOrder(p) == evictionOrder(first) })...)
+}
+
+// podOrder is the position of a pod in the eviction order
+type podOrder struct {
+	daemonSet bool
+	critical  bool
+	priority  int32
+	qos       int
+}
+
+func (o podOrder) before(other podOrder) bool {
+	if o.daemonSet != other.daemonSet {
+		return !o.daemonSet
+	}
+	if o.critical != other.critical {
+		return !o.critical
+	}
+	if o.priority != other.priority {
+		return o.priority < other.priority
+	}
+	return o.qos < other.qos
+}
+
+// qosOrder evicts BestEffort pods, which are the first to be killed under node pressure, ahead of Burstable and
+// Guaranteed pods
+var qosOrder = map[v1.PodQOSClass]int{
+	v1.PodQOSBestEffort: 0,
+	v1.PodQOSBurstable:  1,
+	v1.PodQOSGuaranteed: 2,
+}
+
+// evictionOrder orders DaemonSet pods last, followed by system-critical pods, and otherwise evicts pods with a lower
+// priority, then with a lower QoS class, first
+func evictionOrder(pod *v1.Pod) podOrder {
+	return podOrder{
+		daemonSet: podutil.IsOwnedByDaemonSet(pod),
+		critical:  pod.Spec.PriorityClassName == "system-cluster-critical" || pod.Spec.PriorityClassName == "system-node-critical",
+		priority:  lo.FromPtr(pod.Spec.Priority),
+		qos:       qosOrder[podutil.QOSClass(pod)],
 	}
 }
 
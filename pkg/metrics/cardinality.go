@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-core/pkg/apis/settings"
+)
+
+// families maps each collector that's subject to the cardinality settings to its metric family
+var families sync.Map
+
+type family struct {
+	name  string
+	ratio bool
+}
+
+// RegisterFamily registers the fully-qualified name of the collector's metric family (e.g. karpenter_pods_state) so
+// that it can be disabled through the metricsDisabled setting
+func RegisterFamily(name string, collector prometheus.Collector) {
+	families.Store(collector, family{name: name})
+}
+
+// RegisterRatioFamily registers a gauge whose values are ratios, like percentages. Summing its series would be
+// meaningless, so its labels are never dropped.
+func RegisterRatioFamily(name string, collector prometheus.Collector) {
+	families.Store(collector, family{name: name, ratio: true})
+}
+
+func familyOf(collector prometheus.Collector) family {
+	if f, ok := families.Load(collector); ok {
+		return f.(family)
+	}
+	return family{}
+}
+
+// Enabled returns whether the metric family of the collector hasn't been disabled through the metricsDisabled setting
+func Enabled(ctx context.Context, collector prometheus.Collector) bool {
+	name := familyOf(collector).name
+	return name == "" || !lo.Contains(settings.FromContext(ctx).MetricsDisabled, name)
+}
+
+// WithCardinalityControls applies the metric cardinality settings to the metrics before they're written to a Store.
+// Metrics of disabled families are removed and the values of dropped and hashed labels are rewritten. The returned
+// metrics are copies, so the passed metrics aren't modified. Labels of ratio families aren't dropped.
+func WithCardinalityControls(ctx context.Context, storeMetrics []*StoreMetric) []*StoreMetric {
+	s := settings.FromContext(ctx)
+	if len(s.MetricsDisabled) == 0 && len(s.MetricsDroppedLabels) == 0 && len(s.MetricsHashedLabels) == 0 {
+		return storeMetrics
+	}
+	var res []*StoreMetric
+	for _, m := range storeMetrics {
+		if !Enabled(ctx, m.GaugeVec) {
+			continue
+		}
+		ratio := familyOf(m.GaugeVec).ratio
+		labels := prometheus.Labels{}
+		for k, v := range m.Labels {
+			switch {
+			case lo.Contains(s.MetricsDroppedLabels, k) && !ratio:
+				labels[k] = ""
+			case lo.Contains(s.MetricsHashedLabels, k) && v != "":
+				labels[k] = hash(v)
+			default:
+				labels[k] = v
+			}
+		}
+		res = append(res, &StoreMetric{GaugeVec: m.GaugeVec, Value: m.Value, Labels: labels})
+	}
+	return res
+}
+
+func hash(value string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(value))
+	return fmt.Sprintf("%x", h.Sum64())
+}
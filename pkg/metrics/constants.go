@@ -28,6 +28,9 @@ const (
 	NodePoolLabel    = "nodepool"
 	ReasonLabel      = "reason"
 	TypeLabel        = "type"
+	PhaseLabel       = "phase"
+
+	InstanceTypeLabel = "instance_type"
 
 	// Reasons for CREATE/DELETE shared metrics
 	ConsolidationReason = "consolidation"
@@ -35,6 +38,13 @@ const (
 	ExpirationReason    = "expiration"
 	EmptinessReason     = "emptiness"
 	DriftReason         = "drift"
+	ReplicasReason      = "replicas"
+	RepairReason        = "repair"
+
+	// Reasons for failed machine and nodeclaim metrics
+	RegistrationTimeoutReason   = "registration_timeout"
+	InitializationTimeoutReason = "initialization_timeout"
+	TerminatedExternallyReason  = "terminated_externally"
 )
 
 // DurationBuckets returns a []float64 of default threshold values for duration histograms.
@@ -61,6 +61,52 @@ var (
 			NodePoolLabel,
 		},
 	)
+	NodeClaimsLaunchRetriedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: nodeClaimSubsystem,
+			Name:      "launch_retried",
+			Help:      "Number of times launching a nodeclaim was retried in total by Karpenter. Labeled by the owning nodepool.",
+		},
+		[]string{
+			NodePoolLabel,
+		},
+	)
+	NodeClaimsLaunchFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: nodeClaimSubsystem,
+			Name:      "launch_failed",
+			Help:      "Number of nodeclaims that failed to launch in total by Karpenter, either with an error that isn't retried or after exhausting their retries. Labeled by the owning nodepool.",
+		},
+		[]string{
+			NodePoolLabel,
+		},
+	)
+	NodeClaimsFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: nodeClaimSubsystem,
+			Name:      "failed",
+			Help:      "Number of nodeclaims that failed after launching in total by Karpenter. Labeled by the reason the nodeclaim failed, the owning nodepool and the instance type. Launch failures are counted by karpenter_nodeclaims_launch_failed since no instance type is known for them.",
+		},
+		[]string{
+			ReasonLabel,
+			NodePoolLabel,
+			InstanceTypeLabel,
+		},
+	)
+	NodeClaimsForceDeletedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: nodeClaimSubsystem,
+			Name:      "force_deleted",
+			Help:      "Number of nodeclaims whose termination finalizer was removed after their instance failed to terminate within the termination timeout in total by Karpenter. Labeled by the owning nodepool.",
+		},
+		[]string{
+			NodePoolLabel,
+		},
+	)
 	NodeClaimsRegisteredCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: Namespace,
@@ -107,6 +153,20 @@ var (
 			NodePoolLabel,
 		},
 	)
+	NodeClaimsPhaseDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: nodeClaimSubsystem,
+			Name:      "phase_duration_seconds",
+			Help:      "Duration of the nodeclaim lifecycle phases in seconds. Labeled by the phase that completed (launched, registered, initialized or terminated), the owning nodepool and the instance type.",
+			Buckets:   DurationBuckets(),
+		},
+		[]string{
+			PhaseLabel,
+			NodePoolLabel,
+			InstanceTypeLabel,
+		},
+	)
 	NodesCreatedCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: Namespace,
@@ -167,6 +227,52 @@ var (
 			ProvisionerLabel,
 		},
 	)
+	MachinesLaunchRetriedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: machineSubsystem,
+			Name:      "launch_retried",
+			Help:      "Number of times launching a machine was retried in total by Karpenter. Labeled by the owning provisioner.",
+		},
+		[]string{
+			ProvisionerLabel,
+		},
+	)
+	MachinesLaunchFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: machineSubsystem,
+			Name:      "launch_failed",
+			Help:      "Number of machines that failed to launch in total by Karpenter, either with an error that isn't retried or after exhausting their retries. Labeled by the owning provisioner.",
+		},
+		[]string{
+			ProvisionerLabel,
+		},
+	)
+	MachinesFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: machineSubsystem,
+			Name:      "failed",
+			Help:      "Number of machines that failed after launching in total by Karpenter. Labeled by the reason the machine failed, the owning provisioner and the instance type. Launch failures are counted by karpenter_machines_launch_failed since no instance type is known for them.",
+		},
+		[]string{
+			ReasonLabel,
+			ProvisionerLabel,
+			InstanceTypeLabel,
+		},
+	)
+	MachinesForceDeletedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: machineSubsystem,
+			Name:      "force_deleted",
+			Help:      "Number of machines whose termination finalizer was removed after their instance failed to terminate within the termination timeout in total by Karpenter. Labeled by the owning provisioner.",
+		},
+		[]string{
+			ProvisionerLabel,
+		},
+	)
 	MachinesRegisteredCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: Namespace,
@@ -213,11 +319,27 @@ var (
 			ProvisionerLabel,
 		},
 	)
+	MachinesPhaseDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: machineSubsystem,
+			Name:      "phase_duration_seconds",
+			Help:      "Duration of the machine lifecycle phases in seconds. Labeled by the phase that completed (launched, registered, initialized or terminated), the owning provisioner and the instance type.",
+			Buckets:   DurationBuckets(),
+		},
+		[]string{
+			PhaseLabel,
+			ProvisionerLabel,
+			InstanceTypeLabel,
+		},
+	)
 )
 
 func init() {
 	crmetrics.Registry.MustRegister(NodeClaimsCreatedCounter, NodeClaimsTerminatedCounter, NodeClaimsLaunchedCounter,
-		NodeClaimsRegisteredCounter, NodeClaimsInitializedCounter, NodeClaimsDisruptedCounter, NodeClaimsDriftedCounter,
-		MachinesCreatedCounter, MachinesTerminatedCounter, MachinesLaunchedCounter, MachinesRegisteredCounter, MachinesInitializedCounter,
-		MachinesDisruptedCounter, MachinesDriftedCounter, NodesCreatedCounter, NodesTerminatedCounter)
+		NodeClaimsLaunchRetriedCounter, NodeClaimsLaunchFailedCounter, NodeClaimsFailedCounter, NodeClaimsForceDeletedCounter, NodeClaimsRegisteredCounter, NodeClaimsInitializedCounter,
+		NodeClaimsDisruptedCounter, NodeClaimsDriftedCounter, MachinesCreatedCounter, MachinesTerminatedCounter, MachinesLaunchedCounter,
+		MachinesLaunchRetriedCounter, MachinesLaunchFailedCounter, MachinesFailedCounter, MachinesForceDeletedCounter, MachinesRegisteredCounter, MachinesInitializedCounter,
+		MachinesDisruptedCounter, MachinesDriftedCounter, NodesCreatedCounter, NodesTerminatedCounter, NodeClaimsPhaseDurationHistogram,
+		MachinesPhaseDurationHistogram)
 }
@@ -18,22 +18,24 @@ import (
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/samber/lo"
-	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Store is a mapping from a key to a list of Metrics
 // Each time Update() is called for a key on Store, the metric store ensures that all metrics are "refreshed"
 // for all currently tracked metrics assigned to the key. This means that any metric that contains the same labels
 // as a previous metric will be updated through the standard prometheus.Gauge metric Set() call while any metric with
-// different labels than the recently fired metrics will be removed from the prometheus client response and the Store
+// different labels than the recently fired metrics will be removed from the prometheus client response and the Store.
+// Metrics with the same labels that are assigned to different keys (e.g. once a high-cardinality label is dropped)
+// are summed into a single series.
 type Store struct {
 	sync.Mutex
-	store map[string][]*StoreMetric
+	store  map[string][]*StoreMetric
+	series map[seriesKey]*seriesValue
 }
 
 func NewStore() *Store {
-	return &Store{store: map[string][]*StoreMetric{}}
+	return &Store{store: map[string][]*StoreMetric{}, series: map[seriesKey]*seriesValue{}}
 }
 
 // StoreMetric is a single state metric associated with a prometheus.GaugeVec
@@ -43,22 +45,66 @@ type StoreMetric struct {
 	Labels prometheus.Labels
 }
 
+// seriesKey identifies a single series of a prometheus.GaugeVec
+type seriesKey struct {
+	gaugeVec *prometheus.GaugeVec
+	labels   string
+}
+
+// seriesValue is the sum of the values that all keys in the Store contribute to a series
+type seriesValue struct {
+	value float64
+	refs  int
+}
+
+func (m *StoreMetric) seriesKey() seriesKey {
+	return seriesKey{gaugeVec: m.GaugeVec, labels: labels.Set(m.Labels).String()}
+}
+
+func (s *Store) add(metric *StoreMetric) {
+	k := metric.seriesKey()
+	if _, ok := s.series[k]; !ok {
+		s.series[k] = &seriesValue{}
+	}
+	s.series[k].value += metric.Value
+	s.series[k].refs++
+}
+
+func (s *Store) remove(metric *StoreMetric) {
+	k := metric.seriesKey()
+	if series, ok := s.series[k]; ok {
+		series.value -= metric.Value
+		if series.refs--; series.refs <= 0 {
+			delete(s.series, k)
+		}
+	}
+}
+
+// sync sets the series of the passed metrics to the sum of their contributions, removing series that no key
+// contributes to anymore
+func (s *Store) sync(metrics map[seriesKey]*StoreMetric) {
+	for k, metric := range metrics {
+		if series, ok := s.series[k]; ok {
+			metric.With(metric.Labels).Set(series.value)
+		} else {
+			metric.Delete(metric.Labels)
+		}
+	}
+}
+
 // update is an internal non-thread-safe method for updating metrics given a key in the Store
 func (s *Store) update(key string, metrics []*StoreMetric) {
-	for _, metric := range metrics {
-		metric.With(metric.Labels).Set(metric.Value)
+	affected := map[seriesKey]*StoreMetric{}
+	for _, oldMetric := range s.store[key] {
+		s.remove(oldMetric)
+		affected[oldMetric.seriesKey()] = oldMetric
 	}
-	// Cleanup old metrics if the old metric family has metrics that weren't updated by this round of metrics
-	if oldMetrics, ok := s.store[key]; ok {
-		for _, oldMetric := range oldMetrics {
-			if _, ok = lo.Find(metrics, func(m *StoreMetric) bool {
-				return oldMetric.GaugeVec == m.GaugeVec && equality.Semantic.DeepEqual(oldMetric.Labels, m.Labels)
-			}); !ok {
-				oldMetric.Delete(oldMetric.Labels)
-			}
-		}
+	for _, metric := range metrics {
+		s.add(metric)
+		affected[metric.seriesKey()] = metric
 	}
 	s.store[key] = metrics
+	s.sync(affected)
 }
 
 // Update calls the update() method internally
@@ -89,10 +135,13 @@ func (s *Store) ReplaceAll(newStore map[string][]*StoreMetric) {
 // delete is an internal non-thread-safe method for deleting metrics given a key in the Store
 func (s *Store) delete(key string) {
 	if metrics, ok := s.store[key]; ok {
+		affected := map[seriesKey]*StoreMetric{}
 		for _, metric := range metrics {
-			metric.Delete(metric.Labels)
+			s.remove(metric)
+			affected[metric.seriesKey()] = metric
 		}
 		delete(s.store, key)
+		s.sync(affected)
 	}
 }
 
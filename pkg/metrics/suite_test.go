@@ -15,6 +15,7 @@ limitations under the License.
 package metrics_test
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -24,11 +25,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/metrics"
+	"github.com/aws/karpenter-core/pkg/test"
 	. "github.com/aws/karpenter-core/pkg/test/expectations"
 )
 
-var testGauge1, testGauge2 *prometheus.GaugeVec
+var testGauge1, testGauge2, testRatioGauge *prometheus.GaugeVec
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -38,7 +41,11 @@ func TestAPIs(t *testing.T) {
 var _ = BeforeSuite(func() {
 	testGauge1 = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge_1"}, []string{"label_1", "label_2"})
 	testGauge2 = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge_2"}, []string{"label_1", "label_2"})
-	crmetrics.Registry.MustRegister(testGauge1, testGauge2)
+	testRatioGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_ratio_gauge"}, []string{"label_1", "label_2"})
+	crmetrics.Registry.MustRegister(testGauge1, testGauge2, testRatioGauge)
+	metrics.RegisterFamily("test_gauge_1", testGauge1)
+	metrics.RegisterFamily("test_gauge_2", testGauge2)
+	metrics.RegisterRatioFamily("test_ratio_gauge", testRatioGauge)
 })
 
 var _ = Describe("Store", func() {
@@ -196,6 +203,25 @@ var _ = Describe("Store", func() {
 			Expect(ok).To(BeTrue())
 			Expect(lo.FromPtr(m.Gauge.Value)).To(BeNumerically("==", newStoreMetrics[1].Value))
 		})
+		It("should sum metrics with the same labels across keys", func() {
+			otherKey := client.ObjectKey{Namespace: "default", Name: "other"}
+			labels := prometheus.Labels{"label_1": "shared", "label_2": "shared"}
+			ms.Update(key.String(), []*metrics.StoreMetric{{GaugeVec: testGauge1, Value: 1, Labels: labels}})
+			ms.Update(otherKey.String(), []*metrics.StoreMetric{{GaugeVec: testGauge1, Value: 2, Labels: labels}})
+
+			m, ok := FindMetricWithLabelValues("test_gauge_1", labels)
+			Expect(ok).To(BeTrue())
+			Expect(lo.FromPtr(m.Gauge.Value)).To(BeNumerically("==", 3))
+
+			ms.Delete(key.String())
+			m, ok = FindMetricWithLabelValues("test_gauge_1", labels)
+			Expect(ok).To(BeTrue())
+			Expect(lo.FromPtr(m.Gauge.Value)).To(BeNumerically("==", 2))
+
+			ms.Delete(otherKey.String())
+			_, ok = FindMetricWithLabelValues("test_gauge_1", labels)
+			Expect(ok).To(BeFalse())
+		})
 	})
 	Context("Delete", func() {
 		It("should delete metrics by key", func() {
@@ -363,3 +389,38 @@ var _ = Describe("Store", func() {
 		})
 	})
 })
+
+var _ = Describe("Cardinality Controls", func() {
+	var storeMetrics []*metrics.StoreMetric
+
+	BeforeEach(func() {
+		storeMetrics = []*metrics.StoreMetric{
+			{GaugeVec: testGauge1, Value: 1, Labels: prometheus.Labels{"label_1": "test", "label_2": "test"}},
+			{GaugeVec: testGauge2, Value: 1, Labels: prometheus.Labels{"label_1": "test", "label_2": "test"}},
+			{GaugeVec: testRatioGauge, Value: 50, Labels: prometheus.Labels{"label_1": "test", "label_2": "test"}},
+		}
+	})
+	It("should remove the metrics of disabled families", func() {
+		ctx := settings.ToContext(context.Background(), test.Settings(settings.Settings{MetricsDisabled: []string{"test_gauge_2"}}))
+		res := metrics.WithCardinalityControls(ctx, storeMetrics)
+		Expect(res).To(HaveLen(2))
+		Expect(lo.Map(res, func(m *metrics.StoreMetric, _ int) *prometheus.GaugeVec { return m.GaugeVec })).ToNot(ContainElement(testGauge2))
+		Expect(metrics.Enabled(ctx, testGauge1)).To(BeTrue())
+		Expect(metrics.Enabled(ctx, testGauge2)).To(BeFalse())
+	})
+	It("should drop and hash label values", func() {
+		ctx := settings.ToContext(context.Background(), test.Settings(settings.Settings{MetricsDroppedLabels: []string{"label_1"}, MetricsHashedLabels: []string{"label_2"}}))
+		res := metrics.WithCardinalityControls(ctx, storeMetrics)
+		Expect(res[0].Labels["label_1"]).To(BeEmpty())
+		Expect(res[0].Labels["label_2"]).ToNot(BeEmpty())
+		Expect(res[0].Labels["label_2"]).ToNot(Equal("test"))
+		// The passed metrics aren't modified
+		Expect(storeMetrics[0].Labels["label_1"]).To(Equal("test"))
+	})
+	It("should not drop the labels of ratio families", func() {
+		ctx := settings.ToContext(context.Background(), test.Settings(settings.Settings{MetricsDroppedLabels: []string{"label_1"}}))
+		res := metrics.WithCardinalityControls(ctx, storeMetrics)
+		Expect(res[2].GaugeVec).To(Equal(testRatioGauge))
+		Expect(res[2].Labels["label_1"]).To(Equal("test"))
+	})
+})
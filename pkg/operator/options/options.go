@@ -37,6 +37,9 @@ type Options struct {
 	EnableProfiling      bool
 	EnableLeaderElection bool
 	MemoryLimit          int64
+	// EnableProvisioningDryRun serves provisioning dry runs on the metric endpoint. The endpoint isn't authenticated,
+	// so access to it should be restricted (e.g. with a NetworkPolicy) when this is enabled.
+	EnableProvisioningDryRun bool
 }
 
 // New creates an Options struct and registers CLI flags and environment variables to fill-in the Options struct fields
@@ -55,6 +58,7 @@ func New() *Options {
 	f.IntVar(&opts.KubeClientBurst, "kube-client-burst", env.WithDefaultInt("KUBE_CLIENT_BURST", 300), "The maximum allowed burst of queries to the kube-apiserver")
 	f.BoolVar(&opts.EnableProfiling, "enable-profiling", env.WithDefaultBool("ENABLE_PROFILING", false), "Enable the profiling on the metric endpoint")
 	f.BoolVar(&opts.EnableLeaderElection, "leader-elect", env.WithDefaultBool("LEADER_ELECT", true), "Start leader election client and gain leadership before executing the main loop. Enable this when running replicated components for high availability.")
+	f.BoolVar(&opts.EnableProvisioningDryRun, "enable-provisioning-dry-run", env.WithDefaultBool("ENABLE_PROVISIONING_DRY_RUN", false), "Serve the pods that would schedule to new and existing nodes for a list of pods POSTed to /provisioning/dry-run on the metric endpoint. The metric endpoint is unauthenticated, so restrict access to it before enabling this.")
 	f.Int64Var(&opts.MemoryLimit, "memory-limit", env.WithDefaultInt64("MEMORY_LIMIT", -1), "Memory limit on the container running the controller. The GC soft memory limit is set to 90% of this value.")
 
 	if opts.MemoryLimit > 0 {
@@ -101,10 +101,15 @@ func GetHostPorts(pod *v1.Pod) []HostPort {
 			if hostIP == "" {
 				hostIP = "0.0.0.0"
 			}
+			// Pods that haven't been defaulted by the API server (e.g. daemonset templates) may not have a protocol set
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = v1.ProtocolTCP
+			}
 			usage = append(usage, HostPort{
 				IP:       net.ParseIP(hostIP),
 				Port:     p.HostPort,
-				Protocol: p.Protocol,
+				Protocol: protocol,
 			})
 		}
 	}
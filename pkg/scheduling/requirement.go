@@ -121,6 +121,28 @@ func (r *Requirement) NodeSelectorRequirement() v1.NodeSelectorRequirement {
 	}
 }
 
+// nodeSelectorRequirements returns the requirement as a list of v1.NodeSelectorRequirements. A v1.NodeSelectorRequirement
+// can only express a single bound, so a requirement that is bounded on both sides or that excludes values within its
+// bounds needs more than one v1.NodeSelectorRequirement to be represented.
+func (r *Requirement) nodeSelectorRequirements() []v1.NodeSelectorRequirement {
+	requirements := []v1.NodeSelectorRequirement{r.NodeSelectorRequirement()}
+	if r.greaterThan != nil && r.lessThan != nil {
+		requirements = append(requirements, v1.NodeSelectorRequirement{
+			Key:      r.Key,
+			Operator: v1.NodeSelectorOpLt,
+			Values:   []string{strconv.FormatInt(int64(lo.FromPtr(r.lessThan)), 10)},
+		})
+	}
+	if (r.greaterThan != nil || r.lessThan != nil) && r.complement && len(r.values) > 0 {
+		requirements = append(requirements, v1.NodeSelectorRequirement{
+			Key:      r.Key,
+			Operator: v1.NodeSelectorOpNotIn,
+			Values:   sets.List(r.values),
+		})
+	}
+	return requirements
+}
+
 // Intersection constraints the Requirement from the incoming requirements
 // nolint:gocyclo
 func (r *Requirement) Intersection(requirement *Requirement) *Requirement {
@@ -170,6 +192,10 @@ func (r *Requirement) Any() string {
 		}
 		if r.lessThan != nil {
 			max = *r.lessThan
+			// negative upper bounds without a lower bound would otherwise leave an empty range
+			if r.greaterThan == nil && max <= min {
+				min = max - 1
+			}
 		}
 		return fmt.Sprint(rand.Intn(max-min) + min) //nolint:gosec
 	}
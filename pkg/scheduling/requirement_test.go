@@ -421,6 +421,7 @@ var _ = Describe("Requirement", func() {
 			Expect(strconv.Atoi(greaterThan9.Any())).To(And(BeNumerically(">=", 9), BeNumerically("<", math.MaxInt64)))
 			Expect(lessThan1.Any()).To(Equal("0"))
 			Expect(strconv.Atoi(lessThan9.Any())).To(And(BeNumerically(">=", 0), BeNumerically("<", 9)))
+			Expect(NewRequirement("key", v1.NodeSelectorOpLt, "-5").Any()).To(Equal("-6"))
 		})
 	})
 	Context("String", func() {
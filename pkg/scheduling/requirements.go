@@ -106,8 +106,8 @@ func HasPreferredNodeAffinity(p *v1.Pod) bool {
 }
 
 func (r Requirements) NodeSelectorRequirements() []v1.NodeSelectorRequirement {
-	return lo.Map(lo.Values(r), func(req *Requirement, _ int) v1.NodeSelectorRequirement {
-		return req.NodeSelectorRequirement()
+	return lo.FlatMap(lo.Values(r), func(req *Requirement, _ int) []v1.NodeSelectorRequirement {
+		return req.nodeSelectorRequirements()
 	})
 }
 
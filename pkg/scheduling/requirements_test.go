@@ -650,6 +650,20 @@ var _ = Describe("Requirements", func() {
 			))
 			Expect(reqs.NodeSelectorRequirements()).To(HaveLen(14))
 		})
+		It("should convert bounded requirements to multiple NodeSelectorRequirements", func() {
+			reqs := NewRequirements(
+				NewRequirement("key", v1.NodeSelectorOpGt, "1"),
+				NewRequirement("key", v1.NodeSelectorOpLt, "9"),
+				NewRequirement("key", v1.NodeSelectorOpNotIn, "5"),
+			)
+			Expect(reqs.NodeSelectorRequirements()).To(ConsistOf(
+				v1.NodeSelectorRequirement{Key: "key", Operator: v1.NodeSelectorOpGt, Values: []string{"1"}},
+				v1.NodeSelectorRequirement{Key: "key", Operator: v1.NodeSelectorOpLt, Values: []string{"9"}},
+				v1.NodeSelectorRequirement{Key: "key", Operator: v1.NodeSelectorOpNotIn, Values: []string{"5"}},
+			))
+			// converting back should produce the same requirements
+			Expect(NewNodeSelectorRequirements(reqs.NodeSelectorRequirements()...).Get("key").String()).To(Equal(reqs.Get("key").String()))
+		})
 	})
 	Context("Stringify Requirements", func() {
 		It("should print Requirements in the same order", func() {
@@ -24,7 +24,6 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	csitranslation "k8s.io/csi-translation-lib"
-	"k8s.io/csi-translation-lib/plugins"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -165,8 +164,14 @@ func driverFromVolume(ctx context.Context, kubeClient client.Client, volumeName
 	}
 	if pv.Spec.CSI != nil {
 		return pv.Spec.CSI.Driver, nil
-	} else if pv.Spec.AWSElasticBlockStore != nil {
-		return plugins.AWSEBSDriverName, nil
+	}
+	// In-tree volume sources are tracked by the name of the CSI driver that they've been migrated to
+	pluginName, err := translator.GetInTreePluginNameFromSpec(&pv, nil)
+	if err != nil {
+		return "", nil
+	}
+	if csiName, err := translator.GetCSINameFromInTreeName(pluginName); err == nil {
+		return csiName, nil
 	}
 	return "", nil
 }
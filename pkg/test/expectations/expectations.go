@@ -212,6 +212,8 @@ func ExpectCleanedUp(ctx context.Context, c client.Client) {
 		&v1.PersistentVolumeClaim{},
 		&v1.PersistentVolume{},
 		&storagev1.StorageClass{},
+		&storagev1.CSIDriver{},
+		&storagev1.CSIStorageCapacity{},
 		&v1alpha5.Provisioner{},
 		&v1alpha5.Machine{},
 		&v1beta1.NodePool{},
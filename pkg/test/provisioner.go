@@ -34,6 +34,7 @@ type ProvisionerOptions struct {
 	Limits                 v1.ResourceList
 	Provider               interface{}
 	ProviderRef            *v1alpha5.MachineTemplateRef
+	CloudProvider          string
 	Kubelet                *v1alpha5.KubeletConfiguration
 	Annotations            map[string]string
 	Labels                 map[string]string
@@ -71,6 +72,7 @@ func Provisioner(overrides ...ProvisionerOptions) *v1alpha5.Provisioner {
 			Requirements:           options.Requirements,
 			KubeletConfiguration:   options.Kubelet,
 			ProviderRef:            options.ProviderRef,
+			CloudProvider:          options.CloudProvider,
 			Taints:                 options.Taints,
 			StartupTaints:          options.StartupTaints,
 			Annotations:            options.Annotations,
@@ -36,9 +36,110 @@ func Settings(overrides ...settings.Settings) *settings.Settings {
 	if options.BatchIdleDuration == 0 {
 		options.BatchIdleDuration = time.Second
 	}
+	if options.DeprovisioningOrder == nil {
+		options.DeprovisioningOrder = settings.DeprovisioningMethods
+	}
+	if options.SpotToSpotConsolidationMinFlexibility == 0 {
+		options.SpotToSpotConsolidationMinFlexibility = 15
+	}
+	if options.MultiMachineConsolidationMaxMachines == 0 {
+		options.MultiMachineConsolidationMaxMachines = 100
+	}
+	if options.EvictionBurst == 0 {
+		options.EvictionBurst = 10
+	}
+	if options.EvictionRetryBaseDelay == 0 {
+		options.EvictionRetryBaseDelay = time.Millisecond * 100
+	}
+	if options.EvictionRetryMaxDelay == 0 {
+		options.EvictionRetryMaxDelay = time.Second * 10
+	}
+	if options.TerminationHookTimeout == 0 {
+		options.TerminationHookTimeout = time.Second * 30
+	}
+	if options.TerminationHookFailurePolicy == "" {
+		options.TerminationHookFailurePolicy = settings.TerminationHookFailurePolicyIgnore
+	}
+	if options.DeprovisioningWebhookTimeout == 0 {
+		options.DeprovisioningWebhookTimeout = time.Second * 10
+	}
+	if options.MultiMachineConsolidationTimeout == 0 {
+		options.MultiMachineConsolidationTimeout = time.Minute
+	}
+	if options.DeprovisioningMaxParallelActions == 0 {
+		options.DeprovisioningMaxParallelActions = 1
+	}
+	if options.LaunchRetryLimit == 0 {
+		options.LaunchRetryLimit = 5
+	}
+	if options.LaunchRetryBaseDelay == 0 {
+		options.LaunchRetryBaseDelay = time.Second * 5
+	}
+	if options.RegistrationTTL == 0 {
+		options.RegistrationTTL = time.Minute * 15
+	}
+	if options.NodeRepairMaxUnhealthyPercentage == 0 {
+		options.NodeRepairMaxUnhealthyPercentage = 20
+	}
+	if options.GarbageCollectionInterval == 0 {
+		options.GarbageCollectionInterval = time.Minute * 2
+	}
+	if options.GarbageCollectionMinimumAge == 0 {
+		options.GarbageCollectionMinimumAge = time.Second * 10
+	}
 	return &settings.Settings{
 		BatchMaxDuration:  options.BatchMaxDuration,
 		BatchIdleDuration: options.BatchIdleDuration,
 		DriftEnabled:      options.DriftEnabled,
+
+		KubeletVersionSkewLimit: options.KubeletVersionSkewLimit,
+		DriftRateLimitNodes:     options.DriftRateLimitNodes,
+		DriftRateLimitInterval:  options.DriftRateLimitInterval,
+		DeprovisioningOrder:     options.DeprovisioningOrder,
+
+		ConsolidationMinSavingsPercent: options.ConsolidationMinSavingsPercent,
+		ConsolidationMinSavingsPerHour: options.ConsolidationMinSavingsPerHour,
+
+		ConsolidationUtilizationThreshold: options.ConsolidationUtilizationThreshold,
+
+		SpotToSpotConsolidation:               options.SpotToSpotConsolidation,
+		SpotToSpotConsolidationMinFlexibility: options.SpotToSpotConsolidationMinFlexibility,
+
+		MultiMachineConsolidationMaxMachines:    options.MultiMachineConsolidationMaxMachines,
+		MultiMachineConsolidationTimeout:        options.MultiMachineConsolidationTimeout,
+		DeprovisioningMaxParallelActions:        options.DeprovisioningMaxParallelActions,
+		DeprovisioningDryRun:                    options.DeprovisioningDryRun,
+		DeprovisioningExcludedNodeSelector:      options.DeprovisioningExcludedNodeSelector,
+		DisruptionRateLimitNodes:                options.DisruptionRateLimitNodes,
+		DisruptionRateLimitInterval:             options.DisruptionRateLimitInterval,
+		DeprovisioningWebhookURL:                options.DeprovisioningWebhookURL,
+		DeprovisioningWebhookTimeout:            options.DeprovisioningWebhookTimeout,
+		DrainDaemonSetPods:                      options.DrainDaemonSetPods,
+		TerminationHookTimeout:                  options.TerminationHookTimeout,
+		TerminationHookFailurePolicy:            options.TerminationHookFailurePolicy,
+		OutOfServiceTaintEnabled:                options.OutOfServiceTaintEnabled,
+		EvictionQPS:                             options.EvictionQPS,
+		EvictionBurst:                           options.EvictionBurst,
+		EvictionRetryBaseDelay:                  options.EvictionRetryBaseDelay,
+		EvictionRetryMaxDelay:                   options.EvictionRetryMaxDelay,
+		InterruptionRebalanceReplacementEnabled: options.InterruptionRebalanceReplacementEnabled,
+		PreemptionAwareProvisioning:             options.PreemptionAwareProvisioning,
+		NominationTTL:                           options.NominationTTL,
+		LaunchRetryLimit:                        options.LaunchRetryLimit,
+		LaunchRetryBaseDelay:                    options.LaunchRetryBaseDelay,
+		RegistrationTTL:                         options.RegistrationTTL,
+		InitializationRequiredResources:         options.InitializationRequiredResources,
+		InitializationIgnoredResources:          options.InitializationIgnoredResources,
+		MachineTerminationTimeout:               options.MachineTerminationTimeout,
+		NodeRepairUnhealthyDuration:             options.NodeRepairUnhealthyDuration,
+		NodeRepairConditions:                    options.NodeRepairConditions,
+		NodeRepairMaxUnhealthyPercentage:        options.NodeRepairMaxUnhealthyPercentage,
+		GarbageCollectionInterval:               options.GarbageCollectionInterval,
+		GarbageCollectionBatchSize:              options.GarbageCollectionBatchSize,
+		GarbageCollectionMinimumAge:             options.GarbageCollectionMinimumAge,
+		LeakedInstanceGracePeriod:               options.LeakedInstanceGracePeriod,
+		MetricsDroppedLabels:                    options.MetricsDroppedLabels,
+		MetricsHashedLabels:                     options.MetricsHashedLabels,
+		MetricsDisabled:                         options.MetricsDisabled,
 	}
 }
@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/aws/karpenter-core/pkg/utils/cron"
+)
+
+func TestCron(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cron Suite")
+}
+
+var _ = Describe("Cron", func() {
+	// Wednesday
+	now := time.Date(2023, time.August, 16, 10, 30, 0, 0, time.UTC)
+
+	Context("Parse", func() {
+		It("should parse valid schedules", func() {
+			for _, spec := range []string{"* * * * *", "0 0 * * *", "*/15 1-5 1,15 * 1-5", "@daily", "@weekly", "0 0 * * 7"} {
+				_, err := cron.Parse(spec)
+				Expect(err).ToNot(HaveOccurred(), spec)
+			}
+		})
+		It("should fail to parse invalid schedules", func() {
+			for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@sometimes"} {
+				_, err := cron.Parse(spec)
+				Expect(err).To(HaveOccurred(), spec)
+			}
+		})
+	})
+	Context("Next", func() {
+		It("should return the next activation", func() {
+			s, err := cron.Parse("0 2 * * *")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Next(now)).To(Equal(time.Date(2023, time.August, 17, 2, 0, 0, 0, time.UTC)))
+		})
+		It("should be strictly after the given time", func() {
+			s, err := cron.Parse("30 10 * * *")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Next(now)).To(Equal(time.Date(2023, time.August, 17, 10, 30, 0, 0, time.UTC)))
+		})
+		It("should match either day field when both are restricted", func() {
+			// the 20th or any Friday
+			s, err := cron.Parse("0 0 20 * 5")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Next(now)).To(Equal(time.Date(2023, time.August, 18, 0, 0, 0, 0, time.UTC)))
+		})
+		It("should roll over months and years", func() {
+			s, err := cron.Parse("@yearly")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Next(now)).To(Equal(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)))
+		})
+		It("should return the zero time for schedules that never activate", func() {
+			s, err := cron.Parse("0 0 30 2 *")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Next(now).IsZero()).To(BeTrue())
+		})
+	})
+	Context("Active", func() {
+		It("should be active within the window", func() {
+			s, err := cron.Parse("0 10 * * *")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Active(now, time.Hour)).To(BeTrue())
+			Expect(s.Active(now, 30*time.Minute)).To(BeFalse())
+			Expect(s.Active(now, 10*time.Minute)).To(BeFalse())
+		})
+		It("should be active at the start of the window", func() {
+			s, err := cron.Parse("30 10 * * *")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Active(now, time.Minute)).To(BeTrue())
+		})
+	})
+})
@@ -187,7 +187,9 @@ func New(node *v1.Node, provisioner *v1alpha5.Provisioner) *v1alpha5.Machine {
 	machine.Spec.Taints = provisioner.Spec.Taints
 	machine.Spec.StartupTaints = provisioner.Spec.StartupTaints
 	machine.Spec.Requirements = provisioner.Spec.Requirements
+	machine.Spec.Preferences = provisioner.Spec.Preferences
 	machine.Spec.MachineTemplateRef = provisioner.Spec.ProviderRef
+	machine.Spec.CloudProvider = provisioner.Spec.CloudProvider
 	return machine
 }
 
@@ -231,18 +233,25 @@ func NewFromNodeClaim(nodeClaim *v1beta1.NodeClaim) *v1alpha5.Machine {
 			Taints:        nodeClaim.Spec.Taints,
 			StartupTaints: nodeClaim.Spec.StartupTaints,
 			Requirements:  nodeClaim.Spec.Requirements,
+			Preferences:   nodeClaim.Spec.Preferences,
 			Resources: v1alpha5.ResourceRequirements{
 				Requests: nodeClaim.Spec.Resources.Requests,
 			},
 			Kubelet:            NewKubeletConfiguration(nodeClaim.Spec.KubeletConfiguration),
 			MachineTemplateRef: NewMachineTemplateRef(nodeClaim.Spec.NodeClass),
+			CloudProvider:      nodeClaim.Spec.CloudProvider,
 		},
 		Status: v1alpha5.MachineStatus{
-			NodeName:    nodeClaim.Status.NodeName,
-			ProviderID:  nodeClaim.Status.ProviderID,
-			Capacity:    nodeClaim.Status.Capacity,
-			Allocatable: nodeClaim.Status.Allocatable,
-			Conditions:  NewConditions(nodeClaim.Status.Conditions),
+			NodeName:       nodeClaim.Status.NodeName,
+			ProviderID:     nodeClaim.Status.ProviderID,
+			Capacity:       nodeClaim.Status.Capacity,
+			Allocatable:    nodeClaim.Status.Allocatable,
+			Conditions:     NewConditions(nodeClaim.Status.Conditions),
+			ExpirationTime: nodeClaim.Status.ExpirationTime,
+			InstanceType:   nodeClaim.Status.InstanceType,
+			Zone:           nodeClaim.Status.Zone,
+			CapacityType:   nodeClaim.Status.CapacityType,
+			Price:          nodeClaim.Status.Price,
 		},
 	}
 }
@@ -253,6 +262,8 @@ func NewConditions(conds apis.Conditions) apis.Conditions {
 		switch out[i].Type {
 		case v1beta1.NodeLaunched:
 			out[i].Type = v1alpha5.MachineLaunched
+		case v1beta1.NodeLaunchFailed:
+			out[i].Type = v1alpha5.MachineLaunchFailed
 		case v1beta1.NodeRegistered:
 			out[i].Type = v1alpha5.MachineRegistered
 		case v1beta1.NodeInitialized:
@@ -263,6 +274,8 @@ func NewConditions(conds apis.Conditions) apis.Conditions {
 			out[i].Type = v1alpha5.MachineExpired
 		case v1beta1.NodeDrifted:
 			out[i].Type = v1alpha5.MachineDrifted
+		case v1beta1.NodeUnhealthy:
+			out[i].Type = v1alpha5.MachineUnhealthy
 		}
 	}
 	return out
@@ -18,7 +18,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/patrickmn/go-cache"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
@@ -27,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -189,18 +192,25 @@ func New(machine *v1alpha5.Machine) *v1beta1.NodeClaim {
 			Taints:        machine.Spec.Taints,
 			StartupTaints: machine.Spec.StartupTaints,
 			Requirements:  machine.Spec.Requirements,
+			Preferences:   machine.Spec.Preferences,
 			Resources: v1beta1.ResourceRequirements{
 				Requests: machine.Spec.Resources.Requests,
 			},
 			KubeletConfiguration: NewKubeletConfiguration(machine.Spec.Kubelet),
 			NodeClass:            NewNodeClassReference(machine.Spec.MachineTemplateRef),
+			CloudProvider:        machine.Spec.CloudProvider,
 		},
 		Status: v1beta1.NodeClaimStatus{
-			NodeName:    machine.Status.NodeName,
-			ProviderID:  machine.Status.ProviderID,
-			Capacity:    machine.Status.Capacity,
-			Allocatable: machine.Status.Allocatable,
-			Conditions:  NewConditions(machine.Status.Conditions),
+			NodeName:       machine.Status.NodeName,
+			ProviderID:     machine.Status.ProviderID,
+			Capacity:       machine.Status.Capacity,
+			Allocatable:    machine.Status.Allocatable,
+			Conditions:     NewConditions(machine.Status.Conditions),
+			ExpirationTime: machine.Status.ExpirationTime,
+			InstanceType:   machine.Status.InstanceType,
+			Zone:           machine.Status.Zone,
+			CapacityType:   machine.Status.CapacityType,
+			Price:          machine.Status.Price,
 		},
 		IsMachine: true,
 	}
@@ -212,6 +222,8 @@ func NewConditions(conds apis.Conditions) apis.Conditions {
 		switch out[i].Type {
 		case v1alpha5.MachineLaunched:
 			out[i].Type = v1beta1.NodeLaunched
+		case v1alpha5.MachineLaunchFailed:
+			out[i].Type = v1beta1.NodeLaunchFailed
 		case v1alpha5.MachineRegistered:
 			out[i].Type = v1beta1.NodeRegistered
 		case v1alpha5.MachineInitialized:
@@ -222,6 +234,8 @@ func NewConditions(conds apis.Conditions) apis.Conditions {
 			out[i].Type = v1beta1.NodeExpired
 		case v1alpha5.MachineDrifted:
 			out[i].Type = v1beta1.NodeDrifted
+		case v1alpha5.MachineUnhealthy:
+			out[i].Type = v1beta1.NodeUnhealthy
 		}
 	}
 	return out
@@ -355,6 +369,11 @@ func Delete(ctx context.Context, c client.Client, nodeClaim *v1beta1.NodeClaim)
 	return c.Delete(ctx, nodeClaim)
 }
 
+// Paused returns whether reconciliation of the NodeClaim has been suspended through the paused annotation
+func Paused(nodeClaim *v1beta1.NodeClaim) bool {
+	return nodeClaim.Annotations[lo.Ternary(nodeClaim.IsMachine, v1alpha5.MachinePausedAnnotationKey, v1beta1.NodeClaimPausedAnnotationKey)] == "true"
+}
+
 func CreatedCounter(nodeClaim *v1beta1.NodeClaim, reason string) prometheus.Counter {
 	if nodeClaim.IsMachine {
 		return metrics.MachinesCreatedCounter.With(prometheus.Labels{
@@ -379,6 +398,54 @@ func LaunchedCounter(nodeClaim *v1beta1.NodeClaim) prometheus.Counter {
 	})
 }
 
+func LaunchRetriedCounter(nodeClaim *v1beta1.NodeClaim) prometheus.Counter {
+	if nodeClaim.IsMachine {
+		return metrics.MachinesLaunchRetriedCounter.With(prometheus.Labels{
+			metrics.ProvisionerLabel: nodeClaim.Labels[v1alpha5.ProvisionerNameLabelKey],
+		})
+	}
+	return metrics.NodeClaimsLaunchRetriedCounter.With(prometheus.Labels{
+		metrics.NodePoolLabel: nodeClaim.Labels[v1beta1.NodePoolLabelKey],
+	})
+}
+
+func LaunchFailedCounter(nodeClaim *v1beta1.NodeClaim) prometheus.Counter {
+	if nodeClaim.IsMachine {
+		return metrics.MachinesLaunchFailedCounter.With(prometheus.Labels{
+			metrics.ProvisionerLabel: nodeClaim.Labels[v1alpha5.ProvisionerNameLabelKey],
+		})
+	}
+	return metrics.NodeClaimsLaunchFailedCounter.With(prometheus.Labels{
+		metrics.NodePoolLabel: nodeClaim.Labels[v1beta1.NodePoolLabelKey],
+	})
+}
+
+func FailedCounter(nodeClaim *v1beta1.NodeClaim, reason string) prometheus.Counter {
+	if nodeClaim.IsMachine {
+		return metrics.MachinesFailedCounter.With(prometheus.Labels{
+			metrics.ReasonLabel:       reason,
+			metrics.ProvisionerLabel:  nodeClaim.Labels[v1alpha5.ProvisionerNameLabelKey],
+			metrics.InstanceTypeLabel: nodeClaim.Labels[v1.LabelInstanceTypeStable],
+		})
+	}
+	return metrics.NodeClaimsFailedCounter.With(prometheus.Labels{
+		metrics.ReasonLabel:       reason,
+		metrics.NodePoolLabel:     nodeClaim.Labels[v1beta1.NodePoolLabelKey],
+		metrics.InstanceTypeLabel: nodeClaim.Labels[v1.LabelInstanceTypeStable],
+	})
+}
+
+func ForceDeletedCounter(nodeClaim *v1beta1.NodeClaim) prometheus.Counter {
+	if nodeClaim.IsMachine {
+		return metrics.MachinesForceDeletedCounter.With(prometheus.Labels{
+			metrics.ProvisionerLabel: nodeClaim.Labels[v1alpha5.ProvisionerNameLabelKey],
+		})
+	}
+	return metrics.NodeClaimsForceDeletedCounter.With(prometheus.Labels{
+		metrics.NodePoolLabel: nodeClaim.Labels[v1beta1.NodePoolLabelKey],
+	})
+}
+
 func RegisteredCounter(nodeClaim *v1beta1.NodeClaim) prometheus.Counter {
 	if nodeClaim.IsMachine {
 		return metrics.MachinesRegisteredCounter.With(prometheus.Labels{
@@ -440,6 +507,23 @@ func DriftedCounter(nodeClaim *v1beta1.NodeClaim, driftType string) prometheus.C
 	})
 }
 
+// PhaseDurationHistogram returns the histogram that records how long the NodeClaim took to complete the given
+// lifecycle phase, e.g. "launched" for the time from creation to launch
+func PhaseDurationHistogram(nodeClaim *v1beta1.NodeClaim, phase string) prometheus.Observer {
+	if nodeClaim.IsMachine {
+		return metrics.MachinesPhaseDurationHistogram.With(prometheus.Labels{
+			metrics.PhaseLabel:        phase,
+			metrics.ProvisionerLabel:  nodeClaim.Labels[v1alpha5.ProvisionerNameLabelKey],
+			metrics.InstanceTypeLabel: nodeClaim.Labels[v1.LabelInstanceTypeStable],
+		})
+	}
+	return metrics.NodeClaimsPhaseDurationHistogram.With(prometheus.Labels{
+		metrics.PhaseLabel:        phase,
+		metrics.NodePoolLabel:     nodeClaim.Labels[v1beta1.NodePoolLabelKey],
+		metrics.InstanceTypeLabel: nodeClaim.Labels[v1.LabelInstanceTypeStable],
+	})
+}
+
 func UpdateNodeOwnerReferences(nodeClaim *v1beta1.NodeClaim, node *v1.Node) *v1.Node {
 	// Remove any provisioner owner references since we own them
 	node.OwnerReferences = lo.Reject(node.OwnerReferences, func(o metav1.OwnerReference, _ int) bool {
@@ -492,3 +576,51 @@ func OwnerKey(obj interface{ GetLabels() map[string]string }) nodepoolutil.Key {
 	}
 	return nodepoolutil.Key{}
 }
+
+// ExpirationTTLOverride returns the expiration TTL set through the ttl-until-expired annotation on the NodeClaim
+// or the Node, with the NodeClaim taking precedence. The annotation value is a duration string (e.g. "72h") or "Never".
+// The second return value is false if neither object overrides the TTL with a valid value.
+func ExpirationTTLOverride(ctx context.Context, node *v1.Node, nodeClaim *v1beta1.NodeClaim) (time.Duration, bool) {
+	return ttlOverride(ctx, v1beta1.TTLUntilExpiredAnnotationKey, node, nodeClaim)
+}
+
+// EmptinessTTLOverride returns the emptiness TTL set through the ttl-after-empty annotation on the NodeClaim or
+// the Node, with the NodeClaim taking precedence. The annotation value is a duration string (e.g. "10m") or "Never",
+// which disables emptiness for the node and is returned as a negative duration. The second return value is false
+// if neither object overrides the TTL with a valid value.
+func EmptinessTTLOverride(ctx context.Context, node *v1.Node, nodeClaim *v1beta1.NodeClaim) (time.Duration, bool) {
+	return ttlOverride(ctx, v1beta1.TTLAfterEmptyAnnotationKey, node, nodeClaim)
+}
+
+// invalidTTLOverrides remembers the invalid TTL override annotations that were logged, so that an invalid annotation is
+// logged once instead of every time its node is reconciled
+var invalidTTLOverrides = cache.New(time.Hour, time.Minute)
+
+func ttlOverride(ctx context.Context, key string, node *v1.Node, nodeClaim *v1beta1.NodeClaim) (time.Duration, bool) {
+	var objs []client.Object
+	if nodeClaim != nil {
+		objs = append(objs, nodeClaim)
+	}
+	if node != nil {
+		objs = append(objs, node)
+	}
+	for _, obj := range objs {
+		v, ok := obj.GetAnnotations()[key]
+		if !ok {
+			continue
+		}
+		if v == "Never" {
+			return -1, true
+		}
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			// the value is part of the key, so a different invalid value is logged again
+			if invalidKey := fmt.Sprintf("%s/%s/%s/%s", obj.GetName(), obj.GetUID(), key, v); invalidTTLOverrides.Add(invalidKey, struct{}{}, cache.DefaultExpiration) == nil {
+				logging.FromContext(ctx).Errorf("parsing %s annotation on %s, %s", key, obj.GetName(), err)
+			}
+			continue
+		}
+		return ttl, true
+	}
+	return 0, false
+}
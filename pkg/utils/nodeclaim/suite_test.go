@@ -22,10 +22,14 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
 	. "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -301,6 +305,35 @@ var _ = Describe("NodeClaimUtils", func() {
 		Expect(nodeClaim.StatusConditions().GetCondition(v1beta1.NodeRegistered).IsTrue()).To(BeTrue())
 		Expect(nodeClaim.StatusConditions().GetCondition(v1beta1.NodeInitialized).IsTrue()).To(BeTrue())
 	})
+	It("should only log an invalid ttl-until-expired annotation once", func() {
+		core, logs := observer.New(zapcore.ErrorLevel)
+		ctx := logging.WithLogger(ctx, zap.New(core).Sugar())
+		node := test.Node(test.NodeOptions{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1.TTLUntilExpiredAnnotationKey: "invalid"}}})
+		for i := 0; i < 3; i++ {
+			_, ok := nodeclaimutil.ExpirationTTLOverride(ctx, node, nil)
+			Expect(ok).To(BeFalse())
+		}
+		Expect(logs.Len()).To(Equal(1))
+
+		// a different invalid value is logged again
+		node.Annotations[v1beta1.TTLUntilExpiredAnnotationKey] = "still-invalid"
+		_, ok := nodeclaimutil.ExpirationTTLOverride(ctx, node, nil)
+		Expect(ok).To(BeFalse())
+		Expect(logs.Len()).To(Equal(2))
+	})
+	It("should only log an invalid ttl-after-empty annotation once", func() {
+		core, logs := observer.New(zapcore.ErrorLevel)
+		ctx := logging.WithLogger(ctx, zap.New(core).Sugar())
+		nodeClaim := test.NodeClaim(v1beta1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1.TTLAfterEmptyAnnotationKey: "invalid"}}})
+		node := test.Node(test.NodeOptions{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1beta1.TTLAfterEmptyAnnotationKey: "10m"}}})
+		for i := 0; i < 3; i++ {
+			// the node's valid annotation is used when the NodeClaim's annotation is invalid
+			ttl, ok := nodeclaimutil.EmptinessTTLOverride(ctx, node, nodeClaim)
+			Expect(ok).To(BeTrue())
+			Expect(ttl).To(Equal(10 * time.Minute))
+		}
+		Expect(logs.Len()).To(Equal(1))
+	})
 	It("should retrieve a NodeClaim with a get call", func() {
 		nodeClaim := test.NodeClaim(v1beta1.NodeClaim{
 			Spec: v1beta1.NodeClaimSpec{
@@ -20,9 +20,12 @@ import (
 	"time"
 
 	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/aws/karpenter-core/pkg/apis/settings"
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	provisionerutil "github.com/aws/karpenter-core/pkg/utils/provisioner"
@@ -46,12 +49,19 @@ func New(provisioner *v1alpha5.Provisioner) *v1beta1.NodePool {
 					Taints:               provisioner.Spec.Taints,
 					StartupTaints:        provisioner.Spec.StartupTaints,
 					Requirements:         provisioner.Spec.Requirements,
+					Preferences:          provisioner.Spec.Preferences,
 					KubeletConfiguration: NewKubeletConfiguration(provisioner.Spec.KubeletConfiguration),
 					NodeClass:            NewNodeClassReference(provisioner.Spec.ProviderRef),
+					CloudProvider:        provisioner.Spec.CloudProvider,
 					Provider:             provisioner.Spec.Provider,
 				},
 			},
-			Weight: provisioner.Spec.Weight,
+			Weight:   provisioner.Spec.Weight,
+			Replicas: provisioner.Spec.Replicas,
+		},
+		Status: v1beta1.NodePoolStatus{
+			Resources:       provisioner.Status.Resources,
+			ScopedResources: NewScopedResources(provisioner.Status.ScopedResources),
 		},
 		IsProvisioner: true,
 	}
@@ -60,20 +70,90 @@ func New(provisioner *v1alpha5.Provisioner) *v1beta1.NodePool {
 	} else {
 		np.Spec.Deprovisioning.ExpirationTTL = metav1.Duration{Duration: -1}
 	}
+	np.Spec.Deprovisioning.ExpirationJitter = provisioner.Spec.ExpirationJitter
+	if provisioner.Spec.MaxNodeLifetimeSeconds != nil {
+		np.Spec.Deprovisioning.MaxNodeLifetime = &metav1.Duration{Duration: time.Duration(*provisioner.Spec.MaxNodeLifetimeSeconds) * time.Second}
+	}
+	if provisioner.Spec.ForceExpirationGracePeriodSeconds != nil {
+		np.Spec.Deprovisioning.ForceExpirationGracePeriod = &metav1.Duration{Duration: time.Duration(*provisioner.Spec.ForceExpirationGracePeriodSeconds) * time.Second}
+	}
+	if provisioner.Spec.DrainTimeoutSeconds != nil {
+		np.Spec.Deprovisioning.DrainTimeout = &metav1.Duration{Duration: time.Duration(*provisioner.Spec.DrainTimeoutSeconds) * time.Second}
+	}
+	np.Spec.Deprovisioning.NoExecuteFallback = provisioner.Spec.NoExecuteFallback
+	if provisioner.Spec.ForceEvictAfterSeconds != nil {
+		np.Spec.Deprovisioning.ForceEvictAfter = &metav1.Duration{Duration: time.Duration(*provisioner.Spec.ForceEvictAfterSeconds) * time.Second}
+	}
+	if provisioner.Spec.RegistrationTTLSeconds != nil {
+		np.Spec.RegistrationTTL = &metav1.Duration{Duration: time.Duration(*provisioner.Spec.RegistrationTTLSeconds) * time.Second}
+	}
+	for _, gate := range provisioner.Spec.ReadinessGates {
+		np.Spec.ReadinessGates = append(np.Spec.ReadinessGates, v1beta1.ReadinessGate{
+			ConditionType: gate.ConditionType,
+			Status:        gate.Status,
+			TaintKey:      gate.TaintKey,
+		})
+	}
 	if provisioner.Spec.Consolidation != nil && lo.FromPtr(provisioner.Spec.Consolidation.Enabled) {
-		np.Spec.Deprovisioning.ConsolidationPolicy = v1beta1.ConsolidationPolicyWhenUnderutilized
+		// Consolidating only empty nodes removes them as soon as they are found to be empty
+		np.Spec.Deprovisioning.ConsolidationPolicy = lo.Ternary(provisioner.Spec.ConsolidationPolicy == v1alpha5.ConsolidationPolicyWhenEmpty,
+			v1beta1.ConsolidationPolicyWhenEmpty, v1beta1.ConsolidationPolicyWhenUnderutilized)
+		if provisioner.Spec.Consolidation.ConsolidateAfterSeconds != nil {
+			np.Spec.Deprovisioning.ConsolidateAfter = &metav1.Duration{Duration: time.Duration(*provisioner.Spec.Consolidation.ConsolidateAfterSeconds) * time.Second}
+		}
+		np.Spec.Deprovisioning.ConsolidationMinSavingsPercent = provisioner.Spec.Consolidation.MinSavingsPercent
+		np.Spec.Deprovisioning.ConsolidationMinSavingsPerHour = provisioner.Spec.Consolidation.MinSavingsPerHour
 	} else if provisioner.Spec.TTLSecondsAfterEmpty != nil {
 		np.Spec.Deprovisioning.ConsolidationPolicy = v1beta1.ConsolidationPolicyWhenEmpty
 		np.Spec.Deprovisioning.ConsolidationTTL = metav1.Duration{Duration: lo.Must(time.ParseDuration(fmt.Sprintf("%ds", lo.FromPtr[int64](provisioner.Spec.TTLSecondsAfterEmpty))))}
 	} else {
 		np.Spec.Deprovisioning.ConsolidationPolicy = v1beta1.ConsolidationPolicyNever
 	}
+	if provisioner.Spec.Drift != nil {
+		np.Spec.Deprovisioning.DriftEnabled = provisioner.Spec.Drift.Enabled
+		if provisioner.Spec.Drift.RateLimit != nil {
+			np.Spec.Deprovisioning.DriftRateLimit = &v1beta1.DriftRateLimit{
+				Nodes:    provisioner.Spec.Drift.RateLimit.Nodes,
+				Interval: metav1.Duration{Duration: time.Duration(provisioner.Spec.Drift.RateLimit.IntervalSeconds) * time.Second},
+			}
+		}
+	}
+	if provisioner.Spec.Disruption != nil {
+		np.Spec.Deprovisioning.Budgets = lo.Map(provisioner.Spec.Disruption.Budgets, func(b v1alpha5.Budget, _ int) v1beta1.Budget {
+			return v1beta1.Budget{Nodes: b.Nodes, Schedule: b.Schedule, Duration: b.Duration}
+		})
+		np.Spec.Deprovisioning.DryRun = provisioner.Spec.Disruption.DryRun
+		np.Spec.Deprovisioning.ExcludedNodeSelector = provisioner.Spec.Disruption.ExcludedNodeSelector
+		np.Spec.Deprovisioning.LaunchBeforeCordon = provisioner.Spec.Disruption.LaunchBeforeCordon
+		np.Spec.Deprovisioning.Order = provisioner.Spec.Disruption.Order
+	}
 	if provisioner.Spec.Limits != nil {
-		np.Spec.Limits = v1beta1.Limits(provisioner.Spec.Limits.Resources)
+		np.Spec.Limits = NewLimits(provisioner.Spec.Limits.Resources, provisioner.Spec.Limits.MaxNodes)
+		for _, s := range provisioner.Spec.Limits.Scoped {
+			np.Spec.ScopedLimits = append(np.Spec.ScopedLimits, v1beta1.ScopedLimit{Zone: s.Zone, CapacityType: s.CapacityType, Limits: NewLimits(s.Resources, s.MaxNodes)})
+		}
 	}
 	return np
 }
 
+func NewScopedResources(scopedResources []v1alpha5.ScopedResources) []v1beta1.ScopedResources {
+	var result []v1beta1.ScopedResources
+	for _, s := range scopedResources {
+		result = append(result, v1beta1.ScopedResources{Zone: s.Zone, CapacityType: s.CapacityType, Resources: s.Resources})
+	}
+	return result
+}
+
+// NewLimits merges the Provisioner node limit into the resource limits as the NodePool nodes limit
+func NewLimits(resources v1.ResourceList, maxNodes *int32) v1beta1.Limits {
+	if maxNodes == nil {
+		return v1beta1.Limits(resources)
+	}
+	return v1beta1.Limits(lo.Assign(resources, v1.ResourceList{
+		v1beta1.ResourceNodes: *resource.NewQuantity(int64(*maxNodes), resource.DecimalSI),
+	}))
+}
+
 func NewKubeletConfiguration(kc *v1alpha5.KubeletConfiguration) *v1beta1.KubeletConfiguration {
 	if kc == nil {
 		return nil
@@ -138,6 +218,36 @@ func PatchStatus(ctx context.Context, c client.Client, stored, nodePool *v1beta1
 	return c.Status().Patch(ctx, nodePool, client.MergeFrom(stored))
 }
 
+// DriftEnabled returns whether drift detection is enabled for the NodePool. NodePool-level
+// configuration takes precedence over the global featureGates.driftEnabled setting.
+func DriftEnabled(ctx context.Context, nodePool *v1beta1.NodePool) bool {
+	if nodePool.Spec.Deprovisioning.DriftEnabled != nil {
+		return *nodePool.Spec.Deprovisioning.DriftEnabled
+	}
+	return settings.FromContext(ctx).DriftEnabled
+}
+
+// DisruptionPaused returns whether all voluntary disruption of the NodePool's nodes has been suspended
+// through the disruption-paused annotation
+func DisruptionPaused(nodePool *v1beta1.NodePool) bool {
+	return nodePool.Annotations[v1beta1.DisruptionPausedAnnotationKey] == "true"
+}
+
+// MaxNodeLifetimeDeadlines returns the time at which the node exceeds the NodePool MaxNodeLifetime and the time after
+// which its disruption is forced, ignoring PodDisruptionBudgets and do-not-evict pods. The last return value is false
+// if the NodePool doesn't configure a MaxNodeLifetime.
+func MaxNodeLifetimeDeadlines(nodePool *v1beta1.NodePool, node *v1.Node) (expireAt time.Time, forceAt time.Time, ok bool) {
+	if nodePool.Spec.Deprovisioning.MaxNodeLifetime == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	expireAt = node.CreationTimestamp.Add(nodePool.Spec.Deprovisioning.MaxNodeLifetime.Duration)
+	forceAt = expireAt
+	if nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod != nil {
+		forceAt = forceAt.Add(nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod.Duration)
+	}
+	return expireAt, forceAt, true
+}
+
 func HashAnnotation(nodePool *v1beta1.NodePool) map[string]string {
 	if nodePool.IsProvisioner {
 		provisioner := provisionerutil.New(nodePool)
@@ -77,6 +77,7 @@ var _ = Describe("NodePoolUtils", func() {
 				APIVersion: "test.cloudprovider/v1",
 				Name:       "default",
 			},
+			CloudProvider: "metal",
 			Kubelet: &v1alpha5.KubeletConfiguration{
 				ContainerRuntime: ptr.String("containerd"),
 				MaxPods:          ptr.Int32(110),
@@ -221,6 +222,7 @@ var _ = Describe("NodePoolUtils", func() {
 		Expect(nodePool.Spec.Template.Spec.NodeClass.Kind).To(Equal(provisioner.Spec.ProviderRef.Kind))
 		Expect(nodePool.Spec.Template.Spec.NodeClass.APIVersion).To(Equal(provisioner.Spec.ProviderRef.APIVersion))
 		Expect(nodePool.Spec.Template.Spec.NodeClass.Name).To(Equal(provisioner.Spec.ProviderRef.Name))
+		Expect(nodePool.Spec.Template.Spec.CloudProvider).To(Equal(provisioner.Spec.CloudProvider))
 
 		Expect(nodePool.Spec.Deprovisioning.ConsolidationPolicy).To(Equal(v1beta1.ConsolidationPolicyWhenUnderutilized))
 		Expect(nodePool.Spec.Deprovisioning.ExpirationTTL.Duration.Seconds()).To(BeNumerically("==", lo.FromPtr(provisioner.Spec.TTLSecondsUntilExpired)))
@@ -229,6 +231,54 @@ var _ = Describe("NodePoolUtils", func() {
 		ExpectResources(v1.ResourceList(nodePool.Spec.Limits), provisioner.Spec.Limits.Resources)
 		Expect(lo.FromPtr(nodePool.Spec.Weight)).To(BeNumerically("==", lo.FromPtr(provisioner.Spec.Weight)))
 	})
+	It("should convert a Provisioner that only consolidates empty nodes to a NodePool", func() {
+		provisioner.Spec.TTLSecondsAfterEmpty = nil
+		provisioner.Spec.ConsolidationPolicy = v1alpha5.ConsolidationPolicyWhenEmpty
+
+		nodePool := nodepoolutil.New(provisioner)
+		Expect(nodePool.Spec.Deprovisioning.ConsolidationPolicy).To(Equal(v1beta1.ConsolidationPolicyWhenEmpty))
+		Expect(nodePool.Spec.Deprovisioning.ConsolidationTTL.Duration).To(BeZero())
+	})
+	It("should convert a Provisioner to a NodePool (with MaxNodes)", func() {
+		provisioner.Spec.Limits.MaxNodes = ptr.Int32(3)
+
+		nodePool := nodepoolutil.New(provisioner)
+		ExpectResources(lo.Assign(provisioner.Spec.Limits.Resources, v1.ResourceList{v1beta1.ResourceNodes: resource.MustParse("3")}), v1.ResourceList(nodePool.Spec.Limits))
+	})
+	It("should convert a Provisioner to a NodePool (with scoped limits)", func() {
+		provisioner.Spec.Limits.Scoped = []v1alpha5.ScopedLimit{{
+			Zone:         "test-zone-1",
+			CapacityType: v1alpha5.CapacityTypeSpot,
+			Resources:    v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")},
+			MaxNodes:     ptr.Int32(2),
+		}}
+
+		nodePool := nodepoolutil.New(provisioner)
+		Expect(nodePool.Spec.ScopedLimits).To(HaveLen(1))
+		Expect(nodePool.Spec.ScopedLimits[0].Zone).To(Equal("test-zone-1"))
+		Expect(nodePool.Spec.ScopedLimits[0].CapacityType).To(Equal(v1alpha5.CapacityTypeSpot))
+		ExpectResources(v1.ResourceList{v1.ResourceCPU: resource.MustParse("10"), v1beta1.ResourceNodes: resource.MustParse("2")}, v1.ResourceList(nodePool.Spec.ScopedLimits[0].Limits))
+	})
+	It("should convert a Provisioner to a NodePool (with replicas)", func() {
+		provisioner.Spec.Replicas = ptr.Int32(2)
+		provisioner.Status.Resources = v1.ResourceList{v1alpha5.ResourceNodes: resource.MustParse("1")}
+
+		nodePool := nodepoolutil.New(provisioner)
+		Expect(lo.FromPtr(nodePool.Spec.Replicas)).To(BeNumerically("==", 2))
+		ExpectResources(provisioner.Status.Resources, nodePool.Status.Resources)
+	})
+	It("should convert a Provisioner to a NodePool (with readiness gates)", func() {
+		provisioner.Spec.ReadinessGates = []v1alpha5.ReadinessGate{
+			{ConditionType: "NetworkAvailable", Status: v1.ConditionTrue},
+			{TaintKey: "cilium.io/agent-not-ready"},
+		}
+
+		nodePool := nodepoolutil.New(provisioner)
+		Expect(nodePool.Spec.ReadinessGates).To(Equal([]v1beta1.ReadinessGate{
+			{ConditionType: "NetworkAvailable", Status: v1.ConditionTrue},
+			{TaintKey: "cilium.io/agent-not-ready"},
+		}))
+	})
 	It("should convert a Provisioner to a NodePool (with Provider)", func() {
 		provisioner.Spec.Provider = &runtime.RawExtension{Raw: lo.Must(json.Marshal(map[string]string{
 			"test-key":  "test-value",
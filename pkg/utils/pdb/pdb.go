@@ -12,7 +12,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package deprovisioning
+package pdb
 
 import (
 	"context"
@@ -24,15 +24,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// PDBLimits is used to evaluate if evicting a list of pods is possible.
-type PDBLimits struct {
+// Limits is used to evaluate if evicting a list of pods is possible.
+type Limits struct {
 	ctx        context.Context
 	kubeClient client.Client
 	pdbs       []*pdbItem
 }
 
-func NewPDBLimits(ctx context.Context, kubeClient client.Client) (*PDBLimits, error) {
-	ps := &PDBLimits{
+func NewLimits(ctx context.Context, kubeClient client.Client) (*Limits, error) {
+	ps := &Limits{
 		ctx:        ctx,
 		kubeClient: kubeClient,
 	}
@@ -54,7 +54,7 @@ func NewPDBLimits(ctx context.Context, kubeClient client.Client) (*PDBLimits, er
 
 // CanEvictPods returns true if every pod in the list is evictable. They may not all be evictable simultaneously, but
 // for every PDB that controls the pods at least one pod can be evicted.
-func (s *PDBLimits) CanEvictPods(pods []*v1.Pod) (client.ObjectKey, bool) {
+func (s *Limits) CanEvictPods(pods []*v1.Pod) (client.ObjectKey, bool) {
 	for _, pod := range pods {
 		for _, pdb := range s.pdbs {
 			if pdb.name.Namespace == pod.ObjectMeta.Namespace {
@@ -19,12 +19,15 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/scheduling"
 )
 
 func IsProvisionable(pod *v1.Pod) bool {
 	return !IsScheduled(pod) &&
 		!IsPreempting(pod) &&
+		!IsSchedulingGated(pod) &&
+		!HasResourceClaims(pod) &&
 		FailedToSchedule(pod) &&
 		!IsOwnedByDaemonSet(pod) &&
 		!IsOwnedByNode(pod)
@@ -39,6 +42,18 @@ func FailedToSchedule(pod *v1.Pod) bool {
 	return false
 }
 
+// IsSchedulingGated returns true if the pod has scheduling gates that haven't been removed. The kube-scheduler won't
+// attempt to schedule the pod until all of its gates are removed, so we shouldn't provision capacity for it.
+func IsSchedulingGated(pod *v1.Pod) bool {
+	return len(pod.Spec.SchedulingGates) > 0
+}
+
+// HasResourceClaims returns true if the pod references Dynamic Resource Allocation ResourceClaims. We can't model the
+// resources that are allocated to satisfy a claim, so we don't provision capacity for these pods.
+func HasResourceClaims(pod *v1.Pod) bool {
+	return len(pod.Spec.ResourceClaims) > 0
+}
+
 func IsScheduled(pod *v1.Pod) bool {
 	return pod.Spec.NodeName != ""
 }
@@ -47,8 +62,29 @@ func IsPreempting(pod *v1.Pod) bool {
 	return pod.Status.NominatedNodeName != ""
 }
 
+// IsTerminal returns whether the pod has finished running and won't be restarted. This includes pods whose containers
+// have all exited but whose phase hasn't been updated by the kubelet yet.
 func IsTerminal(pod *v1.Pod) bool {
-	return pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded
+	return pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded || haveContainersCompleted(pod)
+}
+
+// haveContainersCompleted returns whether every container in the pod has terminated and the restart policy
+// guarantees that none of them will be restarted
+func haveContainersCompleted(pod *v1.Pod) bool {
+	if pod.Spec.RestartPolicy == v1.RestartPolicyAlways || len(pod.Spec.Containers) == 0 ||
+		len(pod.Status.ContainerStatuses) != len(pod.Spec.Containers) {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil {
+			return false
+		}
+		// Containers that failed are restarted with the OnFailure policy
+		if pod.Spec.RestartPolicy == v1.RestartPolicyOnFailure && status.State.Terminated.ExitCode != 0 {
+			return false
+		}
+	}
+	return true
 }
 
 func IsTerminating(pod *v1.Pod) bool {
@@ -86,7 +122,50 @@ func HasDoNotEvict(pod *v1.Pod) bool {
 	return pod.Annotations[v1alpha5.DoNotEvictPodAnnotationKey] == "true"
 }
 
-// HasUnschedulableToleration returns true if the pod tolerates node.kubernetes.io/unschedulable taint
+// HasDoNotDisrupt returns true if the pod blocks voluntary disruption (consolidation, drift and expiration) of the
+// node that it's running on
+func HasDoNotDisrupt(pod *v1.Pod) bool {
+	if pod.Annotations == nil {
+		return false
+	}
+	return pod.Annotations[v1beta1.DoNotDisruptAnnotationKey] == "true"
+}
+
+// QOSClass returns the QoS class of the pod, computing it from the pod's containers if the API server hasn't set it
+// https://kubernetes.io/docs/concepts/workloads/pods/pod-qos/
+func QOSClass(pod *v1.Pod) v1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+	containers := append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	bestEffort, guaranteed := true, true
+	for _, c := range containers {
+		if len(c.Resources.Requests) > 0 || len(c.Resources.Limits) > 0 {
+			bestEffort = false
+		}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			limit, ok := c.Resources.Limits[name]
+			if !ok {
+				guaranteed = false
+				continue
+			}
+			// requests default to limits when they aren't set
+			if request, ok := c.Resources.Requests[name]; ok && !request.Equal(limit) {
+				guaranteed = false
+			}
+		}
+	}
+	switch {
+	case bestEffort:
+		return v1.PodQOSBestEffort
+	case guaranteed:
+		return v1.PodQOSGuaranteed
+	default:
+		return v1.PodQOSBurstable
+	}
+}
+
+// ToleratesUnschedulableTaint returns true if the pod tolerates node.kubernetes.io/unschedulable taint
 func ToleratesUnschedulableTaint(pod *v1.Pod) bool {
 	return (scheduling.Taints{{Key: v1.TaintNodeUnschedulable, Effect: v1.TaintEffectNoSchedule}}).Tolerates(pod) == nil
 }
@@ -17,6 +17,7 @@ package provisioner
 import (
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/ptr"
 
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
@@ -32,25 +33,91 @@ func New(nodePool *v1beta1.NodePool) *v1alpha5.Provisioner {
 			Taints:               nodePool.Spec.Template.Spec.Taints,
 			StartupTaints:        nodePool.Spec.Template.Spec.StartupTaints,
 			Requirements:         nodePool.Spec.Template.Spec.Requirements,
+			Preferences:          nodePool.Spec.Template.Spec.Preferences,
 			KubeletConfiguration: NewKubeletConfiguration(nodePool.Spec.Template.Spec.KubeletConfiguration),
 			Provider:             nodePool.Spec.Template.Spec.Provider,
 			ProviderRef:          NewProviderRef(nodePool.Spec.Template.Spec.NodeClass),
-			Limits:               NewLimits(v1.ResourceList(nodePool.Spec.Limits)),
+			CloudProvider:        nodePool.Spec.Template.Spec.CloudProvider,
+			Limits:               NewLimits(v1.ResourceList(nodePool.Spec.Limits), nodePool.Spec.ScopedLimits),
 			Weight:               nodePool.Spec.Weight,
+			Replicas:             nodePool.Spec.Replicas,
+			ExpirationJitter:     nodePool.Spec.Deprovisioning.ExpirationJitter,
 		},
 		Status: v1alpha5.ProvisionerStatus{
-			Resources: nodePool.Status.Resources,
+			Resources:       nodePool.Status.Resources,
+			ScopedResources: NewScopedResources(nodePool.Status.ScopedResources),
 		},
 	}
 	if nodePool.Spec.Deprovisioning.ExpirationTTL.Duration >= 0 {
 		p.Spec.TTLSecondsUntilExpired = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.ExpirationTTL.Seconds()))
 	}
-	if nodePool.Spec.Deprovisioning.ConsolidationPolicy == v1beta1.ConsolidationPolicyWhenEmpty {
+	if nodePool.Spec.Deprovisioning.MaxNodeLifetime != nil {
+		p.Spec.MaxNodeLifetimeSeconds = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.MaxNodeLifetime.Seconds()))
+	}
+	if nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod != nil {
+		p.Spec.ForceExpirationGracePeriodSeconds = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.ForceExpirationGracePeriod.Seconds()))
+	}
+	if nodePool.Spec.Deprovisioning.DrainTimeout != nil {
+		p.Spec.DrainTimeoutSeconds = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.DrainTimeout.Seconds()))
+	}
+	p.Spec.NoExecuteFallback = nodePool.Spec.Deprovisioning.NoExecuteFallback
+	if nodePool.Spec.Deprovisioning.ForceEvictAfter != nil {
+		p.Spec.ForceEvictAfterSeconds = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.ForceEvictAfter.Seconds()))
+	}
+	if nodePool.Spec.RegistrationTTL != nil {
+		p.Spec.RegistrationTTLSeconds = lo.ToPtr(int64(nodePool.Spec.RegistrationTTL.Seconds()))
+	}
+	for _, gate := range nodePool.Spec.ReadinessGates {
+		p.Spec.ReadinessGates = append(p.Spec.ReadinessGates, v1alpha5.ReadinessGate{
+			ConditionType: gate.ConditionType,
+			Status:        gate.Status,
+			TaintKey:      gate.TaintKey,
+		})
+	}
+	// Consolidating only empty nodes removes them as soon as they are found to be empty, so a WhenEmpty NodePool without
+	// a ConsolidationTTL is a Provisioner that consolidates with the WhenEmpty policy
+	if nodePool.Spec.Deprovisioning.ConsolidationPolicy == v1beta1.ConsolidationPolicyWhenEmpty && nodePool.Spec.Deprovisioning.ConsolidationTTL.Duration > 0 {
 		p.Spec.TTLSecondsAfterEmpty = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.ConsolidationTTL.Seconds()))
 	}
+	if nodePool.Spec.Deprovisioning.ConsolidationPolicy == v1beta1.ConsolidationPolicyWhenEmpty && nodePool.Spec.Deprovisioning.ConsolidationTTL.Duration == 0 {
+		p.Spec.ConsolidationPolicy = v1alpha5.ConsolidationPolicyWhenEmpty
+		p.Spec.Consolidation = &v1alpha5.Consolidation{
+			Enabled:           lo.ToPtr(true),
+			MinSavingsPercent: nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent,
+			MinSavingsPerHour: nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPerHour,
+		}
+	}
 	if nodePool.Spec.Deprovisioning.ConsolidationPolicy == v1beta1.ConsolidationPolicyWhenUnderutilized {
 		p.Spec.Consolidation = &v1alpha5.Consolidation{
-			Enabled: lo.ToPtr(true),
+			Enabled:           lo.ToPtr(true),
+			MinSavingsPercent: nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent,
+			MinSavingsPerHour: nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPerHour,
+		}
+		if nodePool.Spec.Deprovisioning.ConsolidateAfter != nil {
+			p.Spec.Consolidation.ConsolidateAfterSeconds = lo.ToPtr(int64(nodePool.Spec.Deprovisioning.ConsolidateAfter.Seconds()))
+		}
+	}
+	if nodePool.Spec.Deprovisioning.DriftEnabled != nil || nodePool.Spec.Deprovisioning.DriftRateLimit != nil {
+		p.Spec.Drift = &v1alpha5.Drift{
+			Enabled: nodePool.Spec.Deprovisioning.DriftEnabled,
+		}
+		if nodePool.Spec.Deprovisioning.DriftRateLimit != nil {
+			p.Spec.Drift.RateLimit = &v1alpha5.DriftRateLimit{
+				Nodes:           nodePool.Spec.Deprovisioning.DriftRateLimit.Nodes,
+				IntervalSeconds: int64(nodePool.Spec.Deprovisioning.DriftRateLimit.Interval.Seconds()),
+			}
+		}
+	}
+	if len(nodePool.Spec.Deprovisioning.Budgets) > 0 || len(nodePool.Spec.Deprovisioning.Order) > 0 || nodePool.Spec.Deprovisioning.DryRun ||
+		nodePool.Spec.Deprovisioning.ExcludedNodeSelector != nil || nodePool.Spec.Deprovisioning.LaunchBeforeCordon {
+		p.Spec.Disruption = &v1alpha5.Disruption{
+			Budgets: lo.Map(nodePool.Spec.Deprovisioning.Budgets, func(b v1beta1.Budget, _ int) v1alpha5.Budget {
+				return v1alpha5.Budget{Nodes: b.Nodes, Schedule: b.Schedule, Duration: b.Duration}
+			}),
+			DryRun:               nodePool.Spec.Deprovisioning.DryRun,
+			ExcludedNodeSelector: nodePool.Spec.Deprovisioning.ExcludedNodeSelector,
+			LaunchBeforeCordon:   nodePool.Spec.Deprovisioning.LaunchBeforeCordon,
+			Order:                nodePool.Spec.Deprovisioning.Order,
 		}
 	}
 	return p
@@ -88,8 +155,30 @@ func NewProviderRef(nc *v1beta1.NodeClassReference) *v1alpha5.MachineTemplateRef
 	}
 }
 
-func NewLimits(limits v1.ResourceList) *v1alpha5.Limits {
-	return &v1alpha5.Limits{
-		Resources: limits,
+func NewLimits(limits v1.ResourceList, scopedLimits []v1beta1.ScopedLimit) *v1alpha5.Limits {
+	l := &v1alpha5.Limits{}
+	l.Resources, l.MaxNodes = splitNodeLimit(limits)
+	for _, scopedLimit := range scopedLimits {
+		scoped := v1alpha5.ScopedLimit{Zone: scopedLimit.Zone, CapacityType: scopedLimit.CapacityType}
+		scoped.Resources, scoped.MaxNodes = splitNodeLimit(v1.ResourceList(scopedLimit.Limits))
+		l.Scoped = append(l.Scoped, scoped)
+	}
+	return l
+}
+
+func NewScopedResources(scopedResources []v1beta1.ScopedResources) []v1alpha5.ScopedResources {
+	var result []v1alpha5.ScopedResources
+	for _, s := range scopedResources {
+		result = append(result, v1alpha5.ScopedResources{Zone: s.Zone, CapacityType: s.CapacityType, Resources: s.Resources})
+	}
+	return result
+}
+
+// splitNodeLimit separates the NodePool nodes limit, which is a separate field on the Provisioner, from the resource limits
+func splitNodeLimit(limits v1.ResourceList) (v1.ResourceList, *int32) {
+	nodes, ok := limits[v1beta1.ResourceNodes]
+	if !ok {
+		return limits, nil
 	}
+	return lo.OmitByKeys(limits, []v1.ResourceName{v1beta1.ResourceNodes}), ptr.Int32(int32(nodes.Value()))
 }
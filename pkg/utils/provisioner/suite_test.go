@@ -35,6 +35,7 @@ import (
 	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
 	"github.com/aws/karpenter-core/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-core/pkg/test"
+	nodepoolutil "github.com/aws/karpenter-core/pkg/utils/nodepool"
 	provisionerutil "github.com/aws/karpenter-core/pkg/utils/provisioner"
 )
 
@@ -166,6 +167,7 @@ var _ = Describe("ProvisionerUtils", func() {
 							APIVersion: "test.cloudprovider/v1",
 							Name:       "default",
 						},
+						CloudProvider: "metal",
 					},
 				},
 				Deprovisioning: v1beta1.Deprovisioning{
@@ -217,6 +219,7 @@ var _ = Describe("ProvisionerUtils", func() {
 		Expect(provisioner.Spec.ProviderRef.Kind).To(Equal(nodePool.Spec.Template.Spec.NodeClass.Kind))
 		Expect(provisioner.Spec.ProviderRef.APIVersion).To(Equal(nodePool.Spec.Template.Spec.NodeClass.APIVersion))
 		Expect(provisioner.Spec.ProviderRef.Name).To(Equal(nodePool.Spec.Template.Spec.NodeClass.Name))
+		Expect(provisioner.Spec.CloudProvider).To(Equal(nodePool.Spec.Template.Spec.CloudProvider))
 
 		Expect(provisioner.Spec.Consolidation).ToNot(BeNil())
 		Expect(provisioner.Spec.Consolidation.Enabled).ToNot(BeNil())
@@ -228,6 +231,56 @@ var _ = Describe("ProvisionerUtils", func() {
 		ExpectResources(provisioner.Spec.Limits.Resources, v1.ResourceList(nodePool.Spec.Limits))
 		Expect(lo.FromPtr(provisioner.Spec.Weight)).To(BeNumerically("==", lo.FromPtr(nodePool.Spec.Weight)))
 	})
+	It("should convert a NodePool that consolidates empty nodes to a Provisioner with the WhenEmpty consolidation policy", func() {
+		nodePool.Spec.Deprovisioning.ConsolidationPolicy = v1beta1.ConsolidationPolicyWhenEmpty
+		nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent = lo.ToPtr[int32](20)
+
+		provisioner := provisionerutil.New(nodePool)
+		Expect(provisioner.Spec.ConsolidationPolicy).To(Equal(v1alpha5.ConsolidationPolicyWhenEmpty))
+		Expect(provisioner.Spec.Consolidation).ToNot(BeNil())
+		Expect(lo.FromPtr(provisioner.Spec.Consolidation.Enabled)).To(BeTrue())
+		Expect(provisioner.Spec.Consolidation.MinSavingsPercent).To(Equal(nodePool.Spec.Deprovisioning.ConsolidationMinSavingsPercent))
+		Expect(provisioner.Spec.TTLSecondsAfterEmpty).To(BeNil())
+	})
+	It("should convert a NodePool that removes empty nodes after a TTL to a Provisioner with ttlSecondsAfterEmpty", func() {
+		nodePool.Spec.Deprovisioning.ConsolidationPolicy = v1beta1.ConsolidationPolicyWhenEmpty
+		nodePool.Spec.Deprovisioning.ConsolidationTTL = metav1.Duration{Duration: time.Minute}
+
+		provisioner := provisionerutil.New(nodePool)
+		Expect(lo.FromPtr(provisioner.Spec.TTLSecondsAfterEmpty)).To(BeNumerically("==", 60))
+		Expect(provisioner.Spec.Consolidation).To(BeNil())
+		Expect(provisioner.Spec.ConsolidationPolicy).To(BeEmpty())
+	})
+	DescribeTable("should round trip the consolidation settings of a Provisioner through a NodePool",
+		func(spec v1alpha5.ProvisionerSpec) {
+			provisioner := test.Provisioner()
+			provisioner.Spec.Consolidation = spec.Consolidation
+			provisioner.Spec.ConsolidationPolicy = spec.ConsolidationPolicy
+			provisioner.Spec.TTLSecondsAfterEmpty = spec.TTLSecondsAfterEmpty
+
+			converted := provisionerutil.New(nodepoolutil.New(provisioner))
+			Expect(converted.Spec.Consolidation).To(Equal(provisioner.Spec.Consolidation))
+			Expect(converted.Spec.ConsolidationPolicy).To(Equal(provisioner.Spec.ConsolidationPolicy))
+			Expect(converted.Spec.TTLSecondsAfterEmpty).To(Equal(provisioner.Spec.TTLSecondsAfterEmpty))
+		},
+		Entry("consolidation of empty nodes", v1alpha5.ProvisionerSpec{
+			Consolidation:       &v1alpha5.Consolidation{Enabled: lo.ToPtr(true), MinSavingsPercent: lo.ToPtr[int32](20)},
+			ConsolidationPolicy: v1alpha5.ConsolidationPolicyWhenEmpty,
+		}),
+		Entry("consolidation of underutilized nodes", v1alpha5.ProvisionerSpec{
+			Consolidation: &v1alpha5.Consolidation{Enabled: lo.ToPtr(true), ConsolidateAfterSeconds: lo.ToPtr[int64](30)},
+		}),
+		Entry("ttlSecondsAfterEmpty", v1alpha5.ProvisionerSpec{
+			TTLSecondsAfterEmpty: lo.ToPtr[int64](30),
+		}),
+	)
+	It("should convert a NodePool to a Provisioner (with a nodes limit)", func() {
+		nodePool.Spec.Limits[v1beta1.ResourceNodes] = resource.MustParse("3")
+
+		provisioner := provisionerutil.New(nodePool)
+		Expect(lo.FromPtr(provisioner.Spec.Limits.MaxNodes)).To(BeNumerically("==", 3))
+		Expect(provisioner.Spec.Limits.Resources).ToNot(HaveKey(v1beta1.ResourceNodes))
+	})
 	It("should convert a Provisioner to a NodePool (with Provider)", func() {
 		nodePool.Spec.Template.Spec.Provider = &runtime.RawExtension{Raw: lo.Must(json.Marshal(map[string]string{
 			"test-key":  "test-value",
@@ -106,6 +106,13 @@ func Ceiling(pod *v1.Pod) v1.ResourceRequirements {
 	}
 	if pod.Spec.Overhead != nil {
 		resources.Requests = MergeInto(resources.Requests, pod.Spec.Overhead)
+		// Overhead is only added to the limits of resources that are limited, matching the kubelet's behavior
+		for resourceName, quantity := range pod.Spec.Overhead {
+			if current, ok := resources.Limits[resourceName]; ok {
+				current.Add(quantity)
+				resources.Limits[resourceName] = current
+			}
+		}
 	}
 	return resources
 }